@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"txing-ai/internal/agent"
+	"txing-ai/internal/app"
+	"txing-ai/internal/eval"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/tool/mcp"
+
+	"go.uber.org/zap"
+)
+
+// runEval 智能体评测子命令，返回进程退出码
+//
+// 用法：txing-ai [-cfg 配置文件] eval -suite eval/suite.json [-mode replay|record|live] [-out eval-report]
+func runEval(args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	suitePath := fs.String("suite", "", "评测套件文件路径（JSON）")
+	mode := fs.String("mode", eval.ModeReplay, "运行模式：replay 离线回放，record 调用真实模型和工具并录制，live 调用真实模型并回放工具")
+	fixtureDir := fs.String("fixtures", "", "录制文件目录，默认使用套件中的配置")
+	outDir := fs.String("out", "eval-report", "报告输出目录")
+	cases := fs.String("cases", "", "只运行指定的用例，多个用逗号分隔")
+	timeout := fs.Int("timeout", 900, "单个用例的超时时间（秒）")
+	endpoint := fs.String("endpoint", os.Getenv("EVAL_ENDPOINT"), "录制和实时模式下使用的模型接口地址")
+	model := fs.String("model", os.Getenv("EVAL_MODEL"), "录制和实时模式下使用的模型")
+	apiKey := fs.String("api-key", os.Getenv("EVAL_API_KEY"), "录制和实时模式下使用的模型 API Key")
+	judgeModel := fs.String("judge-model", os.Getenv("EVAL_JUDGE_MODEL"), "LLM 评审使用的模型，为空表示不使用 LLM 评审")
+	judgeEndpoint := fs.String("judge-endpoint", os.Getenv("EVAL_JUDGE_ENDPOINT"), "LLM 评审使用的模型接口地址，默认同 -endpoint")
+	judgeAPIKey := fs.String("judge-api-key", os.Getenv("EVAL_JUDGE_API_KEY"), "LLM 评审使用的模型 API Key，默认同 -api-key")
+	_ = fs.Parse(args)
+
+	if *suitePath == "" {
+		fmt.Fprintln(os.Stderr, "-suite is required")
+		fs.Usage()
+		return 2
+	}
+	suite, err := eval.LoadSuite(*suitePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// 只有录制模式需要真实的 MCP 工具，其他模式使用录制的工具结果
+	mcpConfig := global.LoadConfig().MCPConfig
+	mcpClientManager := mcp.NewMCPClientManager(ctx, mcpConfig)
+	if *mode == eval.ModeRecord {
+		mcpConfigPath := ""
+		if mcpConfig != nil {
+			mcpConfigPath = mcpConfig.ConfigPath
+		}
+		if err := mcpClientManager.Init(mcpConfigPath); err != nil {
+			log.Error("mcpClientManager init error", zap.Error(err))
+		}
+		defer mcpClientManager.CloseAllMCPServers()
+	}
+	// 评测不需要数据库和 Redis，也不需要工具调用审批
+	factory := agent.NewSimpleAgentFactory(app.NewResourceProvider(nil, nil, mcpClientManager), nil)
+
+	options := &eval.Options{
+		Mode:       *mode,
+		FixtureDir: *fixtureDir,
+		Endpoint:   *endpoint,
+		APIKey:     *apiKey,
+		Model:      *model,
+		Timeout:    time.Duration(*timeout) * time.Second,
+	}
+	if *cases != "" {
+		options.CaseIDs = strings.Split(*cases, ",")
+	}
+	if *judgeModel != "" {
+		options.Judge = &eval.JudgeConfig{Endpoint: *judgeEndpoint, APIKey: *judgeAPIKey, Model: *judgeModel}
+		if options.Judge.Endpoint == "" {
+			options.Judge.Endpoint = *endpoint
+		}
+		if options.Judge.APIKey == "" {
+			options.Judge.APIKey = *apiKey
+		}
+	}
+
+	runner, err := eval.NewRunner(ctx, factory, options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	report := runner.Run(ctx, suite)
+
+	jsonPath := filepath.Join(*outDir, "report.json")
+	markdownPath := filepath.Join(*outDir, "report.md")
+	if err := report.WriteJSON(jsonPath); err != nil {
+		log.Error("write json report failed", zap.Error(err))
+		return 1
+	}
+	if err := report.WriteMarkdown(markdownPath); err != nil {
+		log.Error("write markdown report failed", zap.Error(err))
+		return 1
+	}
+	fmt.Print(report.Markdown())
+	fmt.Printf("\n报告已保存：%s、%s\n", jsonPath, markdownPath)
+
+	if report.Summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
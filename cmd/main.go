@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
@@ -35,6 +36,11 @@ func main() {
 
 	logging.InitLogger(appConfig.LogConfig, appConfig.Profile)
 
+	// 子命令：智能体评测
+	if flag.Arg(0) == "eval" {
+		os.Exit(runEval(flag.Args()[1:]))
+	}
+
 	log.Info("Txing AI service starting...")
 
 	// 初始化翻译器，用于将错误信息等翻译成中文
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"go.uber.org/zap"
+	"txing-ai/internal/adapter/anthropic"
 	adaptercommon "txing-ai/internal/adapter/common"
 	"txing-ai/internal/adapter/eino_openai"
+	"txing-ai/internal/adapter/gemini"
 	"txing-ai/internal/adapter/openai"
 	"txing-ai/internal/adapter/polo"
 	myVolcengine "txing-ai/internal/adapter/volcengine"
@@ -20,6 +22,8 @@ var chatRequesterFactories = map[string]adaptercommon.ChatRequesterFactory{
 	global.ChannelTypePolo:       polo.NewPoloFactory(),
 	global.ChannelOpenai:         openai.NewOpenaiFactory(),
 	global.ChannelEinoOpenai:     eino_openai.NewEinoOpenaiFactory(),
+	global.ChannelAnthropic:      anthropic.NewAnthropicFactory(),
+	global.ChannelGemini:         gemini.NewGeminiFactory(),
 }
 
 func createChatRequest(ctx context.Context, channelConfig iface.ChannelConfig, chatConfig *adaptercommon.ChatConfig, hook global.Hook) error {
@@ -40,3 +44,41 @@ func createChatRequest(ctx context.Context, channelConfig iface.ChannelConfig, c
 
 	return fmt.Errorf("unknown channel type %s (channel #%d)", channelType, channelConfig.GetId())
 }
+
+func createEmbeddings(ctx context.Context, channelConfig iface.ChannelConfig, conf *adaptercommon.EmbeddingConfig) (*adaptercommon.EmbeddingResult, error) {
+	channelType := channelConfig.GetType()
+	factory, ok := chatRequesterFactories[channelType].(adaptercommon.EmbeddingRequesterFactory)
+	if !ok {
+		return nil, fmt.Errorf("channel type %s does not support embeddings (channel #%d)", channelType, channelConfig.GetId())
+	}
+	requester, err := factory.CreateEmbeddingRequester(channelConfig)
+	if err != nil {
+		log.Error("failed to create embedding requester for channel", zap.String("channel_type", channelType), zap.Error(err))
+		return nil, err
+	}
+	result, err := requester.CreateEmbeddings(ctx, conf)
+	if err != nil {
+		log.Error("failed to create embeddings for channel", zap.String("channel_type", channelType), zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+func createRerank(ctx context.Context, channelConfig iface.ChannelConfig, conf *adaptercommon.RerankConfig) (*adaptercommon.RerankResult, error) {
+	channelType := channelConfig.GetType()
+	factory, ok := chatRequesterFactories[channelType].(adaptercommon.RerankerFactory)
+	if !ok {
+		return nil, fmt.Errorf("channel type %s does not support rerank (channel #%d)", channelType, channelConfig.GetId())
+	}
+	reranker, err := factory.CreateReranker(channelConfig)
+	if err != nil {
+		log.Error("failed to create reranker for channel", zap.String("channel_type", channelType), zap.Error(err))
+		return nil, err
+	}
+	result, err := reranker.Rerank(ctx, conf)
+	if err != nil {
+		log.Error("failed to rerank for channel", zap.String("channel_type", channelType), zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,218 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	adaptercommon "txing-ai/internal/adapter/common"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+
+	"go.uber.org/zap"
+)
+
+// https://docs.anthropic.com/en/api/messages-streaming
+
+const (
+	// 没有配置服务地址时使用 Anthropic 官方地址
+	defaultEndpoint = "https://api.anthropic.com/v1"
+	apiVersion      = "2023-06-01"
+	// Messages API 必须指定 max_tokens，没有配置时使用的默认值
+	defaultMaxTokens = 8192
+	// 思考的最小 token 预算
+	minThinkingBudget = 1024
+)
+
+type ChatClient struct {
+	Endpoint   string
+	ApiKey     string
+	httpClient *http.Client
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type thinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type messagesRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float32  `json:"temperature,omitempty"`
+	TopP        *float32  `json:"top_p,omitempty"`
+	TopK        *int      `json:"top_k,omitempty"`
+	Thinking    *thinking `json:"thinking,omitempty"`
+	Stream      bool      `json:"stream"`
+}
+
+// streamEvent 流式响应的事件，只解析用到的字段
+type streamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		Thinking   string `json:"thinking"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Error *apiError `json:"error"`
+}
+
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (c ChatClient) StreamChat(ctx context.Context, conf *adaptercommon.ChatConfig, callback global.Hook) error {
+	body, err := json.Marshal(buildRequest(conf))
+	if err != nil {
+		return fmt.Errorf("marshal request body failed: %v", err)
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request failed: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("x-api-key", c.ApiKey)
+	req.Header.Set("anthropic-version", apiVersion)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var result struct {
+			Error *apiError `json:"error"`
+		}
+		if json.Unmarshal(data, &result) == nil && result.Error != nil {
+			return fmt.Errorf("anthropic request failed with status %d: %s: %s", resp.StatusCode, result.Error.Type, result.Error.Message)
+		}
+		return fmt.Errorf("anthropic request failed with status %d: %s", resp.StatusCode, string(data))
+	}
+
+	return adaptercommon.ReadSSE(ctx, resp.Body, func(_, data string) error {
+		var event streamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("invalid stream event: %v", err)
+		}
+
+		switch event.Type {
+		case "error":
+			// 流中途出错，例如服务过载
+			if event.Error != nil {
+				return fmt.Errorf("anthropic stream error: %s: %s", event.Error.Type, event.Error.Message)
+			}
+			return fmt.Errorf("anthropic stream error: %s", data)
+		case "content_block_delta":
+			chunk := &global.Chunk{}
+			switch event.Delta.Type {
+			case "text_delta":
+				chunk.Content = event.Delta.Text
+			case "thinking_delta":
+				chunk.ReasoningContent = event.Delta.Thinking
+			}
+			// 签名、工具参数等增量不需要推送
+			if chunk.Content == "" && chunk.ReasoningContent == "" {
+				return nil
+			}
+			if err := callback(chunk); err != nil {
+				log.Error("callback error", zap.Error(err))
+				return fmt.Errorf("callback error: %v", err)
+			}
+		case "message_delta":
+			if event.Delta.StopReason == "refusal" {
+				return fmt.Errorf("anthropic refused to respond")
+			}
+		}
+		return nil
+	})
+}
+
+// buildRequest 把聊天配置转换为 Messages API 的请求
+// 系统消息合并到 system 字段，相邻的同角色消息合并为一条，保证用户和助手交替出现
+func buildRequest(conf *adaptercommon.ChatConfig) *messagesRequest {
+	model, enableThinking := adaptercommon.SplitThinkingModel(conf.Model)
+	req := &messagesRequest{
+		Model:     model,
+		MaxTokens: defaultMaxTokens,
+		Stream:    true,
+	}
+
+	var system []string
+	for _, msg := range conf.Message {
+		if msg.Role == global.System {
+			system = append(system, msg.Content)
+			continue
+		}
+		// 空的文本内容会被拒绝
+		if msg.Content == "" {
+			continue
+		}
+		role := global.User
+		if msg.Role == global.Assistant {
+			role = global.Assistant
+		}
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content += "\n\n" + msg.Content
+			continue
+		}
+		req.Messages = append(req.Messages, message{Role: role, Content: msg.Content})
+	}
+	req.System = strings.Join(system, "\n\n")
+
+	if conf.MaxTokens != nil {
+		req.MaxTokens = *conf.MaxTokens
+	}
+	if !enableThinking {
+		// temperature 的范围是 0 到 1，OpenAI 风格的 0 到 2 需要截断；temperature 和 top_p 不能同时设置，优先使用 temperature
+		if conf.Temperature != nil {
+			temperature := min(max(*conf.Temperature, 0), 1)
+			req.Temperature = &temperature
+		} else {
+			req.TopP = conf.TopP
+		}
+		req.TopK = conf.TopK
+		return req
+	}
+
+	// 开启思考时预算不少于 1024 且小于 max_tokens，不支持 temperature 和 top_k，top_p 只能在 0.95 到 1 之间
+	budget := req.MaxTokens / 2
+	if budget < minThinkingBudget {
+		budget = minThinkingBudget
+		req.MaxTokens += minThinkingBudget
+	}
+	req.Thinking = &thinking{Type: "enabled", BudgetTokens: budget}
+	if conf.TopP != nil && *conf.TopP >= 0.95 {
+		req.TopP = conf.TopP
+	}
+	return req
+}
+
+func NewChatClient(endpoint, apiKey string) *ChatClient {
+	return &ChatClient{
+		Endpoint:   endpoint,
+		ApiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+var _ adaptercommon.ChatRequester = (*ChatClient)(nil)
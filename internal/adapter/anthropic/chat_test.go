@@ -0,0 +1,129 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	adaptercommon "txing-ai/internal/adapter/common"
+	"txing-ai/internal/global"
+)
+
+// replayServer 返回录制的 SSE，并记录收到的请求
+func replayServer(t *testing.T, file string, received *messagesRequest) *httptest.Server {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" || r.Header.Get("x-api-key") != "sk-ant-test" || r.Header.Get("anthropic-version") != apiVersion {
+			t.Errorf("path = %s, headers = %v", r.URL.Path, r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write(data)
+	}))
+}
+
+func collect(client *ChatClient, conf *adaptercommon.ChatConfig) (content, reasoning string, err error) {
+	err = client.StreamChat(context.Background(), conf, func(chunk *global.Chunk) error {
+		content += chunk.Content
+		reasoning += chunk.ReasoningContent
+		return nil
+	})
+	return content, reasoning, err
+}
+
+func TestStreamChat(t *testing.T) {
+	var received messagesRequest
+	server := replayServer(t, "testdata/thinking.sse", &received)
+	defer server.Close()
+
+	temperature := float32(0.3)
+	content, reasoning, err := collect(NewChatClient(server.URL, "sk-ant-test"), &adaptercommon.ChatConfig{
+		Model: "claude-sonnet-4-5" + adaptercommon.ThinkingModelSuffix,
+		Message: []global.Message{
+			{Role: global.System, Content: "你是助手"},
+			{Role: global.User, Content: "你好"},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "你好！有什么可以帮你？" || reasoning != "用户在打招呼，简单回应即可。" {
+		t.Errorf("content = %q, reasoning = %q", content, reasoning)
+	}
+	if received.Model != "claude-sonnet-4-5" || received.System != "你是助手" || len(received.Messages) != 1 || !received.Stream {
+		t.Errorf("request = %+v", received)
+	}
+	if received.Thinking == nil || received.Thinking.BudgetTokens >= received.MaxTokens || received.Temperature != nil {
+		t.Errorf("thinking = %+v, max tokens = %d, temperature = %v", received.Thinking, received.MaxTokens, received.Temperature)
+	}
+}
+
+func TestStreamChatError(t *testing.T) {
+	var received messagesRequest
+	server := replayServer(t, "testdata/overloaded.sse", &received)
+	defer server.Close()
+
+	content, _, err := collect(NewChatClient(server.URL, "sk-ant-test"), &adaptercommon.ChatConfig{
+		Model:   "claude-sonnet-4-5",
+		Message: []global.Message{{Role: global.User, Content: "你好"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "overloaded_error") || content != "你好" {
+		t.Errorf("content = %q, err = %v", content, err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: Field required"}}`))
+	}))
+	defer failing.Close()
+	_, _, err = collect(NewChatClient(failing.URL, "sk-ant-test"), &adaptercommon.ChatConfig{Model: "claude-sonnet-4-5"})
+	if err == nil || !strings.Contains(err.Error(), "max_tokens: Field required") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	maxTokens, topK := 1000, 40
+	req := buildRequest(&adaptercommon.ChatConfig{
+		Model: "claude-sonnet-4-5",
+		Message: []global.Message{
+			{Role: global.System, Content: "规则一"},
+			{Role: global.User, Content: "问题"},
+			{Role: global.System, Content: "参考资料"},
+			{Role: global.User, Content: "补充"},
+			{Role: global.Assistant, Content: ""},
+		},
+		MaxTokens: &maxTokens,
+		TopK:      &topK,
+	})
+	if req.System != "规则一\n\n参考资料" || len(req.Messages) != 1 || req.Messages[0].Content != "问题\n\n补充" {
+		t.Errorf("buildRequest() = %+v", req)
+	}
+	if req.MaxTokens != 1000 || req.TopK == nil || req.Thinking != nil {
+		t.Errorf("buildRequest() params = %+v", req)
+	}
+
+	temperature, topP := float32(1.5), float32(0.9)
+	req = buildRequest(&adaptercommon.ChatConfig{Model: "claude-sonnet-4-5", Temperature: &temperature, TopP: &topP})
+	if req.Temperature == nil || *req.Temperature != 1 || req.TopP != nil {
+		t.Errorf("buildRequest() temperature = %v, top_p = %v", req.Temperature, req.TopP)
+	}
+	req = buildRequest(&adaptercommon.ChatConfig{Model: "claude-sonnet-4-5", TopP: &topP})
+	if req.Temperature != nil || req.TopP == nil || *req.TopP != topP {
+		t.Errorf("buildRequest() temperature = %v, top_p = %v", req.Temperature, req.TopP)
+	}
+
+	req = buildRequest(&adaptercommon.ChatConfig{Model: "claude-sonnet-4-5-thinking", MaxTokens: &maxTokens, TopK: &topK})
+	if req.Thinking == nil || req.Thinking.BudgetTokens != minThinkingBudget || req.MaxTokens != 2024 || req.TopK != nil {
+		t.Errorf("buildRequest() thinking = %+v, max tokens = %d", req.Thinking, req.MaxTokens)
+	}
+}
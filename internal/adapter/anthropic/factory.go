@@ -0,0 +1,36 @@
+package anthropic
+
+import (
+	"sync"
+	"txing-ai/internal/adapter/common"
+	"txing-ai/internal/iface"
+)
+
+// https://docs.anthropic.com/en/api/messages
+
+type AnthropicFactory struct {
+	mu sync.Mutex
+	// apiKey -> requester
+	requesterMap map[string]*ChatClient
+}
+
+func (v *AnthropicFactory) CreateChatRequester(conf iface.ChannelConfig) (adaptercommon.ChatRequester, error) {
+	// 判断 requester 是否已经存在，如果存在直接返回，否则新建一个
+	secret := conf.GetRandomSecret()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if requester, ok := v.requesterMap[secret]; ok {
+		return requester, nil
+	}
+	requester := NewChatClient(conf.GetEndpoint(), secret)
+	v.requesterMap[secret] = requester
+	return requester, nil
+}
+
+func NewAnthropicFactory() *AnthropicFactory {
+	return &AnthropicFactory{
+		requesterMap: make(map[string]*ChatClient),
+	}
+}
+
+var _ adaptercommon.ChatRequesterFactory = (*AnthropicFactory)(nil)
@@ -1,6 +1,10 @@
 package adaptercommon
 
-import "txing-ai/internal/global"
+import (
+	"strings"
+	"txing-ai/internal/global"
+	"txing-ai/internal/utils/jsonschema"
+)
 
 type ChatConfig struct {
 	Model     string           `json:"model"`
@@ -14,4 +18,18 @@ type ChatConfig struct {
 	PresencePenalty   *float32 `json:"presence_penalty,omitempty"`
 	FrequencyPenalty  *float32 `json:"frequency_penalty,omitempty"`
 	RepetitionPenalty *float32 `json:"repetition_penalty,omitempty"`
+	// 结构化输出：最终结果需要满足的 JSON Schema，支持的渠道会开启原生 JSON 模式
+	ResponseSchema *jsonschema.Schema `json:"response_schema,omitempty"`
+}
+
+// ThinkingModelSuffix 模型名称带有该后缀时开启思考，与 one-api 等网关的约定一致
+// 用于 Anthropic、Gemini 等需要在请求中显式开启思考的渠道，可以通过渠道的模型映射指定
+const ThinkingModelSuffix = "-thinking"
+
+// SplitThinkingModel 去掉模型名称的思考后缀，返回实际的模型名称和是否开启思考
+func SplitThinkingModel(model string) (string, bool) {
+	if name, ok := strings.CutSuffix(model, ThinkingModelSuffix); ok && name != "" {
+		return name, true
+	}
+	return model, false
 }
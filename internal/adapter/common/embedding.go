@@ -0,0 +1,42 @@
+package adaptercommon
+
+type EmbeddingConfig struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+	// 输出向量的维度，为 0 时使用模型的默认维度，只有部分模型支持
+	Dimensions int `json:"dimensions,omitempty"`
+}
+
+type EmbeddingResult struct {
+	Model string `json:"model"`
+	// 与 Input 一一对应的向量
+	Data  [][]float32 `json:"data"`
+	Usage Usage       `json:"usage"`
+}
+
+type RerankConfig struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	// 返回相关性最高的前 TopN 个文档，为 0 时返回全部
+	TopN int `json:"top_n,omitempty"`
+}
+
+type RerankResult struct {
+	Model string `json:"model"`
+	// 按相关性从高到低排列
+	Results []RerankItem `json:"results"`
+	Usage   Usage        `json:"usage"`
+}
+
+type RerankItem struct {
+	// 文档在 Documents 中的下标
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+}
+
+// 模型调用消耗的 token 数量
+type Usage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
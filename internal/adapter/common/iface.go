@@ -17,3 +17,27 @@ type ChatRequesterFactory interface {
 	// 创建聊天请求器
 	CreateChatRequester(conf iface.ChannelConfig) (ChatRequester, error)
 }
+
+// 向量化请求器
+type EmbeddingRequester interface {
+	// 把文本转换为向量
+	CreateEmbeddings(ctx context.Context, conf *EmbeddingConfig) (*EmbeddingResult, error)
+}
+
+// 向量化请求器工厂，支持向量化模型的渠道类型的工厂实现这个接口
+type EmbeddingRequesterFactory interface {
+	// 创建向量化请求器
+	CreateEmbeddingRequester(conf iface.ChannelConfig) (EmbeddingRequester, error)
+}
+
+// 重排序请求器
+type Reranker interface {
+	// 按与查询的相关性给文档打分
+	Rerank(ctx context.Context, conf *RerankConfig) (*RerankResult, error)
+}
+
+// 重排序请求器工厂，支持重排序模型的渠道类型的工厂实现这个接口
+type RerankerFactory interface {
+	// 创建重排序请求器
+	CreateReranker(conf iface.ChannelConfig) (Reranker, error)
+}
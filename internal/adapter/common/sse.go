@@ -0,0 +1,54 @@
+package adaptercommon
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+)
+
+// 单行 SSE 数据的最大长度
+const maxSSELineSize = 4 << 20
+
+// ReadSSE 逐条读取 SSE 事件，同一事件的多行 data 按换行拼接，handle 返回错误或 ctx 取消时停止读取
+func ReadSSE(ctx context.Context, r io.Reader, handle func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+
+	event := ""
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := handle(event, strings.Join(data, "\n"))
+		event, data = "", data[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			// 空行表示一个事件结束
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// 注释，用于保持连接
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	// 最后一个事件后面可能没有空行
+	return dispatch()
+}
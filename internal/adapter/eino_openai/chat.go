@@ -49,6 +49,12 @@ func (c ChatClient) StreamChat(ctx context.Context, conf *adaptercommon.ChatConf
 	if conf.FrequencyPenalty != nil {
 		chatModelConfig.FrequencyPenalty = conf.FrequencyPenalty
 	}
+	// 结构化输出，使用 JSON 模式（schema 通过提示词约束，结果由上层校验）
+	if conf.ResponseSchema != nil {
+		chatModelConfig.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		}
+	}
 
 	chatModel, err := openai.NewChatModel(ctx, chatModelConfig)
 	if err != nil {
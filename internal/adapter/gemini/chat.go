@@ -0,0 +1,222 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	adaptercommon "txing-ai/internal/adapter/common"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+
+	"go.uber.org/zap"
+)
+
+// https://ai.google.dev/api/generate-content#method:-models.streamgeneratecontent
+
+// 没有配置服务地址时使用 Gemini API 官方地址
+const defaultEndpoint = "https://generativelanguage.googleapis.com/v1beta"
+
+// Gemini 中助手的角色名称
+const roleModel = "model"
+
+// 因安全策略等原因中止生成的结束原因，中止时返回错误
+var blockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+}
+
+type ChatClient struct {
+	Endpoint   string
+	ApiKey     string
+	httpClient *http.Client
+}
+
+type part struct {
+	Text    string `json:"text"`
+	Thought bool   `json:"thought,omitempty"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type thinkingConfig struct {
+	IncludeThoughts bool `json:"includeThoughts"`
+}
+
+type generationConfig struct {
+	MaxOutputTokens  *int            `json:"maxOutputTokens,omitempty"`
+	Temperature      *float32        `json:"temperature,omitempty"`
+	TopP             *float32        `json:"topP,omitempty"`
+	TopK             *int            `json:"topK,omitempty"`
+	PresencePenalty  *float32        `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float32        `json:"frequencyPenalty,omitempty"`
+	ResponseMimeType string          `json:"responseMimeType,omitempty"`
+	ThinkingConfig   *thinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type generateRequest struct {
+	Contents          []content         `json:"contents"`
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+}
+
+// generateResponse 流式响应的一个消息块，只解析用到的字段
+type generateResponse struct {
+	Candidates []struct {
+		Content      content `json:"content"`
+		FinishReason string  `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	Error *apiError `json:"error"`
+}
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func (c ChatClient) StreamChat(ctx context.Context, conf *adaptercommon.ChatConfig, callback global.Hook) error {
+	model, body := buildRequest(conf)
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request body failed: %v", err)
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	requestUrl := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", strings.TrimRight(endpoint, "/"), url.PathEscape(model))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestUrl, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request failed: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.ApiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var result generateResponse
+		if json.Unmarshal(data, &result) == nil && result.Error != nil {
+			return fmt.Errorf("gemini request failed with status %d: %s: %s", resp.StatusCode, result.Error.Status, result.Error.Message)
+		}
+		return fmt.Errorf("gemini request failed with status %d: %s", resp.StatusCode, string(data))
+	}
+
+	return adaptercommon.ReadSSE(ctx, resp.Body, func(_, data string) error {
+		var result generateResponse
+		if err := json.Unmarshal([]byte(data), &result); err != nil {
+			return fmt.Errorf("invalid stream event: %v", err)
+		}
+		if result.Error != nil {
+			return fmt.Errorf("gemini stream error: %s: %s", result.Error.Status, result.Error.Message)
+		}
+		if result.PromptFeedback != nil && result.PromptFeedback.BlockReason != "" {
+			return fmt.Errorf("gemini blocked the prompt: %s", result.PromptFeedback.BlockReason)
+		}
+		if len(result.Candidates) == 0 {
+			return nil
+		}
+
+		candidate := result.Candidates[0]
+		chunk := &global.Chunk{}
+		for _, p := range candidate.Content.Parts {
+			if p.Thought {
+				chunk.ReasoningContent += p.Text
+			} else {
+				chunk.Content += p.Text
+			}
+		}
+		if chunk.Content != "" || chunk.ReasoningContent != "" {
+			if err := callback(chunk); err != nil {
+				log.Error("callback error", zap.Error(err))
+				return fmt.Errorf("callback error: %v", err)
+			}
+		}
+		if blockedFinishReasons[candidate.FinishReason] {
+			return fmt.Errorf("gemini stopped generating: %s", candidate.FinishReason)
+		}
+		return nil
+	})
+}
+
+// buildRequest 把聊天配置转换为 generateContent 的请求，返回实际的模型名称和请求体
+// 系统消息合并到 systemInstruction，相邻的同角色消息合并为一条
+func buildRequest(conf *adaptercommon.ChatConfig) (string, *generateRequest) {
+	model, enableThinking := adaptercommon.SplitThinkingModel(conf.Model)
+	req := &generateRequest{}
+
+	var system []part
+	for _, msg := range conf.Message {
+		if msg.Content == "" {
+			continue
+		}
+		if msg.Role == global.System {
+			system = append(system, part{Text: msg.Content})
+			continue
+		}
+		role := global.User
+		if msg.Role == global.Assistant {
+			role = roleModel
+		}
+		if n := len(req.Contents); n > 0 && req.Contents[n-1].Role == role {
+			req.Contents[n-1].Parts = append(req.Contents[n-1].Parts, part{Text: msg.Content})
+			continue
+		}
+		req.Contents = append(req.Contents, content{Role: role, Parts: []part{{Text: msg.Content}}})
+	}
+	if len(system) > 0 {
+		req.SystemInstruction = &content{Parts: system}
+	}
+
+	config := &generationConfig{
+		MaxOutputTokens:  conf.MaxTokens,
+		Temperature:      conf.Temperature,
+		TopP:             conf.TopP,
+		TopK:             conf.TopK,
+		PresencePenalty:  conf.PresencePenalty,
+		FrequencyPenalty: conf.FrequencyPenalty,
+	}
+	// 结构化输出，使用 JSON 模式（schema 通过提示词约束，结果由上层校验）
+	if conf.ResponseSchema != nil {
+		config.ResponseMimeType = "application/json"
+	}
+	// 返回思考过程的摘要
+	if enableThinking {
+		config.ThinkingConfig = &thinkingConfig{IncludeThoughts: true}
+	}
+	if *config != (generationConfig{}) {
+		req.GenerationConfig = config
+	}
+	return model, req
+}
+
+func NewChatClient(endpoint, apiKey string) *ChatClient {
+	return &ChatClient{
+		Endpoint:   endpoint,
+		ApiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+var _ adaptercommon.ChatRequester = (*ChatClient)(nil)
@@ -0,0 +1,101 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	adaptercommon "txing-ai/internal/adapter/common"
+	"txing-ai/internal/global"
+)
+
+// replayServer 返回录制的 SSE，并记录收到的请求
+func replayServer(t *testing.T, file string, received *generateRequest) *httptest.Server {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" || r.Header.Get("x-goog-api-key") != "AIza-test" {
+			t.Errorf("url = %s, headers = %v", r.URL, r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write(data)
+	}))
+}
+
+func collect(client *ChatClient, conf *adaptercommon.ChatConfig) (content, reasoning string, err error) {
+	err = client.StreamChat(context.Background(), conf, func(chunk *global.Chunk) error {
+		content += chunk.Content
+		reasoning += chunk.ReasoningContent
+		return nil
+	})
+	return content, reasoning, err
+}
+
+func TestStreamChat(t *testing.T) {
+	var received generateRequest
+	server := replayServer(t, "testdata/thinking.sse", &received)
+	defer server.Close()
+
+	temperature := float32(0.5)
+	content, reasoning, err := collect(NewChatClient(server.URL, "AIza-test"), &adaptercommon.ChatConfig{
+		Model: "gemini-2.5-flash" + adaptercommon.ThinkingModelSuffix,
+		Message: []global.Message{
+			{Role: global.System, Content: "你是助手"},
+			{Role: global.User, Content: "你好"},
+			{Role: global.Assistant, Content: "你好！"},
+			{Role: global.User, Content: "再说一遍"},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "你好！有什么可以帮你？" || reasoning != "**Greeting the user**\n\n用户在打招呼。" {
+		t.Errorf("content = %q, reasoning = %q", content, reasoning)
+	}
+	if received.SystemInstruction == nil || received.SystemInstruction.Parts[0].Text != "你是助手" {
+		t.Errorf("system instruction = %+v", received.SystemInstruction)
+	}
+	if len(received.Contents) != 3 || received.Contents[1].Role != roleModel {
+		t.Errorf("contents = %+v", received.Contents)
+	}
+	config := received.GenerationConfig
+	if config == nil || config.Temperature == nil || *config.Temperature != 0.5 || config.ThinkingConfig == nil || !config.ThinkingConfig.IncludeThoughts {
+		t.Errorf("generation config = %+v", config)
+	}
+}
+
+func TestStreamChatError(t *testing.T) {
+	var received generateRequest
+	server := replayServer(t, "testdata/safety.sse", &received)
+	defer server.Close()
+
+	content, _, err := collect(NewChatClient(server.URL, "AIza-test"), &adaptercommon.ChatConfig{
+		Model:   "gemini-2.5-flash",
+		Message: []global.Message{{Role: global.User, Content: "你好"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "SAFETY") || content != "这个问题" {
+		t.Errorf("content = %q, err = %v", content, err)
+	}
+	if received.GenerationConfig != nil {
+		t.Errorf("generation config = %+v, want nil", received.GenerationConfig)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT"}}`))
+	}))
+	defer failing.Close()
+	_, _, err = collect(NewChatClient(failing.URL, "AIza-test"), &adaptercommon.ChatConfig{Model: "gemini-2.5-flash"})
+	if err == nil || !strings.Contains(err.Error(), "INVALID_ARGUMENT") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"time"
 	adaptercommon "txing-ai/internal/adapter/common"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
@@ -13,9 +15,10 @@ import (
 )
 
 type ChatClient struct {
-	Endpoint string
-	ApiKey   string
-	client   *openai.Client
+	Endpoint   string
+	ApiKey     string
+	client     *openai.Client
+	httpClient *http.Client
 }
 
 func (c ChatClient) StreamChat(ctx context.Context, conf *adaptercommon.ChatConfig, callback global.Hook) error {
@@ -51,6 +54,16 @@ func (c ChatClient) StreamChat(ctx context.Context, conf *adaptercommon.ChatConf
 	if conf.FrequencyPenalty != nil {
 		req.FrequencyPenalty = *conf.FrequencyPenalty
 	}
+	// 结构化输出，使用原生 JSON Schema 模式
+	if conf.ResponseSchema != nil {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "response",
+				Schema: conf.ResponseSchema,
+			},
+		}
+	}
 
 	// 创建流式请求
 	stream, err := c.client.CreateChatCompletionStream(ctx, req)
@@ -101,9 +114,10 @@ func NewChatClient(endpoint, apiKey string) *ChatClient {
 	}
 	client := openai.NewClientWithConfig(config)
 	return &ChatClient{
-		Endpoint: endpoint,
-		ApiKey:   apiKey,
-		client:   client,
+		Endpoint:   endpoint,
+		ApiKey:     apiKey,
+		client:     client,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
 	}
 }
 
@@ -0,0 +1,138 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	adaptercommon "txing-ai/internal/adapter/common"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// 没有配置服务地址时使用 OpenAI 官方地址
+const defaultEndpoint = "https://api.openai.com/v1"
+
+func (c ChatClient) CreateEmbeddings(ctx context.Context, conf *adaptercommon.EmbeddingConfig) (*adaptercommon.EmbeddingResult, error) {
+	resp, err := c.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input:      conf.Input,
+		Model:      openai.EmbeddingModel(conf.Model),
+		Dimensions: conf.Dimensions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create embeddings error: %w", err)
+	}
+	if len(resp.Data) != len(conf.Input) {
+		return nil, fmt.Errorf("create embeddings error: got %d embeddings for %d inputs", len(resp.Data), len(conf.Input))
+	}
+
+	// 按 index 排列，保证与输入一一对应
+	sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+	data := make([][]float32, len(resp.Data))
+	for i, item := range resp.Data {
+		if item.Index != i {
+			return nil, fmt.Errorf("create embeddings error: missing embedding for input %d", i)
+		}
+		data[i] = item.Embedding
+	}
+	return &adaptercommon.EmbeddingResult{
+		Model: string(resp.Model),
+		Data:  data,
+		Usage: adaptercommon.Usage{
+			PromptTokens: resp.Usage.PromptTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+// rerankRequest /rerank 接口的请求，OpenAI 没有重排序接口，这里使用 Jina、Cohere、vLLM 等服务通用的格式
+type rerankRequest struct {
+	Model           string   `json:"model"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n,omitempty"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+type rerankResponse struct {
+	Model   string `json:"model"`
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func (c ChatClient) Rerank(ctx context.Context, conf *adaptercommon.RerankConfig) (*adaptercommon.RerankResult, error) {
+	body, err := json.Marshal(rerankRequest{
+		Model:     conf.Model,
+		Query:     conf.Query,
+		Documents: conf.Documents,
+		TopN:      conf.TopN,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/rerank", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.ApiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rerank error: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, fmt.Errorf("rerank error: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank error: status %d: %s", resp.StatusCode, truncate(string(data), 500))
+	}
+
+	var result rerankResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("rerank error: invalid response: %w", err)
+	}
+	items := make([]adaptercommon.RerankItem, 0, len(result.Results))
+	for _, item := range result.Results {
+		if item.Index < 0 || item.Index >= len(conf.Documents) {
+			return nil, fmt.Errorf("rerank error: document index %d out of range", item.Index)
+		}
+		items = append(items, adaptercommon.RerankItem{Index: item.Index, RelevanceScore: item.RelevanceScore})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].RelevanceScore > items[j].RelevanceScore })
+	if conf.TopN > 0 && len(items) > conf.TopN {
+		items = items[:conf.TopN]
+	}
+	usage := adaptercommon.Usage{PromptTokens: result.Usage.PromptTokens, TotalTokens: result.Usage.TotalTokens}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens
+	}
+	return &adaptercommon.RerankResult{Model: result.Model, Results: items, Usage: usage}, nil
+}
+
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+var _ adaptercommon.EmbeddingRequester = (*ChatClient)(nil)
+var _ adaptercommon.Reranker = (*ChatClient)(nil)
@@ -0,0 +1,76 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	adaptercommon "txing-ai/internal/adapter/common"
+)
+
+func TestCreateEmbeddings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		// 故意打乱顺序，客户端需要按 index 还原
+		_, _ = w.Write([]byte(`{"object":"list","model":"bge-m3","data":[
+			{"object":"embedding","index":1,"embedding":[0.3,0.4]},
+			{"object":"embedding","index":0,"embedding":[0.1,0.2]}
+		],"usage":{"prompt_tokens":6,"total_tokens":6}}`))
+	}))
+	defer server.Close()
+
+	client := NewChatClient(server.URL, "sk-test")
+	result, err := client.CreateEmbeddings(context.Background(), &adaptercommon.EmbeddingConfig{Model: "bge-m3", Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Data) != 2 || result.Data[0][0] != 0.1 || result.Data[1][0] != 0.3 {
+		t.Errorf("CreateEmbeddings() data = %v", result.Data)
+	}
+	if result.Usage.TotalTokens != 6 {
+		t.Errorf("CreateEmbeddings() usage = %+v", result.Usage)
+	}
+}
+
+func TestRerank(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("path = %s, authorization = %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req rerankRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query != "q" || len(req.Documents) != 3 {
+			t.Errorf("request = %+v, err = %v", req, err)
+		}
+		_, _ = w.Write([]byte(`{"model":"bge-reranker","results":[
+			{"index":0,"relevance_score":0.1},
+			{"index":2,"relevance_score":0.9},
+			{"index":1,"relevance_score":0.5}
+		],"usage":{"total_tokens":12}}`))
+	}))
+	defer server.Close()
+
+	client := NewChatClient(server.URL, "sk-test")
+	result, err := client.Rerank(context.Background(), &adaptercommon.RerankConfig{
+		Model: "bge-reranker", Query: "q", Documents: []string{"a", "b", "c"}, TopN: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) != 2 || result.Results[0].Index != 2 || result.Results[1].Index != 1 {
+		t.Errorf("Rerank() results = %+v", result.Results)
+	}
+	if result.Usage.TotalTokens != 12 {
+		t.Errorf("Rerank() usage = %+v", result.Usage)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+	}))
+	defer failing.Close()
+	if _, err := NewChatClient(failing.URL, "sk-test").Rerank(context.Background(), &adaptercommon.RerankConfig{Query: "q", Documents: []string{"a"}}); err == nil {
+		t.Error("Rerank() 应该返回错误")
+	}
+}
@@ -1,6 +1,7 @@
 package openai
 
 import (
+	"sync"
 	"txing-ai/internal/adapter/common"
 	"txing-ai/internal/iface"
 )
@@ -8,26 +9,43 @@ import (
 // https://code.poloapi.com/
 
 type OpenaiFactory struct {
+	mu sync.Mutex
 	// apiKey -> requester
-	requesterMap map[string]adaptercommon.ChatRequester
+	requesterMap map[string]*ChatClient
 }
 
-func (v OpenaiFactory) CreateChatRequester(conf iface.ChannelConfig) (adaptercommon.ChatRequester, error) {
+// 同一个密钥的聊天、向量化和重排序请求共用一个客户端
+func (v *OpenaiFactory) client(conf iface.ChannelConfig) *ChatClient {
 	// 判断 requester 是否已经存在，如果存在直接返回，否则新建一个
-	// TODO: 保证并发安全
 	secret := conf.GetRandomSecret()
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	if requester, ok := v.requesterMap[secret]; ok {
-		return requester, nil
+		return requester
 	}
 	requester := NewChatClient(conf.GetEndpoint(), secret)
 	v.requesterMap[secret] = requester
-	return requester, nil
+	return requester
+}
+
+func (v *OpenaiFactory) CreateChatRequester(conf iface.ChannelConfig) (adaptercommon.ChatRequester, error) {
+	return v.client(conf), nil
+}
+
+func (v *OpenaiFactory) CreateEmbeddingRequester(conf iface.ChannelConfig) (adaptercommon.EmbeddingRequester, error) {
+	return v.client(conf), nil
+}
+
+func (v *OpenaiFactory) CreateReranker(conf iface.ChannelConfig) (adaptercommon.Reranker, error) {
+	return v.client(conf), nil
 }
 
 func NewOpenaiFactory() *OpenaiFactory {
 	return &OpenaiFactory{
-		requesterMap: make(map[string]adaptercommon.ChatRequester),
+		requesterMap: make(map[string]*ChatClient),
 	}
 }
 
 var _ adaptercommon.ChatRequesterFactory = (*OpenaiFactory)(nil)
+var _ adaptercommon.EmbeddingRequesterFactory = (*OpenaiFactory)(nil)
+var _ adaptercommon.RerankerFactory = (*OpenaiFactory)(nil)
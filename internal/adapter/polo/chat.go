@@ -52,6 +52,10 @@ func (c ChatClient) StreamChat(ctx context.Context, conf *adaptercommon.ChatConf
 	if conf.RepetitionPenalty != nil {
 		payload["repetition_penalty"] = *conf.RepetitionPenalty
 	}
+	// 结构化输出，使用 JSON 模式（schema 通过提示词约束，结果由上层校验）
+	if conf.ResponseSchema != nil {
+		payload["response_format"] = map[string]string{"type": "json_object"}
+	}
 
 	// 将请求体转换为 JSON
 	jsonData, err := json.Marshal(payload)
@@ -2,15 +2,85 @@ package adapter
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
 	adaptercommon "txing-ai/internal/adapter/common"
 	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
 	"txing-ai/internal/iface"
+	"txing-ai/internal/utils/jsonschema"
 )
 
 func NewChatRequest(ctx context.Context, channelConfig iface.ChannelConfig, chatConfig *adaptercommon.ChatConfig, hook global.Hook) error {
 
 	// TODO 实现限流和重试等机制
 
+	if chatConfig.ResponseSchema != nil {
+		return createStructuredChatRequest(ctx, channelConfig, chatConfig, hook)
+	}
+
 	return createChatRequest(ctx, channelConfig, chatConfig, hook)
 
 }
+
+// 向量化请求，渠道类型的工厂需要实现 EmbeddingRequesterFactory
+func NewEmbeddingRequest(ctx context.Context, channelConfig iface.ChannelConfig, conf *adaptercommon.EmbeddingConfig) (*adaptercommon.EmbeddingResult, error) {
+	return createEmbeddings(ctx, channelConfig, conf)
+}
+
+// 重排序请求，渠道类型的工厂需要实现 RerankerFactory
+func NewRerankRequest(ctx context.Context, channelConfig iface.ChannelConfig, conf *adaptercommon.RerankConfig) (*adaptercommon.RerankResult, error) {
+	return createRerank(ctx, channelConfig, conf)
+}
+
+// 结构化输出请求：缓存模型输出的内容，按 schema 校验，不通过时带上错误信息要求模型修正，
+// 超过最大修正次数仍不通过则返回错误。思考过程依旧实时推送，正文在校验通过后一次性推送
+func createStructuredChatRequest(ctx context.Context, channelConfig iface.ChannelConfig, chatConfig *adaptercommon.ChatConfig, hook global.Hook) error {
+	schema := chatConfig.ResponseSchema
+
+	// 复制一份消息，避免修改调用方的会话记录
+	conf := *chatConfig
+	conf.Message = make([]global.Message, 0, len(chatConfig.Message)+1)
+	conf.Message = append(conf.Message, global.Message{
+		Role:    global.System,
+		Content: schema.Instruction(),
+	})
+	conf.Message = append(conf.Message, chatConfig.Message...)
+
+	maxAttempts := jsonschema.MaxRepairAttempts()
+	for attempt := 0; ; attempt++ {
+		content := ""
+		err := createChatRequest(ctx, channelConfig, &conf, func(chunk *global.Chunk) error {
+			content += chunk.Content
+			if chunk.ReasoningContent == "" {
+				return nil
+			}
+			return hook(&global.Chunk{ReasoningContent: chunk.ReasoningContent})
+		})
+		if err != nil {
+			return err
+		}
+
+		value, err := schema.ParseOutput(content)
+		if err == nil {
+			normalized, _ := json.Marshal(value)
+			return hook(&global.Chunk{
+				Content: string(normalized),
+				Event:   global.EventStructuredOutput,
+				Data:    value,
+			})
+		}
+
+		log.Info("structured output validation failed",
+			zap.Int("attempt", attempt), zap.String("content", content), zap.Error(err))
+		if attempt >= maxAttempts {
+			return fmt.Errorf("structured output still invalid after %d repair attempts: %w", maxAttempts, err)
+		}
+
+		conf.Message = append(conf.Message,
+			global.Message{Role: global.Assistant, Content: content},
+			global.Message{Role: global.User, Content: schema.RepairInstruction(err)},
+		)
+	}
+}
@@ -70,6 +70,13 @@ func (c ChatClient) StreamChat(ctx context.Context, conf *adaptercommon.ChatConf
 	if conf.Temperature != nil {
 		req.Temperature = *conf.Temperature
 	}
+	// 结构化输出，使用 JSON 模式
+	if conf.ResponseSchema != nil {
+		req.ResponseFormat = &model.ResponseFormat{
+			Type:   model.ResponseFormatJsonObject,
+			Schema: conf.ResponseSchema,
+		}
+	}
 
 	stream, err := c.client.CreateBotChatCompletionStream(ctx, req)
 	if err != nil {
@@ -0,0 +1,45 @@
+package volcengine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	adaptercommon "txing-ai/internal/adapter/common"
+
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+)
+
+// CreateEmbeddings 调用方舟的文本向量化接口，Model 为向量化模型的推理接入点 ID 或模型名称
+func (c ChatClient) CreateEmbeddings(ctx context.Context, conf *adaptercommon.EmbeddingConfig) (*adaptercommon.EmbeddingResult, error) {
+	resp, err := c.client.CreateEmbeddings(ctx, model.EmbeddingRequestStrings{
+		Input:      conf.Input,
+		Model:      conf.Model,
+		Dimensions: conf.Dimensions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create embeddings error: %w", err)
+	}
+	if len(resp.Data) != len(conf.Input) {
+		return nil, fmt.Errorf("create embeddings error: got %d embeddings for %d inputs", len(resp.Data), len(conf.Input))
+	}
+
+	// 按 index 排列，保证与输入一一对应
+	sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+	data := make([][]float32, len(resp.Data))
+	for i, item := range resp.Data {
+		if item.Index != i {
+			return nil, fmt.Errorf("create embeddings error: missing embedding for input %d", i)
+		}
+		data[i] = item.Embedding
+	}
+	return &adaptercommon.EmbeddingResult{
+		Model: resp.Model,
+		Data:  data,
+		Usage: adaptercommon.Usage{
+			PromptTokens: resp.Usage.PromptTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+var _ adaptercommon.EmbeddingRequester = (*ChatClient)(nil)
@@ -1,31 +1,44 @@
 package volcengine
 
 import (
+	"sync"
 	"txing-ai/internal/adapter/common"
 	"txing-ai/internal/iface"
 )
 
 type VoclEngineFactory struct {
+	mu sync.Mutex
 	// apiKey -> requester
-	requesterMap map[string]adaptercommon.ChatRequester
+	requesterMap map[string]*ChatClient
 }
 
-func (v VoclEngineFactory) CreateChatRequester(conf iface.ChannelConfig) (adaptercommon.ChatRequester, error) {
+// 同一个密钥的聊天和向量化请求共用一个客户端
+func (v *VoclEngineFactory) client(conf iface.ChannelConfig) *ChatClient {
 	// 判断 requester 是否已经存在，如果存在直接返回，否则新建一个
-	// TODO: 保证并发安全
 	secret := conf.GetRandomSecret()
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	if requester, ok := v.requesterMap[secret]; ok {
-		return requester, nil
+		return requester
 	}
 	requester := NewChatClient(conf.GetEndpoint(), secret)
 	v.requesterMap[secret] = requester
-	return requester, nil
+	return requester
+}
+
+func (v *VoclEngineFactory) CreateChatRequester(conf iface.ChannelConfig) (adaptercommon.ChatRequester, error) {
+	return v.client(conf), nil
+}
+
+func (v *VoclEngineFactory) CreateEmbeddingRequester(conf iface.ChannelConfig) (adaptercommon.EmbeddingRequester, error) {
+	return v.client(conf), nil
 }
 
 func NewVoclEngineFactory() *VoclEngineFactory {
 	return &VoclEngineFactory{
-		requesterMap: make(map[string]adaptercommon.ChatRequester),
+		requesterMap: make(map[string]*ChatClient),
 	}
 }
 
 var _ adaptercommon.ChatRequesterFactory = (*VoclEngineFactory)(nil)
+var _ adaptercommon.EmbeddingRequesterFactory = (*VoclEngineFactory)(nil)
@@ -0,0 +1,340 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"go.uber.org/zap"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/utils"
+)
+
+// ToolPolicy 工具调用策略
+type ToolPolicy string
+
+const (
+	// ToolPolicyAuto 自动执行
+	ToolPolicyAuto ToolPolicy = "auto"
+	// ToolPolicyAsk 执行前需要用户审批
+	ToolPolicyAsk ToolPolicy = "ask"
+	// ToolPolicyDeny 禁止调用
+	ToolPolicyDeny ToolPolicy = "deny"
+)
+
+// ApprovalAction 用户审批操作
+type ApprovalAction string
+
+const (
+	ApprovalActionApprove ApprovalAction = "approve"
+	ApprovalActionEdit    ApprovalAction = "edit"
+	ApprovalActionReject  ApprovalAction = "reject"
+)
+
+const (
+	// EventApprovalRequest 工具调用审批请求事件
+	EventApprovalRequest = "approval_request"
+	// EventApprovalResult 工具调用审批结果事件
+	EventApprovalResult = "approval_result"
+
+	// 默认审批等待超时时间
+	defaultApprovalTimeout = 5 * time.Minute
+)
+
+// ApprovalRequest 推送给前端的审批请求
+type ApprovalRequest struct {
+	ApprovalId string                 `json:"approvalId"`
+	ToolCallId string                 `json:"toolCallId"`
+	ToolName   string                 `json:"toolName"`
+	Arguments  map[string]interface{} `json:"arguments"`
+	ExpireAt   int64                  `json:"expireAt"`
+}
+
+// ApprovalDecision 用户的审批决定
+type ApprovalDecision struct {
+	Action ApprovalAction
+	// 修改后的工具调用参数（JSON），仅 edit 时有效
+	Arguments string
+	// 拒绝原因，会反馈给模型
+	Reason string
+}
+
+type pendingApproval struct {
+	userId   int64
+	toolName string
+	decision chan *ApprovalDecision
+}
+
+// ApprovalManager 管理所有等待用户审批的工具调用
+type ApprovalManager struct {
+	mu      sync.Mutex
+	pending map[string]*pendingApproval
+	// 每次读取最新的配置，配置文件修改后策略立即生效
+	config func() *global.AgentConfig
+}
+
+// NewApprovalManager 创建审批管理器
+func NewApprovalManager() *ApprovalManager {
+	return newApprovalManager(func() *global.AgentConfig {
+		return global.LoadConfig().AgentConfig
+	})
+}
+
+func newApprovalManager(config func() *global.AgentConfig) *ApprovalManager {
+	return &ApprovalManager{
+		pending: make(map[string]*pendingApproval),
+		config:  config,
+	}
+}
+
+// Policy 获取指定工具的调用策略，优先精确匹配，其次匹配最具体的通配符，最后使用默认策略
+func (m *ApprovalManager) Policy(toolName string) ToolPolicy {
+	agentConfig := m.config()
+	if agentConfig == nil {
+		return ToolPolicyAuto
+	}
+
+	if _, policy, ok := matchToolConfig(agentConfig.ToolPolicies, toolName); ok {
+		return parseToolPolicy(policy)
+	}
+	return parseToolPolicy(agentConfig.DefaultToolPolicy)
+}
+
+func (m *ApprovalManager) timeout() time.Duration {
+	agentConfig := m.config()
+	if agentConfig == nil || agentConfig.ApprovalTimeout <= 0 {
+		return defaultApprovalTimeout
+	}
+	return agentConfig.ApprovalTimeout * time.Second
+}
+
+// matchToolConfig 按工具名称查找配置，优先精确匹配，多个通配符都匹配时使用最具体的一个
+// 最具体指去掉通配符后字符最多，相同时按字典序，保证结果不受 map 遍历顺序影响
+func matchToolConfig[T any](configs map[string]T, toolName string) (string, T, bool) {
+	if conf, ok := configs[toolName]; ok {
+		return toolName, conf, true
+	}
+
+	best, found := "", false
+	for pattern := range configs {
+		if matched, _ := path.Match(pattern, toolName); !matched {
+			continue
+		}
+		if !found || moreSpecific(pattern, best) {
+			best, found = pattern, true
+		}
+	}
+	if !found {
+		var zero T
+		return "", zero, false
+	}
+	return best, configs[best], true
+}
+
+func moreSpecific(a, b string) bool {
+	literalA := len(a) - strings.Count(a, "*") - strings.Count(a, "?")
+	literalB := len(b) - strings.Count(b, "*") - strings.Count(b, "?")
+	if literalA != literalB {
+		return literalA > literalB
+	}
+	return a < b
+}
+
+func parseToolPolicy(policy string) ToolPolicy {
+	switch ToolPolicy(policy) {
+	case ToolPolicyAsk, ToolPolicyDeny:
+		return ToolPolicy(policy)
+	default:
+		return ToolPolicyAuto
+	}
+}
+
+// register 登记一个待审批的工具调用
+func (m *ApprovalManager) register(userId int64, toolName string) (string, *pendingApproval) {
+	approvalId := fmt.Sprintf("%d_%s", userId, utils.GenerateUniqueID()+utils.RandomNumber(4))
+	p := &pendingApproval{
+		userId:   userId,
+		toolName: toolName,
+		decision: make(chan *ApprovalDecision, 1),
+	}
+
+	m.mu.Lock()
+	m.pending[approvalId] = p
+	m.mu.Unlock()
+	return approvalId, p
+}
+
+func (m *ApprovalManager) remove(approvalId string) {
+	m.mu.Lock()
+	delete(m.pending, approvalId)
+	m.mu.Unlock()
+}
+
+// Resolve 提交用户的审批决定
+func (m *ApprovalManager) Resolve(approvalId string, userId int64, decision *ApprovalDecision) error {
+	if decision.Action == ApprovalActionEdit {
+		var jsonObj map[string]interface{}
+		if err := json.Unmarshal([]byte(decision.Arguments), &jsonObj); err != nil {
+			return fmt.Errorf("修改后的参数不是合法的JSON: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	p, ok := m.pending[approvalId]
+	if ok && p.userId == userId {
+		delete(m.pending, approvalId)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return ErrApprovalNotFound
+	}
+	if p.userId != userId {
+		return ErrApprovalForbidden
+	}
+
+	p.decision <- decision
+	return nil
+}
+
+type noApprovalKey struct{}
+
+// withoutApproval 标记本次运行无法等待用户审批（例如非流式调用没有推送审批请求的通道），需要审批的工具直接拒绝执行
+func withoutApproval(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noApprovalKey{}, true)
+}
+
+func approvalDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noApprovalKey{}).(bool)
+	return disabled
+}
+
+// approvalTool 包装工具，在执行前根据策略进行拦截或等待用户审批
+type approvalTool struct {
+	tool.InvokableTool
+	name      string
+	policy    ToolPolicy
+	approvals *ApprovalManager
+	callback  func(chunk *global.Chunk) error
+}
+
+// wrapToolsWithApproval 根据工具策略包装工具，auto 策略的工具保持不变
+func wrapToolsWithApproval(ctx context.Context, tools []tool.BaseTool, approvals *ApprovalManager,
+	callback func(chunk *global.Chunk) error) []tool.BaseTool {
+	if approvals == nil {
+		return tools
+	}
+
+	wrapped := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			wrapped = append(wrapped, t)
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			log.Error("Failed to get tool info", zap.Error(err))
+			wrapped = append(wrapped, t)
+			continue
+		}
+		policy := approvals.Policy(info.Name)
+		if policy == ToolPolicyAuto {
+			wrapped = append(wrapped, t)
+			continue
+		}
+		wrapped = append(wrapped, &approvalTool{
+			InvokableTool: invokable,
+			name:          info.Name,
+			policy:        policy,
+			approvals:     approvals,
+			callback:      callback,
+		})
+	}
+	return wrapped
+}
+
+func (t *approvalTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if t.policy == ToolPolicyDeny {
+		log.Info("tool call denied by policy", zap.String("tool", t.name))
+		return fmt.Sprintf("工具 %s 已被系统禁止调用，请不要再调用该工具", t.name), nil
+	}
+	if approvalDisabled(ctx) {
+		log.Info("tool call requires approval but approval is unavailable", zap.String("tool", t.name))
+		return fmt.Sprintf("工具 %s 需要用户确认后才能执行，当前调用方式无法确认，工具未执行，请不要再调用该工具", t.name), nil
+	}
+
+	userId, _ := utils.GetUIDFromContextAllowEmpty(ctx)
+	approvalId, p := t.approvals.register(userId, t.name)
+	defer t.approvals.remove(approvalId)
+
+	timeout := t.approvals.timeout()
+	var arguments map[string]interface{}
+	_ = json.Unmarshal([]byte(argumentsInJSON), &arguments)
+
+	// 推送审批请求，等待用户处理
+	err := t.callback(&global.Chunk{
+		ToolCallId: compose.GetToolCallID(ctx),
+		ToolName:   t.name,
+		ToolParams: argumentsInJSON,
+		ShowMsg:    "等待确认工具调用：" + t.name,
+		Event:      EventApprovalRequest,
+		Data: ApprovalRequest{
+			ApprovalId: approvalId,
+			ToolCallId: compose.GetToolCallID(ctx),
+			ToolName:   t.name,
+			Arguments:  arguments,
+			ExpireAt:   time.Now().Add(timeout).Unix(),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var decision *ApprovalDecision
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-timer.C:
+		log.Info("tool approval timeout", zap.String("tool", t.name), zap.String("approvalId", approvalId))
+		t.notifyResult(ctx, approvalId, "timeout")
+		return fmt.Sprintf("等待用户确认超时，工具 %s 未执行", t.name), nil
+	case decision = <-p.decision:
+	}
+
+	t.notifyResult(ctx, approvalId, string(decision.Action))
+	switch decision.Action {
+	case ApprovalActionReject:
+		msg := fmt.Sprintf("用户拒绝了工具 %s 的调用", t.name)
+		if decision.Reason != "" {
+			msg += "，原因：" + decision.Reason
+		}
+		return msg, nil
+	case ApprovalActionEdit:
+		argumentsInJSON = decision.Arguments
+	}
+
+	return t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+}
+
+func (t *approvalTool) notifyResult(ctx context.Context, approvalId string, result string) {
+	_ = t.callback(&global.Chunk{
+		ToolCallId: compose.GetToolCallID(ctx),
+		ToolName:   t.name,
+		Event:      EventApprovalResult,
+		Data: map[string]string{
+			"approvalId": approvalId,
+			"result":     result,
+		},
+	})
+}
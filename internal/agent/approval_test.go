@@ -0,0 +1,310 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"txing-ai/internal/global"
+)
+
+func newTestApprovalManager(agentConfig *global.AgentConfig) *ApprovalManager {
+	return newApprovalManager(func() *global.AgentConfig {
+		return agentConfig
+	})
+}
+
+func TestApprovalManager_Policy(t *testing.T) {
+	approvals := newTestApprovalManager(&global.AgentConfig{
+		DefaultToolPolicy: "ask",
+		ToolPolicies: map[string]string{
+			"*":                         "auto",
+			"maps_*":                    "deny",
+			"maps_direction_*":          "ask",
+			"maps_direction_?alking":    "auto",
+			"markdown_to_pdf_file_tool": "deny",
+		},
+	})
+
+	tests := []struct {
+		toolName string
+		want     ToolPolicy
+	}{
+		{"markdown_to_pdf_file_tool", ToolPolicyDeny},
+		{"maps_weather", ToolPolicyDeny},
+		{"maps_direction_driving", ToolPolicyAsk},
+		{"maps_direction_walking", ToolPolicyAuto},
+		{"web_search_tool", ToolPolicyAuto},
+	}
+	// 多个通配符同时匹配时结果不能受 map 遍历顺序影响
+	for i := 0; i < 50; i++ {
+		for _, tt := range tests {
+			if got := approvals.Policy(tt.toolName); got != tt.want {
+				t.Fatalf("Policy(%s) = %s, want %s", tt.toolName, got, tt.want)
+			}
+		}
+	}
+
+	approvals = newTestApprovalManager(&global.AgentConfig{DefaultToolPolicy: "ask"})
+	if got := approvals.Policy("web_search_tool"); got != ToolPolicyAsk {
+		t.Errorf("Policy() = %s, want default policy ask", got)
+	}
+	approvals = newTestApprovalManager(&global.AgentConfig{DefaultToolPolicy: "unknown"})
+	if got := approvals.Policy("web_search_tool"); got != ToolPolicyAuto {
+		t.Errorf("Policy() = %s, want auto for unknown policy", got)
+	}
+	if got := newTestApprovalManager(nil).Policy("web_search_tool"); got != ToolPolicyAuto {
+		t.Errorf("Policy() without config = %s, want auto", got)
+	}
+}
+
+func TestMatchToolConfig(t *testing.T) {
+	configs := map[string]int{"*": 1, "maps_*": 2, "maps_?eather": 3, "maps_weathe?": 4}
+	for i := 0; i < 50; i++ {
+		pattern, conf, ok := matchToolConfig(configs, "maps_weather")
+		// 去掉通配符后长度相同时按字典序
+		if !ok || pattern != "maps_?eather" || conf != 3 {
+			t.Fatalf("matchToolConfig() = %s, %d, %v", pattern, conf, ok)
+		}
+	}
+	if _, _, ok := matchToolConfig(map[string]int{"maps_*": 1}, "web_search_tool"); ok {
+		t.Error("matchToolConfig() should not match")
+	}
+}
+
+// approvalTestTool 包装工具并记录推送的事件，收到审批请求后按 decide 的结果提交审批
+func approvalTestTool(t *testing.T, approvals *ApprovalManager, fake *fakeTool,
+	decide func(request ApprovalRequest) *ApprovalDecision) (tool.InvokableTool, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var results []string
+	callback := func(chunk *global.Chunk) error {
+		switch chunk.Event {
+		case EventApprovalRequest:
+			request := chunk.Data.(ApprovalRequest)
+			if decision := decide(request); decision != nil {
+				go func() {
+					if err := approvals.Resolve(request.ApprovalId, 7, decision); err != nil {
+						t.Errorf("Resolve() error = %v", err)
+					}
+				}()
+			}
+		case EventApprovalResult:
+			mu.Lock()
+			results = append(results, chunk.Data.(map[string]string)["result"])
+			mu.Unlock()
+		}
+		return nil
+	}
+	tools := wrapToolsWithApproval(context.Background(), []tool.BaseTool{fake}, approvals, callback)
+	return tools[0].(tool.InvokableTool), &results
+}
+
+func TestApprovalTool(t *testing.T) {
+	agentConfig := &global.AgentConfig{
+		ToolPolicies: map[string]string{
+			"web_search_tool":           "auto",
+			"file_delete_tool":          "deny",
+			"markdown_to_pdf_file_tool": "ask",
+		},
+		ApprovalTimeout: 1,
+	}
+	approvals := newTestApprovalManager(agentConfig)
+	ctx := context.WithValue(context.Background(), "userId", int64(7))
+
+	t.Run("auto", func(t *testing.T) {
+		fake := &fakeTool{name: "web_search_tool", run: func(ctx context.Context, call int32) (string, error) { return "ok", nil }}
+		tools := wrapToolsWithApproval(ctx, []tool.BaseTool{fake}, approvals, func(chunk *global.Chunk) error { return nil })
+		if tools[0] != tool.BaseTool(fake) {
+			t.Errorf("auto 策略的工具不应该被包装")
+		}
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		fake := &fakeTool{name: "file_delete_tool", run: func(ctx context.Context, call int32) (string, error) { return "ok", nil }}
+		denied, _ := approvalTestTool(t, approvals, fake, func(ApprovalRequest) *ApprovalDecision { return nil })
+		result, err := denied.InvokableRun(ctx, "{}")
+		if err != nil || !strings.Contains(result, "禁止调用") || fake.calls.Load() != 0 {
+			t.Errorf("InvokableRun() = %q, %v, calls = %d", result, err, fake.calls.Load())
+		}
+	})
+
+	pdf := func() *fakeTool {
+		return &fakeTool{name: "markdown_to_pdf_file_tool", run: func(ctx context.Context, call int32) (string, error) {
+			return "done", nil
+		}}
+	}
+
+	t.Run("ask approve", func(t *testing.T) {
+		fake := pdf()
+		asked, results := approvalTestTool(t, approvals, fake, func(request ApprovalRequest) *ApprovalDecision {
+			if request.ToolName != "markdown_to_pdf_file_tool" || request.Arguments["title"] != "攻略" {
+				t.Errorf("unexpected approval request: %+v", request)
+			}
+			return &ApprovalDecision{Action: ApprovalActionApprove}
+		})
+		result, err := asked.InvokableRun(ctx, `{"title":"攻略"}`)
+		if err != nil || result != "done" || fake.calls.Load() != 1 {
+			t.Errorf("InvokableRun() = %q, %v, calls = %d", result, err, fake.calls.Load())
+		}
+		if len(*results) != 1 || (*results)[0] != "approve" {
+			t.Errorf("approval results = %v", *results)
+		}
+	})
+
+	t.Run("ask edit", func(t *testing.T) {
+		var arguments string
+		edited := &editTool{fakeTool: &fakeTool{name: "markdown_to_pdf_file_tool"}, arguments: &arguments}
+		tools := wrapToolsWithApproval(ctx, []tool.BaseTool{edited}, approvals, func(chunk *global.Chunk) error {
+			if chunk.Event == EventApprovalRequest {
+				request := chunk.Data.(ApprovalRequest)
+				go func() {
+					_ = approvals.Resolve(request.ApprovalId, 7, &ApprovalDecision{Action: ApprovalActionEdit, Arguments: `{"title":"新攻略"}`})
+				}()
+			}
+			return nil
+		})
+		result, err := tools[0].(tool.InvokableTool).InvokableRun(ctx, `{"title":"攻略"}`)
+		if err != nil || result != "done" || arguments != `{"title":"新攻略"}` {
+			t.Errorf("InvokableRun() = %q, %v, arguments = %s", result, err, arguments)
+		}
+	})
+
+	t.Run("ask reject", func(t *testing.T) {
+		fake := pdf()
+		asked, results := approvalTestTool(t, approvals, fake, func(ApprovalRequest) *ApprovalDecision {
+			return &ApprovalDecision{Action: ApprovalActionReject, Reason: "不需要 PDF"}
+		})
+		result, err := asked.InvokableRun(ctx, "{}")
+		if err != nil || !strings.Contains(result, "不需要 PDF") || fake.calls.Load() != 0 {
+			t.Errorf("InvokableRun() = %q, %v, calls = %d", result, err, fake.calls.Load())
+		}
+		if len(*results) != 1 || (*results)[0] != "reject" {
+			t.Errorf("approval results = %v", *results)
+		}
+	})
+
+	t.Run("ask without approval", func(t *testing.T) {
+		fake := pdf()
+		asked, results := approvalTestTool(t, approvals, fake, func(ApprovalRequest) *ApprovalDecision {
+			t.Error("无法审批时不应该推送审批请求")
+			return nil
+		})
+		// 非流式调用直接拒绝，不等待审批超时
+		result, err := asked.InvokableRun(withoutApproval(ctx), "{}")
+		if err != nil || !strings.Contains(result, "无法确认") || fake.calls.Load() != 0 {
+			t.Errorf("InvokableRun() = %q, %v, calls = %d", result, err, fake.calls.Load())
+		}
+		if len(*results) != 0 || len(approvals.pending) != 0 {
+			t.Errorf("approval results = %v, pending = %d", *results, len(approvals.pending))
+		}
+	})
+
+	t.Run("ask timeout", func(t *testing.T) {
+		fake := pdf()
+		asked, results := approvalTestTool(t, approvals, fake, func(ApprovalRequest) *ApprovalDecision { return nil })
+		result, err := asked.InvokableRun(ctx, "{}")
+		if err != nil || !strings.Contains(result, "超时") || fake.calls.Load() != 0 {
+			t.Errorf("InvokableRun() = %q, %v, calls = %d", result, err, fake.calls.Load())
+		}
+		if len(*results) != 1 || (*results)[0] != "timeout" {
+			t.Errorf("approval results = %v", *results)
+		}
+		// 超时后审批已失效
+		if len(approvals.pending) != 0 {
+			t.Errorf("pending approvals = %d, want 0", len(approvals.pending))
+		}
+	})
+}
+
+// editTool 记录实际执行时的参数
+type editTool struct {
+	*fakeTool
+	arguments *string
+}
+
+func (t *editTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	*t.arguments = argumentsInJSON
+	return "done", nil
+}
+
+func TestApprovalManager_Resolve(t *testing.T) {
+	approvals := newTestApprovalManager(nil)
+	approvalId, p := approvals.register(7, "markdown_to_pdf_file_tool")
+
+	if err := approvals.Resolve("not_exist", 7, &ApprovalDecision{Action: ApprovalActionApprove}); !errors.Is(err, ErrApprovalNotFound) {
+		t.Errorf("Resolve() unknown approval error = %v", err)
+	}
+	if err := approvals.Resolve(approvalId, 8, &ApprovalDecision{Action: ApprovalActionApprove}); !errors.Is(err, ErrApprovalForbidden) {
+		t.Errorf("Resolve() other user error = %v", err)
+	}
+	if err := approvals.Resolve(approvalId, 7, &ApprovalDecision{Action: ApprovalActionEdit, Arguments: "not json"}); err == nil {
+		t.Error("Resolve() should reject invalid edited arguments")
+	}
+
+	// 其他用户和非法参数都不会使审批失效
+	if err := approvals.Resolve(approvalId, 7, &ApprovalDecision{Action: ApprovalActionApprove}); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if decision := <-p.decision; decision.Action != ApprovalActionApprove {
+		t.Errorf("decision = %+v", decision)
+	}
+	// 同一个审批只能提交一次
+	if err := approvals.Resolve(approvalId, 7, &ApprovalDecision{Action: ApprovalActionApprove}); !errors.Is(err, ErrApprovalNotFound) {
+		t.Errorf("Resolve() twice error = %v", err)
+	}
+}
+
+// TestApprovalTool_Parallel 同一轮中的两个工具都需要审批，工具并行执行时回调串行调用
+func TestApprovalTool_Parallel(t *testing.T) {
+	approvals := newTestApprovalManager(&global.AgentConfig{DefaultToolPolicy: "ask"})
+	ctx := context.WithValue(context.Background(), "userId", int64(7))
+
+	// 模拟 SSE 响应，没有加锁，并发写入时 -race 会报告数据竞争
+	var events []string
+	callback := serializeCallback(func(chunk *global.Chunk) error {
+		events = append(events, chunk.Event+":"+chunk.ToolName)
+		if chunk.Event == EventApprovalRequest {
+			request := chunk.Data.(ApprovalRequest)
+			go func() {
+				if err := approvals.Resolve(request.ApprovalId, 7, &ApprovalDecision{Action: ApprovalActionApprove}); err != nil {
+					t.Errorf("Resolve() error = %v", err)
+				}
+			}()
+		}
+		return nil
+	})
+
+	run := func(ctx context.Context, call int32) (string, error) { return "done", nil }
+	fakes := []tool.BaseTool{
+		&fakeTool{name: "markdown_to_pdf_file_tool", run: run},
+		&fakeTool{name: "file_delete_tool", run: run},
+	}
+	toolsNode, err := compose.NewToolNode(ctx, &compose.ToolsNodeConfig{
+		Tools:               wrapToolsWithApproval(ctx, fakes, approvals, callback),
+		ExecuteSequentially: false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := toolsNode.Invoke(ctx, schema.AssistantMessage("", []schema.ToolCall{
+		{ID: "call_1", Type: "function", Function: schema.FunctionCall{Name: "markdown_to_pdf_file_tool", Arguments: "{}"}},
+		{ID: "call_2", Type: "function", Function: schema.FunctionCall{Name: "file_delete_tool", Arguments: "{}"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Content != "done" {
+			t.Errorf("tool %s result = %q", result.ToolName, result.Content)
+		}
+	}
+	if len(events) != 4 {
+		t.Errorf("events = %v, want approval request and result for both tools", events)
+	}
+}
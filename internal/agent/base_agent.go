@@ -10,8 +10,12 @@ import (
 	"go.uber.org/zap"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/utils/jsonschema"
 )
 
+// 执行图的默认最大步数，防止无限循环
+const defaultMaxRunSteps = 30
+
 // Agent 定义智能体接口
 type Agent interface {
 	// Execute 执行智能体任务
@@ -22,6 +26,8 @@ type Agent interface {
 	GetDescription() string
 	ExecuteStream(ctx context.Context, endpoint string, apiKey string, model string,
 		content string, filePath string, callback func(chunk *global.Chunk) error) (string, error)
+	// OutputSchema 获取最终输出需要满足的 JSON Schema，为空表示不限制输出格式
+	OutputSchema() *jsonschema.Schema
 }
 
 // 校验接口实现
@@ -34,6 +40,7 @@ type BaseAgent struct {
 	model        *openai.ChatModel
 	graph        *compose.Graph[[]*schema.Message, *schema.Message]
 	systemPrompt string
+	outputSchema *jsonschema.Schema
 }
 
 // NewBaseAgent 创建一个新的基础智能体
@@ -64,6 +71,16 @@ func (a *BaseAgent) SetSystemPrompt(prompt string) {
 	a.systemPrompt = prompt
 }
 
+// SetOutputSchema 设置最终输出需要满足的 JSON Schema
+func (a *BaseAgent) SetOutputSchema(outputSchema *jsonschema.Schema) {
+	a.outputSchema = outputSchema
+}
+
+// OutputSchema 获取最终输出需要满足的 JSON Schema
+func (a *BaseAgent) OutputSchema() *jsonschema.Schema {
+	return a.outputSchema
+}
+
 // Execute 执行智能体任务的默认实现
 func (a *BaseAgent) Execute(ctx context.Context,
 	endpoint string, apiKey string, model string, input string) (string, error) {
@@ -83,13 +100,17 @@ func (a *BaseAgent) Execute(ctx context.Context,
 	}
 
 	// 使用执行图处理输入
+	systemPrompt := a.systemPrompt
+	if a.outputSchema != nil {
+		systemPrompt += "\n\n" + a.outputSchema.Instruction()
+	}
 	messages := []*schema.Message{
-		schema.SystemMessage(a.systemPrompt),
+		schema.SystemMessage(systemPrompt),
 		schema.UserMessage(input),
 	}
 
 	// 4. 编译Graph，并设置最大步数防止无限循环
-	agent, err := a.graph.Compile(ctx, compose.WithMaxRunSteps(30))
+	agent, err := a.graph.Compile(ctx, compose.WithMaxRunSteps(defaultMaxRunSteps))
 
 	if err != nil {
 		log.Error("agent graph compile failed", zap.Error(err))
@@ -113,5 +134,15 @@ func (a *BaseAgent) ExecuteStream(ctx context.Context, endpoint string, apiKey s
 	chunk := global.Chunk{
 		Content: response,
 	}
+	// 结构化输出只把总结推送给用户，完整结果放到事件数据中，解析失败时推送原始输出
+	if a.outputSchema != nil {
+		if result := ParseStructuredOutput(ctx, a.outputSchema, response); result.Err == nil {
+			if result.Output.Content != "" {
+				chunk.Content = result.Output.Content
+			}
+			chunk.Event = global.EventStructuredOutput
+			chunk.Data = result.Data
+		}
+	}
 	return response, callback(&chunk)
 }
@@ -0,0 +1,317 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+)
+
+// EventBudgetExceeded 达到运行限制事件，随后会根据已有信息生成结果
+const EventBudgetExceeded = "budget_exceeded"
+
+// 达到运行限制时要求模型总结的提示词
+const budgetSummaryPrompt = "已达到本次运行的限制（%s），不能再调用任何工具。请根据目前已经获得的信息直接给出最终结果，信息不足的部分如实说明。"
+
+const (
+	// 默认工具调用超时时间
+	defaultToolTimeout = 60 * time.Second
+	// 默认并行执行的工具调用数量
+	defaultMaxParallelToolCalls = 5
+	// 重试间隔，按重试次数递增
+	toolRetryInterval = 500 * time.Millisecond
+	// 超时后等待工具响应取消并返回的时间
+	toolCancelGrace = 100 * time.Millisecond
+)
+
+// defaultToolLimits 没有配置 tool_limits 时使用的工具限制
+// 高德地图接口有频率限制，智能体的提示词允许并行调用 maps 相关工具，由这里控制并发
+var defaultToolLimits = map[string]*global.ToolLimitConfig{
+	"maps_*": {Timeout: 15, Idempotent: true, Retries: 2, Concurrency: 3},
+}
+
+// RunBudget 单次运行的资源预算，包括工具调用次数、运行时长和 token 消耗，并发安全
+type RunBudget struct {
+	maxToolCalls int64
+	maxTokens    int64
+	maxRunTime   time.Duration
+	start        time.Time
+
+	toolCalls atomic.Int64
+	tokens    atomic.Int64
+}
+
+// NewRunBudget 根据配置创建运行预算，未配置的项不限制
+func NewRunBudget() *RunBudget {
+	return newRunBudget(global.LoadConfig().AgentConfig)
+}
+
+func newRunBudget(agentConfig *global.AgentConfig) *RunBudget {
+	b := &RunBudget{start: time.Now()}
+	if agentConfig == nil || agentConfig.Budget == nil {
+		return b
+	}
+	b.maxToolCalls = int64(agentConfig.Budget.MaxToolCalls)
+	b.maxTokens = int64(agentConfig.Budget.MaxTokens)
+	b.maxRunTime = agentConfig.Budget.MaxRunTime * time.Second
+	return b
+}
+
+// Exceeded 判断预算是否已用完，返回超出的原因，未超出时返回空字符串
+func (b *RunBudget) Exceeded() string {
+	if b == nil {
+		return ""
+	}
+	if b.maxToolCalls > 0 && b.toolCalls.Load() >= b.maxToolCalls {
+		return fmt.Sprintf("工具调用次数达到上限 %d 次", b.maxToolCalls)
+	}
+	if b.maxTokens > 0 && b.tokens.Load() >= b.maxTokens {
+		return fmt.Sprintf("token 消耗达到上限 %d", b.maxTokens)
+	}
+	if b.maxRunTime > 0 && time.Since(b.start) >= b.maxRunTime {
+		return fmt.Sprintf("运行时长达到上限 %s", b.maxRunTime)
+	}
+	return ""
+}
+
+// acquireToolCall 占用一次工具调用次数，超出上限时返回 false
+func (b *RunBudget) acquireToolCall() bool {
+	if b == nil {
+		return true
+	}
+	if b.maxRunTime > 0 && time.Since(b.start) >= b.maxRunTime {
+		return false
+	}
+	calls := b.toolCalls.Add(1)
+	return b.maxToolCalls <= 0 || calls <= b.maxToolCalls
+}
+
+// AddUsage 累计模型消耗的 token
+func (b *RunBudget) AddUsage(msg *schema.Message) {
+	if b == nil || msg == nil || msg.ResponseMeta == nil || msg.ResponseMeta.Usage == nil {
+		return
+	}
+	b.tokens.Add(int64(msg.ResponseMeta.Usage.TotalTokens))
+}
+
+// toolLimit 单个工具的调用限制
+type toolLimit struct {
+	timeout time.Duration
+	// 失败后的重试次数，只有幂等的工具才会重试
+	retries int
+	// 并发限制，为空表示不限制
+	sem chan struct{}
+}
+
+// toolRuntime 工具调用的运行时控制，每次运行创建一个，并发限制在同一次运行内生效
+type toolRuntime struct {
+	budget *RunBudget
+	config *global.AgentConfig
+	// 同一轮并行执行的工具调用数量限制
+	parallel chan struct{}
+
+	mu sync.Mutex
+	// 按配置的工具名称（通配符）共享并发限制
+	sems map[string]chan struct{}
+}
+
+func newToolRuntime(budget *RunBudget, agentConfig *global.AgentConfig) *toolRuntime {
+	maxParallel := defaultMaxParallelToolCalls
+	if agentConfig != nil && agentConfig.MaxParallelToolCalls > 0 {
+		maxParallel = agentConfig.MaxParallelToolCalls
+	}
+	return &toolRuntime{
+		budget:   budget,
+		config:   agentConfig,
+		parallel: make(chan struct{}, maxParallel),
+		sems:     make(map[string]chan struct{}),
+	}
+}
+
+// limit 获取指定工具的调用限制，优先精确匹配，其次匹配最具体的通配符，最后使用默认配置
+func (r *toolRuntime) limit(toolName string) *toolLimit {
+	limit := &toolLimit{timeout: defaultToolTimeout}
+	agentConfig := r.config
+	if agentConfig == nil {
+		agentConfig = &global.AgentConfig{}
+	}
+	if agentConfig.ToolTimeout > 0 {
+		limit.timeout = agentConfig.ToolTimeout * time.Second
+	}
+
+	toolLimits := agentConfig.ToolLimits
+	if toolLimits == nil {
+		toolLimits = defaultToolLimits
+	}
+	pattern, conf, _ := matchToolConfig(toolLimits, toolName)
+	if conf == nil {
+		return limit
+	}
+	if conf.Timeout > 0 {
+		limit.timeout = conf.Timeout * time.Second
+	}
+	// 只有标记为幂等的工具才重试，生成文件等有副作用的工具重试会产生重复的结果
+	if conf.Idempotent {
+		limit.retries = conf.Retries
+		if limit.retries <= 0 {
+			limit.retries = agentConfig.ToolRetries
+		}
+	}
+	if conf.Concurrency > 0 {
+		r.mu.Lock()
+		sem, ok := r.sems[pattern]
+		if !ok {
+			sem = make(chan struct{}, conf.Concurrency)
+			r.sems[pattern] = sem
+		}
+		r.mu.Unlock()
+		limit.sem = sem
+	}
+	return limit
+}
+
+// limitedTool 包装工具，增加超时、重试、并发限制和预算控制
+type limitedTool struct {
+	tool.InvokableTool
+	name    string
+	limit   *toolLimit
+	runtime *toolRuntime
+}
+
+// wrapToolsWithLimits 为工具增加超时、重试、并发限制和预算控制
+func wrapToolsWithLimits(ctx context.Context, tools []tool.BaseTool, runtime *toolRuntime) []tool.BaseTool {
+	wrapped := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			wrapped = append(wrapped, t)
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			log.Error("Failed to get tool info", zap.Error(err))
+			wrapped = append(wrapped, t)
+			continue
+		}
+		wrapped = append(wrapped, &limitedTool{
+			InvokableTool: invokable,
+			name:          info.Name,
+			limit:         runtime.limit(info.Name),
+			runtime:       runtime,
+		})
+	}
+	return wrapped
+}
+
+func (t *limitedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if !t.runtime.budget.acquireToolCall() {
+		reason := t.runtime.budget.Exceeded()
+		log.Info("tool call rejected by budget", zap.String("tool", t.name), zap.String("reason", reason))
+		return fmt.Sprintf("已达到本次运行的限制（%s），工具 %s 未执行，请不要再调用工具，直接根据已有信息给出结果", reason, t.name), nil
+	}
+
+	// 并发限制
+	if err := acquire(ctx, t.runtime.parallel); err != nil {
+		return "", err
+	}
+	defer release(t.runtime.parallel)
+	if t.limit.sem != nil {
+		if err := acquire(ctx, t.limit.sem); err != nil {
+			return "", err
+		}
+		defer release(t.limit.sem)
+	}
+
+	var err error
+	for attempt := 0; attempt <= t.limit.retries; attempt++ {
+		if attempt > 0 {
+			log.Info("retry tool call", zap.String("tool", t.name), zap.Int("attempt", attempt), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Duration(attempt) * toolRetryInterval):
+			}
+		}
+
+		var result string
+		var abandoned bool
+		result, abandoned, err = t.invokeWithTimeout(ctx, argumentsInJSON, opts...)
+		if err == nil {
+			return result, nil
+		}
+		// 整个运行被取消时不再重试
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		// 超时后工具仍在执行，重试会让同一个调用同时执行多次
+		if abandoned {
+			break
+		}
+	}
+
+	// 失败信息反馈给模型，由模型决定下一步，而不是中断整个运行
+	log.Error("tool call failed", zap.String("tool", t.name), zap.Error(err))
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Sprintf("工具 %s 调用超时（%s），请换一种方式或者跳过该信息", t.name, t.limit.timeout), nil
+	}
+	return fmt.Sprintf("工具 %s 调用失败：%v", t.name, err), nil
+}
+
+// invokeWithTimeout 带超时调用工具，工具内部不响应取消时也能按时返回
+// 超时的时候工具还没有返回则 abandoned 为 true，此时工具仍在后台执行，直到它自己结束
+func (t *limitedTool) invokeWithTimeout(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (output string, abandoned bool, err error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, t.limit.timeout)
+	defer cancel()
+
+	type result struct {
+		output string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				done <- result{err: fmt.Errorf("tool panic: %v", err)}
+			}
+		}()
+		output, err := t.InvokableTool.InvokableRun(ctxWithTimeout, argumentsInJSON, opts...)
+		done <- result{output: output, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.output, false, r.err
+	case <-ctxWithTimeout.Done():
+	}
+	// 响应取消的工具会在超时后很快返回，稍等一下再判断是否仍在执行
+	grace := time.NewTimer(toolCancelGrace)
+	defer grace.Stop()
+	select {
+	case r := <-done:
+		return r.output, false, r.err
+	case <-grace.C:
+		log.Warn("tool call timed out and is still running", zap.String("tool", t.name), zap.Duration("timeout", t.limit.timeout))
+		return "", true, ctxWithTimeout.Err()
+	}
+}
+
+func acquire(ctx context.Context, sem chan struct{}) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func release(sem chan struct{}) {
+	<-sem
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// fakeTool 测试用的工具，按调用次数返回预设的结果
+type fakeTool struct {
+	name  string
+	calls atomic.Int32
+	run   func(ctx context.Context, call int32) (string, error)
+}
+
+func (t *fakeTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: t.name}, nil
+}
+
+func (t *fakeTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.run(ctx, t.calls.Add(1))
+}
+
+func newLimitedTool(t *testing.T, fake *fakeTool, agentConfig *global.AgentConfig) *limitedTool {
+	t.Helper()
+	tools := wrapToolsWithLimits(context.Background(), []tool.BaseTool{fake}, newToolRuntime(newRunBudget(agentConfig), agentConfig))
+	limited, ok := tools[0].(*limitedTool)
+	if !ok {
+		t.Fatalf("wrapToolsWithLimits() = %T", tools[0])
+	}
+	return limited
+}
+
+func TestRunBudget(t *testing.T) {
+	budget := newRunBudget(&global.AgentConfig{Budget: &global.AgentBudgetConfig{MaxToolCalls: 2, MaxTokens: 100}})
+	if !budget.acquireToolCall() || !budget.acquireToolCall() {
+		t.Fatal("前两次工具调用应该被允许")
+	}
+	if budget.acquireToolCall() {
+		t.Error("第三次工具调用应该被拒绝")
+	}
+	if reason := budget.Exceeded(); !strings.Contains(reason, "工具调用次数") {
+		t.Errorf("Exceeded() = %q", reason)
+	}
+
+	budget = newRunBudget(&global.AgentConfig{Budget: &global.AgentBudgetConfig{MaxTokens: 100}})
+	budget.AddUsage(&schema.Message{ResponseMeta: &schema.ResponseMeta{Usage: &schema.TokenUsage{TotalTokens: 120}}})
+	if reason := budget.Exceeded(); !strings.Contains(reason, "token") {
+		t.Errorf("Exceeded() = %q", reason)
+	}
+
+	if reason := newRunBudget(nil).Exceeded(); reason != "" {
+		t.Errorf("未配置预算时 Exceeded() = %q", reason)
+	}
+}
+
+func TestToolLimit(t *testing.T) {
+	agentConfig := &global.AgentConfig{
+		ToolTimeout: 20,
+		ToolRetries: 2,
+		ToolLimits: map[string]*global.ToolLimitConfig{
+			"maps_*":                    {Timeout: 5, Idempotent: true, Concurrency: 2},
+			"markdown_to_pdf_file_tool": {Timeout: 180, Retries: 3},
+		},
+	}
+	runtime := newToolRuntime(nil, agentConfig)
+
+	maps := runtime.limit("maps_weather")
+	if maps.timeout != 5*time.Second || maps.retries != 2 || cap(maps.sem) != 2 {
+		t.Errorf("limit(maps_weather) = %+v", maps)
+	}
+	// 同一个通配符共享并发限制
+	if runtime.limit("maps_geo").sem != maps.sem {
+		t.Error("同一个通配符的工具应该共享并发限制")
+	}
+	if pdf := runtime.limit("markdown_to_pdf_file_tool"); pdf.timeout != 180*time.Second || pdf.retries != 0 {
+		t.Errorf("limit(markdown_to_pdf_file_tool) = %+v，没有标记幂等的工具不应该重试", pdf)
+	}
+	if other := runtime.limit("file_write_tool"); other.timeout != 20*time.Second || other.retries != 0 {
+		t.Errorf("limit(file_write_tool) = %+v", other)
+	}
+
+	// 没有配置 tool_limits 时 maps 相关工具使用默认的并发限制
+	for _, runtime := range []*toolRuntime{newToolRuntime(nil, nil), newToolRuntime(nil, &global.AgentConfig{ToolRetries: 1})} {
+		maps := runtime.limit("maps_direction_walking")
+		if maps.timeout != 15*time.Second || maps.retries != 2 || cap(maps.sem) != 3 {
+			t.Errorf("default limit(maps_direction_walking) = %+v", maps)
+		}
+		if other := runtime.limit("web_search_tool"); other.timeout != defaultToolTimeout || other.retries != 0 || other.sem != nil {
+			t.Errorf("default limit(web_search_tool) = %+v", other)
+		}
+	}
+}
+
+func TestLimitedToolRetry(t *testing.T) {
+	flaky := func(ctx context.Context, call int32) (string, error) {
+		if call == 1 {
+			return "", errors.New("connection reset")
+		}
+		return "ok", nil
+	}
+	agentConfig := &global.AgentConfig{
+		ToolLimits: map[string]*global.ToolLimitConfig{"web_search_tool": {Idempotent: true, Retries: 1}},
+	}
+
+	search := &fakeTool{name: "web_search_tool", run: flaky}
+	result, err := newLimitedTool(t, search, agentConfig).InvokableRun(context.Background(), "{}")
+	if err != nil || result != "ok" || search.calls.Load() != 2 {
+		t.Errorf("幂等工具应该重试：result = %q, err = %v, calls = %d", result, err, search.calls.Load())
+	}
+
+	write := &fakeTool{name: "file_write_tool", run: flaky}
+	result, err = newLimitedTool(t, write, agentConfig).InvokableRun(context.Background(), "{}")
+	if err != nil || !strings.Contains(result, "调用失败") || write.calls.Load() != 1 {
+		t.Errorf("有副作用的工具不应该重试：result = %q, err = %v, calls = %d", result, err, write.calls.Load())
+	}
+}
+
+func TestLimitedToolTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	// 不响应取消的工具，超时后仍在执行
+	slow := &fakeTool{name: "maps_weather", run: func(ctx context.Context, call int32) (string, error) {
+		<-release
+		return "late", nil
+	}}
+	limited := newLimitedTool(t, slow, &global.AgentConfig{
+		ToolLimits: map[string]*global.ToolLimitConfig{"maps_*": {Idempotent: true, Retries: 2}},
+	})
+	limited.limit.timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	result, err := limited.InvokableRun(context.Background(), "{}")
+	if err != nil || !strings.Contains(result, "调用超时") {
+		t.Errorf("result = %q, err = %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("超时后应该立即返回，耗时 %s", elapsed)
+	}
+	if calls := slow.calls.Load(); calls != 1 {
+		t.Errorf("超时的调用仍在执行时不应该重试，calls = %d", calls)
+	}
+
+	// 响应取消的工具超时后可以重试
+	cancellable := &fakeTool{name: "maps_geo", run: func(ctx context.Context, call int32) (string, error) {
+		if call == 1 {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return "ok", nil
+	}}
+	limited = newLimitedTool(t, cancellable, &global.AgentConfig{
+		ToolLimits: map[string]*global.ToolLimitConfig{"maps_*": {Idempotent: true, Retries: 1}},
+	})
+	limited.limit.timeout = 50 * time.Millisecond
+	if result, err := limited.InvokableRun(context.Background(), "{}"); err != nil || result != "ok" {
+		t.Errorf("result = %q, err = %v", result, err)
+	}
+}
+
+func TestLimitedToolBudget(t *testing.T) {
+	fake := &fakeTool{name: "web_search_tool", run: func(ctx context.Context, call int32) (string, error) {
+		return "ok", nil
+	}}
+	limited := newLimitedTool(t, fake, &global.AgentConfig{Budget: &global.AgentBudgetConfig{MaxToolCalls: 1}})
+
+	if result, _ := limited.InvokableRun(context.Background(), "{}"); result != "ok" {
+		t.Errorf("result = %q", result)
+	}
+	result, err := limited.InvokableRun(context.Background(), "{}")
+	if err != nil || !strings.Contains(result, "未执行") || fake.calls.Load() != 1 {
+		t.Errorf("超出预算后不应该执行工具：result = %q, err = %v, calls = %d", result, err, fake.calls.Load())
+	}
+}
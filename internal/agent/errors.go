@@ -10,4 +10,6 @@ var (
 	ErrExecutionFailed  = errors.New("execution failed")
 	ErrCapabilityExists = errors.New("capability already exists")
 	ErrCapabilityNotFound = errors.New("capability not found")
+	ErrApprovalNotFound   = errors.New("审批请求不存在或已过期")
+	ErrApprovalForbidden  = errors.New("无权处理该审批请求")
 )
\ No newline at end of file
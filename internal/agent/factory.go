@@ -2,7 +2,9 @@ package agent
 
 import (
 	"fmt"
+	"sort"
 	"txing-ai/internal/iface"
+	fileservice "txing-ai/internal/service/file"
 )
 
 // AgentType represents the type of agent to create
@@ -17,25 +19,43 @@ const (
 	TravelAgentType = "travel"
 )
 
+// AttachmentPurpose 智能体附件的上传用途，简历优化智能体只读取 PDF 简历
+func AttachmentPurpose(agentType AgentType) fileservice.Purpose {
+	if agentType == ResumeAgentType {
+		return fileservice.PurposeResume
+	}
+	return fileservice.PurposeFile
+}
+
 // AgentFactory creates agents of different types
 type AgentFactory interface {
 	// CreateAgent creates an agent of the specified type
 	CreateAgent(agentType AgentType) (Agent, error)
+	// AgentTypes 获取已注册的智能体类型，按名称排序
+	AgentTypes() []AgentType
 }
 
 // SimpleAgentFactory is a basic implementation of AgentFactory
 type SimpleAgentFactory struct {
 	// Registry of agent constructors
 	constructors map[AgentType]func() Agent
+	// 工具调用审批管理器，注入到支持工具调用的智能体中
+	approvals *ApprovalManager
+}
+
+// approvalAware 支持工具调用审批的智能体
+type approvalAware interface {
+	SetApprovalManager(approvals *ApprovalManager)
 }
 
 // 接口实现校验
 var _ AgentFactory = (*SimpleAgentFactory)(nil)
 
 // NewSimpleAgentFactory creates a new simple agent factory
-func NewSimpleAgentFactory(res iface.ResourceProvider) AgentFactory {
+func NewSimpleAgentFactory(res iface.ResourceProvider, approvals *ApprovalManager) AgentFactory {
 	factory := SimpleAgentFactory{
 		constructors: make(map[AgentType]func() Agent),
+		approvals:    approvals,
 	}
 	// 注册一个通用 agent 类型
 	factory.RegisterAgentType(GeneralAgentType, func() Agent {
@@ -63,5 +83,21 @@ func (f *SimpleAgentFactory) CreateAgent(agentType AgentType) (Agent, error) {
 		return nil, fmt.Errorf("unknown agent type: %s", agentType)
 	}
 
-	return constructor(), nil
+	agent := constructor()
+	if a, ok := agent.(approvalAware); ok {
+		a.SetApprovalManager(f.approvals)
+	}
+	return agent, nil
+}
+
+// AgentTypes 获取已注册的智能体类型，按名称排序
+func (f *SimpleAgentFactory) AgentTypes() []AgentType {
+	agentTypes := make([]AgentType, 0, len(f.constructors))
+	for agentType := range f.constructors {
+		agentTypes = append(agentTypes, agentType)
+	}
+	sort.Slice(agentTypes, func(i, j int) bool {
+		return agentTypes[i] < agentTypes[j]
+	})
+	return agentTypes
 }
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/utils/jsonschema"
+
+	"go.uber.org/zap"
+)
+
+// OutputFile 智能体生成的文件
+type OutputFile struct {
+	// 文件名，取生成文件的工具返回结果中的文件名
+	Name string `json:"name"`
+	// 文件说明
+	Description string `json:"description,omitempty"`
+}
+
+// FinalOutput 生成文件类智能体的结构化最终输出
+type FinalOutput struct {
+	// 给用户看的总结
+	Content string `json:"content"`
+	// 生成的文件
+	Files []OutputFile `json:"files"`
+}
+
+// FinalOutputSchema FinalOutput 对应的 JSON Schema
+var FinalOutputSchema = jsonschema.MustParse(`{
+	"type": "object",
+	"properties": {
+		"content": {"type": "string", "minLength": 1, "description": "给用户看的简要总结"},
+		"files": {
+			"type": "array",
+			"description": "本次生成的文件，没有生成文件时为空数组",
+			"items": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string", "minLength": 1, "description": "文件名，取生成文件的工具返回结果中的文件名，以文件后缀名结尾"},
+					"description": {"type": "string", "description": "文件说明"}
+				},
+				"required": ["name"]
+			}
+		}
+	},
+	"required": ["content", "files"]
+}`)
+
+// ParseFinalOutput 按 schema 校验智能体的输出，并解析为 FinalOutput 以及原始结构化数据
+func ParseFinalOutput(schema *jsonschema.Schema, response string) (*FinalOutput, interface{}, error) {
+	value, err := schema.ParseOutput(response)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	var output FinalOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, nil, err
+	}
+	return &output, value, nil
+}
+
+// StructuredOutput 一次运行的结构化输出解析结果
+type StructuredOutput struct {
+	// 解析后的最终输出，输出不满足 schema 时为空
+	Output *FinalOutput
+	// 原始结构化数据
+	Data interface{}
+	// 解析失败的原因
+	Err error
+}
+
+type structuredOutputKey struct{}
+
+// WithStructuredOutput 在上下文中记录本次运行的结构化输出，执行结束后调用方直接读取解析结果，不需要再次解析
+func WithStructuredOutput(ctx context.Context) (context.Context, *StructuredOutput) {
+	result := &StructuredOutput{}
+	return context.WithValue(ctx, structuredOutputKey{}, result), result
+}
+
+// ParseStructuredOutput 解析智能体的最终输出并记录到上下文中
+// 修正次数用完仍不满足 schema 时只记录错误，调用方退回到原始输出，不影响已经生成的文件
+func ParseStructuredOutput(ctx context.Context, schema *jsonschema.Schema, response string) *StructuredOutput {
+	result, ok := ctx.Value(structuredOutputKey{}).(*StructuredOutput)
+	if !ok {
+		result = &StructuredOutput{}
+	}
+	result.Output, result.Data, result.Err = ParseFinalOutput(schema, response)
+	if result.Err != nil {
+		log.Error("parse agent structured output failed, fall back to raw response",
+			zap.String("response", response), zap.Error(result.Err))
+	}
+	return result
+}
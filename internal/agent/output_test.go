@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseStructuredOutput(t *testing.T) {
+	ctx, structured := WithStructuredOutput(context.Background())
+
+	result := ParseStructuredOutput(ctx, FinalOutputSchema, "```json\n{\"content\":\"已生成\",\"files\":[{\"name\":\"攻略.pdf\"}]}\n```")
+	if result != structured {
+		t.Fatal("result should be recorded in context")
+	}
+	if result.Err != nil || result.Output == nil {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Output.Content != "已生成" || len(result.Output.Files) != 1 || result.Output.Files[0].Name != "攻略.pdf" {
+		t.Fatalf("unexpected output: %+v", result.Output)
+	}
+	if result.Data == nil {
+		t.Fatal("structured data should be kept")
+	}
+
+	// 不满足 schema 时只记录错误，调用方退回到原始输出
+	result = ParseStructuredOutput(ctx, FinalOutputSchema, "攻略已生成，请查看文件")
+	if result != structured {
+		t.Fatal("result should be recorded in context")
+	}
+	if result.Err == nil || result.Output != nil || result.Data != nil {
+		t.Fatalf("invalid output should fall back: %+v", result)
+	}
+
+	// 上下文中没有记录时同样返回解析结果
+	result = ParseStructuredOutput(context.Background(), FinalOutputSchema, `{"content":"完成","files":[]}`)
+	if result.Err != nil || result.Output.Content != "完成" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
@@ -0,0 +1,608 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/utils/jsonschema"
+)
+
+const (
+	// EventPlan 规划完成事件，数据为完整的执行计划
+	EventPlan = "plan"
+	// EventPlanProgress 步骤执行进度事件
+	EventPlanProgress = "plan_progress"
+	// EventPlanReview 评审结果事件
+	EventPlanReview = "plan_review"
+)
+
+// 步骤状态
+const (
+	PlanStepPending = "pending"
+	PlanStepRunning = "running"
+	PlanStepDone    = "done"
+	PlanStepFailed  = "failed"
+)
+
+const (
+	defaultPlanMaxSteps        = 8
+	defaultPlanMaxReviewRounds = 1
+	// 子智能体执行图的最大步数
+	executorMaxRunSteps = 20
+)
+
+// ExecutorSpec 规划-执行模式中的子智能体
+type ExecutorSpec struct {
+	// 名称，规划器按名称分派步骤
+	Name string
+	// 能力描述，提供给规划器参考
+	Description string
+	// 系统提示词
+	SystemPrompt string
+	// 可使用的工具名称，支持通配符，如 maps_*，为空表示不使用工具
+	Tools []string
+}
+
+// PlanExecuteConfig 规划-执行多智能体编排配置
+type PlanExecuteConfig struct {
+	// 可分派步骤的子智能体
+	Executors []*ExecutorSpec
+	// 汇总子智能体，评审通过后整合各步骤结果生成最终输出
+	Assembler *ExecutorSpec
+	// 计划最多包含的步骤数
+	MaxSteps int
+	// 评审不通过时最多重新执行的轮数
+	MaxReviewRounds int
+}
+
+// PlanStep 执行计划中的一个步骤
+type PlanStep struct {
+	Id       int    `json:"id"`
+	Executor string `json:"executor"`
+	Task     string `json:"task"`
+	Status   string `json:"status"`
+	// 评审意见，重新执行时提供给子智能体
+	Feedback string `json:"feedback,omitempty"`
+	// 执行结果
+	Result string `json:"-"`
+}
+
+// PlanProgress 推送给前端的步骤执行进度
+type PlanProgress struct {
+	StepId   int    `json:"stepId"`
+	Total    int    `json:"total"`
+	Executor string `json:"executor"`
+	Task     string `json:"task"`
+	Status   string `json:"status"`
+	Round    int    `json:"round"`
+}
+
+// PlanReview 评审结果
+type PlanReview struct {
+	Approved bool             `json:"approved"`
+	Feedback string           `json:"feedback"`
+	Redo     []PlanReviewRedo `json:"redo"`
+	Round    int              `json:"round"`
+}
+
+// PlanReviewRedo 需要重新执行的步骤
+type PlanReviewRedo struct {
+	Id       int    `json:"id"`
+	Feedback string `json:"feedback"`
+}
+
+// PlanState 规划-执行图的状态
+type PlanState struct {
+	// 系统提示词，作为规划、评审、汇总的任务背景
+	SystemPrompt string
+	// 用户需求
+	UserInput    string
+	Steps        []*PlanStep
+	ReviewRounds int
+	Approved     bool
+}
+
+var planReviewSchema = jsonschema.MustParse(`{
+	"type": "object",
+	"properties": {
+		"approved": {"type": "boolean", "description": "所有步骤的结果是否满足要求"},
+		"feedback": {"type": "string", "description": "总体评审意见"},
+		"redo": {
+			"type": "array",
+			"description": "需要重新执行的步骤，approved 为 true 时为空数组",
+			"items": {
+				"type": "object",
+				"properties": {
+					"id": {"type": "integer", "description": "步骤 id"},
+					"feedback": {"type": "string", "description": "该步骤需要改进的地方"}
+				},
+				"required": ["id", "feedback"]
+			}
+		}
+	},
+	"required": ["approved", "redo"]
+}`)
+
+// planExecutor 构建规划-执行图所需的依赖
+type planExecutor struct {
+	config       *PlanExecuteConfig
+	modelConfig  *openai.ChatModelConfig
+	tools        []tool.BaseTool
+	approvals    *ApprovalManager
+	outputSchema *jsonschema.Schema
+	budget       *RunBudget
+	callback     func(chunk *global.Chunk) error
+}
+
+// newPlanExecuteGraph 创建规划-执行图：规划器拆分任务并分派给子智能体，子智能体依次执行，
+// 评审器检查执行结果（不通过则带上意见重新执行），最后由汇总子智能体生成最终输出。
+// 输入输出与 newGraph 一致，可直接替换单智能体的执行图
+func newPlanExecuteGraph(ctx context.Context, modelConfig *openai.ChatModelConfig, tools []tool.BaseTool,
+	approvals *ApprovalManager, outputSchema *jsonschema.Schema, budget *RunBudget, config *PlanExecuteConfig,
+	callback func(chunk *global.Chunk) error) (*compose.Graph[[]*schema.Message, *schema.Message], error) {
+
+	if len(config.Executors) == 0 || config.Assembler == nil {
+		return nil, fmt.Errorf("plan execute config requires executors and assembler")
+	}
+
+	p := &planExecutor{
+		config:       config,
+		modelConfig:  modelConfig,
+		tools:        tools,
+		approvals:    approvals,
+		outputSchema: outputSchema,
+		budget:       budget,
+		callback:     callback,
+	}
+
+	graph := compose.NewGraph[[]*schema.Message, *schema.Message](
+		compose.WithGenLocalState(func(ctx context.Context) *PlanState {
+			return &PlanState{}
+		}))
+
+	// 记录任务背景和用户需求
+	plannerPre := func(ctx context.Context, input []*schema.Message, state *PlanState) ([]*schema.Message, error) {
+		for _, msg := range input {
+			switch msg.Role {
+			case schema.System:
+				state.SystemPrompt = msg.Content
+			case schema.User:
+				state.UserInput = msg.Content
+			}
+		}
+		return input, nil
+	}
+
+	_ = graph.AddLambdaNode("planner", compose.InvokableLambda(p.plan), compose.WithStatePreHandler(plannerPre))
+	_ = graph.AddLambdaNode("executor", compose.InvokableLambda(p.execute))
+	_ = graph.AddLambdaNode("reviewer", compose.InvokableLambda(p.review))
+	_ = graph.AddLambdaNode("assembler", compose.InvokableLambda(p.assemble))
+
+	// 评审通过或者达到最大评审轮数则进入汇总，否则重新执行
+	reviewBranchCondition := func(ctx context.Context, steps []*PlanStep) (string, error) {
+		next := "executor"
+		err := compose.ProcessState[*PlanState](ctx, func(ctx context.Context, state *PlanState) error {
+			if state.Approved || state.ReviewRounds > p.maxReviewRounds() {
+				next = "assembler"
+			}
+			return nil
+		})
+		return next, err
+	}
+
+	_ = graph.AddEdge(compose.START, "planner")
+	_ = graph.AddEdge("planner", "executor")
+	_ = graph.AddEdge("executor", "reviewer")
+	_ = graph.AddBranch("reviewer", compose.NewGraphBranch(reviewBranchCondition, map[string]bool{
+		"executor":  true,
+		"assembler": true,
+	}))
+	_ = graph.AddEdge("assembler", compose.END)
+	return graph, nil
+}
+
+func (p *planExecutor) maxSteps() int {
+	if p.config.MaxSteps <= 0 {
+		return defaultPlanMaxSteps
+	}
+	return p.config.MaxSteps
+}
+
+func (p *planExecutor) maxReviewRounds() int {
+	if p.config.MaxReviewRounds <= 0 {
+		return defaultPlanMaxReviewRounds
+	}
+	return p.config.MaxReviewRounds
+}
+
+// 获取状态的快照
+func (p *planExecutor) state(ctx context.Context) *PlanState {
+	var snapshot PlanState
+	_ = compose.ProcessState[*PlanState](ctx, func(ctx context.Context, state *PlanState) error {
+		snapshot = *state
+		return nil
+	})
+	return &snapshot
+}
+
+// plan 规划器：将任务拆分为步骤并分派给子智能体
+func (p *planExecutor) plan(ctx context.Context, input []*schema.Message) ([]*PlanStep, error) {
+	state := p.state(ctx)
+
+	executorNames := make([]string, 0, len(p.config.Executors))
+	var executors strings.Builder
+	for _, spec := range p.config.Executors {
+		executorNames = append(executorNames, spec.Name)
+		executors.WriteString(fmt.Sprintf("- %s：%s\n", spec.Name, spec.Description))
+	}
+	enum, _ := json.Marshal(executorNames)
+	planSchema, err := jsonschema.Parse([]byte(fmt.Sprintf(`{
+		"type": "object",
+		"properties": {
+			"steps": {
+				"type": "array",
+				"minItems": 1,
+				"maxItems": %d,
+				"items": {
+					"type": "object",
+					"properties": {
+						"executor": {"type": "string", "enum": %s, "description": "负责执行该步骤的子智能体"},
+						"task": {"type": "string", "minLength": 1, "description": "该步骤的具体任务，需要包含执行所需的全部信息"}
+					},
+					"required": ["executor", "task"]
+				}
+			}
+		},
+		"required": ["steps"]
+	}`, p.maxSteps(), enum)))
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := fmt.Sprintf(`你是任务规划专家，负责把复杂任务拆分为若干个可以独立执行的步骤，并分派给合适的子智能体。
+
+## 可用的子智能体
+%s
+## 要求
+1. 步骤按执行顺序排列，后面的步骤可以使用前面步骤的结果
+2. 每个步骤的任务描述要具体、完整，子智能体看不到原始任务背景
+3. 最终结果的整合、排版和文件生成由汇总环节完成，不需要作为步骤
+4. 步骤数量不超过 %d 个
+
+%s`, executors.String(), p.maxSteps(), planSchema.Instruction())
+
+	var output struct {
+		Steps []*PlanStep `json:"steps"`
+	}
+	err = p.generateStructured(ctx, planSchema, prompt, p.taskContext(state), &output)
+	if err != nil {
+		return nil, fmt.Errorf("plan failed: %w", err)
+	}
+
+	for i, step := range output.Steps {
+		step.Id = i + 1
+		step.Status = PlanStepPending
+	}
+	_ = compose.ProcessState[*PlanState](ctx, func(ctx context.Context, state *PlanState) error {
+		state.Steps = output.Steps
+		return nil
+	})
+
+	_ = p.callback(&global.Chunk{
+		ShowMsg: fmt.Sprintf("任务已拆分为 %d 个步骤", len(output.Steps)),
+		Event:   EventPlan,
+		Data:    output.Steps,
+	})
+	return output.Steps, nil
+}
+
+// execute 依次执行未完成的步骤
+func (p *planExecutor) execute(ctx context.Context, steps []*PlanStep) ([]*PlanStep, error) {
+	state := p.state(ctx)
+	round := state.ReviewRounds
+
+	for _, step := range steps {
+		if step.Status == PlanStepDone {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		step.Status = PlanStepRunning
+		p.notifyProgress(step, len(steps), round)
+
+		result, err := p.runStep(ctx, state, steps, step)
+		if err != nil {
+			log.Error("plan step execute failed", zap.Int("step", step.Id), zap.String("executor", step.Executor), zap.Error(err))
+			step.Status = PlanStepFailed
+			step.Result = "执行失败：" + err.Error()
+		} else {
+			step.Status = PlanStepDone
+			step.Result = result
+		}
+		p.notifyProgress(step, len(steps), round)
+	}
+	return steps, nil
+}
+
+// runStep 使用对应的子智能体执行单个步骤
+func (p *planExecutor) runStep(ctx context.Context, state *PlanState, steps []*PlanStep, step *PlanStep) (string, error) {
+	spec := p.executor(step.Executor)
+	if spec == nil {
+		return "", fmt.Errorf("executor %s not found", step.Executor)
+	}
+
+	var input strings.Builder
+	input.WriteString("## 用户需求\n" + state.UserInput + "\n\n")
+	if previous := formatStepResults(steps, step.Id); previous != "" {
+		input.WriteString("## 前面步骤的结果\n" + previous + "\n")
+	}
+	input.WriteString("## 当前任务\n" + step.Task + "\n")
+	if step.Feedback != "" {
+		input.WriteString("\n## 评审意见（请据此改进）\n" + step.Feedback + "\n")
+	}
+
+	msg, err := p.runSubAgent(ctx, spec, nil, spec.SystemPrompt, input.String())
+	if err != nil {
+		return "", err
+	}
+	return msg.Content, nil
+}
+
+// review 评审器：检查各步骤的执行结果，不满足要求的步骤带上意见重新执行
+func (p *planExecutor) review(ctx context.Context, steps []*PlanStep) ([]*PlanStep, error) {
+	state := p.state(ctx)
+
+	var review PlanReview
+	prompt := `你是严格的评审专家，负责检查各个步骤的执行结果是否准确、完整、满足用户需求。
+对于执行失败、信息缺失或者明显错误的步骤，给出具体的改进意见；结果整体满足要求时 approved 为 true。
+
+` + planReviewSchema.Instruction()
+	input := p.taskContext(state) + "\n\n## 各步骤执行结果\n" + formatStepResults(steps, 0)
+
+	if err := p.generateStructured(ctx, planReviewSchema, prompt, input, &review); err != nil {
+		// 评审失败不影响最终结果，直接进入汇总
+		log.Error("plan review failed, skip", zap.Error(err))
+		review = PlanReview{Approved: true}
+	}
+
+	// 只重新执行存在的步骤
+	redo := make(map[int]string)
+	for _, r := range review.Redo {
+		redo[r.Id] = r.Feedback
+	}
+	for _, step := range steps {
+		if step.Status == PlanStepFailed {
+			if _, ok := redo[step.Id]; !ok {
+				redo[step.Id] = "上一次执行失败：" + step.Result
+			}
+		}
+	}
+	review.Approved = review.Approved && len(redo) == 0
+
+	_ = compose.ProcessState[*PlanState](ctx, func(ctx context.Context, state *PlanState) error {
+		state.ReviewRounds++
+		state.Approved = review.Approved
+		review.Round = state.ReviewRounds
+		// 还有评审轮数时才需要重新执行
+		if !review.Approved && state.ReviewRounds <= p.maxReviewRounds() {
+			for _, step := range steps {
+				if feedback, ok := redo[step.Id]; ok {
+					step.Status = PlanStepPending
+					step.Feedback = feedback
+				}
+			}
+		}
+		return nil
+	})
+
+	showMsg := "评审通过"
+	if !review.Approved {
+		showMsg = fmt.Sprintf("评审未通过，%d 个步骤需要改进", len(redo))
+	}
+	_ = p.callback(&global.Chunk{
+		ShowMsg: showMsg,
+		Event:   EventPlanReview,
+		Data:    review,
+	})
+	return steps, nil
+}
+
+// assemble 汇总：整合各步骤结果生成最终输出
+func (p *planExecutor) assemble(ctx context.Context, steps []*PlanStep) (*schema.Message, error) {
+	state := p.state(ctx)
+	spec := p.config.Assembler
+
+	_ = p.callback(&global.Chunk{
+		ShowMsg: "正在汇总各步骤结果",
+		Event:   EventPlanProgress,
+		Data: PlanProgress{
+			Executor: spec.Name,
+			Task:     "汇总结果",
+			Status:   PlanStepRunning,
+			Total:    len(steps),
+			Round:    state.ReviewRounds,
+		},
+	})
+
+	systemPrompt := state.SystemPrompt
+	if spec.SystemPrompt != "" {
+		systemPrompt = spec.SystemPrompt + "\n\n" + systemPrompt
+	}
+	input := "## 用户需求\n" + state.UserInput + "\n\n## 各步骤执行结果\n" + formatStepResults(steps, 0)
+
+	return p.runSubAgent(ctx, spec, p.outputSchema, systemPrompt, input)
+}
+
+// runSubAgent 使用工具调用执行图运行子智能体
+func (p *planExecutor) runSubAgent(ctx context.Context, spec *ExecutorSpec, outputSchema *jsonschema.Schema,
+	systemPrompt string, input string) (*schema.Message, error) {
+	// 每个子智能体使用独立的模型实例，避免绑定的工具互相覆盖
+	chatModel, err := openai.NewChatModel(ctx, p.modelConfig)
+	if err != nil {
+		return nil, fmt.Errorf("create chat model failed: %w", err)
+	}
+
+	messages := []*schema.Message{
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage(input),
+	}
+
+	// 不需要工具的子智能体直接调用模型
+	tools := filterTools(ctx, p.tools, spec.Tools)
+	if len(tools) == 0 {
+		if outputSchema != nil {
+			msg, err := generateValid(ctx, chatModel, outputSchema, p.budget, messages)
+			if err != nil && msg != nil {
+				// 修正次数用完仍不满足 schema 时返回最后一次输出，由调用方退回到原始输出
+				log.Warn("sub agent output still invalid after repair attempts", zap.String("executor", spec.Name), zap.Error(err))
+				return msg, nil
+			}
+			return msg, err
+		}
+		msg, err := chatModel.Generate(ctx, messages)
+		p.budget.AddUsage(msg)
+		return msg, err
+	}
+
+	graph, err := newGraph(ctx, chatModel, &toolCallGraphConfig{
+		tools:        tools,
+		approvals:    p.approvals,
+		outputSchema: outputSchema,
+		budget:       p.budget,
+		maxRunSteps:  executorMaxRunSteps,
+	}, p.callback)
+	if err != nil {
+		return nil, err
+	}
+	runnable, err := graph.Compile(ctx, compose.WithMaxRunSteps(executorMaxRunSteps))
+	if err != nil {
+		return nil, err
+	}
+	return runnable.Invoke(ctx, messages)
+}
+
+// generateStructured 调用模型生成满足 schema 的结果并解析到 result 中
+func (p *planExecutor) generateStructured(ctx context.Context, outputSchema *jsonschema.Schema,
+	systemPrompt string, input string, result interface{}) error {
+	chatModel, err := openai.NewChatModel(ctx, p.modelConfig)
+	if err != nil {
+		return fmt.Errorf("create chat model failed: %w", err)
+	}
+
+	msg, err := generateValid(ctx, chatModel, outputSchema, p.budget, []*schema.Message{
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage(input),
+	})
+	if err != nil {
+		return err
+	}
+	value, _ := outputSchema.ParseOutput(msg.Content)
+	data, _ := json.Marshal(value)
+	return json.Unmarshal(data, result)
+}
+
+// generateValid 调用模型生成满足 schema 的结果，不满足时带上错误信息要求模型修正
+// 修正次数用完仍不满足时同时返回最后一次输出和校验错误
+func generateValid(ctx context.Context, chatModel *openai.ChatModel, outputSchema *jsonschema.Schema,
+	budget *RunBudget, messages []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	for attempt := 0; ; attempt++ {
+		msg, err := chatModel.Generate(ctx, messages, opts...)
+		if err != nil {
+			return nil, err
+		}
+		budget.AddUsage(msg)
+		_, err = outputSchema.ParseOutput(msg.Content)
+		if err == nil {
+			return msg, nil
+		}
+		if attempt >= jsonschema.MaxRepairAttempts() {
+			return msg, err
+		}
+		messages = append(messages, msg, schema.UserMessage(outputSchema.RepairInstruction(err)))
+	}
+}
+
+func (p *planExecutor) executor(name string) *ExecutorSpec {
+	for _, spec := range p.config.Executors {
+		if spec.Name == name {
+			return spec
+		}
+	}
+	return nil
+}
+
+func (p *planExecutor) taskContext(state *PlanState) string {
+	return "## 任务背景\n" + state.SystemPrompt + "\n\n## 用户需求\n" + state.UserInput
+}
+
+func (p *planExecutor) notifyProgress(step *PlanStep, total int, round int) {
+	showMsg := fmt.Sprintf("步骤 %d/%d（%s）", step.Id, total, step.Executor)
+	switch step.Status {
+	case PlanStepRunning:
+		showMsg = "开始执行" + showMsg
+	case PlanStepDone:
+		showMsg = "完成" + showMsg
+	case PlanStepFailed:
+		showMsg = "执行失败：" + showMsg
+	}
+	_ = p.callback(&global.Chunk{
+		ShowMsg: showMsg,
+		Event:   EventPlanProgress,
+		Data: PlanProgress{
+			StepId:   step.Id,
+			Total:    total,
+			Executor: step.Executor,
+			Task:     step.Task,
+			Status:   step.Status,
+			Round:    round,
+		},
+	})
+}
+
+// formatStepResults 格式化步骤结果，before 大于 0 时只包含该步骤之前已完成的步骤
+func formatStepResults(steps []*PlanStep, before int) string {
+	var sb strings.Builder
+	for _, step := range steps {
+		if before > 0 && (step.Id >= before || step.Status != PlanStepDone) {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("### 步骤 %d（%s）：%s\n", step.Id, step.Executor, step.Task))
+		sb.WriteString(fmt.Sprintf("状态：%s\n%s\n\n", step.Status, step.Result))
+	}
+	return sb.String()
+}
+
+// filterTools 按名称筛选工具，支持通配符
+func filterTools(ctx context.Context, tools []tool.BaseTool, patterns []string) []tool.BaseTool {
+	filtered := make([]tool.BaseTool, 0)
+	if len(patterns) == 0 {
+		return filtered
+	}
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			log.Error("Failed to get tool info", zap.Error(err))
+			continue
+		}
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, info.Name); matched {
+				filtered = append(filtered, t)
+				break
+			}
+		}
+	}
+	return filtered
+}
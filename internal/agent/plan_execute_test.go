@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"txing-ai/internal/global"
+)
+
+// fakeChatModel 兼容 OpenAI 接口的假模型，按系统提示词判断调用方返回预设的结果
+type fakeChatModel struct {
+	t      *testing.T
+	server *httptest.Server
+	reply  func(system string, user string) string
+
+	mu sync.Mutex
+	// 每次调用的用户消息，按系统提示词记录
+	inputs map[string][]string
+}
+
+func newFakeChatModel(t *testing.T, reply func(system string, user string) string) *fakeChatModel {
+	m := &fakeChatModel{t: t, reply: reply, inputs: make(map[string][]string)}
+	m.server = httptest.NewServer(http.HandlerFunc(m.handle))
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *fakeChatModel) handle(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		m.t.Errorf("decode chat request failed: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var system, user string
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			system = msg.Content
+		case "user":
+			user = msg.Content
+		}
+	}
+
+	m.mu.Lock()
+	m.inputs[system] = append(m.inputs[system], user)
+	m.mu.Unlock()
+	content := m.reply(system, user)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": 1,
+		"model":   "fake",
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 10, "total_tokens": 20},
+	})
+}
+
+func (m *fakeChatModel) config() *openai.ChatModelConfig {
+	return &openai.ChatModelConfig{BaseURL: m.server.URL, APIKey: "test", Model: "fake"}
+}
+
+var testPlanExecuteConfig = &PlanExecuteConfig{
+	Executors: []*ExecutorSpec{
+		{Name: "maps", Description: "地图专家", SystemPrompt: "你是地图信息专家"},
+		{Name: "search", Description: "资料搜索专家", SystemPrompt: "你是资料搜索专家"},
+	},
+	Assembler: &ExecutorSpec{Name: "writer", SystemPrompt: "你是攻略写作专家"},
+	MaxSteps:  4,
+	// 评审不通过时最多重新执行一轮
+	MaxReviewRounds: 1,
+}
+
+// runPlanExecute 运行规划-执行图，返回最终输出和推送的事件
+func runPlanExecute(t *testing.T, chatModel *fakeChatModel) (*schema.Message, []*global.Chunk) {
+	t.Helper()
+	var mu sync.Mutex
+	var chunks []*global.Chunk
+	callback := func(chunk *global.Chunk) error {
+		mu.Lock()
+		chunks = append(chunks, chunk)
+		mu.Unlock()
+		return nil
+	}
+
+	ctx := context.Background()
+	graph, err := newPlanExecuteGraph(ctx, chatModel.config(), nil, nil, FinalOutputSchema, newRunBudget(nil),
+		testPlanExecuteConfig, callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runnable, err := graph.Compile(ctx, compose.WithMaxRunSteps(defaultMaxRunSteps))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := runnable.Invoke(ctx, []*schema.Message{
+		schema.SystemMessage("你是旅游攻略生成专家"),
+		schema.UserMessage("成都三日游"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output, chunks
+}
+
+// planReply 规划出地图和搜索两个步骤，执行器按是否带评审意见返回不同的结果
+func planReply(review func(round int) string) func(system string, user string) string {
+	var mu sync.Mutex
+	round := 0
+	return func(system string, user string) string {
+		switch {
+		case strings.Contains(system, "任务规划专家"):
+			return `{"steps":[{"executor":"maps","task":"查询宽窄巷子到熊猫基地的距离"},{"executor":"search","task":"搜索熊猫基地资料"}]}`
+		case strings.Contains(system, "评审专家"):
+			mu.Lock()
+			defer mu.Unlock()
+			round++
+			return review(round)
+		case strings.Contains(system, "地图信息专家"):
+			if strings.Contains(user, "评审意见") {
+				return "宽窄巷子 -> 熊猫基地，距离 15 公里，推荐打车，用时 30 分钟"
+			}
+			return "宽窄巷子 -> 熊猫基地"
+		case strings.Contains(system, "资料搜索专家"):
+			return "熊猫基地早上开园，建议 8 点前到达"
+		case strings.Contains(system, "攻略写作专家"):
+			return "```json\n{\"content\":\"成都三日游攻略已生成\",\"files\":[{\"name\":\"成都攻略.pdf\"}]}\n```"
+		}
+		return "unknown"
+	}
+}
+
+func eventsOf(chunks []*global.Chunk, event string) []*global.Chunk {
+	var matched []*global.Chunk
+	for _, chunk := range chunks {
+		if chunk.Event == event {
+			matched = append(matched, chunk)
+		}
+	}
+	return matched
+}
+
+func TestPlanExecuteGraph(t *testing.T) {
+	chatModel := newFakeChatModel(t, planReply(func(round int) string {
+		if round == 1 {
+			return `{"approved":false,"feedback":"缺少距离和用时","redo":[{"id":1,"feedback":"补充距离、交通方式和用时"}]}`
+		}
+		return `{"approved":true,"feedback":"","redo":[]}`
+	}))
+	output, chunks := runPlanExecute(t, chatModel)
+
+	result, _, err := ParseFinalOutput(FinalOutputSchema, output.Content)
+	if err != nil || result.Content != "成都三日游攻略已生成" || len(result.Files) != 1 {
+		t.Fatalf("final output = %q, err = %v", output.Content, err)
+	}
+
+	plans := eventsOf(chunks, EventPlan)
+	if len(plans) != 1 || len(plans[0].Data.([]*PlanStep)) != 2 {
+		t.Fatalf("plan events = %+v", plans)
+	}
+
+	// 第一轮评审不通过，只重新执行被打回的地图步骤，第二轮评审通过
+	reviews := eventsOf(chunks, EventPlanReview)
+	if len(reviews) != 2 {
+		t.Fatalf("review events = %d, want 2", len(reviews))
+	}
+	first, second := reviews[0].Data.(PlanReview), reviews[1].Data.(PlanReview)
+	if first.Approved || first.Round != 1 || len(first.Redo) != 1 || first.Redo[0].Id != 1 {
+		t.Errorf("first review = %+v", first)
+	}
+	if !second.Approved || second.Round != 2 {
+		t.Errorf("second review = %+v", second)
+	}
+
+	executed := map[string]int{}
+	for _, chunk := range eventsOf(chunks, EventPlanProgress) {
+		progress := chunk.Data.(PlanProgress)
+		if progress.Status == PlanStepDone {
+			executed[progress.Executor]++
+		}
+	}
+	if executed["maps"] != 2 || executed["search"] != 1 {
+		t.Errorf("executed steps = %v, want maps twice and search once", executed)
+	}
+
+	// 汇总时使用重新执行后的结果
+	for system, inputs := range chatModel.inputs {
+		if strings.Contains(system, "攻略写作专家") {
+			if len(inputs) != 1 || !strings.Contains(inputs[0], "距离 15 公里") {
+				t.Errorf("assembler inputs = %q", inputs)
+			}
+			return
+		}
+	}
+	t.Error("assembler should be called")
+}
+
+func TestPlanExecuteGraph_MaxReviewRounds(t *testing.T) {
+	var reviewRounds int
+	chatModel := newFakeChatModel(t, planReply(func(round int) string {
+		reviewRounds = round
+		return `{"approved":false,"feedback":"仍然缺少信息","redo":[{"id":1,"feedback":"补充距离"},{"id":2,"feedback":"补充门票价格"}]}`
+	}))
+	output, chunks := runPlanExecute(t, chatModel)
+
+	// 评审一直不通过时，达到最大评审轮数后直接汇总
+	if _, _, err := ParseFinalOutput(FinalOutputSchema, output.Content); err != nil {
+		t.Fatalf("final output = %q, err = %v", output.Content, err)
+	}
+	if reviewRounds != testPlanExecuteConfig.MaxReviewRounds+1 {
+		t.Errorf("review rounds = %d, want %d", reviewRounds, testPlanExecuteConfig.MaxReviewRounds+1)
+	}
+	for _, chunk := range eventsOf(chunks, EventPlanReview) {
+		if chunk.Data.(PlanReview).Approved {
+			t.Errorf("review should not be approved: %+v", chunk.Data)
+		}
+	}
+	executed := 0
+	for _, chunk := range eventsOf(chunks, EventPlanProgress) {
+		if progress := chunk.Data.(PlanProgress); progress.Status == PlanStepDone && progress.Executor != "writer" {
+			executed++
+		}
+	}
+	// 两个步骤各执行两次，最后一轮评审不通过时不再重新执行
+	if executed != 4 {
+		t.Errorf("executed steps = %d, want 4", executed)
+	}
+}
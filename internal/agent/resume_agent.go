@@ -62,11 +62,13 @@ func NewResumeAgent(res iface.ResourceProvider) *ResumeAgent {
 ## 输出格式与约束
 
 ### 最终简历
-将优化内容保存为PDF文件（你可以先以Markdown格式生成内容（无需保存），然后使用此内容作为参数调用"markdown to pdf tool"工具将其保存为PDF文档。你只需保存一个PDF文档，不允许生成其他格式的文档，如md或txt）。
+将优化内容保存为PDF文件（你可以先以Markdown格式生成内容（无需保存），然后使用此内容作为参数调用"markdown to pdf tool"工具将其保存为PDF文档，template 参数使用 resume。你只需保存一个PDF文档，不允许生成其他格式的文档，如md或txt）。
+如果用户需要可编辑的 Word 简历，再使用相同的内容调用 markdown_to_docx_file_tool 工具保存一份 Word 文档，并同时放到 files 字段中。
 
 ### 最后输出
-提供所做主要更改的简要摘要（不需要带上“总结”等字样），解释它们如何与职位描述保持一致。同时在最后一行给出保存的 PDF 文件，格式示例为："文件：优化简历_lzw_腾讯后台开发工程师.pdf"
+在 content 字段中提供所做主要更改的简要摘要（不需要带上“总结”等字样），解释它们如何与职位描述保持一致。同时在 files 字段中给出保存的 PDF 文件，文件名取 markdown_to_pdf_file_tool 工具返回结果中的文件名，如：优化简历_lzw_腾讯后台开发工程师.pdf
 `)
+	a.SetOutputSchema(FinalOutputSchema)
 	return a
 }
 
@@ -74,7 +76,7 @@ func NewResumeAgent(res iface.ResourceProvider) *ResumeAgent {
 func (a *ResumeAgent) Execute(ctx context.Context,
 	endpoint string, apiKey string, model string, input string) (string, error) {
 
-	text, err1 := tool.ReadPdfText(ctx, &tool.PdfReadParams{
+	text, err1 := tool.ReadDocumentText(ctx, &tool.DocumentReadParams{
 		FilePath: input,
 	})
 	if err1 != nil {
@@ -92,7 +94,7 @@ func (a *ResumeAgent) Execute(ctx context.Context,
 func (a *ResumeAgent) ExecuteStream(ctx context.Context, endpoint string, apiKey string, model string,
 	input string, filePath string, callback func(chunk *global.Chunk) error) (string, error) {
 
-	text, err1 := tool.ReadPdfText(ctx, &tool.PdfReadParams{
+	text, err1 := tool.ReadDocumentText(ctx, &tool.DocumentReadParams{
 		FilePath: filePath,
 	})
 	if err1 != nil {
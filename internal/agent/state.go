@@ -5,4 +5,8 @@ import "github.com/cloudwego/eino/schema"
 // 在状态中维护消息历史
 type AgentState struct {
 	Messages []*schema.Message
+	// 结构化输出已修正的次数
+	RepairAttempts int
+	// 模型调用轮数
+	ModelCalls int
 }
@@ -5,20 +5,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cloudwego/eino-ext/components/model/openai"
+	einomodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 	"go.uber.org/zap"
+	"sync"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
 	"txing-ai/internal/iface"
 	mytool "txing-ai/internal/tool"
+	"txing-ai/internal/utils/jsonschema"
 )
 
 // ToolCallAgent 通用智能体实现
 type ToolCallAgent struct {
 	*BaseAgent
-	tools []tool.BaseTool
+	res iface.ResourceProvider
+	// 替换的工具，为空时每次运行使用当前可用的工具
+	tools       []tool.BaseTool
+	approvals   *ApprovalManager
+	planExecute *PlanExecuteConfig
 }
 
 // NewToolCallAgent 创建一个新的通用智能体
@@ -29,25 +36,49 @@ func NewToolCallAgent(res iface.ResourceProvider) *ToolCallAgent {
 
 	return &ToolCallAgent{
 		BaseAgent: baseAgent,
-		tools:     mytool.ProvideTools(res),
+		res:       res,
 	}
 }
 
+// SetApprovalManager 设置工具调用审批管理器
+func (a *ToolCallAgent) SetApprovalManager(approvals *ApprovalManager) {
+	a.approvals = approvals
+}
+
+// Tools 获取智能体可用的工具，MCP 工具可能随服务器重连或配置修改而变化
+func (a *ToolCallAgent) Tools() []tool.BaseTool {
+	if a.tools != nil {
+		return a.tools
+	}
+	return mytool.ProvideTools(a.res)
+}
+
+// SetTools 替换智能体可用的工具，例如评测时替换为录制回放的工具
+func (a *ToolCallAgent) SetTools(tools []tool.BaseTool) {
+	a.tools = tools
+}
+
+// SetPlanExecute 启用规划-执行多智能体编排，为空则使用单智能体的工具调用循环
+func (a *ToolCallAgent) SetPlanExecute(config *PlanExecuteConfig) {
+	a.planExecute = config
+}
+
 // Execute 执行通用智能体任务
 func (a *ToolCallAgent) Execute(ctx context.Context,
 	endpoint string, apiKey string, model string, input string) (string, error) {
 
-	chatModel, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
+	// 每次运行使用独立的工具上下文和工作区，运行结束后删除工作区
+	ctx, finishRun := mytool.StartRun(ctx)
+	defer finishRun()
+	// 非流式调用无法推送审批请求，需要审批的工具直接拒绝，不等待超时
+	ctx = withoutApproval(ctx)
+
+	// 创建一个包含工具的执行图
+	graph, err := a.buildGraph(context.Background(), &openai.ChatModelConfig{
 		BaseURL: endpoint,
 		Model:   model, // 使用的模型版本
 		APIKey:  apiKey,
-	})
-	if err != nil {
-		return "", fmt.Errorf("Failed to create chat model: %w", err)
-	}
-
-	// 创建一个包含工具的执行图
-	graph, err := newGraph(context.Background(), chatModel, a.tools, func(chunk *global.Chunk) error {
+	}, func(chunk *global.Chunk) error {
 		// 不需要处理chunk
 		return nil
 	})
@@ -68,20 +99,22 @@ func (a *ToolCallAgent) Execute(ctx context.Context,
 func (a *ToolCallAgent) ExecuteStream(ctx context.Context, endpoint string, apiKey string, model string,
 	input string, filePath string, callback func(chunk *global.Chunk) error) (string, error) {
 
+	// 每次运行使用独立的工具上下文和工作区，运行结束后删除工作区
+	ctx, finishRun := mytool.StartRun(ctx)
+	defer finishRun()
+
+	// 同一轮的工具调用并行执行，审批等事件会在多个工具的协程中推送，回调需要串行调用
+	callback = serializeCallback(callback)
+
 	// 设置 LLM 响应最大 token 数量，一些模型（例如 DeepSeek v3）默认是 4k，这里上调到 8k，否则最终生成的结果可能会超长导致被截断
 	maxTokens := 8192
-	chatModel, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
+	// 创建一个包含工具的执行图
+	graph, err := a.buildGraph(ctx, &openai.ChatModelConfig{
 		BaseURL:   endpoint,
 		Model:     model, // 使用的模型版本
 		APIKey:    apiKey,
 		MaxTokens: &maxTokens,
-	})
-	if err != nil {
-		return "", fmt.Errorf("Failed to create chat model: %w", err)
-	}
-
-	// 创建一个包含工具的执行图
-	graph, err := newGraph(ctx, chatModel, a.tools, callback)
+	}, callback)
 	if err != nil {
 		log.Error("Failed to create graph", zap.Error(err))
 		return "", err
@@ -91,13 +124,89 @@ func (a *ToolCallAgent) ExecuteStream(ctx context.Context, endpoint string, apiK
 	return a.BaseAgent.ExecuteStream(ctx, endpoint, apiKey, model, input, filePath, callback)
 }
 
-func newGraph(ctx context.Context, model *openai.ChatModel, tools []tool.BaseTool,
+// serializeCallback 包装回调，保证同一时间只有一个协程调用
+// 回调通常直接写 HTTP 响应（例如 SSE），并发写入会导致消息交错
+func serializeCallback(callback func(chunk *global.Chunk) error) func(chunk *global.Chunk) error {
+	var mu sync.Mutex
+	return func(chunk *global.Chunk) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return callback(chunk)
+	}
+}
+
+// buildGraph 根据是否启用规划-执行模式创建对应的执行图
+func (a *ToolCallAgent) buildGraph(ctx context.Context, modelConfig *openai.ChatModelConfig,
 	callback func(chunk *global.Chunk) error) (*compose.Graph[[]*schema.Message, *schema.Message], error) {
+	// 每次运行使用独立的预算和当前可用的工具
+	budget := NewRunBudget()
+	tools := a.Tools()
+	if a.planExecute != nil {
+		return newPlanExecuteGraph(ctx, modelConfig, tools, a.approvals, a.outputSchema, budget, a.planExecute, callback)
+	}
 
-	// 创建工具节点时确保正确配置
+	chatModel, err := openai.NewChatModel(ctx, modelConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create chat model: %w", err)
+	}
+	return newGraph(ctx, chatModel, &toolCallGraphConfig{
+		tools:        tools,
+		approvals:    a.approvals,
+		outputSchema: a.outputSchema,
+		budget:       budget,
+		maxRunSteps:  defaultMaxRunSteps,
+	}, callback)
+}
+
+// toolCallGraphConfig 工具调用执行图的配置
+type toolCallGraphConfig struct {
+	tools     []tool.BaseTool
+	approvals *ApprovalManager
+	// 最终输出需要满足的 JSON Schema，为空表示不校验
+	outputSchema *jsonschema.Schema
+	// 运行预算，同一次运行的所有执行图共享
+	budget *RunBudget
+	// 执行图编译时设置的最大步数，模型调用轮数接近上限时提前结束并总结
+	maxRunSteps int
+}
+
+// maxModelCalls 最大模型调用轮数，每轮包括模型节点和工具节点两步，另外预留总结的步数
+func (c *toolCallGraphConfig) maxModelCalls() int {
+	return (c.maxRunSteps - 2) / 2
+}
+
+// exceeded 判断是否达到运行限制，返回原因，未达到时返回空字符串
+func (c *toolCallGraphConfig) exceeded(ctx context.Context) string {
+	if reason := c.budget.Exceeded(); reason != "" {
+		return reason
+	}
+	if c.maxRunSteps <= 0 {
+		return ""
+	}
+	modelCalls := 0
+	_ = compose.ProcessState[*AgentState](ctx, func(ctx context.Context, state *AgentState) error {
+		modelCalls = state.ModelCalls
+		return nil
+	})
+	if modelCalls >= c.maxModelCalls() {
+		return fmt.Sprintf("模型调用轮数达到上限 %d 轮", c.maxModelCalls())
+	}
+	return ""
+}
+
+func newGraph(ctx context.Context, model *openai.ChatModel, conf *toolCallGraphConfig,
+	callback func(chunk *global.Chunk) error) (*compose.Graph[[]*schema.Message, *schema.Message], error) {
+	outputSchema := conf.outputSchema
+
+	// 为工具增加超时、重试、并发限制和预算控制
+	tools := wrapToolsWithLimits(ctx, conf.tools, newToolRuntime(conf.budget, global.LoadConfig().AgentConfig))
+	// 按工具调用策略包装工具，需要审批的工具在执行前会暂停等待用户确认（等待审批的时间不计入工具超时）
+	tools = wrapToolsWithApproval(ctx, tools, conf.approvals, callback)
+
+	// 创建工具节点，同一轮中的多个工具调用并行执行
 	todoToolsNode, err := compose.NewToolNode(ctx, &compose.ToolsNodeConfig{
 		Tools:               tools,
-		ExecuteSequentially: true,
+		ExecuteSequentially: false,
 	})
 	if err != nil {
 		log.Error("Failed to create tool node", zap.Error(err))
@@ -146,9 +255,16 @@ func newGraph(ctx context.Context, model *openai.ChatModel, tools []tool.BaseToo
 			}
 		}
 		state.Messages = append(state.Messages, input...)
+		state.ModelCalls++
 		return state.Messages, nil
 	}
 
+	// 累计模型消耗的 token
+	modelPostHandle := func(ctx context.Context, output *schema.Message, state *AgentState) (*schema.Message, error) {
+		conf.budget.AddUsage(output)
+		return output, nil
+	}
+
 	// 工具节点的预处理器，接收单个消息并添加到状态中
 	toolsPreHandle := func(ctx context.Context, input *schema.Message, state *AgentState) (*schema.Message, error) {
 		// 打印工具调用信息
@@ -208,7 +324,54 @@ func newGraph(ctx context.Context, model *openai.ChatModel, tools []tool.BaseToo
 		}, nil
 	})
 
-	_ = graph.AddChatModelNode("model", model, compose.WithStatePreHandler(modelPreHandle))
+	// 结构化输出校验失败时，带上错误信息要求模型修正
+	outputRepairPre := func(ctx context.Context, in *schema.Message, state *AgentState) (*schema.Message, error) {
+		state.Messages = append(state.Messages, in)
+		state.RepairAttempts++
+		return in, nil
+	}
+
+	outputRepair := compose.InvokableLambda(func(ctx context.Context, input *schema.Message) ([]*schema.Message, error) {
+		_, err := outputSchema.ParseOutput(input.Content)
+		if err == nil {
+			err = fmt.Errorf("输出格式不正确")
+		}
+		return []*schema.Message{schema.UserMessage(outputSchema.RepairInstruction(err))}, nil
+	})
+
+	// 达到运行限制时，不再调用工具，根据已有信息直接给出结果
+	budgetSummary := compose.InvokableLambda(func(ctx context.Context, input *schema.Message) (*schema.Message, error) {
+		reason := conf.exceeded(ctx)
+		log.Info("agent run limit reached, summarize", zap.String("reason", reason))
+		callback(&global.Chunk{
+			ShowMsg: "已达到运行限制（" + reason + "），正在根据已有信息生成结果",
+			Event:   EventBudgetExceeded,
+			Data:    map[string]string{"reason": reason},
+		})
+
+		var messages []*schema.Message
+		_ = compose.ProcessState[*AgentState](ctx, func(ctx context.Context, state *AgentState) error {
+			messages = append(messages, state.Messages...)
+			return nil
+		})
+		messages = append(messages, schema.UserMessage(fmt.Sprintf(budgetSummaryPrompt, reason)))
+
+		forbidTools := einomodel.WithToolChoice(schema.ToolChoiceForbidden)
+		var msg *schema.Message
+		var err error
+		if outputSchema != nil {
+			msg, err = generateValid(ctx, model, outputSchema, conf.budget, messages, forbidTools)
+		} else {
+			msg, err = model.Generate(ctx, messages, forbidTools)
+			conf.budget.AddUsage(msg)
+		}
+		return msg, err
+	})
+
+	_ = graph.AddChatModelNode("model", model, compose.WithStatePreHandler(modelPreHandle),
+		compose.WithStatePostHandler(modelPostHandle))
+	_ = graph.AddLambdaNode("budget_summary", budgetSummary)
+	_ = graph.AddLambdaNode("output_repair", outputRepair, compose.WithStatePreHandler(outputRepairPre))
 	_ = graph.AddToolsNode("tools", todoToolsNode, compose.WithStatePreHandler(toolsPreHandle))
 	_ = graph.AddLambdaNode("tool_invoke_param_error_handle", toolInvokeParamErrorHandle,
 		compose.WithStatePreHandler(toolInvokeParamErrorHandlePre))
@@ -222,6 +385,11 @@ func newGraph(ctx context.Context, model *openai.ChatModel, tools []tool.BaseToo
 			return "", err
 		}
 		if msg.ToolCalls != nil && len(msg.ToolCalls) > 0 {
+			// 达到运行限制时，不再执行工具调用，转到总结
+			if reason := conf.exceeded(ctx); reason != "" {
+				log.Info("GO TO budget_summary", zap.String("reason", reason))
+				return "budget_summary", nil
+			}
 			//countLimit += 1
 			//if countLimit >= 20 {
 			//	return compose.END, nil // 超过10个工具调用，结束
@@ -244,6 +412,11 @@ func newGraph(ctx context.Context, model *openai.ChatModel, tools []tool.BaseToo
 			log.Info("GO TO tools")
 			return "tools", nil // 如果包含工具调用，转到tools节点
 		}
+		// 需要结构化输出时，校验最终结果，不符合 schema 则要求模型修正
+		if outputSchema != nil && conf.exceeded(ctx) == "" && needOutputRepair(ctx, outputSchema, msg, in) {
+			log.Info("GO TO output_repair")
+			return "output_repair", nil
+		}
 		log.Info("GO TO END")
 		return compose.END, nil // 否则结束
 	}
@@ -254,9 +427,45 @@ func newGraph(ctx context.Context, model *openai.ChatModel, tools []tool.BaseToo
 	_ = graph.AddBranch("model", compose.NewStreamGraphBranch[*schema.Message](modelPostBranchCondition, map[string]bool{
 		"tool_invoke_param_error_handle": true,
 		"tools":                          true,
+		"output_repair":                  true,
+		"budget_summary":                 true,
 		compose.END:                      true,
 	}))
 	_ = graph.AddEdge("tools", "model") // 工具执行结果直接反馈给模型，形成循环
 	_ = graph.AddEdge("tool_invoke_param_error_handle", "model")
+	_ = graph.AddEdge("output_repair", "model")
+	_ = graph.AddEdge("budget_summary", compose.END)
 	return graph, nil
 }
+
+// needOutputRepair 判断模型的最终输出是否需要修正，超过最大修正次数后不再修正
+func needOutputRepair(ctx context.Context, outputSchema *jsonschema.Schema, first *schema.Message,
+	in *schema.StreamReader[*schema.Message]) bool {
+	// 读取剩余的消息块，拼接出完整的输出
+	msgs := []*schema.Message{first}
+	for {
+		msg, err := in.Recv()
+		if err != nil {
+			break
+		}
+		msgs = append(msgs, msg)
+	}
+	msg, err := schema.ConcatMessages(msgs)
+	if err != nil {
+		log.Error("concat model output failed", zap.Error(err))
+		return false
+	}
+
+	_, err = outputSchema.ParseOutput(msg.Content)
+	if err == nil {
+		return false
+	}
+
+	repairAttempts := 0
+	_ = compose.ProcessState[*AgentState](ctx, func(ctx context.Context, state *AgentState) error {
+		repairAttempts = state.RepairAttempts
+		return nil
+	})
+	log.Info("structured output validation failed", zap.Int("repairAttempts", repairAttempts), zap.Error(err))
+	return repairAttempts < jsonschema.MaxRepairAttempts()
+}
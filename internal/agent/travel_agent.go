@@ -112,15 +112,16 @@ C -> D
 - 总体篇幅适中，内容全面但不冗余
 
 ## 输出格式
-将生成的旅游攻略保存为PDF文件（使用"markdown_to_pdf_file_tool"工具将最终内容保存为PDF文档，注意要嵌入图片，内容中的图片支持网络链接，所以无需单独调用图片下载工具）。
+将生成的旅游攻略保存为PDF文件（使用"markdown_to_pdf_file_tool"工具将最终内容保存为PDF文档，template 参数使用 travel，注意要嵌入图片，内容中的图片支持网络链接，所以无需单独调用图片下载工具）。
+预算明细表格同时使用"xlsx_file_tool"工具保存为Excel文件，方便用户修改。
 
 ## 最后输出
-提供一个简短的总结，说明攻略的主要特点和亮点。在最后一行给出保存的PDF文件名（最后以文件后缀名结尾，不得包含其他字符，如空格、**等），格式："文件：<文件名>"，其中文件名取 markdown_to_pdf_file_tool 工具返回结果中的文件名。
+在 content 字段中提供一个简短的总结，说明攻略的主要特点和亮点。在 files 字段中给出保存的PDF文件名（最后以文件后缀名结尾，不得包含其他字符，如空格、**等），其中文件名取 markdown_to_pdf_file_tool 工具返回结果中的文件名，如果保存了Excel文件也一并给出。
 
 注意：
 1. 图片一定要丰富，一个景点至少配 5 张图片以上！！！
 2. 发起工具调用应该在 ToolName、ToolCallID、ToolCalls 字段带上调用信息，而不是在 Content 字段中
-3. maps 相关工具一批次最多 3 个调用，超过 3 个调用需要分批调用，否则会超出频率限制导致失败
+3. maps 相关工具可以在一批次中并行调用多个，系统会自动控制调用频率
 
 ## 推荐执行流程
 1. 通过网络搜索或 maps 相关工具查询到景点或路线信息，完成每天路线的规划
@@ -131,9 +132,41 @@ C -> D
 6. 使用 Markdown to PDF 工具将 Markdown 文档转换为 PDF 文档
 7. 完成总结
 `)
+	a.SetOutputSchema(FinalOutputSchema)
+	if agentConfig := global.LoadConfig().AgentConfig; agentConfig != nil && agentConfig.PlanExecute {
+		a.SetPlanExecute(travelPlanExecuteConfig)
+	}
 	return a
 }
 
+// travelPlanExecuteConfig 旅游攻略的规划-执行编排：地图、搜索子智能体收集信息，写作子智能体汇总生成攻略
+var travelPlanExecuteConfig = &PlanExecuteConfig{
+	Executors: []*ExecutorSpec{
+		{
+			Name:        "maps",
+			Description: "地图专家，查询景点位置、经纬度、周边、天气，以及景点之间的交通方式、距离和用时",
+			SystemPrompt: `你是地图信息专家，使用 maps 相关工具完成查询任务，输出准确、结构化的查询结果（包括名称、地址、坐标、交通方式、距离、用时等）。
+注意：maps 相关工具可以在一批次中并行调用多个，系统会自动控制调用频率。`,
+			Tools: []string{"maps_*"},
+		},
+		{
+			Name:        "search",
+			Description: "资料搜索专家，搜索景点介绍、美食、住宿、文化体验、注意事项等资料，以及相关的图片链接",
+			SystemPrompt: `你是旅游资料搜索专家，使用网络搜索、网页抓取和图片搜索工具收集资料，输出整理后的要点，并附上相关的图片链接（每个景点至少 5 张）。`,
+			Tools: []string{"web_search_tool", "web_scraping_tool", "image_search_tool"},
+		},
+	},
+	Assembler: &ExecutorSpec{
+		Name: "writer",
+		SystemPrompt: `你是旅游攻略写作专家，根据各步骤收集到的信息撰写完整的旅游攻略（Markdown 格式，嵌入图片链接），
+然后调用 markdown_to_pdf_file_tool 工具保存为 PDF 文档（template 参数使用 travel），
+并将预算明细表格调用 xlsx_file_tool 工具保存为 Excel 文件，方便用户修改。以下是攻略的具体要求：`,
+		Tools: []string{"markdown_to_pdf_file_tool", "xlsx_file_tool"},
+	},
+	MaxSteps:        8,
+	MaxReviewRounds: 1,
+}
+
 // Execute 执行旅游攻略生成任务
 func (a *TravelAgent) Execute(ctx context.Context,
 	endpoint string, apiKey string, model string, input string) (string, error) {
@@ -7,12 +7,18 @@ import (
 	"go.uber.org/zap"
 	"net/http"
 	"txing-ai/internal/agent"
+	"txing-ai/internal/controller/mcpserver"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/config"
 	"txing-ai/internal/global/logging/log"
 	"txing-ai/internal/iface"
 	"txing-ai/internal/middleware"
 	"txing-ai/internal/route"
+	fileservice "txing-ai/internal/service/file"
+	knowledgeservice "txing-ai/internal/service/knowledge"
+	mcpservice "txing-ai/internal/service/mcp"
+	"txing-ai/internal/storage"
+	"txing-ai/internal/tool"
 	"txing-ai/internal/tool/mcp"
 	"txing-ai/internal/utils"
 	"txing-ai/internal/utils/captcha"
@@ -69,13 +75,21 @@ func New(ctx context.Context, appConfig *global.AppConfig) Server {
 	// 初始化 Redis
 	redisClient := config.NewRedisClient(appConfig.RedisConfig, ctx)
 
-	mcpClientManager := mcp.NewMCPClientManager(ctx)
-
-	// TODO 支持配置路径 暂时先用默认路径
-	err := mcpClientManager.Init("")
+	// 初始化 MCP 客户端，连接失败的服务器会在后台自动重连，配置文件修改后自动重新加载
+	mcpConfig := appConfig.MCPConfig
+	mcpConfigPath := ""
+	if mcpConfig != nil {
+		mcpConfigPath = mcpConfig.ConfigPath
+	}
+	mcpClientManager := mcp.NewMCPClientManager(ctx, mcpConfig)
+	err := mcpClientManager.Init(mcpConfigPath)
 	if err != nil {
 		log.Error("mcpClientManager init error", zap.Error(err))
 	}
+	// 加载管理后台配置的 MCP 服务器
+	if err := mcpservice.SyncMCPServers(db, mcpClientManager); err != nil {
+		log.Error("sync mcp servers error", zap.Error(err))
+	}
 
 	// 初始化资源提供者
 	resProvider := NewResourceProvider(redisClient, db, mcpClientManager)
@@ -86,6 +100,10 @@ func New(ctx context.Context, appConfig *global.AppConfig) Server {
 	// 初始化 captcha 验证码 store
 	captcha.InitStore(redisClient)
 
+	// 初始化网页搜索和网页抓取，搜索结果和网页正文缓存到 Redis
+	tool.InitWebSearch(redisClient)
+	tool.InitWebScraping(redisClient)
+
 	// 初始化 COS 客户端
 	cosClient, err := utils.NewCOSClient(appConfig.CosConfig)
 	if err != nil {
@@ -93,12 +111,45 @@ func New(ctx context.Context, appConfig *global.AppConfig) Server {
 		panic(err)
 	}
 
-	factory := agent.NewSimpleAgentFactory(resProvider)
+	// 初始化文件存储，上传的文件和工具生成的文件都保存到这里
+	blobStores, err := storage.NewStoresFromConfig(appConfig)
+	if err != nil {
+		log.Error("blob store init error", zap.Error(err))
+		panic(err)
+	}
+
+	// 初始化上传文件的大小限制、用户存储配额和病毒扫描
+	fileservice.InitUpload(appConfig)
+
+	// 按保留策略定期清理文件，没有配置 COS 时不清理直传的对象
+	if appConfig.RetentionConfig != nil && appConfig.RetentionConfig.Enabled {
+		janitorCOS := cosClient
+		if appConfig.CosConfig == nil || appConfig.CosConfig.BaseURL == "" || appConfig.CosConfig.AccessKey == "" {
+			janitorCOS = nil
+		}
+		fileservice.NewJanitor(db, blobStores, janitorCOS, appConfig.RetentionConfig).Start(ctx)
+	}
+
+	// 初始化知识库服务，后台解析和向量化上传到知识库的文档
+	knowledge, err := knowledgeservice.NewService(db, blobStores, appConfig.KnowledgeConfig)
+	if err != nil {
+		log.Error("knowledge service init error", zap.Error(err))
+		panic(err)
+	}
+	knowledge.Start(ctx)
+
+	// 工具调用审批管理器
+	approvalManager := agent.NewApprovalManager()
+
+	factory := agent.NewSimpleAgentFactory(resProvider, approvalManager)
 
 	// 注册全局中间（局部中间件在具体的路由处注册）
-	middleware.RegisterMiddleware(engine, db, redisClient, cosClient, factory)
+	middleware.RegisterMiddleware(engine, db, redisClient, cosClient, factory, approvalManager, mcpClientManager, blobStores, knowledge)
+	// 对外提供的 MCP 服务
+	mcpServer := mcpserver.New(factory, approvalManager)
+
 	// 注册路由
-	route.Register(engine, resProvider)
+	route.Register(engine, resProvider, mcpServer)
 
 	// 获取启动端口
 	// 定义一个命令行参数，默认值为 8080，描述为 "port to listen on"
@@ -3,18 +3,24 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
-	"net/url"
-	"strings"
 	"txing-ai/internal/agent"
+	"txing-ai/internal/domain"
 	"txing-ai/internal/dto"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
 	"txing-ai/internal/service/channel"
+	fileservice "txing-ai/internal/service/file"
+	mcpservice "txing-ai/internal/service/mcp"
+	"txing-ai/internal/storage"
+	mytool "txing-ai/internal/tool"
+	"txing-ai/internal/tool/mcp"
 	"txing-ai/internal/utils"
+	"txing-ai/internal/vo"
 )
 
 const exceedUseLimit = "您今日的使用次数已达上限（%d次），请明天再来尝试啦！"
@@ -72,8 +78,19 @@ func Exec(ctx *gin.Context) {
 		utils.ErrorWithMsg(ctx, "选择渠道失败", err)
 		return
 	}
+	// 读取作为上下文的MCP资源
+	mcpClientManager := utils.GetMCPClientManagerFromContext[*mcp.MCPClientManager](ctx)
+	resourceContext, err := mcpservice.BuildResourceContext(ctx, mcpClientManager, req.Resources, utils.GetIsAdminFromContext(ctx))
+	if err != nil {
+		log.Error("build mcp resource context failed", zap.Error(err))
+		utils.ErrorWithMsg(ctx, err.Error(), err)
+		return
+	}
 	// 获取请求中的内容
 	content := req.Content
+	if resourceContext != "" {
+		content += "\n\n" + resourceContext
+	}
 	// 执行智能体，传入上下文、渠道、模型和内容
 	resp, err := agent.Execute(ctx, channel.GetEndpoint(), channel.GetRandomSecret(), mappingModel, content)
 
@@ -89,13 +106,14 @@ func Exec(ctx *gin.Context) {
 
 // ExecStream 基于 SSE 调用智能体
 // @Summary 基于 SSE 调用智能体
-// @Description 使用 Server-Sent Events 流式调用智能体
+// @Description 使用 Server-Sent Events 流式调用智能体，结束消息（end 为 true）中的 data 为结构化输出结果，files 为生成的文件ID及下载地址
 // @Tags agent
 // @Accept multipart/form-data
 // @Produce text/event-stream
 // @Param agentType formData string true "智能体类型"
 // @Param content formData string false "请求内容"
 // @Param file formData file false "上传文件"
+// @Param resources formData string false "作为上下文的MCP资源，JSON 数组，如 [{\"server\":\"docs\",\"uri\":\"docs://readme\"}]"
 // @Success 200 {object} utils.Response
 // @Router /api/agent/exec/stream [POST]
 func ExecStream(ctx *gin.Context) {
@@ -111,10 +129,17 @@ func ExecStream(ctx *gin.Context) {
 		utils.ErrorWithMsg(ctx, "智能体类型不能为空", nil)
 		return
 	}
+	if resources := ctx.PostForm("resources"); resources != "" {
+		if err := json.Unmarshal([]byte(resources), &req.Resources); err != nil {
+			utils.ErrorWithMsg(ctx, "MCP资源格式错误", err)
+			return
+		}
+	}
 
 	// 从上下文中获取智能体工厂和数据库连接
 	agentFactory := utils.GetAgentFactoryFromContext[agent.AgentFactory](ctx)
 	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	blobStores := utils.GetBlobStoresFromContext[*storage.Stores](ctx)
 	userId := utils.GetUIDFromContext(ctx)
 	messageLimiter := utils.GetMessageLimiterFromContext[*utils.MessageLimiter](ctx)
 	role := utils.GetRoleFromContext(ctx)
@@ -122,7 +147,7 @@ func ExecStream(ctx *gin.Context) {
 	// 将请求中的 AgentType 字符串转换为 AgentType 类型
 	agentType := agent.AgentType(req.AgentType)
 	// 使用智能体工厂创建指定类型的智能体实例
-	agent, err := agentFactory.CreateAgent(agentType)
+	agentInstance, err := agentFactory.CreateAgent(agentType)
 	if err != nil {
 		// 如果创建智能体失败，记录错误日志并返回错误响应
 		log.Error("create agent failed", zap.Error(err))
@@ -148,6 +173,15 @@ func ExecStream(ctx *gin.Context) {
 		return
 	}
 
+	// 读取作为上下文的MCP资源，附加到请求内容后面
+	mcpClientManager := utils.GetMCPClientManagerFromContext[*mcp.MCPClientManager](ctx)
+	resourceContext, err := mcpservice.BuildResourceContext(ctx, mcpClientManager, req.Resources, utils.GetIsAdminFromContext(ctx))
+	if err != nil {
+		log.Error("build mcp resource context failed", zap.Error(err))
+		utils.ErrorWithMsg(ctx, err.Error(), err)
+		return
+	}
+
 	// 设置 SSE 响应头
 	ctx.Writer.Header().Set("Content-Type", "text/event-stream")
 	ctx.Writer.Header().Set("Cache-Control", "no-cache")
@@ -157,6 +191,9 @@ func ExecStream(ctx *gin.Context) {
 
 	// 获取请求中的内容
 	content := req.Content
+	if resourceContext != "" {
+		content += "\n\n" + resourceContext
+	}
 
 	ctxWithCancel, cancel := context.WithCancel(ctx)
 
@@ -171,6 +208,8 @@ func ExecStream(ctx *gin.Context) {
 			"toolParams":       chunk.ToolParams,
 			"toolResult":       chunk.ToolResult,
 			"showMsg":          chunk.ShowMsg,
+			"event":            chunk.Event,
+			"data":             chunk.Data,
 			"end":              false,
 		}
 
@@ -197,17 +236,39 @@ func ExecStream(ctx *gin.Context) {
 	// 文件路径
 	filePath := ""
 	if err == nil && file != nil {
-		// 保存文件到本地
-		saveFilePath, fileSize, saveErr := utils.SaveUploadedFile(file, header.Filename, userId, "", "")
+		defer file.Close()
+		// 校验后保存文件到本地
+		saveFilePath, inspection, saveErr := fileservice.SaveLocalUpload(ctx, db, userId, agent.AttachmentPurpose(agent.AgentType(req.AgentType)), header.Filename, file)
 		if saveErr != nil {
+			if fileservice.IsRejected(saveErr) {
+				// 没有通过校验的文件直接返回错误
+				log.Warn("uploaded file rejected", zap.Int64("userId", userId), zap.String("filename", header.Filename), zap.Error(saveErr))
+				errData := map[string]interface{}{
+					"error": saveErr.Error(),
+					"end":   true,
+				}
+				jsonData, _ := json.Marshal(errData)
+				_, _ = fmt.Fprintf(ctx.Writer, "data: %s\n\n", jsonData)
+				ctx.Writer.Flush()
+				return
+			}
 			log.Error("save uploaded file failed", zap.Error(saveErr))
 		} else {
-			log.Info("file saved successfully", zap.String("path", filePath), zap.Int64("size", fileSize))
+			log.Info("file saved successfully", zap.String("path", saveFilePath), zap.Int64("size", inspection.Size),
+				zap.String("scanStatus", inspection.ScanStatus))
 			filePath = saveFilePath
+			// 登记上传的附件，登记失败不影响智能体执行
+			if _, err := fileservice.RegisterLocalUpload(ctx, db, blobStores, userId, saveFilePath, inspection, domain.FileSourceAgent, req.AgentType); err != nil {
+				log.Error("register uploaded file failed", zap.String("path", saveFilePath), zap.Error(err))
+			}
 		}
-		defer file.Close()
 	}
 
+	// 记录工具生成的文件，执行结束后按文件ID返回
+	ctxWithCancel, outputFiles := mytool.WithOutputFiles(ctxWithCancel, db, blobStores, userId)
+	// 记录结构化输出的解析结果，结束消息直接使用
+	ctxWithCancel, structured := agent.WithStructuredOutput(ctxWithCancel)
+
 	// 检查使用次数是否达到上限
 	allowed, err := messageLimiter.CheckAndIncrement(ctx, userId, role, utils.BusinessTypeResume)
 	limitMsg := ""
@@ -233,8 +294,7 @@ func ExecStream(ctx *gin.Context) {
 	}
 
 	// 执行智能体，传入上下文、渠道、模型、内容和回调函数
-	response := ""
-	response, err = agent.ExecuteStream(ctxWithCancel, channel.GetEndpoint(), channel.GetRandomSecret(), mappingModel, content, filePath, callback)
+	_, err = agentInstance.ExecuteStream(ctxWithCancel, channel.GetEndpoint(), channel.GetRandomSecret(), mappingModel, content, filePath, callback)
 	if err != nil {
 		// 如果执行智能体失败，记录错误日志
 		log.Error("execute agent stream failed", zap.Error(err))
@@ -260,30 +320,21 @@ func ExecStream(ctx *gin.Context) {
 		return
 	}
 
-	downloadURL := ""
-	// 从响应中获取文件路径
-	if response != "" {
-		// response 中包含文件的格式："文件：优化简历_lzw_腾讯后台开发工程师.pdf"
-		// 从中获取文件名，然后拼接下载路径，再更新到 response 中
-		// 下载路径 /api/file/download?filePath={文件名}
-		if strings.Contains(response, "文件：") {
-			// 提取文件名
-			filePrefix := "文件："
-			startIndex := strings.Index(response, filePrefix) + len(filePrefix)
-			fileName := strings.TrimSpace(response[startIndex:])
-			// 去掉后面可能有的 ** 符号
-			fileName = strings.Split(fileName, "**")[0]
-
-			// 拼接下载路径
-			downloadURL = fmt.Sprintf("/api/file/download?filePath=%s", url.QueryEscape(fileName))
-		}
-	}
-
-	// 发送结束消息
+	// 发送结束消息，带上本次运行生成的文件，结构化输出的智能体会带上结构化数据
 	endData := map[string]interface{}{
-		"content": downloadURL,
+		"content": "",
 		"end":     true,
 	}
+	// 结构化输出解析失败时只返回生成的文件，不影响本次运行的结果
+	if structured.Output != nil {
+		endData["data"] = structured.Data
+	}
+	files := vo.ToAgentOutputFileVOs(outputFiles.List(), structured.Output)
+	endData["files"] = files
+	// 兼容旧版前端，content 为第一个文件的下载地址
+	if len(files) > 0 {
+		endData["content"] = files[0].URL
+	}
 	jsonData, err := json.Marshal(endData)
 	if err != nil {
 		log.Error("json marshal data failed", zap.Error(err))
@@ -292,3 +343,47 @@ func ExecStream(ctx *gin.Context) {
 	_, _ = fmt.Fprintf(ctx.Writer, "data: %s\n\n", jsonData)
 	ctx.Writer.Flush()
 }
+
+// Approve 处理工具调用审批
+// @Summary 处理工具调用审批
+// @Description 智能体执行过程中遇到需要审批的工具调用时会推送 approval_request 事件并暂停，用户通过该接口批准、修改参数后批准或拒绝
+// @Tags agent
+// @Accept json
+// @Produce json
+// @Param data body dto.AgentApprovalReq true "审批信息"
+// @Success 200 {object} utils.Response
+// @Router /api/agent/approval [POST]
+func Approve(ctx *gin.Context) {
+	var req dto.AgentApprovalReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	if req.Action == string(agent.ApprovalActionEdit) && req.Arguments == "" {
+		utils.ErrorWithCodeAndMsg(ctx, global.CodeInvalidParams, "修改参数不能为空", nil)
+		return
+	}
+
+	approvalManager := utils.GetApprovalManagerFromContext[*agent.ApprovalManager](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+
+	err := approvalManager.Resolve(req.ApprovalId, userId, &agent.ApprovalDecision{
+		Action:    agent.ApprovalAction(req.Action),
+		Arguments: req.Arguments,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, agent.ErrApprovalNotFound):
+			utils.ErrorWithCodeAndMsg(ctx, global.CodeNotFound, err.Error(), err)
+		case errors.Is(err, agent.ErrApprovalForbidden):
+			utils.ErrorWithCode(ctx, global.CodeNotPermission, err)
+		default:
+			utils.ErrorWithCodeAndMsg(ctx, global.CodeInvalidParams, err.Error(), err)
+		}
+		return
+	}
+
+	utils.Ok(ctx)
+}
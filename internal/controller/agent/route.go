@@ -10,4 +10,6 @@ func Register(r *gin.RouterGroup) {
 	//r.POST("/exec", Exec)
 	// 添加基于 SSE 的智能体流式执行路由
 	r.POST("/exec/stream", middleware.AuthMiddleware(), ExecStream)
+	// 工具调用审批
+	r.POST("/approval", middleware.AuthMiddleware(), Approve)
 }
@@ -0,0 +1,185 @@
+package apikey
+
+import (
+	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/enum"
+	"txing-ai/internal/utils"
+	"txing-ai/internal/vo"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// Create 创建 API Key
+// @Summary 创建 API Key
+// @Description 为当前用户创建 API Key，用于 MCP 客户端、脚本、OpenAI 兼容接口等场景的认证，明文只在创建时返回一次
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param data body dto.CreateApiKeyReq true "API Key 信息"
+// @Success 200 {object} utils.Response{data=vo.CreateApiKeyVO}
+// @Router /api/apikey [post]
+func Create(ctx *gin.Context) {
+	var req dto.CreateApiKeyReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+
+	key, hash, prefix, err := utils.GenerateApiKey()
+	if err != nil {
+		utils.ErrorWithMsg(ctx, "生成 API Key 失败", err)
+		return
+	}
+
+	scopes := lo.Uniq(req.Scopes)
+	if len(scopes) == 0 {
+		scopes = enum.ApiKeyScopes
+	}
+
+	apiKey := &domain.ApiKey{
+		UserID:    userId,
+		Name:      req.Name,
+		KeyHash:   hash,
+		KeyPrefix: prefix,
+		Scopes:    scopes,
+	}
+	if err := db.Create(apiKey).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "创建 API Key 失败", err)
+		return
+	}
+
+	utils.OkWithData(ctx, vo.CreateApiKeyVO{
+		ApiKeyVO: vo.ToApiKeyVO(*apiKey),
+		Key:      key,
+	})
+}
+
+// List 获取 API Key 列表
+// @Summary 获取 API Key 列表
+// @Description 获取当前用户的所有 API Key
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Success 200 {object} utils.Response{data=[]vo.ApiKeyVO}
+// @Router /api/apikey/list [get]
+func List(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+
+	var apiKeys []domain.ApiKey
+	if err := db.Where("user_id = ?", userId).Order("id DESC").Find(&apiKeys).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "获取 API Key 列表失败", err)
+		return
+	}
+
+	utils.OkWithData(ctx, vo.ToApiKeyVOs(apiKeys))
+}
+
+// Update 更新 API Key
+// @Summary 更新 API Key
+// @Description 修改当前用户 API Key 的名称和权限范围，已吊销的 API Key 不能修改
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param id path int true "API Key ID"
+// @Param data body dto.UpdateApiKeyReq true "API Key 信息"
+// @Success 200 {object} utils.Response{data=vo.ApiKeyVO}
+// @Router /api/apikey/{id} [put]
+func Update(ctx *gin.Context) {
+	var req dto.UpdateApiKeyReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+
+	var apiKey domain.ApiKey
+	if err := db.Where("user_id = ?", userId).First(&apiKey, ctx.Param("id")).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "API Key 不存在", err)
+		return
+	}
+	if apiKey.Revoked() {
+		utils.ErrorWithMsg(ctx, "API Key 已吊销", nil)
+		return
+	}
+
+	if req.Name != "" {
+		apiKey.Name = req.Name
+	}
+	if len(req.Scopes) > 0 {
+		apiKey.Scopes = lo.Uniq(req.Scopes)
+	}
+	if err := db.Model(&apiKey).Select("name", "scopes").Updates(&apiKey).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "更新 API Key 失败", err)
+		return
+	}
+
+	utils.OkWithData(ctx, vo.ToApiKeyVO(apiKey))
+}
+
+// Revoke 吊销 API Key
+// @Summary 吊销 API Key
+// @Description 吊销当前用户的 API Key，吊销后立即失效，记录保留在列表中
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param id path int true "API Key ID"
+// @Success 200 {object} utils.Response
+// @Router /api/apikey/{id}/revoke [post]
+func Revoke(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+
+	var apiKey domain.ApiKey
+	if err := db.Where("user_id = ?", userId).First(&apiKey, ctx.Param("id")).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "API Key 不存在", err)
+		return
+	}
+	if apiKey.Revoked() {
+		utils.OkWithMsg(ctx, "吊销成功")
+		return
+	}
+
+	if err := db.Model(&apiKey).UpdateColumn("revoked_time", time.Now()).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "吊销 API Key 失败", err)
+		return
+	}
+
+	utils.OkWithMsg(ctx, "吊销成功")
+}
+
+// Delete 删除 API Key
+// @Summary 删除 API Key
+// @Description 删除当前用户的 API Key，删除后立即失效且不再出现在列表中
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param id path int true "API Key ID"
+// @Success 200 {object} utils.Response
+// @Router /api/apikey/{id} [delete]
+func Delete(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+
+	var apiKey domain.ApiKey
+	if err := db.Where("user_id = ?", userId).First(&apiKey, ctx.Param("id")).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "API Key 不存在", err)
+		return
+	}
+
+	if err := db.Delete(&apiKey).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "删除 API Key 失败", err)
+		return
+	}
+
+	utils.OkWithMsg(ctx, "删除成功")
+}
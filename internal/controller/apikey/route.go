@@ -0,0 +1,20 @@
+package apikey
+
+import (
+	"github.com/gin-gonic/gin"
+	"txing-ai/internal/middleware"
+)
+
+func Register(router gin.IRouter) {
+
+	// API Key 只能通过登录令牌管理，不能用 API Key 创建新的 API Key
+	groupRouter := router.Group("/apikey", middleware.AuthMiddleware())
+	{
+		groupRouter.POST("", Create)
+		groupRouter.GET("/list", List)
+		groupRouter.PUT("/:id", Update)
+		groupRouter.POST("/:id/revoke", Revoke)
+		groupRouter.DELETE("/:id", Delete)
+	}
+
+}
@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"errors"
 	"strconv"
 	"txing-ai/internal/domain"
 	"txing-ai/internal/dto"
@@ -8,7 +9,9 @@ import (
 	"txing-ai/internal/global/logging/log"
 	"txing-ai/internal/service/chat"
 	"txing-ai/internal/service/conversation"
+	knowledgeservice "txing-ai/internal/service/knowledge"
 	presetservice "txing-ai/internal/service/preset"
+	"txing-ai/internal/storage"
 	"txing-ai/internal/utils"
 	"txing-ai/internal/utils/page"
 	"txing-ai/internal/vo"
@@ -34,9 +37,9 @@ import (
 // @Failure 401 {object} utils.Response "未授权"
 // @Failure 500 {object} utils.Response "服务器内部错误"
 // @Router /api/chat/ws [get]
-// @x-message-request {"type":"chat","content":"聊天内容","model":"模型标识","context":1,"enableWeb":false,"max_tokens":2048,"temperature":1.0,"top_p":0.7,"top_k":50,"presence_penalty":0.0,"frequency_penalty":0.0,"repetition_penalty":1.0}
+// @x-message-request {"type":"chat","content":"聊天内容","model":"模型标识","context":1,"enableWeb":false,"max_tokens":2048,"temperature":1.0,"top_p":0.7,"top_k":50,"presence_penalty":0.0,"frequency_penalty":0.0,"repetition_penalty":1.0,"response_schema":{"type":"object","properties":{"answer":{"type":"string"}},"required":["answer"]},"files":[12],"knowledge_base_ids":[3]}
 // @x-message-stop {"type":"stop"}
-// @x-message-response {"conversationId":123,"content":"AI回复内容","reasoning_content":"思考过程","end":false}
+// @x-message-response {"conversationId":123,"content":"AI回复内容","reasoning_content":"思考过程","data":{"answer":"结构化输出结果"},"references":[{"index":1,"knowledgeBaseId":3,"documentId":8,"fileId":12,"name":"产品手册.pdf","section":"安装","pageStart":2,"pageEnd":2,"score":0.82,"snippet":"资料片段"}],"end":false}
 // @x-message-error {"type":"error","message":"错误信息"}
 func Chat(c *gin.Context) {
 	var webSocket *utils.WebSocket
@@ -80,7 +83,19 @@ func Chat(c *gin.Context) {
 		switch msg.Type {
 		case global.MessageTypeChat:
 			// 处理聊天消息
-			// 1. 保存消息
+			// 1. 读取附件，保存消息
+			if len(msg.Files) > 0 {
+				if err := attachFiles(c, db, userId, msg); err != nil {
+					buf.Send(dto.WsMessageResponse{Content: err.Error(), End: true, ConversationId: conversation.Id})
+					return nil
+				}
+			}
+			if msg.KnowledgeBaseIds != nil {
+				if err := checkKnowledgeBases(db, userId, conversation, msg); err != nil {
+					buf.Send(dto.WsMessageResponse{Content: err.Error(), End: true, ConversationId: conversation.Id})
+					return nil
+				}
+			}
 			if err := conversation.HandleMessage(msg, db); err == nil {
 				// 开启协程处理聊天，为了不阻塞当前协程，确保能继续接收并处理其他消息，例如停止消息
 				// TODO 限制只能同时处理一个聊天请求
@@ -105,6 +120,45 @@ func Chat(c *gin.Context) {
 	})
 }
 
+// attachFiles 解析消息中的附件，其他错误不直接返回给用户
+func attachFiles(c *gin.Context, db *gorm.DB, userId int64, msg *dto.WsMessageRequest) error {
+	if userId == -1 {
+		return errors.New("登录后才能发送附件")
+	}
+	blobStores := utils.GetBlobStoresFromContext[*storage.Stores](c)
+	err := chat.AttachFiles(c, db, blobStores, userId, msg)
+	var attachmentErr *chat.AttachmentError
+	if err != nil && !errors.As(err, &attachmentErr) {
+		log.Error("attach files failed", zap.Int64s("files", msg.Files), zap.Error(err))
+		return errors.New("读取附件失败，请稍后再试")
+	}
+	return err
+}
+
+// checkKnowledgeBases 校验消息中指定的知识库，预设引用的知识库使用该预设的用户都可以引用
+func checkKnowledgeBases(db *gorm.DB, userId int64, conversation *domain.Conversation, msg *dto.WsMessageRequest) error {
+	if userId == -1 {
+		return errors.New("登录后才能使用知识库")
+	}
+	var allowed []int64
+	if conversation.PresetID != nil {
+		var preset domain.Preset
+		if err := db.Select("id", "knowledge_base_ids").First(&preset, *conversation.PresetID).Error; err == nil {
+			allowed = preset.KnowledgeBaseIDs
+		}
+	}
+	ids, err := knowledgeservice.CheckAccessible(db, userId, msg.KnowledgeBaseIds, allowed)
+	if errors.Is(err, knowledgeservice.ErrKnowledgeBaseNotFound) {
+		return err
+	}
+	if err != nil {
+		log.Error("check knowledge bases failed", zap.Int64s("knowledgeBaseIds", msg.KnowledgeBaseIds), zap.Error(err))
+		return errors.New("读取知识库失败，请稍后再试")
+	}
+	msg.KnowledgeBaseIds = ids
+	return nil
+}
+
 // @Summary 获取会话列表
 // @Description 获取用户的会话列表
 // @Tags 聊天
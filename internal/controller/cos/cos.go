@@ -3,16 +3,20 @@ package cos
 import (
 	"net/http"
 	"time"
+	"txing-ai/internal/domain"
 	"txing-ai/internal/dto"
+	fileservice "txing-ai/internal/service/file"
+	"txing-ai/internal/storage"
 	"txing-ai/internal/vo"
 
 	"txing-ai/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // @Summary 获取预签名URL
-// @Description 获取文件上传或下载的预签名URL
+// @Description 获取文件上传或下载的预签名URL，上传时文件保存到当前用户对应用途的目录下，返回实际的对象键；只能下载当前用户上传目录下的文件
 // @Tags 对象存储相关
 // @Accept json
 // @Produce json
@@ -27,6 +31,7 @@ func GetPresignedURL(ctx *gin.Context) {
 	}
 
 	cosClient := utils.GetCosClientFromContext[*utils.COSClient](ctx)
+	userId := utils.GetUIDFromContext(ctx)
 
 	// 默认过期时间 1 小时
 	expire := 3600
@@ -37,11 +42,24 @@ func GetPresignedURL(ctx *gin.Context) {
 		err error
 	)
 
+	key := req.Key
 	switch req.Type {
 	case "upload":
-		url, err = cosClient.GenerateUploadPresignedURL(req.Key)
+		// 上传只能写入当前用户的目录，上传后需要确认才能保存到业务数据中
+		purpose := utils.UploadPurpose(req.Purpose)
+		if !purpose.Valid() {
+			utils.ErrorWithMsg(ctx, utils.ErrUploadPurpose.Error(), nil)
+			return
+		}
+		key = utils.UploadKey(userId, purpose, req.Key)
+		url, err = cosClient.GenerateUploadPresignedURL(key)
 	case "download":
-		url, err = cosClient.GenerateDownloadPresignedURL(req.Key)
+		// 只能下载当前用户上传的文件，其他对象（例如其他用户的附件）的地址由各业务接口在校验权限后生成
+		if !utils.IsUserUploadKey(userId, utils.UploadPurpose(req.Purpose), key) {
+			utils.ErrorWithMsg(ctx, utils.ErrUploadKey.Error(), nil)
+			return
+		}
+		url, err = cosClient.GenerateDownloadPresignedURL(key)
 	default:
 		utils.ErrorWithCode(ctx, http.StatusBadRequest, nil)
 		return
@@ -57,7 +75,103 @@ func GetPresignedURL(ctx *gin.Context) {
 
 	utils.OkWithData(ctx, vo.GetPresignedURLVO{
 		URL:      url,
-		Key:      req.Key,
+		Key:      key,
 		ExpireAt: expireAt,
 	})
 }
+
+// @Summary 获取临时密钥
+// @Description 获取只能上传到当前用户对应用途目录下的临时密钥，限制文件大小和类型
+// @Tags 对象存储相关
+// @Accept json
+// @Produce json
+// @Param data body dto.GetTempCredentialReq true "请求参数"
+// @Success 200 {object} utils.Response{data=vo.TempCredentialVO} "成功"
+// @Router /api/cos/temp-credential [post]
+func GetTempCredential(ctx *gin.Context) {
+	var req dto.GetTempCredentialReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	cosClient := utils.GetCosClientFromContext[*utils.COSClient](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+	purpose := utils.UploadPurpose(req.Purpose)
+
+	credential, err := cosClient.GetTempCredential(userId, purpose)
+	if err != nil {
+		utils.ErrorWithMsg(ctx, "获取临时密钥失败", err)
+		return
+	}
+
+	utils.OkWithData(ctx, vo.TempCredentialVO{
+		SecretID:     credential.SecretID,
+		SecretKey:    credential.SecretKey,
+		SessionToken: credential.SessionToken,
+		StartTime:    credential.StartTime,
+		ExpiredTime:  credential.ExpiredTime,
+		Bucket:       credential.Bucket,
+		Region:       credential.Region,
+		Prefix:       credential.Prefix,
+		MaxSize:      purpose.MaxSize(),
+	})
+}
+
+// @Summary 确认直传文件
+// @Description 客户端直传完成后确认文件，检查文件路径、大小和类型，不符合要求的文件会被删除；附件会登记到文件表
+// @Tags 对象存储相关
+// @Accept json
+// @Produce json
+// @Param data body dto.ConfirmUploadReq true "请求参数"
+// @Success 200 {object} utils.Response{data=vo.ConfirmUploadVO} "成功"
+// @Router /api/cos/confirm [post]
+func ConfirmUpload(ctx *gin.Context) {
+	var req dto.ConfirmUploadReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	cosClient := utils.GetCosClientFromContext[*utils.COSClient](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+	purpose := utils.UploadPurpose(req.Purpose)
+
+	object, err := cosClient.ConfirmUpload(ctx, userId, purpose, req.Key)
+	if err != nil {
+		if utils.IsUploadError(err) {
+			utils.ErrorWithMsg(ctx, err.Error(), err)
+			return
+		}
+		utils.ErrorWithMsg(ctx, "确认文件失败", err)
+		return
+	}
+
+	url, err := cosClient.GenerateDownloadPresignedURL(object.Key)
+	if err != nil {
+		utils.ErrorWithMsg(ctx, "生成下载地址失败", err)
+		return
+	}
+	result := vo.ConfirmUploadVO{
+		Key:         object.Key,
+		Size:        object.Size,
+		ContentType: object.ContentType,
+		URL:         url,
+	}
+
+	// 附件登记到文件表，之后可以按文件ID引用和下载
+	if purpose == utils.UploadPurposeAttachment {
+		db := utils.GetDBFromContext[*gorm.DB](ctx)
+		blobStores := utils.GetBlobStoresFromContext[*storage.Stores](ctx)
+		file, err := fileservice.RegisterObject(ctx, db, blobStores, storage.BackendCOS, userId, req.Name, object.Key,
+			domain.FileSourceUpload, string(purpose))
+		if err != nil {
+			utils.ErrorWithMsg(ctx, "登记文件失败", err)
+			return
+		}
+		result.FileId = file.Id
+		result.DownloadURL = fileservice.DownloadURL(file.Id)
+	}
+
+	utils.OkWithData(ctx, result)
+}
@@ -9,4 +9,8 @@ import (
 func Register(r *gin.RouterGroup) {
 	// 获取预签名URL
 	r.POST("/presigned-url", middleware.AuthMiddleware(), GetPresignedURL)
+	// 获取只能上传到用户目录下的临时密钥
+	r.POST("/temp-credential", middleware.AuthMiddleware(), GetTempCredential)
+	// 确认直传的文件
+	r.POST("/confirm", middleware.AuthMiddleware(), ConfirmUpload)
 }
@@ -1,36 +1,48 @@
 package file
 
 import (
+	"errors"
 	"fmt"
-	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/dto"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
+	fileservice "txing-ai/internal/service/file"
+	"txing-ai/internal/storage"
 	"txing-ai/internal/utils"
+	"txing-ai/internal/utils/page"
+	"txing-ai/internal/vo"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // 文件上传响应
 type UploadResponse struct {
-	FileName string `json:"fileName"` // 文件名
-	FileSize int64  `json:"fileSize"` // 文件大小
-	FileURL  string `json:"fileUrl"`  // 文件下载URL
+	FileId      int64  `json:"fileId"`      // 文件ID
+	FileName    string `json:"fileName"`    // 文件名
+	FileSize    int64  `json:"fileSize"`    // 文件大小
+	FileURL     string `json:"fileUrl"`     // 文件在存储中的路径
+	DownloadURL string `json:"downloadUrl"` // 按文件ID下载的地址
 }
 
 // Upload 上传文件
 // @Summary 上传文件
-// @Description 上传文件到服务器本地
+// @Description 上传文件到文件存储（本地、COS 或 S3），按用途检查扩展名和文件内容，配置了扫描服务时扫描病毒
 // @Tags 文件
 // @Accept multipart/form-data
 // @Produce json
 // @Param Authorization header string true "用户令牌"
 // @Param file formData file true "文件"
+// @Param purpose formData string false "用途 file（默认，文档和图片）、chat（对话图片）、resume（简历 PDF、Word、Markdown、文本）"
 // @Success 200 {object} utils.Response{data=UploadResponse} "成功"
 // @Failure 400 {object} utils.Response "请求错误"
 // @Failure 500 {object} utils.Response "服务器内部错误"
@@ -46,88 +58,89 @@ func Upload(c *gin.Context) {
 	}
 	defer file.Close()
 
-	localUploadConfig := global.LoadConfig().LocalUploadConfig
-
-	// 检查文件大小
-	if header.Size > int64(localUploadConfig.MaxSize) {
-		log.Error("文件大小超出限制", zap.Int64("size", header.Size), zap.Int("max_size", localUploadConfig.MaxSize))
-		utils.ErrorWithMsg(c, "文件大小超出限制: "+strconv.FormatInt(int64(localUploadConfig.MaxSize/1024/1024), 10)+" MB", fmt.Errorf("文件大小超出限制"))
+	purpose := fileservice.Purpose(c.DefaultPostForm("purpose", string(fileservice.PurposeFile)))
+	if !purpose.Valid() {
+		utils.ErrorWithCodeAndMsg(c, global.CodeInvalidParams, "不支持的上传用途", nil)
 		return
 	}
 
 	userId := utils.GetUIDFromContext(c)
-	// 获取当前工作目录
-	currentDir, err := os.Getwd()
+	db := utils.GetDBFromContext[*gorm.DB](c)
+	blobStores := utils.GetBlobStoresFromContext[*storage.Stores](c)
+
+	// 校验后流式写入文件存储并登记，之后通过文件ID下载
+	record, err := fileservice.SaveUpload(c, db, blobStores, userId, purpose, header.Filename, file, header.Size, domain.FileSourceUpload, string(purpose))
 	if err != nil {
-		log.Error("获取当前工作目录失败", zap.Error(err))
-		utils.ErrorWithMsg(c, "获取当前工作目录失败", err)
+		if fileservice.IsRejected(err) {
+			log.Warn("上传的文件没有通过校验", zap.Int64("userId", userId), zap.String("filename", header.Filename), zap.Error(err))
+			utils.ErrorWithMsg(c, err.Error(), err)
+			return
+		}
+		log.Error("保存文件失败", zap.String("filename", header.Filename), zap.Error(err))
+		utils.ErrorWithMsg(c, "保存文件失败", err)
 		return
 	}
 
-	// 获取当前日期作为目录名
-	currentDate := time.Now().Format("2006-01-02")
-
-	fileDir := filepath.Join(localUploadConfig.Dir, strconv.FormatInt(userId, 10), currentDate)
-
-	// 生成唯一文件名（时间戳+原始文件名）
-	fileName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), header.Filename)
-	filePath := filepath.Join(fileDir, fileName)
-
-	// 确保上传目录存在（用户ID/日期）
-	absUploadDir := filepath.Join(currentDir, fileDir)
-	if err := os.MkdirAll(absUploadDir, 0755); err != nil {
-		log.Error("创建上传目录失败", zap.String("dir", absUploadDir), zap.Error(err))
-		utils.ErrorWithMsg(c, "创建上传目录失败", err)
-		return
-	}
+	// 记录成功日志
+	log.Info("文件上传成功",
+		zap.Int64("fileId", record.Id),
+		zap.String("filename", record.Name),
+		zap.String("storage", record.Storage),
+		zap.String("key", record.StorageKey),
+		zap.Int64("size", record.Size),
+		zap.String("scanStatus", record.ScanStatus),
+		zap.Time("timestamp", time.Now()))
 
-	absUploadPath := filepath.Join(absUploadDir, fileName)
+	// 返回响应
+	utils.OkWithData(c, UploadResponse{
+		FileId:      record.Id,
+		FileName:    record.Name,
+		FileSize:    record.Size,
+		FileURL:     record.StorageKey,
+		DownloadURL: fileservice.DownloadURL(record.Id),
+	})
+}
 
-	// 创建目标文件
-	dst, err := os.Create(absUploadPath)
+// DownloadById 按文件ID下载文件
+// @Summary 按文件ID下载文件
+// @Description 下载当前用户的文件，文件ID来自上传接口或智能体生成的文件列表
+// @Tags 文件
+// @Produce octet-stream
+// @Param id path int true "文件ID"
+// @Success 200 {file} binary "文件内容"
+// @Failure 400 {object} utils.Response "请求错误"
+// @Failure 404 {object} utils.Response "文件不存在"
+// @Failure 500 {object} utils.Response "服务器内部错误"
+// @Router /api/file/{id}/download [get]
+func DownloadById(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		log.Error("创建文件失败", zap.String("path", absUploadPath), zap.Error(err))
-		utils.ErrorWithMsg(c, "创建文件失败", err)
-		return
-	}
-	defer dst.Close()
-
-	// 将上传的文件内容复制到目标文件
-	if _, err = io.Copy(dst, file); err != nil {
-		log.Error("保存文件失败", zap.String("path", absUploadPath), zap.Error(err))
-		utils.ErrorWithMsg(c, "保存文件失败", err)
+		utils.ErrorWithCode(c, global.CodeInvalidParams, err)
 		return
 	}
 
-	// 获取文件大小
-	fileInfo, err := os.Stat(absUploadPath)
+	db := utils.GetDBFromContext[*gorm.DB](c)
+	userId := utils.GetUIDFromContext(c)
+	file, err := fileservice.GetUserFile(db, userId, id)
 	if err != nil {
-		log.Error("获取文件信息失败", zap.String("path", filePath), zap.Error(err))
-		utils.ErrorWithMsg(c, "获取文件信息失败", err)
+		if errors.Is(err, fileservice.ErrFileNotFound) || errors.Is(err, fileservice.ErrFileExpired) {
+			utils.ErrorWithCodeAndMsg(c, global.CodeNotFound, err.Error(), err)
+			return
+		}
+		log.Error("查询文件失败", zap.Int64("id", id), zap.Error(err))
+		utils.ErrorWithMsg(c, "查询文件失败", err)
 		return
 	}
 
-	// 记录成功日志
-	log.Info("文件上传成功",
-		zap.String("filename", header.Filename),
-		zap.String("path", filePath),
-		zap.Int64("size", fileInfo.Size()),
-		zap.Time("timestamp", time.Now()))
-
-	// 返回响应
-	utils.OkWithData(c, UploadResponse{
-		FileName: header.Filename,
-		FileSize: fileInfo.Size(),
-		FileURL:  fmt.Sprintf("%s/%s/%s", strconv.FormatInt(userId, 10), currentDate, fileName),
-	})
+	serveFile(c, file)
 }
 
-// Download 下载文件
-// @Summary 下载文件
-// @Description 从服务器下载文件
+// Download 按文件名下载文件
+// @Summary 按文件名下载文件
+// @Description 按保存后的文件名下载当前用户的文件，优先查找文件登记表，兼容旧版的下载链接，推荐使用按文件ID下载
 // @Tags 文件
 // @Produce octet-stream
-// @Param filePath query string true "文件相对路径"
+// @Param filePath query string true "文件名"
 // @Success 200 {file} binary "文件内容"
 // @Failure 400 {object} utils.Response "请求错误"
 // @Failure 404 {object} utils.Response "文件不存在"
@@ -140,7 +153,22 @@ func Download(c *gin.Context) {
 		utils.ErrorWithCode(c, global.CodeInvalidParams, nil)
 		return
 	}
+	// 只允许文件名，不能访问其他目录
+	filePath = filepath.Base(filePath)
 
+	db := utils.GetDBFromContext[*gorm.DB](c)
+	userId := utils.GetUIDFromContext(c)
+	file, err := fileservice.FindUserFileByStoredName(db, userId, filePath)
+	if err == nil {
+		serveFile(c, file)
+		return
+	}
+	if errors.Is(err, fileservice.ErrFileExpired) {
+		utils.ErrorWithCodeAndMsg(c, global.CodeNotFound, err.Error(), err)
+		return
+	}
+
+	// 没有登记的文件，按当天的保存目录查找
 	config := global.LoadConfig().LocalUploadConfig
 
 	// 获取当前工作目录
@@ -151,42 +179,106 @@ func Download(c *gin.Context) {
 		return
 	}
 
-	userId := utils.GetUIDFromContext(c)
 	// 获取当前日期作为目录名
 	currentDate := time.Now().Format("2006-01-02")
-	filePath = filepath.Join(strconv.FormatInt(userId, 10), currentDate, filePath)
-
-	// 构建文件路径
-	absFilePath := filepath.Join(currentDir, config.Dir, filePath)
-
-	// 检查文件是否存在
-	if _, err := os.Stat(absFilePath); os.IsNotExist(err) {
+	absFilePath := filepath.Join(currentDir, config.Dir, strconv.FormatInt(userId, 10), currentDate, filePath)
+	if _, err := os.Stat(absFilePath); err != nil {
 		log.Error("File does not exist", zap.String("path", absFilePath))
-		utils.ErrorWithMsg(c, "文件不存在", err)
+		utils.ErrorWithCodeAndMsg(c, global.CodeNotFound, "文件不存在", err)
 		return
 	}
+	setDownloadHeaders(c, filePath, "application/octet-stream")
+	c.File(absFilePath)
+}
 
-	// 获取原始文件名
-	originalFileName := filepath.Base(filePath)
+// serveFile 从文件存储中读取文件，以附件形式流式返回
+func serveFile(c *gin.Context, file *domain.File) {
+	blobStores := utils.GetBlobStoresFromContext[*storage.Stores](c)
+	reader, info, err := fileservice.Open(c, blobStores, file)
+	if err != nil {
+		if errors.Is(err, fileservice.ErrFileNotFound) {
+			log.Error("File does not exist", zap.Int64("id", file.Id), zap.String("key", file.StorageKey))
+			utils.ErrorWithCodeAndMsg(c, global.CodeNotFound, err.Error(), err)
+			return
+		}
+		log.Error("读取文件失败", zap.Int64("id", file.Id), zap.Error(err))
+		utils.ErrorWithMsg(c, "读取文件失败", err)
+		return
+	}
+	defer reader.Close()
 
-	// URL编码文件名，确保特殊字符正确处理
-	encodedFileName := url.QueryEscape(originalFileName)
+	mimeType := file.MimeType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	setDownloadHeaders(c, file.Name, mimeType)
+	c.DataFromReader(http.StatusOK, info.Size, mimeType, reader, nil)
+
+	// 记录下载日志
+	log.Info("文件下载",
+		zap.Int64("id", file.Id),
+		zap.String("filename", file.Name),
+		zap.String("storage", file.Storage),
+		zap.Time("timestamp", time.Now()))
+}
 
-	// 设置响应头
+// setDownloadHeaders 设置附件下载的响应头
+func setDownloadHeaders(c *gin.Context, fileName string, mimeType string) {
+	// URL编码文件名，确保特殊字符正确处理
+	encodedFileName := url.PathEscape(fileName)
 	c.Header("Content-Description", "File Transfer")
 	c.Header("Content-Transfer-Encoding", "binary")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"; filename*=UTF-8''%s", originalFileName, encodedFileName))
-	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"; filename*=UTF-8''%s", strings.ReplaceAll(fileName, `"`, ""), encodedFileName))
+	c.Header("Content-Type", mimeType)
 	c.Header("Cache-Control", "no-cache")
+}
 
-	// 使用绝对路径提供文件
-	c.File(absFilePath)
+// ListStorageUsage 用户存储空间使用情况(管理员接口)
+// @Summary 用户存储空间使用情况
+// @Description 按用户统计文件数量和总大小，以及各来源文件的大小，支持分页
+// @Tags 文件
+// @Produce json
+// @Param Authorization header string true "用户令牌"
+// @Param page query int true "页码" minimum(1)
+// @Param limit query int true "每页数量" minimum(1)
+// @Param order_by query string false "用户的排序字段"
+// @Param order query string false "排序方式(asc/desc)"
+// @Param username query string false "用户名"
+// @Success 200 {object} utils.Response
+// @Failure 500 {object} utils.Response "服务器内部错误"
+// @Router /api/admin/file/usage [get]
+func ListStorageUsage(c *gin.Context) {
+	var req dto.ListStorageUsageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
 
-	// 记录下载日志
-	log.Info("",
-		zap.String("filename", originalFileName),
-		zap.String("path", absFilePath),
-		zap.Time("timestamp", time.Now()))
+	db := utils.GetDBFromContext[*gorm.DB](c)
+	query := db.Model(&domain.User{})
+	if req.Username != "" {
+		query = query.Where("username LIKE ?", "%"+req.Username+"%")
+	}
+
+	var users []domain.User
+	pageVo, err := page.Paginate[domain.User](query, req.PageRequest, &users)
+	if err != nil {
+		utils.ErrorWithMsg(c, "获取用户列表失败", err)
+		return
+	}
+
+	userIds := make([]int64, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, user.Id)
+	}
+	usages := map[int64]*fileservice.Usage{}
+	if len(userIds) > 0 {
+		if usages, err = fileservice.GetUsages(db, userIds); err != nil {
+			log.Error("统计存储空间失败", zap.Error(err))
+			utils.ErrorWithMsg(c, "统计存储空间失败", err)
+			return
+		}
+	}
 
-	utils.Ok(c)
+	utils.OkWithData(c, page.Convert(pageVo, vo.ToStorageUsageVOs(users, usages, fileservice.UserQuota())))
 }
@@ -13,7 +13,17 @@ func Register(router gin.IRouter) {
 		// 上传文件接口，需要登录验证
 		fileRouter.POST("/upload", middleware.AuthMiddleware(), Upload)
 
-		// 下载文件接口，不需要登录验证
+		// 按文件名下载文件，兼容旧版的下载链接
 		fileRouter.GET("/download", middleware.AuthMiddleware(), Download)
+
+		// 按文件ID下载文件
+		fileRouter.GET("/:id/download", middleware.AuthMiddleware(), DownloadById)
+	}
+
+	// 管理员路由组
+	adminRouter := router.Group("/admin/file", middleware.AuthMiddleware())
+	{
+		// 用户存储空间使用情况
+		adminRouter.GET("/usage", ListStorageUsage)
 	}
 }
@@ -0,0 +1,425 @@
+package knowledge
+
+import (
+	"errors"
+	"net/http"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	knowledgeservice "txing-ai/internal/service/knowledge"
+	"txing-ai/internal/utils"
+	"txing-ai/internal/utils/page"
+	"txing-ai/internal/vo"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Create 创建知识库
+// @Summary 创建知识库
+// @Description 创建知识库，之后把上传的文件添加到知识库中，文档在后台解析、切分和向量化
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param data body dto.CreateKnowledgeBaseReq true "知识库信息"
+// @Success 200 {object} utils.Response{data=vo.KnowledgeBaseVO}
+// @Router /api/knowledge [post]
+func Create(ctx *gin.Context) {
+	var req dto.CreateKnowledgeBaseReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	userId := utils.GetUIDFromContext(ctx)
+	isAdmin := utils.GetIsAdminFromContext(ctx)
+	knowledge := utils.GetKnowledgeFromContext[*knowledgeservice.Service](ctx)
+
+	// 非管理员只能创建自己的知识库
+	if !isAdmin {
+		req.Official = false
+	}
+
+	kb := &domain.KnowledgeBase{
+		UserID:         userId,
+		Name:           req.Name,
+		Description:    req.Description,
+		EmbeddingModel: req.EmbeddingModel,
+		ChunkSize:      req.ChunkSize,
+		ChunkOverlap:   req.ChunkOverlap,
+		Official:       req.Official,
+	}
+	if err := knowledge.Create(kb); err != nil {
+		utils.ErrorWithMsg(ctx, "创建知识库失败", err)
+		return
+	}
+
+	utils.OkWithData(ctx, vo.ToKnowledgeBaseVO(*kb))
+}
+
+// Update 更新知识库
+// @Summary 更新知识库
+// @Description 更新知识库的名称、描述，管理员可以设置是否官方知识库
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param id path int true "知识库ID"
+// @Param data body dto.UpdateKnowledgeBaseReq true "知识库信息"
+// @Success 200 {object} utils.Response{data=vo.KnowledgeBaseVO}
+// @Router /api/knowledge/{id} [put]
+func Update(ctx *gin.Context) {
+	var req dto.UpdateKnowledgeBaseReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	isAdmin := utils.GetIsAdminFromContext(ctx)
+
+	kb, ok := manageableKnowledgeBase(ctx, db)
+	if !ok {
+		return
+	}
+
+	if req.Name != "" {
+		kb.Name = req.Name
+	}
+	if req.Description != "" {
+		kb.Description = req.Description
+	}
+	if req.Official != nil && isAdmin {
+		kb.Official = *req.Official
+	}
+
+	if err := db.Save(kb).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "更新知识库失败", err)
+		return
+	}
+
+	utils.OkWithData(ctx, vo.ToKnowledgeBaseVO(*kb))
+}
+
+// Delete 删除知识库
+// @Summary 删除知识库
+// @Description 删除知识库和其中的文档，引用该知识库的预设和会话不再从中检索
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param id path int true "知识库ID"
+// @Success 200 {object} utils.Response
+// @Router /api/knowledge/{id} [delete]
+func Delete(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	knowledge := utils.GetKnowledgeFromContext[*knowledgeservice.Service](ctx)
+
+	kb, ok := manageableKnowledgeBase(ctx, db)
+	if !ok {
+		return
+	}
+
+	if err := knowledge.DeleteKnowledgeBase(ctx, kb); err != nil {
+		utils.ErrorWithMsg(ctx, "删除知识库失败", err)
+		return
+	}
+
+	utils.OkWithMsg(ctx, "删除成功")
+}
+
+// Get 获取知识库详情
+// @Summary 获取知识库详情
+// @Description 获取官方知识库或自己创建的知识库的详细信息
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param id path int true "知识库ID"
+// @Success 200 {object} utils.Response{data=vo.KnowledgeBaseVO}
+// @Router /api/knowledge/{id} [get]
+func Get(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	kb, ok := usableKnowledgeBase(ctx, db)
+	if !ok {
+		return
+	}
+
+	utils.OkWithData(ctx, vo.ToKnowledgeBaseVO(*kb))
+}
+
+// List 获取知识库列表
+// @Summary 获取知识库列表
+// @Description 获取官方知识库和自己创建的知识库，支持分页
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param page query int true "页码" minimum(1)
+// @Param limit query int true "每页数量" minimum(1)
+// @Param order_by query string false "排序字段"
+// @Param order query string false "排序方式(asc/desc)"
+// @Param official query bool false "是否官方知识库"
+// @Param name query string false "知识库名称"
+// @Success 200 {object} utils.Response
+// @Router /api/knowledge/list [get]
+func List(ctx *gin.Context) {
+	var req dto.ListKnowledgeBaseReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+
+	query := db.Model(&domain.KnowledgeBase{}).Where("official = ? OR user_id = ?", true, userId)
+	if req.Official != nil {
+		query = query.Where("official = ?", *req.Official)
+	}
+	if req.Name != "" {
+		query = query.Where("name like ?", "%"+req.Name+"%")
+	}
+
+	var kbs []domain.KnowledgeBase
+	pageVo, err := page.Paginate[domain.KnowledgeBase](query, req.PageRequest, &kbs)
+	if err != nil {
+		utils.ErrorWithMsg(ctx, "获取知识库列表失败", err)
+		return
+	}
+
+	utils.OkWithData(ctx, page.Convert(pageVo, vo.ToKnowledgeBaseVOs(kbs)))
+}
+
+// AddDocuments 添加文档到知识库
+// @Summary 添加文档到知识库
+// @Description 把上传的文件添加到知识库，支持 PDF、Word、Markdown、HTML 和文本文件，文档在后台解析和向量化，通过文档列表查看处理状态
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param id path int true "知识库ID"
+// @Param data body dto.AddKnowledgeDocumentsReq true "文件ID列表"
+// @Success 200 {object} utils.Response{data=[]vo.KnowledgeDocumentVO}
+// @Router /api/knowledge/{id}/documents [post]
+func AddDocuments(ctx *gin.Context) {
+	var req dto.AddKnowledgeDocumentsReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+	knowledge := utils.GetKnowledgeFromContext[*knowledgeservice.Service](ctx)
+
+	kb, ok := manageableKnowledgeBase(ctx, db)
+	if !ok {
+		return
+	}
+
+	docs, err := knowledge.AddDocuments(ctx, kb, userId, req.FileIds)
+	if err != nil {
+		log.Error("添加知识库文档失败", zap.Int64("knowledgeBaseId", kb.Id), zap.Int64s("fileIds", req.FileIds), zap.Error(err))
+		utils.ErrorWithMsg(ctx, err.Error(), err)
+		return
+	}
+
+	utils.OkWithData(ctx, vo.ToKnowledgeDocumentVOs(docs))
+}
+
+// ListDocuments 获取知识库文档列表
+// @Summary 获取知识库文档列表
+// @Description 获取知识库中的文档和处理状态，支持分页
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param id path int true "知识库ID"
+// @Param page query int true "页码" minimum(1)
+// @Param limit query int true "每页数量" minimum(1)
+// @Param status query string false "处理状态 pending/processing/ready/failed"
+// @Success 200 {object} utils.Response
+// @Router /api/knowledge/{id}/documents [get]
+func ListDocuments(ctx *gin.Context) {
+	var req dto.ListKnowledgeDocumentReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	kb, ok := usableKnowledgeBase(ctx, db)
+	if !ok {
+		return
+	}
+
+	query := db.Model(&domain.KnowledgeDocument{}).Where("knowledge_base_id = ?", kb.Id)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var docs []domain.KnowledgeDocument
+	pageVo, err := page.Paginate[domain.KnowledgeDocument](query, req.PageRequest, &docs)
+	if err != nil {
+		utils.ErrorWithMsg(ctx, "获取文档列表失败", err)
+		return
+	}
+
+	utils.OkWithData(ctx, page.Convert(pageVo, vo.ToKnowledgeDocumentVOs(docs)))
+}
+
+// DeleteDocument 删除知识库中的文档
+// @Summary 删除知识库中的文档
+// @Description 删除文档和它的文本块，上传的文件不会被删除
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param id path int true "知识库ID"
+// @Param documentId path int true "文档ID"
+// @Success 200 {object} utils.Response
+// @Router /api/knowledge/{id}/documents/{documentId} [delete]
+func DeleteDocument(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	knowledge := utils.GetKnowledgeFromContext[*knowledgeservice.Service](ctx)
+
+	doc, ok := manageableDocument(ctx, db)
+	if !ok {
+		return
+	}
+
+	if err := knowledge.DeleteDocument(ctx, doc); err != nil {
+		utils.ErrorWithMsg(ctx, "删除文档失败", err)
+		return
+	}
+
+	utils.OkWithMsg(ctx, "删除成功")
+}
+
+// ReindexDocument 重新处理知识库中的文档
+// @Summary 重新处理文档
+// @Description 重新解析和向量化文档，用于处理失败后重试，正在处理的文档不会重复处理
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param id path int true "知识库ID"
+// @Param documentId path int true "文档ID"
+// @Success 200 {object} utils.Response
+// @Router /api/knowledge/{id}/documents/{documentId}/reindex [post]
+func ReindexDocument(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	knowledge := utils.GetKnowledgeFromContext[*knowledgeservice.Service](ctx)
+
+	doc, ok := manageableDocument(ctx, db)
+	if !ok {
+		return
+	}
+
+	if err := knowledge.Reindex(ctx, doc); err != nil {
+		utils.ErrorWithMsg(ctx, "重新处理文档失败", err)
+		return
+	}
+
+	utils.OkWithMsg(ctx, "已加入处理队列")
+}
+
+// Search 在知识库中检索
+// @Summary 在知识库中检索
+// @Description 检索与问题相关的文本块，用于测试知识库的检索效果，结果与对话时引用的资料格式相同
+// @Tags 知识库
+// @Accept json
+// @Produce json
+// @Param id path int true "知识库ID"
+// @Param data body dto.SearchKnowledgeReq true "检索内容"
+// @Success 200 {object} utils.Response{data=[]dto.KnowledgeReference}
+// @Router /api/knowledge/{id}/search [post]
+func Search(ctx *gin.Context) {
+	var req dto.SearchKnowledgeReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	knowledge := utils.GetKnowledgeFromContext[*knowledgeservice.Service](ctx)
+
+	kb, ok := usableKnowledgeBase(ctx, db)
+	if !ok {
+		return
+	}
+
+	passages, err := knowledge.Retrieve(ctx, []int64{kb.Id}, req.Query, req.TopK)
+	if err != nil {
+		log.Error("检索知识库失败", zap.Int64("knowledgeBaseId", kb.Id), zap.Error(err))
+		utils.ErrorWithMsg(ctx, "检索知识库失败："+err.Error(), err)
+		return
+	}
+
+	utils.OkWithData(ctx, knowledgeservice.References(passages))
+}
+
+// findKnowledgeBase 查询路径参数中的知识库，不存在时返回错误响应
+func findKnowledgeBase(ctx *gin.Context, db *gorm.DB) (*domain.KnowledgeBase, bool) {
+	id := utils.SafeParseInt64(ctx.Param("id"), 0)
+	if id <= 0 {
+		utils.ErrorWithMsg(ctx, "知识库ID不能为空", nil)
+		return nil, false
+	}
+	kb, err := knowledgeservice.GetKnowledgeBase(db, id)
+	if err != nil {
+		if errors.Is(err, knowledgeservice.ErrKnowledgeBaseNotFound) {
+			utils.ErrorWithMsg(ctx, "知识库不存在", err)
+		} else {
+			utils.ErrorWithMsg(ctx, "查询知识库失败", err)
+		}
+		return nil, false
+	}
+	return kb, true
+}
+
+// usableKnowledgeBase 查询当前用户可以检索的知识库
+func usableKnowledgeBase(ctx *gin.Context, db *gorm.DB) (*domain.KnowledgeBase, bool) {
+	kb, ok := findKnowledgeBase(ctx, db)
+	if !ok {
+		return nil, false
+	}
+	userId := utils.GetUIDFromContext(ctx)
+	if !knowledgeservice.CanUse(kb, userId) && !utils.GetIsAdminFromContext(ctx) {
+		log.Error("当前用户无权限访问该知识库", zap.Int64("userId", userId), zap.Int64("kb.UserID", kb.UserID))
+		utils.ErrorWithHttpCode(ctx, http.StatusForbidden, global.CodeNotPermission, nil)
+		return nil, false
+	}
+	return kb, true
+}
+
+// manageableKnowledgeBase 查询当前用户可以修改的知识库
+func manageableKnowledgeBase(ctx *gin.Context, db *gorm.DB) (*domain.KnowledgeBase, bool) {
+	kb, ok := findKnowledgeBase(ctx, db)
+	if !ok {
+		return nil, false
+	}
+	userId := utils.GetUIDFromContext(ctx)
+	if !knowledgeservice.CanManage(kb, userId, utils.GetIsAdminFromContext(ctx)) {
+		log.Error("当前用户无权限修改该知识库", zap.Int64("userId", userId), zap.Int64("kb.UserID", kb.UserID))
+		utils.ErrorWithHttpCode(ctx, http.StatusForbidden, global.CodeNotPermission, nil)
+		return nil, false
+	}
+	return kb, true
+}
+
+// manageableDocument 查询当前用户可以修改的知识库中的文档
+func manageableDocument(ctx *gin.Context, db *gorm.DB) (*domain.KnowledgeDocument, bool) {
+	kb, ok := manageableKnowledgeBase(ctx, db)
+	if !ok {
+		return nil, false
+	}
+	documentId := utils.SafeParseInt64(ctx.Param("documentId"), 0)
+	doc, err := knowledgeservice.GetDocument(db, kb.Id, documentId)
+	if err != nil {
+		if errors.Is(err, knowledgeservice.ErrDocumentNotFound) {
+			utils.ErrorWithMsg(ctx, "文档不存在", err)
+		} else {
+			utils.ErrorWithMsg(ctx, "查询文档失败", err)
+		}
+		return nil, false
+	}
+	return doc, true
+}
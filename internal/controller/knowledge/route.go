@@ -0,0 +1,27 @@
+package knowledge
+
+import (
+	"github.com/gin-gonic/gin"
+	"txing-ai/internal/middleware"
+)
+
+// Register 注册知识库相关路由
+func Register(router gin.IRouter) {
+
+	group := router.Group("/knowledge", middleware.AuthMiddleware())
+
+	group.POST("", Create)
+	group.PUT("/:id", Update)
+	group.DELETE("/:id", Delete)
+	group.GET("/:id", Get)
+	group.GET("/list", List)
+
+	// 知识库中的文档
+	group.POST("/:id/documents", AddDocuments)
+	group.GET("/:id/documents", ListDocuments)
+	group.DELETE("/:id/documents/:documentId", DeleteDocument)
+	group.POST("/:id/documents/:documentId/reindex", ReindexDocument)
+
+	// 检索测试
+	group.POST("/:id/search", Search)
+}
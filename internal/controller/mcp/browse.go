@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"txing-ai/internal/dto"
+	mcpservice "txing-ai/internal/service/mcp"
+	"txing-ai/internal/tool/mcp"
+	"txing-ai/internal/utils"
+	"txing-ai/internal/vo"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// ListServers 获取可浏览的MCP服务器
+// @Summary 获取可浏览的MCP服务器
+// @Description 获取当前运行的MCP服务器及其是否支持资源和 prompt，不包含连接配置
+// @Tags MCP资源
+// @Accept json
+// @Produce json
+// @Success 200 {object} utils.Response{data=[]vo.MCPBrowseServerVO}
+// @Router /api/mcp/servers [get]
+func ListServers(ctx *gin.Context) {
+	manager := utils.GetMCPClientManagerFromContext[*mcp.MCPClientManager](ctx)
+	servers := lo.Map(manager.ServerNames(), func(name string, _ int) vo.MCPBrowseServerVO {
+		status, _ := manager.ServerStatus(name)
+		return vo.ToMCPBrowseServerVO(name, status)
+	})
+	utils.OkWithData(ctx, servers)
+}
+
+// ListResources 获取MCP服务器的资源
+// @Summary 获取MCP服务器的资源
+// @Description 获取指定MCP服务器提供的资源和资源模板，资源模板只返回给管理员
+// @Tags MCP资源
+// @Accept json
+// @Produce json
+// @Param name path string true "MCP服务器名称"
+// @Success 200 {object} utils.Response{data=vo.MCPResourcesVO}
+// @Router /api/mcp/servers/{name}/resources [get]
+func ListResources(ctx *gin.Context) {
+	manager := utils.GetMCPClientManagerFromContext[*mcp.MCPClientManager](ctx)
+	resources, templates, err := manager.ListResources(ctx, ctx.Param("name"))
+	if err != nil {
+		utils.ErrorWithMsg(ctx, err.Error(), err)
+		return
+	}
+	// 普通用户不能读取填充资源模板得到的地址，不返回资源模板
+	if !utils.GetIsAdminFromContext(ctx) {
+		templates = nil
+	}
+	utils.OkWithData(ctx, vo.ToMCPResourcesVO(resources, templates))
+}
+
+// ReadResource 读取MCP服务器的资源
+// @Summary 读取MCP服务器的资源
+// @Description 读取指定MCP服务器的资源内容，普通用户只能读取服务器列出的资源，管理员可以读取填充资源模板得到的地址
+// @Tags MCP资源
+// @Accept json
+// @Produce json
+// @Param name path string true "MCP服务器名称"
+// @Param uri query string true "资源地址"
+// @Success 200 {object} utils.Response{data=[]vo.MCPResourceContentVO}
+// @Router /api/mcp/servers/{name}/resource [get]
+func ReadResource(ctx *gin.Context) {
+	var req dto.ReadMCPResourceReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	manager := utils.GetMCPClientManagerFromContext[*mcp.MCPClientManager](ctx)
+	contents, err := mcpservice.ReadResource(ctx, manager, ctx.Param("name"), req.URI, utils.GetIsAdminFromContext(ctx))
+	if err != nil {
+		utils.ErrorWithMsg(ctx, err.Error(), err)
+		return
+	}
+	utils.OkWithData(ctx, vo.ToMCPResourceContentVOs(contents))
+}
+
+// ListPrompts 获取MCP服务器的 prompt
+// @Summary 获取MCP服务器的 prompt
+// @Description 获取指定MCP服务器提供的 prompt 模板，可以导入为预设
+// @Tags MCP资源
+// @Accept json
+// @Produce json
+// @Param name path string true "MCP服务器名称"
+// @Success 200 {object} utils.Response{data=[]vo.MCPPromptVO}
+// @Router /api/mcp/servers/{name}/prompts [get]
+func ListPrompts(ctx *gin.Context) {
+	manager := utils.GetMCPClientManagerFromContext[*mcp.MCPClientManager](ctx)
+	prompts, err := manager.ListPrompts(ctx, ctx.Param("name"))
+	if err != nil {
+		utils.ErrorWithMsg(ctx, err.Error(), err)
+		return
+	}
+	utils.OkWithData(ctx, vo.ToMCPPromptVOs(prompts))
+}
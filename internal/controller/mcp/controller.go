@@ -0,0 +1,324 @@
+package mcp
+
+import (
+	"context"
+	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/global/logging/log"
+	mcpservice "txing-ai/internal/service/mcp"
+	"txing-ai/internal/tool/mcp"
+	"txing-ai/internal/utils"
+	"txing-ai/internal/utils/page"
+	"txing-ai/internal/vo"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 测试连接的超时时间，stdio 方式首次启动可能需要下载依赖
+const testConnectionTimeout = time.Minute
+
+// Create 创建MCP服务器
+// @Summary 创建MCP服务器
+// @Description 创建新的MCP服务器，启用后立即连接并提供工具给智能体
+// @Tags MCP服务器管理
+// @Accept json
+// @Produce json
+// @Param data body dto.CreateMCPServerReq true "MCP服务器信息"
+// @Success 200 {object} utils.Response{data=vo.MCPServerVO}
+// @Router /api/admin/mcp [post]
+func Create(ctx *gin.Context) {
+	var req dto.CreateMCPServerReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	server := &domain.MCPServer{}
+	applyReq(server, &req)
+	if !validateServer(ctx, db, server) {
+		return
+	}
+
+	if err := db.Create(server).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "创建MCP服务器失败", err)
+		return
+	}
+
+	syncServers(ctx, db)
+	utils.OkWithData(ctx, toServerVO(ctx, *server))
+}
+
+// Update 更新MCP服务器
+// @Summary 更新MCP服务器
+// @Description 更新MCP服务器信息，环境变量、请求头和认证令牌未修改时提交脱敏后的原值即可，连接配置有变化时重新连接
+// @Tags MCP服务器管理
+// @Accept json
+// @Produce json
+// @Param id path int true "MCP服务器ID"
+// @Param data body dto.UpdateMCPServerReq true "MCP服务器信息"
+// @Success 200 {object} utils.Response{data=vo.MCPServerVO}
+// @Router /api/admin/mcp/{id} [put]
+func Update(ctx *gin.Context) {
+	var req dto.UpdateMCPServerReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	var server domain.MCPServer
+	if err := db.First(&server, ctx.Param("id")).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "MCP服务器不存在", err)
+		return
+	}
+
+	// 还原未修改的脱敏值
+	req.Environment = utils.UnmaskSecrets(req.Environment, server.Environment)
+	req.Headers = utils.UnmaskSecrets(req.Headers, server.Headers)
+	req.AuthToken = utils.UnmaskSecret(req.AuthToken, server.AuthToken)
+	applyReq(&server, &req.CreateMCPServerReq)
+	if !validateServer(ctx, db, &server) {
+		return
+	}
+
+	if err := db.Save(&server).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "更新MCP服务器失败", err)
+		return
+	}
+
+	syncServers(ctx, db)
+	utils.OkWithData(ctx, toServerVO(ctx, server))
+}
+
+// Delete 删除MCP服务器
+// @Summary 删除MCP服务器
+// @Description 删除MCP服务器并断开连接
+// @Tags MCP服务器管理
+// @Accept json
+// @Produce json
+// @Param id path int true "MCP服务器ID"
+// @Success 200 {object} utils.Response
+// @Router /api/admin/mcp/{id} [delete]
+func Delete(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	var server domain.MCPServer
+	if err := db.First(&server, ctx.Param("id")).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "MCP服务器不存在", err)
+		return
+	}
+
+	if err := db.Delete(&server).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "删除MCP服务器失败", err)
+		return
+	}
+
+	syncServers(ctx, db)
+	utils.OkWithMsg(ctx, "删除成功")
+}
+
+// Get 获取MCP服务器详情
+// @Summary 获取MCP服务器详情
+// @Description 获取MCP服务器的配置、连接状态和当前提供的工具
+// @Tags MCP服务器管理
+// @Accept json
+// @Produce json
+// @Param id path int true "MCP服务器ID"
+// @Success 200 {object} utils.Response{data=vo.MCPServerVO}
+// @Router /api/admin/mcp/{id} [get]
+func Get(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	var server domain.MCPServer
+	if err := db.First(&server, ctx.Param("id")).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "MCP服务器不存在", err)
+		return
+	}
+
+	utils.OkWithData(ctx, toServerVO(ctx, server))
+}
+
+// List 获取MCP服务器列表
+// @Summary 获取MCP服务器列表
+// @Description 获取MCP服务器列表，支持分页
+// @Tags MCP服务器管理
+// @Accept json
+// @Produce json
+// @Param page query int true "页码" minimum(1)
+// @Param limit query int true "每页数量" minimum(1)
+// @Param order_by query string false "排序字段"
+// @Param order query string false "排序方式(asc/desc)"
+// @Param name query string false "服务器名称"
+// @Param transport query string false "传输方式"
+// @Param enabled query bool false "启用状态"
+// @Success 200 {object} utils.Response
+// @Router /api/admin/mcp/list [get]
+func List(ctx *gin.Context) {
+	var req dto.ListMCPServerReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	// 构建查询条件
+	query := db.Model(&domain.MCPServer{})
+	if req.Name != "" {
+		query = query.Where("name like ?", "%"+req.Name+"%")
+	}
+	if req.Transport != "" {
+		query = query.Where("transport = ?", req.Transport)
+	}
+	if req.Enabled != nil {
+		query = query.Where("enabled = ?", *req.Enabled)
+	}
+
+	var servers []domain.MCPServer
+
+	// 获取分页数据
+	pageVo, err := page.Paginate[domain.MCPServer](query, req.PageRequest, &servers)
+	if err != nil {
+		utils.ErrorWithMsg(ctx, "获取MCP服务器列表失败", err)
+		return
+	}
+
+	// 转换为 VO
+	serverVOs := lo.Map(servers, func(server domain.MCPServer, _ int) vo.MCPServerVO {
+		return toServerVO(ctx, server)
+	})
+	convert := page.Convert(pageVo, serverVOs)
+
+	utils.OkWithData(ctx, convert)
+}
+
+// Test 测试连接MCP服务器
+// @Summary 测试连接MCP服务器
+// @Description 使用已保存的配置连接MCP服务器并返回发现的工具，不影响正在运行的连接
+// @Tags MCP服务器管理
+// @Accept json
+// @Produce json
+// @Param id path int true "MCP服务器ID"
+// @Success 200 {object} utils.Response{data=[]vo.MCPToolVO}
+// @Router /api/admin/mcp/{id}/test [post]
+func Test(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	var server domain.MCPServer
+	if err := db.First(&server, ctx.Param("id")).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "MCP服务器不存在", err)
+		return
+	}
+
+	testCtx, cancel := context.WithTimeout(ctx, testConnectionTimeout)
+	defer cancel()
+	tools, err := mcp.TestConnection(testCtx, mcpservice.ToMCPServerConfig(&server))
+	if err != nil {
+		utils.ErrorWithMsg(ctx, "连接MCP服务器失败: "+err.Error(), err)
+		return
+	}
+
+	utils.OkWithData(ctx, vo.ToMCPToolVOs(tools))
+}
+
+// UpdateTool 启用或禁用MCP工具
+// @Summary 启用或禁用MCP工具
+// @Description 禁用的工具不会提供给智能体，修改后立即生效，不需要重新连接
+// @Tags MCP服务器管理
+// @Accept json
+// @Produce json
+// @Param id path int true "MCP服务器ID"
+// @Param tool path string true "工具名称"
+// @Param data body dto.UpdateMCPToolReq true "启用状态"
+// @Success 200 {object} utils.Response{data=vo.MCPServerVO}
+// @Router /api/admin/mcp/{id}/tools/{tool} [put]
+func UpdateTool(ctx *gin.Context) {
+	var req dto.UpdateMCPToolReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	var server domain.MCPServer
+	if err := db.First(&server, ctx.Param("id")).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "MCP服务器不存在", err)
+		return
+	}
+
+	toolName := ctx.Param("tool")
+	if *req.Enabled {
+		server.DisabledTools = lo.Without(server.DisabledTools, toolName)
+	} else if !lo.Contains(server.DisabledTools, toolName) {
+		server.DisabledTools = append(server.DisabledTools, toolName)
+	}
+
+	if err := db.Save(&server).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "更新MCP工具失败", err)
+		return
+	}
+
+	syncServers(ctx, db)
+	utils.OkWithData(ctx, toServerVO(ctx, server))
+}
+
+// applyReq 将请求中的配置写入 MCPServer
+func applyReq(server *domain.MCPServer, req *dto.CreateMCPServerReq) {
+	server.Name = req.Name
+	server.Description = req.Description
+	server.Transport = req.Transport
+	server.Command = req.Command
+	server.Args = req.Args
+	server.Environment = req.Environment
+	server.URL = req.URL
+	server.Headers = req.Headers
+	server.AuthToken = req.AuthToken
+	server.Enabled = req.Enabled
+	server.DisabledTools = req.DisabledTools
+}
+
+// validateServer 校验MCP服务器配置和名称是否重复，校验失败时返回错误响应
+func validateServer(ctx *gin.Context, db *gorm.DB, server *domain.MCPServer) bool {
+	config := mcpservice.ToMCPServerConfig(server)
+	if err := config.Validate(); err != nil {
+		utils.ErrorWithMsg(ctx, "MCP服务器配置错误: "+err.Error(), err)
+		return false
+	}
+
+	var count int64
+	if err := db.Model(&domain.MCPServer{}).Where("name = ? AND id <> ?", server.Name, server.Id).Count(&count).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "查询MCP服务器失败", err)
+		return false
+	}
+	if count > 0 {
+		utils.ErrorWithMsg(ctx, "MCP服务器名称已存在", nil)
+		return false
+	}
+	return true
+}
+
+// syncServers 将修改同步到当前实例的MCP客户端，连接失败的服务器会在后台重连，只记录日志
+func syncServers(ctx *gin.Context, db *gorm.DB) {
+	manager := utils.GetMCPClientManagerFromContext[*mcp.MCPClientManager](ctx)
+	if err := mcpservice.SyncMCPServers(db, manager); err != nil {
+		log.Warn("sync mcp servers failed", zap.Error(err))
+	}
+}
+
+// toServerVO 转换为 VO，并附带当前实例中的连接状态
+func toServerVO(ctx *gin.Context, server domain.MCPServer) vo.MCPServerVO {
+	manager := utils.GetMCPClientManagerFromContext[*mcp.MCPClientManager](ctx)
+	var status *mcp.ServerStatus
+	if server.Enabled {
+		status, _ = manager.ServerStatus(server.Name)
+	}
+	return vo.ToMCPServerVO(server, status)
+}
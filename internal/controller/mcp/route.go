@@ -0,0 +1,30 @@
+package mcp
+
+import (
+	"github.com/gin-gonic/gin"
+	"txing-ai/internal/middleware"
+)
+
+func Register(router gin.IRouter) {
+
+	groupRouter := router.Group("/admin/mcp", middleware.AuthMiddleware())
+	{
+		groupRouter.POST("", Create)
+		groupRouter.PUT("/:id", Update)
+		groupRouter.DELETE("/:id", Delete)
+		groupRouter.GET("/:id", Get)
+		groupRouter.GET("/list", List)
+		groupRouter.POST("/:id/test", Test)
+		groupRouter.PUT("/:id/tools/:tool", UpdateTool)
+	}
+
+	// 浏览MCP服务器提供的资源和 prompt
+	browseRouter := router.Group("/mcp/servers", middleware.AuthMiddleware())
+	{
+		browseRouter.GET("", ListServers)
+		browseRouter.GET("/:name/resources", ListResources)
+		browseRouter.GET("/:name/resource", ReadResource)
+		browseRouter.GET("/:name/prompts", ListPrompts)
+	}
+
+}
@@ -0,0 +1,247 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"txing-ai/internal/agent"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	fileservice "txing-ai/internal/service/file"
+	"txing-ai/internal/storage"
+	mytool "txing-ai/internal/tool"
+	"txing-ai/internal/utils"
+	"txing-ai/internal/utils/httputils"
+	"txing-ai/internal/vo"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 智能体工具名称前缀
+	agentToolPrefix = "agent_"
+	// 智能体使用的模型，和网页端流式执行保持一致
+	agentModel     = "deepseek-v3"
+	agentModelType = global.LLMTypeModel
+
+	// 附件的最大大小
+	maxAttachmentSize = 20 << 20
+	// 下载附件的超时时间
+	attachmentTimeout = time.Minute
+)
+
+// attachmentClient 下载附件使用的客户端，拒绝访问内网地址，避免通过附件地址探测服务端所在的网络
+var attachmentClient = httputils.NewSafeClient(attachmentTimeout)
+
+// addAgentTools 将已注册的智能体注册为 MCP 工具，执行过程通过进度通知推送
+func (s *Server) addAgentTools() {
+	for _, agentType := range s.agentFactory.AgentTypes() {
+		instance, err := s.agentFactory.CreateAgent(agentType)
+		if err != nil {
+			log.Error("create agent failed", zap.String("agentType", string(agentType)), zap.Error(err))
+			continue
+		}
+		description := instance.GetDescription()
+		if description == "" {
+			description = instance.GetName()
+		}
+		s.mcpServer.AddTool(mcp.NewTool(agentToolPrefix+string(agentType),
+			mcp.WithDescription(description+"。执行耗时较长（数分钟），执行过程通过进度通知推送，需要用户审批的工具调用会在进度中给出审批ID，可在网页端或调用 /api/agent/approval 处理"),
+			mcp.WithString("content", mcp.Required(), mcp.Description("任务内容")),
+			mcp.WithString("file_url", mcp.Description("附件的下载地址（http/https），例如简历优化智能体需要的简历 PDF")),
+		), s.agentToolHandler(agentType))
+	}
+}
+
+func (s *Server) agentToolHandler(agentType agent.AgentType) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ginCtx := ginContext(ctx)
+		content, err := request.RequireString("content")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		agentInstance, err := s.agentFactory.CreateAgent(agentType)
+		if err != nil {
+			log.Error("create agent failed", zap.Error(err))
+			return mcp.NewToolResultError("创建智能体失败"), nil
+		}
+		db := utils.GetDBFromContext[*gorm.DB](ginCtx)
+		endpoint, apiKey, model, err := s.chooseChannel(db)
+		if err != nil {
+			log.Error("choose channel failed", zap.Error(err))
+			return mcp.NewToolResultError("选择渠道失败"), nil
+		}
+
+		if result := checkLimit(ginCtx, utils.BusinessTypeResume); result != nil {
+			return result, nil
+		}
+
+		userId, _ := utils.GetUIDFromContextAllowEmpty(ginCtx)
+		blobStores := utils.GetBlobStoresFromContext[*storage.Stores](ginCtx)
+		filePath := ""
+		if fileURL := request.GetString("file_url", ""); fileURL != "" {
+			var inspection *fileservice.Inspection
+			filePath, inspection, err = downloadAttachment(ctx, db, fileURL, userId, agent.AttachmentPurpose(agentType))
+			if err != nil {
+				log.Error("download attachment failed", zap.String("url", fileURL), zap.Error(err))
+				return mcp.NewToolResultError("下载附件失败: " + err.Error()), nil
+			}
+			if _, err := fileservice.RegisterLocalUpload(ctx, db, blobStores, userId, filePath, inspection, domain.FileSourceAgent, string(agentType)); err != nil {
+				log.Error("register attachment failed", zap.String("path", filePath), zap.Error(err))
+			}
+		}
+
+		// 记录工具生成的文件，执行结束后按文件ID返回
+		runCtx, outputFiles := mytool.WithOutputFiles(ctx, db, blobStores, userId)
+		runCtx, structured := agent.WithStructuredOutput(runCtx)
+		reporter := newProgressReporter(ctx, request)
+		response, err := agentInstance.ExecuteStream(runCtx, endpoint, apiKey, model, content, filePath, reporter.onChunk)
+		if err != nil {
+			log.Error("mcp execute agent failed", zap.String("agentType", string(agentType)), zap.Error(err))
+			return mcp.NewToolResultError("执行智能体失败: " + err.Error()), nil
+		}
+		return agentResult(ginCtx, structured, response, outputFiles.List()), nil
+	}
+}
+
+// agentResult 构建智能体的执行结果，带上本次运行生成文件的完整下载地址
+// 结构化输出解析失败时退回到原始输出
+func agentResult(ctx *gin.Context, structured *agent.StructuredOutput, response string, outputFiles []*domain.File) *mcp.CallToolResult {
+	output := structured.Output
+	content := response
+	if output != nil {
+		content = output.Content
+	}
+
+	files := vo.ToAgentOutputFileVOs(outputFiles, output)
+	if output == nil && len(files) == 0 {
+		return mcp.NewToolResultText(response)
+	}
+	baseURL := requestBaseURL(ctx)
+	text := content
+	for _, fileVO := range files {
+		fileVO.URL = baseURL + fileVO.URL
+		text += fmt.Sprintf("\n\n- [%s](%s)", fileVO.Name, fileVO.URL)
+	}
+	return mcp.NewToolResultStructured(map[string]interface{}{
+		"content": content,
+		"files":   files,
+	}, text)
+}
+
+// requestBaseURL 当前请求的访问地址，用于拼接文件的下载地址
+func requestBaseURL(ctx *gin.Context) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := ctx.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + ctx.Request.Host
+}
+
+// downloadAttachment 下载附件，按智能体的用途校验后保存到当前用户的上传目录，返回保存的文件路径和检查结果
+func downloadAttachment(ctx context.Context, db *gorm.DB, fileURL string, userId int64, purpose fileservice.Purpose) (string, *fileservice.Inspection, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", nil, fmt.Errorf("附件地址不是合法的 http(s) 地址")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, attachmentTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := attachmentClient.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("状态码：%d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxAttachmentSize {
+		return "", nil, fmt.Errorf("附件超过 %d MB", maxAttachmentSize>>20)
+	}
+
+	fileName := path.Base(u.Path)
+	if fileName == "" || fileName == "/" || fileName == "." {
+		fileName = "attachment"
+	}
+	// 地址中没有扩展名时按响应的类型补上允许的扩展名
+	if path.Ext(fileName) == "" {
+		if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			exts, _ := mime.ExtensionsByType(mediaType)
+			if i := slices.IndexFunc(exts, func(ext string) bool {
+				return slices.Contains(purpose.Extensions(), ext)
+			}); i >= 0 {
+				fileName += exts[i]
+			}
+		}
+	}
+	return fileservice.SaveLocalUpload(ctx, db, userId, purpose, fileName, io.LimitReader(resp.Body, maxAttachmentSize))
+}
+
+// progressReporter 通过 MCP 进度通知推送智能体的执行过程，客户端没有提供 progressToken 时不推送
+type progressReporter struct {
+	ctx   context.Context
+	token mcp.ProgressToken
+
+	mu       sync.Mutex
+	progress int
+}
+
+func newProgressReporter(ctx context.Context, request mcp.CallToolRequest) *progressReporter {
+	reporter := &progressReporter{ctx: ctx}
+	if request.Params.Meta != nil {
+		reporter.token = request.Params.Meta.ProgressToken
+	}
+	return reporter
+}
+
+// onChunk 智能体的回调，只推送工具调用、审批等过程信息，模型输出的内容在执行结束后一次性返回
+func (r *progressReporter) onChunk(chunk *global.Chunk) error {
+	message := chunk.ShowMsg
+	if chunk.Event == agent.EventApprovalRequest {
+		if approval, ok := chunk.Data.(agent.ApprovalRequest); ok {
+			message = fmt.Sprintf("等待确认工具调用：%s，审批ID：%s", approval.ToolName, approval.ApprovalId)
+		}
+	}
+	if r.token == nil || strings.TrimSpace(message) == "" {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress++
+	mcpServer := server.ServerFromContext(r.ctx)
+	if mcpServer == nil {
+		return nil
+	}
+	err := mcpServer.SendNotificationToClient(r.ctx, "notifications/progress", map[string]any{
+		"progressToken": r.token,
+		"progress":      r.progress,
+		"message":       message,
+	})
+	if err != nil {
+		// 推送失败不影响智能体继续执行
+		log.Warn("send mcp progress notification failed", zap.Error(err))
+	}
+	return nil
+}
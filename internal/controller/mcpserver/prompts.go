@@ -0,0 +1,87 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/utils"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 预设 prompt 名称前缀，后接预设ID
+const presetPromptPrefix = "preset_"
+
+// listPrompts 预设因用户而异（官方预设和用户自己的预设），列出 prompt 时按当前用户查询
+func (s *Server) listPrompts(ctx context.Context, id any, message *mcp.ListPromptsRequest, result *mcp.ListPromptsResult) {
+	ginCtx := ginContext(ctx)
+	db := utils.GetDBFromContext[*gorm.DB](ginCtx)
+	userId, _ := utils.GetUIDFromContextAllowEmpty(ginCtx)
+
+	var presets []*domain.Preset
+	if err := db.Where("official = ? OR user_id = ?", true, userId).Order("id").Find(&presets).Error; err != nil {
+		log.Error("list presets failed", zap.Error(err))
+		return
+	}
+	prompts := make([]mcp.Prompt, 0, len(presets))
+	for _, preset := range presets {
+		prompts = append(prompts, presetPrompt(preset))
+	}
+	result.Prompts = prompts
+}
+
+// registerPrompt 获取预设 prompt 前按需注册处理函数，处理函数中再校验当前用户是否可以使用该预设
+func (s *Server) registerPrompt(ctx context.Context, id any, message *mcp.GetPromptRequest) {
+	if !strings.HasPrefix(message.Params.Name, presetPromptPrefix) {
+		return
+	}
+	presetId, err := strconv.ParseInt(strings.TrimPrefix(message.Params.Name, presetPromptPrefix), 10, 64)
+	if err != nil {
+		return
+	}
+	s.mcpServer.AddPrompt(mcp.NewPrompt(message.Params.Name), s.presetPromptHandler(presetId))
+}
+
+func (s *Server) presetPromptHandler(presetId int64) func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		ginCtx := ginContext(ctx)
+		db := utils.GetDBFromContext[*gorm.DB](ginCtx)
+		userId, _ := utils.GetUIDFromContextAllowEmpty(ginCtx)
+
+		var preset domain.Preset
+		err := db.Where("id = ? AND (official = ? OR user_id = ?)", presetId, true, userId).First(&preset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("预设不存在")
+		}
+		if err != nil {
+			log.Error("get preset failed", zap.Error(err))
+			return nil, fmt.Errorf("获取预设失败")
+		}
+
+		text := preset.Context
+		if input := request.Params.Arguments["input"]; input != "" {
+			text += "\n\n" + input
+		}
+		return mcp.NewGetPromptResult(preset.Description, []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+		}), nil
+	}
+}
+
+// presetPrompt 将预设转换为 MCP prompt
+func presetPrompt(preset *domain.Preset) mcp.Prompt {
+	description := preset.Name
+	if preset.Description != "" {
+		description += "：" + preset.Description
+	}
+	return mcp.NewPrompt(presetPromptPrefix+strconv.FormatInt(preset.Id, 10),
+		mcp.WithPromptDescription(description),
+		mcp.WithArgument("input", mcp.ArgumentDescription("追加在预设上下文之后的用户输入")),
+	)
+}
@@ -0,0 +1,145 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"txing-ai/internal/agent"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/service/channel"
+	"txing-ai/internal/tool"
+	"txing-ai/internal/utils"
+
+	einotool "github.com/cloudwego/eino/components/tool"
+	"github.com/gin-gonic/gin"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	serverName    = "txing-ai"
+	serverVersion = "1.0.0"
+
+	exceedUseLimit = "您今日的使用次数已达上限（%d次），请明天再来尝试啦！"
+)
+
+// 选择智能体使用的渠道和模型，返回渠道地址、密钥和映射后的模型
+type channelChooser func(db *gorm.DB) (endpoint string, apiKey string, model string, err error)
+
+// Server 以 MCP 服务器（Streamable HTTP）的形式对外提供平台能力：
+// 内置工具作为普通工具，智能体作为长时间运行的工具，预设作为 prompt
+type Server struct {
+	mcpServer     *server.MCPServer
+	httpServer    *server.StreamableHTTPServer
+	agentFactory  agent.AgentFactory
+	approvals     *agent.ApprovalManager
+	chooseChannel channelChooser
+}
+
+// New 创建 MCP 服务器
+func New(agentFactory agent.AgentFactory, approvals *agent.ApprovalManager) *Server {
+	return newServer(agentFactory, approvals, tool.BuiltinTools(), chooseAgentChannel)
+}
+
+func newServer(agentFactory agent.AgentFactory, approvals *agent.ApprovalManager,
+	builtinTools []einotool.BaseTool, chooseChannel channelChooser) *Server {
+	s := &Server{
+		agentFactory:  agentFactory,
+		approvals:     approvals,
+		chooseChannel: chooseChannel,
+	}
+
+	hooks := &server.Hooks{}
+	hooks.AddAfterListPrompts(s.listPrompts)
+	hooks.AddBeforeGetPrompt(s.registerPrompt)
+
+	s.mcpServer = server.NewMCPServer(serverName, serverVersion,
+		server.WithToolCapabilities(false),
+		server.WithPromptCapabilities(false),
+		server.WithToolFilter(s.filterTools),
+		server.WithHooks(hooks),
+		server.WithRecovery(),
+		server.WithInstructions("txing-ai 平台提供的工具、智能体和预设。智能体工具耗时较长，会通过进度通知推送执行过程。"),
+	)
+	s.addBuiltinTools(builtinTools)
+	s.addAgentTools()
+
+	s.httpServer = server.NewStreamableHTTPServer(s.mcpServer)
+	return s
+}
+
+// Register 注册 MCP 接口，支持登录令牌和 API Key 认证
+func (s *Server) Register(router gin.IRouter, middlewares ...gin.HandlerFunc) {
+	handlers := append(middlewares, s.handle)
+	router.Match([]string{"GET", "POST", "DELETE"}, "/mcp", handlers...)
+}
+
+// handle 将请求交给 MCP 服务器处理，请求处理期间 gin.Context 保持有效
+func (s *Server) handle(ctx *gin.Context) {
+	request := ctx.Request.WithContext(&callerContext{Context: ctx.Request.Context(), ginCtx: ctx})
+	s.httpServer.ServeHTTP(ctx.Writer, request)
+}
+
+// callerContext 让 MCP 请求的 context 可以读取 gin.Context 中的当前用户、数据库等信息
+type callerContext struct {
+	context.Context
+	ginCtx *gin.Context
+}
+
+type ginContextKey struct{}
+
+func (c *callerContext) Value(key any) any {
+	if key == (ginContextKey{}) {
+		return c.ginCtx
+	}
+	if value := c.Context.Value(key); value != nil {
+		return value
+	}
+	return c.ginCtx.Value(key)
+}
+
+// ginContext 获取 MCP 请求对应的 gin.Context
+func ginContext(ctx context.Context) *gin.Context {
+	return ctx.Value(ginContextKey{}).(*gin.Context)
+}
+
+// filterTools 不列出被系统禁止调用的工具
+func (s *Server) filterTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	if s.approvals == nil {
+		return tools
+	}
+	filtered := make([]mcp.Tool, 0, len(tools))
+	for _, t := range tools {
+		if s.approvals.Policy(t.Name) != agent.ToolPolicyDeny {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// checkLimit 检查并增加当前用户的使用次数，和网页端共用每日限制，超过限制时返回错误结果
+func checkLimit(ctx *gin.Context, businessType string) *mcp.CallToolResult {
+	messageLimiter := utils.GetMessageLimiterFromContext[*utils.MessageLimiter](ctx)
+	userId, _ := utils.GetUIDFromContextAllowEmpty(ctx)
+	allowed, err := messageLimiter.CheckAndIncrement(ctx, userId, utils.GetRoleFromContext(ctx), businessType)
+	if err != nil {
+		log.Error("check use limit error", zap.Error(err))
+		return mcp.NewToolResultError("检查使用次数失败，请稍后再试")
+	}
+	if !allowed {
+		return mcp.NewToolResultError(fmt.Sprintf(exceedUseLimit, utils.BusinessUseLimits[businessType]))
+	}
+	return nil
+}
+
+// chooseAgentChannel 和网页端流式执行智能体使用相同的模型
+func chooseAgentChannel(db *gorm.DB) (string, string, string, error) {
+	ch, mappingModel, err := channel.ChooseChannelAndModel(db, agentModel, map[string]interface{}{
+		"type": agentModelType,
+	})
+	if err != nil {
+		return "", "", "", err
+	}
+	return ch.GetEndpoint(), ch.GetRandomSecret(), mappingModel, nil
+}
@@ -0,0 +1,210 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"txing-ai/internal/agent"
+	"txing-ai/internal/enum"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging"
+	"txing-ai/internal/storage"
+	"txing-ai/internal/utils"
+	"txing-ai/internal/utils/httputils"
+	"txing-ai/internal/utils/jsonschema"
+
+	einotool "github.com/cloudwego/eino/components/tool"
+	toolutils "github.com/cloudwego/eino/components/tool/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+	m.Run()
+}
+
+type fakeAgent struct{}
+
+func (a *fakeAgent) Execute(ctx context.Context, endpoint string, apiKey string, model string, input string) (string, error) {
+	return "", nil
+}
+
+func (a *fakeAgent) GetName() string { return "fake" }
+
+func (a *fakeAgent) GetDescription() string { return "测试智能体" }
+
+func (a *fakeAgent) ExecuteStream(ctx context.Context, endpoint string, apiKey string, model string,
+	content string, filePath string, callback func(chunk *global.Chunk) error) (string, error) {
+	_ = callback(&global.Chunk{ToolName: "web_search_tool", ShowMsg: "搜索：" + content})
+	_ = callback(&global.Chunk{Content: "只有内容的消息块不推送"})
+	// 进度通知异步写入响应，等待推送完成后再返回结果
+	time.Sleep(50 * time.Millisecond)
+	response := `{"content":"完成：` + content + `","files":[{"name":"攻略.pdf"}]}`
+	agent.ParseStructuredOutput(ctx, agent.FinalOutputSchema, response)
+	return response, nil
+}
+
+func (a *fakeAgent) OutputSchema() *jsonschema.Schema { return agent.FinalOutputSchema }
+
+type fakeFactory struct{}
+
+func (f *fakeFactory) CreateAgent(agentType agent.AgentType) (agent.Agent, error) {
+	return &fakeAgent{}, nil
+}
+
+func (f *fakeFactory) AgentTypes() []agent.AgentType {
+	return []agent.AgentType{agent.TravelAgentType}
+}
+
+type echoParams struct {
+	Text string `json:"text" jsonschema:"description=text to echo"`
+}
+
+func newTestClient(t *testing.T) *client.Client {
+	echoTool, err := toolutils.InferTool("echo_tool", "echo text", func(ctx context.Context, params *echoParams) (string, error) {
+		return "echo: " + params.Text, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chooser := func(db *gorm.DB) (string, string, string, error) {
+		return "http://llm", "sk-test", "test-model", nil
+	}
+	s := newServer(&fakeFactory{}, nil, []einotool.BaseTool{echoTool}, chooser)
+
+	engine := gin.New()
+	s.Register(engine, func(ctx *gin.Context) {
+		ctx.Set("db", (*gorm.DB)(nil))
+		ctx.Set("blobStores", (*storage.Stores)(nil))
+		ctx.Set("userId", int64(1))
+		ctx.Set("role", int8(enum.UserTypeSuper))
+		ctx.Set("messageLimiter", utils.NewMessageLimiter(nil))
+	})
+	httpServer := httptest.NewServer(engine)
+	t.Cleanup(httpServer.Close)
+
+	c, err := client.NewStreamableHttpClient(httpServer.URL + "/mcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Initialize(context.Background(), mcp.InitializeRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestServer(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var progress []string
+	c.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method != "notifications/progress" {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		message, _ := notification.Params.AdditionalFields["message"].(string)
+		progress = append(progress, message)
+	})
+
+	tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got != "agent_travel,echo_tool" {
+		t.Fatalf("tools = %s", got)
+	}
+
+	tests := []struct {
+		name     string
+		tool     string
+		args     map[string]any
+		wantText string
+		wantErr  bool
+	}{
+		{"builtin tool", "echo_tool", map[string]any{"text": "hi"}, "echo: hi", false},
+		{"agent tool", "agent_travel", map[string]any{"content": "北京"}, "完成：北京", false},
+		{"agent without content", "agent_travel", map[string]any{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Name = tt.tool
+			request.Params.Arguments = tt.args
+			request.Params.Meta = &mcp.Meta{ProgressToken: tt.name}
+			result, err := c.CallTool(ctx, request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.IsError != tt.wantErr {
+				t.Fatalf("IsError = %v, content = %+v", result.IsError, result.Content)
+			}
+			if tt.wantErr {
+				return
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !strings.HasPrefix(text, tt.wantText) {
+				t.Fatalf("text = %q, want prefix %q", text, tt.wantText)
+			}
+		})
+	}
+
+	// 智能体的结构化输出带上完整的下载地址，执行过程通过进度通知推送
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "agent_travel"
+	request.Params.Arguments = map[string]any{"content": "上海"}
+	result, err := c.CallTool(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Content[0].(mcp.TextContent).Text, "http://127.0.0.1") {
+		t.Fatalf("download url should be absolute: %+v", result.Content)
+	}
+	if result.StructuredContent == nil {
+		t.Fatal("structured content is empty")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(progress) != 1 || progress[0] != "搜索：北京" {
+		t.Fatalf("progress = %v", progress)
+	}
+}
+
+func TestDownloadAttachmentRejectsInternalAddress(t *testing.T) {
+	var requested bool
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	}))
+	defer internal.Close()
+
+	for _, fileURL := range []string{internal.URL + "/resume.pdf", "http://169.254.169.254/latest/meta-data/"} {
+		_, _, err := downloadAttachment(context.Background(), nil, fileURL, 1, agent.AttachmentPurpose(agent.ResumeAgentType))
+		if !errors.Is(err, httputils.ErrForbiddenAddress) {
+			t.Errorf("downloadAttachment(%s) error = %v, want ErrForbiddenAddress", fileURL, err)
+		}
+	}
+	if requested {
+		t.Error("internal address should not be requested")
+	}
+}
@@ -0,0 +1,82 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"txing-ai/internal/agent"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/utils"
+
+	einotool "github.com/cloudwego/eino/components/tool"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// 不对外提供的内置工具：文档读取工具可以读取运行目录下任意文件，无法按用户隔离；
+// 文件操作工具只能操作单次运行的工作区，每次 MCP 调用之间无法共享文件
+var excludedTools = map[string]bool{
+	"document_read_tool": true,
+	"file_read_tool":     true,
+	"file_write_tool":    true,
+	"file_replace_tool":  true,
+	"file_delete_tool":   true,
+	"file_list_tool":     true,
+}
+
+// addBuiltinTools 将内置工具注册为 MCP 工具
+// MCP 客户端在调用工具前会自行让用户确认，所以只处理禁止调用的策略，不走网页端的审批流程
+func (s *Server) addBuiltinTools(tools []einotool.BaseTool) {
+	for _, t := range tools {
+		invokable, ok := t.(einotool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(context.Background())
+		if err != nil {
+			log.Error("get tool info failed", zap.Error(err))
+			continue
+		}
+		if excludedTools[info.Name] {
+			continue
+		}
+
+		inputSchema := json.RawMessage(`{"type":"object","properties":{}}`)
+		if info.ParamsOneOf != nil {
+			schema, err := info.ParamsOneOf.ToJSONSchema()
+			if err != nil {
+				log.Error("convert tool params to json schema failed", zap.String("tool", info.Name), zap.Error(err))
+				continue
+			}
+			if inputSchema, err = json.Marshal(schema); err != nil {
+				log.Error("marshal tool json schema failed", zap.String("tool", info.Name), zap.Error(err))
+				continue
+			}
+		}
+
+		s.mcpServer.AddTool(mcp.NewToolWithRawSchema(info.Name, info.Desc, inputSchema),
+			s.builtinToolHandler(info.Name, invokable))
+	}
+}
+
+func (s *Server) builtinToolHandler(name string, invokable einotool.InvokableTool) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if s.approvals != nil && s.approvals.Policy(name) == agent.ToolPolicyDeny {
+			return mcp.NewToolResultError("工具 " + name + " 已被系统禁止调用"), nil
+		}
+		if result := checkLimit(ginContext(ctx), utils.BusinessTypeTool); result != nil {
+			return result, nil
+		}
+
+		arguments, err := json.Marshal(request.GetArguments())
+		if err != nil {
+			return mcp.NewToolResultError("工具参数格式错误: " + err.Error()), nil
+		}
+		output, err := invokable.InvokableRun(ctx, string(arguments))
+		if err != nil {
+			log.Error("mcp call builtin tool failed", zap.String("tool", name), zap.Error(err))
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(output), nil
+	}
+}
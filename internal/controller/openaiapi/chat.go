@@ -0,0 +1,268 @@
+package openaiapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	adaptercommon "txing-ai/internal/adapter/common"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/service/channel"
+	"txing-ai/internal/service/chat"
+	"txing-ai/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const exceedMessageLimit = "您今日的消息次数已达上限（%d条），请明天再试"
+
+// 模型列表中模型的所有者
+const modelOwner = "txing-ai"
+
+// ChatCompletions 聊天补全
+// @Summary 聊天补全（OpenAI 兼容）
+// @Description 通过渠道调用模型，请求和响应格式与 OpenAI 的 /v1/chat/completions 一致，stream 为 true 时以 SSE 返回消息块，与网页聊天共用每日消息次数
+// @Tags OpenAI 兼容接口
+// @Accept json
+// @Produce json,text/event-stream
+// @Param Authorization header string true "Bearer 登录令牌或 API Key"
+// @Param data body dto.ChatCompletionReq true "聊天补全请求"
+// @Success 200 {object} dto.ChatCompletionResp "非流式响应；流式响应为 dto.ChatCompletionChunk 组成的 SSE"
+// @Failure 400 {object} dto.OpenaiErrorResp
+// @Failure 429 {object} dto.OpenaiErrorResp
+// @Failure 502 {object} dto.OpenaiErrorResp
+// @Router /v1/chat/completions [post]
+func ChatCompletions(ctx *gin.Context) {
+	var req dto.ChatCompletionReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, http.StatusBadRequest, errorTypeInvalidRequest, err.Error())
+		return
+	}
+	chatConfig, err := toChatConfig(&req)
+	if err != nil {
+		abortWithError(ctx, http.StatusBadRequest, errorTypeInvalidRequest, err.Error())
+		return
+	}
+
+	// 与网页聊天使用相同的每日消息次数
+	messageLimiter := utils.GetMessageLimiterFromContext[*utils.MessageLimiter](ctx)
+	allowed, err := messageLimiter.CheckAndIncrement(ctx, utils.GetUIDFromContext(ctx), utils.GetRoleFromContext(ctx), utils.BusinessTypeChat)
+	if err != nil {
+		log.Error("check message limit error", zap.Error(err))
+		abortWithError(ctx, http.StatusInternalServerError, errorTypeServer, "check message limit failed")
+		return
+	}
+	if !allowed {
+		abortWithError(ctx, http.StatusTooManyRequests, errorTypeQuota, fmt.Sprintf(exceedMessageLimit, utils.BusinessUseLimits[utils.BusinessTypeChat]))
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	completion := &completion{
+		id:      newCompletionId(),
+		model:   req.Model,
+		created: time.Now().Unix(),
+	}
+
+	if req.Stream {
+		streamCompletion(ctx, db, chatConfig, completion)
+		return
+	}
+
+	var content, reasoningContent strings.Builder
+	err = chat.NewChatRequest(ctx.Request.Context(), db, chatConfig, func(chunk *global.Chunk) error {
+		content.WriteString(chunk.Content)
+		reasoningContent.WriteString(chunk.ReasoningContent)
+		return nil
+	})
+	if err != nil {
+		log.Error("chat completion failed", zap.String("model", req.Model), zap.Error(err))
+		abortWithError(ctx, http.StatusBadGateway, errorTypeServer, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.ChatCompletionResp{
+		Id:      completion.id,
+		Object:  "chat.completion",
+		Created: completion.created,
+		Model:   completion.model,
+		Choices: []dto.ChatCompletionChoice{{
+			Message: dto.ChatCompletionResult{
+				Role:             global.Assistant,
+				Content:          content.String(),
+				ReasoningContent: reasoningContent.String(),
+			},
+			FinishReason: finishReasonStop,
+		}},
+	})
+}
+
+const finishReasonStop = "stop"
+
+// completion 一次聊天补全的公共信息，流式响应的每个消息块都需要带上
+type completion struct {
+	id      string
+	model   string
+	created int64
+}
+
+// chunk 把增量内容包装为流式响应的消息块
+func (c *completion) chunk(delta dto.ChatCompletionResult, finishReason *string) dto.ChatCompletionChunk {
+	return dto.ChatCompletionChunk{
+		Id:      c.id,
+		Object:  "chat.completion.chunk",
+		Created: c.created,
+		Model:   c.model,
+		Choices: []dto.ChatCompletionChunkChoice{{Delta: delta, FinishReason: finishReason}},
+	}
+}
+
+// streamCompletion 把渠道返回的消息块转换为 OpenAI 格式的 SSE
+// 收到第一个消息块之前出错时按普通错误响应返回，之后出错时在流中发送错误信息
+func streamCompletion(ctx *gin.Context, db *gorm.DB, chatConfig *adaptercommon.ChatConfig, completion *completion) {
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		ctx.Header("Content-Type", "text/event-stream")
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.Header("Connection", "keep-alive")
+		// 关闭 Nginx 的响应缓冲，保证消息块即时发送
+		ctx.Header("X-Accel-Buffering", "no")
+		ctx.Status(http.StatusOK)
+		// 第一个消息块只带角色
+		_ = writeEvent(ctx, completion.chunk(dto.ChatCompletionResult{Role: global.Assistant}, nil))
+	}
+
+	err := chat.NewChatRequest(ctx.Request.Context(), db, chatConfig, func(chunk *global.Chunk) error {
+		if chunk.Content == "" && chunk.ReasoningContent == "" {
+			return nil
+		}
+		start()
+		return writeEvent(ctx, completion.chunk(dto.ChatCompletionResult{
+			Content:          chunk.Content,
+			ReasoningContent: chunk.ReasoningContent,
+		}, nil))
+	})
+	if err != nil {
+		log.Error("stream chat completion failed", zap.String("model", completion.model), zap.Error(err))
+		if !started {
+			abortWithError(ctx, http.StatusBadGateway, errorTypeServer, err.Error())
+			return
+		}
+		_ = writeEvent(ctx, dto.OpenaiErrorResp{Error: dto.OpenaiError{Message: err.Error(), Type: errorTypeServer}})
+		return
+	}
+
+	start()
+	finishReason := finishReasonStop
+	_ = writeEvent(ctx, completion.chunk(dto.ChatCompletionResult{}, &finishReason))
+	_, _ = ctx.Writer.WriteString("data: [DONE]\n\n")
+	ctx.Writer.Flush()
+}
+
+// writeEvent 发送一条 SSE 数据并立即刷新，客户端断开时返回错误以停止请求模型
+func writeEvent(ctx *gin.Context, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := ctx.Writer.WriteString("data: " + string(payload) + "\n\n"); err != nil {
+		return err
+	}
+	ctx.Writer.Flush()
+	return nil
+}
+
+// toChatConfig 把 OpenAI 格式的请求转换为渠道的聊天配置
+func toChatConfig(req *dto.ChatCompletionReq) (*adaptercommon.ChatConfig, error) {
+	messages := make([]global.Message, 0, len(req.Messages))
+	for i := range req.Messages {
+		message := &req.Messages[i]
+		content, err := message.Text()
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		role := message.Role
+		// developer 是新版 OpenAI 接口中 system 的别名
+		if role == "developer" {
+			role = global.System
+		}
+		messages = append(messages, global.Message{Role: role, Content: content, Name: message.Name})
+	}
+
+	chatConfig := &adaptercommon.ChatConfig{
+		Model:            req.Model,
+		Message:          messages,
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+	}
+	if req.MaxCompletionTokens != nil {
+		chatConfig.MaxTokens = req.MaxCompletionTokens
+	}
+	if format := req.ResponseFormat; format != nil && format.Type == "json_schema" {
+		if format.JSONSchema == nil || format.JSONSchema.Schema == nil {
+			return nil, fmt.Errorf("response_format.json_schema.schema is required")
+		}
+		if err := format.JSONSchema.Schema.Check(); err != nil {
+			return nil, fmt.Errorf("response_format.json_schema.schema: %w", err)
+		}
+		chatConfig.ResponseSchema = format.JSONSchema.Schema
+	}
+	return chatConfig, nil
+}
+
+// newCompletionId 生成聊天补全 ID，格式与 OpenAI 一致
+func newCompletionId() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return "chatcmpl-" + hex.EncodeToString(buf)
+}
+
+// Models 模型列表
+// @Summary 模型列表（OpenAI 兼容）
+// @Description 返回启用的渠道支持的所有模型，格式与 OpenAI 的 /v1/models 一致
+// @Tags OpenAI 兼容接口
+// @Produce json
+// @Param Authorization header string true "Bearer 登录令牌或 API Key"
+// @Success 200 {object} dto.ModelListResp
+// @Failure 500 {object} dto.OpenaiErrorResp
+// @Router /v1/models [get]
+func Models(ctx *gin.Context) {
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	names, err := channel.AvailableModels(db)
+	if err != nil {
+		log.Error("list available models failed", zap.Error(err))
+		abortWithError(ctx, http.StatusInternalServerError, errorTypeServer, "list models failed")
+		return
+	}
+
+	// 在模型管理中登记过的模型使用登记时间作为创建时间
+	var models []domain.Model
+	if err := db.Where("name IN ?", names).Find(&models).Error; err != nil {
+		log.Warn("query models failed", zap.Error(err))
+	}
+	created := make(map[string]int64, len(models))
+	for _, model := range models {
+		created[model.Name] = model.CreateTime.Unix()
+	}
+
+	data := make([]dto.OpenaiModel, len(names))
+	for i, name := range names {
+		data[i] = dto.OpenaiModel{Id: name, Object: "model", Created: created[name], OwnedBy: modelOwner}
+	}
+	ctx.JSON(http.StatusOK, dto.ModelListResp{Object: "list", Data: data})
+}
@@ -0,0 +1,44 @@
+package openaiapi
+
+import (
+	"encoding/json"
+	"testing"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/global"
+)
+
+func TestToChatConfig(t *testing.T) {
+	var req dto.ChatCompletionReq
+	err := json.Unmarshal([]byte(`{
+		"model": "deepseek-v3",
+		"messages": [
+			{"role": "developer", "content": "你是助手"},
+			{"role": "user", "content": [{"type": "text", "text": "你好，"}, {"type": "text", "text": "介绍一下自己"}]}
+		],
+		"max_tokens": 100,
+		"max_completion_tokens": 200,
+		"response_format": {"type": "json_schema", "json_schema": {"name": "answer", "schema": {"type": "object"}}}
+	}`), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chatConfig, err := toChatConfig(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chatConfig.Message) != 2 || chatConfig.Message[0].Role != global.System || chatConfig.Message[1].Content != "你好，介绍一下自己" {
+		t.Errorf("toChatConfig() messages = %+v", chatConfig.Message)
+	}
+	if chatConfig.MaxTokens == nil || *chatConfig.MaxTokens != 200 {
+		t.Errorf("toChatConfig() max tokens = %v", chatConfig.MaxTokens)
+	}
+	if chatConfig.ResponseSchema == nil {
+		t.Error("toChatConfig() 缺少 response schema")
+	}
+
+	req.Messages[1].Content = json.RawMessage(`[{"type": "image_url", "image_url": {"url": "https://example.com/a.png"}}]`)
+	if _, err := toChatConfig(&req); err == nil {
+		t.Error("toChatConfig() 不支持的内容类型应该返回错误")
+	}
+}
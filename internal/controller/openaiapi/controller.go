@@ -0,0 +1,150 @@
+package openaiapi
+
+import (
+	"encoding/base64"
+	"net/http"
+	adaptercommon "txing-ai/internal/adapter/common"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/service/channel"
+	"txing-ai/internal/utils"
+	"txing-ai/internal/vectorstore"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 每次向量化请求最多的输入数量，与 OpenAI 的限制一致
+	maxEmbeddingInputs = 2048
+	// 每次重排序请求最多的文档数量
+	maxRerankDocuments = 1000
+)
+
+// 错误类型，与 OpenAI 一致
+const (
+	errorTypeInvalidRequest = "invalid_request_error"
+	errorTypeServer         = "server_error"
+	errorTypeQuota          = "insufficient_quota"
+)
+
+// Embeddings 向量化
+// @Summary 向量化（OpenAI 兼容）
+// @Description 通过支持该模型的渠道把文本转换为向量，请求和响应格式与 OpenAI 的 /v1/embeddings 一致
+// @Tags OpenAI 兼容接口
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer 登录令牌或 API Key"
+// @Param data body dto.EmbeddingReq true "向量化请求"
+// @Success 200 {object} dto.EmbeddingResp
+// @Failure 400 {object} dto.OpenaiErrorResp
+// @Failure 502 {object} dto.OpenaiErrorResp
+// @Router /v1/embeddings [post]
+func Embeddings(ctx *gin.Context) {
+	var req dto.EmbeddingReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, http.StatusBadRequest, errorTypeInvalidRequest, err.Error())
+		return
+	}
+	inputs, err := req.Inputs()
+	if err != nil {
+		abortWithError(ctx, http.StatusBadRequest, errorTypeInvalidRequest, err.Error())
+		return
+	}
+	if len(inputs) == 0 || len(inputs) > maxEmbeddingInputs {
+		abortWithError(ctx, http.StatusBadRequest, errorTypeInvalidRequest, "input must contain 1 to 2048 items")
+		return
+	}
+	for _, input := range inputs {
+		if input == "" {
+			abortWithError(ctx, http.StatusBadRequest, errorTypeInvalidRequest, "input cannot contain empty strings")
+			return
+		}
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	result, err := channel.NewEmbeddingRequest(ctx, db, &adaptercommon.EmbeddingConfig{
+		Model:      req.Model,
+		Input:      inputs,
+		Dimensions: req.Dimensions,
+	})
+	if err != nil {
+		log.Error("create embeddings failed", zap.String("model", req.Model), zap.Error(err))
+		abortWithError(ctx, http.StatusBadGateway, errorTypeServer, err.Error())
+		return
+	}
+
+	data := make([]dto.EmbeddingData, len(result.Data))
+	for i, vector := range result.Data {
+		var embedding interface{} = vector
+		if req.EncodingFormat == "base64" {
+			embedding = base64.StdEncoding.EncodeToString(vectorstore.Encode(vector))
+		}
+		data[i] = dto.EmbeddingData{Object: "embedding", Index: i, Embedding: embedding}
+	}
+	ctx.JSON(http.StatusOK, dto.EmbeddingResp{
+		Object: "list",
+		Data:   data,
+		// 返回调用方请求的模型名称，而不是渠道映射后的名称
+		Model: req.Model,
+		Usage: dto.OpenaiUsage{PromptTokens: result.Usage.PromptTokens, TotalTokens: result.Usage.TotalTokens},
+	})
+}
+
+// Rerank 重排序
+// @Summary 重排序
+// @Description 通过支持该模型的渠道按与查询的相关性给文档打分，请求和响应格式与 Jina、Cohere 的重排序接口一致
+// @Tags OpenAI 兼容接口
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer 登录令牌或 API Key"
+// @Param data body dto.RerankReq true "重排序请求"
+// @Success 200 {object} dto.RerankResp
+// @Failure 400 {object} dto.OpenaiErrorResp
+// @Failure 502 {object} dto.OpenaiErrorResp
+// @Router /v1/rerank [post]
+func Rerank(ctx *gin.Context) {
+	var req dto.RerankReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, http.StatusBadRequest, errorTypeInvalidRequest, err.Error())
+		return
+	}
+	if len(req.Documents) > maxRerankDocuments {
+		abortWithError(ctx, http.StatusBadRequest, errorTypeInvalidRequest, "documents must contain at most 1000 items")
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+
+	result, err := channel.NewRerankRequest(ctx, db, &adaptercommon.RerankConfig{
+		Model:     req.Model,
+		Query:     req.Query,
+		Documents: req.Documents,
+		TopN:      req.TopN,
+	})
+	if err != nil {
+		log.Error("rerank failed", zap.String("model", req.Model), zap.Error(err))
+		abortWithError(ctx, http.StatusBadGateway, errorTypeServer, err.Error())
+		return
+	}
+
+	results := make([]dto.RerankResult, len(result.Results))
+	for i, item := range result.Results {
+		results[i] = dto.RerankResult{Index: item.Index, RelevanceScore: item.RelevanceScore}
+		if req.ReturnDocuments {
+			results[i].Document = &dto.RerankDocument{Text: req.Documents[item.Index]}
+		}
+	}
+	ctx.JSON(http.StatusOK, dto.RerankResp{
+		Model:   req.Model,
+		Results: results,
+		Usage:   dto.OpenaiUsage{PromptTokens: result.Usage.PromptTokens, TotalTokens: result.Usage.TotalTokens},
+	})
+}
+
+// abortWithError 按 OpenAI 的格式返回错误，OpenAI 的 SDK 可以直接解析
+func abortWithError(ctx *gin.Context, status int, errType string, message string) {
+	ctx.AbortWithStatusJSON(status, dto.OpenaiErrorResp{Error: dto.OpenaiError{Message: message, Type: errType}})
+}
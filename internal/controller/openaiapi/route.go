@@ -0,0 +1,24 @@
+package openaiapi
+
+import (
+	"github.com/gin-gonic/gin"
+	"txing-ai/internal/enum"
+	"txing-ai/internal/middleware"
+)
+
+// Register 注册 OpenAI 兼容接口，支持登录令牌和 API Key 认证
+// 客户端把 base_url 设置为 http(s)://<host>/v1 即可使用
+func Register(router gin.IRouter) {
+
+	chatRouter := router.Group("", middleware.ApiKeyAuthMiddleware(enum.ApiKeyScopeChat))
+	{
+		chatRouter.POST("/chat/completions", ChatCompletions)
+		chatRouter.GET("/models", Models)
+	}
+
+	embeddingRouter := router.Group("", middleware.ApiKeyAuthMiddleware(enum.ApiKeyScopeEmbedding))
+	{
+		embeddingRouter.POST("/embeddings", Embeddings)
+		embeddingRouter.POST("/rerank", Rerank)
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"txing-ai/internal/dto"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
+	knowledgeservice "txing-ai/internal/service/knowledge"
+	mcpservice "txing-ai/internal/service/mcp"
 	presetservice "txing-ai/internal/service/preset"
+	"txing-ai/internal/tool/mcp"
 	"txing-ai/internal/utils"
 	"txing-ai/internal/utils/page"
 	"txing-ai/internal/vo"
@@ -44,14 +47,28 @@ func Create(ctx *gin.Context) {
 		req.Official = false
 	}
 
+	avatar, err := cosClient.ConfirmObjectPath(ctx, userId, utils.UploadPurposePreset, req.Avatar, "")
+	if err != nil {
+		utils.ErrorWithMsg(ctx, "预设头像不可用："+err.Error(), err)
+		return
+	}
+
+	knowledgeBaseIds, err := knowledgeservice.CheckAccessible(db, userId, req.KnowledgeBaseIds, nil)
+	if err != nil {
+		utils.ErrorWithMsg(ctx, err.Error(), err)
+		return
+	}
+
 	preset := &domain.Preset{
 		UserID:      &userId,
-		Avatar:      cosClient.ConvertObjectPath(req.Avatar),
+		Avatar:      avatar,
 		Name:        req.Name,
 		Description: req.Description,
 		Context:     req.Context,
 		Tags:        req.Tags,
 		Official:    req.Official,
+
+		KnowledgeBaseIDs: knowledgeBaseIds,
 	}
 
 	if err := db.Create(preset).Error; err != nil {
@@ -62,6 +79,63 @@ func Create(ctx *gin.Context) {
 	utils.OkWithData(ctx, vo.ToPresetVO(*preset))
 }
 
+// ImportMCPPrompt 将MCP服务器的 prompt 导入为预设
+// @Summary 导入MCP prompt 为预设
+// @Description 使用指定参数获取MCP服务器的 prompt，将其消息作为预设上下文创建当前用户的预设
+// @Tags 预设管理
+// @Accept json
+// @Produce json
+// @Param data body dto.ImportMCPPromptReq true "MCP prompt 信息"
+// @Success 200 {object} utils.Response{data=vo.PresetVO}
+// @Router /api/preset/import/mcp [post]
+func ImportMCPPrompt(ctx *gin.Context) {
+	var req dto.ImportMCPPromptReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(ctx, err)
+		return
+	}
+
+	db := utils.GetDBFromContext[*gorm.DB](ctx)
+	userId := utils.GetUIDFromContext(ctx)
+	cosClient := utils.GetCosClientFromContext[*utils.COSClient](ctx)
+	manager := utils.GetMCPClientManagerFromContext[*mcp.MCPClientManager](ctx)
+
+	description, messages, err := manager.GetPrompt(ctx, req.Server, req.Prompt, req.Arguments)
+	if err != nil {
+		utils.ErrorWithMsg(ctx, err.Error(), err)
+		return
+	}
+	if len(messages) == 0 {
+		utils.ErrorWithMsg(ctx, "prompt 没有文本内容，无法导入为预设", nil)
+		return
+	}
+
+	name := req.Name
+	if name == "" {
+		name = req.Prompt
+	}
+	avatar, err := cosClient.ConfirmObjectPath(ctx, userId, utils.UploadPurposePreset, req.Avatar, "")
+	if err != nil {
+		utils.ErrorWithMsg(ctx, "预设头像不可用："+err.Error(), err)
+		return
+	}
+	preset := &domain.Preset{
+		UserID:      &userId,
+		Avatar:      avatar,
+		Name:        name,
+		Description: description,
+		Context:     mcpservice.PromptToPresetContext(messages),
+		Tags:        req.Tags,
+	}
+
+	if err := db.Create(preset).Error; err != nil {
+		utils.ErrorWithMsg(ctx, "导入预设失败", err)
+		return
+	}
+
+	utils.OkWithData(ctx, vo.ToPresetVO(*preset))
+}
+
 // Update 更新预设
 // @Summary 更新预设
 // @Description 更新预设信息
@@ -97,7 +171,12 @@ func Update(ctx *gin.Context) {
 	}
 
 	if req.Avatar != "" {
-		preset.Avatar = cosClient.ConvertObjectPath(req.Avatar)
+		avatar, err := cosClient.ConfirmObjectPath(ctx, userId, utils.UploadPurposePreset, req.Avatar, preset.Avatar)
+		if err != nil {
+			utils.ErrorWithMsg(ctx, "预设头像不可用："+err.Error(), err)
+			return
+		}
+		preset.Avatar = avatar
 	}
 	if req.Name != "" {
 		preset.Name = req.Name
@@ -114,6 +193,15 @@ func Update(ctx *gin.Context) {
 	if req.Official != nil && isAdmin {
 		preset.Official = *req.Official
 	}
+	if req.KnowledgeBaseIds != nil {
+		// 预设中已经引用的知识库不要求当前用户可以访问，管理员修改他人的官方预设时保留原有引用
+		knowledgeBaseIds, err := knowledgeservice.CheckAccessible(db, userId, req.KnowledgeBaseIds, preset.KnowledgeBaseIDs)
+		if err != nil {
+			utils.ErrorWithMsg(ctx, err.Error(), err)
+			return
+		}
+		preset.KnowledgeBaseIDs = knowledgeBaseIds
+	}
 
 	if err := db.Save(&preset).Error; err != nil {
 		utils.ErrorWithMsg(ctx, "更新预设失败", err)
@@ -10,6 +10,7 @@ func Register(router gin.IRouter) {
 	group := router.Group("/preset")
 
 	group.POST("", middleware.AuthMiddleware(), Create)
+	group.POST("/import/mcp", middleware.AuthMiddleware(), ImportMCPPrompt)
 	group.PUT("/:id", middleware.AuthMiddleware(), Update)
 	group.DELETE("/:id", middleware.AuthMiddleware(), Delete)
 	group.GET("/:id", middleware.AuthMiddleware(), Get)
@@ -181,7 +181,12 @@ func UpdateProfile(ctx *gin.Context) {
 		updates["age"] = req.Age
 	}
 	if req.Avatar != "" {
-		updates["avatar"] = cosClient.ConvertObjectPath(req.Avatar)
+		avatar, err := cosClient.ConfirmObjectPath(ctx, userId, utils.UploadPurposeAvatar, req.Avatar, user.Avatar)
+		if err != nil {
+			utils.ErrorWithMsg(ctx, "头像不可用："+err.Error(), err)
+			return
+		}
+		updates["avatar"] = avatar
 	}
 
 	// 使用 Updates 方法只更新有变化的字段
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// ApiKey 用户的 API Key，用于脚本、MCP 客户端等无法使用登录令牌的场景
+// 只保存 API Key 的摘要，明文只在创建时返回一次；吊销后保留记录，便于辨认
+type ApiKey struct {
+	BaseModel
+	UserID    int64  `gorm:"column:user_id;type:bigint;index;not null;comment:用户ID" json:"userId"`
+	Name      string `gorm:"type:varchar(100);not null;comment:名称" json:"name"`
+	KeyHash   string `gorm:"type:char(64);uniqueIndex;not null;comment:API Key 的 SHA-256 摘要" json:"-"`
+	KeyPrefix string `gorm:"type:varchar(20);comment:API Key 前缀，用于辨认" json:"keyPrefix"`
+	// 权限范围，为空表示不限制（兼容增加权限范围之前创建的 API Key）
+	Scopes       []string   `gorm:"type:json;serializer:json;comment:权限范围" json:"scopes"`
+	LastUsedTime *time.Time `gorm:"comment:最后使用时间" json:"lastUsedTime"`
+	RevokedTime  *time.Time `gorm:"comment:吊销时间" json:"revokedTime"`
+	User         *User      `gorm:"foreignKey:UserID;references:Id;constraint:OnUpdate:NO ACTION,OnDelete:NO ACTION" json:"-"`
+}
+
+// Revoked 是否已吊销
+func (k *ApiKey) Revoked() bool {
+	return k.RevokedTime != nil
+}
+
+// HasScope 是否拥有指定的权限范围
+func (k *ApiKey) HasScope(scope string) bool {
+	return len(k.Scopes) == 0 || lo.Contains(k.Scopes, scope)
+}
@@ -6,6 +6,7 @@ import (
 	"txing-ai/internal/dto"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/utils/jsonschema"
 	"unicode/utf8"
 
 	"github.com/samber/lo"
@@ -35,9 +36,13 @@ type Conversation struct {
 
 	// 预设 id
 	PresetID *int64 `gorm:"type:bigint;comment:预设 id" json:"presetId"`
+	// 引用的知识库，发送消息前从中检索相关资料
+	KnowledgeBaseIDs []int64 `gorm:"column:knowledge_base_ids;type:json;serializer:json;comment:引用的知识库ID列表" json:"knowledgeBaseIds"`
 
 	// 非数据库字段
 	FormattedMessage []global.Message `gorm:"-" json:"formattedMessage"`
+	// 本次调用要求的结构化输出 schema，仅对当前消息生效
+	ResponseSchema *jsonschema.Schema `gorm:"-" json:"-"`
 }
 
 const (
@@ -83,6 +88,12 @@ func (c *Conversation) addMessageFromWsMessageRequest(msg *dto.WsMessageRequest)
 	if len(msg.Content) == 0 {
 		return errors.New("message content is empty")
 	}
+	// 校验结构化输出的 schema
+	if msg.ResponseSchema != nil {
+		if err := msg.ResponseSchema.Check(); err != nil {
+			return err
+		}
+	}
 
 	// 将消息添加到会话消息记录中
 	c.addMessage(global.Message{
@@ -118,6 +129,10 @@ func (c *Conversation) applyCallParams(msg *dto.WsMessageRequest) {
 	c.EnableWeb = msg.EnableWeb
 	c.Temperature = msg.Temperature
 	c.Model = msg.Model
+	c.ResponseSchema = msg.ResponseSchema
+	if msg.KnowledgeBaseIds != nil {
+		c.KnowledgeBaseIDs = msg.KnowledgeBaseIds
+	}
 	//c.setContextLength(msg.Context)
 
 }
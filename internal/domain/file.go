@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// 文件的来源
+const (
+	FileSourceUpload = "upload" // 用户上传
+	FileSourceAgent  = "agent"  // 智能体运行时上传的附件
+	FileSourceTool   = "tool"   // 工具生成
+)
+
+// 文件的病毒扫描状态
+const (
+	FileScanSkipped  = "skipped"  // 没有配置扫描服务或者不需要扫描
+	FileScanClean    = "clean"    // 扫描通过
+	FileScanInfected = "infected" // 发现病毒
+	FileScanError    = "error"    // 扫描服务不可用，按配置放行
+)
+
+// File 文件登记表，通过文件ID下载，不依赖文件的保存日期和路径
+type File struct {
+	BaseModel
+	UserID     int64      `gorm:"column:user_id;type:bigint;index;not null;comment:所属用户ID" json:"userId"`
+	Name       string     `gorm:"type:varchar(255);not null;comment:原始文件名" json:"name"`
+	MimeType   string     `gorm:"type:varchar(100);comment:MIME类型" json:"mimeType"`
+	Size       int64      `gorm:"type:bigint;comment:文件大小（字节）" json:"size"`
+	Sha256     string     `gorm:"type:char(64);index;comment:文件内容的 SHA-256 摘要" json:"sha256"`
+	Storage    string     `gorm:"type:varchar(20);not null;comment:存储后端 local/cos/s3" json:"storage"`
+	StorageKey string     `gorm:"type:varchar(500);not null;comment:对象在存储中的路径" json:"-"`
+	Source     string     `gorm:"type:varchar(20);not null;comment:来源 upload/agent/tool" json:"source"`
+	SourceRef  string     `gorm:"type:varchar(100);comment:来源说明，例如智能体类型、工具名称" json:"sourceRef"`
+	ScanStatus string     `gorm:"type:varchar(20);comment:病毒扫描状态 skipped/clean/infected/error" json:"scanStatus"`
+	ScanDetail string     `gorm:"type:varchar(255);comment:病毒扫描结果，例如病毒名称、扫描错误" json:"scanDetail"`
+	ExpireTime *time.Time `gorm:"index;comment:过期时间，为NULL则永久保存" json:"expireTime"`
+}
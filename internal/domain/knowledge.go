@@ -0,0 +1,53 @@
+package domain
+
+// 知识库文档的处理状态
+const (
+	KnowledgeDocPending    = "pending"    // 等待处理
+	KnowledgeDocProcessing = "processing" // 正在解析和向量化
+	KnowledgeDocReady      = "ready"      // 可以检索
+	KnowledgeDocFailed     = "failed"     // 处理失败，Error 中保存原因
+)
+
+// KnowledgeBase 知识库，由上传的文档切分、向量化后组成，预设和会话可以引用知识库
+type KnowledgeBase struct {
+	BaseModel
+	UserID      int64  `gorm:"column:user_id;type:bigint;index;not null;comment:创建者用户ID" json:"userId"`
+	Name        string `gorm:"type:varchar(255);not null;comment:知识库名称" json:"name"`
+	Description string `gorm:"type:text;comment:知识库描述" json:"description"`
+	// 向量化使用的模型，通过支持该模型的渠道调用，创建后不能修改
+	EmbeddingModel string `gorm:"type:varchar(100);not null;comment:向量化模型" json:"embeddingModel"`
+	// 向量维度，第一次向量化后记录，用于校验模型是否被更换
+	Dimensions   int `gorm:"type:int;default:0;comment:向量维度" json:"dimensions"`
+	ChunkSize    int `gorm:"type:int;default:0;comment:切分块大小（字符）" json:"chunkSize"`
+	ChunkOverlap int `gorm:"type:int;default:0;comment:相邻块重叠字符数" json:"chunkOverlap"`
+	// 官方知识库所有用户都可以引用
+	Official bool  `gorm:"comment:是否官方知识库" json:"official"`
+	User     *User `gorm:"foreignKey:UserID;references:Id;constraint:OnUpdate:NO ACTION,OnDelete:NO ACTION" json:"-"`
+}
+
+// KnowledgeDocument 知识库中的文档，引用文件表中的文件
+type KnowledgeDocument struct {
+	BaseModel
+	KnowledgeBaseID int64  `gorm:"column:knowledge_base_id;type:bigint;index;not null;comment:知识库ID" json:"knowledgeBaseId"`
+	FileID          int64  `gorm:"column:file_id;type:bigint;index;not null;comment:文件ID" json:"fileId"`
+	Name            string `gorm:"type:varchar(255);not null;comment:文档名称" json:"name"`
+	Status          string `gorm:"type:varchar(20);not null;index;comment:处理状态 pending/processing/ready/failed" json:"status"`
+	Error           string `gorm:"type:varchar(500);comment:处理失败的原因" json:"error"`
+	Language        string `gorm:"type:varchar(10);comment:检测到的语言" json:"language"`
+	Pages           int    `gorm:"type:int;default:0;comment:页数" json:"pages"`
+	ChunkCount      int    `gorm:"type:int;default:0;comment:切分块数量" json:"chunkCount"`
+}
+
+// KnowledgeChunk 文档切分后的文本块和向量，向量索引从这里加载
+type KnowledgeChunk struct {
+	Id              int64  `gorm:"column:id;primary_key;comment:主键ID" json:"id"`
+	KnowledgeBaseID int64  `gorm:"column:knowledge_base_id;type:bigint;index;not null;comment:知识库ID" json:"knowledgeBaseId"`
+	DocumentID      int64  `gorm:"column:document_id;type:bigint;index;not null;comment:文档ID" json:"documentId"`
+	Seq             int    `gorm:"type:int;not null;comment:在文档中的序号" json:"seq"`
+	Content         string `gorm:"type:mediumtext;comment:文本内容" json:"content"`
+	Section         string `gorm:"type:varchar(500);comment:所在章节" json:"section"`
+	PageStart       int    `gorm:"type:int;default:0;comment:起始页码" json:"pageStart"`
+	PageEnd         int    `gorm:"type:int;default:0;comment:结束页码" json:"pageEnd"`
+	// 小端序 float32 数组
+	Embedding []byte `gorm:"type:mediumblob;comment:向量" json:"-"`
+}
@@ -0,0 +1,21 @@
+package domain
+
+// MCPServer 管理后台配置的 MCP 服务器
+type MCPServer struct {
+	BaseModel
+	Name        string `gorm:"type:varchar(100);not null;comment:服务器名称，同时作为工具来源的标识" json:"name"`
+	Description string `gorm:"type:varchar(500);comment:服务器描述" json:"description"`
+	// 传输方式：stdio、sse、streamable-http
+	Transport string `gorm:"type:varchar(50);not null;comment:传输方式" json:"transport"`
+	// stdio 方式的启动命令、参数和环境变量
+	Command     string            `gorm:"type:varchar(500);comment:启动命令" json:"command"`
+	Args        []string          `gorm:"type:json;serializer:json;comment:启动参数" json:"args"`
+	Environment map[string]string `gorm:"type:json;serializer:json;comment:环境变量" json:"environment"`
+	// sse、streamable-http 方式的服务地址、请求头和认证令牌
+	URL       string            `gorm:"column:url;type:varchar(500);comment:服务地址" json:"url"`
+	Headers   map[string]string `gorm:"type:json;serializer:json;comment:请求头" json:"headers"`
+	AuthToken string            `gorm:"type:varchar(500);comment:认证令牌" json:"authToken"`
+	Enabled   bool              `gorm:"type:tinyint;comment:启用状态(0: 禁用 1: 启用)" json:"enabled"`
+	// 禁用的工具名称，禁用后不会提供给智能体
+	DisabledTools []string `gorm:"type:json;serializer:json;comment:禁用的工具列表" json:"disabledTools"`
+}
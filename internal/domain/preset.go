@@ -10,6 +10,8 @@ type Preset struct {
 	Context     string `gorm:"type:text;comment:预设上下文" json:"context"`
 	Tags        string `gorm:"type:varchar(255);comment:预设标签,多个标签用逗号分隔" json:"tags"`
 	// 是否官方预设
-	Official bool  `gorm:"comment:是否官方预设" json:"official"`
-	User     *User `gorm:"foreignKey:UserID;references:Id;constraint:OnUpdate:NO ACTION,OnDelete:NO ACTION" json:"-"`
+	Official bool `gorm:"comment:是否官方预设" json:"official"`
+	// 引用的知识库，使用该预设的会话会从这些知识库中检索资料
+	KnowledgeBaseIDs []int64 `gorm:"column:knowledge_base_ids;type:json;serializer:json;comment:引用的知识库ID列表" json:"knowledgeBaseIds"`
+	User             *User   `gorm:"foreignKey:UserID;references:Id;constraint:OnUpdate:NO ACTION,OnDelete:NO ACTION" json:"-"`
 }
@@ -2,6 +2,15 @@ package dto
 
 // AgentExecReq Agent执行请求
 type AgentExecReq struct {
-	AgentType string `json:"agentType" binding:"required" example:"general"` // 智能体类型
-	Content   string `json:"content" example:"生成一份深圳旅游攻略"`                   // 请求内容
+	AgentType string           `json:"agentType" binding:"required" example:"general"` // 智能体类型
+	Content   string           `json:"content" example:"生成一份深圳旅游攻略"`                   // 请求内容
+	Resources []MCPResourceRef `json:"resources"`                                      // 作为上下文的MCP资源
+}
+
+// AgentApprovalReq 工具调用审批请求
+type AgentApprovalReq struct {
+	ApprovalId string `json:"approvalId" binding:"required" example:"1_1678956123456"`               // 审批ID
+	Action     string `json:"action" binding:"required,oneof=approve edit reject" example:"approve"` // 审批操作 approve/edit/reject
+	Arguments  string `json:"arguments" example:"{\"fileName\":\"旅游攻略\"}"`                           // 修改后的工具调用参数（JSON），action 为 edit 时必填
+	Reason     string `json:"reason" example:"不需要生成 PDF"`                                            // 拒绝原因
 }
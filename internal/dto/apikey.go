@@ -0,0 +1,14 @@
+package dto
+
+// CreateApiKeyReq 创建 API Key 请求
+type CreateApiKeyReq struct {
+	Name string `json:"name" binding:"required,max=100" example:"Cursor"` // 名称
+	// 权限范围，可选值：chat、embedding、mcp，不指定时授予全部
+	Scopes []string `json:"scopes" binding:"omitempty,dive,oneof=chat embedding mcp" example:"chat,mcp"`
+}
+
+// UpdateApiKeyReq 更新 API Key 请求，字段为空时保持不变
+type UpdateApiKeyReq struct {
+	Name   string   `json:"name" binding:"omitempty,max=100" example:"Cursor"`                             // 名称
+	Scopes []string `json:"scopes" binding:"omitempty,min=1,dive,oneof=chat embedding mcp" example:"chat"` // 权限范围
+}
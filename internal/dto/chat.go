@@ -1,5 +1,7 @@
 package dto
 
+import "txing-ai/internal/utils/jsonschema"
+
 type WsMessageRequest struct {
 	Type    string `json:"type"`
 	Content string `json:"content"`
@@ -16,6 +18,12 @@ type WsMessageRequest struct {
 	PresencePenalty   *float32 `json:"presence_penalty,omitempty"`
 	FrequencyPenalty  *float32 `json:"frequency_penalty,omitempty"`
 	RepetitionPenalty *float32 `json:"repetition_penalty,omitempty"`
+	// 结构化输出，指定最终回复需要满足的 JSON Schema
+	ResponseSchema *jsonschema.Schema `json:"response_schema,omitempty"`
+	// 附件的文件 ID，解析出的文本追加到消息内容后面，需要登录
+	Files []int64 `json:"files,omitempty"`
+	// 会话引用的知识库，为 nil 时保持不变，为空数组时取消引用，需要登录
+	KnowledgeBaseIds []int64 `json:"knowledge_base_ids,omitempty"`
 }
 
 type WsMessageResponse struct {
@@ -23,7 +31,25 @@ type WsMessageResponse struct {
 	Content        string `json:"content"`
 	// 思考过程消息
 	ReasoningContent string `json:"reasoning_content"`
-	End              bool   `json:"end"`
+	// 结构化输出校验通过后的结果
+	Data interface{} `json:"data,omitempty"`
+	// 本次回复引用的知识库资料，在回复内容之前发送
+	References []KnowledgeReference `json:"references,omitempty"`
+	End        bool                 `json:"end"`
+}
+
+// KnowledgeReference 从知识库中检索到的资料，回复中用 [n] 引用
+type KnowledgeReference struct {
+	Index           int     `json:"index"`
+	KnowledgeBaseId int64   `json:"knowledgeBaseId"`
+	DocumentId      int64   `json:"documentId"`
+	FileId          int64   `json:"fileId"`
+	Name            string  `json:"name"`
+	Section         string  `json:"section,omitempty"`
+	PageStart       int     `json:"pageStart,omitempty"`
+	PageEnd         int     `json:"pageEnd,omitempty"`
+	Score           float32 `json:"score"`
+	Snippet         string  `json:"snippet"`
 }
 
 // BatchDeleteRequest 批量删除请求
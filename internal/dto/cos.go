@@ -2,6 +2,19 @@ package dto
 
 // 获取预签名URL请求
 type GetPresignedURLReq struct {
-	Key  string `json:"key" binding:"required"`  // 对象键
-	Type string `json:"type" binding:"required"` // 类型：upload/download
+	Key     string `json:"key" binding:"required"`                                                      // 对象键，上传时只取文件名，保存到用户对应用途的目录下
+	Type    string `json:"type" binding:"required"`                                                     // 类型：upload/download
+	Purpose string `json:"purpose" binding:"omitempty,oneof=avatar preset attachment" example:"avatar"` // 上传用途：avatar/preset/attachment，上传时必填，下载时只能下载当前用户该用途目录下的文件
+}
+
+// 获取临时密钥请求
+type GetTempCredentialReq struct {
+	Purpose string `json:"purpose" binding:"required,oneof=avatar preset attachment" example:"avatar"` // 上传用途：avatar/preset/attachment
+}
+
+// 确认直传文件请求
+type ConfirmUploadReq struct {
+	Purpose string `json:"purpose" binding:"required,oneof=avatar preset attachment" example:"avatar"` // 上传用途：avatar/preset/attachment
+	Key     string `json:"key" binding:"required" example:"avatar/1/1745647348066-761.jpg"`            // 上传后的对象键
+	Name    string `json:"name" example:"报告.pdf"`                                                      // 原始文件名，附件登记到文件表时使用
 }
@@ -0,0 +1,9 @@
+package dto
+
+import "txing-ai/internal/utils/page"
+
+// ListStorageUsageReq 用户存储空间使用情况列表请求
+type ListStorageUsageReq struct {
+	page.PageRequest
+	Username string `form:"username" example:"john"` // 用户名
+}
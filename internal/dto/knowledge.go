@@ -0,0 +1,44 @@
+package dto
+
+import "txing-ai/internal/utils/page"
+
+// CreateKnowledgeBaseReq 创建知识库请求
+type CreateKnowledgeBaseReq struct {
+	Name           string `json:"name" binding:"required,max=255" example:"产品手册"`                // 知识库名称
+	Description    string `json:"description" example:"产品的安装和使用说明"`                              // 知识库描述
+	EmbeddingModel string `json:"embeddingModel" example:"text-embedding-3-small"`               // 向量化模型，为空时使用默认模型，创建后不能修改
+	ChunkSize      int    `json:"chunkSize" binding:"omitempty,min=100,max=8000" example:"800"`  // 切分块大小（字符），为空时使用默认值
+	ChunkOverlap   int    `json:"chunkOverlap" binding:"omitempty,min=0,max=2000" example:"100"` // 相邻块重叠字符数，为空时使用默认值
+	Official       bool   `json:"official" example:"false"`                                      // 是否官方知识库，仅管理员可以设置
+}
+
+// UpdateKnowledgeBaseReq 更新知识库请求
+type UpdateKnowledgeBaseReq struct {
+	Name        string `json:"name" binding:"max=255" example:"产品手册"` // 知识库名称
+	Description string `json:"description" example:"产品的安装和使用说明"`      // 知识库描述
+	Official    *bool  `json:"official" example:"false"`              // 是否官方知识库，仅管理员可以设置
+}
+
+// ListKnowledgeBaseReq 获取知识库列表请求，返回官方知识库和自己创建的知识库
+type ListKnowledgeBaseReq struct {
+	page.PageRequest
+	Official *bool  `form:"official"` // 是否官方知识库
+	Name     string `form:"name"`     // 知识库名称
+}
+
+// AddKnowledgeDocumentsReq 添加文档到知识库请求
+type AddKnowledgeDocumentsReq struct {
+	FileIds []int64 `json:"fileIds" binding:"required,min=1,max=20" example:"12"` // 上传文件返回的文件ID
+}
+
+// ListKnowledgeDocumentReq 获取知识库文档列表请求
+type ListKnowledgeDocumentReq struct {
+	page.PageRequest
+	Status string `form:"status"` // 处理状态 pending/processing/ready/failed
+}
+
+// SearchKnowledgeReq 在知识库中检索请求
+type SearchKnowledgeReq struct {
+	Query string `json:"query" binding:"required" example:"如何安装"`           // 检索内容
+	TopK  int    `json:"topK" binding:"omitempty,min=1,max=50" example:"5"` // 返回数量，为空时使用默认值
+}
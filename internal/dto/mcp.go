@@ -0,0 +1,47 @@
+package dto
+
+import "txing-ai/internal/utils/page"
+
+// CreateMCPServerReq 创建MCP服务器请求
+type CreateMCPServerReq struct {
+	Name          string            `json:"name" binding:"required,max=100" example:"amap"`                               // 服务器名称
+	Description   string            `json:"description" binding:"max=500" example:"高德地图"`                                 // 服务器描述
+	Transport     string            `json:"transport" binding:"required,oneof=stdio sse streamable-http" example:"stdio"` // 传输方式 stdio/sse/streamable-http
+	Command       string            `json:"command" example:"npx"`                                                        // 启动命令，stdio 方式必填
+	Args          []string          `json:"args" example:"-y,@amap/amap-maps-mcp-server"`                                 // 启动参数
+	Environment   map[string]string `json:"environment"`                                                                  // 环境变量
+	URL           string            `json:"url" example:"https://mcp.amap.com/mcp"`                                       // 服务地址，sse、streamable-http 方式必填
+	Headers       map[string]string `json:"headers"`                                                                      // 请求头
+	AuthToken     string            `json:"authToken"`                                                                    // 认证令牌
+	Enabled       bool              `json:"enabled" example:"true"`                                                       // 启用状态
+	DisabledTools []string          `json:"disabledTools"`                                                                // 禁用的工具名称
+}
+
+// UpdateMCPServerReq 更新MCP服务器请求，环境变量、请求头和认证令牌未修改时提交脱敏后的原值即可
+type UpdateMCPServerReq struct {
+	CreateMCPServerReq
+}
+
+// ListMCPServerReq 获取MCP服务器列表请求
+type ListMCPServerReq struct {
+	page.PageRequest
+	Name      string `form:"name" example:"amap"`       // 服务器名称
+	Transport string `form:"transport" example:"stdio"` // 传输方式
+	Enabled   *bool  `form:"enabled" example:"true"`    // 启用状态
+}
+
+// UpdateMCPToolReq 启用或禁用MCP工具请求
+type UpdateMCPToolReq struct {
+	Enabled *bool `json:"enabled" binding:"required" example:"false"` // 是否启用
+}
+
+// MCPResourceRef 引用的MCP资源
+type MCPResourceRef struct {
+	Server string `json:"server" binding:"required" example:"docs"`       // MCP服务器名称
+	URI    string `json:"uri" binding:"required" example:"docs://readme"` // 资源地址
+}
+
+// ReadMCPResourceReq 读取MCP资源请求
+type ReadMCPResourceReq struct {
+	URI string `form:"uri" binding:"required" example:"docs://readme"` // 资源地址
+}
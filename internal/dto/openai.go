@@ -0,0 +1,208 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"txing-ai/internal/utils/jsonschema"
+)
+
+// OpenAI 兼容接口的请求和响应，字段与 OpenAI API 保持一致
+
+// EmbeddingReq 向量化请求
+type EmbeddingReq struct {
+	Model string `json:"model" binding:"required" example:"text-embedding-3-small"` // 向量化模型
+	// 输入文本，可以是字符串或字符串数组
+	Input          json.RawMessage `json:"input" binding:"required" swaggertype:"array,string"`
+	Dimensions     int             `json:"dimensions" binding:"omitempty,min=1" example:"1024"`                    // 输出向量的维度，只有部分模型支持
+	EncodingFormat string          `json:"encoding_format" binding:"omitempty,oneof=float base64" example:"float"` // 向量的编码格式
+	User           string          `json:"user"`                                                                   // 终端用户标识，不转发给渠道
+}
+
+// Inputs 解析输入文本，不支持 token 数组
+func (r *EmbeddingReq) Inputs() ([]string, error) {
+	var single string
+	if err := json.Unmarshal(r.Input, &single); err == nil {
+		return []string{single}, nil
+	}
+	var list []string
+	if err := json.Unmarshal(r.Input, &list); err != nil {
+		return nil, errors.New("input must be a string or an array of strings")
+	}
+	return list, nil
+}
+
+// EmbeddingResp 向量化响应
+type EmbeddingResp struct {
+	Object string          `json:"object" example:"list"`
+	Data   []EmbeddingData `json:"data"`
+	Model  string          `json:"model" example:"text-embedding-3-small"`
+	Usage  OpenaiUsage     `json:"usage"`
+}
+
+// EmbeddingData 一个输入文本的向量，encoding_format 为 base64 时 Embedding 为小端序 float32 数组的 base64 编码
+type EmbeddingData struct {
+	Object    string      `json:"object" example:"embedding"`
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding" swaggertype:"array,number"`
+}
+
+// RerankReq 重排序请求，格式与 Jina、Cohere 的重排序接口一致
+type RerankReq struct {
+	Model     string   `json:"model" binding:"required" example:"bge-reranker-v2-m3"` // 重排序模型
+	Query     string   `json:"query" binding:"required" example:"如何安装"`               // 查询
+	Documents []string `json:"documents" binding:"required,min=1"`                    // 待排序的文档
+	TopN      int      `json:"top_n" binding:"omitempty,min=1" example:"3"`           // 返回相关性最高的前 N 个文档
+	// 是否在结果中返回文档内容
+	ReturnDocuments bool `json:"return_documents"`
+}
+
+// RerankResp 重排序响应，结果按相关性从高到低排列
+type RerankResp struct {
+	Model   string         `json:"model" example:"bge-reranker-v2-m3"`
+	Results []RerankResult `json:"results"`
+	Usage   OpenaiUsage    `json:"usage"`
+}
+
+// RerankResult 一个文档的相关性
+type RerankResult struct {
+	Index          int             `json:"index"`
+	RelevanceScore float64         `json:"relevance_score"`
+	Document       *RerankDocument `json:"document,omitempty"`
+}
+
+// RerankDocument 文档内容
+type RerankDocument struct {
+	Text string `json:"text"`
+}
+
+// ChatCompletionReq 聊天补全请求
+type ChatCompletionReq struct {
+	Model    string                  `json:"model" binding:"required" example:"deepseek-v3"` // 模型
+	Messages []ChatCompletionMessage `json:"messages" binding:"required,min=1,dive"`         // 消息列表
+	Stream   bool                    `json:"stream"`                                         // 是否流式响应
+	// 可选的模型参数
+	MaxTokens           *int     `json:"max_tokens" binding:"omitempty,min=1" example:"2048"`
+	MaxCompletionTokens *int     `json:"max_completion_tokens" binding:"omitempty,min=1"` // 与 max_tokens 相同，同时指定时优先使用
+	Temperature         *float32 `json:"temperature" binding:"omitempty,min=0,max=2" example:"1.0"`
+	TopP                *float32 `json:"top_p" binding:"omitempty,min=0,max=1" example:"0.7"`
+	PresencePenalty     *float32 `json:"presence_penalty" binding:"omitempty,min=-2,max=2"`
+	FrequencyPenalty    *float32 `json:"frequency_penalty" binding:"omitempty,min=-2,max=2"`
+	// 结构化输出，只支持 json_schema 类型
+	ResponseFormat *ChatCompletionResponseFormat `json:"response_format"`
+	User           string                        `json:"user"` // 终端用户标识，不转发给渠道
+}
+
+// ChatCompletionMessage 聊天消息，content 可以是字符串或只包含文本的内容数组
+type ChatCompletionMessage struct {
+	Role    string          `json:"role" binding:"required,oneof=system developer user assistant" example:"user"`
+	Content json.RawMessage `json:"content" binding:"required" swaggertype:"string" example:"你好"`
+	Name    *string         `json:"name"`
+}
+
+// Text 解析消息内容，内容数组中的文本按顺序拼接，不支持图片、音频等类型
+func (m *ChatCompletionMessage) Text() (string, error) {
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return text, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", errors.New("content must be a string or an array of content parts")
+	}
+	var builder strings.Builder
+	for _, part := range parts {
+		if part.Type != "text" {
+			return "", errors.New("content part type " + part.Type + " is not supported")
+		}
+		builder.WriteString(part.Text)
+	}
+	return builder.String(), nil
+}
+
+// ChatCompletionResponseFormat 输出格式
+type ChatCompletionResponseFormat struct {
+	Type       string                    `json:"type" binding:"required,oneof=text json_schema" example:"json_schema"`
+	JSONSchema *ChatCompletionJSONSchema `json:"json_schema"`
+}
+
+// ChatCompletionJSONSchema 结构化输出的 JSON Schema
+type ChatCompletionJSONSchema struct {
+	Name   string             `json:"name" example:"answer"`
+	Schema *jsonschema.Schema `json:"schema" swaggertype:"object"`
+}
+
+// ChatCompletionResp 非流式聊天补全响应
+type ChatCompletionResp struct {
+	Id      string                 `json:"id" example:"chatcmpl-1f0c..."`
+	Object  string                 `json:"object" example:"chat.completion"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model" example:"deepseek-v3"`
+	Choices []ChatCompletionChoice `json:"choices"`
+}
+
+// ChatCompletionChoice 非流式响应的回复
+type ChatCompletionChoice struct {
+	Index        int                  `json:"index"`
+	Message      ChatCompletionResult `json:"message"`
+	FinishReason string               `json:"finish_reason" example:"stop"`
+}
+
+// ChatCompletionResult 模型回复的消息
+type ChatCompletionResult struct {
+	Role             string `json:"role,omitempty" example:"assistant"`
+	Content          string `json:"content"`
+	ReasoningContent string `json:"reasoning_content,omitempty"` // 思考过程
+}
+
+// ChatCompletionChunk 流式聊天补全响应的消息块，通过 SSE 逐个发送，最后发送 [DONE]
+type ChatCompletionChunk struct {
+	Id      string                      `json:"id" example:"chatcmpl-1f0c..."`
+	Object  string                      `json:"object" example:"chat.completion.chunk"`
+	Created int64                       `json:"created"`
+	Model   string                      `json:"model" example:"deepseek-v3"`
+	Choices []ChatCompletionChunkChoice `json:"choices"`
+}
+
+// ChatCompletionChunkChoice 消息块中的增量内容，结束时 FinishReason 为 stop
+type ChatCompletionChunkChoice struct {
+	Index        int                  `json:"index"`
+	Delta        ChatCompletionResult `json:"delta"`
+	FinishReason *string              `json:"finish_reason"`
+}
+
+// ModelListResp 模型列表
+type ModelListResp struct {
+	Object string        `json:"object" example:"list"`
+	Data   []OpenaiModel `json:"data"`
+}
+
+// OpenaiModel 模型信息
+type OpenaiModel struct {
+	Id      string `json:"id" example:"deepseek-v3"`
+	Object  string `json:"object" example:"model"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by" example:"txing-ai"`
+}
+
+// OpenaiUsage 调用消耗的 token 数量
+type OpenaiUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// OpenaiErrorResp OpenAI 兼容接口的错误响应
+type OpenaiErrorResp struct {
+	Error OpenaiError `json:"error"`
+}
+
+// OpenaiError 错误信息
+type OpenaiError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
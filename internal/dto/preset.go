@@ -11,6 +11,8 @@ type CreatePresetReq struct {
 	Context     string `json:"context" example:"你是一个智能助手,能够帮助用户解决各种问题..."`      // 预设上下文
 	Tags        string `json:"tags" example:"popular,tools"`                    // 预设标签
 	Official    bool   `json:"official" example:"false"`                        // 是否官方预设
+	// 引用的知识库ID列表，使用该预设的会话从这些知识库中检索资料
+	KnowledgeBaseIds []int64 `json:"knowledgeBaseIds" example:"3"`
 }
 
 // UpdatePresetReq 更新预设请求
@@ -22,6 +24,8 @@ type UpdatePresetReq struct {
 	Context     string `json:"context" example:"你是一个智能助手,能够帮助用户解决各种问题..."`      // 预设上下文
 	Tags        string `json:"tags" example:"popular,tools"`                    // 预设标签
 	Official    *bool  `json:"official" example:"false"`                        // 是否官方预设
+	// 引用的知识库ID列表，为 nil 时不修改，为空数组时取消引用
+	KnowledgeBaseIds []int64 `json:"knowledgeBaseIds" example:"3"`
 }
 
 // ListPresetReq 获取预设列表请求
@@ -32,3 +36,13 @@ type ListPresetReq struct {
 	Name     string `form:"name"`               // 预设名称
 	Tags     string `form:"tags"`               // 预设标签
 }
+
+// ImportMCPPromptReq 将MCP服务器的 prompt 导入为预设请求
+type ImportMCPPromptReq struct {
+	Server    string            `json:"server" binding:"required" example:"docs"`        // MCP服务器名称
+	Prompt    string            `json:"prompt" binding:"required" example:"review"`      // prompt 名称
+	Arguments map[string]string `json:"arguments"`                                       // prompt 参数
+	Name      string            `json:"name" example:"代码审查"`                             // 预设名称，为空时使用 prompt 名称
+	Avatar    string            `json:"avatar" example:"https://example.com/avatar.png"` // 预设头像
+	Tags      string            `json:"tags" example:"mcp"`                              // 预设标签
+}
@@ -0,0 +1,18 @@
+package enum
+
+// API Key 权限范围
+const (
+	ApiKeyScopeChat      = "chat"      // 调用 /v1/chat/completions、/v1/models
+	ApiKeyScopeEmbedding = "embedding" // 调用 /v1/embeddings、/v1/rerank
+	ApiKeyScopeMcp       = "mcp"       // 调用对外提供的 MCP 服务
+)
+
+// API Key 权限范围描述
+var ApiKeyScopeDesc = map[string]string{
+	ApiKeyScopeChat:      "聊天补全",
+	ApiKeyScopeEmbedding: "向量化与重排序",
+	ApiKeyScopeMcp:       "MCP 服务",
+}
+
+// ApiKeyScopes 所有权限范围，创建时不指定权限范围则授予全部
+var ApiKeyScopes = []string{ApiKeyScopeChat, ApiKeyScopeEmbedding, ApiKeyScopeMcp}
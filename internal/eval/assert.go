@@ -0,0 +1,253 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"txing-ai/internal/utils/jsonschema"
+)
+
+// 断言类型
+const (
+	// AssertContains 包含所有指定文本
+	AssertContains = "contains"
+	// AssertNotContains 不包含任何指定文本
+	AssertNotContains = "not_contains"
+	// AssertSections 包含所有指定的 Markdown 标题
+	AssertSections = "sections"
+	// AssertSchema 满足 JSON Schema，没有指定时使用智能体的输出 Schema
+	AssertSchema = "schema"
+	// AssertToolCalled 调用了指定工具，调用次数在 [min, max] 范围内
+	AssertToolCalled = "tool_called"
+	// AssertToolNotCalled 没有调用指定工具
+	AssertToolNotCalled = "tool_not_called"
+)
+
+// 断言检查的对象
+const (
+	// TargetOutput 智能体的最终输出
+	TargetOutput = "output"
+	// TargetContent 结构化输出中的 content 字段，不是结构化输出时为最终输出
+	TargetContent = "content"
+	// TargetToolPrefix 指定工具调用参数中的文本，例如 tool:markdown_to_pdf_file_tool，工具名支持通配符
+	TargetToolPrefix = "tool:"
+)
+
+// Assertion 断言
+type Assertion struct {
+	Type string `json:"type"`
+	// 检查的对象，默认为 output
+	Target string `json:"target"`
+	// contains、not_contains、sections 断言的文本
+	Values []string `json:"values"`
+	// schema 断言的 JSON Schema
+	Schema json.RawMessage `json:"schema"`
+	// tool_called、tool_not_called 断言的工具名，支持通配符
+	Tool string `json:"tool"`
+	// tool_called 断言的调用次数范围，min 默认为 1，max 为 0 表示不限制
+	Min int `json:"min"`
+	Max int `json:"max"`
+
+	schema *jsonschema.Schema
+}
+
+// AssertionResult 断言结果
+type AssertionResult struct {
+	Type    string `json:"type"`
+	Target  string `json:"target,omitempty"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// RunResult 智能体单次运行的结果
+type RunResult struct {
+	// 最终输出
+	Output string
+	// 工具调用
+	ToolCalls []*ToolCall
+	// 智能体的输出 Schema
+	OutputSchema *jsonschema.Schema
+}
+
+// check 校验断言配置
+func (a *Assertion) check() error {
+	switch a.Type {
+	case AssertContains, AssertNotContains, AssertSections:
+		if len(a.Values) == 0 {
+			return fmt.Errorf("assertion %s: values is required", a.Type)
+		}
+	case AssertSchema:
+		if len(a.Schema) > 0 {
+			s, err := jsonschema.Parse(a.Schema)
+			if err != nil {
+				return fmt.Errorf("assertion %s: %w", a.Type, err)
+			}
+			a.schema = s
+		}
+	case AssertToolCalled, AssertToolNotCalled:
+		if a.Tool == "" {
+			return fmt.Errorf("assertion %s: tool is required", a.Type)
+		}
+		if _, err := path.Match(a.Tool, ""); err != nil {
+			return fmt.Errorf("assertion %s: invalid tool pattern: %w", a.Type, err)
+		}
+	default:
+		return fmt.Errorf("unknown assertion type: %s", a.Type)
+	}
+	if a.Target != "" && a.Target != TargetOutput && a.Target != TargetContent &&
+		!strings.HasPrefix(a.Target, TargetToolPrefix) {
+		return fmt.Errorf("assertion %s: unknown target %s", a.Type, a.Target)
+	}
+	return nil
+}
+
+// Evaluate 对运行结果执行断言
+func (a *Assertion) Evaluate(run *RunResult) *AssertionResult {
+	result := &AssertionResult{Type: a.Type, Target: a.Target}
+	var failures []string
+
+	switch a.Type {
+	case AssertContains:
+		text := a.targetText(run)
+		for _, v := range a.Values {
+			if !strings.Contains(text, v) {
+				failures = append(failures, "missing "+v)
+			}
+		}
+	case AssertNotContains:
+		text := a.targetText(run)
+		for _, v := range a.Values {
+			if strings.Contains(text, v) {
+				failures = append(failures, "unexpected "+v)
+			}
+		}
+	case AssertSections:
+		headings := markdownHeadings(a.targetText(run))
+		for _, v := range a.Values {
+			if !containsHeading(headings, v) {
+				failures = append(failures, "missing section "+v)
+			}
+		}
+	case AssertSchema:
+		s := a.schema
+		if s == nil {
+			s = run.OutputSchema
+		}
+		if s == nil {
+			failures = append(failures, "no schema to validate")
+		} else if _, err := s.ParseOutput(a.targetText(run)); err != nil {
+			failures = append(failures, err.Error())
+		}
+	case AssertToolCalled:
+		count := countToolCalls(run.ToolCalls, a.Tool)
+		min := a.Min
+		if min <= 0 {
+			min = 1
+		}
+		if count < min || (a.Max > 0 && count > a.Max) {
+			failures = append(failures, fmt.Sprintf("%s called %d times", a.Tool, count))
+		}
+	case AssertToolNotCalled:
+		if count := countToolCalls(run.ToolCalls, a.Tool); count > 0 {
+			failures = append(failures, fmt.Sprintf("%s called %d times", a.Tool, count))
+		}
+	}
+
+	result.Passed = len(failures) == 0
+	result.Message = strings.Join(failures, "; ")
+	return result
+}
+
+// targetText 获取断言检查的文本
+func (a *Assertion) targetText(run *RunResult) string {
+	switch {
+	case a.Target == TargetContent:
+		return outputContent(run.Output)
+	case strings.HasPrefix(a.Target, TargetToolPrefix):
+		pattern := strings.TrimPrefix(a.Target, TargetToolPrefix)
+		var texts []string
+		for _, call := range run.ToolCalls {
+			if matched, _ := path.Match(pattern, call.Name); matched {
+				texts = append(texts, argumentTexts(call.Arguments)...)
+			}
+		}
+		return strings.Join(texts, "\n\n")
+	default:
+		return run.Output
+	}
+}
+
+// outputContent 获取结构化输出中的 content 字段
+func outputContent(output string) string {
+	raw, err := jsonschema.ExtractJSON(output)
+	if err != nil {
+		return output
+	}
+	var value struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil || value.Content == "" {
+		return output
+	}
+	return value.Content
+}
+
+// argumentTexts 提取工具调用参数中的所有字符串，例如生成文档工具的 Markdown 内容
+func argumentTexts(arguments string) []string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+		return []string{arguments}
+	}
+	var texts []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			texts = append(texts, v)
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(value)
+	return texts
+}
+
+// markdownHeadings 获取 Markdown 中的所有标题
+func markdownHeadings(text string) []string {
+	var headings []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		headings = append(headings, strings.TrimSpace(strings.TrimLeft(line, "#")))
+	}
+	return headings
+}
+
+func containsHeading(headings []string, section string) bool {
+	for _, h := range headings {
+		if strings.Contains(h, section) {
+			return true
+		}
+	}
+	return false
+}
+
+func countToolCalls(calls []*ToolCall, pattern string) int {
+	count := 0
+	for _, call := range calls {
+		if matched, _ := path.Match(pattern, call.Name); matched {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,139 @@
+package eval
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAssertion_Evaluate(t *testing.T) {
+	markdown, _ := json.Marshal(map[string]string{
+		"fileName": "成都攻略",
+		"content":  "# 成都攻略\n\n## 路线预览\n\n宽窄巷子 -> 大熊猫基地\n\n## 美食指南\n\n火锅",
+	})
+	run := &RunResult{
+		Output: `{"content":"已生成成都三日游攻略","files":[{"name":"成都攻略.pdf"}]}`,
+		ToolCalls: []*ToolCall{
+			{Name: "maps_geo", Arguments: `{"address":"宽窄巷子"}`},
+			{Name: "maps_direction_walking", Arguments: `{"origin":"1,1","destination":"2,2"}`},
+			{Name: "markdown_to_pdf_file_tool", Arguments: string(markdown)},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		assertion *Assertion
+		want      bool
+	}{
+		{
+			name:      "输出包含文本",
+			assertion: &Assertion{Type: AssertContains, Target: TargetContent, Values: []string{"三日游"}},
+			want:      true,
+		},
+		{
+			name:      "content 不包含 JSON 字段名",
+			assertion: &Assertion{Type: AssertNotContains, Target: TargetContent, Values: []string{"files"}},
+			want:      true,
+		},
+		{
+			name:      "工具参数中包含章节",
+			assertion: &Assertion{Type: AssertSections, Target: "tool:markdown_to_pdf_file_tool", Values: []string{"路线预览", "美食指南"}},
+			want:      true,
+		},
+		{
+			name:      "缺少章节",
+			assertion: &Assertion{Type: AssertSections, Target: "tool:markdown_to_pdf_file_tool", Values: []string{"住宿推荐"}},
+			want:      false,
+		},
+		{
+			name:      "正文中的文字不算章节",
+			assertion: &Assertion{Type: AssertSections, Target: "tool:markdown_*", Values: []string{"火锅"}},
+			want:      false,
+		},
+		{
+			name: "满足指定 Schema",
+			assertion: &Assertion{Type: AssertSchema, Schema: json.RawMessage(
+				`{"type":"object","properties":{"files":{"type":"array","minItems":1}},"required":["content","files"]}`)},
+			want: true,
+		},
+		{
+			name:      "没有 Schema",
+			assertion: &Assertion{Type: AssertSchema},
+			want:      false,
+		},
+		{
+			name:      "工具调用次数在范围内",
+			assertion: &Assertion{Type: AssertToolCalled, Tool: "maps_*", Min: 2, Max: 2},
+			want:      true,
+		},
+		{
+			name:      "工具调用次数超出范围",
+			assertion: &Assertion{Type: AssertToolCalled, Tool: "maps_*", Max: 1},
+			want:      false,
+		},
+		{
+			name:      "没有调用工具",
+			assertion: &Assertion{Type: AssertToolNotCalled, Tool: "markdown_save_tool"},
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.assertion.check(); err != nil {
+				t.Fatalf("check() error = %v", err)
+			}
+			got := tt.assertion.Evaluate(run)
+			if got.Passed != tt.want {
+				t.Errorf("Evaluate() passed = %v, want %v, message = %s", got.Passed, tt.want, got.Message)
+			}
+		})
+	}
+}
+
+func TestToolRecorder_lookup(t *testing.T) {
+	fixture := &Fixture{ToolCalls: []*ToolCall{
+		{Name: "maps_geo", Arguments: `{"address":"宽窄巷子","city":"成都"}`, Output: "1"},
+		{Name: "maps_geo", Arguments: `{"address":"春熙路","city":"成都"}`, Output: "2"},
+	}}
+
+	tests := []struct {
+		name      string
+		mode      string
+		toolName  string
+		arguments string
+		want      string
+	}{
+		{
+			name:      "忽略字段顺序和空白",
+			mode:      ModeReplay,
+			toolName:  "maps_geo",
+			arguments: `{ "city": "成都", "address": "春熙路" }`,
+			want:      "2",
+		},
+		{
+			name:      "回放模式下参数不同时没有结果",
+			mode:      ModeReplay,
+			toolName:  "maps_geo",
+			arguments: `{"address":"太古里","city":"成都"}`,
+			want:      "",
+		},
+		{
+			name:      "实时模式下按工具名匹配",
+			mode:      ModeLive,
+			toolName:  "maps_geo",
+			arguments: `{"address":"太古里","city":"成都"}`,
+			want:      "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := newToolRecorder(tt.mode, fixture)
+			got := ""
+			if call := recorder.lookup(tt.toolName, tt.arguments); call != nil {
+				got = call.Output
+			}
+			if got != tt.want {
+				t.Errorf("lookup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
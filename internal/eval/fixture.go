@@ -0,0 +1,92 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// 运行模式
+const (
+	// ModeReplay 模型和工具都使用录制的结果，完全离线运行
+	ModeReplay = "replay"
+	// ModeRecord 调用真实的模型和工具，并录制结果
+	ModeRecord = "record"
+	// ModeLive 调用真实的模型，工具使用录制的结果，用于评测提示词修改的效果
+	ModeLive = "live"
+)
+
+// Fixture 单个用例的录制结果
+type Fixture struct {
+	// 录制时使用的模型
+	Model string `json:"model"`
+	// 模型响应，按调用顺序回放
+	ModelResponses []json.RawMessage `json:"model_responses"`
+	// 录制时智能体可用的工具，回放时用于还原没有注册的工具（例如 MCP 工具）
+	Tools []*ToolSpec `json:"tools"`
+	// 工具调用结果
+	ToolCalls []*ToolCall `json:"tool_calls"`
+}
+
+// ToolSpec 工具定义
+type ToolSpec struct {
+	Name   string           `json:"name"`
+	Desc   string           `json:"desc"`
+	Params *openapi3.Schema `json:"params,omitempty"`
+}
+
+// ToolCall 工具调用记录
+type ToolCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Output    string `json:"output"`
+	// 调用失败时的错误信息
+	Error string `json:"error,omitempty"`
+}
+
+// LoadFixture 加载录制文件
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("parse fixture %s failed: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// Save 保存录制文件
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// newToolSpec 根据工具信息创建工具定义
+func newToolSpec(info *schema.ToolInfo) (*ToolSpec, error) {
+	spec := &ToolSpec{Name: info.Name, Desc: info.Desc}
+	if info.ParamsOneOf != nil {
+		params, err := info.ParamsOneOf.ToOpenAPIV3()
+		if err != nil {
+			return nil, err
+		}
+		spec.Params = params
+	}
+	return spec, nil
+}
+
+// toolInfo 转换为工具信息
+func (s *ToolSpec) toolInfo() *schema.ToolInfo {
+	info := &schema.ToolInfo{Name: s.Name, Desc: s.Desc}
+	if s.Params != nil {
+		info.ParamsOneOf = schema.NewParamsOneOfByOpenAPIV3(s.Params)
+	}
+	return info
+}
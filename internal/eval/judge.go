@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/schema"
+	"txing-ai/internal/utils/jsonschema"
+)
+
+// 默认通过的最低分
+const defaultJudgeMinScore = 6
+
+// 评审时附带的工具调用参数的最大长度，避免超出模型上下文
+const maxJudgeArtifactLength = 20000
+
+// JudgeConfig LLM 评审使用的模型
+type JudgeConfig struct {
+	Endpoint string
+	APIKey   string
+	Model    string
+}
+
+// JudgeResult LLM 评审结果
+type JudgeResult struct {
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+	Passed bool   `json:"passed"`
+}
+
+// 评审结果的 JSON Schema
+var judgeSchema = jsonschema.MustParse(`{
+	"type": "object",
+	"properties": {
+		"score": {"type": "integer", "minimum": 1, "maximum": 10, "description": "评分，1-10 分"},
+		"reason": {"type": "string", "minLength": 1, "description": "评分理由，指出主要的优点和不足"}
+	},
+	"required": ["score", "reason"]
+}`)
+
+const judgeSystemPrompt = `你是一个严格的智能体输出评审专家。你将看到用户的输入、智能体的最终输出以及智能体生成的文档内容，
+请根据评分标准给出 1-10 分的评分，10 分表示完全满足标准，1 分表示完全不满足。评分要客观，不要因为内容长就给高分。`
+
+// judge LLM 评审
+type judge struct {
+	model *openai.ChatModel
+}
+
+func newJudge(ctx context.Context, config *JudgeConfig) (*judge, error) {
+	chatModel, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
+		BaseURL: config.Endpoint,
+		APIKey:  config.APIKey,
+		Model:   config.Model,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create judge model failed: %w", err)
+	}
+	return &judge{model: chatModel}, nil
+}
+
+// Evaluate 对用例的运行结果评分
+func (j *judge) Evaluate(ctx context.Context, c *Case, run *RunResult) (*JudgeResult, error) {
+	var prompt strings.Builder
+	prompt.WriteString("## 评分标准\n\n" + c.Judge.Rubric + "\n\n")
+	prompt.WriteString("## 用户输入\n\n" + c.Input + "\n\n")
+	prompt.WriteString("## 智能体最终输出\n\n" + run.Output + "\n\n")
+	for _, call := range run.ToolCalls {
+		artifact := strings.Join(argumentTexts(call.Arguments), "\n\n")
+		// 只附带生成文档的工具调用，其他工具调用的参数通常很短
+		if len([]rune(artifact)) < 200 {
+			continue
+		}
+		if runes := []rune(artifact); len(runes) > maxJudgeArtifactLength {
+			artifact = string(runes[:maxJudgeArtifactLength]) + "\n...（内容过长已截断）"
+		}
+		prompt.WriteString("## 工具 " + call.Name + " 的调用内容\n\n" + artifact + "\n\n")
+	}
+
+	messages := []*schema.Message{
+		schema.SystemMessage(judgeSystemPrompt + "\n\n" + judgeSchema.Instruction()),
+		schema.UserMessage(prompt.String()),
+	}
+	maxAttempts := jsonschema.MaxRepairAttempts()
+	for attempt := 0; ; attempt++ {
+		msg, err := j.model.Generate(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+		value, err := judgeSchema.ParseOutput(msg.Content)
+		if err == nil {
+			return newJudgeResult(value, c.Judge.MinScore)
+		}
+		if attempt >= maxAttempts {
+			return nil, fmt.Errorf("judge output is invalid: %w", err)
+		}
+		messages = append(messages, msg, schema.UserMessage(judgeSchema.RepairInstruction(err)))
+	}
+}
+
+func newJudgeResult(value interface{}, minScore int) (*JudgeResult, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result JudgeResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if minScore <= 0 {
+		minScore = defaultJudgeMinScore
+	}
+	result.Passed = result.Score >= minScore
+	return &result, nil
+}
@@ -0,0 +1,158 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"txing-ai/internal/global/logging/log"
+)
+
+// modelStub 兼容 OpenAI 接口的模型桩服务
+// 录制模式下转发请求到真实的模型接口并录制响应，回放模式下按调用顺序返回录制的响应
+type modelStub struct {
+	mode string
+	// 录制模式下转发的真实模型接口地址
+	upstream string
+	fixture  *Fixture
+	client   *http.Client
+
+	mu   sync.Mutex
+	next int
+
+	listener net.Listener
+	server   *http.Server
+}
+
+func newModelStub(mode string, upstream string, fixture *Fixture) (*modelStub, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen model stub failed: %w", err)
+	}
+	m := &modelStub{
+		mode:     mode,
+		upstream: strings.TrimSuffix(upstream, "/"),
+		fixture:  fixture,
+		client:   &http.Client{Timeout: 10 * time.Minute},
+		listener: listener,
+	}
+	m.server = &http.Server{Handler: m}
+	go func() {
+		if err := m.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("model stub serve error", zap.Error(err))
+		}
+	}()
+	return m, nil
+}
+
+// URL 模型桩服务地址，作为智能体的模型接口地址
+func (m *modelStub) URL() string {
+	return "http://" + m.listener.Addr().String()
+}
+
+// Close 关闭模型桩服务
+func (m *modelStub) Close() error {
+	return m.server.Close()
+}
+
+func (m *modelStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		writeModelError(w, http.StatusNotFound, "unsupported path "+r.URL.Path)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeModelError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request struct {
+		Stream bool `json:"stream"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeModelError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.Stream {
+		writeModelError(w, http.StatusBadRequest, "stream is not supported by eval model stub")
+		return
+	}
+
+	if m.mode == ModeRecord {
+		m.record(w, r, body)
+		return
+	}
+	m.replay(w)
+}
+
+// replay 按调用顺序返回录制的响应
+func (m *modelStub) replay(w http.ResponseWriter) {
+	m.mu.Lock()
+	index := m.next
+	m.next++
+	m.mu.Unlock()
+
+	if index >= len(m.fixture.ModelResponses) {
+		writeModelError(w, http.StatusInternalServerError,
+			fmt.Sprintf("no recorded model response for call %d, please record the case again", index+1))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(m.fixture.ModelResponses[index])
+}
+
+// record 转发请求到真实的模型接口并录制响应
+func (m *modelStub) record(w http.ResponseWriter, r *http.Request, body []byte) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, m.upstream+r.URL.Path, bytes.NewReader(body))
+	if err != nil {
+		writeModelError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", r.Header.Get("Authorization"))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		writeModelError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		writeModelError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	// 只录制成功的响应
+	if resp.StatusCode == http.StatusOK {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, respBody); err != nil {
+			log.Warn("model response is not json, skip recording", zap.Error(err))
+		} else {
+			m.mu.Lock()
+			m.fixture.ModelResponses = append(m.fixture.ModelResponses, compacted.Bytes())
+			m.mu.Unlock()
+		}
+	}
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(respBody)
+}
+
+// writeModelError 按 OpenAI 接口的格式返回错误
+func writeModelError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{
+			"message": message,
+			"type":    "eval_stub_error",
+		},
+	})
+}
@@ -0,0 +1,160 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Report 评测报告，字段和顺序保持稳定，便于在不同版本之间 diff
+type Report struct {
+	Suite   string         `json:"suite"`
+	Mode    string         `json:"mode"`
+	Summary *ReportSummary `json:"summary"`
+	Cases   []*CaseResult  `json:"cases"`
+}
+
+// ReportSummary 评测汇总
+type ReportSummary struct {
+	Total  int `json:"total"`
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	// 运行出错的用例数，同时计入失败数
+	Errors int `json:"errors"`
+	// LLM 评审的平均分，没有评审时为 0
+	AvgJudgeScore float64 `json:"avg_judge_score"`
+}
+
+// CaseResult 用例结果
+type CaseResult struct {
+	ID         string             `json:"id"`
+	Agent      string             `json:"agent"`
+	Passed     bool               `json:"passed"`
+	Error      string             `json:"error,omitempty"`
+	Assertions []*AssertionResult `json:"assertions"`
+	Judge      *JudgeResult       `json:"judge,omitempty"`
+	// 各工具的调用次数
+	ToolCalls map[string]int `json:"tool_calls"`
+	Output    string         `json:"output"`
+	// 运行耗时，只写入 JSON 报告，避免 Markdown 报告 diff 时的干扰
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// summarize 计算汇总
+func (r *Report) summarize() {
+	summary := &ReportSummary{Total: len(r.Cases)}
+	judged, totalScore := 0, 0
+	for _, c := range r.Cases {
+		if c.Passed {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+		if c.Error != "" {
+			summary.Errors++
+		}
+		if c.Judge != nil {
+			judged++
+			totalScore += c.Judge.Score
+		}
+	}
+	if judged > 0 {
+		summary.AvgJudgeScore = float64(totalScore) / float64(judged)
+	}
+	r.Summary = summary
+}
+
+// WriteJSON 写入 JSON 报告
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// WriteMarkdown 写入 Markdown 报告
+func (r *Report) WriteMarkdown(path string) error {
+	return writeFile(path, []byte(r.Markdown()))
+}
+
+// Markdown 生成 Markdown 报告
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 评测报告：%s\n\n", r.Suite)
+	fmt.Fprintf(&b, "- 模式：%s\n", r.Mode)
+	fmt.Fprintf(&b, "- 通过：%d/%d（出错 %d）\n", r.Summary.Passed, r.Summary.Total, r.Summary.Errors)
+	if r.Summary.AvgJudgeScore > 0 {
+		fmt.Fprintf(&b, "- LLM 评审平均分：%.2f\n", r.Summary.AvgJudgeScore)
+	}
+
+	b.WriteString("\n| 用例 | 智能体 | 结果 | 断言 | 评审 | 工具调用 |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, c := range r.Cases {
+		passedAssertions := 0
+		for _, a := range c.Assertions {
+			if a.Passed {
+				passedAssertions++
+			}
+		}
+		judgeScore := "-"
+		if c.Judge != nil {
+			judgeScore = fmt.Sprintf("%d", c.Judge.Score)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %d/%d | %s | %s |\n", c.ID, c.Agent, resultMark(c),
+			passedAssertions, len(c.Assertions), judgeScore, formatToolCalls(c.ToolCalls))
+	}
+
+	for _, c := range r.Cases {
+		if c.Passed {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", c.ID)
+		if c.Error != "" {
+			fmt.Fprintf(&b, "- 错误：%s\n", c.Error)
+		}
+		for _, a := range c.Assertions {
+			if !a.Passed {
+				fmt.Fprintf(&b, "- 断言 %s %s：%s\n", a.Type, a.Target, a.Message)
+			}
+		}
+		if c.Judge != nil && !c.Judge.Passed {
+			fmt.Fprintf(&b, "- 评审 %d 分：%s\n", c.Judge.Score, c.Judge.Reason)
+		}
+	}
+	return b.String()
+}
+
+func resultMark(c *CaseResult) string {
+	if c.Passed {
+		return "✅"
+	}
+	return "❌"
+}
+
+// formatToolCalls 按工具名排序输出调用次数
+func formatToolCalls(calls map[string]int) string {
+	if len(calls) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(calls))
+	for name := range calls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s×%d", name, calls[name]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
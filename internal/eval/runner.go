@@ -0,0 +1,238 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"go.uber.org/zap"
+	"txing-ai/internal/agent"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	mytool "txing-ai/internal/tool"
+)
+
+// 默认单个用例的超时时间
+const defaultCaseTimeout = 15 * time.Minute
+
+// 回放模式下录制文件没有记录模型时使用的模型名称
+const replayModel = "eval-replay"
+
+// errNoFixture 回放模式下用例没有录制文件，该用例记为失败
+var errNoFixture = errors.New("没有录制文件")
+
+// Options 评测运行选项
+type Options struct {
+	// 运行模式，默认为回放模式
+	Mode string
+	// 录制文件目录，为空时使用套件配置
+	FixtureDir string
+	// 录制和实时模式下使用的真实模型
+	Endpoint string
+	APIKey   string
+	Model    string
+	// LLM 评审使用的模型，为空表示不使用 LLM 评审
+	Judge *JudgeConfig
+	// 只运行指定的用例，为空表示全部运行
+	CaseIDs []string
+	// 单个用例的超时时间
+	Timeout time.Duration
+}
+
+// toolAware 支持替换工具的智能体
+type toolAware interface {
+	Tools() []tool.BaseTool
+	SetTools(tools []tool.BaseTool)
+}
+
+// Runner 评测运行器
+type Runner struct {
+	factory agent.AgentFactory
+	options *Options
+	judge   *judge
+}
+
+// NewRunner 创建评测运行器
+func NewRunner(ctx context.Context, factory agent.AgentFactory, options *Options) (*Runner, error) {
+	if options.Mode == "" {
+		options.Mode = ModeReplay
+	}
+	switch options.Mode {
+	case ModeReplay:
+	case ModeRecord, ModeLive:
+		if options.Endpoint == "" || options.Model == "" {
+			return nil, fmt.Errorf("endpoint and model are required in %s mode", options.Mode)
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode: %s", options.Mode)
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultCaseTimeout
+	}
+
+	r := &Runner{factory: factory, options: options}
+	if options.Judge != nil {
+		j, err := newJudge(ctx, options.Judge)
+		if err != nil {
+			return nil, err
+		}
+		r.judge = j
+	}
+	return r, nil
+}
+
+// Run 运行评测套件
+func (r *Runner) Run(ctx context.Context, suite *Suite) *Report {
+	report := &Report{Suite: suite.Name, Mode: r.options.Mode}
+	for _, c := range suite.Cases {
+		if !r.selected(c) {
+			continue
+		}
+		log.Info("eval case start", zap.String("case", c.ID), zap.String("agent", c.Agent))
+		result := r.runCase(ctx, suite, c)
+		log.Info("eval case finished", zap.String("case", c.ID), zap.Bool("passed", result.Passed),
+			zap.String("error", result.Error))
+		report.Cases = append(report.Cases, result)
+	}
+	report.summarize()
+	return report
+}
+
+func (r *Runner) selected(c *Case) bool {
+	if len(r.options.CaseIDs) == 0 {
+		return true
+	}
+	for _, id := range r.options.CaseIDs {
+		if id == c.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// runCase 运行单个用例并打分
+func (r *Runner) runCase(ctx context.Context, suite *Suite, c *Case) *CaseResult {
+	start := time.Now()
+	result := &CaseResult{
+		ID:         c.ID,
+		Agent:      c.Agent,
+		Assertions: make([]*AssertionResult, 0, len(c.Assertions)),
+		ToolCalls:  make(map[string]int),
+	}
+	defer func() {
+		result.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	run, err := r.execute(ctx, suite, c)
+	if run != nil {
+		result.Output = run.Output
+		for _, call := range run.ToolCalls {
+			result.ToolCalls[call.Name]++
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	passed := true
+	for _, a := range c.Assertions {
+		assertionResult := a.Evaluate(run)
+		result.Assertions = append(result.Assertions, assertionResult)
+		passed = passed && assertionResult.Passed
+	}
+
+	if c.Judge != nil && r.judge != nil {
+		judgeResult, err := r.judge.Evaluate(ctx, c, run)
+		if err != nil {
+			result.Error = fmt.Sprintf("judge failed: %v", err)
+			return result
+		}
+		result.Judge = judgeResult
+		passed = passed && judgeResult.Passed
+	}
+	result.Passed = passed
+	return result
+}
+
+// execute 使用录制回放的模型和工具运行智能体
+func (r *Runner) execute(ctx context.Context, suite *Suite, c *Case) (*RunResult, error) {
+	mode := r.options.Mode
+	fixturePath := suite.fixturePath(r.options.FixtureDir, c)
+	fixture := &Fixture{Model: r.options.Model}
+	if mode != ModeRecord {
+		loaded, err := LoadFixture(fixturePath)
+		switch {
+		case err == nil:
+			fixture = loaded
+		case mode == ModeLive && errors.Is(err, os.ErrNotExist):
+			// 实时模式下没有录制文件时，所有工具调用都返回未录制
+		case errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("%w：%s，请先使用 -mode record 录制", errNoFixture, fixturePath)
+		default:
+			return nil, fmt.Errorf("load fixture failed: %w", err)
+		}
+	}
+
+	instance, err := r.factory.CreateAgent(agent.AgentType(c.Agent))
+	if err != nil {
+		return nil, err
+	}
+	recorder := newToolRecorder(mode, fixture)
+	if toolAgent, ok := instance.(toolAware); ok {
+		tools, err := stubTools(ctx, toolAgent.Tools(), recorder)
+		if err != nil {
+			return nil, err
+		}
+		toolAgent.SetTools(tools)
+	}
+
+	endpoint, apiKey, model := r.options.Endpoint, r.options.APIKey, r.options.Model
+	if mode != ModeLive {
+		stub, err := newModelStub(mode, endpoint, fixture)
+		if err != nil {
+			return nil, err
+		}
+		defer stub.Close()
+		endpoint = stub.URL()
+		if mode == ModeReplay {
+			apiKey, model = "eval", fixture.Model
+			if model == "" {
+				model = replayModel
+			}
+		}
+	}
+
+	// 用例的上传文件不经过上传流程，直接允许智能体读取其所在目录，不复制到上传目录中
+	runCtx := ctx
+	filePath := suite.filePath(c)
+	if filePath != "" {
+		runCtx = mytool.WithAllowedDir(runCtx, filepath.Dir(filePath))
+	}
+	runCtx, cancel := context.WithTimeout(runCtx, r.options.Timeout)
+	defer cancel()
+	output, err := instance.ExecuteStream(runCtx, endpoint, apiKey, model, c.Input, filePath,
+		func(chunk *global.Chunk) error {
+			return nil
+		})
+	run := &RunResult{
+		Output:       output,
+		ToolCalls:    recorder.Calls(),
+		OutputSchema: instance.OutputSchema(),
+	}
+	if err != nil {
+		return run, err
+	}
+
+	if mode == ModeRecord {
+		if err := fixture.Save(fixturePath); err != nil {
+			return run, fmt.Errorf("save fixture failed: %w", err)
+		}
+		log.Info("eval fixture saved", zap.String("case", c.ID), zap.String("path", fixturePath))
+	}
+	return run, nil
+}
@@ -0,0 +1,75 @@
+package eval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"txing-ai/internal/global/logging"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+func TestRunner_FailsCaseWithoutFixture(t *testing.T) {
+	dir := t.TempDir()
+	suitePath := filepath.Join(dir, "suite.json")
+	err := os.WriteFile(suitePath, []byte(`{
+		"name": "test",
+		"cases": [{"id": "no-fixture", "agent": "travel", "input": "成都三日游", "assertions": [{"type": "schema"}]}]
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite, err := LoadSuite(suitePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := NewRunner(context.Background(), nil, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := runner.Run(context.Background(), suite)
+	if len(report.Cases) != 1 || report.Cases[0].Passed {
+		t.Fatalf("case without fixture should fail: %+v", report.Cases)
+	}
+	if !strings.Contains(report.Cases[0].Error, filepath.Join(dir, "fixtures", "no-fixture.json")) {
+		t.Errorf("error should contain fixture path: %s", report.Cases[0].Error)
+	}
+	if report.Summary.Failed != 1 || report.Summary.Errors != 1 || report.Summary.Passed != 0 {
+		t.Errorf("unexpected summary: %+v", report.Summary)
+	}
+}
+
+func TestSuite_filePath(t *testing.T) {
+	suite, err := LoadSuite("../../eval/suite.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range suite.Cases {
+		if c.File == "" {
+			continue
+		}
+		if _, err := os.Stat(suite.filePath(c)); err != nil {
+			t.Errorf("case %s file not found: %v", c.ID, err)
+		}
+	}
+}
+
+func TestSuite_fixtures(t *testing.T) {
+	suite, err := LoadSuite("../../eval/suite.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range suite.Cases {
+		if _, err := LoadFixture(suite.fixturePath("", c)); err != nil {
+			t.Errorf("case %s fixture: %v", c.ID, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Package eval 智能体评测：按测试用例运行智能体，使用录制回放的模型和工具离线运行，并对输出打分生成报告
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Suite 评测套件
+type Suite struct {
+	// 套件名称
+	Name string `json:"name"`
+	// 录制文件目录，相对于套件文件所在目录，默认为 fixtures
+	FixtureDir string `json:"fixture_dir"`
+	// 测试用例
+	Cases []*Case `json:"cases"`
+
+	// 套件文件所在目录
+	dir string
+}
+
+// Case 测试用例
+type Case struct {
+	// 用例 ID，同时作为录制文件名，同一套件内唯一
+	ID string `json:"id"`
+	// 用例说明
+	Description string `json:"description"`
+	// 智能体类型，如 resume、travel
+	Agent string `json:"agent"`
+	// 用户输入
+	Input string `json:"input"`
+	// 上传的文件路径（例如简历优化智能体需要的简历 PDF），相对路径相对于套件文件所在目录
+	File string `json:"file"`
+	// 断言
+	Assertions []*Assertion `json:"assertions"`
+	// LLM 评审，为空表示不使用 LLM 评审
+	Judge *JudgeCriteria `json:"judge"`
+}
+
+// JudgeCriteria LLM 评审标准
+type JudgeCriteria struct {
+	// 评分标准
+	Rubric string `json:"rubric"`
+	// 通过的最低分（1-10），默认为 6
+	MinScore int `json:"min_score"`
+}
+
+// LoadSuite 从 JSON 文件加载评测套件
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read suite failed: %w", err)
+	}
+	var suite Suite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("parse suite failed: %w", err)
+	}
+	suite.dir = filepath.Dir(path)
+	if suite.FixtureDir == "" {
+		suite.FixtureDir = "fixtures"
+	}
+	if err := suite.check(); err != nil {
+		return nil, err
+	}
+	return &suite, nil
+}
+
+// check 校验套件配置
+func (s *Suite) check() error {
+	ids := make(map[string]bool, len(s.Cases))
+	for i, c := range s.Cases {
+		if c.ID == "" {
+			return fmt.Errorf("case %d: id is required", i)
+		}
+		if ids[c.ID] {
+			return fmt.Errorf("case %s: duplicate id", c.ID)
+		}
+		ids[c.ID] = true
+		if c.Agent == "" {
+			return fmt.Errorf("case %s: agent is required", c.ID)
+		}
+		for _, a := range c.Assertions {
+			if err := a.check(); err != nil {
+				return fmt.Errorf("case %s: %w", c.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fixturePath 获取用例录制文件的路径
+func (s *Suite) fixturePath(fixtureDir string, c *Case) string {
+	if fixtureDir == "" {
+		fixtureDir = s.FixtureDir
+		if !filepath.IsAbs(fixtureDir) {
+			fixtureDir = filepath.Join(s.dir, fixtureDir)
+		}
+	}
+	return filepath.Join(fixtureDir, c.ID+".json")
+}
+
+// filePath 获取用例上传文件的路径
+func (s *Suite) filePath(c *Case) string {
+	if c.File == "" || filepath.IsAbs(c.File) {
+		return c.File
+	}
+	return filepath.Join(s.dir, c.File)
+}
@@ -0,0 +1,170 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// toolRecorder 工具调用的录制和回放，同一个用例的所有工具共享
+type toolRecorder struct {
+	mode    string
+	fixture *Fixture
+
+	mu sync.Mutex
+	// 录制的工具调用是否已经回放过
+	used []bool
+	// 本次运行的工具调用
+	calls []*ToolCall
+}
+
+func newToolRecorder(mode string, fixture *Fixture) *toolRecorder {
+	return &toolRecorder{
+		mode:    mode,
+		fixture: fixture,
+		used:    make([]bool, len(fixture.ToolCalls)),
+	}
+}
+
+// Calls 本次运行的工具调用
+func (r *toolRecorder) Calls() []*ToolCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*ToolCall(nil), r.calls...)
+}
+
+func (r *toolRecorder) add(call *ToolCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+	if r.mode == ModeRecord {
+		r.fixture.ToolCalls = append(r.fixture.ToolCalls, call)
+	}
+}
+
+// lookup 查找录制的工具调用
+// 优先匹配工具名和参数都相同且没有回放过的调用，其次是参数相同的调用（重复调用），
+// 实时模式下模型的参数可能变化，最后按工具名匹配没有回放过的调用
+func (r *toolRecorder) lookup(name string, arguments string) *ToolCall {
+	args := normalizeArguments(arguments)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	repeated := -1
+	for i, call := range r.fixture.ToolCalls {
+		if call.Name != name || normalizeArguments(call.Arguments) != args {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return call
+		}
+		if repeated < 0 {
+			repeated = i
+		}
+	}
+	if repeated >= 0 {
+		return r.fixture.ToolCalls[repeated]
+	}
+	if r.mode != ModeLive {
+		return nil
+	}
+	for i, call := range r.fixture.ToolCalls {
+		if call.Name == name && !r.used[i] {
+			r.used[i] = true
+			return call
+		}
+	}
+	return nil
+}
+
+// normalizeArguments 规范化 JSON 参数，忽略字段顺序和空白的差异
+func normalizeArguments(arguments string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+		return arguments
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return arguments
+	}
+	return string(data)
+}
+
+// stubTool 录制回放工具，录制模式下调用真实工具并录制结果，其他模式下返回录制的结果
+type stubTool struct {
+	info     *schema.ToolInfo
+	inner    tool.InvokableTool
+	recorder *toolRecorder
+}
+
+// 接口实现校验
+var _ tool.InvokableTool = (*stubTool)(nil)
+
+func (t *stubTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.info, nil
+}
+
+func (t *stubTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if t.recorder.mode == ModeRecord {
+		output, err := t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+		call := &ToolCall{Name: t.info.Name, Arguments: argumentsInJSON, Output: output}
+		if err != nil {
+			call.Error = err.Error()
+		}
+		t.recorder.add(call)
+		return output, err
+	}
+
+	recorded := t.recorder.lookup(t.info.Name, argumentsInJSON)
+	if recorded == nil {
+		output := fmt.Sprintf("工具 %s 没有录制该参数的调用结果，请根据已有信息继续", t.info.Name)
+		t.recorder.add(&ToolCall{Name: t.info.Name, Arguments: argumentsInJSON, Output: output, Error: "not recorded"})
+		return output, nil
+	}
+	t.recorder.add(&ToolCall{Name: t.info.Name, Arguments: argumentsInJSON, Output: recorded.Output, Error: recorded.Error})
+	if recorded.Error != "" {
+		return recorded.Output, errors.New(recorded.Error)
+	}
+	return recorded.Output, nil
+}
+
+// stubTools 将智能体的工具替换为录制回放工具
+// 回放时录制文件中有但当前没有注册的工具（例如没有启动的 MCP 工具）也会根据录制的定义还原
+func stubTools(ctx context.Context, tools []tool.BaseTool, recorder *toolRecorder) ([]tool.BaseTool, error) {
+	stubbed := make([]tool.BaseTool, 0, len(tools))
+	registered := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		registered[info.Name] = true
+		if recorder.mode == ModeRecord {
+			spec, err := newToolSpec(info)
+			if err != nil {
+				return nil, fmt.Errorf("convert tool %s failed: %w", info.Name, err)
+			}
+			recorder.fixture.Tools = append(recorder.fixture.Tools, spec)
+		}
+		stubbed = append(stubbed, &stubTool{info: info, inner: invokable, recorder: recorder})
+	}
+	if recorder.mode == ModeRecord {
+		return stubbed, nil
+	}
+	for _, spec := range recorder.fixture.Tools {
+		if !registered[spec.Name] {
+			stubbed = append(stubbed, &stubTool{info: spec.toolInfo(), recorder: recorder})
+		}
+	}
+	return stubbed, nil
+}
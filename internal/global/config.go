@@ -24,18 +24,29 @@ var (
 )
 
 type AppConfig struct {
-	*ServerConfig      `mapstructure:"server"`
-	*LogConfig         `mapstructure:"log"`
-	*MysqlConfig       `mapstructure:"mysql"`
-	*RedisConfig       `mapstructure:"redis"`
-	*SnowflakeConfig   `mapstructure:"snowflake"`
-	*AuthConfig        `mapstructure:"auth"`
-	*CosConfig         `mapstructure:"cos"`
-	*AmapConfig        `mapstructure:"amap"`
-	*AWSConfig         `mapstructure:"aws"`
-	*SearchAPIConfig   `mapstructure:"searchapi"`
-	*ImageSearchConfig `mapstructure:"image_search"`
-	*LocalUploadConfig `mapstructure:"local_upload"`
+	*ServerConfig           `mapstructure:"server"`
+	*LogConfig              `mapstructure:"log"`
+	*MysqlConfig            `mapstructure:"mysql"`
+	*RedisConfig            `mapstructure:"redis"`
+	*SnowflakeConfig        `mapstructure:"snowflake"`
+	*AuthConfig             `mapstructure:"auth"`
+	*CosConfig              `mapstructure:"cos"`
+	*AmapConfig             `mapstructure:"amap"`
+	*AWSConfig              `mapstructure:"aws"`
+	*SearchAPIConfig        `mapstructure:"searchapi"`
+	*WebSearchConfig        `mapstructure:"web_search"`
+	*WebScrapingConfig      `mapstructure:"web_scraping"`
+	*ImageSearchConfig      `mapstructure:"image_search"`
+	*LocalUploadConfig      `mapstructure:"local_upload"`
+	*AgentConfig            `mapstructure:"agent"`
+	*StructuredOutputConfig `mapstructure:"structured_output"`
+	*MCPConfig              `mapstructure:"mcp"`
+	*WorkspaceConfig        `mapstructure:"workspace"`
+	*PDFConfig              `mapstructure:"pdf"`
+	*StorageConfig          `mapstructure:"storage"`
+	*ScanConfig             `mapstructure:"scan"`
+	*RetentionConfig        `mapstructure:"retention"`
+	*KnowledgeConfig        `mapstructure:"knowledge"`
 }
 
 type ServerConfig struct {
@@ -105,12 +116,43 @@ type AWSConfig struct {
 	Bucket    string `mapstructure:"bucket"`
 	// 预签名URL有效期 单位秒
 	SignExpire time.Duration `mapstructure:"sign_expire"`
+	// S3 兼容服务的地址，例如 MinIO 的 http://minio:9000，为空时使用 AWS S3
+	Endpoint string `mapstructure:"endpoint"`
+	// 使用路径风格的地址（endpoint/bucket/key），MinIO 等自建服务一般需要开启
+	UsePathStyle bool `mapstructure:"use_path_style"`
 }
 
 type SearchAPIConfig struct {
 	Endpoint string `mapstructure:"endpoint"`
 	ApiKey   string `mapstructure:"api_key"`
 	Engine   string `mapstructure:"Engine"`
+	// 每分钟最多请求次数，0 表示不限制
+	RateLimit int `mapstructure:"rate_limit"`
+}
+
+type WebSearchConfig struct {
+	// 启用的搜索服务，按顺序尝试，前一个失败或者限流时使用下一个
+	// 可选 searchapi、searxng、bing、brave，为空时只使用 searchapi
+	Providers []string `mapstructure:"providers"`
+	// 返回的搜索结果数量
+	MaxResults int `mapstructure:"max_results"`
+	// 搜索结果缓存时间 单位秒，0 表示不缓存
+	CacheTTL time.Duration         `mapstructure:"cache_ttl"`
+	SearXNG  *SearchProviderConfig `mapstructure:"searxng"`
+	Bing     *SearchProviderConfig `mapstructure:"bing"`
+	Brave    *SearchProviderConfig `mapstructure:"brave"`
+}
+
+type WebScrapingConfig struct {
+	// 网页正文缓存时间 单位秒，0 表示不缓存
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+}
+
+type SearchProviderConfig struct {
+	Endpoint string `mapstructure:"endpoint"`
+	ApiKey   string `mapstructure:"api_key"`
+	// 每分钟最多请求次数，0 表示不限制
+	RateLimit int `mapstructure:"rate_limit"`
 }
 
 type ImageSearchConfig struct {
@@ -128,6 +170,63 @@ type LocalUploadConfig struct {
 	MaxSize int    `mapstructure:"max_size"`
 }
 
+type AgentConfig struct {
+	// 工具调用默认策略：auto（自动执行）、ask（需用户审批）、deny（禁止调用）
+	DefaultToolPolicy string `mapstructure:"default_tool_policy"`
+	// 单个工具的调用策略，key 为工具名称，支持通配符，如 maps_*，多个通配符匹配时使用最具体的一个
+	ToolPolicies map[string]string `mapstructure:"tool_policies"`
+	// 等待用户审批的超时时间 单位秒
+	ApprovalTimeout time.Duration `mapstructure:"approval_timeout"`
+	// 支持的智能体（如旅游攻略）是否启用规划-执行多智能体编排
+	PlanExecute bool `mapstructure:"plan_execute"`
+	// 工具调用默认超时时间 单位秒
+	ToolTimeout time.Duration `mapstructure:"tool_timeout"`
+	// 幂等工具调用失败或超时后的默认重试次数，只对 tool_limits 中标记为 idempotent 的工具生效
+	ToolRetries int `mapstructure:"tool_retries"`
+	// 同一轮中并行执行的工具调用数量上限
+	MaxParallelToolCalls int `mapstructure:"max_parallel_tool_calls"`
+	// 单个工具的超时、重试、并发限制，key 为工具名称，支持通配符
+	ToolLimits map[string]*ToolLimitConfig `mapstructure:"tool_limits"`
+	// 单次运行的预算
+	Budget *AgentBudgetConfig `mapstructure:"budget"`
+}
+
+type WorkspaceConfig struct {
+	// 工作区根目录，相对于当前工作目录
+	Dir string `mapstructure:"dir"`
+	// 每个用户所有工作区的总大小上限 单位字节
+	UserQuota int64 `mapstructure:"user_quota"`
+	// 单个文件的大小上限 单位字节
+	MaxFileSize int64 `mapstructure:"max_file_size"`
+	// 运行异常退出后残留工作区的保留时间 单位秒
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+type ToolLimitConfig struct {
+	// 超时时间 单位秒
+	Timeout time.Duration `mapstructure:"timeout"`
+	// 是否幂等（如搜索、地图、网页抓取），只有幂等的工具才会在失败后重试
+	Idempotent bool `mapstructure:"idempotent"`
+	// 重试次数，为 0 时使用 tool_retries，只对幂等的工具生效
+	Retries int `mapstructure:"retries"`
+	// 同时执行的调用数量上限，0 表示不限制
+	Concurrency int `mapstructure:"concurrency"`
+}
+
+type AgentBudgetConfig struct {
+	// 工具调用总次数上限
+	MaxToolCalls int `mapstructure:"max_tool_calls"`
+	// 运行总时长上限 单位秒
+	MaxRunTime time.Duration `mapstructure:"max_run_time"`
+	// 模型消耗的 token 总数上限
+	MaxTokens int `mapstructure:"max_tokens"`
+}
+
+type StructuredOutputConfig struct {
+	// 输出不符合 JSON Schema 时，最多要求模型修正的次数
+	MaxRepairAttempts int `mapstructure:"max_repair_attempts"`
+}
+
 func LoadConfig() *AppConfig {
 	configOnce.Do(func() {
 		var configPath string
@@ -163,3 +262,80 @@ func LoadConfig() *AppConfig {
 
 	return appConfig
 }
+
+type MCPConfig struct {
+	// MCP 服务器配置文件路径，为空时使用 runtime/mcp_config.json，修改后自动重新加载
+	ConfigPath string `mapstructure:"config_path"`
+	// 健康检查间隔 单位秒
+	HealthCheckInterval time.Duration `mapstructure:"health_check_interval"`
+	// 断线重连的最大退避时间 单位秒
+	MaxReconnectBackoff time.Duration `mapstructure:"max_reconnect_backoff"`
+}
+
+type PDFConfig struct {
+	// 渲染方式 native（纯 Go 实现，默认）或者 wkhtmltopdf（需要在服务器上安装）
+	Backend string `mapstructure:"backend"`
+	// 中文字体文件路径，只支持 TTF 格式，为空时在常见位置查找
+	FontPath string `mapstructure:"font_path"`
+	// 粗体字体文件路径，为空时粗体使用常规字体
+	BoldFontPath string `mapstructure:"bold_font_path"`
+	// 没有指定模板时使用的模板，可选 default、resume、travel
+	Template string `mapstructure:"template"`
+}
+
+type ScanConfig struct {
+	// 上传文件的病毒扫描方式 none（默认，不扫描）或者 clamav（通过 clamd 的 INSTREAM 命令扫描）
+	Backend string `mapstructure:"backend"`
+	// clamd 地址，例如 tcp://127.0.0.1:3310 或者 unix:///var/run/clamav/clamd.ctl
+	Address string `mapstructure:"address"`
+	// 单个文件的扫描超时 单位秒
+	Timeout time.Duration `mapstructure:"timeout"`
+	// 扫描服务不可用时是否放行，放行的文件扫描状态记录为 error
+	FailOpen bool `mapstructure:"fail_open"`
+}
+
+type StorageConfig struct {
+	// 新文件使用的存储后端 local（默认，保存在 local_upload.dir）、cos 或者 s3（包括 MinIO 等兼容服务）
+	Backend string `mapstructure:"backend"`
+	// 文件在存储中的路径前缀，例如 files/
+	Prefix string `mapstructure:"prefix"`
+}
+
+type RetentionConfig struct {
+	// 是否启动后台清理任务
+	Enabled bool `mapstructure:"enabled"`
+	// 清理间隔 单位秒
+	Interval time.Duration `mapstructure:"interval"`
+	// 各来源文件的保留天数，0 表示永久保存
+	UploadDays int `mapstructure:"upload_days"`
+	AgentDays  int `mapstructure:"agent_days"`
+	ToolDays   int `mapstructure:"tool_days"`
+	// 没有被会话、预设、知识库、头像引用的用户上传文件超过这个天数后删除，0 表示不清理
+	OrphanDays int `mapstructure:"orphan_days"`
+	// 上传目录中没有登记的临时文件（转换失败的 HTML、下载的图片等）的保留天数，0 表示不清理
+	ScratchDays int `mapstructure:"scratch_days"`
+	// 每个用户的文件总大小上限 单位字节，0 表示不限制
+	UserQuota int64 `mapstructure:"user_quota"`
+	// 每批处理的文件数量
+	BatchSize int `mapstructure:"batch_size"`
+}
+
+type KnowledgeConfig struct {
+	// 向量索引后端，默认 memory（内存中暴力检索）
+	VectorStore string `mapstructure:"vector_store"`
+	// 新建知识库默认使用的向量化模型，需要有渠道支持该模型
+	EmbeddingModel string `mapstructure:"embedding_model"`
+	// 每次向量化请求的文本块数量
+	EmbeddingBatch int `mapstructure:"embedding_batch"`
+	// 切分块大小和相邻块重叠的字符数
+	ChunkSize    int `mapstructure:"chunk_size"`
+	ChunkOverlap int `mapstructure:"chunk_overlap"`
+	// 每次对话检索的文本块数量
+	TopK int `mapstructure:"top_k"`
+	// 相似度低于该值的文本块不使用
+	MinScore float32 `mapstructure:"min_score"`
+	// 同时处理文档的数量
+	Workers int `mapstructure:"workers"`
+	// 单个文档的最大字节数
+	MaxDocumentSize int64 `mapstructure:"max_document_size"`
+}
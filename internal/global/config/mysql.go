@@ -82,6 +82,12 @@ func NewMysqlDB(conf *global.MysqlConfig) *gorm.DB {
 	db.AutoMigrate(&model.Preset{})
 	db.AutoMigrate(&model.Conversation{})
 	db.AutoMigrate(&model.Website{})
+	db.AutoMigrate(&model.MCPServer{})
+	db.AutoMigrate(&model.ApiKey{})
+	db.AutoMigrate(&model.File{})
+	db.AutoMigrate(&model.KnowledgeBase{})
+	db.AutoMigrate(&model.KnowledgeDocument{})
+	db.AutoMigrate(&model.KnowledgeChunk{})
 
 	// 设置 GORM 的 JSON 序列化器
 	db.Config.PrepareStmt = true
@@ -13,6 +13,8 @@ const (
 	ChannelTypePolo       = "polo"
 	ChannelOpenai         = "OpenAI"
 	ChannelEinoOpenai     = "Eino OpenAI"
+	ChannelAnthropic      = "Anthropic"
+	ChannelGemini         = "Gemini"
 )
 
 // 所有大模型
@@ -27,6 +29,12 @@ const (
 	MessageTypeStop = "stop"
 )
 
+// 流式消息块事件类型
+const (
+	// 结构化输出校验通过
+	EventStructuredOutput = "structured_output"
+)
+
 // 目标模型类型（用于模型映射条件）
 const (
 	// 直连 LLM
@@ -26,6 +26,10 @@ type Chunk struct {
 	ToolResult string `json:"tool_result"`
 	// 显示信息（用于前端显示）
 	ShowMsg string `json:"show_msg"`
+	// 事件类型（为空表示普通消息块），如工具审批请求
+	Event string `json:"event,omitempty"`
+	// 事件附带的数据
+	Data interface{} `json:"data,omitempty"`
 }
 
 // ModelMapping 模型映射规则
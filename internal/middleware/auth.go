@@ -2,8 +2,12 @@ package middleware
 
 import (
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 	"net/http"
 	"strings"
+	"time"
+	"txing-ai/internal/domain"
 	"txing-ai/internal/enum"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
@@ -84,3 +88,61 @@ func AuthMiddleware() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// API Key 最后使用时间的更新间隔，避免每次请求都写数据库
+const apiKeyTouchInterval = time.Minute
+
+// ApiKeyAuthMiddleware 认证中间件，同时支持登录令牌和 API Key
+// 用于 MCP 等提供给脚本和第三方客户端调用的接口，格式：Authorization: Bearer <token 或 API Key>
+// scope 为接口需要的权限范围，使用 API Key 认证时检查，登录令牌不受限制
+func ApiKeyAuthMiddleware(scope string) gin.HandlerFunc {
+	jwtAuth := AuthMiddleware()
+	return func(ctx *gin.Context) {
+		parts := strings.SplitN(ctx.Request.Header.Get(TokenKey), " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" || !utils.IsApiKey(parts[1]) {
+			jwtAuth(ctx)
+			return
+		}
+
+		db := utils.GetDBFromContext[*gorm.DB](ctx)
+		var apiKey domain.ApiKey
+		if err := db.Preload("User").Where("key_hash = ?", utils.HashApiKey(parts[1])).First(&apiKey).Error; err != nil {
+			log.Error("Api key is invalid", zap.Error(err))
+			utils.ErrorWithHttpCode(ctx, http.StatusUnauthorized, global.CodeNotLogin, nil)
+			ctx.Abort()
+			return
+		}
+		if apiKey.Revoked() {
+			log.Error("Api key is revoked", zap.Int64("apiKeyId", apiKey.Id))
+			utils.ErrorWithHttpCode(ctx, http.StatusUnauthorized, global.CodeNotLogin, nil)
+			ctx.Abort()
+			return
+		}
+		if !apiKey.HasScope(scope) {
+			log.Error("Api key scope is not allowed", zap.Int64("apiKeyId", apiKey.Id), zap.String("scope", scope))
+			utils.ErrorWithHttpCode(ctx, http.StatusForbidden, global.CodeNotPermission, nil)
+			ctx.Abort()
+			return
+		}
+		// 用户被禁用后 API Key 同时失效
+		if apiKey.User == nil || apiKey.User.Status == enum.UserStatusForbidden {
+			log.Error("Api key owner is disabled", zap.Int64("userId", apiKey.UserID))
+			utils.ErrorWithHttpCode(ctx, http.StatusForbidden, global.CodeNotPermission, nil)
+			ctx.Abort()
+			return
+		}
+
+		now := time.Now()
+		if apiKey.LastUsedTime == nil || now.Sub(*apiKey.LastUsedTime) > apiKeyTouchInterval {
+			if err := db.Model(&apiKey).UpdateColumn("last_used_time", now).Error; err != nil {
+				log.Warn("update api key last used time failed", zap.Error(err))
+			}
+		}
+
+		// 验证通过，设置当前用户 ID 到上下文中
+		ctx.Set("userId", apiKey.UserID)
+		ctx.Set("role", apiKey.User.Role)
+		ctx.Set("apiKeyId", apiKey.Id)
+		ctx.Next()
+	}
+}
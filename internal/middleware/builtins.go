@@ -5,11 +5,15 @@ import (
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 	"txing-ai/internal/agent"
+	knowledgeservice "txing-ai/internal/service/knowledge"
+	"txing-ai/internal/storage"
+	"txing-ai/internal/tool/mcp"
 	"txing-ai/internal/utils"
 )
 
 func BuiltinMiddleWare(db *gorm.DB, cache *redis.Client, cosClient *utils.COSClient,
-	agentFactory agent.AgentFactory) gin.HandlerFunc {
+	agentFactory agent.AgentFactory, approvalManager *agent.ApprovalManager, mcpClientManager *mcp.MCPClientManager,
+	blobStores *storage.Stores, knowledge *knowledgeservice.Service) gin.HandlerFunc {
 	// 创建消息限制工具类实例
 	messageLimiter := utils.NewMessageLimiter(cache)
 	return func(c *gin.Context) {
@@ -18,6 +22,10 @@ func BuiltinMiddleWare(db *gorm.DB, cache *redis.Client, cosClient *utils.COSCli
 		c.Set("cos", cosClient)
 		c.Set("agentFactory", agentFactory)
 		c.Set("messageLimiter", messageLimiter)
+		c.Set("approvalManager", approvalManager)
+		c.Set("mcpClientManager", mcpClientManager)
+		c.Set("blobStores", blobStores)
+		c.Set("knowledge", knowledge)
 		c.Next()
 	}
 }
@@ -7,14 +7,19 @@ import (
 	"gorm.io/gorm"
 	"time"
 	"txing-ai/internal/agent"
+	knowledgeservice "txing-ai/internal/service/knowledge"
+	"txing-ai/internal/storage"
+	"txing-ai/internal/tool/mcp"
 	"txing-ai/internal/utils"
 )
 
-func RegisterMiddleware(app *gin.Engine, db *gorm.DB, redis *redis.Client, cosClient *utils.COSClient, agentFactory agent.AgentFactory) {
+func RegisterMiddleware(app *gin.Engine, db *gorm.DB, redis *redis.Client, cosClient *utils.COSClient, agentFactory agent.AgentFactory,
+	approvalManager *agent.ApprovalManager, mcpClientManager *mcp.MCPClientManager, blobStores *storage.Stores,
+	knowledge *knowledgeservice.Service) {
 
 	app.Use(LoggerWithZap(zap.L(), time.DateTime, false))
 
-	app.Use(BuiltinMiddleWare(db, redis, cosClient, agentFactory))
+	app.Use(BuiltinMiddleWare(db, redis, cosClient, agentFactory, approvalManager, mcpClientManager, blobStores, knowledge))
 
 	app.Use(RecoveryWithZap(zap.L(), false))
 
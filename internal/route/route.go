@@ -3,16 +3,23 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"txing-ai/internal/controller/agent"
+	"txing-ai/internal/controller/apikey"
 	"txing-ai/internal/controller/captcha"
 	"txing-ai/internal/controller/channel"
 	"txing-ai/internal/controller/chat"
 	"txing-ai/internal/controller/cos"
 	"txing-ai/internal/controller/file"
+	"txing-ai/internal/controller/knowledge"
+	"txing-ai/internal/controller/mcp"
+	"txing-ai/internal/controller/mcpserver"
 	"txing-ai/internal/controller/model"
+	"txing-ai/internal/controller/openaiapi"
 	"txing-ai/internal/controller/preset"
 	"txing-ai/internal/controller/user"
 	"txing-ai/internal/controller/website"
+	"txing-ai/internal/enum"
 	"txing-ai/internal/iface"
+	"txing-ai/internal/middleware"
 	"txing-ai/static"
 
 	_ "txing-ai/docs"
@@ -21,7 +28,7 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func Register(router gin.IRouter, res iface.ResourceProvider) {
+func Register(router gin.IRouter, res iface.ResourceProvider, mcpServer *mcpserver.Server) {
 
 	// 注册前端页面
 	static.Register(router.Group("/dash"))
@@ -54,6 +61,21 @@ func Register(router gin.IRouter, res iface.ResourceProvider) {
 	// 文件上传下载相关路由
 	file.Register(group)
 
+	// 知识库相关路由
+	knowledge.Register(group)
+
+	// MCP 服务器管理路由
+	mcp.Register(group)
+
+	// API Key 管理路由
+	apikey.Register(group)
+
+	// 对外提供的 MCP 服务，支持登录令牌和 API Key 认证
+	mcpServer.Register(group, middleware.ApiKeyAuthMiddleware(enum.ApiKeyScopeMcp))
+
+	// OpenAI 兼容接口
+	openaiapi.Register(router.Group("/v1"))
+
 	// 注册Swagger
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 }
@@ -3,6 +3,7 @@ package channel
 import (
 	"errors"
 	"math/rand"
+	"sort"
 	"txing-ai/internal/domain"
 	"txing-ai/internal/global/logging/log"
 
@@ -50,8 +51,26 @@ func ChooseChannelAndModel(db *gorm.DB, model string, mappingParams map[string]i
 		return c.GetMappingModel(model, mappingParams) != ""
 	})
 
+	if len(filteredSequence) == 0 {
+		log.Error("no channel matches the mapping params", zap.String("model", model), zap.Any("params", mappingParams))
+		return nil, "", errors.New("no channel matches the mapping params for model " + model)
+	}
+
 	// TODO 后续优化为根据优先级和权重选择 以及实现重试机制
 	// 从中随机选择一个 channel
 	targetChannel := filteredSequence[rand.Intn(len(filteredSequence))]
 	return &targetChannel, targetChannel.GetMappingModel(model, mappingParams), nil
 }
+
+// AvailableModels 返回所有启用的渠道支持的模型，按名称排序
+func AvailableModels(db *gorm.DB) ([]string, error) {
+	var channels []domain.Channel
+	if err := db.Where("status = ?", 1).Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	models := lo.Uniq(lo.FlatMap(channels, func(channel domain.Channel, _ int) []string {
+		return channel.Models
+	}))
+	sort.Strings(models)
+	return models, nil
+}
@@ -0,0 +1,36 @@
+package channel
+
+import (
+	"context"
+	"txing-ai/internal/adapter"
+	adaptercommon "txing-ai/internal/adapter/common"
+	"txing-ai/internal/global/logging/log"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NewEmbeddingRequest 选择支持该向量化模型的渠道，按渠道的模型映射替换模型名称后发送请求
+func NewEmbeddingRequest(ctx context.Context, db *gorm.DB, conf *adaptercommon.EmbeddingConfig) (*adaptercommon.EmbeddingResult, error) {
+	targetChannel, mappingModel, err := ChooseChannelAndModel(db, conf.Model, map[string]interface{}{})
+	if err != nil {
+		log.Error("choose channel failed", zap.Error(err))
+		return nil, err
+	}
+	// 复制一份配置，避免修改调用方的模型名称
+	mapped := *conf
+	mapped.Model = mappingModel
+	return adapter.NewEmbeddingRequest(ctx, targetChannel, &mapped)
+}
+
+// NewRerankRequest 选择支持该重排序模型的渠道，按渠道的模型映射替换模型名称后发送请求
+func NewRerankRequest(ctx context.Context, db *gorm.DB, conf *adaptercommon.RerankConfig) (*adaptercommon.RerankResult, error) {
+	targetChannel, mappingModel, err := ChooseChannelAndModel(db, conf.Model, map[string]interface{}{})
+	if err != nil {
+		log.Error("choose channel failed", zap.Error(err))
+		return nil, err
+	}
+	mapped := *conf
+	mapped.Model = mappingModel
+	return adapter.NewRerankRequest(ctx, targetChannel, &mapped)
+}
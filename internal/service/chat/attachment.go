@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/global/logging/log"
+	fileservice "txing-ai/internal/service/file"
+	"txing-ai/internal/storage"
+	"txing-ai/internal/tool/ingest"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 每条消息最多的附件数
+	maxAttachments = 5
+	// 读取的附件最大字节数
+	maxAttachmentSize = 32 << 20
+	// 每个附件放入消息的最多字符数，超出的部分截断
+	maxAttachmentRunes = 20000
+	// 附件内容与消息内容之间的分隔
+	attachmentSeparator = "\n\n---\n附件 "
+)
+
+// AttachmentError 附件不能使用的原因，可以直接提示给用户
+type AttachmentError struct {
+	Message string
+}
+
+func (e *AttachmentError) Error() string {
+	return e.Message
+}
+
+// AttachFiles 解析消息中引用的附件，把提取出的文本追加到消息内容后面
+// 附件必须是当前用户上传的文件，内容中保留附件的下载链接，文件清理时会把它当作仍在使用的文件
+func AttachFiles(ctx context.Context, db *gorm.DB, stores *storage.Stores, userId int64, msg *dto.WsMessageRequest) error {
+	if len(msg.Files) == 0 {
+		return nil
+	}
+	if len(msg.Files) > maxAttachments {
+		return &AttachmentError{Message: fmt.Sprintf("每条消息最多添加 %d 个附件", maxAttachments)}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(msg.Content)
+	for _, id := range msg.Files {
+		file, err := fileservice.GetUserFile(db, userId, id)
+		if errors.Is(err, fileservice.ErrFileNotFound) || errors.Is(err, fileservice.ErrFileExpired) {
+			return &AttachmentError{Message: fmt.Sprintf("附件 %d %s", id, err.Error())}
+		}
+		if err != nil {
+			return err
+		}
+		if !ingest.Supported(file.Name) {
+			return &AttachmentError{Message: fmt.Sprintf("不支持读取附件 %s 的内容", file.Name)}
+		}
+		doc, err := parseFile(ctx, stores, file)
+		if err != nil {
+			return err
+		}
+		for _, warning := range doc.Warnings {
+			log.Warn("解析附件时出现问题", zap.Int64("id", file.Id), zap.String("warning", warning))
+		}
+
+		text := doc.Text()
+		if runes := []rune(text); len(runes) > maxAttachmentRunes {
+			text = string(runes[:maxAttachmentRunes]) + "\n\n……（内容过长，已截断）"
+		}
+		sb.WriteString(fmt.Sprintf("%s[%s](/api/file/%d/download) 的内容：\n\n%s", attachmentSeparator, file.Name, file.Id, text))
+	}
+	msg.Content = sb.String()
+	return nil
+}
+
+// parseFile 读取文件存储中的文件并解析
+func parseFile(ctx context.Context, stores *storage.Stores, file *domain.File) (*ingest.Document, error) {
+	reader, info, err := fileservice.Open(ctx, stores, file)
+	if err != nil {
+		if errors.Is(err, fileservice.ErrFileNotFound) {
+			return nil, &AttachmentError{Message: fmt.Sprintf("附件 %s 不存在", file.Name)}
+		}
+		return nil, err
+	}
+	defer reader.Close()
+	if info.Size > maxAttachmentSize {
+		return nil, &AttachmentError{Message: fmt.Sprintf("附件 %s 太大，最多读取 %d MB", file.Name, maxAttachmentSize>>20)}
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, maxAttachmentSize))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ingest.Parse(ctx, file.Name, bytes.NewReader(data), int64(len(data)), nil)
+	if err != nil {
+		return nil, &AttachmentError{Message: fmt.Sprintf("解析附件 %s 失败: %v", file.Name, err)}
+	}
+	return doc, nil
+}
@@ -11,6 +11,7 @@ import (
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
 	"txing-ai/internal/service/channel"
+	knowledgeservice "txing-ai/internal/service/knowledge"
 	"txing-ai/internal/utils"
 
 	"go.uber.org/zap"
@@ -73,8 +74,18 @@ func HandleChat(ctx *gin.Context, conn *utils.Connection, conversation *domain.C
 
 	conn.SetCancelFunc(cancel)
 
+	// 从会话引用的知识库中检索资料，先把引用的资料发送给客户端
+	knowledge := utils.GetKnowledgeFromContext[*knowledgeservice.Service](ctx)
+	messages, references := withKnowledge(ctxWithCancel, knowledge, conversation, conversation.GetChatMessages())
+	if len(references) > 0 {
+		conn.Send(dto.WsMessageResponse{
+			References:     references,
+			ConversationId: conversation.Id,
+		})
+	}
+
 	// 开启聊天
-	err = execChat(ctxWithCancel, conn, conversation, buffer, db)
+	err = execChat(ctxWithCancel, conn, conversation, messages, buffer, db)
 
 	if err != nil {
 		log.Error("execChat failed", zap.Error(err))
@@ -109,7 +120,7 @@ func HandleChat(ctx *gin.Context, conn *utils.Connection, conversation *domain.C
 }
 
 // 开启聊天
-func execChat(ctx context.Context, conn *utils.Connection, conversation *domain.Conversation, buffer *utils.ChatRespBuffer, db *gorm.DB) error {
+func execChat(ctx context.Context, conn *utils.Connection, conversation *domain.Conversation, messages []global.Message, buffer *utils.ChatRespBuffer, db *gorm.DB) error {
 	// 创建 channel 用于接收大模型的响应
 	chunkChan := make(chan partialChunk, 20)
 	defer close(chunkChan)
@@ -127,7 +138,7 @@ func execChat(ctx context.Context, conn *utils.Connection, conversation *domain.
 			db,
 			&adaptercommon.ChatConfig{
 				Model:             conversation.Model,
-				Message:           conversation.GetChatMessages(),
+				Message:           messages,
 				EnableWeb:         conversation.EnableWeb,
 				MaxTokens:         conversation.MaxTokens,
 				Temperature:       conversation.Temperature,
@@ -136,6 +147,7 @@ func execChat(ctx context.Context, conn *utils.Connection, conversation *domain.
 				FrequencyPenalty:  conversation.FrequencyPenalty,
 				PresencePenalty:   conversation.PresencePenalty,
 				RepetitionPenalty: conversation.RepetitionPenalty,
+				ResponseSchema:    conversation.ResponseSchema,
 			},
 			func(chunk *global.Chunk) error {
 				chunkChan <- partialChunk{Chunk: chunk, End: false, Err: nil}
@@ -165,6 +177,7 @@ func execChat(ctx context.Context, conn *utils.Connection, conversation *domain.
 			err := conn.Send(dto.WsMessageResponse{
 				Content:          content,
 				ReasoningContent: reasoningContent,
+				Data:             data.Chunk.Data,
 				End:              false,
 				ConversationId:   conversation.Id,
 			})
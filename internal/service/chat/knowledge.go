@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	knowledgeservice "txing-ai/internal/service/knowledge"
+
+	"go.uber.org/zap"
+)
+
+// withKnowledge 从会话引用的知识库中检索与最后一条用户消息相关的资料，作为系统消息插入到这条用户消息之前
+// 检索失败时只记录日志，不影响正常聊天；插入的资料不保存到会话记录中
+func withKnowledge(ctx context.Context, knowledge *knowledgeservice.Service, conversation *domain.Conversation, messages []global.Message) ([]global.Message, []dto.KnowledgeReference) {
+	if knowledge == nil || len(conversation.KnowledgeBaseIDs) == 0 {
+		return messages, nil
+	}
+	last := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == global.User {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return messages, nil
+	}
+
+	passages, err := knowledge.Retrieve(ctx, conversation.KnowledgeBaseIDs, retrievalQuery(messages[last].Content), 0)
+	if err != nil {
+		log.Error("retrieve knowledge failed", zap.Int64("conversationId", conversation.Id), zap.Error(err))
+		return messages, nil
+	}
+	if len(passages) == 0 {
+		return messages, nil
+	}
+
+	result := make([]global.Message, 0, len(messages)+1)
+	result = append(result, messages[:last]...)
+	result = append(result, global.Message{Role: global.System, Content: knowledgeservice.BuildContext(passages)})
+	result = append(result, messages[last:]...)
+	return result, knowledgeservice.References(passages)
+}
+
+// retrievalQuery 去掉消息中附件的内容，只用用户输入的问题检索
+func retrievalQuery(content string) string {
+	if i := strings.Index(content, attachmentSeparator); i >= 0 {
+		content = content[:i]
+	}
+	return content
+}
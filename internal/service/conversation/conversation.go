@@ -65,6 +65,8 @@ func ExtractConversation(db *gorm.DB, id int64, userId int64, presetId string) *
 			conversation.AddMessageFromAssistant(helloMsg, "")
 
 			conversation.PresetID = &preset.Id
+			// 使用预设引用的知识库
+			conversation.KnowledgeBaseIDs = preset.KnowledgeBaseIDs
 		}
 
 		if err := db.Create(conversation).Error; err != nil {
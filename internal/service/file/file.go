@@ -0,0 +1,369 @@
+package fileservice
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/storage"
+	"txing-ai/internal/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFileNotFound = errors.New("文件不存在")
+	ErrFileExpired  = errors.New("文件已过期")
+)
+
+// 系统 MIME 表中可能没有的常见文档类型
+var extMimeTypes = map[string]string{
+	".md":   "text/markdown; charset=utf-8",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// SaveUpload 校验用户上传的文件并流式保存到默认存储，size 小于 0 表示长度未知
+// 超出用户的存储配额时拒绝，文件名会被清理，扩展名需要在用途允许的范围内，文件内容需要和扩展名相符，配置了扫描服务时同时扫描病毒
+// 没有通过校验时返回 *RejectedError，对象路径为 <用户ID>/<日期>/<时间戳>_<文件名>
+func SaveUpload(ctx context.Context, db *gorm.DB, stores *storage.Stores, userId int64, purpose Purpose, name string, r io.Reader, size int64, source string, sourceRef string) (*domain.File, error) {
+	name, err := checkName(purpose, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSize(size); err != nil {
+		return nil, err
+	}
+	if err := checkQuota(db, userId, size); err != nil {
+		return nil, err
+	}
+	storedName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), name)
+	key := stores.Key(strconv.FormatInt(userId, 10), time.Now().Format("2006-01-02"), storedName)
+	file := &domain.File{UserID: userId, Name: name, Source: source, SourceRef: sourceRef}
+	return put(ctx, db, stores.Default(), file, key, newLimitReader(r), size, true)
+}
+
+// SaveLocalUpload 校验用户上传的文件并保存到本地上传目录，用于需要读取本地文件的智能体
+// 校验规则和 SaveUpload 相同，没有通过校验时删除已经保存的文件并返回 *RejectedError
+// 返回文件路径和检查结果，之后通过 RegisterLocalUpload 登记
+func SaveLocalUpload(ctx context.Context, db *gorm.DB, userId int64, purpose Purpose, name string, r io.Reader) (string, *Inspection, error) {
+	name, err := checkName(purpose, name)
+	if err != nil {
+		return "", nil, err
+	}
+	body := r
+	if maxUploadSize > 0 {
+		body = io.LimitReader(r, maxUploadSize+1)
+	}
+	path, size, err := utils.SaveUploadedFile(io.NopCloser(body), name, userId, "", "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	inspection, err := inspectLocalFile(ctx, name, path, size)
+	if err == nil {
+		err = checkQuota(db, userId, size)
+	}
+	if err != nil {
+		if removeErr := os.Remove(path); removeErr != nil {
+			log.Warn("删除未通过校验的文件失败", zap.String("path", path), zap.Error(removeErr))
+		}
+		return "", nil, err
+	}
+	return path, inspection, nil
+}
+
+// inspectLocalFile 检查本地文件的大小和内容，并扫描病毒
+func inspectLocalFile(ctx context.Context, name string, path string, size int64) (*Inspection, error) {
+	if err := checkSize(size); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	mimeType, err := checkContent(name, head[:n])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	status, detail, err := scanFile(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return &Inspection{Name: name, MimeType: mimeType, Size: size, ScanStatus: status, ScanDetail: detail}, nil
+}
+
+// RegisterLocalUpload 登记 SaveLocalUpload 保存的文件，记录检查结果
+func RegisterLocalUpload(ctx context.Context, db *gorm.DB, stores *storage.Stores, userId int64, path string, inspection *Inspection, source string, sourceRef string) (*domain.File, error) {
+	file := &domain.File{
+		UserID:     userId,
+		Name:       inspection.Name,
+		MimeType:   inspection.MimeType,
+		Source:     source,
+		SourceRef:  sourceRef,
+		ScanStatus: inspection.ScanStatus,
+		ScanDetail: inspection.ScanDetail,
+	}
+	return saveLocalFile(ctx, db, stores, file, path)
+}
+
+// SaveLocalFile 登记本地生成的文件，path 为绝对路径或相对于工作目录的路径
+// 默认存储为本地存储且文件已经在存储目录中时直接登记，否则上传到默认存储，保留原来的文件名
+func SaveLocalFile(ctx context.Context, db *gorm.DB, stores *storage.Stores, userId int64, name string, path string, source string, sourceRef string) (*domain.File, error) {
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	file := &domain.File{
+		UserID:     userId,
+		Name:       name,
+		Source:     source,
+		SourceRef:  sourceRef,
+		ScanStatus: domain.FileScanSkipped,
+	}
+	return saveLocalFile(ctx, db, stores, file, path)
+}
+
+func saveLocalFile(ctx context.Context, db *gorm.DB, stores *storage.Stores, file *domain.File, path string) (*domain.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	store := stores.Default()
+	if local, ok := store.(*storage.LocalStore); ok {
+		if key, ok := local.KeyOf(path); ok {
+			if err := digest(file, f); err != nil {
+				return nil, err
+			}
+			file.Storage = local.Name()
+			file.StorageKey = key
+			if err := db.Create(file).Error; err != nil {
+				return nil, err
+			}
+			return file, nil
+		}
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	key := stores.Key(strconv.FormatInt(file.UserID, 10), time.Now().Format("2006-01-02"), filepath.Base(path))
+	return put(ctx, db, store, file, key, f, info.Size(), false)
+}
+
+// RegisterObject 登记客户端直传到存储中的对象，读取对象内容计算大小和摘要
+func RegisterObject(ctx context.Context, db *gorm.DB, stores *storage.Stores, storageName string, userId int64, name string, key string, source string, sourceRef string) (*domain.File, error) {
+	store, err := stores.Get(storageName)
+	if err != nil {
+		return nil, err
+	}
+	reader, _, err := store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if name == "" {
+		name = filepath.Base(key)
+	}
+	file := &domain.File{UserID: userId, Name: name, Source: source, SourceRef: sourceRef, ScanStatus: domain.FileScanSkipped}
+	if err := digest(file, reader); err != nil {
+		return nil, err
+	}
+	file.Storage = store.Name()
+	file.StorageKey = key
+	if err := db.Create(file).Error; err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// put 写入存储并登记，登记失败时删除已经写入的对象
+// check 为 true 时按文件头校验文件内容并扫描病毒，没有通过时删除已经写入的对象
+func put(ctx context.Context, db *gorm.DB, store storage.BlobStore, file *domain.File, key string, r io.Reader, size int64, check bool) (*domain.File, error) {
+	var job *scanJob
+	if check {
+		job = startScan(ctx)
+		r = job.tee(r)
+	}
+	reader := newDigestReader(r)
+	head, _ := reader.Peek(512)
+	contentType := detectMimeType(file.Name, head)
+	if check {
+		var err error
+		if contentType, err = checkContent(file.Name, head); err != nil {
+			job.wait(err)
+			return nil, err
+		}
+	}
+
+	_, err := store.Put(ctx, key, reader, size, contentType)
+	if check {
+		status, detail, scanErr := job.wait(err)
+		if err == nil && scanErr != nil {
+			deleteObject(store, key)
+			return nil, scanErr
+		}
+		file.ScanStatus, file.ScanDetail = status, detail
+	}
+	if err != nil {
+		// 超出大小限制等校验错误由读取方返回，存储可能会包装错误
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			return nil, rejected
+		}
+		return nil, err
+	}
+
+	file.MimeType = contentType
+	file.Size = reader.size
+	file.Sha256 = hex.EncodeToString(reader.digest.Sum(nil))
+	file.Storage = store.Name()
+	file.StorageKey = key
+	if err := db.Create(file).Error; err != nil {
+		deleteObject(store, key)
+		return nil, err
+	}
+	return file, nil
+}
+
+func deleteObject(store storage.BlobStore, key string) {
+	if err := store.Delete(context.Background(), key); err != nil {
+		log.Warn("删除未登记的文件失败", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// digest 读取文件内容，计算大小、SHA-256 摘要和 MIME 类型，已经有 MIME 类型时不再识别
+func digest(file *domain.File, r io.Reader) error {
+	reader := newDigestReader(r)
+	head, _ := reader.Peek(512)
+	if file.MimeType == "" {
+		file.MimeType = detectMimeType(file.Name, head)
+	}
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return err
+	}
+	file.Size = reader.size
+	file.Sha256 = hex.EncodeToString(reader.digest.Sum(nil))
+	return nil
+}
+
+// Open 读取文件内容，调用方负责关闭返回的 ReadCloser
+func Open(ctx context.Context, stores *storage.Stores, file *domain.File) (io.ReadCloser, *storage.ObjectInfo, error) {
+	store, err := stores.Get(file.Storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, info, err := store.Get(ctx, file.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrFileNotFound
+	}
+	return reader, info, err
+}
+
+// digestReader 读取的同时计算内容的大小和 SHA-256 摘要
+type digestReader struct {
+	*bufio.Reader
+	digest hash.Hash
+	size   int64
+}
+
+func newDigestReader(r io.Reader) *digestReader {
+	d := &digestReader{digest: sha256.New()}
+	d.Reader = bufio.NewReader(io.TeeReader(r, countWriter{d}))
+	return d
+}
+
+type countWriter struct {
+	d *digestReader
+}
+
+func (w countWriter) Write(p []byte) (int, error) {
+	w.d.size += int64(len(p))
+	return w.d.digest.Write(p)
+}
+
+// detectMimeType 优先按扩展名识别文件类型，识别不了时按文件内容识别
+func detectMimeType(name string, head []byte) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if mimeType, ok := extMimeTypes[ext]; ok {
+		return mimeType
+	}
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		return mimeType
+	}
+	return http.DetectContentType(head)
+}
+
+// GetUserFile 获取用户的文件，文件不属于该用户时同样返回 ErrFileNotFound
+func GetUserFile(db *gorm.DB, userId int64, id int64) (*domain.File, error) {
+	var file domain.File
+	err := db.Where("id = ? AND user_id = ?", id, userId).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if file.ExpireTime != nil && file.ExpireTime.Before(time.Now()) {
+		return nil, ErrFileExpired
+	}
+	return &file, nil
+}
+
+// FindUserFileByStoredName 按保存后的文件名查找用户最近的文件，用于兼容按文件名下载的旧链接
+func FindUserFileByStoredName(db *gorm.DB, userId int64, storedName string) (*domain.File, error) {
+	var file domain.File
+	pattern := "%/" + escapeLike(storedName)
+	err := db.Where("user_id = ? AND storage_key LIKE ?", userId, pattern).Order("id DESC").First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if file.ExpireTime != nil && file.ExpireTime.Before(time.Now()) {
+		return nil, ErrFileExpired
+	}
+	return &file, nil
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// DownloadURL 按文件ID下载的地址
+func DownloadURL(id int64) string {
+	return fmt.Sprintf("/api/file/%d/download", id)
+}
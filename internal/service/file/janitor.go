@@ -0,0 +1,609 @@
+package fileservice
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/storage"
+	"txing-ai/internal/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 默认的清理间隔
+	defaultJanitorInterval = time.Hour
+	// 默认每批处理的文件数量
+	defaultJanitorBatchSize = 500
+)
+
+// errStopBatch 提前结束分批处理
+var errStopBatch = errors.New("stop batch")
+
+// JanitorReport 一次清理的结果
+type JanitorReport struct {
+	// 过期的文件
+	Expired int
+	// 超过来源保留天数的文件
+	Retention int
+	// 没有被引用的文件
+	Orphans int
+	// 超出用户存储配额删除的文件
+	OverQuota int
+	// 上传目录中没有登记的临时文件
+	Scratch int
+	// 客户端直传到 COS 但没有被引用的对象
+	COSObjects int
+	// 释放的空间 单位字节
+	FreedBytes int64
+}
+
+// Janitor 按保留策略清理文件，包括过期和超过保留天数的文件、没有被引用的文件、超出配额的文件、
+// 上传目录中的临时文件以及客户端直传到 COS 的无用对象
+// 多副本部署时删除是幂等的，也可以只在一个副本上开启
+type Janitor struct {
+	db        *gorm.DB
+	stores    *storage.Stores
+	cosClient *utils.COSClient
+	config    global.RetentionConfig
+	interval  time.Duration
+	batchSize int
+}
+
+// NewJanitor 创建清理任务，cosClient 为 nil 时不清理 COS 中的直传对象
+func NewJanitor(db *gorm.DB, stores *storage.Stores, cosClient *utils.COSClient, config *global.RetentionConfig) *Janitor {
+	j := &Janitor{
+		db:        db,
+		stores:    stores,
+		cosClient: cosClient,
+		interval:  defaultJanitorInterval,
+		batchSize: defaultJanitorBatchSize,
+	}
+	if config != nil {
+		j.config = *config
+		if config.Interval > 0 {
+			j.interval = config.Interval * time.Second
+		}
+		if config.BatchSize > 0 {
+			j.batchSize = config.BatchSize
+		}
+	}
+	return j
+}
+
+// Start 在后台定期清理，启动时立即执行一次，ctx 取消后退出
+func (j *Janitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			report, err := j.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Error("file janitor failed", zap.Error(err))
+			}
+			if report != nil {
+				log.Info("file janitor finished", zap.Any("report", report))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce 执行一次清理，某一步失败时继续执行后面的步骤，返回第一个错误
+func (j *Janitor) RunOnce(ctx context.Context) (*JanitorReport, error) {
+	report := &JanitorReport{}
+	now := time.Now()
+	var errs []error
+	step := func(name string, err error) {
+		if err != nil {
+			log.Warn("file janitor step failed", zap.String("step", name), zap.Error(err))
+			errs = append(errs, err)
+		}
+	}
+
+	step("expired", j.deleteWhere(ctx, &report.Expired, &report.FreedBytes, "expire_time < ?", now))
+	for source, days := range map[string]int{
+		domain.FileSourceUpload: j.config.UploadDays,
+		domain.FileSourceAgent:  j.config.AgentDays,
+		domain.FileSourceTool:   j.config.ToolDays,
+	} {
+		if days > 0 {
+			step("retention", j.deleteWhere(ctx, &report.Retention, &report.FreedBytes,
+				"source = ? AND create_time < ?", source, daysAgo(now, days)))
+		}
+	}
+
+	if j.config.OrphanDays > 0 || j.config.UserQuota > 0 {
+		refs, err := j.collectReferences(ctx)
+		step("references", err)
+		if err == nil {
+			if j.config.OrphanDays > 0 {
+				step("orphans", j.deleteOrphans(ctx, refs, daysAgo(now, j.config.OrphanDays), report))
+				if j.cosClient != nil {
+					step("cos", j.sweepCOS(ctx, refs, daysAgo(now, j.config.OrphanDays), report))
+				}
+			}
+			if j.config.UserQuota > 0 {
+				step("quota", j.trimOverQuota(ctx, refs, report))
+			}
+		}
+	}
+
+	if j.config.ScratchDays > 0 {
+		step("scratch", j.sweepLocalScratch(ctx, daysAgo(now, j.config.ScratchDays), report))
+	}
+
+	if len(errs) > 0 {
+		return report, errs[0]
+	}
+	return report, nil
+}
+
+func daysAgo(now time.Time, days int) time.Time {
+	return now.AddDate(0, 0, -days)
+}
+
+// eachBatch 按ID从小到大分批读取符合条件的文件，fn 返回 errStopBatch 时结束
+func (j *Janitor) eachBatch(ctx context.Context, query *gorm.DB, fn func(files []domain.File) error) error {
+	query = query.Session(&gorm.Session{})
+	var lastId int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var files []domain.File
+		if err := query.Where("id > ?", lastId).Order("id").Limit(j.batchSize).Find(&files).Error; err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		lastId = files[len(files)-1].Id
+		if err := fn(files); err != nil {
+			if errors.Is(err, errStopBatch) {
+				return nil
+			}
+			return err
+		}
+		if len(files) < j.batchSize {
+			return nil
+		}
+	}
+}
+
+// deleteWhere 删除符合条件的文件
+func (j *Janitor) deleteWhere(ctx context.Context, count *int, freed *int64, query string, args ...any) error {
+	return j.eachBatch(ctx, j.db.WithContext(ctx).Where(query, args...), func(files []domain.File) error {
+		n, size, err := j.deleteFiles(ctx, files)
+		*count += n
+		*freed += size
+		return err
+	})
+}
+
+// deleteOrphans 删除创建时间早于 cutoff 且没有被引用的用户上传文件
+// 智能体的运行记录不保存到数据库，智能体附件和工具生成的文件永远不会被引用，只按 agent_days、tool_days 清理
+func (j *Janitor) deleteOrphans(ctx context.Context, refs *references, cutoff time.Time, report *JanitorReport) error {
+	query := j.db.WithContext(ctx).Where("source = ? AND create_time < ?", domain.FileSourceUpload, cutoff)
+	return j.eachBatch(ctx, query, func(files []domain.File) error {
+		var orphans []domain.File
+		for _, file := range files {
+			if !refs.has(&file) {
+				orphans = append(orphans, file)
+			}
+		}
+		n, size, err := j.deleteFiles(ctx, orphans)
+		report.Orphans += n
+		report.FreedBytes += size
+		return err
+	})
+}
+
+// trimOverQuota 超出存储配额的用户按时间从旧到新删除没有被引用的文件，被引用的文件不删除
+func (j *Janitor) trimOverQuota(ctx context.Context, refs *references, report *JanitorReport) error {
+	var totals []struct {
+		UserID int64
+		Total  int64
+	}
+	err := j.db.WithContext(ctx).Model(&domain.File{}).
+		Select("user_id, SUM(size) AS total").
+		Group("user_id").
+		Having("SUM(size) > ?", j.config.UserQuota).
+		Scan(&totals).Error
+	if err != nil {
+		return err
+	}
+
+	for _, total := range totals {
+		over := total.Total - j.config.UserQuota
+		err := j.eachBatch(ctx, j.db.WithContext(ctx).Where("user_id = ?", total.UserID), func(files []domain.File) error {
+			var victims []domain.File
+			var planned int64
+			for _, file := range files {
+				if planned >= over {
+					break
+				}
+				if !refs.has(&file) {
+					victims = append(victims, file)
+					planned += file.Size
+				}
+			}
+			n, size, err := j.deleteFiles(ctx, victims)
+			report.OverQuota += n
+			report.FreedBytes += size
+			over -= size
+			if err != nil {
+				return err
+			}
+			if over <= 0 {
+				return errStopBatch
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if over > 0 {
+			log.Warn("user storage still over quota", zap.Int64("userId", total.UserID), zap.Int64("over", over))
+		}
+	}
+	return nil
+}
+
+// deleteFiles 删除文件对象后删除登记记录，返回删除的数量和大小
+// COS 中的对象通过 DeleteObjects 批量删除，对象删除失败的文件保留登记记录，下次再删除
+func (j *Janitor) deleteFiles(ctx context.Context, files []domain.File) (int, int64, error) {
+	if len(files) == 0 {
+		return 0, 0, nil
+	}
+	var firstErr error
+	var ids []int64
+	var freed int64
+	var cosFiles []domain.File
+	for _, file := range files {
+		if file.Storage == storage.BackendCOS && j.cosClient != nil {
+			cosFiles = append(cosFiles, file)
+			continue
+		}
+		store, err := j.stores.Get(file.Storage)
+		if err == nil {
+			err = store.Delete(ctx, file.StorageKey)
+		}
+		if err != nil {
+			log.Warn("delete file object failed", zap.Int64("fileId", file.Id), zap.String("key", file.StorageKey), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		ids = append(ids, file.Id)
+		freed += file.Size
+	}
+	if len(cosFiles) > 0 {
+		keys := make([]string, 0, len(cosFiles))
+		for _, file := range cosFiles {
+			keys = append(keys, file.StorageKey)
+		}
+		if err := j.cosClient.DeleteObjects(keys); err != nil {
+			log.Warn("delete cos objects failed", zap.Int("count", len(keys)), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			for _, file := range cosFiles {
+				ids = append(ids, file.Id)
+				freed += file.Size
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return 0, 0, firstErr
+	}
+	// 对象已经删除，登记记录直接物理删除
+	if err := j.db.WithContext(ctx).Unscoped().Delete(&domain.File{}, ids).Error; err != nil {
+		return 0, 0, err
+	}
+	return len(ids), freed, firstErr
+}
+
+// sweepLocalScratch 清理本地存储目录中没有登记的文件，包括转换失败留下的 HTML、下载的图片，以及文件登记之前保存的旧文件
+func (j *Janitor) sweepLocalScratch(ctx context.Context, cutoff time.Time, report *JanitorReport) error {
+	store, err := j.stores.Get(storage.BackendLocal)
+	if err != nil {
+		return err
+	}
+	local, ok := store.(*storage.LocalStore)
+	if !ok {
+		return nil
+	}
+	n, size, err := sweepScratch(ctx, local.Dir(), cutoff, j.batchSize, func(keys []string) (map[string]bool, error) {
+		return j.registeredKeys(ctx, storage.BackendLocal, keys)
+	})
+	report.Scratch += n
+	report.FreedBytes += size
+	return err
+}
+
+// registeredKeys 已经登记的对象路径，包括已经软删除的记录
+func (j *Janitor) registeredKeys(ctx context.Context, storageName string, keys []string) (map[string]bool, error) {
+	var found []string
+	err := j.db.WithContext(ctx).Unscoped().Model(&domain.File{}).
+		Where("storage = ? AND storage_key IN ?", storageName, keys).
+		Pluck("storage_key", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[string]bool, len(found))
+	for _, key := range found {
+		registered[key] = true
+	}
+	return registered, nil
+}
+
+// sweepCOS 清理客户端直传到 COS 上传前缀下、修改时间早于 cutoff 且没有被引用也没有登记的对象
+func (j *Janitor) sweepCOS(ctx context.Context, refs *references, cutoff time.Time, report *JanitorReport) error {
+	for _, purpose := range utils.UploadPurposes() {
+		marker := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			objects, next, err := j.cosClient.ListObjects(ctx, string(purpose)+"/", marker, j.batchSize)
+			if err != nil {
+				return err
+			}
+			candidates := map[string]int64{}
+			keys := make([]string, 0, len(objects))
+			for _, object := range objects {
+				if object.LastModified.IsZero() || !object.LastModified.Before(cutoff) || refs.keys[object.Key] {
+					continue
+				}
+				candidates[object.Key] = object.Size
+				keys = append(keys, object.Key)
+			}
+			if len(keys) > 0 {
+				registered, err := j.registeredKeys(ctx, storage.BackendCOS, keys)
+				if err != nil {
+					return err
+				}
+				keys = keys[:0]
+				var size int64
+				for key, objectSize := range candidates {
+					if !registered[key] {
+						keys = append(keys, key)
+						size += objectSize
+					}
+				}
+				if len(keys) > 0 {
+					if err := j.cosClient.DeleteObjects(keys); err != nil {
+						return err
+					}
+					report.COSObjects += len(keys)
+					report.FreedBytes += size
+				}
+			}
+			if next == "" {
+				break
+			}
+			marker = next
+		}
+	}
+	return nil
+}
+
+// 会话消息和预设中的文件链接
+var (
+	fileIdLinkPattern   = regexp.MustCompile(`/api/file/(\d+)/download`)
+	fileNameLinkPattern = regexp.MustCompile(`/api/file/download\?filePath=([^&\s"'()<>\\]+)`)
+)
+
+// references 被引用的文件
+type references struct {
+	// 按文件ID下载的链接
+	ids map[int64]bool
+	// 兼容旧版按文件名下载的链接，保存后的文件名
+	names map[string]bool
+	// 直接保存对象路径的头像等
+	keys map[string]bool
+}
+
+func newReferences() *references {
+	return &references{ids: map[int64]bool{}, names: map[string]bool{}, keys: map[string]bool{}}
+}
+
+// scan 查找文本中的文件链接
+func (r *references) scan(text string) {
+	for _, match := range fileIdLinkPattern.FindAllStringSubmatch(text, -1) {
+		if id, err := strconv.ParseInt(match[1], 10, 64); err == nil {
+			r.ids[id] = true
+		}
+	}
+	for _, match := range fileNameLinkPattern.FindAllStringSubmatch(text, -1) {
+		name, err := url.QueryUnescape(match[1])
+		if err != nil {
+			name = match[1]
+		}
+		r.names[filepath.Base(name)] = true
+	}
+}
+
+// addKey 记录直接引用的对象路径，外部地址忽略
+func (r *references) addKey(key string) {
+	if utils.IsObjectKey(key) {
+		r.keys[key] = true
+	}
+}
+
+// has 文件是否被引用
+func (r *references) has(file *domain.File) bool {
+	return r.ids[file.Id] || r.names[path.Base(file.StorageKey)] || r.keys[file.StorageKey]
+}
+
+// collectReferences 收集会话消息、预设、知识库文档和用户头像中引用的文件，已经删除的会话、预设和文档不算引用
+func (j *Janitor) collectReferences(ctx context.Context) (*references, error) {
+	refs := newReferences()
+	db := j.db.WithContext(ctx)
+
+	var conversations []domain.Conversation
+	err := db.Select("id", "message").FindInBatches(&conversations, j.batchSize, func(tx *gorm.DB, batch int) error {
+		for _, conversation := range conversations {
+			refs.scan(conversation.Message)
+		}
+		return ctx.Err()
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var presets []domain.Preset
+	err = db.Select("id", "avatar", "description", "context").FindInBatches(&presets, j.batchSize, func(tx *gorm.DB, batch int) error {
+		for _, preset := range presets {
+			refs.addKey(preset.Avatar)
+			refs.scan(preset.Description)
+			refs.scan(preset.Context)
+		}
+		return ctx.Err()
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var fileIds []int64
+	if err := db.Model(&domain.KnowledgeDocument{}).Distinct().Pluck("file_id", &fileIds).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range fileIds {
+		refs.ids[id] = true
+	}
+
+	var users []domain.User
+	err = db.Select("id", "avatar").Where("avatar <> ''").FindInBatches(&users, j.batchSize, func(tx *gorm.DB, batch int) error {
+		for _, user := range users {
+			refs.addKey(user.Avatar)
+		}
+		return ctx.Err()
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// sweepScratch 删除 root 下修改时间早于 cutoff 且没有登记的文件，然后删除空目录
+// registered 返回一批对象路径中已经登记的部分，对象路径为相对于 root 的路径
+func sweepScratch(ctx context.Context, root string, cutoff time.Time, batchSize int, registered func(keys []string) (map[string]bool, error)) (int, int64, error) {
+	type candidate struct {
+		path string
+		size int64
+	}
+	var removed int
+	var freed int64
+	// 删除过文件的目录，清空后即使修改时间较新也可以删除
+	touched := map[string]bool{}
+	batch := map[string]candidate{}
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		keys := make([]string, 0, len(batch))
+		for key := range batch {
+			keys = append(keys, key)
+		}
+		found, err := registered(keys)
+		if err != nil {
+			return err
+		}
+		for key, c := range batch {
+			if found[key] {
+				continue
+			}
+			if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+				log.Warn("remove scratch file failed", zap.String("path", c.path), zap.Error(err))
+				continue
+			}
+			removed++
+			freed += c.size
+			touched[filepath.Dir(c.path)] = true
+		}
+		clear(batch)
+		return nil
+	}
+
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		batch[filepath.ToSlash(rel)] = candidate{path: p, size: info.Size()}
+		if len(batch) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return removed, freed, err
+	}
+	removeEmptyDirs(root, cutoff, touched, true)
+	return removed, freed, nil
+}
+
+// removeEmptyDirs 删除空目录，只删除修改时间早于 cutoff 或者刚被清空的目录，避免和正在创建的日期目录冲突
+func removeEmptyDirs(dir string, cutoff time.Time, touched map[string]bool, isRoot bool) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	empty := true
+	for _, entry := range entries {
+		if !entry.IsDir() || !removeEmptyDirs(filepath.Join(dir, entry.Name()), cutoff, touched, false) {
+			empty = false
+		}
+	}
+	if isRoot || !empty {
+		return false
+	}
+	info, err := os.Stat(dir)
+	if err != nil || (!touched[dir] && !info.ModTime().Before(cutoff)) {
+		return false
+	}
+	if err := os.Remove(dir); err != nil {
+		return false
+	}
+	touched[filepath.Dir(dir)] = true
+	return true
+}
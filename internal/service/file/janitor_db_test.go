@@ -0,0 +1,140 @@
+//go:build cgo
+
+// SQLite 驱动依赖 cgo，CGO_ENABLED=0 时跳过需要数据库的测试
+
+package fileservice
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"testing"
+	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global"
+	"txing-ai/internal/storage"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newJanitorDB 创建内存中的 SQLite 数据库
+func newJanitorDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&domain.File{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// addFile 登记一个文件并写入存储，创建时间为 age 之前
+func addFile(t *testing.T, db *gorm.DB, store *memStore, userId int64, source, key string, size int64, age time.Duration) *domain.File {
+	t.Helper()
+	if _, err := store.Put(context.Background(), key, bytes.NewReader(make([]byte, size)), size, ""); err != nil {
+		t.Fatal(err)
+	}
+	file := &domain.File{UserID: userId, Name: path.Base(key), Size: size, Storage: store.Name(), StorageKey: key, Source: source}
+	if err := db.Create(file).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 创建时间由钩子设置为当前时间，这里再改为测试需要的时间
+	file.CreateTime = time.Now().Add(-age)
+	if err := db.Model(file).UpdateColumn("create_time", file.CreateTime).Error; err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func remainingFiles(t *testing.T, db *gorm.DB) map[int64]bool {
+	t.Helper()
+	var ids []int64
+	if err := db.Unscoped().Model(&domain.File{}).Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	remaining := map[int64]bool{}
+	for _, id := range ids {
+		remaining[id] = true
+	}
+	return remaining
+}
+
+func TestDeleteOrphans(t *testing.T) {
+	db := newJanitorDB(t)
+	store := newMemStore()
+	day := 24 * time.Hour
+
+	orphan := addFile(t, db, store, 1, domain.FileSourceUpload, "1/old.pdf", 10, 10*day)
+	referenced := addFile(t, db, store, 1, domain.FileSourceUpload, "1/used.pdf", 10, 10*day)
+	recent := addFile(t, db, store, 1, domain.FileSourceUpload, "1/new.pdf", 10, day)
+	// 智能体附件和工具生成的文件不会被会话引用，不能当作孤儿删除
+	agentFile := addFile(t, db, store, 1, domain.FileSourceAgent, "1/resume.pdf", 10, 10*day)
+	toolFile := addFile(t, db, store, 1, domain.FileSourceTool, "1/guide.pdf", 10, 10*day)
+
+	refs := newReferences()
+	refs.ids[referenced.Id] = true
+
+	j := NewJanitor(db, storage.NewStores(store), nil, &global.RetentionConfig{BatchSize: 2})
+	report := &JanitorReport{}
+	if err := j.deleteOrphans(context.Background(), refs, time.Now().Add(-7*day), report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Orphans != 1 || report.FreedBytes != 10 {
+		t.Errorf("report = %+v", report)
+	}
+	remaining := remainingFiles(t, db)
+	if remaining[orphan.Id] || store.len() != 4 {
+		t.Errorf("orphan should be removed, remaining = %v, objects = %d", remaining, store.len())
+	}
+	for _, file := range []*domain.File{referenced, recent, agentFile, toolFile} {
+		if !remaining[file.Id] {
+			t.Errorf("%s should be kept", file.StorageKey)
+		}
+	}
+}
+
+func TestTrimOverQuota(t *testing.T) {
+	db := newJanitorDB(t)
+	store := newMemStore()
+	day := 24 * time.Hour
+
+	// 用户 1 共 100 字节，超出 40 字节，按时间从旧到新删除没有被引用的文件
+	oldest := addFile(t, db, store, 1, domain.FileSourceUpload, "1/a.pdf", 30, 5*day)
+	referenced := addFile(t, db, store, 1, domain.FileSourceUpload, "1/b.pdf", 30, 4*day)
+	older := addFile(t, db, store, 1, domain.FileSourceTool, "1/c.pdf", 20, 3*day)
+	newest := addFile(t, db, store, 1, domain.FileSourceUpload, "1/d.pdf", 20, day)
+	// 用户 2 没有超出配额
+	other := addFile(t, db, store, 2, domain.FileSourceUpload, "2/a.pdf", 50, 5*day)
+
+	refs := newReferences()
+	refs.ids[referenced.Id] = true
+
+	j := NewJanitor(db, storage.NewStores(store), nil, &global.RetentionConfig{UserQuota: 60, BatchSize: 2})
+	report := &JanitorReport{}
+	if err := j.trimOverQuota(context.Background(), refs, report); err != nil {
+		t.Fatal(err)
+	}
+	if report.OverQuota != 2 || report.FreedBytes != 50 {
+		t.Errorf("report = %+v", report)
+	}
+	remaining := remainingFiles(t, db)
+	for _, file := range []*domain.File{oldest, older} {
+		if remaining[file.Id] {
+			t.Errorf("%s should be removed", file.StorageKey)
+		}
+	}
+	for _, file := range []*domain.File{referenced, newest, other} {
+		if !remaining[file.Id] {
+			t.Errorf("%s should be kept", file.StorageKey)
+		}
+	}
+}
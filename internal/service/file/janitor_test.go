@@ -0,0 +1,110 @@
+package fileservice
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"txing-ai/internal/domain"
+)
+
+func TestReferences(t *testing.T) {
+	refs := newReferences()
+	// 会话消息保存为 JSON，& 等字符会被转义
+	refs.scan(`[{"role":"assistant","content":"[报告.pdf](/api/file/42/download) 和 [旧文件](/api/file/download?filePath=1700000000_%E7%AE%80%E5%8E%86.pdf&x=1)"}]`)
+	refs.scan(`![图片](https://example.com/api/file/7/download)`)
+	refs.addKey("avatar/3/a.png")
+	refs.addKey("https://example.com/a.png")
+
+	tests := []struct {
+		name string
+		file domain.File
+		want bool
+	}{
+		{name: "文件ID", file: domain.File{BaseModel: domain.BaseModel{Id: 42}, StorageKey: "1/2024-01-01/x.pdf"}, want: true},
+		{name: "完整地址", file: domain.File{BaseModel: domain.BaseModel{Id: 7}, StorageKey: "1/2024-01-01/y.png"}, want: true},
+		{name: "旧版链接", file: domain.File{BaseModel: domain.BaseModel{Id: 1}, StorageKey: "files/1/2024-01-01/1700000000_简历.pdf"}, want: true},
+		{name: "头像", file: domain.File{BaseModel: domain.BaseModel{Id: 2}, StorageKey: "avatar/3/a.png"}, want: true},
+		{name: "没有引用", file: domain.File{BaseModel: domain.BaseModel{Id: 4}, StorageKey: "1/2024-01-01/z.pdf"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := refs.has(&tt.file); got != tt.want {
+			t.Errorf("%s: has = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if len(refs.keys) != 1 {
+		t.Errorf("external url should be ignored, keys = %v", refs.keys)
+	}
+}
+
+func TestSweepScratch(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	cutoff := time.Now().Add(-24 * time.Hour)
+	write := func(name string, modTime time.Time) string {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	registeredFile := write("1/2024-01-01/1_report.pdf", old)
+	scratchFile := write("1/2024-01-01/2_page.html", old)
+	onlyScratch := write("2/2024-01-01/3_image.png", old)
+	newFile := write("1/2024-01-02/4_new.html", time.Now())
+	// 正在使用的空日期目录
+	todayDir := filepath.Join(root, "3", time.Now().Format("2006-01-02"))
+	if err := os.MkdirAll(todayDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var queried []string
+	removed, freed, err := sweepScratch(context.Background(), root, cutoff, 1, func(keys []string) (map[string]bool, error) {
+		queried = append(queried, keys...)
+		return map[string]bool{"1/2024-01-01/1_report.pdf": true}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 || freed != 10 {
+		t.Errorf("removed = %d, freed = %d, want 2, 10", removed, freed)
+	}
+	if len(queried) != 3 {
+		t.Errorf("queried = %v, want only old files", queried)
+	}
+	for _, p := range []string{registeredFile, newFile, todayDir} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s should be kept: %v", p, err)
+		}
+	}
+	for _, p := range []string{scratchFile, onlyScratch, filepath.Dir(onlyScratch), filepath.Join(root, "2")} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed: %v", p, err)
+		}
+	}
+
+	// 查询登记记录失败时不删除
+	write("1/2024-01-01/5_page.html", old)
+	if _, _, err := sweepScratch(context.Background(), root, cutoff, 10, func(keys []string) (map[string]bool, error) {
+		return nil, errors.New("db error")
+	}); err == nil {
+		t.Error("registered error should be returned")
+	}
+	if _, err := os.Stat(filepath.Join(root, "1", "2024-01-01", "5_page.html")); err != nil {
+		t.Errorf("file should be kept when registered fails: %v", err)
+	}
+
+	// 目录不存在
+	if _, _, err := sweepScratch(context.Background(), filepath.Join(root, "missing"), cutoff, 10, nil); err != nil {
+		t.Errorf("missing root: %v", err)
+	}
+}
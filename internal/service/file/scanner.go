@@ -0,0 +1,169 @@
+package fileservice
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ScanBackendNone   = "none"
+	ScanBackendClamAV = "clamav"
+
+	// 默认的扫描超时
+	defaultScanTimeout = 60 * time.Second
+	// INSTREAM 每次发送的数据块大小
+	clamdChunkSize = 32 << 10
+)
+
+// ScanResult 病毒扫描结果
+type ScanResult struct {
+	Infected bool
+	// 发现的病毒名称
+	Signature string
+}
+
+// Scanner 病毒扫描服务
+type Scanner interface {
+	// Scan 读取全部内容并扫描，扫描服务出错时返回 error
+	Scan(ctx context.Context, r io.Reader) (*ScanResult, error)
+}
+
+var (
+	scanner         Scanner
+	scanFailOpen    bool
+	scannerInitOnce sync.Once
+)
+
+// InitScanner 根据配置初始化上传文件的病毒扫描，没有配置时不扫描，只在第一次调用时生效
+func InitScanner(config *global.ScanConfig) {
+	scannerInitOnce.Do(func() {
+		if config == nil || config.Backend == "" || config.Backend == ScanBackendNone {
+			return
+		}
+		switch config.Backend {
+		case ScanBackendClamAV:
+			s, err := NewClamdScanner(config.Address, config.Timeout*time.Second)
+			if err != nil {
+				log.Error("init clamav scanner failed", zap.Error(err))
+				return
+			}
+			scanner = s
+			scanFailOpen = config.FailOpen
+			log.Info("upload scanner", zap.String("backend", config.Backend), zap.String("address", config.Address))
+		default:
+			log.Error("unknown scan backend", zap.String("backend", config.Backend))
+		}
+	})
+}
+
+// ClamdScanner 通过 clamd 的 INSTREAM 命令扫描，不需要和 clamd 共享文件系统
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner 创建 clamd 客户端，address 形如 tcp://127.0.0.1:3310 或 unix:///var/run/clamav/clamd.ctl
+// 没有协议时按 tcp 处理
+func NewClamdScanner(address string, timeout time.Duration) (*ClamdScanner, error) {
+	if timeout <= 0 {
+		timeout = defaultScanTimeout
+	}
+	network, addr := "tcp", address
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("clamd 地址不合法: %s", address)
+		}
+		switch u.Scheme {
+		case "tcp":
+			addr = u.Host
+		case "unix":
+			network, addr = "unix", u.Path
+		default:
+			return nil, fmt.Errorf("不支持的 clamd 地址: %s", address)
+		}
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("clamd 地址不合法: %s", address)
+	}
+	return &ClamdScanner{network: network, address: addr, timeout: timeout}, nil
+}
+
+func (s *ClamdScanner) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("连接 clamd 失败: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	// z 前缀的命令以 \0 结尾，数据按 <4 字节长度><内容> 分块发送，长度为 0 表示结束
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, readErr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// clamd 超出 StreamMaxLength 时会提前返回结果并关闭连接
+				return s.readResult(conn, err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return s.readResult(conn, err)
+	}
+	return s.readResult(conn, nil)
+}
+
+// readResult 解析 clamd 的响应，例如 stream: OK、stream: Eicar-Signature FOUND
+func (s *ClamdScanner) readResult(conn net.Conn, writeErr error) (*ScanResult, error) {
+	// 响应同样以 \0 结尾
+	reply, err := bufio.NewReader(io.LimitReader(conn, 4096)).ReadBytes(0)
+	if len(reply) == 0 {
+		if writeErr != nil {
+			return nil, writeErr
+		}
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("读取 clamd 响应失败: %w", err)
+	}
+	line := strings.TrimSpace(string(bytes.TrimRight(reply, "\x00")))
+	line = strings.TrimPrefix(line, "stream: ")
+	switch {
+	case line == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(line, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(line, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd 扫描失败: %s", line)
+	}
+}
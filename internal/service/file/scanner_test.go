@@ -0,0 +1,143 @@
+package fileservice
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd 实现 clamd 的 INSTREAM 命令，内容包含 EICAR 时报告发现病毒
+// maxLength 大于 0 时模拟 StreamMaxLength，超出后提前返回错误并关闭连接
+func fakeClamd(t *testing.T, maxLength int) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn, maxLength)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveClamd(conn net.Conn, maxLength int) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	command, err := reader.ReadString(0)
+	if err != nil || command != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+	var data bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&data, reader, int64(size)); err != nil {
+			return
+		}
+		if maxLength > 0 && data.Len() > maxLength {
+			conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			return
+		}
+	}
+	if bytes.Contains(data.Bytes(), []byte("EICAR")) {
+		conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		return
+	}
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClamdScanner(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewClamdScanner("tcp://"+fakeClamd(t, 0), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Scan(ctx, strings.NewReader("hello"))
+	if err != nil || result.Infected {
+		t.Errorf("clean: result = %+v, err = %v", result, err)
+	}
+	// 超过一个数据块的内容
+	infected := append(bytes.Repeat([]byte("a"), 3*clamdChunkSize), []byte("X5O!P%@AP EICAR")...)
+	result, err = s.Scan(ctx, bytes.NewReader(infected))
+	if err != nil || !result.Infected || result.Signature != "Eicar-Test-Signature" {
+		t.Errorf("infected: result = %+v, err = %v", result, err)
+	}
+	result, err = s.Scan(ctx, strings.NewReader(""))
+	if err != nil || result.Infected {
+		t.Errorf("empty: result = %+v, err = %v", result, err)
+	}
+}
+
+func TestClamdScanner_errors(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := NewClamdScanner(fakeClamd(t, 1024), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Scan(ctx, bytes.NewReader(make([]byte, 10*clamdChunkSize))); err == nil {
+		t.Error("size limit exceeded should fail")
+	}
+
+	// 关闭的端口
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	s, _ = NewClamdScanner(addr, time.Second)
+	if _, err := s.Scan(ctx, strings.NewReader("hello")); err == nil {
+		t.Error("unreachable clamd should fail")
+	}
+
+	for _, address := range []string{"", "http://127.0.0.1:3310", "unix://"} {
+		if _, err := NewClamdScanner(address, time.Second); err == nil {
+			t.Errorf("NewClamdScanner(%q) should fail", address)
+		}
+	}
+	if s, err := NewClamdScanner("unix:///var/run/clamav/clamd.ctl", 0); err != nil || s.network != "unix" || s.address != "/var/run/clamav/clamd.ctl" {
+		t.Errorf("unix address: %+v, %v", s, err)
+	}
+}
+
+// 扫描服务提前返回时写入方不会阻塞
+func TestScanJob_earlyReturn(t *testing.T) {
+	withUploadConfig(t, 0, &fakeScanner{}, false)
+	scanner = scannerFunc(func(ctx context.Context, r io.Reader) (*ScanResult, error) {
+		return nil, io.ErrUnexpectedEOF
+	})
+	job := startScan(context.Background())
+	if _, err := io.Copy(io.Discard, job.tee(bytes.NewReader(make([]byte, 1<<20)))); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := job.wait(nil); !errors.Is(err, ErrFileScanFailed) {
+		t.Errorf("err = %v, want %v", err, ErrFileScanFailed)
+	}
+}
+
+type scannerFunc func(ctx context.Context, r io.Reader) (*ScanResult, error)
+
+func (f scannerFunc) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	return f(ctx, r)
+}
@@ -0,0 +1,68 @@
+package fileservice
+
+import (
+	"txing-ai/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// Usage 用户的存储空间使用情况
+type Usage struct {
+	UserID    int64
+	FileCount int64
+	TotalSize int64
+	// 各来源文件的总大小
+	UploadSize int64
+	AgentSize  int64
+	ToolSize   int64
+}
+
+// UserQuota 每个用户的文件总大小上限，0 表示不限制
+func UserQuota() int64 {
+	return max(userQuota, 0)
+}
+
+// GetUsages 统计用户的存储空间使用情况，没有文件的用户不在返回结果中
+func GetUsages(db *gorm.DB, userIds []int64) (map[int64]*Usage, error) {
+	var usages []*Usage
+	err := db.Model(&domain.File{}).
+		Select(`user_id, COUNT(*) AS file_count, COALESCE(SUM(size), 0) AS total_size,
+			COALESCE(SUM(CASE WHEN source = ? THEN size ELSE 0 END), 0) AS upload_size,
+			COALESCE(SUM(CASE WHEN source = ? THEN size ELSE 0 END), 0) AS agent_size,
+			COALESCE(SUM(CASE WHEN source = ? THEN size ELSE 0 END), 0) AS tool_size`,
+			domain.FileSourceUpload, domain.FileSourceAgent, domain.FileSourceTool).
+		Where("user_id IN ?", userIds).
+		Group("user_id").
+		Scan(&usages).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int64]*Usage, len(usages))
+	for _, usage := range usages {
+		result[usage.UserID] = usage
+	}
+	return result, nil
+}
+
+// usedSize 用户所有文件的总大小
+func usedSize(db *gorm.DB, userId int64) (int64, error) {
+	var used int64
+	err := db.Model(&domain.File{}).Where("user_id = ?", userId).Select("COALESCE(SUM(size), 0)").Scan(&used).Error
+	return used, err
+}
+
+// checkQuota 检查保存 size 字节后是否超出用户的存储配额，size 小于 0 表示长度未知，按 0 计算
+// 超出的部分由清理任务按时间从旧到新删除没有被引用的文件
+func checkQuota(db *gorm.DB, userId int64, size int64) error {
+	if userQuota <= 0 || db == nil {
+		return nil
+	}
+	used, err := usedSize(db, userId)
+	if err != nil {
+		return err
+	}
+	if used+max(size, 0) > userQuota {
+		return reject(ErrQuotaExceeded, "已使用 %d MB，上限 %d MB", used>>20, userQuota>>20)
+	}
+	return nil
+}
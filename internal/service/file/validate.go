@@ -0,0 +1,289 @@
+package fileservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global"
+	"txing-ai/internal/utils"
+)
+
+// Purpose 上传文件的用途，不同用途允许的文件类型不同
+type Purpose string
+
+const (
+	PurposeFile   Purpose = "file"   // 通用文件，文档和图片
+	PurposeChat   Purpose = "chat"   // 对话中发送的图片
+	PurposeResume Purpose = "resume" // 简历优化智能体读取的简历
+)
+
+// 上传文件被拒绝的原因，通过 errors.Is 判断
+var (
+	ErrFileTooLarge        = errors.New("文件大小超出限制")
+	ErrFileTypeNotAllowed  = errors.New("不支持的文件类型")
+	ErrFileContentMismatch = errors.New("文件内容与扩展名不符")
+	ErrFileInfected        = errors.New("文件未通过病毒扫描")
+	ErrFileScanFailed      = errors.New("文件病毒扫描失败")
+	ErrQuotaExceeded       = errors.New("存储空间不足")
+)
+
+// RejectedError 上传文件没有通过校验，Reason 为上面的错误之一
+type RejectedError struct {
+	Reason error
+	Detail string
+}
+
+func (e *RejectedError) Error() string {
+	if e.Detail == "" {
+		return e.Reason.Error()
+	}
+	return e.Reason.Error() + ": " + e.Detail
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Reason
+}
+
+func reject(reason error, format string, args ...any) error {
+	return &RejectedError{Reason: reason, Detail: fmt.Sprintf(format, args...)}
+}
+
+// IsRejected 是否为上传文件没有通过校验的错误，这类错误可以直接提示给用户
+func IsRejected(err error) bool {
+	var rejected *RejectedError
+	return errors.As(err, &rejected)
+}
+
+// 各扩展名按文件头识别出的类型，docx 等新版 Office 文档为 zip 格式，doc 等旧版为 OLE 格式
+var extContentTypes = map[string][]string{
+	".pdf":  {"application/pdf"},
+	".png":  {"image/png"},
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".gif":  {"image/gif"},
+	".webp": {"image/webp"},
+	".docx": {"application/zip"},
+	".xlsx": {"application/zip"},
+	".pptx": {"application/zip"},
+	".doc":  {"application/x-ole-storage"},
+	".xls":  {"application/x-ole-storage"},
+	".ppt":  {"application/x-ole-storage"},
+	".txt":  {"text/plain"},
+	".csv":  {"text/plain"},
+	".json": {"text/plain"},
+	".md":   {"text/plain", "text/html"},
+}
+
+var imageExts = []string{".png", ".jpg", ".jpeg", ".gif", ".webp"}
+
+// 各用途允许的扩展名
+var purposeExts = map[Purpose][]string{
+	PurposeFile: {
+		".pdf", ".png", ".jpg", ".jpeg", ".gif", ".webp",
+		".docx", ".xlsx", ".pptx", ".doc", ".xls", ".ppt",
+		".txt", ".csv", ".json", ".md",
+	},
+	PurposeChat:   imageExts,
+	PurposeResume: {".pdf", ".docx", ".md", ".txt"},
+}
+
+// OLE 复合文档的文件头，http.DetectContentType 识别不了
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+var (
+	// 上传文件的大小限制，小于等于 0 时不限制
+	maxUploadSize int64
+	// 每个用户的文件总大小上限，小于等于 0 时不限制
+	userQuota int64
+)
+
+// InitUpload 初始化上传文件的大小限制、用户存储配额和病毒扫描
+func InitUpload(config *global.AppConfig) {
+	if config.LocalUploadConfig != nil {
+		maxUploadSize = int64(config.LocalUploadConfig.MaxSize)
+	}
+	if config.RetentionConfig != nil {
+		userQuota = config.RetentionConfig.UserQuota
+	}
+	InitScanner(config.ScanConfig)
+}
+
+// Valid 是否为支持的用途
+func (p Purpose) Valid() bool {
+	_, ok := purposeExts[p]
+	return ok
+}
+
+// Extensions 允许的扩展名
+func (p Purpose) Extensions() []string {
+	return append([]string(nil), purposeExts[p]...)
+}
+
+// Inspection 上传文件的检查结果
+type Inspection struct {
+	// 清理后的文件名
+	Name     string
+	MimeType string
+	Size     int64
+	// 病毒扫描状态和结果，见 domain.FileScanXxx
+	ScanStatus string
+	ScanDetail string
+}
+
+// checkName 清理文件名并检查扩展名是否允许
+func checkName(purpose Purpose, name string) (string, error) {
+	exts, ok := purposeExts[purpose]
+	if !ok {
+		return "", reject(ErrFileTypeNotAllowed, "未知的上传用途 %s", purpose)
+	}
+	name = utils.SanitizeFilename(name)
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, allowed := range exts {
+		if ext == allowed {
+			return name, nil
+		}
+	}
+	return "", reject(ErrFileTypeNotAllowed, "只支持 %s 文件", strings.Join(exts, "、"))
+}
+
+// checkContent 按文件头识别类型，和扩展名不符时拒绝，返回记录到文件表中的类型
+func checkContent(name string, head []byte) (string, error) {
+	sniffed := sniffContentType(head)
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, expected := range extContentTypes[ext] {
+		if sniffed == expected {
+			return detectMimeType(name, head), nil
+		}
+	}
+	return "", reject(ErrFileContentMismatch, "%s 文件的内容识别为 %s", ext, sniffed)
+}
+
+// sniffContentType 按文件头识别类型，不带参数
+func sniffContentType(head []byte) string {
+	if bytes.HasPrefix(head, oleMagic) {
+		return "application/x-ole-storage"
+	}
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// checkSize 检查文件大小，size 小于 0 表示长度未知
+func checkSize(size int64) error {
+	if maxUploadSize > 0 && size > maxUploadSize {
+		return reject(ErrFileTooLarge, "最大 %d MB", maxUploadSize>>20)
+	}
+	return nil
+}
+
+// limitReader 读取超过 maxUploadSize 时返回 ErrFileTooLarge，用于长度未知的上传
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func newLimitReader(r io.Reader) io.Reader {
+	if maxUploadSize <= 0 {
+		return r
+	}
+	return &limitReader{r: r, n: maxUploadSize}
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, checkSize(maxUploadSize + 1)
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, checkSize(maxUploadSize + 1)
+	}
+	return n, err
+}
+
+// scanJob 在写入存储的同时把内容交给扫描服务扫描
+type scanJob struct {
+	pw     *io.PipeWriter
+	done   chan struct{}
+	result *ScanResult
+	err    error
+}
+
+// startScan 没有配置扫描服务时返回 nil
+func startScan(ctx context.Context) *scanJob {
+	if scanner == nil {
+		return nil
+	}
+	pr, pw := io.Pipe()
+	job := &scanJob{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(job.done)
+		job.result, job.err = scanner.Scan(ctx, pr)
+		// 扫描服务提前返回时继续读完剩余的内容，避免写入方阻塞
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	return job
+}
+
+// tee 写入存储的内容同时写入扫描服务
+func (j *scanJob) tee(r io.Reader) io.Reader {
+	if j == nil {
+		return r
+	}
+	return io.TeeReader(r, j.pw)
+}
+
+// wait 结束写入并等待扫描结果
+func (j *scanJob) wait(writeErr error) (string, string, error) {
+	if j == nil {
+		return domain.FileScanSkipped, "", nil
+	}
+	if writeErr != nil {
+		j.pw.CloseWithError(writeErr)
+	} else {
+		j.pw.Close()
+	}
+	<-j.done
+	return scanOutcome(j.result, j.err)
+}
+
+// scanFile 扫描完整的内容，用于已经保存到本地的文件
+func scanFile(ctx context.Context, r io.Reader) (string, string, error) {
+	if scanner == nil {
+		return domain.FileScanSkipped, "", nil
+	}
+	return scanOutcome(scanner.Scan(ctx, r))
+}
+
+// scanOutcome 转换为记录到文件表中的扫描状态，发现病毒或者扫描失败且不放行时返回 *RejectedError
+func scanOutcome(result *ScanResult, err error) (string, string, error) {
+	if err != nil {
+		if scanFailOpen {
+			return domain.FileScanError, truncate(err.Error(), 255), nil
+		}
+		return "", "", reject(ErrFileScanFailed, "%v", err)
+	}
+	if result.Infected {
+		return "", "", reject(ErrFileInfected, "%s", result.Signature)
+	}
+	return domain.FileScanClean, "", nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
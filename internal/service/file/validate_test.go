@@ -0,0 +1,265 @@
+package fileservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global/logging"
+	"txing-ai/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+var (
+	pdfContent  = []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	pngContent  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01")
+	docxContent = []byte("PK\x03\x04\x14\x00\x06\x00\x08\x00\x00\x00!\x00[Content_Types].xml")
+	docContent  = append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, make([]byte, 32)...)
+	exeContent  = []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00")
+)
+
+// memStore 内存中的文件存储
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{objects: map[string][]byte{}}
+}
+
+func (s *memStore) Name() string { return "mem" }
+
+func (s *memStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*storage.ObjectInfo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return &storage.ObjectInfo{Key: key, Size: int64(len(data)), ContentType: contentType}, nil
+}
+
+func (s *memStore) Get(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), &storage.ObjectInfo{Key: key, Size: int64(len(data))}, nil
+}
+
+func (s *memStore) Stat(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	_, info, err := s.Get(ctx, key)
+	return info, err
+}
+
+func (s *memStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memStore) PresignGet(ctx context.Context, key string, expire time.Duration) (string, error) {
+	return "", storage.ErrPresignNotSupported
+}
+
+func (s *memStore) PresignPut(ctx context.Context, key string, expire time.Duration) (string, error) {
+	return "", storage.ErrPresignNotSupported
+}
+
+func (s *memStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects)
+}
+
+// fakeScanner 把包含 EICAR 字样的内容当作病毒
+type fakeScanner struct {
+	err error
+}
+
+func (s *fakeScanner) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if bytes.Contains(data, []byte("EICAR")) {
+		return &ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return &ScanResult{}, nil
+}
+
+// withUploadConfig 临时修改上传限制和扫描服务
+func withUploadConfig(t *testing.T, maxSize int64, s Scanner, failOpen bool) {
+	oldMax, oldScanner, oldFailOpen := maxUploadSize, scanner, scanFailOpen
+	maxUploadSize, scanner, scanFailOpen = maxSize, s, failOpen
+	t.Cleanup(func() {
+		maxUploadSize, scanner, scanFailOpen = oldMax, oldScanner, oldFailOpen
+	})
+}
+
+func TestCheckName(t *testing.T) {
+	tests := []struct {
+		purpose Purpose
+		name    string
+		want    string
+		wantErr error
+	}{
+		{purpose: PurposeResume, name: "简历.pdf", want: "简历.pdf"},
+		{purpose: PurposeResume, name: "简历.PDF", want: "简历.PDF"},
+		{purpose: PurposeResume, name: "简历.docx", want: "简历.docx"},
+		{purpose: PurposeResume, name: "简历.doc", wantErr: ErrFileTypeNotAllowed},
+		{purpose: PurposeChat, name: "../../etc/a.png", want: "a.png"},
+		{purpose: PurposeChat, name: `C:\Users\me\photo.jpg`, want: "photo.jpg"},
+		{purpose: PurposeChat, name: "a.pdf", wantErr: ErrFileTypeNotAllowed},
+		{purpose: PurposeFile, name: "report.docx", want: "report.docx"},
+		{purpose: PurposeFile, name: "run.exe", wantErr: ErrFileTypeNotAllowed},
+		{purpose: PurposeFile, name: "noext", wantErr: ErrFileTypeNotAllowed},
+		{purpose: "other", name: "a.pdf", wantErr: ErrFileTypeNotAllowed},
+	}
+	for _, tt := range tests {
+		got, err := checkName(tt.purpose, tt.name)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) || !IsRejected(err) {
+				t.Errorf("checkName(%s, %q) err = %v, want %v", tt.purpose, tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("checkName(%s, %q) = %q, %v, want %q", tt.purpose, tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheckContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		wantType string
+		wantErr  error
+	}{
+		{name: "a.pdf", content: pdfContent, wantType: "application/pdf"},
+		{name: "a.png", content: pngContent, wantType: "image/png"},
+		{name: "a.docx", content: docxContent, wantType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{name: "a.doc", content: docContent, wantType: "application/msword"},
+		{name: "a.md", content: []byte("# 标题\n\n正文"), wantType: "text/markdown; charset=utf-8"},
+		{name: "a.md", content: []byte("<div align=\"center\">标题</div>"), wantType: "text/markdown; charset=utf-8"},
+		{name: "a.pdf", content: exeContent, wantErr: ErrFileContentMismatch},
+		{name: "a.jpg", content: pngContent, wantErr: ErrFileContentMismatch},
+		{name: "a.txt", content: exeContent, wantErr: ErrFileContentMismatch},
+		{name: "a.docx", content: pdfContent, wantErr: ErrFileContentMismatch},
+	}
+	for _, tt := range tests {
+		got, err := checkContent(tt.name, tt.content)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkContent(%s) err = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.wantType {
+			t.Errorf("checkContent(%s) = %q, %v, want %q", tt.name, got, err, tt.wantType)
+		}
+	}
+}
+
+func TestSaveUpload_rejected(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		purpose  Purpose
+		filename string
+		content  []byte
+		// 长度未知时为 -1
+		size     int64
+		scanErr  error
+		failOpen bool
+		wantErr  error
+	}{
+		{name: "扩展名不允许", purpose: PurposeResume, filename: "a.exe", content: exeContent, size: -1, wantErr: ErrFileTypeNotAllowed},
+		{name: "内容与扩展名不符", purpose: PurposeResume, filename: "a.pdf", content: exeContent, size: -1, wantErr: ErrFileContentMismatch},
+		{name: "声明的大小超出限制", purpose: PurposeFile, filename: "a.txt", content: []byte("hello"), size: 2048, wantErr: ErrFileTooLarge},
+		{name: "实际大小超出限制", purpose: PurposeFile, filename: "a.txt", content: bytes.Repeat([]byte("a"), 2048), size: -1, wantErr: ErrFileTooLarge},
+		{name: "发现病毒", purpose: PurposeFile, filename: "a.txt", content: []byte("X5O!P%@AP EICAR test"), size: -1, wantErr: ErrFileInfected},
+		{name: "扫描失败", purpose: PurposeResume, filename: "a.pdf", content: pdfContent, size: -1, scanErr: errors.New("connection refused"), wantErr: ErrFileScanFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withUploadConfig(t, 1024, &fakeScanner{err: tt.scanErr}, tt.failOpen)
+			store := newMemStore()
+			// 被拒绝的文件不会写入数据库
+			_, err := SaveUpload(ctx, nil, storage.NewStores(store), 1, tt.purpose, tt.filename, bytes.NewReader(tt.content), tt.size,
+				domain.FileSourceUpload, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var rejected *RejectedError
+			if !errors.As(err, &rejected) {
+				t.Errorf("err = %T, want *RejectedError", err)
+			}
+			if store.len() != 0 {
+				t.Error("rejected file should be deleted from store")
+			}
+		})
+	}
+}
+
+func TestInspectLocalFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	withUploadConfig(t, 1024, nil, false)
+	inspection, err := inspectLocalFile(ctx, "a.pdf", write("a.pdf", pdfContent), int64(len(pdfContent)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspection.MimeType != "application/pdf" || inspection.ScanStatus != domain.FileScanSkipped {
+		t.Errorf("inspection = %+v", inspection)
+	}
+
+	withUploadConfig(t, 1024, &fakeScanner{}, false)
+	inspection, err = inspectLocalFile(ctx, "a.pdf", write("b.pdf", pdfContent), int64(len(pdfContent)))
+	if err != nil || inspection.ScanStatus != domain.FileScanClean {
+		t.Errorf("inspection = %+v, err = %v", inspection, err)
+	}
+	if _, err := inspectLocalFile(ctx, "a.txt", write("c.txt", []byte("EICAR")), 5); !errors.Is(err, ErrFileInfected) {
+		t.Errorf("err = %v, want %v", err, ErrFileInfected)
+	}
+	if _, err := inspectLocalFile(ctx, "a.pdf", write("d.pdf", pdfContent), 2048); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("err = %v, want %v", err, ErrFileTooLarge)
+	}
+
+	// 扫描服务不可用且放行时记录扫描错误
+	withUploadConfig(t, 1024, &fakeScanner{err: errors.New("connection refused")}, true)
+	inspection, err = inspectLocalFile(ctx, "a.pdf", write("e.pdf", pdfContent), int64(len(pdfContent)))
+	if err != nil || inspection.ScanStatus != domain.FileScanError || !strings.Contains(inspection.ScanDetail, "connection refused") {
+		t.Errorf("inspection = %+v, err = %v", inspection, err)
+	}
+}
@@ -0,0 +1,44 @@
+package knowledgeservice
+
+import (
+	"context"
+	"fmt"
+	adaptercommon "txing-ai/internal/adapter/common"
+	"txing-ai/internal/service/channel"
+
+	"gorm.io/gorm"
+)
+
+// Embedder 把文本转换为向量，返回的向量和输入一一对应
+type Embedder interface {
+	Embed(ctx context.Context, model string, inputs []string) ([][]float32, error)
+}
+
+// channelEmbedder 通过支持向量化模型的渠道调用向量化接口
+type channelEmbedder struct {
+	db *gorm.DB
+}
+
+// NewChannelEmbedder 创建通过渠道调用的 Embedder
+func NewChannelEmbedder(db *gorm.DB) Embedder {
+	return &channelEmbedder{db: db}
+}
+
+func (e *channelEmbedder) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	result, err := channel.NewEmbeddingRequest(ctx, e.db, &adaptercommon.EmbeddingConfig{
+		Model: model,
+		Input: inputs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("向量化失败: %w", err)
+	}
+	for i, vector := range result.Data {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("向量化接口返回的第 %d 个向量为空", i)
+		}
+	}
+	return result.Data, nil
+}
@@ -0,0 +1,255 @@
+package knowledgeservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global/logging/log"
+	fileservice "txing-ai/internal/service/file"
+	"txing-ai/internal/tool/ingest"
+	"txing-ai/internal/vectorstore"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 定期扫描等待处理的文档，补上队列已满时没有放入队列的文档
+const scanInterval = time.Minute
+
+// Start 启动处理文档的协程，上次退出时正在处理的文档重新处理
+func (s *Service) Start(ctx context.Context) {
+	err := s.db.Model(&domain.KnowledgeDocument{}).Where("status = ?", domain.KnowledgeDocProcessing).
+		Update("status", domain.KnowledgeDocPending).Error
+	if err != nil {
+		log.Error("重置知识库文档状态失败", zap.Error(err))
+	}
+	for i := 0; i < s.config.Workers; i++ {
+		go s.work(ctx)
+	}
+	go func() {
+		ticker := time.NewTicker(scanInterval)
+		defer ticker.Stop()
+		for {
+			s.enqueuePending()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Enqueue 把文档放入处理队列，队列已满时等待定时扫描
+func (s *Service) Enqueue(documentId int64) {
+	select {
+	case s.queue <- documentId:
+	default:
+		log.Warn("知识库文档处理队列已满", zap.Int64("documentId", documentId))
+	}
+}
+
+func (s *Service) enqueuePending() {
+	var ids []int64
+	err := s.db.Model(&domain.KnowledgeDocument{}).Where("status = ?", domain.KnowledgeDocPending).
+		Order("id").Limit(queueSize).Pluck("id", &ids).Error
+	if err != nil {
+		log.Error("查询等待处理的知识库文档失败", zap.Error(err))
+		return
+	}
+	for _, id := range ids {
+		s.Enqueue(id)
+	}
+}
+
+func (s *Service) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			s.process(ctx, id)
+		}
+	}
+}
+
+// process 处理一个文档，同一个文档只会被一个协程处理
+func (s *Service) process(ctx context.Context, documentId int64) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("处理知识库文档 panic", zap.Int64("documentId", documentId), zap.Any("err", err))
+			s.fail(documentId, fmt.Errorf("%v", err))
+		}
+	}()
+
+	claimed := s.db.Model(&domain.KnowledgeDocument{}).
+		Where("id = ? AND status = ?", documentId, domain.KnowledgeDocPending).
+		Update("status", domain.KnowledgeDocProcessing)
+	if claimed.Error != nil {
+		log.Error("更新知识库文档状态失败", zap.Int64("documentId", documentId), zap.Error(claimed.Error))
+		return
+	}
+	if claimed.RowsAffected == 0 {
+		return
+	}
+
+	var doc domain.KnowledgeDocument
+	if err := s.db.First(&doc, documentId).Error; err != nil {
+		log.Error("查询知识库文档失败", zap.Int64("documentId", documentId), zap.Error(err))
+		return
+	}
+	start := time.Now()
+	if err := s.index(ctx, &doc); err != nil {
+		log.Warn("处理知识库文档失败", zap.Int64("documentId", documentId), zap.Error(err))
+		s.fail(documentId, err)
+		return
+	}
+	log.Info("知识库文档处理完成", zap.Int64("documentId", documentId), zap.Int("chunks", doc.ChunkCount), zap.Duration("cost", time.Since(start)))
+}
+
+func (s *Service) fail(documentId int64, cause error) {
+	message := []rune(cause.Error())
+	if len(message) > 500 {
+		message = message[:500]
+	}
+	err := s.db.Model(&domain.KnowledgeDocument{}).Where("id = ?", documentId).
+		Updates(map[string]interface{}{"status": domain.KnowledgeDocFailed, "error": string(message)}).Error
+	if err != nil {
+		log.Error("更新知识库文档状态失败", zap.Int64("documentId", documentId), zap.Error(err))
+	}
+}
+
+// index 解析文档、切分、向量化，替换文档原有的文本块
+func (s *Service) index(ctx context.Context, doc *domain.KnowledgeDocument) error {
+	kb, err := GetKnowledgeBase(s.db, doc.KnowledgeBaseID)
+	if err != nil {
+		return err
+	}
+	parsed, err := s.parse(ctx, doc)
+	if err != nil {
+		return err
+	}
+	for _, warning := range parsed.Warnings {
+		log.Warn("解析知识库文档时出现问题", zap.Int64("documentId", doc.Id), zap.String("warning", warning))
+	}
+	pieces := parsed.Chunks(ingest.ChunkOptions{Size: kb.ChunkSize, Overlap: kb.ChunkOverlap})
+	if len(pieces) == 0 {
+		return errors.New("文档中没有可以提取的文本")
+	}
+
+	chunks := make([]domain.KnowledgeChunk, len(pieces))
+	vectors := make([][]float32, 0, len(pieces))
+	for i, piece := range pieces {
+		chunks[i] = domain.KnowledgeChunk{
+			KnowledgeBaseID: kb.Id,
+			DocumentID:      doc.Id,
+			Seq:             piece.Index,
+			Content:         piece.Text,
+			Section:         truncateRunes(piece.Section, 500),
+			PageStart:       piece.PageStart,
+			PageEnd:         piece.PageEnd,
+		}
+	}
+	for i := 0; i < len(pieces); i += s.config.EmbeddingBatch {
+		end := min(i+s.config.EmbeddingBatch, len(pieces))
+		inputs := make([]string, 0, end-i)
+		for _, chunk := range chunks[i:end] {
+			inputs = append(inputs, embeddingInput(chunk.Section, chunk.Content))
+		}
+		batch, err := s.embedder.Embed(ctx, kb.EmbeddingModel, inputs)
+		if err != nil {
+			return err
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	dimensions := len(vectors[0])
+	for i, vector := range vectors {
+		if len(vector) != dimensions {
+			return fmt.Errorf("%w: 第 %d 个文本块为 %d 维，应为 %d 维", vectorstore.ErrDimensionMismatch, i, len(vector), dimensions)
+		}
+		chunks[i].Embedding = vectorstore.Encode(vector)
+	}
+	if kb.Dimensions != 0 && kb.Dimensions != dimensions {
+		return fmt.Errorf("%w: 知识库为 %d 维，向量化模型返回 %d 维，向量化模型可能已被更换", vectorstore.ErrDimensionMismatch, kb.Dimensions, dimensions)
+	}
+
+	doc.Status = domain.KnowledgeDocReady
+	doc.Error = ""
+	doc.Language = parsed.Language
+	doc.Pages = parsed.Pages
+	doc.ChunkCount = len(chunks)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 处理期间文档可能已经被删除
+		if err := tx.Select("id").First(&domain.KnowledgeDocument{}, doc.Id).Error; err != nil {
+			return err
+		}
+		if kb.Dimensions == 0 {
+			if err := tx.Model(kb).Update("dimensions", dimensions).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("document_id = ?", doc.Id).Delete(&domain.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(chunks, 100).Error; err != nil {
+			return err
+		}
+		return tx.Model(doc).Select("status", "error", "language", "pages", "chunk_count").Updates(doc).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	records := make([]vectorstore.Record, len(chunks))
+	for i, chunk := range chunks {
+		records[i] = vectorstore.Record{ID: chunk.Id, KnowledgeBaseID: kb.Id, DocumentID: doc.Id, Vector: vectors[i]}
+	}
+	if err := s.vectors.DeleteDocument(ctx, kb.Id, doc.Id); err != nil {
+		return err
+	}
+	return s.vectors.Upsert(ctx, records)
+}
+
+// parse 读取文档引用的文件并解析
+func (s *Service) parse(ctx context.Context, doc *domain.KnowledgeDocument) (*ingest.Document, error) {
+	var file domain.File
+	if err := s.db.First(&file, doc.FileID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fileservice.ErrFileNotFound
+		}
+		return nil, err
+	}
+	reader, info, err := fileservice.Open(ctx, s.stores, &file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	if info.Size > s.config.MaxDocumentSize {
+		return nil, fmt.Errorf("文件太大，最多 %d MB", s.config.MaxDocumentSize>>20)
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, s.config.MaxDocumentSize))
+	if err != nil {
+		return nil, err
+	}
+	return ingest.Parse(ctx, doc.Name, bytes.NewReader(data), int64(len(data)), nil)
+}
+
+// embeddingInput 向量化时带上章节标题，让只包含正文的文本块也能按标题检索到
+func embeddingInput(section string, content string) string {
+	if section == "" {
+		return content
+	}
+	return section + "\n\n" + content
+}
+
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
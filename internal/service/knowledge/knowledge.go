@@ -0,0 +1,303 @@
+package knowledgeservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	fileservice "txing-ai/internal/service/file"
+	"txing-ai/internal/storage"
+	"txing-ai/internal/tool/ingest"
+	"txing-ai/internal/vectorstore"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrKnowledgeBaseNotFound = errors.New("知识库不存在")
+	ErrDocumentNotFound      = errors.New("文档不存在")
+)
+
+// 配置项没有设置时的默认值
+const (
+	defaultEmbeddingModel  = "text-embedding-3-small"
+	defaultEmbeddingBatch  = 32
+	defaultChunkSize       = 800
+	defaultChunkOverlap    = 100
+	defaultTopK            = 5
+	defaultWorkers         = 2
+	defaultMaxDocumentSize = 50 << 20
+	// 每个会话最多引用的知识库数量
+	MaxKnowledgeBases = 5
+	// 等待处理的文档队列长度，队列满时由定时扫描补上
+	queueSize = 256
+)
+
+// Service 知识库服务，负责文档的解析、向量化和检索
+type Service struct {
+	db       *gorm.DB
+	stores   *storage.Stores
+	embedder Embedder
+	vectors  vectorstore.Store
+	config   global.KnowledgeConfig
+	queue    chan int64
+}
+
+// NewService 创建知识库服务，向量索引按配置创建，内存索引从数据库加载向量
+func NewService(db *gorm.DB, stores *storage.Stores, config *global.KnowledgeConfig) (*Service, error) {
+	s := &Service{
+		db:       db,
+		stores:   stores,
+		embedder: NewChannelEmbedder(db),
+		config:   withDefaults(config),
+		queue:    make(chan int64, queueSize),
+	}
+	vectors, err := vectorstore.New(s.config.VectorStore, s.loadRecords, s.loadVersion)
+	if err != nil {
+		return nil, err
+	}
+	s.vectors = vectors
+	return s, nil
+}
+
+func withDefaults(config *global.KnowledgeConfig) global.KnowledgeConfig {
+	var c global.KnowledgeConfig
+	if config != nil {
+		c = *config
+	}
+	if c.EmbeddingModel == "" {
+		c.EmbeddingModel = defaultEmbeddingModel
+	}
+	if c.EmbeddingBatch <= 0 {
+		c.EmbeddingBatch = defaultEmbeddingBatch
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = defaultChunkSize
+	}
+	if c.ChunkOverlap < 0 || c.ChunkOverlap >= c.ChunkSize {
+		c.ChunkOverlap = defaultChunkOverlap
+	}
+	if c.TopK <= 0 {
+		c.TopK = defaultTopK
+	}
+	if c.Workers <= 0 {
+		c.Workers = defaultWorkers
+	}
+	if c.MaxDocumentSize <= 0 {
+		c.MaxDocumentSize = defaultMaxDocumentSize
+	}
+	return c
+}
+
+// loadRecords 从数据库加载知识库的全部向量
+func (s *Service) loadRecords(ctx context.Context, knowledgeBaseId int64) ([]vectorstore.Record, error) {
+	var chunks []domain.KnowledgeChunk
+	err := s.db.WithContext(ctx).Select("id", "knowledge_base_id", "document_id", "embedding").
+		Where("knowledge_base_id = ?", knowledgeBaseId).Find(&chunks).Error
+	if err != nil {
+		return nil, err
+	}
+	records := make([]vectorstore.Record, 0, len(chunks))
+	for _, chunk := range chunks {
+		vector, err := vectorstore.Decode(chunk.Embedding)
+		if err != nil {
+			log.Warn("知识库文本块的向量无效", zap.Int64("id", chunk.Id), zap.Error(err))
+			continue
+		}
+		records = append(records, vectorstore.Record{ID: chunk.Id, KnowledgeBaseID: chunk.KnowledgeBaseID, DocumentID: chunk.DocumentID, Vector: vector})
+	}
+	return records, nil
+}
+
+// loadVersion 知识库文本块的版本，由文本块数量和最大 ID 组成
+// 文本块只会新增和删除，重新索引时先删除再以新的 ID 写入，任何变化都会改变数量或者最大 ID
+func (s *Service) loadVersion(ctx context.Context, knowledgeBaseId int64) (string, error) {
+	var version struct {
+		Count int64
+		MaxId int64
+	}
+	err := s.db.WithContext(ctx).Model(&domain.KnowledgeChunk{}).
+		Select("COUNT(*) AS count, COALESCE(MAX(id), 0) AS max_id").
+		Where("knowledge_base_id = ?", knowledgeBaseId).Scan(&version).Error
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", version.Count, version.MaxId), nil
+}
+
+// Create 创建知识库，没有指定的向量化模型和切分参数使用配置中的默认值
+func (s *Service) Create(kb *domain.KnowledgeBase) error {
+	if kb.EmbeddingModel == "" {
+		kb.EmbeddingModel = s.config.EmbeddingModel
+	}
+	if kb.ChunkSize <= 0 {
+		kb.ChunkSize = s.config.ChunkSize
+	}
+	if kb.ChunkOverlap <= 0 || kb.ChunkOverlap >= kb.ChunkSize {
+		kb.ChunkOverlap = min(s.config.ChunkOverlap, kb.ChunkSize/4)
+	}
+	return s.db.Create(kb).Error
+}
+
+// GetKnowledgeBase 查询知识库
+func GetKnowledgeBase(db *gorm.DB, id int64) (*domain.KnowledgeBase, error) {
+	var kb domain.KnowledgeBase
+	if err := db.First(&kb, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKnowledgeBaseNotFound
+		}
+		return nil, err
+	}
+	return &kb, nil
+}
+
+// GetDocument 查询知识库中的文档
+func GetDocument(db *gorm.DB, knowledgeBaseId int64, id int64) (*domain.KnowledgeDocument, error) {
+	var doc domain.KnowledgeDocument
+	if err := db.Where("knowledge_base_id = ?", knowledgeBaseId).First(&doc, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// CanUse 官方知识库和自己创建的知识库可以检索和引用
+func CanUse(kb *domain.KnowledgeBase, userId int64) bool {
+	return kb.Official || kb.UserID == userId
+}
+
+// CanManage 创建者可以修改知识库，管理员可以修改官方知识库
+func CanManage(kb *domain.KnowledgeBase, userId int64, isAdmin bool) bool {
+	return kb.UserID == userId || (isAdmin && kb.Official)
+}
+
+// CheckAccessible 校验用户可以引用这些知识库，返回去重后的 ID
+// allowed 是预设中引用的知识库，使用该预设的用户即使不是创建者也可以引用
+func CheckAccessible(db *gorm.DB, userId int64, ids []int64, allowed []int64) ([]int64, error) {
+	result := make([]int64, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+	if len(result) > MaxKnowledgeBases {
+		return nil, fmt.Errorf("最多引用 %d 个知识库", MaxKnowledgeBases)
+	}
+
+	var kbs []domain.KnowledgeBase
+	if err := db.Where("id IN ?", result).Find(&kbs).Error; err != nil {
+		return nil, err
+	}
+	found := make(map[int64]*domain.KnowledgeBase, len(kbs))
+	for i := range kbs {
+		found[kbs[i].Id] = &kbs[i]
+	}
+	for _, id := range result {
+		kb, ok := found[id]
+		if !ok || !(CanUse(kb, userId) || containsId(allowed, id)) {
+			return nil, fmt.Errorf("%w: %d", ErrKnowledgeBaseNotFound, id)
+		}
+	}
+	return result, nil
+}
+
+func containsId(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AddDocuments 把用户上传的文件添加到知识库，文档在后台解析和向量化
+func (s *Service) AddDocuments(ctx context.Context, kb *domain.KnowledgeBase, userId int64, fileIds []int64) ([]domain.KnowledgeDocument, error) {
+	docs := make([]domain.KnowledgeDocument, 0, len(fileIds))
+	for _, id := range fileIds {
+		file, err := fileservice.GetUserFile(s.db, userId, id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %d", err, id)
+		}
+		if !ingest.Supported(file.Name) {
+			return nil, fmt.Errorf("不支持解析文件 %s", file.Name)
+		}
+		if file.Size > s.config.MaxDocumentSize {
+			return nil, fmt.Errorf("文件 %s 太大，最多 %d MB", file.Name, s.config.MaxDocumentSize>>20)
+		}
+		var count int64
+		if err := s.db.Model(&domain.KnowledgeDocument{}).Where("knowledge_base_id = ? AND file_id = ?", kb.Id, file.Id).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("文件 %s 已经在知识库中", file.Name)
+		}
+		docs = append(docs, domain.KnowledgeDocument{
+			KnowledgeBaseID: kb.Id,
+			FileID:          file.Id,
+			Name:            file.Name,
+			Status:          domain.KnowledgeDocPending,
+		})
+	}
+	if len(docs) == 0 {
+		return docs, nil
+	}
+	if err := s.db.Create(&docs).Error; err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		s.Enqueue(doc.Id)
+	}
+	return docs, nil
+}
+
+// Reindex 重新解析和向量化文档
+func (s *Service) Reindex(ctx context.Context, doc *domain.KnowledgeDocument) error {
+	err := s.db.Model(doc).Where("status <> ?", domain.KnowledgeDocProcessing).
+		Updates(map[string]interface{}{"status": domain.KnowledgeDocPending, "error": ""}).Error
+	if err != nil {
+		return err
+	}
+	s.Enqueue(doc.Id)
+	return nil
+}
+
+// DeleteDocument 删除文档和它的文本块，文件本身由文件清理任务处理
+func (s *Service) DeleteDocument(ctx context.Context, doc *domain.KnowledgeDocument) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", doc.Id).Delete(&domain.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(doc).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.vectors.DeleteDocument(ctx, doc.KnowledgeBaseID, doc.Id)
+}
+
+// DeleteKnowledgeBase 删除知识库和其中的文档，引用它的预设和会话检索时会忽略不存在的知识库
+func (s *Service) DeleteKnowledgeBase(ctx context.Context, kb *domain.KnowledgeBase) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("knowledge_base_id = ?", kb.Id).Delete(&domain.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("knowledge_base_id = ?", kb.Id).Delete(&domain.KnowledgeDocument{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(kb).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.vectors.DeleteKnowledgeBase(ctx, kb.Id)
+}
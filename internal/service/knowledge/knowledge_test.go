@@ -0,0 +1,73 @@
+package knowledgeservice
+
+import (
+	"strings"
+	"testing"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global"
+	"txing-ai/internal/vectorstore"
+)
+
+func TestFilterHits(t *testing.T) {
+	hits := []vectorstore.Hit{
+		{ID: 1, Score: 0.2},
+		{ID: 2, Score: 0.9},
+		{ID: 3, Score: 0.5},
+		{ID: 4, Score: 0.7},
+	}
+	got := filterHits(hits, 0.3, 2)
+	if len(got) != 2 || got[0].ID != 2 || got[1].ID != 4 {
+		t.Errorf("filterHits() = %+v", got)
+	}
+	if got := filterHits(hits, 0.95, 5); len(got) != 0 {
+		t.Errorf("filterHits() = %+v, want empty", got)
+	}
+}
+
+func TestBuildContextAndReferences(t *testing.T) {
+	passages := []Passage{
+		{
+			Chunk:    domain.KnowledgeChunk{KnowledgeBaseID: 3, Content: "先安装依赖。", Section: "安装", PageStart: 2, PageEnd: 3},
+			Document: domain.KnowledgeDocument{BaseModel: domain.BaseModel{Id: 8}, FileID: 12, Name: "手册.pdf"},
+			Score:    0.8,
+		},
+		{
+			Chunk:    domain.KnowledgeChunk{KnowledgeBaseID: 3, Content: strings.Repeat("长", 300)},
+			Document: domain.KnowledgeDocument{BaseModel: domain.BaseModel{Id: 9}, FileID: 13, Name: "说明.md"},
+			Score:    0.6,
+		},
+	}
+
+	context := BuildContext(passages)
+	for _, want := range []string{"[1] 《手册.pdf》 安装 第 2-3 页\n先安装依赖。", "[2] 《说明.md》\n", "[编号]"} {
+		if !strings.Contains(context, want) {
+			t.Errorf("BuildContext() 缺少 %q:\n%s", want, context)
+		}
+	}
+	if BuildContext(nil) != "" {
+		t.Error("BuildContext(nil) 应该为空")
+	}
+
+	references := References(passages)
+	if len(references) != 2 {
+		t.Fatalf("References() = %+v", references)
+	}
+	first := references[0]
+	if first.Index != 1 || first.DocumentId != 8 || first.FileId != 12 || first.KnowledgeBaseId != 3 || first.Snippet != "先安装依赖。" {
+		t.Errorf("References()[0] = %+v", first)
+	}
+	if snippet := []rune(references[1].Snippet); len(snippet) != snippetRunes+2 || references[1].Index != 2 {
+		t.Errorf("References()[1].Snippet 长度 = %d", len(snippet))
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	c := withDefaults(nil)
+	if c.EmbeddingModel != defaultEmbeddingModel || c.ChunkSize != defaultChunkSize || c.TopK != defaultTopK || c.Workers != defaultWorkers {
+		t.Errorf("withDefaults(nil) = %+v", c)
+	}
+	c = withDefaults(&global.KnowledgeConfig{ChunkSize: 300, ChunkOverlap: 400, TopK: 8})
+	if c.ChunkSize != 300 || c.ChunkOverlap != defaultChunkOverlap || c.TopK != 8 {
+		t.Errorf("withDefaults() = %+v", c)
+	}
+}
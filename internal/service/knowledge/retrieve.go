@@ -0,0 +1,172 @@
+package knowledgeservice
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/vectorstore"
+)
+
+const (
+	// 用于检索的问题最多保留的字符数
+	maxQueryRunes = 2000
+	// 返回给客户端的引用片段字符数
+	snippetRunes = 200
+)
+
+// Passage 检索到的一段资料
+type Passage struct {
+	Chunk    domain.KnowledgeChunk
+	Document domain.KnowledgeDocument
+	Score    float32
+}
+
+// Retrieve 在知识库中检索与 query 相关的文本块，相似度低于配置的下限的不返回
+// 知识库可能使用不同的向量化模型，按模型分组检索后合并
+func (s *Service) Retrieve(ctx context.Context, knowledgeBaseIds []int64, query string, topK int) ([]Passage, error) {
+	query = strings.TrimSpace(truncateRunes(query, maxQueryRunes))
+	if len(knowledgeBaseIds) == 0 || query == "" {
+		return nil, nil
+	}
+	if topK <= 0 {
+		topK = s.config.TopK
+	}
+
+	var kbs []domain.KnowledgeBase
+	if err := s.db.WithContext(ctx).Where("id IN ?", knowledgeBaseIds).Find(&kbs).Error; err != nil {
+		return nil, err
+	}
+	groups := map[string][]int64{}
+	for _, kb := range kbs {
+		groups[kb.EmbeddingModel] = append(groups[kb.EmbeddingModel], kb.Id)
+	}
+
+	var hits []vectorstore.Hit
+	for model, ids := range groups {
+		vectors, err := s.embedder.Embed(ctx, model, []string{query})
+		if err != nil {
+			return nil, err
+		}
+		found, err := s.vectors.Search(ctx, ids, vectors[0], topK)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, found...)
+	}
+	hits = filterHits(hits, s.config.MinScore, topK)
+	if len(hits) == 0 {
+		return nil, nil
+	}
+	return s.loadPassages(ctx, hits)
+}
+
+// filterHits 去掉相似度过低的结果，按相似度从高到低保留前 topK 条
+func filterHits(hits []vectorstore.Hit, minScore float32, topK int) []vectorstore.Hit {
+	result := make([]vectorstore.Hit, 0, len(hits))
+	for _, hit := range hits {
+		if hit.Score >= minScore {
+			result = append(result, hit)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+	if len(result) > topK {
+		result = result[:topK]
+	}
+	return result
+}
+
+// loadPassages 从数据库读取检索结果的文本和所属文档，已经删除的文档不返回
+func (s *Service) loadPassages(ctx context.Context, hits []vectorstore.Hit) ([]Passage, error) {
+	chunkIds := make([]int64, len(hits))
+	docIds := make([]int64, len(hits))
+	for i, hit := range hits {
+		chunkIds[i] = hit.ID
+		docIds[i] = hit.DocumentID
+	}
+	var chunks []domain.KnowledgeChunk
+	if err := s.db.WithContext(ctx).Omit("embedding").Where("id IN ?", chunkIds).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	var docs []domain.KnowledgeDocument
+	if err := s.db.WithContext(ctx).Where("id IN ?", docIds).Find(&docs).Error; err != nil {
+		return nil, err
+	}
+	chunkMap := make(map[int64]domain.KnowledgeChunk, len(chunks))
+	for _, chunk := range chunks {
+		chunkMap[chunk.Id] = chunk
+	}
+	docMap := make(map[int64]domain.KnowledgeDocument, len(docs))
+	for _, doc := range docs {
+		docMap[doc.Id] = doc
+	}
+
+	passages := make([]Passage, 0, len(hits))
+	for _, hit := range hits {
+		chunk, ok := chunkMap[hit.ID]
+		if !ok {
+			continue
+		}
+		doc, ok := docMap[hit.DocumentID]
+		if !ok {
+			continue
+		}
+		passages = append(passages, Passage{Chunk: chunk, Document: doc, Score: hit.Score})
+	}
+	return passages, nil
+}
+
+// BuildContext 把检索到的资料组织成系统消息，要求模型用 [n] 标注引用的资料
+func BuildContext(passages []Passage) string {
+	if len(passages) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("以下是从知识库中检索到的资料，请优先依据这些资料回答用户的问题。")
+	sb.WriteString("引用资料时在相应内容后用 [编号] 标注来源，例如 [1]；资料与问题无关时忽略它们，资料中没有答案时如实说明，不要编造。\n")
+	for i, passage := range passages {
+		sb.WriteString(fmt.Sprintf("\n[%d] %s\n%s\n", i+1, sourceTitle(passage), passage.Chunk.Content))
+	}
+	return sb.String()
+}
+
+// sourceTitle 资料的来源，包含文档名称、章节和页码
+func sourceTitle(passage Passage) string {
+	parts := []string{"《" + passage.Document.Name + "》"}
+	if passage.Chunk.Section != "" {
+		parts = append(parts, passage.Chunk.Section)
+	}
+	switch {
+	case passage.Chunk.PageStart > 0 && passage.Chunk.PageEnd > passage.Chunk.PageStart:
+		parts = append(parts, fmt.Sprintf("第 %d-%d 页", passage.Chunk.PageStart, passage.Chunk.PageEnd))
+	case passage.Chunk.PageStart > 0:
+		parts = append(parts, fmt.Sprintf("第 %d 页", passage.Chunk.PageStart))
+	}
+	return strings.Join(parts, " ")
+}
+
+// References 转换为返回给客户端的引用列表，编号与 BuildContext 中的一致
+func References(passages []Passage) []dto.KnowledgeReference {
+	references := make([]dto.KnowledgeReference, len(passages))
+	for i, passage := range passages {
+		snippet := truncateRunes(passage.Chunk.Content, snippetRunes)
+		if snippet != passage.Chunk.Content {
+			snippet += "……"
+		}
+		references[i] = dto.KnowledgeReference{
+			Index:           i + 1,
+			KnowledgeBaseId: passage.Chunk.KnowledgeBaseID,
+			DocumentId:      passage.Document.Id,
+			FileId:          passage.Document.FileID,
+			Name:            passage.Document.Name,
+			Section:         passage.Chunk.Section,
+			PageStart:       passage.Chunk.PageStart,
+			PageEnd:         passage.Chunk.PageEnd,
+			Score:           passage.Score,
+			Snippet:         snippet,
+		}
+	}
+	return references
+}
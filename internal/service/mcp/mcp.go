@@ -0,0 +1,37 @@
+package mcpservice
+
+import (
+	"txing-ai/internal/domain"
+	"txing-ai/internal/tool/mcp"
+
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// ToMCPServerConfig 将 MCPServer 转换为MCP客户端使用的配置
+func ToMCPServerConfig(server *domain.MCPServer) mcp.MCPServerConfig {
+	return mcp.MCPServerConfig{
+		Name:          server.Name,
+		Transport:     server.Transport,
+		Command:       server.Command,
+		Args:          server.Args,
+		Environment:   server.Environment,
+		URL:           server.URL,
+		Headers:       server.Headers,
+		AuthToken:     server.AuthToken,
+		DisabledTools: server.DisabledTools,
+	}
+}
+
+// SyncMCPServers 将数据库中启用的MCP服务器同步到MCP客户端管理器，配置有变化的服务器会重新连接
+// 只同步当前实例，多实例部署时其他实例在重启后生效
+func SyncMCPServers(db *gorm.DB, manager *mcp.MCPClientManager) error {
+	var servers []domain.MCPServer
+	if err := db.Where("enabled = ?", true).Order("id").Find(&servers).Error; err != nil {
+		return err
+	}
+	configs := lo.Map(servers, func(server domain.MCPServer, _ int) mcp.MCPServerConfig {
+		return ToMCPServerConfig(&server)
+	})
+	return manager.SetManagedServers(configs)
+}
@@ -0,0 +1,63 @@
+package mcpservice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"txing-ai/internal/dto"
+	"txing-ai/internal/tool/mcp"
+)
+
+// 作为上下文的资源总长度上限（字符数），避免超出模型的上下文窗口
+const maxResourceContextLength = 100000
+
+// ReadResource 读取MCP资源，普通用户只能读取服务器列出的资源，管理员可以读取填充资源模板得到的任意地址
+func ReadResource(ctx context.Context, manager *mcp.MCPClientManager, server string, uri string, isAdmin bool) ([]mcp.ResourceContent, error) {
+	if isAdmin {
+		return manager.ReadResource(ctx, server, uri)
+	}
+	return manager.ReadListedResource(ctx, server, uri)
+}
+
+// BuildResourceContext 读取MCP资源，拼接为附加到用户输入后面的上下文，只支持文本资源
+func BuildResourceContext(ctx context.Context, manager *mcp.MCPClientManager, refs []dto.MCPResourceRef, isAdmin bool) (string, error) {
+	if len(refs) == 0 {
+		return "", nil
+	}
+	if manager == nil {
+		return "", fmt.Errorf("MCP服务未初始化")
+	}
+
+	var builder strings.Builder
+	builder.WriteString("以下是用户提供的参考资料：")
+	length := 0
+	for _, ref := range refs {
+		contents, err := ReadResource(ctx, manager, ref.Server, ref.URI, isAdmin)
+		if err != nil {
+			return "", err
+		}
+		for _, content := range contents {
+			if content.Text == "" {
+				if content.Blob != "" {
+					return "", fmt.Errorf("资源 %s 不是文本，无法作为上下文", content.URI)
+				}
+				continue
+			}
+			length += len([]rune(content.Text))
+			if length > maxResourceContextLength {
+				return "", fmt.Errorf("资源内容过长，最多 %d 个字符", maxResourceContextLength)
+			}
+			builder.WriteString(fmt.Sprintf("\n\n<resource uri=%q>\n%s\n</resource>", content.URI, content.Text))
+		}
+	}
+	return builder.String(), nil
+}
+
+// PromptToPresetContext 将MCP prompt 的消息拼接为预设上下文
+func PromptToPresetContext(messages []mcp.PromptMessage) string {
+	texts := make([]string, 0, len(messages))
+	for _, message := range messages {
+		texts = append(texts, message.Text)
+	}
+	return strings.Join(texts, "\n\n")
+}
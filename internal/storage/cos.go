@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"txing-ai/internal/global"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// COSStore 腾讯云对象存储
+type COSStore struct {
+	client    *cos.Client
+	secretId  string
+	secretKey string
+}
+
+// NewCOSStore 创建腾讯云对象存储，和头像等文件使用同一个存储桶
+func NewCOSStore(config *global.CosConfig) (*COSStore, error) {
+	bucketURL, err := url.Parse(config.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse bucket url failed: %v", err)
+	}
+	return newCOSStore(bucketURL, config.AccessKey, config.SecretKey), nil
+}
+
+func newCOSStore(bucketURL *url.URL, secretId string, secretKey string) *COSStore {
+	client := cos.NewClient(&cos.BaseURL{BucketURL: bucketURL}, &http.Client{
+		Transport: &cos.AuthorizationTransport{
+			SecretID:  secretId,
+			SecretKey: secretKey,
+		},
+	})
+	return &COSStore{client: client, secretId: secretId, secretKey: secretKey}
+}
+
+func (s *COSStore) Name() string {
+	return BackendCOS
+}
+
+func (s *COSStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	// 简单上传需要知道内容长度，长度未知时先写入临时文件
+	if size < 0 {
+		tmp, n, err := spool(r)
+		if err != nil {
+			return nil, err
+		}
+		defer removeSpool(tmp)
+		r, size = tmp, n
+	}
+	opt := &cos.ObjectPutOptions{ObjectPutHeaderOptions: &cos.ObjectPutHeaderOptions{
+		ContentType:   contentType,
+		ContentLength: size,
+	}}
+	if _, err := s.client.Object.Put(ctx, key, r, opt); err != nil {
+		return nil, cosError(err)
+	}
+	return s.Stat(ctx, key)
+}
+
+func (s *COSStore) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.client.Object.Get(ctx, key, nil)
+	if err != nil {
+		return nil, nil, cosError(err)
+	}
+	return resp.Body, cosObjectInfo(key, resp.Response), nil
+}
+
+func (s *COSStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Object.Head(ctx, key, nil)
+	if err != nil {
+		return nil, cosError(err)
+	}
+	return cosObjectInfo(key, resp.Response), nil
+}
+
+func (s *COSStore) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	if _, err := s.client.Object.Delete(ctx, key); err != nil && !cos.IsNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *COSStore) PresignGet(ctx context.Context, key string, expire time.Duration) (string, error) {
+	return s.presign(ctx, http.MethodGet, key, expire)
+}
+
+func (s *COSStore) PresignPut(ctx context.Context, key string, expire time.Duration) (string, error) {
+	return s.presign(ctx, http.MethodPut, key, expire)
+}
+
+func (s *COSStore) presign(ctx context.Context, method string, key string, expire time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	u, err := s.client.Object.GetPresignedURL(ctx, method, key, s.secretId, s.secretKey, expire, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func cosError(err error) error {
+	if cos.IsNotFoundError(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func cosObjectInfo(key string, resp *http.Response) *ObjectInfo {
+	info := &ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = modified
+	}
+	return info
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStore 保存在本地目录中的文件，多副本部署时需要挂载共享目录
+type LocalStore struct {
+	// 存储目录（绝对路径）
+	dir string
+}
+
+// NewLocalStore 创建本地存储，和上传目录的约定一致，dir 总是相对于工作目录（以 / 开头也一样）
+func NewLocalStore(dir string) (*LocalStore, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return &LocalStore{dir: filepath.Join(currentDir, dir)}, nil
+}
+
+func (s *LocalStore) Name() string {
+	return BackendLocal
+}
+
+// Dir 存储目录
+func (s *LocalStore) Dir() string {
+	return s.dir
+}
+
+// KeyOf 获取存储目录中的文件对应的对象路径，文件不在存储目录中时返回 false
+func (s *LocalStore) KeyOf(filePath string) (string, bool) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(s.dir, absPath)
+	if err != nil {
+		return "", false
+	}
+	key, err := cleanKey(filepath.ToSlash(rel))
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// path 对象在磁盘上的路径
+func (s *LocalStore) path(key string) (string, string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return key, filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put 先写入同目录下的临时文件再重命名，读取方不会看到写了一半的文件
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error) {
+	key, fullPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, contextReader{ctx: ctx, r: r}); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return nil, err
+	}
+	return s.Stat(ctx, key)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	key, fullPath, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, nil, localError(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, nil, ErrNotFound
+	}
+	return file, localObjectInfo(key, info), nil
+}
+
+func (s *LocalStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	key, fullPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, localError(err)
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+	return localObjectInfo(key, info), nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	_, fullPath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// PresignGet 本地存储只能通过服务端下载
+func (s *LocalStore) PresignGet(ctx context.Context, key string, expire time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+// PresignPut 本地存储只能通过服务端上传
+func (s *LocalStore) PresignPut(ctx context.Context, key string, expire time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+func localError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func localObjectInfo(key string, info fs.FileInfo) *ObjectInfo {
+	contentType := mime.TypeByExtension(strings.ToLower(path.Ext(key)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  contentType,
+		LastModified: info.ModTime(),
+	}
+}
+
+// contextReader 读取时检查 context 是否已经取消，用于中断大文件的写入
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"txing-ai/internal/global"
+)
+
+// 存储后端名称，保存在文件表的 storage 字段中
+const (
+	BackendLocal = "local"
+	BackendCOS   = "cos"
+	BackendS3    = "s3"
+)
+
+var (
+	ErrNotFound            = errors.New("对象不存在")
+	ErrInvalidKey          = errors.New("非法的对象路径")
+	ErrPresignNotSupported = errors.New("存储后端不支持预签名")
+)
+
+// ObjectInfo 对象的元信息
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// BlobStore 文件存储，对象路径使用 / 分隔
+type BlobStore interface {
+	// Name 存储后端名称
+	Name() string
+	// Put 流式写入对象，size 小于 0 表示长度未知
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*ObjectInfo, error)
+	// Get 读取对象，调用方负责关闭返回的 ReadCloser，对象不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Stat 获取对象的元信息，对象不存在时返回 ErrNotFound
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// PresignGet 生成下载地址，不支持时返回 ErrPresignNotSupported
+	PresignGet(ctx context.Context, key string, expire time.Duration) (string, error)
+	// PresignPut 生成上传地址，不支持时返回 ErrPresignNotSupported
+	PresignPut(ctx context.Context, key string, expire time.Duration) (string, error)
+}
+
+// cleanKey 校验并规范化对象路径，不能是绝对路径，也不能包含 ..
+func cleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	key = path.Clean(key)
+	if key == "." {
+		return "", ErrInvalidKey
+	}
+	return key, nil
+}
+
+// spool 将长度未知的内容写入临时文件，返回定位到开头的文件和内容长度，用完后调用 removeSpool 删除
+func spool(r io.Reader) (*os.File, int64, error) {
+	tmp, err := os.CreateTemp("", "blob-upload-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := io.Copy(tmp, r)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		removeSpool(tmp)
+		return nil, 0, err
+	}
+	return tmp, size, nil
+}
+
+func removeSpool(tmp *os.File) {
+	tmp.Close()
+	os.Remove(tmp.Name())
+}
+
+// Stores 已配置的存储后端，新文件保存到默认后端，读取时使用文件记录的后端
+type Stores struct {
+	def    BlobStore
+	stores map[string]BlobStore
+	prefix string
+}
+
+// NewStores 使用 def 作为默认后端，其他后端用于读取之前保存的文件
+func NewStores(def BlobStore, others ...BlobStore) *Stores {
+	s := &Stores{def: def, stores: map[string]BlobStore{def.Name(): def}}
+	for _, store := range others {
+		if _, ok := s.stores[store.Name()]; !ok {
+			s.stores[store.Name()] = store
+		}
+	}
+	return s
+}
+
+// NewStoresFromConfig 根据配置创建存储后端，本地存储始终可用，配置了的对象存储也会打开以便读取旧文件
+func NewStoresFromConfig(config *global.AppConfig) (*Stores, error) {
+	backend := BackendLocal
+	prefix := ""
+	if config.StorageConfig != nil {
+		if config.StorageConfig.Backend != "" {
+			backend = config.StorageConfig.Backend
+		}
+		prefix = config.StorageConfig.Prefix
+	}
+
+	dir := ""
+	if config.LocalUploadConfig != nil {
+		dir = config.LocalUploadConfig.Dir
+	}
+	local, err := NewLocalStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	stores := []BlobStore{local}
+	if config.CosConfig != nil && config.CosConfig.BaseURL != "" && config.CosConfig.AccessKey != "" {
+		cosStore, err := NewCOSStore(config.CosConfig)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, cosStore)
+	}
+	if config.AWSConfig != nil && config.AWSConfig.Bucket != "" {
+		s3Store, err := NewS3Store(config.AWSConfig)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, s3Store)
+	}
+
+	for i, store := range stores {
+		if store.Name() == backend {
+			stores[0], stores[i] = stores[i], stores[0]
+			s := NewStores(stores[0], stores[1:]...)
+			s.prefix = prefix
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("存储后端 %s 不存在或者没有配置", backend)
+}
+
+// Default 保存新文件使用的后端
+func (s *Stores) Default() BlobStore {
+	return s.def
+}
+
+// Get 按名称获取存储后端
+func (s *Stores) Get(name string) (BlobStore, error) {
+	store, ok := s.stores[name]
+	if !ok {
+		return nil, fmt.Errorf("存储后端 %s 不可用", name)
+	}
+	return store, nil
+}
+
+// Key 为新文件生成带路径前缀的对象路径
+func (s *Stores) Key(parts ...string) string {
+	return path.Join(append([]string{s.prefix}, parts...)...)
+}
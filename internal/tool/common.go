@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"go.uber.org/zap"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
-	"txing-ai/internal/utils"
 )
 
 func buildSaveDir(ctx context.Context) (string, error) {
@@ -23,7 +25,7 @@ func buildSaveDir(ctx context.Context) (string, error) {
 
 	savePath := currentDir
 	savePath = filepath.Join(savePath, localUploadConfig.Dir)
-	userId, exist := utils.GetUIDFromContextAllowEmpty(ctx)
+	userId, exist := userIdFromContext(ctx)
 	if exist {
 		savePath = filepath.Join(savePath, fmt.Sprintf("%d", userId))
 	}
@@ -36,3 +38,39 @@ func buildSaveDir(ctx context.Context) (string, error) {
 	}
 	return savePath, nil
 }
+
+// saveDocument 在保存目录中创建文件并写入内容，文件名会加上时间戳和扩展名
+// 保存后的文件会登记到文件表，返回保存后的文件名和下载地址
+func saveDocument(ctx context.Context, toolName string, filename string, ext string, write func(w io.Writer) error) (string, string, error) {
+	savePath, err := buildSaveDir(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("构建保存目录失败: %w", err)
+	}
+
+	// 文件名不能包含目录，去掉重复的扩展名
+	name := strings.TrimSuffix(filepath.Base(strings.TrimSpace(filename)), ext)
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "document"
+	}
+	filename = fmt.Sprintf("%s_%d%s", name, time.Now().UnixNano(), ext)
+	fullPath := filepath.Join(savePath, filename)
+	file, err := os.Create(fullPath)
+	if err != nil {
+		return "", "", fmt.Errorf("创建文件失败: %w", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(fullPath)
+		return "", "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(fullPath)
+		return "", "", fmt.Errorf("保存文件失败: %w", err)
+	}
+	return filename, registerOutputFile(ctx, toolName, fullPath), nil
+}
+
+// downloadURL 按文件名下载的地址，只能下载当天生成的文件，用于无法登记文件时的兼容
+func downloadURL(filename string) string {
+	return fmt.Sprintf("/api/file/download?filePath=%s", url.QueryEscape(filename))
+}
@@ -0,0 +1,72 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/tool/ingest"
+
+	"go.uber.org/zap"
+)
+
+// DocumentReadParams 文档读取参数
+type DocumentReadParams struct {
+	FilePath string `json:"file_path" jsonschema:"description=要读取的文档路径，支持 PDF、Word(docx)、Markdown、HTML 和纯文本"`
+}
+
+// ReadDocumentText 读取用户上传的文档，返回规范化后的全文，PDF 按页加上页码标记，解析中遇到的问题附在最后
+func ReadDocumentText(ctx context.Context, params *DocumentReadParams) (string, error) {
+	if !isPathAllowed(ctx, params.FilePath) {
+		log.Error("不允许访问该路径", zap.String("path", params.FilePath))
+		return "没有权限访问该路径", nil
+	}
+	if _, err := os.Stat(params.FilePath); os.IsNotExist(err) {
+		log.Error("文件不存在", zap.String("path", params.FilePath))
+		return fmt.Sprintf("文件不存在: %s", params.FilePath), nil
+	}
+	if !ingest.Supported(params.FilePath) {
+		return fmt.Sprintf("不支持的文档格式: %s", filepath.Ext(params.FilePath)), nil
+	}
+
+	doc, err := ingest.ParseFile(ctx, params.FilePath, nil)
+	if err != nil {
+		log.Error("解析文档失败", zap.String("path", params.FilePath), zap.Error(err))
+		return fmt.Sprintf("解析文档失败: %v", err), nil
+	}
+	for _, warning := range doc.Warnings {
+		log.Warn("解析文档时出现问题", zap.String("path", params.FilePath), zap.String("warning", warning))
+	}
+	return FormatDocument(doc), nil
+}
+
+// FormatDocument 将解析后的文档格式化为提供给模型的文本
+func FormatDocument(doc *ingest.Document) string {
+	var sb strings.Builder
+	if doc.Pages == 0 {
+		sb.WriteString(doc.Text())
+	} else {
+		page := 0
+		for _, block := range doc.Blocks {
+			if block.Page != page {
+				if page != 0 {
+					sb.WriteString("\n\n")
+				}
+				page = block.Page
+				sb.WriteString(fmt.Sprintf("--- 第 %d 页 ---\n", page))
+			} else {
+				sb.WriteString("\n\n")
+			}
+			if block.Level > 0 {
+				sb.WriteString(strings.Repeat("#", block.Level) + " ")
+			}
+			sb.WriteString(block.Text)
+		}
+	}
+	if len(doc.Warnings) > 0 {
+		sb.WriteString("\n\n注意：" + strings.Join(doc.Warnings, "；"))
+	}
+	return sb.String()
+}
@@ -3,67 +3,35 @@ package tool
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"sort"
 	"strings"
 	"txing-ai/internal/global/logging/log"
 
 	"go.uber.org/zap"
 )
 
-// 允许操作的目录列表
-var allowedDirectories = []string{
-	"runtime/temp",
-}
-
-// 检查文件路径是否在允许的目录中
-func isPathAllowed(path string) bool {
-	// 获取当前工作目录
-	currentDir, err := os.Getwd()
-	if err != nil {
-		log.Error("获取当前工作目录失败", zap.Error(err))
-		return false
-	}
-
-	// 规范化路径
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		log.Error("获取绝对路径失败", zap.Error(err))
-		return false
-	}
-
-	// 检查路径是否在允许的目录中
-	for _, dir := range allowedDirectories {
-		allowedPath := filepath.Join(currentDir, dir)
-		if strings.HasPrefix(absPath, allowedPath) {
-			return true
-		}
-	}
-
-	return false
-}
+// 文件工具只能操作本次运行的工作区，路径为相对于工作区根目录的路径
+// 工作区在运行结束后删除，需要交付给用户的文件应使用 markdown_save_tool 等工具保存
 
 // 文件读取参数
 type fileReadParams struct {
-	FilePath string `json:"file_path" jsonschema:"description=要读取的文件路径"`
+	FilePath string `json:"file_path" jsonschema:"description=要读取的文件路径（相对于工作区）"`
 }
 
 // 读取文件内容
 func readFile(ctx context.Context, params *fileReadParams) (string, error) {
-	// 检查路径是否允许
-	if !isPathAllowed(params.FilePath) {
-		return "", fmt.Errorf("不允许访问该路径: %s", params.FilePath)
+	workspace, err := workspaceFromContext(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(params.FilePath); os.IsNotExist(err) {
+	content, err := workspace.ReadFile(params.FilePath)
+	if errors.Is(err, fs.ErrNotExist) {
 		return "", fmt.Errorf("文件不存在: %s", params.FilePath)
 	}
-
-	// 读取文件内容
-	content, err := ioutil.ReadFile(params.FilePath)
 	if err != nil {
 		log.Error("读取文件失败", zap.String("path", params.FilePath), zap.Error(err))
 		return "", fmt.Errorf("读取文件失败: %v", err)
@@ -74,27 +42,18 @@ func readFile(ctx context.Context, params *fileReadParams) (string, error) {
 
 // 文件写入参数
 type fileWriteParams struct {
-	FilePath string `json:"file_path" jsonschema:"description=要写入的文件路径"`
+	FilePath string `json:"file_path" jsonschema:"description=要写入的文件路径（相对于工作区）"`
 	Content  string `json:"content" jsonschema:"description=要写入的文件内容"`
 }
 
 // 写入文件内容
 func writeFile(ctx context.Context, params *fileWriteParams) (string, error) {
-	// 检查路径是否允许
-	if !isPathAllowed(params.FilePath) {
-		return "", fmt.Errorf("不允许访问该路径: %s", params.FilePath)
-	}
-
-	// 确保目录存在
-	dir := filepath.Dir(params.FilePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Error("创建目录失败", zap.String("dir", dir), zap.Error(err))
-		return "", fmt.Errorf("创建目录失败: %v", err)
+	workspace, err := workspaceFromContext(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	// 写入文件
-	err := ioutil.WriteFile(params.FilePath, []byte(params.Content), 0644)
-	if err != nil {
+	if err := workspace.WriteFile(params.FilePath, []byte(params.Content)); err != nil {
 		log.Error("写入文件失败", zap.String("path", params.FilePath), zap.Error(err))
 		return "", fmt.Errorf("写入文件失败: %v", err)
 	}
@@ -104,36 +63,33 @@ func writeFile(ctx context.Context, params *fileWriteParams) (string, error) {
 
 // 文件内容替换参数
 type fileReplaceParams struct {
-	FilePath string `json:"file_path" jsonschema:"description=要替换内容的文件路径"`
+	FilePath string `json:"file_path" jsonschema:"description=要替换内容的文件路径（相对于工作区）"`
 	OldText  string `json:"old_text" jsonschema:"description=要替换的文本"`
 	NewText  string `json:"new_text" jsonschema:"description=替换后的文本"`
 }
 
 // 替换文件内容
 func replaceFileContent(ctx context.Context, params *fileReplaceParams) (string, error) {
-	// 检查路径是否允许
-	if !isPathAllowed(params.FilePath) {
-		return "", fmt.Errorf("不允许访问该路径: %s", params.FilePath)
+	workspace, err := workspaceFromContext(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(params.FilePath); os.IsNotExist(err) {
+	content, err := workspace.ReadFile(params.FilePath)
+	if errors.Is(err, fs.ErrNotExist) {
 		return "", fmt.Errorf("文件不存在: %s", params.FilePath)
 	}
-
-	// 读取文件内容
-	content, err := ioutil.ReadFile(params.FilePath)
 	if err != nil {
 		log.Error("读取文件失败", zap.String("path", params.FilePath), zap.Error(err))
 		return "", fmt.Errorf("读取文件失败: %v", err)
 	}
+	if params.OldText == "" || !strings.Contains(string(content), params.OldText) {
+		return "", fmt.Errorf("文件中没有找到要替换的文本")
+	}
 
 	// 替换内容
-	newContent := strings.Replace(string(content), params.OldText, params.NewText, -1)
-
-	// 写入文件
-	err = ioutil.WriteFile(params.FilePath, []byte(newContent), 0644)
-	if err != nil {
+	newContent := strings.ReplaceAll(string(content), params.OldText, params.NewText)
+	if err := workspace.WriteFile(params.FilePath, []byte(newContent)); err != nil {
 		log.Error("写入文件失败", zap.String("path", params.FilePath), zap.Error(err))
 		return "", fmt.Errorf("写入文件失败: %v", err)
 	}
@@ -143,7 +99,7 @@ func replaceFileContent(ctx context.Context, params *fileReplaceParams) (string,
 
 // 文件列表参数
 type fileListParams struct {
-	DirPath string `json:"dir_path" jsonschema:"description=要列出文件的目录路径"`
+	DirPath string `json:"dir_path" jsonschema:"description=要列出文件的目录路径（相对于工作区），为空表示工作区根目录"`
 }
 
 // 文件信息结构
@@ -156,37 +112,33 @@ type fileInfo struct {
 
 // 列出目录中的文件
 func listFiles(ctx context.Context, params *fileListParams) (string, error) {
-	// 检查路径是否允许
-	if !isPathAllowed(params.DirPath) {
-		return "", fmt.Errorf("不允许访问该路径: %s", params.DirPath)
+	workspace, err := workspaceFromContext(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	// 检查目录是否存在
-	file, err := os.Stat(params.DirPath)
-	if os.IsNotExist(err) {
+	entries, err := workspace.ReadDir(params.DirPath)
+	if errors.Is(err, fs.ErrNotExist) {
 		return "", fmt.Errorf("目录不存在: %s", params.DirPath)
 	}
-
-	// 确保是目录
-	if !file.IsDir() {
-		return "", fmt.Errorf("指定路径不是目录: %s", params.DirPath)
-	}
-
-	// 读取目录内容
-	files, err := ioutil.ReadDir(params.DirPath)
 	if err != nil {
 		log.Error("读取目录失败", zap.String("path", params.DirPath), zap.Error(err))
 		return "", fmt.Errorf("读取目录失败: %v", err)
 	}
 
-	// 构建文件信息列表
-	fileInfoList := make([]fileInfo, 0, len(files))
-	for _, file := range files {
+	// 构建文件信息列表，按名称排序
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	fileInfoList := make([]fileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
 		fileInfoList = append(fileInfoList, fileInfo{
-			Name:      file.Name(),
-			IsDir:     file.IsDir(),
-			Size:      file.Size(),
-			UpdatedAt: file.ModTime().Format("2006-01-02 15:04:05"),
+			Name:      entry.Name(),
+			IsDir:     entry.IsDir(),
+			Size:      info.Size(),
+			UpdatedAt: info.ModTime().Format("2006-01-02 15:04:05"),
 		})
 	}
 
@@ -202,23 +154,20 @@ func listFiles(ctx context.Context, params *fileListParams) (string, error) {
 
 // 文件删除参数
 type fileDeleteParams struct {
-	FilePath string `json:"file_path" jsonschema:"description=要删除的文件路径"`
+	FilePath string `json:"file_path" jsonschema:"description=要删除的文件路径（相对于工作区）"`
 }
 
 // 删除文件
 func deleteFile(ctx context.Context, params *fileDeleteParams) (string, error) {
-	// 检查路径是否允许
-	if !isPathAllowed(params.FilePath) {
-		return "", fmt.Errorf("不允许访问该路径: %s", params.FilePath)
+	workspace, err := workspaceFromContext(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(params.FilePath); os.IsNotExist(err) {
+	err = workspace.Remove(params.FilePath)
+	if errors.Is(err, fs.ErrNotExist) {
 		return "", fmt.Errorf("文件不存在: %s", params.FilePath)
 	}
-
-	// 删除文件
-	err := os.Remove(params.FilePath)
 	if err != nil {
 		log.Error("删除文件失败", zap.String("path", params.FilePath), zap.Error(err))
 		return "", fmt.Errorf("删除文件失败: %v", err)
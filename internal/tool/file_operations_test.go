@@ -2,147 +2,68 @@ package tool
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
-// 测试前准备工作
-func setupTestEnvironment() (string, string, string, error) {
-	// 获取当前工作目录
-	currentDir, err := os.Getwd()
+// 测试前准备工作：创建工作区并写入测试文件，工作区外创建不允许访问的文件
+func setupTestEnvironment(t *testing.T) (context.Context, *Workspace) {
+	baseDir := t.TempDir()
+	workspace, err := newWorkspace(workspaceSettings{
+		dir:         filepath.Join(baseDir, "workspace"),
+		userQuota:   1024,
+		maxFileSize: 512,
+		ttl:         time.Hour,
+	}, 1)
 	if err != nil {
-		return "", "", "", err
+		t.Fatalf("创建工作区失败: %v", err)
 	}
+	t.Cleanup(func() { _ = workspace.Close() })
 
-	// 创建测试目录
-	testDir := filepath.Join(currentDir, "runtime", "test")
-	if err := os.MkdirAll(testDir, 0755); err != nil {
-		return "", "", "", err
+	if err := workspace.WriteFile("test.txt", []byte("这是测试文件内容")); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
 	}
-
-	// 创建测试文件
-	testFilePath := filepath.Join(testDir, "test.txt")
-	testContent := "这是测试文件内容"
-	if err := os.WriteFile(testFilePath, []byte(testContent), 0644); err != nil {
-		return "", "", "", err
+	if err := os.WriteFile(filepath.Join(baseDir, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
 	}
-
-	// 创建不允许访问的测试目录
-	invalidDir := filepath.Join(currentDir, "invalid_dir")
-	if err := os.MkdirAll(invalidDir, 0755); err != nil {
-		return "", "", "", err
+	// 指向工作区外的符号链接
+	if err := os.Symlink(filepath.Join(baseDir, "secret.txt"), filepath.Join(workspace.Dir(), "link.txt")); err != nil {
+		t.Fatalf("创建符号链接失败: %v", err)
 	}
-
-	return testDir, testFilePath, testContent, nil
-}
-
-// 测试后清理工作
-func cleanupTestEnvironment(testDir string, invalidDir string) {
-	os.RemoveAll(testDir)
-	os.RemoveAll(invalidDir)
-}
-
-func Test_isPathAllowed(t *testing.T) {
-	currentDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("获取当前工作目录失败: %v", err)
+	if err := os.Symlink(baseDir, filepath.Join(workspace.Dir(), "link_dir")); err != nil {
+		t.Fatalf("创建符号链接失败: %v", err)
 	}
 
-	tests := []struct {
-		name string
-		path string
-		want bool
-	}{
-		{
-			name: "允许访问runtime目录",
-			path: "runtime/test.txt",
-			want: true,
-		},
-		{
-			name: "允许访问data目录",
-			path: filepath.Join(currentDir, "data", "test.txt"),
-			want: true,
-		},
-		{
-			name: "允许访问temp目录",
-			path: filepath.Join(currentDir, "temp", "test.txt"),
-			want: true,
-		},
-		{
-			name: "不允许访问根目录",
-			path: filepath.Join(currentDir, "test.txt"),
-			want: false,
-		},
-		{
-			name: "不允许访问上级目录",
-			path: filepath.Join(currentDir, "..", "test.txt"),
-			want: false,
-		},
-		{
-			name: "不允许访问其他目录",
-			path: filepath.Join(currentDir, "other", "test.txt"),
-			want: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := isPathAllowed(tt.path); got != tt.want {
-				t.Errorf("isPathAllowed() = %v, want %v", got, tt.want)
-			}
-		})
-	}
+	ctx := WithToolContext(context.Background(), &ToolContext{UserId: 1, Workspace: workspace})
+	return ctx, workspace
 }
 
 func Test_readFile(t *testing.T) {
-	testDir, testFilePath, testContent, err := setupTestEnvironment()
-	if err != nil {
-		t.Fatalf("设置测试环境失败: %v", err)
-	}
-	defer cleanupTestEnvironment(testDir, filepath.Join(filepath.Dir(testDir), "invalid_dir"))
+	ctx, workspace := setupTestEnvironment(t)
 
-	currentDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("获取当前工作目录失败: %v", err)
-	}
-
-	ctx := context.Background()
 	tests := []struct {
 		name    string
-		params  *fileReadParams
+		ctx     context.Context
+		path    string
 		want    string
 		wantErr bool
 	}{
-		{
-			name: "成功读取文件",
-			params: &fileReadParams{
-				FilePath: testFilePath,
-			},
-			want:    testContent,
-			wantErr: false,
-		},
-		{
-			name: "文件不存在",
-			params: &fileReadParams{
-				FilePath: filepath.Join(testDir, "not_exist.txt"),
-			},
-			want:    "",
-			wantErr: true,
-		},
-		{
-			name: "路径不允许访问",
-			params: &fileReadParams{
-				FilePath: filepath.Join(currentDir, "invalid_dir", "test.txt"),
-			},
-			want:    "",
-			wantErr: true,
-		},
+		{name: "成功读取文件", ctx: ctx, path: "test.txt", want: "这是测试文件内容"},
+		{name: "工作区内的绝对路径", ctx: ctx, path: filepath.Join(workspace.Dir(), "test.txt"), want: "这是测试文件内容"},
+		{name: "文件不存在", ctx: ctx, path: "not_exist.txt", wantErr: true},
+		{name: "上级目录", ctx: ctx, path: "../secret.txt", wantErr: true},
+		{name: "工作区外的绝对路径", ctx: ctx, path: filepath.Join(filepath.Dir(workspace.Dir()), "secret.txt"), wantErr: true},
+		{name: "符号链接指向工作区外", ctx: ctx, path: "link.txt", wantErr: true},
+		{name: "通过符号链接目录逃逸", ctx: ctx, path: "link_dir/secret.txt", wantErr: true},
+		{name: "没有工作区", ctx: context.Background(), path: "test.txt", wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := readFile(ctx, tt.params)
+			got, err := readFile(tt.ctx, &fileReadParams{FilePath: tt.path})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("readFile() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -155,61 +76,32 @@ func Test_readFile(t *testing.T) {
 }
 
 func Test_writeFile(t *testing.T) {
-	testDir, _, _, err := setupTestEnvironment()
-	if err != nil {
-		t.Fatalf("设置测试环境失败: %v", err)
-	}
-	defer cleanupTestEnvironment(testDir, filepath.Join(filepath.Dir(testDir), "invalid_dir"))
-
-	currentDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("获取当前工作目录失败: %v", err)
-	}
-
-	ctx := context.Background()
-	newFilePath := filepath.Join(testDir, "new_file.txt")
-	newContent := "这是新文件内容"
+	ctx, workspace := setupTestEnvironment(t)
 
 	tests := []struct {
 		name    string
 		params  *fileWriteParams
-		want    string
 		wantErr bool
 	}{
-		{
-			name: "成功写入文件",
-			params: &fileWriteParams{
-				FilePath: newFilePath,
-				Content:  newContent,
-			},
-			want:    "文件已成功写入: " + newFilePath,
-			wantErr: false,
-		},
-		{
-			name: "路径不允许访问",
-			params: &fileWriteParams{
-				FilePath: filepath.Join(currentDir, "invalid_dir", "test.txt"),
-				Content:  "不允许写入",
-			},
-			want:    "",
-			wantErr: true,
-		},
+		{name: "成功写入文件", params: &fileWriteParams{FilePath: "new_file.txt", Content: "这是新文件内容"}},
+		{name: "自动创建父目录", params: &fileWriteParams{FilePath: "a/b/c.txt", Content: "内容"}},
+		{name: "覆盖已有文件", params: &fileWriteParams{FilePath: "test.txt", Content: "覆盖"}},
+		{name: "上级目录", params: &fileWriteParams{FilePath: "../escape.txt", Content: "不允许写入"}, wantErr: true},
+		{name: "通过符号链接目录逃逸", params: &fileWriteParams{FilePath: "link_dir/escape.txt", Content: "不允许写入"}, wantErr: true},
+		{name: "超过单文件大小", params: &fileWriteParams{FilePath: "large.txt", Content: string(make([]byte, 513))}, wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := writeFile(ctx, tt.params)
+			_, err := writeFile(ctx, tt.params)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("writeFile() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
-				t.Errorf("writeFile() got = %v, want %v", got, tt.want)
-			}
 
 			// 验证文件是否成功写入
 			if !tt.wantErr {
-				content, err := os.ReadFile(tt.params.FilePath)
+				content, err := os.ReadFile(filepath.Join(workspace.Dir(), tt.params.FilePath))
 				if err != nil {
 					t.Errorf("读取写入的文件失败: %v", err)
 					return
@@ -220,23 +112,13 @@ func Test_writeFile(t *testing.T) {
 			}
 		})
 	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(workspace.Dir()), "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("文件不应该写到工作区以外")
+	}
 }
 
 func Test_replaceFileContent(t *testing.T) {
-	testDir, testFilePath, testContent, err := setupTestEnvironment()
-	if err != nil {
-		t.Fatalf("设置测试环境失败: %v", err)
-	}
-	defer cleanupTestEnvironment(testDir, filepath.Join(filepath.Dir(testDir), "invalid_dir"))
-
-	currentDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("获取当前工作目录失败: %v", err)
-	}
-
-	ctx := context.Background()
-	oldText := "测试文件"
-	newText := "替换后的文件"
+	ctx, workspace := setupTestEnvironment(t)
 
 	tests := []struct {
 		name    string
@@ -244,186 +126,97 @@ func Test_replaceFileContent(t *testing.T) {
 		want    string
 		wantErr bool
 	}{
-		{
-			name: "成功替换文件内容",
-			params: &fileReplaceParams{
-				FilePath: testFilePath,
-				OldText:  oldText,
-				NewText:  newText,
-			},
-			want:    "文件内容已成功替换: " + testFilePath,
-			wantErr: false,
-		},
-		{
-			name: "文件不存在",
-			params: &fileReplaceParams{
-				FilePath: filepath.Join(testDir, "not_exist.txt"),
-				OldText:  oldText,
-				NewText:  newText,
-			},
-			want:    "",
-			wantErr: true,
-		},
-		{
-			name: "路径不允许访问",
-			params: &fileReplaceParams{
-				FilePath: filepath.Join(currentDir, "invalid_dir", "test.txt"),
-				OldText:  oldText,
-				NewText:  newText,
-			},
-			want:    "",
-			wantErr: true,
-		},
+		{name: "成功替换文件内容", params: &fileReplaceParams{FilePath: "test.txt", OldText: "测试文件", NewText: "替换后的文件"}, want: "这是替换后的文件内容"},
+		{name: "没有找到要替换的文本", params: &fileReplaceParams{FilePath: "test.txt", OldText: "不存在", NewText: "x"}, wantErr: true},
+		{name: "文件不存在", params: &fileReplaceParams{FilePath: "not_exist.txt", OldText: "a", NewText: "b"}, wantErr: true},
+		{name: "符号链接指向工作区外", params: &fileReplaceParams{FilePath: "link.txt", OldText: "secret", NewText: "x"}, wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// 如果是成功替换的测试，先确保文件内容包含要替换的文本
-			if tt.name == "成功替换文件内容" {
-				err := os.WriteFile(testFilePath, []byte(testContent), 0644)
-				if err != nil {
-					t.Fatalf("准备测试文件失败: %v", err)
-				}
-			}
-
-			got, err := replaceFileContent(ctx, tt.params)
+			_, err := replaceFileContent(ctx, tt.params)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("replaceFileContent() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
-				t.Errorf("replaceFileContent() got = %v, want %v", got, tt.want)
-			}
-
-			// 验证文件内容是否成功替换
-			if !tt.wantErr && tt.name == "成功替换文件内容" {
-				_, err := os.ReadFile(tt.params.FilePath)
-				if err != nil {
-					t.Errorf("读取替换后的文件失败: %v", err)
-					return
-				}
-				if oldText != "" && newText != "" {
-
-					if oldText != "" && newText != "" {
-
-					}
+			if !tt.wantErr {
+				content, _ := workspace.ReadFile(tt.params.FilePath)
+				if string(content) != tt.want {
+					t.Errorf("文件内容不匹配, got = %v, want %v", string(content), tt.want)
 				}
 			}
 		})
 	}
 }
 
-func Test_deleteFile(t *testing.T) {
-	testDir, testFilePath, _, err := setupTestEnvironment()
-	if err != nil {
-		t.Fatalf("设置测试环境失败: %v", err)
-	}
-	defer cleanupTestEnvironment(testDir, filepath.Join(filepath.Dir(testDir), "invalid_dir"))
-
-	currentDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("获取当前工作目录失败: %v", err)
+func Test_listFiles(t *testing.T) {
+	ctx, workspace := setupTestEnvironment(t)
+	if err := workspace.WriteFile("dir/a.txt", []byte("a")); err != nil {
+		t.Fatal(err)
 	}
 
-	ctx := context.Background()
 	tests := []struct {
-		name    string
-		params  *fileDeleteParams
-		want    string
-		wantErr bool
+		name      string
+		dirPath   string
+		wantNames []string
+		wantErr   bool
 	}{
-		{
-			name: "成功删除文件",
-			params: &fileDeleteParams{
-				FilePath: testFilePath,
-			},
-			want:    "文件已成功删除: " + testFilePath,
-			wantErr: false,
-		},
-		{
-			name: "文件不存在",
-			params: &fileDeleteParams{
-				FilePath: filepath.Join(testDir, "not_exist.txt"),
-			},
-			want:    "",
-			wantErr: true,
-		},
-		{
-			name: "路径不允许访问",
-			params: &fileDeleteParams{
-				FilePath: filepath.Join(currentDir, "invalid_dir", "test.txt"),
-			},
-			want:    "",
-			wantErr: true,
-		},
+		{name: "工作区根目录", dirPath: "", wantNames: []string{"dir", "link.txt", "link_dir", "test.txt"}},
+		{name: "子目录", dirPath: "dir", wantNames: []string{"a.txt"}},
+		{name: "目录不存在", dirPath: "not_exist", wantErr: true},
+		{name: "上级目录", dirPath: "..", wantErr: true},
+		{name: "符号链接目录", dirPath: "link_dir", wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// 如果是成功删除的测试，先确保文件存在
-			if tt.name == "成功删除文件" {
-				if _, err := os.Stat(testFilePath); os.IsNotExist(err) {
-					// 重新创建测试文件
-					err := os.WriteFile(testFilePath, []byte("测试内容"), 0644)
-					if err != nil {
-						t.Fatalf("准备测试文件失败: %v", err)
-					}
-				}
-			}
-
-			got, err := deleteFile(ctx, tt.params)
+			got, err := listFiles(ctx, &fileListParams{DirPath: tt.dirPath})
 			if (err != nil) != tt.wantErr {
-				t.Errorf("deleteFile() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("listFiles() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
-				t.Errorf("deleteFile() got = %v, want %v", got, tt.want)
+			if tt.wantErr {
+				return
 			}
-
-			// 验证文件是否成功删除
-			if !tt.wantErr && tt.name == "成功删除文件" {
-				_, err := os.Stat(tt.params.FilePath)
-				if !os.IsNotExist(err) {
-					t.Errorf("文件应该已被删除，但仍然存在")
+			var files []fileInfo
+			if err := json.Unmarshal([]byte(got), &files); err != nil {
+				t.Fatalf("解析文件列表失败: %v", err)
+			}
+			if len(files) != len(tt.wantNames) {
+				t.Fatalf("listFiles() got %d files, want %d: %s", len(files), len(tt.wantNames), got)
+			}
+			for i, file := range files {
+				if file.Name != tt.wantNames[i] {
+					t.Errorf("listFiles()[%d] = %s, want %s", i, file.Name, tt.wantNames[i])
 				}
 			}
 		})
 	}
 }
 
-func Test_listFiles(t *testing.T) {
-	type args struct {
-		ctx    context.Context
-		params *fileListParams
-	}
+func Test_deleteFile(t *testing.T) {
+	ctx, workspace := setupTestEnvironment(t)
+
 	tests := []struct {
 		name    string
-		args    args
-		want    string
+		path    string
 		wantErr bool
 	}{
-		{
-			name: "列出目录中的文件",
-			args: args{
-				ctx: context.Background(),
-				params: &fileListParams{
-					DirPath: "runtime",
-				},
-			},
-			want:    "runtime/test.txt",
-			wantErr: false,
-		},
+		{name: "成功删除文件", path: "test.txt"},
+		{name: "文件不存在", path: "not_exist.txt", wantErr: true},
+		{name: "工作区根目录", path: ".", wantErr: true},
+		{name: "上级目录", path: "../secret.txt", wantErr: true},
 	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := listFiles(tt.args.ctx, tt.args.params)
+			_, err := deleteFile(ctx, &fileDeleteParams{FilePath: tt.path})
 			if (err != nil) != tt.wantErr {
-				t.Errorf("listFiles() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if got != tt.want {
-				t.Errorf("listFiles() got = %v, want %v", got, tt.want)
+				t.Errorf("deleteFile() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(workspace.Dir()))), "secret.txt")); err != nil {
+		t.Errorf("工作区外的文件不应该被删除: %v", err)
+	}
 }
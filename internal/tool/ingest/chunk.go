@@ -0,0 +1,176 @@
+package ingest
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// 默认每块的最大字符数
+	DefaultChunkSize = 800
+	// 默认相邻块重叠的字符数
+	DefaultChunkOverlap = 100
+)
+
+// Chunk 切分后的文本块
+type Chunk struct {
+	// 在文档中的序号，从 0 开始
+	Index int
+	Text  string
+	// 所在章节的标题路径，例如 "工作经历 > 腾讯"
+	Section string
+	// 起止页码，没有分页的文档为 0
+	PageStart int
+	PageEnd   int
+}
+
+// ChunkOptions 切分选项，字段为 0 时使用默认值，Overlap 小于 0 表示不重叠
+type ChunkOptions struct {
+	// 每块的最大字符数
+	Size int
+	// 同一章节中相邻块重叠的字符数
+	Overlap int
+}
+
+// 句子的结束符，过长的段落在句子边界处切分
+var sentenceEnds = []string{"\n", "。", "！", "？", "；", "! ", "? ", ". ", "; "}
+
+// Chunks 按章节切分文档，标题总是开始新的块，同一章节中的段落合并到不超过 Size 个字符，过长的段落在句子边界处切分
+func (d *Document) Chunks(opts ChunkOptions) []Chunk {
+	size, overlap := opts.Size, opts.Overlap
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap == 0 {
+		overlap = DefaultChunkOverlap
+	}
+	if overlap < 0 || overlap >= size/2 {
+		overlap = max(min(overlap, size/4), 0)
+	}
+
+	c := &chunker{size: size, overlap: overlap}
+	var headings []string
+	for _, block := range d.Blocks {
+		if block.Level > 0 {
+			c.flush(false)
+			headings = append(headings[:min(len(headings), block.Level-1)], block.Text)
+			c.section = strings.Join(headings, " > ")
+			c.add(block.markdown(), block.Page, false)
+			continue
+		}
+		// 过长的段落切分时给上一块带过来的重叠内容留出空间
+		text := block.Text
+		for utf8.RuneCountInString(text) > size-overlap {
+			var head string
+			head, text = splitSentence(text, size-overlap)
+			c.add(head, block.Page, true)
+		}
+		c.add(text, block.Page, true)
+	}
+	c.flush(false)
+	return c.chunks
+}
+
+// chunker 累积文本块
+type chunker struct {
+	size    int
+	overlap int
+	section string
+	chunks  []Chunk
+
+	parts   []string
+	length  int
+	hasBody bool
+	// 只有上一块带过来的重叠内容
+	carried   bool
+	pageStart int
+	pageEnd   int
+}
+
+// add 添加一段文本，超过大小时先输出已经累积的内容
+func (c *chunker) add(text string, page int, body bool) {
+	n := utf8.RuneCountInString(text)
+	if c.hasBody && c.length+n+2 > c.size {
+		c.flush(true)
+	}
+	if c.carried && c.length+n+2 > c.size {
+		// 放不下重叠内容时直接丢弃
+		c.reset()
+	}
+	c.parts = append(c.parts, text)
+	c.length += n + 2
+	c.hasBody = c.hasBody || body
+	if page > 0 {
+		if c.pageStart == 0 || page < c.pageStart {
+			c.pageStart = page
+		}
+		c.pageEnd = max(c.pageEnd, page)
+	}
+}
+
+// flush 输出累积的内容，carry 为 true 时把结尾的一部分带到下一块
+// 只有标题的内容留给下一块，只有重叠内容时直接丢弃
+func (c *chunker) flush(carry bool) {
+	if !c.hasBody {
+		if c.carried {
+			c.reset()
+		}
+		return
+	}
+	text := strings.Join(c.parts, "\n\n")
+	c.chunks = append(c.chunks, Chunk{
+		Index:     len(c.chunks),
+		Text:      text,
+		Section:   c.section,
+		PageStart: c.pageStart,
+		PageEnd:   c.pageEnd,
+	})
+	page := c.pageEnd
+	c.reset()
+	if carry && c.overlap > 0 {
+		if tail := overlapTail(text, c.overlap); tail != "" {
+			c.add(tail, page, false)
+			c.carried = true
+		}
+	}
+}
+
+func (c *chunker) reset() {
+	c.parts, c.length, c.hasBody, c.carried, c.pageStart, c.pageEnd = nil, 0, false, false, 0, 0
+}
+
+// overlapTail 文本结尾最多 n 个字符，尽量从句子开头截取
+func overlapTail(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return ""
+	}
+	tail := string(runes[len(runes)-n:])
+	best := -1
+	for _, end := range sentenceEnds {
+		if i := strings.Index(tail, end); i >= 0 && (best < 0 || i < best) {
+			best = i + len(end)
+		}
+	}
+	if best > 0 && best < len(tail) {
+		tail = tail[best:]
+	}
+	return strings.TrimSpace(tail)
+}
+
+// splitSentence 在 size 个字符以内的最后一个句子边界处切分，后 40% 的范围内没有边界时直接截断
+func splitSentence(text string, size int) (string, string) {
+	runes := []rune(text)
+	head := string(runes[:size])
+	minLen := len(string(runes[:size*3/5]))
+	cut := -1
+	for _, end := range sentenceEnds {
+		if i := strings.LastIndex(head, end); i >= minLen && i+len(end) > cut {
+			cut = i + len(end)
+		}
+	}
+	if cut < 0 {
+		cut = len(head)
+	}
+	return strings.TrimSpace(text[:cut]), strings.TrimSpace(text[cut:])
+}
@@ -0,0 +1,243 @@
+package ingest
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 解压后单个部件的最大字节数，防止压缩炸弹
+const docxMaxPartSize = 64 << 20
+
+// docxParser Word 文档，按段落样式识别标题，列表项加 "- " 前缀，表格每行一行
+type docxParser struct{}
+
+func (docxParser) Parse(ctx context.Context, r io.ReaderAt, size int64, opts *Options) (*Document, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("打开Word文档失败: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	body, ok := files["word/document.xml"]
+	if !ok {
+		return nil, errors.New("打开Word文档失败: 缺少 word/document.xml")
+	}
+
+	doc := &Document{}
+	if f, ok := files["docProps/core.xml"]; ok {
+		doc.Title, _ = docxCoreTitle(f)
+	}
+	levels := map[string]int{}
+	if f, ok := files["word/styles.xml"]; ok {
+		if levels, err = docxHeadingStyles(f); err != nil {
+			doc.Warnings = append(doc.Warnings, fmt.Sprintf("样式解析失败: %v", err))
+		}
+	}
+	blocks, err := docxBlocks(body, levels)
+	if err != nil {
+		return nil, fmt.Errorf("解析Word文档失败: %w", err)
+	}
+	doc.Blocks = blocks
+	return doc, nil
+}
+
+func openPart(f *zip.File) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(rc, docxMaxPartSize), rc}, nil
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// docxCoreTitle 文档属性中的标题
+func docxCoreTitle(f *zip.File) (string, error) {
+	rc, err := openPart(f)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	var core struct {
+		Title string `xml:"title"`
+	}
+	err = xml.NewDecoder(rc).Decode(&core)
+	return core.Title, err
+}
+
+// docxHeadingStyles 标题样式对应的级别：名称为 "heading N" 或 "title" 的样式，以及设置了大纲级别的样式
+func docxHeadingStyles(f *zip.File) (map[string]int, error) {
+	rc, err := openPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var styles struct {
+		Styles []struct {
+			Id         string   `xml:"styleId,attr"`
+			Name       valAttr  `xml:"name"`
+			OutlineLvl *valAttr `xml:"pPr>outlineLvl"`
+		} `xml:"style"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&styles); err != nil {
+		return nil, err
+	}
+	levels := map[string]int{}
+	for _, s := range styles.Styles {
+		name := strings.ToLower(s.Name.Val)
+		switch {
+		case name == "title":
+			levels[s.Id] = 1
+		case strings.HasPrefix(name, "heading "):
+			if n, err := strconv.Atoi(strings.TrimPrefix(name, "heading ")); err == nil && n >= 1 && n <= 6 {
+				levels[s.Id] = n
+			}
+		case s.OutlineLvl != nil:
+			if n, err := strconv.Atoi(s.OutlineLvl.Val); err == nil && n >= 0 && n < 6 {
+				levels[s.Id] = n + 1
+			}
+		}
+	}
+	return levels, nil
+}
+
+type valAttr struct {
+	Val string `xml:"val,attr"`
+}
+
+// docxParagraph 正在解析的段落
+type docxParagraph struct {
+	text  strings.Builder
+	level int
+	list  bool
+}
+
+// docxBlocks 按顺序读取正文中的段落和表格，嵌套表格的内容合并到外层单元格中
+func docxBlocks(f *zip.File, levels map[string]int) ([]Block, error) {
+	rc, err := openPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		blocks    []Block
+		para      *docxParagraph
+		inText    bool
+		tableDeep int
+		rows      [][]string
+		row       []string
+		cell      []string
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para = &docxParagraph{}
+			case "pStyle":
+				if para != nil {
+					para.level = levels[attr(t, "val")]
+				}
+			case "outlineLvl":
+				if n, err := strconv.Atoi(attr(t, "val")); para != nil && err == nil && n >= 0 && n < 6 {
+					para.level = n + 1
+				}
+			case "numPr":
+				if para != nil {
+					para.list = true
+				}
+			case "t":
+				inText = true
+			case "tab":
+				if para != nil {
+					para.text.WriteString(" ")
+				}
+			case "br", "cr":
+				if para != nil {
+					para.text.WriteString("\n")
+				}
+			case "tbl":
+				tableDeep++
+				if tableDeep == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tableDeep == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDeep == 1 {
+					cell = nil
+				}
+			}
+		case xml.CharData:
+			if inText && para != nil {
+				para.text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if para == nil {
+					continue
+				}
+				text := strings.TrimSpace(para.text.String())
+				switch {
+				case tableDeep > 0:
+					if text != "" {
+						cell = append(cell, text)
+					}
+				case para.level > 0:
+					blocks = append(blocks, Block{Text: text, Level: para.level})
+				case para.list:
+					blocks = append(blocks, Block{Text: "- " + text})
+				default:
+					blocks = append(blocks, Block{Text: text})
+				}
+				para = nil
+			case "tc":
+				if tableDeep == 1 {
+					row = append(row, strings.Join(cell, " "))
+				}
+			case "tr":
+				if tableDeep == 1 && strings.TrimSpace(strings.Join(row, "")) != "" {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				tableDeep--
+				if tableDeep == 0 && len(rows) > 0 {
+					blocks = append(blocks, Block{Text: tableText(rows)})
+				}
+			}
+		}
+	}
+	return blocks, nil
+}
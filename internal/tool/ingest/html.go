@@ -0,0 +1,178 @@
+package ingest
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// htmlParser 网页，去掉脚本、样式和导航，按块级元素分段
+type htmlParser struct{}
+
+// 不包含正文的元素
+var htmlSkip = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Head:     true,
+	atom.Nav:      true,
+	atom.Iframe:   true,
+	atom.Button:   true,
+	atom.Select:   true,
+}
+
+// 块级元素，开始和结束时分段
+var htmlBlocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Table: true, atom.Figure: true, atom.Figcaption: true, atom.Form: true,
+	atom.Hr: true, atom.Address: true, atom.Details: true, atom.Summary: true,
+}
+
+var htmlHeadings = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+var spacePattern = regexp.MustCompile(`\s+`)
+
+func (htmlParser) Parse(ctx context.Context, r io.ReaderAt, size int64, opts *Options) (*Document, error) {
+	reader, err := charset.NewReader(io.NewSectionReader(r, 0, size), "text/html")
+	if err != nil {
+		return nil, err
+	}
+	root, err := html.Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+	w := &htmlWalker{doc: &Document{}}
+	if title := findElement(root, atom.Title); title != nil {
+		w.doc.Title = textContent(title)
+	}
+	w.walk(root)
+	w.flush()
+	return w.doc, nil
+}
+
+type htmlWalker struct {
+	doc  *Document
+	text strings.Builder
+}
+
+// flush 把累积的文字作为一个块输出
+func (w *htmlWalker) flush() {
+	if text := strings.TrimSpace(w.text.String()); text != "" {
+		w.doc.Blocks = append(w.doc.Blocks, Block{Text: text})
+	}
+	w.text.Reset()
+}
+
+func (w *htmlWalker) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		// HTML 中的换行和连续空白等同于一个空格
+		w.text.WriteString(spacePattern.ReplaceAllString(n.Data, " "))
+		return
+	case html.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			w.walk(c)
+		}
+		return
+	}
+
+	if htmlSkip[n.DataAtom] {
+		return
+	}
+	if level, ok := htmlHeadings[n.DataAtom]; ok {
+		w.flush()
+		w.doc.Blocks = append(w.doc.Blocks, Block{Text: textContent(n), Level: level})
+		return
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		w.text.WriteString("\n")
+		return
+	case atom.Pre:
+		w.flush()
+		w.doc.Blocks = append(w.doc.Blocks, Block{Text: rawText(n)})
+		return
+	case atom.Tr:
+		w.flush()
+		var cells []string
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Td || c.DataAtom == atom.Th {
+				cells = append(cells, textContent(c))
+			}
+		}
+		if strings.TrimSpace(strings.Join(cells, "")) != "" {
+			w.doc.Blocks = append(w.doc.Blocks, Block{Text: strings.Join(cells, " | ")})
+		}
+		return
+	}
+
+	block := htmlBlocks[n.DataAtom] || n.DataAtom == atom.Li
+	if block {
+		w.flush()
+	}
+	if n.DataAtom == atom.Li {
+		w.text.WriteString("- ")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+	if block {
+		w.flush()
+	}
+}
+
+// findElement 深度优先查找第一个指定的元素
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// textContent 元素中的文字，空白合并为一个空格
+func textContent(n *html.Node) string {
+	return strings.Join(strings.Fields(rawText(n)), " ")
+}
+
+// rawText 元素中的原始文字，跳过脚本和样式
+func rawText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode {
+			if htmlSkip[n.DataAtom] {
+				return
+			}
+			if n.DataAtom == atom.Br {
+				sb.WriteString("\n")
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
@@ -0,0 +1,186 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 文档格式
+const (
+	FormatPDF      = "pdf"
+	FormatDOCX     = "docx"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatText     = "text"
+)
+
+var ErrUnsupportedFormat = errors.New("不支持的文档格式")
+
+// 各扩展名对应的文档格式
+var extFormats = map[string]string{
+	".pdf":      FormatPDF,
+	".docx":     FormatDOCX,
+	".md":       FormatMarkdown,
+	".markdown": FormatMarkdown,
+	".html":     FormatHTML,
+	".htm":      FormatHTML,
+	".txt":      FormatText,
+	".csv":      FormatText,
+	".json":     FormatText,
+}
+
+// Block 文档中的一段文本，例如标题、段落、列表项、表格行
+type Block struct {
+	Text string
+	// 标题级别 1-6，0 表示正文
+	Level int
+	// 页码，从 1 开始，没有分页的文档为 0
+	Page int
+}
+
+// Document 解析后的文档，文本已经规范化
+type Document struct {
+	Name   string
+	Format string
+	Title  string
+	// 检测到的主要语言，例如 zh、en，无法判断时为 und
+	Language string
+	// 总页数，没有分页的文档为 0
+	Pages  int
+	Blocks []Block
+	// 解析过程中遇到的问题，例如某一页提取失败、扫描件没有配置 OCR
+	Warnings []string
+}
+
+// OCR 识别扫描件页面中的文字，r 为整个 PDF 文件，page 从 1 开始
+type OCR interface {
+	RecognizePage(ctx context.Context, r io.ReaderAt, size int64, page int) (string, error)
+}
+
+// Options 解析选项
+type Options struct {
+	// 扫描件页面的文字识别，为 nil 时只记录警告
+	OCR OCR
+	// 页面中可以提取的字符少于这个数量时认为是扫描件，默认 16
+	MinPageChars int
+}
+
+// Parser 某种格式的文档解析器
+type Parser interface {
+	Parse(ctx context.Context, r io.ReaderAt, size int64, opts *Options) (*Document, error)
+}
+
+var parsers = map[string]Parser{
+	FormatPDF:      pdfParser{},
+	FormatDOCX:     docxParser{},
+	FormatMarkdown: markdownParser{},
+	FormatHTML:     htmlParser{},
+	FormatText:     textParser{},
+}
+
+// FormatOf 按扩展名判断文档格式，不支持时返回空字符串
+func FormatOf(name string) string {
+	return extFormats[strings.ToLower(filepath.Ext(name))]
+}
+
+// Supported 是否支持解析该文件
+func Supported(name string) bool {
+	return FormatOf(name) != ""
+}
+
+// Parse 按文件名的扩展名选择解析器，解析后规范化文本并检测语言
+func Parse(ctx context.Context, name string, r io.ReaderAt, size int64, opts *Options) (*Document, error) {
+	format := FormatOf(name)
+	if format == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, filepath.Ext(name))
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	doc, err := parsers[format].Parse(ctx, r, size, opts)
+	if err != nil {
+		return nil, err
+	}
+	doc.Name = name
+	doc.Format = format
+	doc.finish()
+	return doc, nil
+}
+
+// ParseFile 解析本地文件
+func ParseFile(ctx context.Context, path string, opts *Options) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Parse(ctx, filepath.Base(path), f, info.Size(), opts)
+}
+
+// finish 规范化文本，去掉空块，没有标题时使用第一个标题块，最后检测语言
+func (d *Document) finish() {
+	blocks := d.Blocks[:0]
+	for _, block := range d.Blocks {
+		block.Text = normalizeText(block.Text)
+		if block.Text == "" {
+			continue
+		}
+		if block.Level > 0 {
+			// 标题只保留一行
+			block.Text = strings.Join(strings.Fields(block.Text), " ")
+		}
+		blocks = append(blocks, block)
+	}
+	d.Blocks = blocks
+	d.Title = strings.Join(strings.Fields(normalizeText(d.Title)), " ")
+	if d.Title == "" {
+		for _, block := range d.Blocks {
+			if block.Level > 0 {
+				d.Title = block.Text
+				break
+			}
+		}
+	}
+	d.Language = DetectLanguage(d.sample(languageSampleSize))
+}
+
+// sample 文档开头最多 n 个字节的文本
+func (d *Document) sample(n int) string {
+	var sb strings.Builder
+	for _, block := range d.Blocks {
+		if sb.Len() >= n {
+			break
+		}
+		sb.WriteString(block.Text)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Text 文档的全文，标题使用 Markdown 格式，块之间用空行分隔
+func (d *Document) Text() string {
+	var sb strings.Builder
+	for i, block := range d.Blocks {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(block.markdown())
+	}
+	return sb.String()
+}
+
+func (b *Block) markdown() string {
+	if b.Level > 0 {
+		return strings.Repeat("#", b.Level) + " " + b.Text
+	}
+	return b.Text
+}
@@ -0,0 +1,266 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-pdf/fpdf"
+
+	"txing-ai/internal/tool/office"
+)
+
+// testPDF 生成三页带页眉页脚的 PDF，最后加一页空白页模拟扫描件
+func testPDF(t *testing.T) []byte {
+	t.Helper()
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Quarterly Report", true)
+	pdf.SetHeaderFunc(func() {
+		if pdf.PageNo() > 3 {
+			return
+		}
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.Text(20, 12, "ACME Corp Confidential")
+	})
+	pdf.SetFooterFunc(func() {
+		if pdf.PageNo() > 3 {
+			return
+		}
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.Text(100, 285, fmt.Sprintf("Page %d", pdf.PageNo()))
+	})
+	for i := 1; i <= 3; i++ {
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "B", 20)
+		pdf.Text(20, 30, fmt.Sprintf("Section %d", i))
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 45, "The quarterly results of the company are")
+		pdf.Text(20, 50, "described in this section for review.")
+		pdf.Text(20, 70, fmt.Sprintf("Second paragraph on page %d.", i))
+	}
+	pdf.AddPage()
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("pdf.Output() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+type fakeOCR struct {
+	pages []int
+}
+
+func (o *fakeOCR) RecognizePage(ctx context.Context, r io.ReaderAt, size int64, page int) (string, error) {
+	o.pages = append(o.pages, page)
+	return "扫描件中识别出的文字", nil
+}
+
+func TestParse_PDF(t *testing.T) {
+	data := testPDF(t)
+	doc, err := Parse(context.Background(), "report.pdf", bytes.NewReader(data), int64(len(data)), nil)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if doc.Pages != 4 || doc.Title != "Quarterly Report" || doc.Language != "en" {
+		t.Errorf("Pages = %d, Title = %q, Language = %q", doc.Pages, doc.Title, doc.Language)
+	}
+	text := doc.Text()
+	if strings.Contains(text, "Confidential") || strings.Contains(text, "Page ") {
+		t.Errorf("页眉页脚没有去掉:\n%s", text)
+	}
+	want := []Block{
+		{Text: "Section 1", Level: 1, Page: 1},
+		{Text: "The quarterly results of the company are described in this section for review.", Page: 1},
+		{Text: "Second paragraph on page 1.", Page: 1},
+	}
+	if len(doc.Blocks) != 9 {
+		t.Fatalf("Blocks = %d, want 9:\n%s", len(doc.Blocks), text)
+	}
+	for i, block := range want {
+		if doc.Blocks[i] != block {
+			t.Errorf("Blocks[%d] = %+v, want %+v", i, doc.Blocks[i], block)
+		}
+	}
+	if doc.Blocks[8].Page != 3 {
+		t.Errorf("Blocks[8].Page = %d, want 3", doc.Blocks[8].Page)
+	}
+	if len(doc.Warnings) != 1 || !strings.Contains(doc.Warnings[0], "第 4 页") {
+		t.Errorf("Warnings = %v", doc.Warnings)
+	}
+
+	ocr := &fakeOCR{}
+	doc, err = Parse(context.Background(), "report.pdf", bytes.NewReader(data), int64(len(data)), &Options{OCR: ocr})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if fmt.Sprint(ocr.pages) != "[4]" {
+		t.Errorf("OCR pages = %v, want [4]", ocr.pages)
+	}
+	if last := doc.Blocks[len(doc.Blocks)-1]; last.Text != "扫描件中识别出的文字" || last.Page != 4 {
+		t.Errorf("last block = %+v", last)
+	}
+	if len(doc.Warnings) != 0 {
+		t.Errorf("Warnings = %v", doc.Warnings)
+	}
+}
+
+func TestParse_DOCX(t *testing.T) {
+	var buf bytes.Buffer
+	markdown := "# 张三的简历\n\n## 工作经历\n\n- 负责后端服务开发\n- 参与架构设计\n\n| 项目 | 费用 |\n| --- | --- |\n| 住宿 | 600 |\n"
+	if err := office.WriteDOCX(&buf, "个人简历", office.ParseMarkdown(markdown)); err != nil {
+		t.Fatalf("WriteDOCX() error = %v", err)
+	}
+	doc, err := Parse(context.Background(), "简历.DOCX", bytes.NewReader(buf.Bytes()), int64(buf.Len()), nil)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []Block{
+		{Text: "张三的简历", Level: 1},
+		{Text: "工作经历", Level: 2},
+		{Text: "- 负责后端服务开发"},
+		{Text: "- 参与架构设计"},
+		{Text: "项目 | 费用\n住宿 | 600"},
+	}
+	if fmt.Sprint(doc.Blocks) != fmt.Sprint(want) {
+		t.Errorf("Blocks = %+v, want %+v", doc.Blocks, want)
+	}
+	if doc.Format != FormatDOCX || doc.Title != "个人简历" || doc.Language != "zh" {
+		t.Errorf("Format = %q, Title = %q, Language = %q", doc.Format, doc.Title, doc.Language)
+	}
+}
+
+func TestParse_Markdown(t *testing.T) {
+	markdown := "# Title\n\nFirst line\nsecond line\n\n1. one\n2. two\n\n```\ncode block\n```\n\n---\n\n| a | b |\n| --- | --- |\n| 1 | 2 |\n"
+	doc, err := Parse(context.Background(), "notes.md", strings.NewReader(markdown), int64(len(markdown)), nil)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := "# Title\n\nFirst line\nsecond line\n\n1. one\n\n2. two\n\ncode block\n\na | b\n1 | 2"
+	if got := doc.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestParse_HTML(t *testing.T) {
+	page := `<html><head><title> 测试页面 </title><style>body { color: red }</style></head>
+<body><nav><a href="/">首页</a></nav>
+<h1>产品介绍</h1>
+<p>第一段
+  文字，<b>加粗</b>内容。</p>
+<script>alert("x")</script>
+<ul><li>功能一</li><li>功能二</li></ul>
+<table><tr><th>名称</th><th>价格</th></tr><tr><td>基础版</td><td>99</td></tr></table>
+<p>换行<br>之后</p>
+<pre>line 1
+line 2</pre>
+</body></html>`
+	doc, err := Parse(context.Background(), "page.html", strings.NewReader(page), int64(len(page)), nil)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := "# 产品介绍\n\n第一段 文字，加粗内容。\n\n- 功能一\n\n- 功能二\n\n名称 | 价格\n\n基础版 | 99\n\n换行\n之后\n\nline 1\nline 2"
+	if got := doc.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	if doc.Title != "测试页面" || doc.Language != "zh" {
+		t.Errorf("Title = %q, Language = %q", doc.Title, doc.Language)
+	}
+}
+
+func TestParse_Unsupported(t *testing.T) {
+	if _, err := Parse(context.Background(), "a.exe", strings.NewReader(""), 0, nil); err == nil {
+		t.Error("Parse() error = nil, want ErrUnsupportedFormat")
+	}
+	if !Supported("a.TXT") || Supported("a.doc") {
+		t.Error("Supported() 结果不正确")
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"a  b\t\tc", "a b c"},
+		{"con\u00adtrol\u200b", "control"},
+		{"\ufb01nal e\u0301", "final \u00e9"},
+		{"exam-\nple text", "example text"},
+		{"Jean-\nPierre", "Jean-\nPierre"},
+		{"第一行\r\n\r\n\r\n\r\n第二行", "第一行\n\n第二行"},
+		{"全角，标点\u3000空格", "全角，标点 空格"},
+	}
+	for _, tt := range tests {
+		if got := normalizeText(tt.in); got != tt.want {
+			t.Errorf("normalizeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", LanguageUnknown},
+		{"12345 !!!", LanguageUnknown},
+		{"熟悉 Go、Kubernetes 和 MySQL，负责后端服务的设计与开发", "zh"},
+		{"The quick brown fox jumps over the lazy dog and the cat", "en"},
+		{"Le chat est sur la table et le chien dans la maison", "fr"},
+		{"Der Hund ist nicht mit der Katze auf dem Tisch und das", "de"},
+		{"これは日本語の文章です。東京に住んでいます。", "ja"},
+		{"한국어 문장입니다. 서울에 살고 있습니다.", "ko"},
+		{"Это предложение на русском языке", "ru"},
+		{"Go Kubernetes MySQL Redis", "en"},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.text); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDocument_Chunks(t *testing.T) {
+	long := strings.Repeat("这是一个很长的句子，用来测试切分。", 10)
+	doc := &Document{Blocks: []Block{
+		{Text: "简历", Level: 1, Page: 1},
+		{Text: "个人信息", Level: 2, Page: 1},
+		{Text: "张三，五年工作经验。", Page: 1},
+		{Text: "工作经历", Level: 2, Page: 2},
+		{Text: long, Page: 2},
+		{Text: "最后一段。", Page: 3},
+	}}
+	chunks := doc.Chunks(ChunkOptions{Size: 100, Overlap: 20})
+	if len(chunks) < 3 {
+		t.Fatalf("Chunks() = %d, want >= 3", len(chunks))
+	}
+	first := chunks[0]
+	if first.Text != "# 简历\n\n## 个人信息\n\n张三，五年工作经验。" || first.Section != "简历 > 个人信息" || first.PageStart != 1 || first.PageEnd != 1 {
+		t.Errorf("chunks[0] = %+v", first)
+	}
+	for i, chunk := range chunks[1:] {
+		if chunk.Index != i+1 || chunk.Section != "简历 > 工作经历" {
+			t.Errorf("chunks[%d] = %+v", i+1, chunk)
+		}
+		if n := len([]rune(chunk.Text)); n > 100 {
+			t.Errorf("chunks[%d] 长度 %d 超过 100", i+1, n)
+		}
+		if strings.Contains(chunk.Text, "个人信息") || strings.Contains(chunk.Text, "五年") {
+			t.Errorf("chunks[%d] 包含上一章节的内容: %q", i+1, chunk.Text)
+		}
+	}
+	if !strings.HasPrefix(chunks[1].Text, "## 工作经历\n\n") || chunks[1].PageStart != 2 {
+		t.Errorf("chunks[1] = %+v", chunks[1])
+	}
+	// 相邻的块有重叠
+	if tail := []rune(chunks[1].Text); !strings.Contains(chunks[2].Text, string(tail[len(tail)-10:])) {
+		t.Errorf("chunks[2] 没有和 chunks[1] 重叠: %q / %q", chunks[1].Text, chunks[2].Text)
+	}
+	last := chunks[len(chunks)-1]
+	if !strings.HasSuffix(last.Text, "最后一段。") || last.PageEnd != 3 {
+		t.Errorf("last chunk = %+v", last)
+	}
+}
@@ -0,0 +1,109 @@
+package ingest
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LanguageUnknown 无法判断语言
+const LanguageUnknown = "und"
+
+// 检测语言时最多读取的文本长度
+const languageSampleSize = 8 << 10
+
+// 拉丁字母语言的常见虚词，用于区分英语、法语等
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "for", "with", "that", "on", "as", "are", "this", "by", "be"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "dans", "pour", "que", "qui", "sur", "du", "au", "avec"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "von", "ein", "eine", "zu", "auf", "für", "sich"},
+	"es": {"el", "los", "las", "y", "es", "una", "por", "con", "para", "que", "del", "como", "en", "se", "su"},
+	"pt": {"os", "as", "e", "é", "uma", "um", "não", "com", "para", "que", "do", "da", "em", "no", "na"},
+	"it": {"il", "gli", "e", "è", "una", "non", "con", "per", "che", "del", "della", "di", "nel", "sono", "anche"},
+}
+
+// DetectLanguage 按文字的书写系统和常见虚词检测文本的主要语言，返回 ISO 639-1 代码，例如 zh、en、ja
+// 中日韩文字按字计数，其他文字按词计数，中英混排的中文文档识别为 zh
+func DetectLanguage(text string) string {
+	if len(text) > languageSampleSize {
+		text = text[:languageSampleSize]
+	}
+	var han, kana, hangul int
+	words := map[string]int{}
+	latinWords := map[string]int{}
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r) || isCJK(r)
+	}) {
+		first := []rune(word)[0]
+		switch {
+		case unicode.Is(unicode.Latin, first):
+			words["latin"]++
+			latinWords[strings.ToLower(word)]++
+		case unicode.Is(unicode.Cyrillic, first):
+			words["ru"]++
+		case unicode.Is(unicode.Arabic, first):
+			words["ar"]++
+		case unicode.Is(unicode.Thai, first):
+			words["th"]++
+		case unicode.Is(unicode.Greek, first):
+			words["el"]++
+		case unicode.Is(unicode.Hebrew, first):
+			words["he"]++
+		case unicode.Is(unicode.Devanagari, first):
+			words["hi"]++
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		}
+	}
+
+	cjk := han + kana + hangul
+	best, bestCount := "", 0
+	for script, count := range words {
+		if count > bestCount || (count == bestCount && script < best) {
+			best, bestCount = script, count
+		}
+	}
+	if cjk == 0 && bestCount == 0 {
+		return LanguageUnknown
+	}
+	if cjk >= bestCount {
+		switch {
+		case kana*20 >= cjk:
+			return "ja"
+		case hangul > han:
+			return "ko"
+		default:
+			return "zh"
+		}
+	}
+	if best != "latin" {
+		return best
+	}
+	return latinLanguage(latinWords)
+}
+
+// latinLanguage 按常见虚词出现的次数区分拉丁字母语言
+func latinLanguage(words map[string]int) string {
+	best, bestScore := LanguageUnknown, 0
+	for _, lang := range []string{"en", "fr", "de", "es", "pt", "it"} {
+		score := 0
+		for _, word := range stopwords[lang] {
+			score += words[word]
+		}
+		if score > bestScore {
+			best, bestScore = lang, score
+		}
+	}
+	if bestScore == 0 {
+		// 没有虚词的短文本，例如关键词列表，按英语处理
+		return "en"
+	}
+	return best
+}
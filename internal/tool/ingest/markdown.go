@@ -0,0 +1,50 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"txing-ai/internal/tool/office"
+)
+
+// markdownParser Markdown 文档，复用导出 DOCX 时的解析结果
+type markdownParser struct{}
+
+func (markdownParser) Parse(ctx context.Context, r io.ReaderAt, size int64, opts *Options) (*Document, error) {
+	text, err := readText(r, size)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	for _, block := range office.ParseMarkdown(text) {
+		switch block.Kind {
+		case office.BlockHeading:
+			doc.Blocks = append(doc.Blocks, Block{Text: block.Text(), Level: block.Level})
+		case office.BlockListItem:
+			marker := "- "
+			if block.Number > 0 {
+				marker = fmt.Sprintf("%d. ", block.Number)
+			}
+			doc.Blocks = append(doc.Blocks, Block{Text: marker + block.Text()})
+		case office.BlockCode:
+			doc.Blocks = append(doc.Blocks, Block{Text: block.Code})
+		case office.BlockTable:
+			doc.Blocks = append(doc.Blocks, Block{Text: tableText(block.Rows)})
+		case office.BlockRule:
+		default:
+			doc.Blocks = append(doc.Blocks, Block{Text: block.Text()})
+		}
+	}
+	return doc, nil
+}
+
+// tableText 表格每行一行，单元格之间用 " | " 分隔
+func tableText(rows [][]string) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, " | "))
+	}
+	return strings.Join(lines, "\n")
+}
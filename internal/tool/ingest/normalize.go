@@ -0,0 +1,114 @@
+package ingest
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 不可见字符和 PDF 中常见的连字，全角标点保持不变，不使用 NFKC
+var textReplacer = strings.NewReplacer(
+	"\r\n", "\n",
+	"\r", "\n",
+	"\t", " ",
+	"\u00a0", " ", // 不换行空格
+	"\u3000", " ", // 全角空格
+	"\u00ad", "", // 软连字符
+	"\u200b", "",
+	"\u200c", "",
+	"\u200d", "",
+	"\u2060", "",
+	"\ufeff", "",
+	"\ufb00", "ff",
+	"\ufb01", "fi",
+	"\ufb02", "fl",
+	"\ufb03", "ffi",
+	"\ufb04", "ffl",
+)
+
+// normalizeText 规范化文本：统一换行和空白、去掉控制字符和不可见字符、合并行尾连字符断开的单词、最多保留一个空行
+func normalizeText(s string) string {
+	s = textReplacer.Replace(norm.NFC.String(s))
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' {
+			return r
+		}
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			blank++
+			continue
+		}
+		if len(out) > 0 {
+			if blank > 0 {
+				out = append(out, "")
+			} else if joined, ok := joinHyphenated(out[len(out)-1], line); ok {
+				out[len(out)-1] = joined
+				continue
+			}
+		}
+		blank = 0
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// joinHyphenated 合并被连字符断开的英文单词，例如 "exam-" 和 "ple"
+func joinHyphenated(prev string, next string) (string, bool) {
+	if len(prev) < 2 || !strings.HasSuffix(prev, "-") {
+		return "", false
+	}
+	before, _ := utf8.DecodeLastRuneInString(prev[:len(prev)-1])
+	first, _ := utf8.DecodeRuneInString(next)
+	if !isLatinLetter(before) || !unicode.IsLower(first) || !isLatinLetter(first) {
+		return "", false
+	}
+	return prev[:len(prev)-1] + next, true
+}
+
+func isLatinLetter(r rune) bool {
+	return unicode.Is(unicode.Latin, r)
+}
+
+// isCJK 中日韩文字和全角标点，这些字符之间不需要空格
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// joinLines 合并同一段落中的多行，中文之间不加空格，英文之间加空格
+func joinLines(lines []string) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			prev := sb.String()
+			if joined, ok := joinHyphenated(prev, line); ok {
+				sb.Reset()
+				sb.WriteString(joined)
+				continue
+			}
+			last, _ := utf8.DecodeLastRuneInString(prev)
+			first, _ := utf8.DecodeRuneInString(line)
+			if !isCJK(last) || !isCJK(first) {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
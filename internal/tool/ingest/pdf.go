@@ -0,0 +1,276 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// 字号大于正文字号的这个倍数时认为是标题
+	pdfHeadingScale = 1.2
+	// 标题最多的字符数
+	pdfHeadingMaxChars = 80
+	// 行距超过正文行高的这个倍数时认为是新段落
+	pdfParagraphGap = 1.5
+	// 页眉页脚至少在这么多页中重复出现
+	pdfRepeatMinPages = 3
+)
+
+// pdfParser 按文字的坐标还原行和段落，根据字号识别标题，去掉每页重复的页眉页脚
+type pdfParser struct{}
+
+// pdfLine 页面中的一行文字
+type pdfLine struct {
+	text     string
+	y        float64
+	fontSize float64
+}
+
+func (pdfParser) Parse(ctx context.Context, r io.ReaderAt, size int64, opts *Options) (*Document, error) {
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("打开PDF文件失败: %w", err)
+	}
+	minChars := opts.MinPageChars
+	if minChars <= 0 {
+		minChars = 16
+	}
+
+	doc := &Document{Pages: reader.NumPage(), Title: pdfTitle(reader)}
+	pages := make([][]pdfLine, doc.Pages+1)
+	for i := 1; i <= doc.Pages; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lines, err := pdfPageLines(reader, i)
+		if err != nil {
+			doc.Warnings = append(doc.Warnings, fmt.Sprintf("第 %d 页提取失败: %v", i, err))
+			continue
+		}
+		pages[i] = lines
+	}
+	removeRepeatedLines(pages)
+
+	bodySize := medianFontSize(pages)
+	for i := 1; i <= doc.Pages; i++ {
+		lines := pages[i]
+		chars := 0
+		for _, line := range lines {
+			chars += utf8.RuneCountInString(strings.TrimSpace(line.text))
+		}
+		if chars < minChars {
+			// 几乎没有文字的页面认为是扫描件
+			if opts.OCR == nil {
+				if chars == 0 {
+					doc.Warnings = append(doc.Warnings, fmt.Sprintf("第 %d 页没有可提取的文字，可能是扫描件", i))
+				}
+			} else if text, err := opts.OCR.RecognizePage(ctx, r, size, i); err != nil {
+				doc.Warnings = append(doc.Warnings, fmt.Sprintf("第 %d 页文字识别失败: %v", i, err))
+			} else if strings.TrimSpace(text) != "" {
+				for _, paragraph := range splitParagraphs(text) {
+					doc.Blocks = append(doc.Blocks, Block{Text: paragraph, Page: i})
+				}
+				continue
+			}
+		}
+		doc.Blocks = append(doc.Blocks, pdfBlocks(lines, bodySize, i)...)
+	}
+	return doc, nil
+}
+
+// pdfTitle 文档信息中的标题
+func pdfTitle(reader *pdf.Reader) (title string) {
+	defer func() {
+		if recover() != nil {
+			title = ""
+		}
+	}()
+	return reader.Trailer().Key("Info").Key("Title").Text()
+}
+
+// pdfPageLines 提取一页中的文字并按坐标组成行，从上到下排列
+// pdf 库遇到损坏的内容流会 panic，这里转换为错误
+func pdfPageLines(reader *pdf.Reader, num int) (lines []pdfLine, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			lines, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	page := reader.Page(num)
+	if page.V.IsNull() {
+		return nil, nil
+	}
+	texts := page.Content().Text
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	// 按 Y 从上到下排序，Y 相差不超过字号一半的相邻文字属于同一行
+	sort.SliceStable(texts, func(i, j int) bool { return texts[i].Y > texts[j].Y })
+	var row []pdf.Text
+	for _, t := range texts {
+		if len(row) > 0 && math.Abs(row[0].Y-t.Y) > lineTolerance(row[0], t) {
+			lines = append(lines, buildLine(row))
+			row = row[:0]
+		}
+		row = append(row, t)
+	}
+	if len(row) > 0 {
+		lines = append(lines, buildLine(row))
+	}
+	return lines, nil
+}
+
+func lineTolerance(a pdf.Text, b pdf.Text) float64 {
+	return math.Max(math.Max(a.FontSize, b.FontSize)/2, 1)
+}
+
+// buildLine 合并同一行的文字，文字之间的间距超过字号的四分之一时加空格，中文之间不加
+func buildLine(row []pdf.Text) pdfLine {
+	sorted := append([]pdf.Text(nil), row...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].X < sorted[j].X })
+
+	var sb strings.Builder
+	end := math.Inf(-1)
+	var fontSize float64
+	for _, t := range sorted {
+		if t.S == "" {
+			continue
+		}
+		if sb.Len() > 0 && t.X-end > t.FontSize*0.25 {
+			last, _ := utf8.DecodeLastRuneInString(sb.String())
+			first, _ := utf8.DecodeRuneInString(t.S)
+			if last != ' ' && first != ' ' && !(isCJK(last) && isCJK(first)) {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(t.S)
+		w := t.W
+		if w <= 0 {
+			// 部分 CID 字体没有宽度信息，按半个字号估算
+			w = t.FontSize * 0.5 * float64(utf8.RuneCountInString(t.S))
+		}
+		end = math.Max(end, t.X+w)
+		fontSize = math.Max(fontSize, t.FontSize)
+	}
+	return pdfLine{text: strings.TrimSpace(sb.String()), y: sorted[0].Y, fontSize: fontSize}
+}
+
+// 页眉页脚中的页码
+var digitsPattern = regexp.MustCompile(`\d+`)
+
+// removeRepeatedLines 去掉页眉页脚：每页的第一行和最后一行把数字替换后，在至少一半的页面中重复出现
+func removeRepeatedLines(pages [][]pdfLine) {
+	counts := map[string]int{}
+	total := 0
+	for _, lines := range pages {
+		if len(lines) == 0 {
+			continue
+		}
+		total++
+		for _, key := range edgeKeys(lines) {
+			counts[key]++
+		}
+	}
+	if total < pdfRepeatMinPages {
+		return
+	}
+	repeated := func(line pdfLine) bool {
+		n := counts[digitsPattern.ReplaceAllString(line.text, "#")]
+		return n >= pdfRepeatMinPages && n*2 >= total
+	}
+	for i, lines := range pages {
+		if len(lines) > 0 && repeated(lines[0]) {
+			lines = lines[1:]
+		}
+		if len(lines) > 0 && repeated(lines[len(lines)-1]) {
+			lines = lines[:len(lines)-1]
+		}
+		pages[i] = lines
+	}
+}
+
+func edgeKeys(lines []pdfLine) []string {
+	first := digitsPattern.ReplaceAllString(lines[0].text, "#")
+	last := digitsPattern.ReplaceAllString(lines[len(lines)-1].text, "#")
+	if len(lines) == 1 || first == last {
+		return []string{first}
+	}
+	return []string{first, last}
+}
+
+// medianFontSize 按字符数加权的正文字号
+func medianFontSize(pages [][]pdfLine) float64 {
+	weights := map[float64]int{}
+	total := 0
+	for _, lines := range pages {
+		for _, line := range lines {
+			n := utf8.RuneCountInString(line.text)
+			weights[math.Round(line.fontSize*10)/10] += n
+			total += n
+		}
+	}
+	sizes := make([]float64, 0, len(weights))
+	for size := range weights {
+		sizes = append(sizes, size)
+	}
+	sort.Float64s(sizes)
+	acc := 0
+	for _, size := range sizes {
+		acc += weights[size]
+		if acc*2 >= total {
+			return size
+		}
+	}
+	return 0
+}
+
+// pdfBlocks 把一页中的行组成段落，行距明显变大或字号变化时开始新段落
+func pdfBlocks(lines []pdfLine, bodySize float64, page int) []Block {
+	var blocks []Block
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, Block{Text: joinLines(paragraph), Page: page})
+			paragraph = nil
+		}
+	}
+	for i, line := range lines {
+		if line.text == "" {
+			continue
+		}
+		if bodySize > 0 && line.fontSize >= bodySize*pdfHeadingScale && utf8.RuneCountInString(line.text) <= pdfHeadingMaxChars {
+			flush()
+			level := 2
+			if line.fontSize >= bodySize*1.6 {
+				level = 1
+			}
+			// 跨行的标题合并为一个
+			if n := len(blocks); n > 0 && i > 0 && blocks[n-1].Level == level && lines[i-1].fontSize == line.fontSize {
+				blocks[n-1].Text = joinLines([]string{blocks[n-1].Text, line.text})
+				continue
+			}
+			blocks = append(blocks, Block{Text: line.text, Level: level, Page: page})
+			continue
+		}
+		if i > 0 && len(paragraph) > 0 {
+			prev := lines[i-1]
+			// 正文行高按字号的 1.2 倍估算
+			height := math.Max(prev.fontSize, line.fontSize) * 1.2
+			if prev.y-line.y > height*pdfParagraphGap || math.Abs(prev.fontSize-line.fontSize) > 0.5 {
+				flush()
+			}
+		}
+		paragraph = append(paragraph, line.text)
+	}
+	flush()
+	return blocks
+}
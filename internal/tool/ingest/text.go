@@ -0,0 +1,54 @@
+package ingest
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// textParser 纯文本，按空行分段，非 UTF-8 编码的文本按内容猜测编码后转换
+type textParser struct{}
+
+func (textParser) Parse(ctx context.Context, r io.ReaderAt, size int64, opts *Options) (*Document, error) {
+	text, err := readText(r, size)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	for _, paragraph := range splitParagraphs(text) {
+		doc.Blocks = append(doc.Blocks, Block{Text: paragraph})
+	}
+	return doc, nil
+}
+
+// readText 读取全部内容并转换为 UTF-8
+func readText(r io.ReaderAt, size int64) (string, error) {
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return "", err
+	}
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	enc, _, _ := charset.DetermineEncoding(data, "text/plain")
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data), nil
+	}
+	return string(decoded), nil
+}
+
+// splitParagraphs 按空行分段
+func splitParagraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var paragraphs []string
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return paragraphs
+}
@@ -55,7 +55,7 @@ func saveMarkdown(ctx context.Context, params *markdownSaveParams) (string, erro
 		zap.Int("contentLength", len(params.Content)),
 		zap.Time("timestamp", time.Now()))
 
-	return fmt.Sprintf("Markdown文件已成功保存到: ./%s", filename), nil
+	return fmt.Sprintf("Markdown文件已成功保存到: ./%s，下载地址: %s", filename, registerOutputFile(ctx, markdownSaveToolName, fullPath)), nil
 }
 
 // 展示消息构造
@@ -0,0 +1,50 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/tool/office"
+
+	"go.uber.org/zap"
+)
+
+// markdownToDocxParams 转换Markdown到Word文档的参数
+type markdownToDocxParams struct {
+	Content  string `json:"content" jsonschema:"description=要转换的Markdown内容"`
+	Filename string `json:"filename" jsonschema:"description=文件名(不含扩展名)"`
+}
+
+// saveMarkdownToDocx 将Markdown内容转换为Word文档并保存到本地文件
+func saveMarkdownToDocx(ctx context.Context, params *markdownToDocxParams) (string, error) {
+	filename, url, err := saveDocument(ctx, markdownToDocxToolName, params.Filename, ".docx", func(w io.Writer) error {
+		return office.WriteDOCX(w, params.Filename, office.ParseMarkdown(params.Content))
+	})
+	if err != nil {
+		log.Error("Markdown转Word失败", zap.Error(err))
+		return fmt.Sprintf("Markdown转Word失败: %v", err), nil
+	}
+	return fmt.Sprintf("Word文档已成功保存: ./%s，下载地址: %s", filename, url), nil
+}
+
+// 展示消息构造：将逻辑内聚到工具文件
+type markdownToDocxShowBuilder struct{}
+
+func (markdownToDocxShowBuilder) BuildRequest(paramsStr string) (string, error) {
+	var params markdownToDocxParams
+	if err := json.Unmarshal([]byte(paramsStr), &params); err != nil {
+		log.Error("构建Markdown转Word请求显示信息失败", zap.Error(err))
+		return "", ErrInvalidJSON
+	}
+	return "保存为 Word 文件：" + params.Filename, nil
+}
+
+func (markdownToDocxShowBuilder) BuildResponse(response string) (string, error) {
+	return response, nil
+}
+
+func init() {
+	RegisterShowMsgBuilder(markdownToDocxToolName, markdownToDocxShowBuilder{})
+}
@@ -2,127 +2,9 @@ package tool
 
 import (
 	"context"
-	"reflect"
 	"testing"
 )
 
-func Test_cleanupTempImages(t *testing.T) {
-	type args struct {
-		imagePaths []string
-	}
-	tests := []struct {
-		name string
-		args args
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			cleanupTempImages(tt.args.imagePaths)
-		})
-	}
-}
-
-func Test_convertHTMLToPDF(t *testing.T) {
-	type args struct {
-		htmlPath string
-		pdfPath  string
-	}
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if err := convertHTMLToPDF(tt.args.htmlPath, tt.args.pdfPath); (err != nil) != tt.wantErr {
-				t.Errorf("convertHTMLToPDF() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
-func Test_downloadImageFromURL(t *testing.T) {
-	type args struct {
-		url string
-		dir string
-	}
-	tests := []struct {
-		name    string
-		args    args
-		want    string
-		wantErr bool
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := downloadImageFromURL(tt.args.url, tt.args.dir)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("downloadImageFromURL() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if got != tt.want {
-				t.Errorf("downloadImageFromURL() got = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_markdownToHTML(t *testing.T) {
-	type args struct {
-		content string
-		title   string
-	}
-	tests := []struct {
-		name string
-		args args
-		want string
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := markdownToHTML(tt.args.content, tt.args.title); got != tt.want {
-				t.Errorf("markdownToHTML() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_processMarkdownImages(t *testing.T) {
-	type args struct {
-		content string
-		tempDir string
-	}
-	tests := []struct {
-		name    string
-		args    args
-		want    string
-		want1   []string
-		wantErr bool
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, got1, err := processMarkdownImages(tt.args.content, tt.args.tempDir)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("processMarkdownImages() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if got != tt.want {
-				t.Errorf("processMarkdownImages() got = %v, want %v", got, tt.want)
-			}
-			if !reflect.DeepEqual(got1, tt.want1) {
-				t.Errorf("processMarkdownImages() got1 = %v, want %v", got1, tt.want1)
-			}
-		})
-	}
-}
-
 func Test_saveMarkdownToPDF(t *testing.T) {
 	type args struct {
 		ctx    context.Context
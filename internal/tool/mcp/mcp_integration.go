@@ -3,51 +3,126 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	einomcp "github.com/cloudwego/eino-ext/components/tool/mcp"
 	"github.com/cloudwego/eino/components/tool"
+	"github.com/fsnotify/fsnotify"
 	"github.com/mark3labs/mcp-go/client"
-	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"go.uber.org/zap"
+	"net/url"
 	"os"
-	_ "path/filepath"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
 	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
 )
 
-// mCPServerConfig 定义单个MCP服务器配置
-type mCPServerConfig struct {
-	Name        string            `json:"name"`
+const (
+	// 默认健康检查间隔
+	defaultHealthCheckInterval = 30 * time.Second
+	// 默认断线重连的最大退避时间
+	defaultMaxReconnectBackoff = 5 * time.Minute
+	// 配置文件修改后延迟重新加载的时间，合并编辑器保存时的多次写入
+	configReloadDelay = 500 * time.Millisecond
+)
+
+// MCP 服务器的传输方式
+const (
+	// TransportStdio 以子进程方式启动本地 MCP 服务器，通过标准输入输出通信
+	TransportStdio = "stdio"
+	// TransportSSE 通过 HTTP + SSE 连接远程 MCP 服务器
+	TransportSSE = "sse"
+	// TransportStreamableHTTP 通过 Streamable HTTP 连接远程 MCP 服务器
+	TransportStreamableHTTP = "streamable-http"
+)
+
+// MCPServerConfig 定义单个MCP服务器配置
+type MCPServerConfig struct {
+	Name string `json:"name"`
+	// 传输方式，为空时默认为 stdio
+	Transport string `json:"transport"`
+	// stdio 方式的启动命令、参数和环境变量
 	Command     string            `json:"command"`
 	Args        []string          `json:"args"`
 	Environment map[string]string `json:"environment"`
+	// sse、streamable-http 方式的服务地址
+	URL string `json:"url"`
+	// sse、streamable-http 方式的请求头，值支持 ${ENV} 引用环境变量
+	Headers map[string]string `json:"headers"`
+	// sse、streamable-http 方式的认证令牌，以 Authorization: Bearer <token> 请求头发送，支持 ${ENV} 引用环境变量
+	AuthToken string `json:"auth_token"`
+	// 禁用的工具名称，禁用后不会提供给智能体，修改后不需要重新连接
+	DisabledTools []string `json:"disabled_tools"`
 }
 
 // mCPServerConfigs 定义多个MCP服务器配置
 type mCPServerConfigs struct {
-	Servers []mCPServerConfig `json:"servers"`
+	Servers []MCPServerConfig `json:"servers"`
 }
 
-// MCPClientManager 管理MCP客户端
+// MCPClientManager 管理MCP客户端，负责健康检查、断线重连以及配置文件修改后的热更新
+// MCP服务器来自配置文件和管理后台两个来源，名称相同时以管理后台的配置为准
 type MCPClientManager struct {
-	clients map[string]*client.Client
-	tools   map[string][]tool.BaseTool
-	ctx     context.Context
+	ctx context.Context
+	// 健康检查间隔
+	healthCheckInterval time.Duration
+	// 断线重连的最大退避时间
+	maxReconnectBackoff time.Duration
+
+	// 保证配置的加载和应用串行执行，同时保护 fileConfigs 和 managedConfigs
+	reloadMu sync.Mutex
+	// 配置文件中的服务器
+	fileConfigs []MCPServerConfig
+	// 管理后台配置的服务器
+	managedConfigs []MCPServerConfig
+
+	mu         sync.RWMutex
+	configPath string
+	servers    map[string]*mcpServer
+	watcher    *fsnotify.Watcher
+	closed     bool
 }
 
-// Init 初始化所有MCP服务器
-func NewMCPClientManager(ctx context.Context) *MCPClientManager {
+// NewMCPClientManager 创建MCP客户端管理器，config 为空时使用默认配置
+func NewMCPClientManager(ctx context.Context, config *global.MCPConfig) *MCPClientManager {
 
 	// 全局MCP客户端管理器
 	var mcpManager = &MCPClientManager{
-		clients: make(map[string]*client.Client),
-		tools:   make(map[string][]tool.BaseTool),
-		ctx:     ctx,
+		ctx:                 ctx,
+		healthCheckInterval: defaultHealthCheckInterval,
+		maxReconnectBackoff: defaultMaxReconnectBackoff,
+		servers:             make(map[string]*mcpServer),
+	}
+	if config != nil {
+		if config.HealthCheckInterval > 0 {
+			mcpManager.healthCheckInterval = config.HealthCheckInterval * time.Second
+		}
+		if config.MaxReconnectBackoff > 0 {
+			mcpManager.maxReconnectBackoff = config.MaxReconnectBackoff * time.Second
+		}
 	}
 
 	return mcpManager
 }
 
-// Init 初始化所有MCP服务器
+// Init 初始化所有MCP服务器，并监听配置文件的修改
+// 连接失败的服务器会在后台按退避时间重连，返回的错误只用于提示
 func (m *MCPClientManager) Init(configPath string) error {
+	if configPath == "" {
+		configPath = "./" + global.RuntimeDir + "/mcp_config.json"
+	}
+	m.mu.Lock()
+	m.configPath = configPath
+	m.mu.Unlock()
+
+	// 配置文件不存在时也监听，创建后自动加载
+	if err := m.watchConfig(); err != nil {
+		log.Warn("watch mcp config failed", zap.String("path", configPath), zap.Error(err))
+	}
 
 	// 读取配置文件
 	configs, err := loadMCPServerConfigs(configPath)
@@ -56,70 +131,241 @@ func (m *MCPClientManager) Init(configPath string) error {
 	}
 
 	// 初始化每个MCP服务器
-	// 以 stdio 方式启动 MCP 服务端
-	for _, serverConfig := range configs.Servers {
-		if err := initMCPServer(m, serverConfig); err != nil {
-			return fmt.Errorf("初始化MCP服务器 %s 失败: %w", serverConfig.Name, err)
+	return errors.Join(m.applyFileConfigs(configs)...)
+}
+
+// SetManagedServers 替换管理后台配置的MCP服务器，配置有变化的服务器会重新连接
+// 连接失败的服务器会在后台按退避时间重连，返回的错误只用于提示
+func (m *MCPClientManager) SetManagedServers(servers []MCPServerConfig) error {
+	configs := &mCPServerConfigs{Servers: servers}
+	if err := configs.validate(); err != nil {
+		return fmt.Errorf("MCP配置错误: %w", err)
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.managedConfigs = configs.Servers
+	return errors.Join(m.apply()...)
+}
+
+// applyFileConfigs 替换配置文件中的MCP服务器
+func (m *MCPClientManager) applyFileConfigs(configs *mCPServerConfigs) []error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.fileConfigs = configs.Servers
+	return m.apply()
+}
+
+// mergedConfigs 合并两个来源的服务器配置，名称相同时以管理后台的配置为准，调用方需要持有 reloadMu
+func (m *MCPClientManager) mergedConfigs() []MCPServerConfig {
+	managed := make(map[string]bool, len(m.managedConfigs))
+	for _, config := range m.managedConfigs {
+		managed[config.Name] = true
+	}
+	configs := make([]MCPServerConfig, 0, len(m.fileConfigs)+len(m.managedConfigs))
+	for _, config := range m.fileConfigs {
+		if managed[config.Name] {
+			log.Warn("mcp server in config file is overridden by managed server", zap.String("server", config.Name))
+			continue
 		}
+		configs = append(configs, config)
 	}
+	return append(configs, m.managedConfigs...)
+}
 
+// apply 按配置增删MCP服务器，连接配置有变化的服务器会重新连接，调用方需要持有 reloadMu
+func (m *MCPClientManager) apply() []error {
+	configs := m.mergedConfigs()
+	wanted := make(map[string]MCPServerConfig, len(configs))
+	for _, config := range configs {
+		wanted[config.Name] = config
+	}
+
+	// 找出已删除或配置有变化的服务器
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	var stopped []*mcpServer
+	for name, server := range m.servers {
+		config, ok := wanted[name]
+		if !ok || !config.sameConnection(server.config) {
+			stopped = append(stopped, server)
+			delete(m.servers, name)
+			continue
+		}
+		server.setDisabledTools(config.DisabledTools)
+	}
+	var started []MCPServerConfig
+	for _, config := range configs {
+		if _, ok := m.servers[config.Name]; !ok {
+			started = append(started, config)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, server := range stopped {
+		log.Info("stop mcp server", zap.String("server", server.config.Name))
+		server.stop()
+	}
+
+	// 连接新的服务器，连接耗时较长，不持有锁
+	var errs []error
+	for _, config := range started {
+		server := newMCPServer(m.ctx, config, m.healthCheckInterval, m.maxReconnectBackoff)
+		if err := server.start(); err != nil {
+			errs = append(errs, fmt.Errorf("初始化MCP服务器 %s 失败: %w", config.Name, err))
+		} else {
+			log.Info("mcp server connected", zap.String("server", config.Name))
+		}
+
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			server.stop()
+			continue
+		}
+		m.servers[config.Name] = server
+		m.mu.Unlock()
+	}
+	return errs
+}
+
+// watchConfig 监听配置文件所在目录，编辑器保存文件时可能是先删除再创建，所以不直接监听文件
+func (m *MCPClientManager) watchConfig() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(m.configPath)); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+
+	m.mu.Lock()
+	if m.watcher != nil {
+		_ = m.watcher.Close()
+	}
+	m.watcher = watcher
+	m.mu.Unlock()
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case <-m.ctx.Done():
+				_ = watcher.Close()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(m.configPath) ||
+					!event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				// 合并短时间内的多次修改
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(configReloadDelay, m.reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error("watch mcp config error", zap.Error(err))
+			}
+		}
+	}()
 	return nil
 }
 
-// GetMCPTools 获取指定MCP服务器的工具
+// reload 重新加载配置文件，配置不合法时保持当前的服务器不变
+func (m *MCPClientManager) reload() {
+	configs, err := loadMCPServerConfigs(m.configPath)
+	if err != nil {
+		log.Error("reload mcp config failed, keep current servers", zap.Error(err))
+		return
+	}
+	for _, err := range m.applyFileConfigs(configs) {
+		log.Error("reload mcp server failed", zap.Error(err))
+	}
+	log.Info("mcp config reloaded", zap.String("path", m.configPath))
+}
+
+// GetMCPTools 获取指定MCP服务器当前的工具，服务器断开时为空
 func (m *MCPClientManager) GetMCPTools(serverName string) ([]tool.BaseTool, error) {
-	tools, exists := m.tools[serverName]
+	m.mu.RLock()
+	server, exists := m.servers[serverName]
+	m.mu.RUnlock()
 	if !exists {
 		return nil, fmt.Errorf("MCP服务器 %s 不存在或未初始化", serverName)
 	}
-	return tools, nil
+	return server.currentTools(), nil
 }
 
-// GetAllMCPTools 获取所有MCP服务器的工具
+// GetAllMCPTools 获取所有MCP服务器当前的工具，按服务器名称排序
 func (m *MCPClientManager) GetAllMCPTools() []tool.BaseTool {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 	var allTools []tool.BaseTool
-	for _, tools := range m.tools {
-		allTools = append(allTools, tools...)
+	for _, name := range names {
+		allTools = append(allTools, m.servers[name].currentTools()...)
 	}
+	m.mu.RUnlock()
 	return allTools
 }
 
-// CloseMCPServer 关闭指定MCP服务器
-func (m *MCPClientManager) CloseMCPServer(serverName string) error {
-	cli, exists := m.clients[serverName]
+// ServerStatus 获取指定MCP服务器的运行状态，服务器未配置或未启用时返回 false
+func (m *MCPClientManager) ServerStatus(serverName string) (*ServerStatus, bool) {
+	m.mu.RLock()
+	server, exists := m.servers[serverName]
+	m.mu.RUnlock()
 	if !exists {
-		return fmt.Errorf("MCP服务器 %s 不存在或未初始化", serverName)
+		return nil, false
 	}
+	return server.status(), true
+}
 
-	// 关闭客户端
-	if err := cli.Close(); err != nil {
-		return fmt.Errorf("关闭MCP客户端失败: %w", err)
+// CloseMCPServer 关闭指定MCP服务器，配置文件再次修改时会重新连接
+func (m *MCPClientManager) CloseMCPServer(serverName string) error {
+	m.mu.Lock()
+	server, exists := m.servers[serverName]
+	delete(m.servers, serverName)
+	m.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("MCP服务器 %s 不存在或未初始化", serverName)
 	}
 
-	// 删除客户端和工具
-	delete(m.clients, serverName)
-	delete(m.tools, serverName)
-
+	server.stop()
 	return nil
 }
 
-// CloseAllMCPServers 关闭所有MCP服务器
+// CloseAllMCPServers 关闭所有MCP服务器，并停止监听配置文件
 func (m *MCPClientManager) CloseAllMCPServers() error {
-	var lastErr error
-	for name := range m.clients {
-		if err := m.CloseMCPServer(name); err != nil {
-			lastErr = err
-		}
+	m.mu.Lock()
+	m.closed = true
+	if m.watcher != nil {
+		_ = m.watcher.Close()
+		m.watcher = nil
+	}
+	servers := m.servers
+	m.servers = make(map[string]*mcpServer)
+	m.mu.Unlock()
+
+	for _, server := range servers {
+		server.stop()
 	}
-	return lastErr
+	return nil
 }
 
 // loadMCPServerConfigs 加载MCP服务器配置
 func loadMCPServerConfigs(configPath string) (*mCPServerConfigs, error) {
-	if configPath == "" {
-		configPath = "./" + global.RuntimeDir + "/mcp_config.json"
-	}
-
 	// 确保配置文件存在
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("配置文件不存在: %s", configPath)
@@ -137,45 +383,96 @@ func loadMCPServerConfigs(configPath string) (*mCPServerConfigs, error) {
 		return nil, fmt.Errorf("解析配置文件失败: %w", err)
 	}
 
+	// 校验配置，避免启动后才发现配置错误
+	if err := configs.validate(); err != nil {
+		return nil, fmt.Errorf("MCP配置错误: %w", err)
+	}
+
 	return &configs, nil
 }
 
-// initMCPServer 初始化单个MCP服务器
-func initMCPServer(m *MCPClientManager, config mCPServerConfig) error {
-	// 将 config.Environment 转换为字符串数组
-	var envs []string
-	for key, value := range config.Environment {
-		envs = append(envs, fmt.Sprintf("%s=%s", key, value))
+// validate 校验所有MCP服务器配置，并补全默认值
+func (c *mCPServerConfigs) validate() error {
+	names := make(map[string]bool, len(c.Servers))
+	for i := range c.Servers {
+		config := &c.Servers[i]
+		if config.Name == "" {
+			return fmt.Errorf("第 %d 个服务器缺少 name", i+1)
+		}
+		if names[config.Name] {
+			return fmt.Errorf("服务器 %s 重复", config.Name)
+		}
+		names[config.Name] = true
+		if err := config.Validate(); err != nil {
+			return fmt.Errorf("服务器 %s: %w", config.Name, err)
+		}
 	}
+	return nil
+}
 
-	// 创建stdio MCP客户端
-	cli, err := client.NewStdioMCPClient(config.Command, envs, config.Args...)
-	if err != nil {
-		return fmt.Errorf("创建MCP客户端失败: %w", err)
+// Validate 校验单个MCP服务器配置，并补全默认值
+func (c *MCPServerConfig) Validate() error {
+	if c.Transport == "" {
+		c.Transport = TransportStdio
 	}
-
-	// 初始化MCP客户端
-	initRequest := mcp.InitializeRequest{}
-	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
-	initRequest.Params.ClientInfo = mcp.Implementation{
-		Name:    config.Name,
-		Version: "1.0.0",
+	switch c.Transport {
+	case TransportStdio:
+		if c.Command == "" {
+			return fmt.Errorf("stdio 方式需要配置 command")
+		}
+		if c.URL != "" {
+			return fmt.Errorf("stdio 方式不支持配置 url")
+		}
+	case TransportSSE, TransportStreamableHTTP:
+		if c.URL == "" {
+			return fmt.Errorf("%s 方式需要配置 url", c.Transport)
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url 不是合法的 http(s) 地址: %s", c.URL)
+		}
+		if c.Command != "" {
+			return fmt.Errorf("%s 方式不支持配置 command", c.Transport)
+		}
+	default:
+		return fmt.Errorf("不支持的传输方式: %s，可选值为 %s、%s、%s",
+			c.Transport, TransportStdio, TransportSSE, TransportStreamableHTTP)
 	}
+	return nil
+}
 
-	_, err = cli.Initialize(m.ctx, initRequest)
-	if err != nil {
-		return fmt.Errorf("初始化MCP客户端失败: %w", err)
-	}
+// sameConnection 连接配置是否相同，禁用的工具不影响连接
+func (c *MCPServerConfig) sameConnection(other MCPServerConfig) bool {
+	a, b := *c, other
+	a.DisabledTools, b.DisabledTools = nil, nil
+	return reflect.DeepEqual(a, b)
+}
 
-	// 获取MCP工具
-	mcpTools, err := einomcp.GetTools(m.ctx, &einomcp.Config{Cli: cli})
-	if err != nil {
-		return fmt.Errorf("获取MCP工具失败: %w", err)
+// headers 远程MCP服务器的请求头，包括认证令牌
+func (c *MCPServerConfig) headers() map[string]string {
+	headers := make(map[string]string, len(c.Headers)+1)
+	for key, value := range c.Headers {
+		headers[key] = os.ExpandEnv(value)
 	}
+	if c.AuthToken != "" {
+		headers["Authorization"] = "Bearer " + os.ExpandEnv(c.AuthToken)
+	}
+	return headers
+}
 
-	// 存储客户端和工具
-	m.clients[config.Name] = cli
-	m.tools[config.Name] = mcpTools
-
-	return nil
+// newMCPClient 根据传输方式创建MCP客户端
+func newMCPClient(config MCPServerConfig) (*client.Client, error) {
+	switch config.Transport {
+	case TransportSSE:
+		return client.NewSSEMCPClient(config.URL, transport.WithHeaders(config.headers()))
+	case TransportStreamableHTTP:
+		return client.NewStreamableHttpClient(config.URL, transport.WithHTTPHeaders(config.headers()))
+	default:
+		// 将 config.Environment 转换为字符串数组
+		var envs []string
+		for key, value := range config.Environment {
+			envs = append(envs, fmt.Sprintf("%s=%s", key, value))
+		}
+		return client.NewStdioMCPClient(config.Command, envs, config.Args...)
+	}
 }
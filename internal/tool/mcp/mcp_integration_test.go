@@ -0,0 +1,459 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// newTestMCPServer 创建一个带 echo 工具的 MCP 服务器
+func newTestMCPServer() *server.MCPServer {
+	s := server.NewMCPServer("test-server", "1.0.0")
+	s.AddTool(mcp.NewTool("echo",
+		mcp.WithDescription("Echo the message"),
+		mcp.WithString("message", mcp.Required()),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("echo: " + request.GetString("message", "")), nil
+	})
+	return s
+}
+
+// headerRecorder 记录远程 MCP 服务器收到的认证请求头
+type headerRecorder struct {
+	mu            sync.Mutex
+	authorization string
+}
+
+func (r *headerRecorder) record(ctx context.Context, req *http.Request) context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		r.authorization = auth
+	}
+	return ctx
+}
+
+func (r *headerRecorder) get() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.authorization
+}
+
+func TestMCPClientManager_RemoteTransports(t *testing.T) {
+	t.Setenv("TEST_MCP_TOKEN", "secret")
+
+	tests := []struct {
+		name      string
+		transport string
+		newServer func(recorder *headerRecorder) (url string, closeFn func())
+	}{
+		{
+			name:      "sse",
+			transport: TransportSSE,
+			newServer: func(recorder *headerRecorder) (string, func()) {
+				ts := server.NewTestServer(newTestMCPServer(), server.WithSSEContextFunc(recorder.record))
+				return ts.URL + "/sse", ts.Close
+			},
+		},
+		{
+			name:      "streamable-http",
+			transport: TransportStreamableHTTP,
+			newServer: func(recorder *headerRecorder) (string, func()) {
+				ts := server.NewTestStreamableHTTPServer(newTestMCPServer(), server.WithHTTPContextFunc(recorder.record))
+				return ts.URL + "/mcp", ts.Close
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &headerRecorder{}
+			serverURL, closeServer := tt.newServer(recorder)
+			defer closeServer()
+
+			configPath := writeMCPConfig(t, mCPServerConfigs{Servers: []MCPServerConfig{{
+				Name:      "remote",
+				Transport: tt.transport,
+				URL:       serverURL,
+				AuthToken: "${TEST_MCP_TOKEN}",
+			}}})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			manager := NewMCPClientManager(ctx, nil)
+			if err := manager.Init(configPath); err != nil {
+				t.Fatalf("Init() error = %v", err)
+			}
+			defer manager.CloseAllMCPServers()
+
+			tools, err := manager.GetMCPTools("remote")
+			if err != nil {
+				t.Fatalf("GetMCPTools() error = %v", err)
+			}
+			if len(tools) != 1 {
+				t.Fatalf("GetMCPTools() got %d tools, want 1", len(tools))
+			}
+			echo, ok := tools[0].(tool.InvokableTool)
+			if !ok {
+				t.Fatalf("tool is not invokable")
+			}
+			output, err := echo.InvokableRun(ctx, `{"message":"hi"}`)
+			if err != nil {
+				t.Fatalf("InvokableRun() error = %v", err)
+			}
+			if !strings.Contains(output, "echo: hi") {
+				t.Errorf("InvokableRun() = %s, want contains echo: hi", output)
+			}
+			if got := recorder.get(); got != "Bearer secret" {
+				t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+			}
+		})
+	}
+}
+
+func TestMCPServerConfigs_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []MCPServerConfig
+		wantErr bool
+	}{
+		{
+			name:    "stdio 默认传输方式",
+			servers: []MCPServerConfig{{Name: "amap", Command: "npx"}},
+		},
+		{
+			name:    "streamable-http",
+			servers: []MCPServerConfig{{Name: "amap", Transport: TransportStreamableHTTP, URL: "https://mcp.amap.com/mcp"}},
+		},
+		{
+			name:    "缺少名称",
+			servers: []MCPServerConfig{{Command: "npx"}},
+			wantErr: true,
+		},
+		{
+			name:    "名称重复",
+			servers: []MCPServerConfig{{Name: "a", Command: "npx"}, {Name: "a", Command: "npx"}},
+			wantErr: true,
+		},
+		{
+			name:    "stdio 缺少命令",
+			servers: []MCPServerConfig{{Name: "a", Transport: TransportStdio}},
+			wantErr: true,
+		},
+		{
+			name:    "sse 缺少地址",
+			servers: []MCPServerConfig{{Name: "a", Transport: TransportSSE}},
+			wantErr: true,
+		},
+		{
+			name:    "地址不是 http(s)",
+			servers: []MCPServerConfig{{Name: "a", Transport: TransportSSE, URL: "ftp://example.com/sse"}},
+			wantErr: true,
+		},
+		{
+			name:    "不支持的传输方式",
+			servers: []MCPServerConfig{{Name: "a", Transport: "websocket", URL: "https://example.com"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs := &mCPServerConfigs{Servers: tt.servers}
+			if err := configs.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMCPClientManager_Lifecycle(t *testing.T) {
+	mcpServer := newTestMCPServer()
+	serve := func(addr string) (string, *http.Server) {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		httpServer := &http.Server{Handler: server.NewStreamableHTTPServer(mcpServer)}
+		go httpServer.Serve(listener)
+		return listener.Addr().String(), httpServer
+	}
+	addr, httpServer := serve("127.0.0.1:0")
+	other := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer other.Close()
+
+	configPath := filepath.Join(t.TempDir(), "mcp_config.json")
+	writeMCPConfigTo(t, configPath, mCPServerConfigs{Servers: []MCPServerConfig{
+		{Name: "a", Transport: TransportStreamableHTTP, URL: "http://" + addr + "/mcp"},
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := NewMCPClientManager(ctx, &global.MCPConfig{HealthCheckInterval: 1, MaxReconnectBackoff: 1})
+	if err := manager.Init(configPath); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer manager.CloseAllMCPServers()
+	toolCount := func(name string) int {
+		tools, err := manager.GetMCPTools(name)
+		if err != nil {
+			return -1
+		}
+		return len(tools)
+	}
+
+	// 服务器的工具列表变化后刷新
+	mcpServer.AddTool(mcp.NewTool("ping_tool"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("pong"), nil
+	})
+	waitFor(t, "tools refreshed", func() bool { return toolCount("a") == 2 })
+
+	// 服务器断开后清空工具，恢复后自动重连
+	_ = httpServer.Close()
+	waitFor(t, "tools cleared", func() bool { return toolCount("a") == 0 })
+	_, httpServer = serve(addr)
+	defer httpServer.Close()
+	waitFor(t, "reconnected", func() bool { return toolCount("a") == 2 })
+
+	// 修改配置文件后新增和删除服务器
+	writeMCPConfigTo(t, configPath, mCPServerConfigs{Servers: []MCPServerConfig{
+		{Name: "b", Transport: TransportStreamableHTTP, URL: other.URL + "/mcp"},
+	}})
+	waitFor(t, "config reloaded", func() bool { return toolCount("a") == -1 && toolCount("b") == 1 })
+
+	// 配置不合法时保持当前的服务器
+	if err := os.WriteFile(configPath, []byte(`{"servers":[{"name":"c","transport":"unknown"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * configReloadDelay)
+	if got := toolCount("b"); got != 1 {
+		t.Errorf("invalid config should keep current servers, got %d tools", got)
+	}
+}
+
+func TestMCPClientManager_ManagedServers(t *testing.T) {
+	fileServer := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer fileServer.Close()
+	managedServer := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer managedServer.Close()
+
+	configPath := writeMCPConfig(t, mCPServerConfigs{Servers: []MCPServerConfig{
+		{Name: "file", Transport: TransportStreamableHTTP, URL: fileServer.URL + "/mcp"},
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := NewMCPClientManager(ctx, nil)
+	if err := manager.Init(configPath); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer manager.CloseAllMCPServers()
+
+	managed := MCPServerConfig{Name: "managed", Transport: TransportStreamableHTTP, URL: managedServer.URL + "/mcp"}
+
+	// 测试连接不影响正在运行的服务器
+	tools, err := TestConnection(ctx, managed)
+	if err != nil {
+		t.Fatalf("TestConnection() error = %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "echo" || !tools[0].Enabled {
+		t.Errorf("TestConnection() = %+v, want enabled echo tool", tools)
+	}
+	if _, ok := manager.ServerStatus("managed"); ok {
+		t.Errorf("TestConnection() should not start server")
+	}
+
+	// 禁用的工具不提供给智能体
+	managed.DisabledTools = []string{"echo"}
+	if err := manager.SetManagedServers([]MCPServerConfig{managed}); err != nil {
+		t.Fatalf("SetManagedServers() error = %v", err)
+	}
+	if got := len(manager.GetAllMCPTools()); got != 1 {
+		t.Errorf("GetAllMCPTools() got %d tools, want 1", got)
+	}
+	status, ok := manager.ServerStatus("managed")
+	if !ok || !status.Connected || len(status.Tools) != 1 || status.Tools[0].Enabled {
+		t.Errorf("ServerStatus() = %+v, want connected with disabled echo tool", status)
+	}
+
+	// 只修改禁用的工具时不重新连接
+	before := manager.servers["managed"]
+	managed.DisabledTools = nil
+	if err := manager.SetManagedServers([]MCPServerConfig{managed}); err != nil {
+		t.Fatalf("SetManagedServers() error = %v", err)
+	}
+	if after := manager.servers["managed"]; after != before {
+		t.Errorf("changing disabled tools should not reconnect")
+	}
+	if got := len(manager.GetAllMCPTools()); got != 2 {
+		t.Errorf("GetAllMCPTools() got %d tools, want 2", got)
+	}
+
+	// 名称相同时以管理后台的配置为准，删除后恢复配置文件中的服务器
+	override := MCPServerConfig{Name: "file", Transport: TransportStreamableHTTP, URL: managedServer.URL + "/mcp"}
+	if err := manager.SetManagedServers([]MCPServerConfig{override}); err != nil {
+		t.Fatalf("SetManagedServers() error = %v", err)
+	}
+	if got := manager.servers["file"].config.URL; got != override.URL {
+		t.Errorf("managed server should override config file, got url %s", got)
+	}
+	if _, ok := manager.ServerStatus("managed"); ok {
+		t.Errorf("removed managed server should be stopped")
+	}
+	if err := manager.SetManagedServers(nil); err != nil {
+		t.Fatalf("SetManagedServers() error = %v", err)
+	}
+	if got := manager.servers["file"].config.URL; got != fileServer.URL+"/mcp" {
+		t.Errorf("config file server should be restored, got url %s", got)
+	}
+
+	// 配置不合法时不修改当前的服务器
+	if err := manager.SetManagedServers([]MCPServerConfig{{Name: "bad", Transport: TransportSSE}}); err == nil {
+		t.Errorf("SetManagedServers() with invalid config should return error")
+	}
+	if _, ok := manager.ServerStatus("file"); !ok {
+		t.Errorf("invalid config should keep current servers")
+	}
+}
+
+// waitFor 等待条件满足，超时后测试失败
+func waitFor(t *testing.T, name string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("wait for %s timeout", name)
+}
+
+func writeMCPConfig(t *testing.T, configs mCPServerConfigs) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mcp_config.json")
+	writeMCPConfigTo(t, path, configs)
+	return path
+}
+
+func writeMCPConfigTo(t *testing.T, path string, configs mCPServerConfigs) {
+	t.Helper()
+	data, err := json.Marshal(configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMCPClientManager_ResourcesAndPrompts(t *testing.T) {
+	mcpServer := newTestMCPServer()
+	mcpServer.AddResource(mcp.NewResource("docs://readme", "readme", mcp.WithMIMEType("text/markdown")),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "text/markdown", Text: "# readme"}}, nil
+		})
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate("docs://{name}", "doc"),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: request.Params.URI, Text: "doc"}}, nil
+		})
+	mcpServer.AddPrompt(mcp.NewPrompt("review", mcp.WithPromptDescription("Code review"),
+		mcp.WithArgument("language", mcp.RequiredArgument())),
+		func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return mcp.NewGetPromptResult("Code review", []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("Review "+request.Params.Arguments["language"]+" code")),
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewImageContent("aGk=", "image/png")),
+			}), nil
+		})
+	withResources := server.NewTestStreamableHTTPServer(mcpServer)
+	defer withResources.Close()
+	toolsOnly := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer toolsOnly.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := NewMCPClientManager(ctx, nil)
+	defer manager.CloseAllMCPServers()
+	err := manager.SetManagedServers([]MCPServerConfig{
+		{Name: "docs", Transport: TransportStreamableHTTP, URL: withResources.URL + "/mcp"},
+		{Name: "tools", Transport: TransportStreamableHTTP, URL: toolsOnly.URL + "/mcp"},
+	})
+	if err != nil {
+		t.Fatalf("SetManagedServers() error = %v", err)
+	}
+	if got := strings.Join(manager.ServerNames(), ","); got != "docs,tools" {
+		t.Errorf("ServerNames() = %s", got)
+	}
+	if status, _ := manager.ServerStatus("docs"); !status.SupportsResources || !status.SupportsPrompts {
+		t.Errorf("ServerStatus() = %+v, want resources and prompts supported", status)
+	}
+
+	resources, templates, err := manager.ListResources(ctx, "docs")
+	if err != nil {
+		t.Fatalf("ListResources() error = %v", err)
+	}
+	if len(resources) != 1 || resources[0].URI != "docs://readme" || resources[0].MIMEType != "text/markdown" {
+		t.Errorf("ListResources() resources = %+v", resources)
+	}
+	if len(templates) != 1 || templates[0].URITemplate != "docs://{name}" {
+		t.Errorf("ListResources() templates = %+v", templates)
+	}
+	contents, err := manager.ReadResource(ctx, "docs", "docs://readme")
+	if err != nil {
+		t.Fatalf("ReadResource() error = %v", err)
+	}
+	if len(contents) != 1 || contents[0].Text != "# readme" {
+		t.Errorf("ReadResource() = %+v", contents)
+	}
+
+	// 普通用户只能读取列出的资源，不能读取填充资源模板得到的地址
+	if contents, err := manager.ReadListedResource(ctx, "docs", "docs://readme"); err != nil || len(contents) != 1 {
+		t.Errorf("ReadListedResource() = %+v, %v", contents, err)
+	}
+	if _, err := manager.ReadListedResource(ctx, "docs", "docs://secret"); !errors.Is(err, ErrResourceNotListed) {
+		t.Errorf("ReadListedResource() template uri error = %v, want %v", err, ErrResourceNotListed)
+	}
+	if contents, err := manager.ReadResource(ctx, "docs", "docs://secret"); err != nil || len(contents) != 1 || contents[0].Text != "doc" {
+		t.Errorf("ReadResource() template uri = %+v, %v", contents, err)
+	}
+
+	prompts, err := manager.ListPrompts(ctx, "docs")
+	if err != nil {
+		t.Fatalf("ListPrompts() error = %v", err)
+	}
+	if len(prompts) != 1 || prompts[0].Name != "review" || len(prompts[0].Arguments) != 1 || !prompts[0].Arguments[0].Required {
+		t.Errorf("ListPrompts() = %+v", prompts)
+	}
+	description, messages, err := manager.GetPrompt(ctx, "docs", "review", map[string]string{"language": "Go"})
+	if err != nil {
+		t.Fatalf("GetPrompt() error = %v", err)
+	}
+	if description != "Code review" || len(messages) != 1 || messages[0].Text != "Review Go code" {
+		t.Errorf("GetPrompt() = %s, %+v", description, messages)
+	}
+
+	// 不支持资源的服务器
+	if _, _, err := manager.ListResources(ctx, "tools"); err == nil {
+		t.Errorf("ListResources() on tools-only server should fail")
+	}
+	if _, err := manager.ListPrompts(ctx, "missing"); err == nil {
+		t.Errorf("ListPrompts() on missing server should fail")
+	}
+}
@@ -0,0 +1,271 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+	"txing-ai/internal/global/logging/log"
+)
+
+// ErrResourceNotListed 资源地址不在MCP服务器列出的资源中
+var ErrResourceNotListed = errors.New("只能读取MCP服务器列出的资源")
+
+// Resource MCP服务器提供的资源
+type Resource struct {
+	URI         string
+	Name        string
+	Description string
+	MIMEType    string
+}
+
+// ResourceTemplate MCP服务器提供的资源模板，URI 中的参数由调用方填充
+type ResourceTemplate struct {
+	URITemplate string
+	Name        string
+	Description string
+	MIMEType    string
+}
+
+// ResourceContent 资源的内容，文本资源为 Text，二进制资源为 base64 编码的 Blob
+type ResourceContent struct {
+	URI      string
+	MIMEType string
+	Text     string
+	Blob     string
+}
+
+// Prompt MCP服务器提供的 prompt 模板
+type Prompt struct {
+	Name        string
+	Description string
+	Arguments   []PromptArgument
+}
+
+// PromptArgument prompt 模板的参数
+type PromptArgument struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// PromptMessage 获取 prompt 得到的消息，非文本内容（图片、音频等）会被忽略
+type PromptMessage struct {
+	Role string
+	Text string
+}
+
+// ServerNames 获取所有MCP服务器的名称，按名称排序
+func (m *MCPClientManager) ServerNames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ListResources 获取指定MCP服务器的资源和资源模板
+func (m *MCPClientManager) ListResources(ctx context.Context, serverName string) ([]Resource, []ResourceTemplate, error) {
+	cli, err := m.resourceClient(serverName)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	result, err := cli.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取MCP资源失败: %w", err)
+	}
+	resources := make([]Resource, 0, len(result.Resources))
+	for _, r := range result.Resources {
+		resources = append(resources, Resource{
+			URI:         r.URI,
+			Name:        r.Name,
+			Description: r.Description,
+			MIMEType:    r.MIMEType,
+		})
+	}
+
+	// 部分服务器没有实现资源模板，获取失败时只返回资源
+	templateResult, err := cli.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	if err != nil {
+		log.Warn("list mcp resource templates failed", zap.String("server", serverName), zap.Error(err))
+		return resources, nil, nil
+	}
+	templates := make([]ResourceTemplate, 0, len(templateResult.ResourceTemplates))
+	for _, t := range templateResult.ResourceTemplates {
+		template := ResourceTemplate{
+			Name:        t.Name,
+			Description: t.Description,
+			MIMEType:    t.MIMEType,
+		}
+		if t.URITemplate != nil && t.URITemplate.Template != nil {
+			template.URITemplate = t.URITemplate.Raw()
+		}
+		templates = append(templates, template)
+	}
+	return resources, templates, nil
+}
+
+// ReadResource 读取指定MCP服务器的资源
+func (m *MCPClientManager) ReadResource(ctx context.Context, serverName string, uri string) ([]ResourceContent, error) {
+	cli, err := m.resourceClient(serverName)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	result, err := cli.ReadResource(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("读取MCP资源失败: %w", err)
+	}
+	contents := make([]ResourceContent, 0, len(result.Contents))
+	for _, c := range result.Contents {
+		switch content := c.(type) {
+		case mcp.TextResourceContents:
+			contents = append(contents, ResourceContent{URI: content.URI, MIMEType: content.MIMEType, Text: content.Text})
+		case mcp.BlobResourceContents:
+			contents = append(contents, ResourceContent{URI: content.URI, MIMEType: content.MIMEType, Blob: content.Blob})
+		}
+	}
+	return contents, nil
+}
+
+// ReadListedResource 读取MCP服务器列出的资源，不允许读取填充资源模板得到的地址
+// 资源模板可以拼出任意地址（例如文件系统服务器的 file:///etc/passwd），普通用户只能读取服务器主动列出的资源
+func (m *MCPClientManager) ReadListedResource(ctx context.Context, serverName string, uri string) ([]ResourceContent, error) {
+	cli, err := m.resourceClient(serverName)
+	if err != nil {
+		return nil, err
+	}
+	listCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	result, err := cli.ListResources(listCtx, mcp.ListResourcesRequest{})
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("获取MCP资源失败: %w", err)
+	}
+	for _, r := range result.Resources {
+		if r.URI == uri {
+			return m.ReadResource(ctx, serverName, uri)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrResourceNotListed, uri)
+}
+
+// ListPrompts 获取指定MCP服务器的 prompt 模板
+func (m *MCPClientManager) ListPrompts(ctx context.Context, serverName string) ([]Prompt, error) {
+	cli, err := m.promptClient(serverName)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	result, err := cli.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("获取MCP prompt 失败: %w", err)
+	}
+	prompts := make([]Prompt, 0, len(result.Prompts))
+	for _, p := range result.Prompts {
+		prompt := Prompt{
+			Name:        p.Name,
+			Description: p.Description,
+			Arguments:   make([]PromptArgument, 0, len(p.Arguments)),
+		}
+		for _, arg := range p.Arguments {
+			prompt.Arguments = append(prompt.Arguments, PromptArgument{
+				Name:        arg.Name,
+				Description: arg.Description,
+				Required:    arg.Required,
+			})
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts, nil
+}
+
+// GetPrompt 使用指定参数获取MCP服务器的 prompt，返回 prompt 的说明和消息
+func (m *MCPClientManager) GetPrompt(ctx context.Context, serverName string, name string,
+	arguments map[string]string) (string, []PromptMessage, error) {
+	cli, err := m.promptClient(serverName)
+	if err != nil {
+		return "", nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = arguments
+	result, err := cli.GetPrompt(ctx, request)
+	if err != nil {
+		return "", nil, fmt.Errorf("获取MCP prompt 失败: %w", err)
+	}
+	messages := make([]PromptMessage, 0, len(result.Messages))
+	for _, message := range result.Messages {
+		text := ""
+		switch content := message.Content.(type) {
+		case mcp.TextContent:
+			text = content.Text
+		case mcp.EmbeddedResource:
+			if resource, ok := content.Resource.(mcp.TextResourceContents); ok {
+				text = resource.Text
+			}
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		messages = append(messages, PromptMessage{Role: string(message.Role), Text: text})
+	}
+	return result.Description, messages, nil
+}
+
+// resourceClient 获取支持资源的MCP服务器的客户端
+func (m *MCPClientManager) resourceClient(serverName string) (*client.Client, error) {
+	cli, err := m.connectedClient(serverName)
+	if err != nil {
+		return nil, err
+	}
+	if cli.GetServerCapabilities().Resources == nil {
+		return nil, fmt.Errorf("MCP服务器 %s 不支持资源", serverName)
+	}
+	return cli, nil
+}
+
+// promptClient 获取支持 prompt 的MCP服务器的客户端
+func (m *MCPClientManager) promptClient(serverName string) (*client.Client, error) {
+	cli, err := m.connectedClient(serverName)
+	if err != nil {
+		return nil, err
+	}
+	if cli.GetServerCapabilities().Prompts == nil {
+		return nil, fmt.Errorf("MCP服务器 %s 不支持 prompt", serverName)
+	}
+	return cli, nil
+}
+
+func (m *MCPClientManager) connectedClient(serverName string) (*client.Client, error) {
+	m.mu.RLock()
+	server, exists := m.servers[serverName]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("MCP服务器 %s 不存在或未初始化", serverName)
+	}
+	cli := server.currentClient()
+	if cli == nil {
+		return nil, fmt.Errorf("MCP服务器 %s 未连接", serverName)
+	}
+	return cli, nil
+}
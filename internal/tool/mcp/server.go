@@ -0,0 +1,359 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	einomcp "github.com/cloudwego/eino-ext/components/tool/mcp"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+	"txing-ai/internal/global/logging/log"
+)
+
+const (
+	// 断线重连的初始退避时间，每次失败后翻倍
+	minReconnectBackoff = time.Second
+	// 初始化、健康检查等请求的超时时间
+	requestTimeout = 30 * time.Second
+)
+
+// mcpServer 单个MCP服务器的连接，后台定期健康检查并刷新工具列表，断开后按退避时间重连
+type mcpServer struct {
+	config              MCPServerConfig
+	healthCheckInterval time.Duration
+	maxReconnectBackoff time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// 后台协程退出后关闭
+	done chan struct{}
+
+	mu     sync.RWMutex
+	client *client.Client
+	tools  []tool.BaseTool
+	// 禁用的工具，修改后不需要重新连接
+	disabledTools map[string]bool
+	// 最近一次连接或健康检查的错误
+	lastErr error
+}
+
+// ToolInfo MCP服务器提供的工具
+type ToolInfo struct {
+	Name        string
+	Description string
+	// 是否允许智能体使用
+	Enabled bool
+}
+
+// ServerStatus MCP服务器的运行状态
+type ServerStatus struct {
+	Connected bool
+	// 服务器提供的所有工具，包括禁用的工具
+	Tools []ToolInfo
+	// 是否支持资源和 prompt
+	SupportsResources bool
+	SupportsPrompts   bool
+	// 最近一次连接或健康检查的错误
+	LastError string
+}
+
+func newMCPServer(ctx context.Context, config MCPServerConfig,
+	healthCheckInterval, maxReconnectBackoff time.Duration) *mcpServer {
+	ctx, cancel := context.WithCancel(ctx)
+	return &mcpServer{
+		config:              config,
+		healthCheckInterval: healthCheckInterval,
+		maxReconnectBackoff: maxReconnectBackoff,
+		ctx:                 ctx,
+		cancel:              cancel,
+		done:                make(chan struct{}),
+		disabledTools:       toolNameSet(config.DisabledTools),
+	}
+}
+
+// start 连接服务器并启动后台监控，首次连接失败时也会在后台重连
+func (s *mcpServer) start() error {
+	err := s.connect()
+	if err != nil {
+		s.setErr(err)
+	}
+	go s.supervise()
+	return err
+}
+
+// stop 停止后台监控并断开连接
+func (s *mcpServer) stop() {
+	s.cancel()
+	<-s.done
+	s.disconnect(nil)
+}
+
+// currentTools 当前可用的工具，不包括禁用的工具
+func (s *mcpServer) currentTools() []tool.BaseTool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.disabledTools) == 0 {
+		return s.tools
+	}
+	tools := make([]tool.BaseTool, 0, len(s.tools))
+	for _, t := range s.tools {
+		info, err := t.Info(s.ctx)
+		if err != nil || s.disabledTools[info.Name] {
+			continue
+		}
+		tools = append(tools, t)
+	}
+	return tools
+}
+
+// setDisabledTools 修改禁用的工具
+func (s *mcpServer) setDisabledTools(names []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disabledTools = toolNameSet(names)
+}
+
+// status 当前的运行状态
+func (s *mcpServer) status() *ServerStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status := &ServerStatus{
+		Connected: s.client != nil,
+		Tools:     toolInfos(s.ctx, s.tools, s.disabledTools),
+	}
+	if s.client != nil {
+		capabilities := s.client.GetServerCapabilities()
+		status.SupportsResources = capabilities.Resources != nil
+		status.SupportsPrompts = capabilities.Prompts != nil
+	}
+	if s.lastErr != nil {
+		status.LastError = s.lastErr.Error()
+	}
+	return status
+}
+
+func (s *mcpServer) currentClient() *client.Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.client
+}
+
+func (s *mcpServer) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+}
+
+// connect 创建客户端，完成初始化握手并获取工具
+func (s *mcpServer) connect() error {
+	cli, mcpTools, err := dial(s.ctx, s.config)
+	if err != nil {
+		return err
+	}
+
+	// 服务器通知工具列表变化时刷新工具
+	cli.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method == mcp.MethodNotificationToolsListChanged {
+			go func() {
+				if err := s.refreshTools(); err != nil {
+					log.Warn("refresh mcp tools failed", zap.String("server", s.config.Name), zap.Error(err))
+				}
+			}()
+		}
+	})
+
+	s.mu.Lock()
+	s.client = cli
+	s.tools = mcpTools
+	s.lastErr = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// disconnect 断开连接并清空工具
+func (s *mcpServer) disconnect(err error) {
+	s.mu.Lock()
+	cli := s.client
+	s.client = nil
+	s.tools = nil
+	if err != nil {
+		s.lastErr = err
+	}
+	s.mu.Unlock()
+
+	if cli != nil {
+		if err := cli.Close(); err != nil {
+			log.Warn("close mcp client failed", zap.String("server", s.config.Name), zap.Error(err))
+		}
+	}
+}
+
+// refreshTools 重新获取工具列表
+func (s *mcpServer) refreshTools() error {
+	cli := s.currentClient()
+	if cli == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, requestTimeout)
+	defer cancel()
+	mcpTools, err := einomcp.GetTools(ctx, &einomcp.Config{Cli: cli})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 刷新期间已经重连时丢弃旧连接的结果
+	if s.client != cli {
+		return nil
+	}
+	if len(mcpTools) != len(s.tools) {
+		log.Info("mcp tools changed", zap.String("server", s.config.Name),
+			zap.Int("before", len(s.tools)), zap.Int("after", len(mcpTools)))
+	}
+	s.tools = mcpTools
+	return nil
+}
+
+// healthCheck 发送 ping 检查连接，并刷新工具列表（部分服务器不会发送工具列表变化的通知）
+func (s *mcpServer) healthCheck() error {
+	cli := s.currentClient()
+	if cli == nil {
+		return fmt.Errorf("MCP服务器未连接")
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, requestTimeout)
+	defer cancel()
+	if err := cli.Ping(ctx); err != nil {
+		return fmt.Errorf("ping 失败: %w", err)
+	}
+	return s.refreshTools()
+}
+
+// supervise 后台监控：连接正常时定期健康检查，断开后按指数退避重连
+func (s *mcpServer) supervise() {
+	defer close(s.done)
+
+	backoff := minReconnectBackoff
+	for {
+		connected := s.currentClient() != nil
+		wait := s.healthCheckInterval
+		if !connected {
+			wait = backoff
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if connected {
+			if err := s.healthCheck(); err != nil {
+				if s.ctx.Err() != nil {
+					return
+				}
+				log.Warn("mcp server health check failed, reconnecting",
+					zap.String("server", s.config.Name), zap.Error(err))
+				s.disconnect(err)
+				backoff = minReconnectBackoff
+			}
+			continue
+		}
+
+		if err := s.connect(); err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.setErr(err)
+			backoff = min(backoff*2, s.maxReconnectBackoff)
+			log.Warn("mcp server reconnect failed", zap.String("server", s.config.Name),
+				zap.Duration("retry_in", backoff), zap.Error(err))
+			continue
+		}
+		log.Info("mcp server reconnected", zap.String("server", s.config.Name))
+		backoff = minReconnectBackoff
+	}
+}
+
+// dial 连接MCP服务器，完成初始化握手并获取工具
+// ctx 同时作为 SSE 长连接的生命周期，请求超时单独控制
+func dial(ctx context.Context, config MCPServerConfig) (*client.Client, []tool.BaseTool, error) {
+	cli, err := newMCPClient(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建MCP客户端失败: %w", err)
+	}
+
+	// 启动传输层（stdio 方式在创建时已经启动）
+	if err := cli.Start(ctx); err != nil {
+		_ = cli.Close()
+		return nil, nil, fmt.Errorf("连接MCP服务器失败: %w", err)
+	}
+
+	requestCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	// 初始化MCP客户端
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    config.Name,
+		Version: "1.0.0",
+	}
+	if _, err := cli.Initialize(requestCtx, initRequest); err != nil {
+		_ = cli.Close()
+		return nil, nil, fmt.Errorf("初始化MCP客户端失败: %w", err)
+	}
+
+	// 获取MCP工具
+	mcpTools, err := einomcp.GetTools(requestCtx, &einomcp.Config{Cli: cli})
+	if err != nil {
+		_ = cli.Close()
+		return nil, nil, fmt.Errorf("获取MCP工具失败: %w", err)
+	}
+	return cli, mcpTools, nil
+}
+
+// TestConnection 使用指定配置连接MCP服务器并获取工具列表，完成后断开连接，不影响正在运行的服务器
+func TestConnection(ctx context.Context, config MCPServerConfig) ([]ToolInfo, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	cli, mcpTools, err := dial(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := cli.Close(); err != nil {
+			log.Warn("close mcp client failed", zap.String("server", config.Name), zap.Error(err))
+		}
+	}()
+	return toolInfos(ctx, mcpTools, toolNameSet(config.DisabledTools)), nil
+}
+
+// toolInfos 获取工具的名称和描述，并标记是否禁用
+func toolInfos(ctx context.Context, tools []tool.BaseTool, disabledTools map[string]bool) []ToolInfo {
+	infos := make([]ToolInfo, 0, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, ToolInfo{
+			Name:        info.Name,
+			Description: info.Desc,
+			Enabled:     !disabledTools[info.Name],
+		})
+	}
+	return infos
+}
+
+func toolNameSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
@@ -0,0 +1,258 @@
+package office
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	docxDocumentType  = "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"
+	docxStylesType    = "application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"
+	docxNumberingType = "application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"
+
+	relOfficeDocument = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"
+	relStyles         = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	relNumbering      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering"
+	relHyperlink      = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+
+	// A4 纸张去掉左右各 2.54 厘米页边距后的宽度，单位 twip（1/20 磅）
+	docxTextWidth = 11906 - 2*1440
+	// 无序列表使用的编号
+	docxBulletNumId = 1
+)
+
+// WriteDOCX 将文档块写入 Word 文档
+func WriteDOCX(w io.Writer, title string, blocks []Block) error {
+	d := &docxWriter{rels: newRelationships(), nextNumId: docxBulletNumId + 1, orderedNums: map[int]int{}}
+	d.rels.add(relStyles, "styles.xml", false)
+	d.rels.add(relNumbering, "numbering.xml", false)
+
+	d.body.WriteString(xmlHeader + `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:body>`)
+	for i := range blocks {
+		d.block(&blocks[i])
+	}
+	d.body.WriteString(`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/>` +
+		`<w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="851" w:footer="992" w:gutter="0"/>` +
+		`</w:sectPr></w:body></w:document>`)
+
+	pkg := newPackage(title)
+	pkg.add("word/document.xml", docxDocumentType, d.body.String())
+	pkg.add("word/styles.xml", docxStylesType, docxStyles)
+	pkg.add("word/numbering.xml", docxNumberingType, d.numbering())
+	pkg.add("word/_rels/document.xml.rels", pkg.defaults["rels"], d.rels.String())
+	return pkg.write(w, "word/document.xml", relOfficeDocument)
+}
+
+type docxWriter struct {
+	body strings.Builder
+	rels *relationships
+	// 有序列表的编号，每个列表重新从 1 开始编号
+	nums        []string
+	nextNumId   int
+	orderedNums map[int]int
+}
+
+func (d *docxWriter) block(b *Block) {
+	switch b.Kind {
+	case BlockHeading:
+		d.paragraph(fmt.Sprintf(`<w:pStyle w:val="Heading%d"/>`, min(b.Level, 6)), b.Runs)
+	case BlockParagraph:
+		d.paragraph("", b.Runs)
+	case BlockQuote:
+		d.paragraph(`<w:pStyle w:val="Quote"/>`, b.Runs)
+	case BlockListItem:
+		level := min(b.Level, 8)
+		numId := docxBulletNumId
+		if b.Number > 0 {
+			numId = d.orderedNumId(level, b.Number == 1)
+		}
+		d.paragraph(fmt.Sprintf(`<w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, level, numId), b.Runs)
+	case BlockCode:
+		var runs []Run
+		for i, line := range strings.Split(b.Code, "\n") {
+			if i > 0 {
+				runs = append(runs, Run{Break: true})
+			}
+			runs = append(runs, Run{Text: line})
+		}
+		d.paragraph(`<w:pStyle w:val="Code"/>`, runs)
+	case BlockTable:
+		d.table(b.Rows)
+	case BlockRule:
+		d.body.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr></w:pPr></w:p>`)
+	}
+}
+
+// orderedNumId 获取有序列表的编号，新的列表使用新的编号，序号重新从 1 开始
+func (d *docxWriter) orderedNumId(level int, restart bool) int {
+	if numId, ok := d.orderedNums[level]; ok && !restart {
+		return numId
+	}
+	numId := d.nextNumId
+	d.nextNumId++
+	d.orderedNums[level] = numId
+	d.nums = append(d.nums, fmt.Sprintf(`<w:num w:numId="%d"><w:abstractNumId w:val="1"/>`+
+		`<w:lvlOverride w:ilvl="%d"><w:startOverride w:val="1"/></w:lvlOverride></w:num>`, numId, level))
+	return numId
+}
+
+func (d *docxWriter) paragraph(properties string, runs []Run) {
+	d.body.WriteString(`<w:p>`)
+	if properties != "" {
+		d.body.WriteString(`<w:pPr>` + properties + `</w:pPr>`)
+	}
+	for _, r := range runs {
+		d.run(r)
+	}
+	d.body.WriteString(`</w:p>`)
+}
+
+func (d *docxWriter) run(r Run) {
+	if r.Break {
+		d.body.WriteString(`<w:r><w:br/></w:r>`)
+		return
+	}
+	if r.Text == "" {
+		return
+	}
+	link := isExternalLink(r.Link)
+	if link {
+		id := d.rels.add(relHyperlink, r.Link, true)
+		d.body.WriteString(`<w:hyperlink r:id="` + id + `">`)
+	}
+
+	// rPr 中的元素需要按照规范中的顺序排列
+	var props strings.Builder
+	if link {
+		props.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+	}
+	if r.Code {
+		props.WriteString(`<w:rFonts w:ascii="Consolas" w:hAnsi="Consolas"/>`)
+	}
+	if r.Bold {
+		props.WriteString(`<w:b/>`)
+	}
+	if r.Italic {
+		props.WriteString(`<w:i/>`)
+	}
+	if r.Strike {
+		props.WriteString(`<w:strike/>`)
+	}
+	if r.Code {
+		props.WriteString(`<w:color w:val="C7254E"/><w:shd w:val="clear" w:color="auto" w:fill="F6F8FA"/>`)
+	}
+	d.body.WriteString(`<w:r>`)
+	if props.Len() > 0 {
+		d.body.WriteString(`<w:rPr>` + props.String() + `</w:rPr>`)
+	}
+	d.body.WriteString(`<w:t xml:space="preserve">` + escape(r.Text) + `</w:t></w:r>`)
+	if link {
+		d.body.WriteString(`</w:hyperlink>`)
+	}
+}
+
+func (d *docxWriter) table(rows [][]string) {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return
+	}
+	width := docxTextWidth / columns
+
+	d.body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/></w:tblPr><w:tblGrid>`)
+	for i := 0; i < columns; i++ {
+		fmt.Fprintf(&d.body, `<w:gridCol w:w="%d"/>`, width)
+	}
+	d.body.WriteString(`</w:tblGrid>`)
+	for i, row := range rows {
+		header := i == 0
+		d.body.WriteString(`<w:tr>`)
+		if header {
+			// 跨页时重复表头
+			d.body.WriteString(`<w:trPr><w:tblHeader/></w:trPr>`)
+		}
+		for j := 0; j < columns; j++ {
+			text := ""
+			if j < len(row) {
+				text = row[j]
+			}
+			fmt.Fprintf(&d.body, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, width)
+			if header {
+				d.body.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="D9E2F3"/>`)
+			}
+			d.body.WriteString(`</w:tcPr>`)
+			d.paragraph(`<w:spacing w:after="0"/>`, []Run{{Text: text, Bold: header}})
+			d.body.WriteString(`</w:tc>`)
+		}
+		d.body.WriteString(`</w:tr>`)
+	}
+	// 表格后面需要一个段落，否则相邻的表格会合并
+	d.body.WriteString(`</w:tbl><w:p/>`)
+}
+
+// numbering 无序列表使用项目符号，有序列表使用数字编号，最多 9 级
+func (d *docxWriter) numbering() string {
+	var sb strings.Builder
+	sb.WriteString(xmlHeader + `<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
+	bullets := []string{"•", "◦", "▪"}
+	for id, ordered := range []bool{false, true} {
+		fmt.Fprintf(&sb, `<w:abstractNum w:abstractNumId="%d"><w:multiLevelType w:val="hybridMultilevel"/>`, id)
+		for level := 0; level < 9; level++ {
+			format, text := "bullet", bullets[level%len(bullets)]
+			if ordered {
+				format, text = "decimal", fmt.Sprintf("%%%d.", level+1)
+			}
+			fmt.Fprintf(&sb, `<w:lvl w:ilvl="%d"><w:start w:val="1"/><w:numFmt w:val="%s"/><w:lvlText w:val="%s"/>`+
+				`<w:lvlJc w:val="left"/><w:pPr><w:ind w:left="%d" w:hanging="420"/></w:pPr></w:lvl>`,
+				level, format, text, 420*(level+1))
+		}
+		sb.WriteString(`</w:abstractNum>`)
+	}
+	fmt.Fprintf(&sb, `<w:num w:numId="%d"><w:abstractNumId w:val="0"/></w:num>`, docxBulletNumId)
+	sb.WriteString(strings.Join(d.nums, ""))
+	sb.WriteString(`</w:numbering>`)
+	return sb.String()
+}
+
+// isExternalLink 只有网页和邮件链接可以在文档中打开
+func isExternalLink(link string) bool {
+	return strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") || strings.HasPrefix(link, "mailto:")
+}
+
+// docxStyles 文档样式，中文使用微软雅黑，英文使用 Calibri
+const docxStyles = xmlHeader + `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:eastAsia="微软雅黑" w:hAnsi="Calibri" w:cs="Calibri"/>` +
+	`<w:sz w:val="21"/><w:szCs w:val="21"/><w:lang w:val="en-US" w:eastAsia="zh-CN"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="120" w:line="300" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="360" w:after="160"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:color w:val="1F4E79"/><w:sz w:val="36"/><w:szCs w:val="36"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:pBdr><w:bottom w:val="single" w:sz="4" w:space="1" w:color="D0D7DE"/></w:pBdr><w:spacing w:before="280" w:after="120"/><w:outlineLvl w:val="1"/></w:pPr>` +
+	`<w:rPr><w:b/><w:color w:val="1F4E79"/><w:sz w:val="30"/><w:szCs w:val="30"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="240" w:after="80"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="26"/><w:szCs w:val="26"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading4"><w:name w:val="heading 4"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="200" w:after="80"/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:b/><w:sz w:val="24"/><w:szCs w:val="24"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading5"><w:name w:val="heading 5"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:outlineLvl w:val="4"/></w:pPr><w:rPr><w:b/><w:sz w:val="22"/><w:szCs w:val="22"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading6"><w:name w:val="heading 6"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:outlineLvl w:val="5"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:pBdr><w:left w:val="single" w:sz="18" w:space="8" w:color="D0D7DE"/></w:pBdr><w:ind w:left="420"/></w:pPr><w:rPr><w:color w:val="6A737D"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F6F8FA"/><w:spacing w:line="240" w:lineRule="auto"/></w:pPr>` +
+	`<w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas"/><w:sz w:val="19"/><w:szCs w:val="19"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:after="60"/><w:contextualSpacing/></w:pPr></w:style>` +
+	`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr></w:style>` +
+	`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr><w:tblBorders>` +
+	`<w:top w:val="single" w:sz="4" w:space="0" w:color="BFBFBF"/><w:left w:val="single" w:sz="4" w:space="0" w:color="BFBFBF"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:space="0" w:color="BFBFBF"/><w:right w:val="single" w:sz="4" w:space="0" w:color="BFBFBF"/>` +
+	`<w:insideH w:val="single" w:sz="4" w:space="0" w:color="BFBFBF"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="BFBFBF"/>` +
+	`</w:tblBorders><w:tblCellMar><w:left w:w="108" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>` +
+	`</w:styles>`
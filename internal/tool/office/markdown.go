@@ -0,0 +1,225 @@
+package office
+
+import (
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// BlockKind 文档块的类型
+type BlockKind int
+
+const (
+	BlockParagraph BlockKind = iota
+	BlockHeading
+	BlockListItem
+	BlockQuote
+	BlockCode
+	BlockTable
+	BlockRule
+)
+
+// Run 一段相同样式的行内文本
+type Run struct {
+	Text   string
+	Bold   bool
+	Italic bool
+	Strike bool
+	Code   bool
+	Link   string
+	// 换行
+	Break bool
+}
+
+// Block Markdown 解析后的文档块，DOCX 和 PPTX 共用
+type Block struct {
+	Kind BlockKind
+	// 标题级别 1-6，列表的嵌套层级从 0 开始
+	Level int
+	// 有序列表的序号，从 1 开始，无序列表为 0
+	Number int
+	Runs   []Run
+	// 代码块的内容
+	Code string
+	// 表格，第一行为表头
+	Rows [][]string
+}
+
+// Text 块的纯文本内容
+func (b *Block) Text() string {
+	var sb strings.Builder
+	for _, r := range b.Runs {
+		if r.Break {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(r.Text)
+	}
+	return sb.String()
+}
+
+// ParseMarkdown 将 Markdown 解析为文档块，单个换行也作为换行
+func ParseMarkdown(markdown string) []Block {
+	extensions := blackfriday.CommonExtensions | blackfriday.HardLineBreak
+	root := blackfriday.New(blackfriday.WithExtensions(extensions)).Parse([]byte(strings.ReplaceAll(markdown, "\r\n", "\n")))
+
+	p := &markdownParser{}
+	for node := root.FirstChild; node != nil; node = node.Next {
+		p.block(node, false)
+	}
+	return p.blocks
+}
+
+type markdownParser struct {
+	blocks []Block
+}
+
+func (p *markdownParser) block(node *blackfriday.Node, quote bool) {
+	switch node.Type {
+	case blackfriday.Heading:
+		p.blocks = append(p.blocks, Block{Kind: BlockHeading, Level: node.Level, Runs: collectRuns(node, Run{}, nil)})
+	case blackfriday.Paragraph:
+		kind := BlockParagraph
+		if quote {
+			kind = BlockQuote
+		}
+		p.blocks = append(p.blocks, Block{Kind: kind, Runs: collectRuns(node, Run{}, nil)})
+	case blackfriday.List:
+		p.list(node, 0)
+	case blackfriday.BlockQuote:
+		for child := node.FirstChild; child != nil; child = child.Next {
+			p.block(child, true)
+		}
+	case blackfriday.CodeBlock:
+		p.blocks = append(p.blocks, Block{Kind: BlockCode, Code: strings.TrimRight(string(node.Literal), "\n")})
+	case blackfriday.Table:
+		p.blocks = append(p.blocks, Block{Kind: BlockTable, Rows: tableRows(node)})
+	case blackfriday.HorizontalRule:
+		p.blocks = append(p.blocks, Block{Kind: BlockRule})
+	case blackfriday.HTMLBlock:
+		// 不支持 HTML，忽略
+	default:
+		for child := node.FirstChild; child != nil; child = child.Next {
+			p.block(child, quote)
+		}
+	}
+}
+
+func (p *markdownParser) list(node *blackfriday.Node, level int) {
+	ordered := node.ListFlags&blackfriday.ListTypeOrdered != 0
+	number := 0
+	for item := node.FirstChild; item != nil; item = item.Next {
+		if item.Type != blackfriday.Item {
+			continue
+		}
+		number++
+		block := Block{Kind: BlockListItem, Level: level}
+		if ordered {
+			block.Number = number
+		}
+		// 列表项中的第一个段落作为列表项的内容，其它段落换行显示，嵌套列表单独成块
+		var nested []*blackfriday.Node
+		for child := item.FirstChild; child != nil; child = child.Next {
+			switch child.Type {
+			case blackfriday.List:
+				nested = append(nested, child)
+			case blackfriday.Paragraph:
+				if len(block.Runs) > 0 {
+					block.Runs = append(block.Runs, Run{Break: true})
+				}
+				block.Runs = collectRuns(child, Run{}, block.Runs)
+			default:
+				text := strings.TrimRight(string(child.Literal), "\n")
+				if text != "" {
+					block.Runs = append(block.Runs, Run{Text: text})
+				}
+			}
+		}
+		p.blocks = append(p.blocks, block)
+		for _, child := range nested {
+			p.list(child, level+1)
+		}
+	}
+}
+
+// collectRuns 将行内节点展开为带样式的文本片段
+func collectRuns(node *blackfriday.Node, style Run, runs []Run) []Run {
+	for child := node.FirstChild; child != nil; child = child.Next {
+		switch child.Type {
+		case blackfriday.Text:
+			if len(child.Literal) > 0 {
+				r := style
+				r.Text = string(child.Literal)
+				runs = append(runs, r)
+			}
+		case blackfriday.Code:
+			r := style
+			r.Text, r.Code = string(child.Literal), true
+			runs = append(runs, r)
+		case blackfriday.Hardbreak:
+			runs = append(runs, Run{Break: true})
+		case blackfriday.Softbreak:
+			r := style
+			r.Text = " "
+			runs = append(runs, r)
+		case blackfriday.HTMLSpan:
+			if strings.HasPrefix(strings.ToLower(string(child.Literal)), "<br") {
+				runs = append(runs, Run{Break: true})
+			}
+		case blackfriday.Image:
+			// 文档中不嵌入图片，保留图片说明和链接
+			r := style
+			r.Text, r.Link = plainText(child), string(child.LinkData.Destination)
+			if r.Text == "" {
+				r.Text = r.Link
+			}
+			runs = append(runs, r)
+		case blackfriday.Strong:
+			s := style
+			s.Bold = true
+			runs = collectRuns(child, s, runs)
+		case blackfriday.Emph:
+			s := style
+			s.Italic = true
+			runs = collectRuns(child, s, runs)
+		case blackfriday.Del:
+			s := style
+			s.Strike = true
+			runs = collectRuns(child, s, runs)
+		case blackfriday.Link:
+			s := style
+			s.Link = string(child.LinkData.Destination)
+			runs = collectRuns(child, s, runs)
+		default:
+			runs = collectRuns(child, style, runs)
+		}
+	}
+	return runs
+}
+
+func plainText(node *blackfriday.Node) string {
+	var sb strings.Builder
+	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && (n.Type == blackfriday.Text || n.Type == blackfriday.Code) {
+			sb.Write(n.Literal)
+		}
+		return blackfriday.GoToNext
+	})
+	return sb.String()
+}
+
+func tableRows(node *blackfriday.Node) [][]string {
+	var rows [][]string
+	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || n.Type != blackfriday.TableRow {
+			return blackfriday.GoToNext
+		}
+		var row []string
+		for cell := n.FirstChild; cell != nil; cell = cell.Next {
+			row = append(row, strings.TrimSpace(plainText(cell)))
+		}
+		rows = append(rows, row)
+		return blackfriday.SkipChildren
+	})
+	return rows
+}
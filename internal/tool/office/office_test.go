@@ -0,0 +1,165 @@
+package office
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testMarkdown = `# 张三的简历
+
+**Go** 开发工程师，邮箱 [zhangsan@example.com](mailto:zhangsan@example.com)
+第二行使用单个换行 ` + "`code`" + ` & <特殊字符>
+
+## 工作经历
+
+1. 负责后端服务开发
+2. 参与架构设计
+   - 嵌套列表
+
+> 这是一段引用
+
+` + "```go\nfunc main() {\n}\n```" + `
+
+## 预算
+
+| 项目 | 费用 |
+| --- | ---: |
+| 住宿 | 600 |
+| 门票 | 075 |
+| 合计 | =SUM(B2:B3) |
+
+---
+
+最后一页
+`
+
+// readPackage 读取压缩包中的所有文件，并检查 XML 格式和 [Content_Types].xml
+func readPackage(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err != nil {
+				if !errors.Is(err, io.EOF) {
+					t.Errorf("%s is not well-formed: %v", f.Name, err)
+				}
+				break
+			}
+		}
+	}
+	for name := range files {
+		if strings.HasSuffix(name, ".rels") || name == "[Content_Types].xml" {
+			continue
+		}
+		if !strings.Contains(files["[Content_Types].xml"], `PartName="/`+name+`"`) {
+			t.Errorf("%s is missing in [Content_Types].xml", name)
+		}
+	}
+	return files
+}
+
+func TestWriteDOCX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDOCX(&buf, "简历", ParseMarkdown(testMarkdown)); err != nil {
+		t.Fatalf("WriteDOCX() error = %v", err)
+	}
+	files := readPackage(t, buf.Bytes())
+	document := files["word/document.xml"]
+	for _, want := range []string{`<w:pStyle w:val="Heading1"/>`, `<w:numId w:val="2"/>`, `<w:ilvl w:val="1"/>`,
+		`<w:tbl>`, `<w:pStyle w:val="Code"/>`, `<w:hyperlink r:id=`, `&amp; &lt;特殊字符&gt;`} {
+		if !strings.Contains(document, want) {
+			t.Errorf("document.xml does not contain %s", want)
+		}
+	}
+	if !strings.Contains(files["word/_rels/document.xml.rels"], `Target="mailto:zhangsan@example.com" TargetMode="External"`) {
+		t.Errorf("hyperlink relationship is missing")
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	sheets := SheetsFromMarkdown(testMarkdown)
+	sheets = append(sheets, Sheet{Name: "预算", Rows: [][]string{{"a"}}}, Sheet{Name: "a/b:c", Rows: nil})
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, "预算", sheets); err != nil {
+		t.Fatalf("WriteXLSX() error = %v", err)
+	}
+	files := readPackage(t, buf.Bytes())
+	for _, want := range []string{`name="预算"`, `name="预算(2)"`, `name="a_b_c"`} {
+		if !strings.Contains(files["xl/workbook.xml"], want) {
+			t.Errorf("workbook.xml does not contain %s", want)
+		}
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{`<c r="B2"><v>600</v></c>`, `<c r="B3" t="inlineStr"><is><t xml:space="preserve">075</t>`,
+		`<c r="B4"><f>SUM(B2:B3)</f></c>`, `<c r="A1" s="1" t="inlineStr">`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet1.xml does not contain %s", want)
+		}
+	}
+}
+
+func TestWritePPTX(t *testing.T) {
+	slides := SlidesFromMarkdown(testMarkdown)
+	if len(slides) != 4 || slides[0].Subtitle == "" || slides[2].Rows == nil || slides[3].Title != "" {
+		t.Fatalf("SlidesFromMarkdown() = %+v", slides)
+	}
+	var buf bytes.Buffer
+	if err := WritePPTX(&buf, "简历", slides); err != nil {
+		t.Fatalf("WritePPTX() error = %v", err)
+	}
+	files := readPackage(t, buf.Bytes())
+	if !strings.Contains(files["ppt/presentation.xml"], `<p:sldId id="259" r:id="rId6"/>`) {
+		t.Errorf("presentation.xml = %s", files["ppt/presentation.xml"])
+	}
+	if !strings.Contains(files["ppt/slides/slide3.xml"], `<a:tbl>`) {
+		t.Errorf("slide3.xml does not contain table")
+	}
+}
+
+func Test_splitSlides(t *testing.T) {
+	bullets := make([]Bullet, maxBulletsPerSlide+1)
+	rows := make([][]string, maxRowsPerSlide+1)
+	slides := splitSlides([]Slide{
+		{Title: "封面", Subtitle: "副标题", Bullets: bullets[:1]},
+		{Title: "要点", Bullets: bullets},
+		{Title: "表格", Rows: rows},
+	})
+	if len(slides) != 6 {
+		t.Fatalf("splitSlides() got %d slides, want 6", len(slides))
+	}
+	if slides[0].Bullets != nil || slides[1].Subtitle != "" || len(slides[3].Bullets) != 1 || len(slides[5].Rows) != 2 {
+		t.Errorf("splitSlides() = %+v", slides)
+	}
+}
+
+func Test_columnName(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"}, {25, "Z"}, {26, "AA"}, {701, "ZZ"}, {702, "AAA"},
+	}
+	for _, tt := range tests {
+		if got := columnName(tt.index); got != tt.want {
+			t.Errorf("columnName(%d) = %s, want %s", tt.index, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package office
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// part OOXML 压缩包中的一个文件
+type part struct {
+	name        string
+	contentType string
+	data        string
+}
+
+// ooxmlPackage DOCX、XLSX 和 PPTX 都是由 XML 文件组成的 zip 压缩包
+type ooxmlPackage struct {
+	parts []part
+	// 按扩展名注册的默认类型
+	defaults map[string]string
+}
+
+func newPackage(title string) *ooxmlPackage {
+	p := &ooxmlPackage{defaults: map[string]string{
+		"rels": "application/vnd.openxmlformats-package.relationships+xml",
+		"xml":  "application/xml",
+	}}
+	now := time.Now().UTC().Format(time.RFC3339)
+	p.add("docProps/core.xml", "application/vnd.openxmlformats-package.core-properties+xml", xmlHeader+
+		`<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" `+
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" `+
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`+
+		`<dc:title>`+escape(title)+`</dc:title><dc:creator>TxingAI</dc:creator>`+
+		`<dcterms:created xsi:type="dcterms:W3CDTF">`+now+`</dcterms:created>`+
+		`<dcterms:modified xsi:type="dcterms:W3CDTF">`+now+`</dcterms:modified>`+
+		`</cp:coreProperties>`)
+	p.add("docProps/app.xml", "application/vnd.openxmlformats-officedocument.extended-properties+xml", xmlHeader+
+		`<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Application>TxingAI</Application></Properties>`)
+	return p
+}
+
+func (p *ooxmlPackage) add(name, contentType, data string) {
+	p.parts = append(p.parts, part{name: name, contentType: contentType, data: data})
+}
+
+// write 写入 [Content_Types].xml、根关系和所有文件，mainPart 为文档主体
+func (p *ooxmlPackage) write(w io.Writer, mainPart, mainType string) error {
+	var types strings.Builder
+	types.WriteString(xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	for _, ext := range []string{"rels", "xml"} {
+		fmt.Fprintf(&types, `<Default Extension="%s" ContentType="%s"/>`, ext, p.defaults[ext])
+	}
+	for _, part := range p.parts {
+		fmt.Fprintf(&types, `<Override PartName="/%s" ContentType="%s"/>`, part.name, part.contentType)
+	}
+	types.WriteString(`</Types>`)
+
+	rels := newRelationships()
+	rels.add(mainType, mainPart, false)
+	rels.add("http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties", "docProps/core.xml", false)
+	rels.add("http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties", "docProps/app.xml", false)
+
+	zw := zip.NewWriter(w)
+	files := append([]part{
+		{name: "[Content_Types].xml", data: types.String()},
+		{name: "_rels/.rels", data: rels.String()},
+	}, p.parts...)
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// relationships 文件之间的引用关系
+type relationships struct {
+	items []string
+}
+
+func newRelationships() *relationships {
+	return &relationships{}
+}
+
+// add 添加引用并返回引用 ID，external 为 true 时表示外部链接
+func (r *relationships) add(relType, target string, external bool) string {
+	id := fmt.Sprintf("rId%d", len(r.items)+1)
+	mode := ""
+	if external {
+		mode = ` TargetMode="External"`
+	}
+	r.items = append(r.items, fmt.Sprintf(`<Relationship Id="%s" Type="%s" Target="%s"%s/>`, id, relType, escape(target), mode))
+	return id
+}
+
+func (r *relationships) String() string {
+	return xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		strings.Join(r.items, "") + `</Relationships>`
+}
+
+// escape 转义 XML 文本，并删除 XML 中不允许出现的控制字符
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 {
+			return r
+		}
+		return -1
+	}, s)))
+	return buf.String()
+}
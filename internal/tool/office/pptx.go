@@ -0,0 +1,310 @@
+package office
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	pptxPresentationType = "application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml"
+	pptxSlideType        = "application/vnd.openxmlformats-officedocument.presentationml.slide+xml"
+	pptxLayoutType       = "application/vnd.openxmlformats-officedocument.presentationml.slideLayout+xml"
+	pptxMasterType       = "application/vnd.openxmlformats-officedocument.presentationml.slideMaster+xml"
+	pptxThemeType        = "application/vnd.openxmlformats-officedocument.theme+xml"
+
+	relSlide       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide"
+	relSlideLayout = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout"
+	relSlideMaster = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster"
+	relTheme       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+
+	pptxNamespaces = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+		`xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+
+	// 16:9 幻灯片的尺寸，单位 EMU（1 厘米 = 360000 EMU）
+	slideWidth  = 12192000
+	slideHeight = 6858000
+	slideMargin = 457200
+	// 每页最多的要点数量，超过时拆分到下一页
+	maxBulletsPerSlide = 8
+	// 每页最多的表格行数，包括表头
+	maxRowsPerSlide = 10
+)
+
+// Bullet 幻灯片中的要点，Level 为缩进层级
+type Bullet struct {
+	Text  string
+	Level int
+}
+
+// Slide 一页幻灯片，只有标题和副标题时显示为封面
+type Slide struct {
+	Title    string
+	Subtitle string
+	Bullets  []Bullet
+	// 表格，第一行为表头
+	Rows [][]string
+}
+
+// WritePPTX 将幻灯片写入 PowerPoint 文件，要点过多的幻灯片会拆分为多页
+func WritePPTX(w io.Writer, title string, slides []Slide) error {
+	slides = splitSlides(slides)
+	if len(slides) == 0 {
+		slides = []Slide{{Title: title}}
+	}
+
+	pkg := newPackage(title)
+	rels := newRelationships()
+	rels.add(relSlideMaster, "slideMasters/slideMaster1.xml", false)
+	rels.add(relTheme, "theme/theme1.xml", false)
+
+	var slideIds strings.Builder
+	for i, slide := range slides {
+		id := rels.add(relSlide, fmt.Sprintf("slides/slide%d.xml", i+1), false)
+		fmt.Fprintf(&slideIds, `<p:sldId id="%d" r:id="%s"/>`, 256+i, id)
+
+		slideRels := newRelationships()
+		slideRels.add(relSlideLayout, "../slideLayouts/slideLayout1.xml", false)
+		pkg.add(fmt.Sprintf("ppt/slides/slide%d.xml", i+1), pptxSlideType, slideXML(&slide))
+		pkg.add(fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", i+1), pkg.defaults["rels"], slideRels.String())
+	}
+
+	presentation := xmlHeader + `<p:presentation ` + pptxNamespaces + `>` +
+		`<p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst>` +
+		`<p:sldIdLst>` + slideIds.String() + `</p:sldIdLst>` +
+		fmt.Sprintf(`<p:sldSz cx="%d" cy="%d"/><p:notesSz cx="6858000" cy="9144000"/>`, slideWidth, slideHeight) +
+		`</p:presentation>`
+
+	masterRels := newRelationships()
+	masterRels.add(relSlideLayout, "../slideLayouts/slideLayout1.xml", false)
+	masterRels.add(relTheme, "../theme/theme1.xml", false)
+	layoutRels := newRelationships()
+	layoutRels.add(relSlideMaster, "../slideMasters/slideMaster1.xml", false)
+
+	pkg.add("ppt/presentation.xml", pptxPresentationType, presentation)
+	pkg.add("ppt/_rels/presentation.xml.rels", pkg.defaults["rels"], rels.String())
+	pkg.add("ppt/slideMasters/slideMaster1.xml", pptxMasterType, pptxMaster)
+	pkg.add("ppt/slideMasters/_rels/slideMaster1.xml.rels", pkg.defaults["rels"], masterRels.String())
+	pkg.add("ppt/slideLayouts/slideLayout1.xml", pptxLayoutType, pptxLayout)
+	pkg.add("ppt/slideLayouts/_rels/slideLayout1.xml.rels", pkg.defaults["rels"], layoutRels.String())
+	pkg.add("ppt/theme/theme1.xml", pptxThemeType, pptxTheme)
+	return pkg.write(w, "ppt/presentation.xml", relOfficeDocument)
+}
+
+// SlidesFromMarkdown 将 Markdown 转换为幻灯片
+// 一、二级标题或者分隔线 --- 开始新的一页，段落和列表作为要点，表格单独成页
+func SlidesFromMarkdown(markdown string) []Slide {
+	var slides []Slide
+	var current *Slide
+	next := func(title string) {
+		slides = append(slides, Slide{Title: title})
+		current = &slides[len(slides)-1]
+	}
+
+	for _, block := range ParseMarkdown(markdown) {
+		text := strings.TrimSpace(block.Text())
+		switch block.Kind {
+		case BlockHeading:
+			if block.Level <= 2 {
+				next(text)
+				continue
+			}
+		case BlockRule:
+			next("")
+			continue
+		}
+		if current == nil {
+			next("")
+		}
+
+		switch block.Kind {
+		case BlockTable:
+			if len(current.Bullets) > 0 || len(current.Rows) > 0 {
+				next(current.Title)
+			}
+			current.Rows = block.Rows
+		case BlockListItem:
+			current.Bullets = append(current.Bullets, Bullet{Text: text, Level: block.Level})
+		case BlockCode:
+			for _, line := range strings.Split(block.Code, "\n") {
+				if strings.TrimSpace(line) != "" {
+					current.Bullets = append(current.Bullets, Bullet{Text: line, Level: 1})
+				}
+			}
+		case BlockParagraph, BlockQuote, BlockHeading:
+			// 第一页的一级标题后面的段落作为副标题
+			if len(slides) == 1 && current.Subtitle == "" && len(current.Bullets) == 0 && block.Kind == BlockParagraph {
+				current.Subtitle = text
+				continue
+			}
+			for _, line := range strings.Split(text, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					current.Bullets = append(current.Bullets, Bullet{Text: line})
+				}
+			}
+		}
+	}
+	return slides
+}
+
+// splitSlides 将要点或表格行过多的幻灯片拆分为多页，表格在每页重复表头
+func splitSlides(slides []Slide) []Slide {
+	var result []Slide
+	for _, slide := range slides {
+		if slide.Subtitle != "" && len(slide.Bullets) > 0 {
+			// 封面不显示要点，要点放到下一页
+			result = append(result, Slide{Title: slide.Title, Subtitle: slide.Subtitle})
+			slide.Subtitle = ""
+		}
+		for len(slide.Bullets) > maxBulletsPerSlide {
+			result = append(result, Slide{Title: slide.Title, Bullets: slide.Bullets[:maxBulletsPerSlide]})
+			slide.Bullets = slide.Bullets[maxBulletsPerSlide:]
+		}
+		for len(slide.Rows) > maxRowsPerSlide {
+			rows := slide.Rows[:maxRowsPerSlide]
+			result = append(result, Slide{Title: slide.Title, Rows: rows})
+			slide.Rows = append([][]string{slide.Rows[0]}, slide.Rows[maxRowsPerSlide:]...)
+		}
+		result = append(result, slide)
+	}
+	return result
+}
+
+func slideXML(slide *Slide) string {
+	var shapes strings.Builder
+	cover := len(slide.Bullets) == 0 && len(slide.Rows) == 0
+	contentWidth := slideWidth - 2*slideMargin
+	if cover {
+		shapes.WriteString(textBox(2, "Title", slideMargin, 2286000, contentWidth, 1371600, "b", "ctr",
+			[]Bullet{{Text: slide.Title}}, 4000, true, false))
+		if slide.Subtitle != "" {
+			shapes.WriteString(textBox(3, "Subtitle", slideMargin, 3886200, contentWidth, 1143000, "t", "ctr",
+				[]Bullet{{Text: slide.Subtitle}}, 2000, false, false))
+		}
+	} else {
+		top := slideMargin
+		if slide.Title != "" {
+			shapes.WriteString(textBox(2, "Title", slideMargin, slideMargin, contentWidth, 914400, "b", "l",
+				[]Bullet{{Text: slide.Title}}, 3200, true, false))
+			top = 1600200
+		}
+		height := slideHeight - top - slideMargin
+		if len(slide.Rows) > 0 {
+			shapes.WriteString(tableFrame(3, slideMargin, top, contentWidth, slide.Rows))
+		} else {
+			shapes.WriteString(textBox(3, "Content", slideMargin, top, contentWidth, height, "t", "l",
+				slide.Bullets, 2000, false, true))
+		}
+	}
+	return xmlHeader + `<p:sld ` + pptxNamespaces + `><p:cSld><p:spTree>` + groupShapeProperties +
+		shapes.String() + `</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`
+}
+
+// textBox 文本框，bullet 为 true 时每个段落显示项目符号
+func textBox(id int, name string, x, y, cx, cy int, anchor, align string, paragraphs []Bullet, size int, title, bullet bool) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<p:sp><p:nvSpPr><p:cNvPr id="%d" name="%s"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr>`+
+		`<p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/></p:spPr>`+
+		`<p:txBody><a:bodyPr wrap="square" anchor="%s"><a:normAutofit/></a:bodyPr><a:lstStyle/>`, id, name, x, y, cx, cy, anchor)
+	for _, p := range paragraphs {
+		level := min(p.Level, 4)
+		if bullet {
+			fmt.Fprintf(&sb, `<a:p><a:pPr marL="%d" lvl="%d" indent="-285750"><a:spcBef><a:spcPts val="600"/></a:spcBef>`+
+				`<a:buFont typeface="Arial"/><a:buChar char="•"/></a:pPr>`, 285750+level*457200, level)
+		} else {
+			fmt.Fprintf(&sb, `<a:p><a:pPr algn="%s"/>`, align)
+		}
+		attrs := fmt.Sprintf(`lang="zh-CN" altLang="en-US" sz="%d"`, size-level*200)
+		color := "404040"
+		if title {
+			attrs += ` b="1"`
+			color = "1F4E79"
+		}
+		fmt.Fprintf(&sb, `<a:r><a:rPr %s dirty="0"><a:solidFill><a:srgbClr val="%s"/></a:solidFill></a:rPr><a:t>%s</a:t></a:r></a:p>`,
+			attrs, color, escape(p.Text))
+	}
+	sb.WriteString(`</p:txBody></p:sp>`)
+	return sb.String()
+}
+
+func tableFrame(id, x, y, cx int, rows [][]string) string {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return ""
+	}
+	columnWidth := cx / columns
+	rowHeight := 370840
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<p:graphicFrame><p:nvGraphicFramePr><p:cNvPr id="%d" name="Table"/>`+
+		`<p:cNvGraphicFramePr><a:graphicFrameLocks noGrp="1"/></p:cNvGraphicFramePr><p:nvPr/></p:nvGraphicFramePr>`+
+		`<p:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></p:xfrm>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/table"><a:tbl><a:tblPr firstRow="1" bandRow="1"/><a:tblGrid>`,
+		id, x, y, columnWidth*columns, rowHeight*len(rows))
+	for i := 0; i < columns; i++ {
+		fmt.Fprintf(&sb, `<a:gridCol w="%d"/>`, columnWidth)
+	}
+	sb.WriteString(`</a:tblGrid>`)
+
+	border := `<a:solidFill><a:srgbClr val="BFBFBF"/></a:solidFill>`
+	for i, row := range rows {
+		fmt.Fprintf(&sb, `<a:tr h="%d">`, rowHeight)
+		for j := 0; j < columns; j++ {
+			text := ""
+			if j < len(row) {
+				text = row[j]
+			}
+			bold, fill := "", "FFFFFF"
+			if i == 0 {
+				bold, fill = ` b="1"`, "D9E2F3"
+			}
+			fmt.Fprintf(&sb, `<a:tc><a:txBody><a:bodyPr/><a:lstStyle/><a:p><a:r><a:rPr lang="zh-CN" altLang="en-US" sz="1400"%s dirty="0"/><a:t>%s</a:t></a:r></a:p></a:txBody>`+
+				`<a:tcPr><a:lnL w="6350">%s</a:lnL><a:lnR w="6350">%s</a:lnR><a:lnT w="6350">%s</a:lnT><a:lnB w="6350">%s</a:lnB>`+
+				`<a:solidFill><a:srgbClr val="%s"/></a:solidFill></a:tcPr></a:tc>`,
+				bold, escape(text), border, border, border, border, fill)
+		}
+		sb.WriteString(`</a:tr>`)
+	}
+	sb.WriteString(`</a:tbl></a:graphicData></a:graphic></p:graphicFrame>`)
+	return sb.String()
+}
+
+const groupShapeProperties = `<p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr>` +
+	`<p:grpSpPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/><a:chOff x="0" y="0"/><a:chExt cx="0" cy="0"/></a:xfrm></p:grpSpPr>`
+
+// pptxMaster 幻灯片母版，所有内容都直接绘制在幻灯片中，母版只提供配色映射
+const pptxMaster = xmlHeader + `<p:sldMaster ` + pptxNamespaces + `><p:cSld>` +
+	`<p:bg><p:bgRef idx="1001"><a:schemeClr val="bg1"/></p:bgRef></p:bg><p:spTree>` + groupShapeProperties + `</p:spTree></p:cSld>` +
+	`<p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2" accent1="accent1" accent2="accent2" accent3="accent3" ` +
+	`accent4="accent4" accent5="accent5" accent6="accent6" hlink="hlink" folHlink="folHlink"/>` +
+	`<p:sldLayoutIdLst><p:sldLayoutId id="2147483649" r:id="rId1"/></p:sldLayoutIdLst>` +
+	`<p:txStyles><p:titleStyle/><p:bodyStyle/><p:otherStyle/></p:txStyles></p:sldMaster>`
+
+const pptxLayout = xmlHeader + `<p:sldLayout ` + pptxNamespaces + ` type="blank" preserve="1"><p:cSld name="Blank"><p:spTree>` +
+	groupShapeProperties + `</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sldLayout>`
+
+// pptxTheme 主题，中文字体使用微软雅黑
+const pptxTheme = xmlHeader + `<a:theme xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" name="TxingAI"><a:themeElements>` +
+	`<a:clrScheme name="TxingAI"><a:dk1><a:srgbClr val="000000"/></a:dk1><a:lt1><a:srgbClr val="FFFFFF"/></a:lt1>` +
+	`<a:dk2><a:srgbClr val="1F4E79"/></a:dk2><a:lt2><a:srgbClr val="E7E6E6"/></a:lt2>` +
+	`<a:accent1><a:srgbClr val="4472C4"/></a:accent1><a:accent2><a:srgbClr val="ED7D31"/></a:accent2>` +
+	`<a:accent3><a:srgbClr val="A5A5A5"/></a:accent3><a:accent4><a:srgbClr val="FFC000"/></a:accent4>` +
+	`<a:accent5><a:srgbClr val="5B9BD5"/></a:accent5><a:accent6><a:srgbClr val="70AD47"/></a:accent6>` +
+	`<a:hlink><a:srgbClr val="0563C1"/></a:hlink><a:folHlink><a:srgbClr val="954F72"/></a:folHlink></a:clrScheme>` +
+	`<a:fontScheme name="TxingAI"><a:majorFont><a:latin typeface="Calibri"/><a:ea typeface="微软雅黑"/><a:cs typeface=""/></a:majorFont>` +
+	`<a:minorFont><a:latin typeface="Calibri"/><a:ea typeface="微软雅黑"/><a:cs typeface=""/></a:minorFont></a:fontScheme>` +
+	`<a:fmtScheme name="TxingAI"><a:fillStyleLst>` +
+	`<a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill>` +
+	`</a:fillStyleLst><a:lnStyleLst>` +
+	`<a:ln w="6350"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln>` +
+	`<a:ln w="12700"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln>` +
+	`<a:ln w="19050"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln>` +
+	`</a:lnStyleLst><a:effectStyleLst>` +
+	`<a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle>` +
+	`</a:effectStyleLst><a:bgFillStyleLst>` +
+	`<a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill>` +
+	`</a:bgFillStyleLst></a:fmtScheme></a:themeElements></a:theme>`
@@ -0,0 +1,201 @@
+package office
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	xlsxWorkbookType  = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"
+	xlsxWorksheetType = "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"
+	xlsxStylesType    = "application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"
+
+	relWorksheet = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+
+	// 工作表名称最长 31 个字符
+	maxSheetNameLength = 31
+)
+
+// Sheet 工作表，第一行为表头
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// WriteXLSX 将工作表写入 Excel 文件
+// 数字单元格保存为数值，以 = 开头的单元格保存为公式，例如 =SUM(B2:B5)
+func WriteXLSX(w io.Writer, title string, sheets []Sheet) error {
+	if len(sheets) == 0 {
+		sheets = []Sheet{{}}
+	}
+
+	pkg := newPackage(title)
+	rels := newRelationships()
+	var workbook strings.Builder
+	workbook.WriteString(xmlHeader + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	used := map[string]bool{}
+	for i, sheet := range sheets {
+		name := sheetName(sheet.Name, i+1, used)
+		target := fmt.Sprintf("worksheets/sheet%d.xml", i+1)
+		id := rels.add(relWorksheet, target, false)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="%s"/>`, escape(name), i+1, id)
+		pkg.add("xl/"+target, xlsxWorksheetType, worksheet(sheet.Rows))
+	}
+	workbook.WriteString(`</sheets></workbook>`)
+	rels.add("http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles", "styles.xml", false)
+
+	pkg.add("xl/workbook.xml", xlsxWorkbookType, workbook.String())
+	pkg.add("xl/styles.xml", xlsxStylesType, xlsxStyles)
+	pkg.add("xl/_rels/workbook.xml.rels", pkg.defaults["rels"], rels.String())
+	return pkg.write(w, "xl/workbook.xml", relOfficeDocument)
+}
+
+// SheetsFromMarkdown 将 Markdown 中的每个表格转换为一个工作表，使用表格前面最近的标题作为工作表名称
+func SheetsFromMarkdown(markdown string) []Sheet {
+	var sheets []Sheet
+	heading := ""
+	for _, block := range ParseMarkdown(markdown) {
+		switch block.Kind {
+		case BlockHeading:
+			heading = strings.TrimSpace(block.Text())
+		case BlockTable:
+			sheets = append(sheets, Sheet{Name: heading, Rows: block.Rows})
+		}
+	}
+	return sheets
+}
+
+func worksheet(rows [][]string) string {
+	var sb strings.Builder
+	sb.WriteString(xmlHeader + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(rows) > 1 {
+		// 冻结表头
+		sb.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+
+	// 根据内容估算列宽，中文字符按两个字符计算
+	var widths []int
+	for _, row := range rows {
+		for j, cell := range row {
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[j] = max(widths[j], displayWidth(cell))
+		}
+	}
+	if len(widths) > 0 {
+		sb.WriteString(`<cols>`)
+		for j, width := range widths {
+			fmt.Fprintf(&sb, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, j+1, j+1, min(max(width+2, 8), 60))
+		}
+		sb.WriteString(`</cols>`)
+	}
+
+	sb.WriteString(`<sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, i+1)
+		for j, value := range row {
+			ref := columnName(j) + strconv.Itoa(i+1)
+			style := ""
+			if i == 0 && len(rows) > 1 {
+				style = ` s="1"`
+			}
+			sb.WriteString(cell(ref, style, value))
+		}
+		sb.WriteString(`</row>`)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String()
+}
+
+func cell(ref, style, value string) string {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return fmt.Sprintf(`<c r="%s"%s/>`, ref, style)
+	case strings.HasPrefix(value, "=") && len(value) > 1:
+		return fmt.Sprintf(`<c r="%s"%s><f>%s</f></c>`, ref, style, escape(value[1:]))
+	case isNumber(value):
+		return fmt.Sprintf(`<c r="%s"%s><v>%s</v></c>`, ref, style, value)
+	default:
+		return fmt.Sprintf(`<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(value))
+	}
+}
+
+// isNumber 是否保存为数值，以 0 开头的编号（例如电话号码）保持为文本
+func isNumber(value string) bool {
+	if strings.HasPrefix(value, "+") {
+		return false
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return false
+	}
+	digits := strings.TrimPrefix(value, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return false
+	}
+	// 超过 15 位的数字会丢失精度，例如身份证号
+	return len(strings.TrimLeft(digits, "0.")) <= 15 && !strings.ContainsAny(value, "eEnN")
+}
+
+// columnName 列号转换为列名，0 -> A，26 -> AA
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if utf8.RuneLen(r) > 1 {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// sheetName 工作表名称不能包含 []:*?/\，不能超过 31 个字符并且不能重复
+func sheetName(name string, index int, used map[string]bool) string {
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name))
+	if name == "" {
+		name = fmt.Sprintf("Sheet%d", index)
+	}
+	if runes := []rune(name); len(runes) > maxSheetNameLength {
+		name = string(runes[:maxSheetNameLength])
+	}
+	base := name
+	for n := 2; used[strings.ToLower(name)]; n++ {
+		suffix := fmt.Sprintf("(%d)", n)
+		runes := []rune(base)
+		name = string(runes[:min(len(runes), maxSheetNameLength-len(suffix))]) + suffix
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// xlsxStyles 样式 0 为默认样式，样式 1 为表头：粗体、浅蓝色背景
+const xlsxStyles = xmlHeader + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFD9E2F3"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
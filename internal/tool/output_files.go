@@ -0,0 +1,77 @@
+package tool
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/global/logging/log"
+	fileservice "txing-ai/internal/service/file"
+	"txing-ai/internal/storage"
+	"txing-ai/internal/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// OutputFiles 一次运行中工具生成并登记到文件表的文件
+type OutputFiles struct {
+	db     *gorm.DB
+	stores *storage.Stores
+	userId int64
+
+	mu    sync.Mutex
+	files []*domain.File
+}
+
+type outputFilesKey struct{}
+
+// WithOutputFiles 记录本次运行中工具生成的文件，生成的文件会保存到 stores 并登记到 db 中，归属于 userId
+func WithOutputFiles(ctx context.Context, db *gorm.DB, stores *storage.Stores, userId int64) (context.Context, *OutputFiles) {
+	outputs := &OutputFiles{db: db, stores: stores, userId: userId}
+	return context.WithValue(ctx, outputFilesKey{}, outputs), outputs
+}
+
+// List 按生成顺序返回已登记的文件
+func (o *OutputFiles) List() []*domain.File {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*domain.File(nil), o.files...)
+}
+
+func (o *OutputFiles) add(file *domain.File) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.files = append(o.files, file)
+}
+
+// registerOutputFile 将工具生成的文件保存到文件存储并登记，返回下载地址
+// 没有可用的数据库、文件存储或者登记失败时退回到按文件名下载的地址
+func registerOutputFile(ctx context.Context, toolName string, fullPath string) string {
+	filename := filepath.Base(fullPath)
+	outputs, _ := ctx.Value(outputFilesKey{}).(*OutputFiles)
+
+	var db *gorm.DB
+	var stores *storage.Stores
+	var userId int64
+	if outputs != nil {
+		db, stores, userId = outputs.db, outputs.stores, outputs.userId
+	} else {
+		db, _ = utils.GetFromContextWithOK[*gorm.DB](ctx, "db")
+		stores, _ = utils.GetFromContextWithOK[*storage.Stores](ctx, "blobStores")
+		userId, _ = userIdFromContext(ctx)
+	}
+	if db == nil || stores == nil {
+		return downloadURL(filename)
+	}
+
+	file, err := fileservice.SaveLocalFile(ctx, db, stores, userId, filename, fullPath, domain.FileSourceTool, toolName)
+	if err != nil {
+		log.Error("登记生成的文件失败", zap.String("path", fullPath), zap.Error(err))
+		return downloadURL(filename)
+	}
+	if outputs != nil {
+		outputs.add(file)
+	}
+	return fileservice.DownloadURL(file.Id)
+}
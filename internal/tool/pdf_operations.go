@@ -4,13 +4,66 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
 
 	"github.com/ledongthuc/pdf"
 	"go.uber.org/zap"
 )
 
+type allowedDirKey struct{}
+
+// WithAllowedDir 额外允许读取 dir 目录下的文件，用于评测等不经过上传流程的场景
+func WithAllowedDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, allowedDirKey{}, dir)
+}
+
+// isPathAllowed 检查文件是否在当前用户上传文件的目录或 context 中额外允许的目录中，
+// 解析符号链接后再检查，避免通过符号链接访问其他文件
+func isPathAllowed(ctx context.Context, path string) bool {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	if realPath, err = filepath.Abs(realPath); err != nil {
+		return false
+	}
+	if dir, ok := ctx.Value(allowedDirKey{}).(string); ok && dir != "" && isWithinDir(dir, realPath) {
+		return true
+	}
+
+	uploadConfig := global.LoadConfig().LocalUploadConfig
+	if uploadConfig == nil {
+		return false
+	}
+	currentDir, err := os.Getwd()
+	if err != nil {
+		log.Error("获取当前工作目录失败", zap.Error(err))
+		return false
+	}
+	uploadDir := filepath.Join(currentDir, uploadConfig.Dir)
+	if userId, ok := userIdFromContext(ctx); ok {
+		uploadDir = filepath.Join(uploadDir, strconv.FormatInt(userId, 10))
+	}
+	return isWithinDir(uploadDir, realPath)
+}
+
+// isWithinDir 检查已解析符号链接的绝对路径 realPath 是否在 dir 目录中
+func isWithinDir(dir string, realPath string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, realPath)
+	return err == nil && filepath.IsLocal(rel)
+}
+
 // PDF文件读取参数
 type PdfReadParams struct {
 	FilePath string `json:"file_path" jsonschema:"description=要读取的PDF文件路径"`
@@ -18,57 +71,12 @@ type PdfReadParams struct {
 
 // 读取PDF文件内容
 func ReadPdfText(ctx context.Context, params *PdfReadParams) (string, error) {
-	// 检查路径是否允许
-	if !isPathAllowed(params.FilePath) {
-		log.Error("不允许访问该路径", zap.String("path", params.FilePath))
-		return "没有权限访问该路径", nil
-	}
-
-	// 检查文件是否存在
-	if _, err := os.Stat(params.FilePath); os.IsNotExist(err) {
-		log.Error("文件不存在", zap.String("path", params.FilePath))
-		return fmt.Sprintf("文件不存在: %s", params.FilePath), nil
-	}
-
 	// 检查文件扩展名
 	if !strings.HasSuffix(strings.ToLower(params.FilePath), ".pdf") {
 		log.Error("文件不是PDF格式", zap.String("path", params.FilePath))
 		return fmt.Sprintf("文件不是PDF格式: %s", params.FilePath), nil
 	}
-
-	// 打开PDF文件
-	f, r, err := pdf.Open(params.FilePath)
-	if err != nil {
-		log.Error("打开PDF文件失败", zap.String("path", params.FilePath), zap.Error(err))
-		return fmt.Sprintf("打开PDF文件失败: %v", err), nil
-	}
-	defer f.Close()
-
-	// 提取文本内容
-	var textBuilder strings.Builder
-	totalPage := r.NumPage()
-
-	for pageIndex := 1; pageIndex <= totalPage; pageIndex++ {
-		p := r.Page(pageIndex)
-		if p.V.IsNull() {
-			continue
-		}
-
-		text, err := p.GetPlainText(nil)
-		if err != nil {
-			log.Error("提取PDF页面文本失败",
-				zap.String("path", params.FilePath),
-				zap.Int("page", pageIndex),
-				zap.Error(err))
-			continue
-		}
-
-		textBuilder.WriteString(fmt.Sprintf("--- 第 %d 页 ---\n", pageIndex))
-		textBuilder.WriteString(text)
-		textBuilder.WriteString("\n\n")
-	}
-
-	return textBuilder.String(), nil
+	return ReadDocumentText(ctx, &DocumentReadParams{FilePath: params.FilePath})
 }
 
 // 验证PDF文件参数
@@ -79,7 +87,7 @@ type pdfValidateParams struct {
 // 验证PDF文件
 func validatePdf(ctx context.Context, params *pdfValidateParams) (string, error) {
 	// 检查路径是否允许
-	if !isPathAllowed(params.FilePath) {
+	if !isPathAllowed(ctx, params.FilePath) {
 		return "", fmt.Errorf("不允许访问该路径: %s", params.FilePath)
 	}
 
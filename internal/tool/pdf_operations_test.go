@@ -2,6 +2,8 @@ package tool
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -66,3 +68,18 @@ func Test_validatePdf(t *testing.T) {
 		})
 	}
 }
+
+func Test_isPathAllowed_allowedDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "resume.pdf")
+	if err := os.WriteFile(path, []byte("%PDF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithAllowedDir(context.Background(), dir)
+	if !isPathAllowed(ctx, path) {
+		t.Errorf("isPathAllowed(%s) = false, want true", path)
+	}
+	if isPathAllowed(ctx, filepath.Join(dir, "missing.pdf")) {
+		t.Errorf("isPathAllowed() should reject missing file")
+	}
+}
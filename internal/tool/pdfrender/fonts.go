@@ -0,0 +1,145 @@
+package pdfrender
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unicode"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// defaultFontPaths 没有配置字体时按顺序查找的中文 TTF 字体
+var defaultFontPaths = []string{
+	"runtime/fonts/NotoSansSC-Regular.ttf",
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+	"/usr/share/fonts/truetype/noto/NotoSansSC-Regular.ttf",
+	"/usr/share/fonts/truetype/arphic-gbsn00lp/gbsn00lp.ttf",
+	"C:/Windows/Fonts/simhei.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+}
+
+// defaultFontDirs 常见位置都没有找到字体时，在这些目录中按文件名查找 defaultFontNames
+// 不同发行版的字体包安装位置不同，例如 fonts-droid-fallback 在 Debian 上位于 truetype/droid，在 Arch 上位于 droid
+var defaultFontDirs = []string{
+	"/usr/share/fonts",
+	"/usr/local/share/fonts",
+}
+
+// defaultFontNames 在字体目录中查找的中文 TTF 字体文件名，按顺序优先
+var defaultFontNames = []string{
+	"NotoSansSC-Regular.ttf",
+	"DroidSansFallbackFull.ttf",
+	"DroidSansFallback.ttf",
+	"gbsn00lp.ttf",
+	"wqy-microhei.ttf",
+}
+
+// ErrFontNotFound 没有找到可用的中文字体
+var ErrFontNotFound = errors.New("没有找到可用的中文字体，请在配置文件 pdf.font_path 中指定 TTF 字体")
+
+// Fonts 嵌入到 PDF 中的字体，只会嵌入实际用到的字形
+type Fonts struct {
+	Regular []byte
+	Bold    []byte
+	// 用于判断字体是否包含某个字符
+	font *sfnt.Font
+}
+
+// LoadFonts 加载字体文件，regularPath 为空时在常见位置查找，boldPath 为空时粗体使用常规字体
+func LoadFonts(regularPath, boldPath string) (*Fonts, error) {
+	var regular []byte
+	var err error
+	if regularPath != "" {
+		if regular, err = os.ReadFile(regularPath); err != nil {
+			return nil, fmt.Errorf("读取字体文件失败: %w", err)
+		}
+	} else {
+		path := findFont(defaultFontPaths, defaultFontDirs, defaultFontNames)
+		if path == "" {
+			return nil, ErrFontNotFound
+		}
+		if regular, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("读取字体文件失败: %w", err)
+		}
+	}
+
+	var bold []byte
+	if boldPath != "" {
+		if bold, err = os.ReadFile(boldPath); err != nil {
+			return nil, fmt.Errorf("读取粗体字体文件失败: %w", err)
+		}
+	}
+	return NewFonts(regular, bold)
+}
+
+// findFont 先按顺序检查 paths，都不存在时在 dirs 中递归查找文件名为 names 的字体，没有找到时返回空字符串
+func findFont(paths, dirs, names []string) string {
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	found := make(map[string]string, len(names))
+	for _, dir := range dirs {
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if _, ok := found[d.Name()]; !ok {
+				found[d.Name()] = path
+			}
+			return nil
+		})
+	}
+	for _, name := range names {
+		if path, ok := found[name]; ok {
+			return path
+		}
+	}
+	return ""
+}
+
+// NewFonts 使用字体数据创建字体，只支持 TrueType 轮廓的 TTF 字体
+func NewFonts(regular, bold []byte) (*Fonts, error) {
+	for _, data := range [][]byte{regular, bold} {
+		if bytes.HasPrefix(data, []byte("ttcf")) {
+			return nil, errors.New("不支持 TTC 字体集合，请使用 TTF 字体")
+		}
+		if bytes.HasPrefix(data, []byte("OTTO")) {
+			return nil, errors.New("不支持 CFF 轮廓的 OTF 字体，请使用 TTF 字体")
+		}
+	}
+	font, err := sfnt.Parse(regular)
+	if err != nil {
+		return nil, fmt.Errorf("解析字体失败: %w", err)
+	}
+	if bold == nil {
+		bold = regular
+	}
+	return &Fonts{Regular: regular, Bold: bold, font: font}, nil
+}
+
+// Supports 字体是否包含该字符
+func (f *Fonts) Supports(r rune) bool {
+	index, err := f.font.GlyphIndex(nil, r)
+	return err == nil && index != 0
+}
+
+// sanitize 删除字体中没有的字符（例如 emoji），避免渲染为空白方框
+func (f *Fonts) sanitize(s string) string {
+	return string(bytes.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r == '\n' || r == ' ':
+			return r
+		case unicode.IsControl(r) || !f.Supports(r):
+			return -1
+		}
+		return r
+	}, []byte(s)))
+}
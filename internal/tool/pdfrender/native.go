@@ -0,0 +1,916 @@
+package pdfrender
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/russross/blackfriday/v2"
+	"go.uber.org/zap"
+	_ "golang.org/x/image/webp"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/utils/httputils"
+)
+
+const (
+	fontFamily = "cjk"
+	// 图片最大 10MB
+	maxImageSize = 10 << 20
+	// 图片最多占页面高度的比例
+	maxImageHeightRatio = 0.45
+	// 列表缩进 单位毫米
+	listIndent = 6.0
+	// 引用缩进 单位毫米
+	quoteIndent = 5.0
+	// 代码块和表格的内边距 单位毫米
+	cellPadding = 2.5
+)
+
+var (
+	textColor  = [3]int{36, 41, 47}
+	mutedColor = [3]int{106, 115, 125}
+	codeColor  = [3]int{199, 37, 78}
+	fillColor  = [3]int{246, 248, 250}
+	lineColor  = [3]int{223, 226, 229}
+	tagRegexp  = regexp.MustCompile(`<[^>]*>`)
+)
+
+// NativeRenderer 纯 Go 实现的渲染器，直接解析 Markdown 并排版为 PDF，不依赖外部程序
+type NativeRenderer struct {
+	fonts           *Fonts
+	defaultTemplate string
+	// 下载网络图片，图片地址来自模型生成的 Markdown，不允许访问内网地址
+	client *http.Client
+}
+
+func NewNativeRenderer(fonts *Fonts, defaultTemplate string) *NativeRenderer {
+	return &NativeRenderer{
+		fonts:           fonts,
+		defaultTemplate: defaultTemplate,
+		client:          httputils.NewSafeClient(10 * time.Second),
+	}
+}
+
+func (r *NativeRenderer) Name() string {
+	return BackendNative
+}
+
+func (r *NativeRenderer) Render(ctx context.Context, doc *Document, output string) error {
+	name := doc.Template
+	if name == "" {
+		name = r.defaultTemplate
+	}
+	w := newNativeWriter(ctx, r, doc, GetTemplate(name))
+
+	// 单个换行也作为换行显示，和聊天界面中的显示效果保持一致
+	extensions := blackfriday.CommonExtensions | blackfriday.HardLineBreak
+	root := blackfriday.New(blackfriday.WithExtensions(extensions)).Parse([]byte(strings.ReplaceAll(doc.Markdown, "\r\n", "\n")))
+
+	w.pdf.AddPage()
+	for node := root.FirstChild; node != nil; node = node.Next {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w.block(node)
+	}
+	if err := w.pdf.Error(); err != nil {
+		return fmt.Errorf("生成PDF失败: %w", err)
+	}
+	if err := w.pdf.OutputFileAndClose(output); err != nil {
+		return fmt.Errorf("保存PDF失败: %w", err)
+	}
+	return nil
+}
+
+// inlineStyle 行内文本的样式
+type inlineStyle struct {
+	bold   bool
+	italic bool
+	strike bool
+	code   bool
+	link   string
+}
+
+// span 一段相同样式的行内文本，image 不为空时表示图片
+type span struct {
+	text  string
+	style inlineStyle
+	image *blackfriday.Node
+}
+
+// run 一行中相同样式的文本
+type run struct {
+	text  string
+	style inlineStyle
+	width float64
+}
+
+// line 排版后的一行，image 不为空时表示独占一行的图片
+type line struct {
+	runs  []run
+	width float64
+	image *blackfriday.Node
+}
+
+// nativeWriter 保存一次渲染的排版状态
+type nativeWriter struct {
+	ctx      context.Context
+	pdf      *fpdf.Fpdf
+	fonts    *Fonts
+	tpl      *Template
+	doc      *Document
+	client   *http.Client
+	margin   float64
+	color    [3]int
+	images   int
+	bulleted string
+}
+
+func newNativeWriter(ctx context.Context, r *NativeRenderer, doc *Document, tpl *Template) *nativeWriter {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(doc.Title, true)
+	pdf.SetCreator("TxingAI", true)
+	pdf.AddUTF8FontFromBytes(fontFamily, "", r.fonts.Regular)
+	pdf.AddUTF8FontFromBytes(fontFamily, "B", r.fonts.Bold)
+	pdf.SetMargins(tpl.Margin, tpl.Margin, tpl.Margin)
+	pdf.SetAutoPageBreak(true, tpl.Margin)
+	pdf.SetCellMargin(0)
+
+	w := &nativeWriter{
+		ctx:      ctx,
+		pdf:      pdf,
+		fonts:    r.fonts,
+		tpl:      tpl,
+		doc:      doc,
+		client:   r.client,
+		margin:   tpl.Margin,
+		color:    textColor,
+		bulleted: "-",
+	}
+	if r.fonts.Supports('•') {
+		w.bulleted = "•"
+	}
+	if tpl.ShowHeader {
+		pdf.SetHeaderFuncMode(w.header, false)
+	}
+	if tpl.ShowPageNumber {
+		pdf.AliasNbPages("")
+		pdf.SetFooterFunc(w.footer)
+	}
+	return w
+}
+
+// header 页眉：左侧文档标题，右侧模板的页眉文字
+func (w *nativeWriter) header() {
+	pageWidth, _ := w.pdf.GetPageSize()
+	width := pageWidth - 2*w.margin
+	y := w.margin / 2
+	w.pdf.SetFont(fontFamily, "", 8)
+	w.pdf.SetTextColor(mutedColor[0], mutedColor[1], mutedColor[2])
+	w.pdf.SetXY(w.margin, y)
+	w.pdf.CellFormat(width, 4, w.fonts.sanitize(w.doc.Title), "", 0, "L", false, 0, "")
+	if w.tpl.HeaderText != "" {
+		w.pdf.SetXY(w.margin, y)
+		w.pdf.CellFormat(width, 4, w.fonts.sanitize(w.tpl.HeaderText), "", 0, "R", false, 0, "")
+	}
+	w.pdf.SetDrawColor(lineColor[0], lineColor[1], lineColor[2])
+	w.pdf.SetLineWidth(0.2)
+	w.pdf.Line(w.margin, y+5, pageWidth-w.margin, y+5)
+	w.pdf.SetY(w.margin)
+}
+
+// footer 页脚：居中显示页码
+func (w *nativeWriter) footer() {
+	pageWidth, pageHeight := w.pdf.GetPageSize()
+	w.pdf.SetFont(fontFamily, "", 8)
+	w.pdf.SetTextColor(mutedColor[0], mutedColor[1], mutedColor[2])
+	w.pdf.SetXY(w.margin, pageHeight-w.margin/2-4)
+	w.pdf.CellFormat(pageWidth-2*w.margin, 4, fmt.Sprintf("%d / {nb}", w.pdf.PageNo()), "", 0, "C", false, 0, "")
+}
+
+func (w *nativeWriter) block(node *blackfriday.Node) {
+	switch node.Type {
+	case blackfriday.Heading:
+		w.heading(node)
+	case blackfriday.Paragraph:
+		w.paragraph(node)
+	case blackfriday.List:
+		w.list(node)
+	case blackfriday.BlockQuote:
+		w.blockquote(node)
+	case blackfriday.CodeBlock:
+		w.codeBlock(node)
+	case blackfriday.Table:
+		w.table(node)
+	case blackfriday.HorizontalRule:
+		w.rule()
+	case blackfriday.HTMLBlock:
+		// 不支持 HTML，只保留其中的文字
+		text := strings.TrimSpace(tagRegexp.ReplaceAllString(string(node.Literal), ""))
+		if text != "" {
+			w.renderLines(w.wrap([]span{{text: text}}, w.tpl.FontSize, w.contentWidth()), w.tpl.FontSize, "L")
+			w.pdf.Ln(w.paragraphGap())
+		}
+	default:
+		for child := node.FirstChild; child != nil; child = child.Next {
+			w.block(child)
+		}
+	}
+}
+
+func (w *nativeWriter) heading(node *blackfriday.Node) {
+	size := w.tpl.HeadingSizes[node.Level-1]
+	lineHeight := w.lineHeight(size)
+	band := node.Level == 1 && w.tpl.TitleBand
+	align := "L"
+	if node.Level == 1 && w.tpl.CenterTitle {
+		align = "C"
+	}
+
+	// 标题前留出空白，页面顶部除外
+	_, top, _, _ := w.pdf.GetMargins()
+	if w.pdf.GetY() > top+1 {
+		w.pdf.Ln(w.pdf.PointConvert(size) * 0.6)
+	}
+	// 标题和后面至少两行正文放在同一页
+	if w.pdf.GetY()+lineHeight+2*w.lineHeight(w.tpl.FontSize) > w.pageBottom() {
+		w.pdf.AddPage()
+	}
+
+	width := w.contentWidth()
+	if band {
+		width -= 2 * cellPadding
+	}
+	lines := w.wrap(w.collect(node, inlineStyle{bold: true}, nil), size, width)
+
+	color := w.color
+	if node.Level <= 3 {
+		color = w.tpl.Accent
+	}
+	if band {
+		height := float64(len(lines))*lineHeight + 2*cellPadding
+		w.pdf.SetFillColor(w.tpl.Accent[0], w.tpl.Accent[1], w.tpl.Accent[2])
+		w.pdf.Rect(w.left(), w.pdf.GetY(), w.contentWidth(), height, "F")
+		w.pdf.Ln(cellPadding)
+		color = [3]int{255, 255, 255}
+	}
+
+	saved := w.color
+	w.color = color
+	w.renderLinesAt(lines, size, align, band)
+	w.color = saved
+
+	if band {
+		w.pdf.Ln(cellPadding)
+	}
+	if w.tpl.HeadingRule && node.Level <= 2 && !band {
+		y := w.pdf.GetY() + 1
+		w.pdf.SetDrawColor(lineColor[0], lineColor[1], lineColor[2])
+		w.pdf.SetLineWidth(0.3)
+		w.pdf.Line(w.left(), y, w.left()+w.contentWidth(), y)
+		w.pdf.Ln(2)
+	}
+	w.pdf.Ln(w.paragraphGap() / 2)
+}
+
+func (w *nativeWriter) paragraph(node *blackfriday.Node) {
+	lines := w.wrap(w.collect(node, inlineStyle{}, nil), w.tpl.FontSize, w.contentWidth())
+	w.renderLines(lines, w.tpl.FontSize, "L")
+	// 紧凑列表中的段落之间不留空白
+	if node.Parent != nil && node.Parent.Type == blackfriday.Item && node.Parent.Tight {
+		return
+	}
+	w.pdf.Ln(w.paragraphGap())
+}
+
+func (w *nativeWriter) list(node *blackfriday.Node) {
+	left := w.left()
+	lineHeight := w.lineHeight(w.tpl.FontSize)
+	ordered := node.ListFlags&blackfriday.ListTypeOrdered != 0
+
+	index := 1
+	for item := node.FirstChild; item != nil; item = item.Next {
+		if item.Type != blackfriday.Item {
+			continue
+		}
+		marker := w.bulleted
+		if ordered {
+			marker = fmt.Sprintf("%d.", index)
+		}
+		index++
+
+		// 序号和内容的第一行放在同一页
+		if w.pdf.GetY()+lineHeight > w.pageBottom() {
+			w.pdf.AddPage()
+		}
+		w.setStyle(inlineStyle{}, w.tpl.FontSize)
+		w.pdf.SetX(left)
+		w.pdf.CellFormat(listIndent, lineHeight, marker, "", 0, "L", false, 0, "")
+
+		w.pdf.SetLeftMargin(left + listIndent)
+		for child := item.FirstChild; child != nil; child = child.Next {
+			w.block(child)
+		}
+		w.pdf.SetLeftMargin(left)
+		w.pdf.SetX(left)
+	}
+	// 嵌套列表后面不留空白
+	if node.Parent == nil || node.Parent.Type != blackfriday.Item {
+		w.pdf.Ln(w.paragraphGap())
+	}
+}
+
+func (w *nativeWriter) blockquote(node *blackfriday.Node) {
+	left := w.left()
+	startPage, startY := w.pdf.PageNo(), w.pdf.GetY()
+
+	saved := w.color
+	w.color = mutedColor
+	w.pdf.SetLeftMargin(left + quoteIndent)
+	w.pdf.SetX(left + quoteIndent)
+	for child := node.FirstChild; child != nil; child = child.Next {
+		w.block(child)
+	}
+	w.pdf.SetLeftMargin(left)
+	w.pdf.SetX(left)
+	w.color = saved
+
+	// 左侧的竖线，跨页时只画在最后一页
+	if w.pdf.PageNo() != startPage {
+		_, startY, _, _ = w.pdf.GetMargins()
+	}
+	endY := w.pdf.GetY() - w.paragraphGap()
+	if endY > startY {
+		w.pdf.SetFillColor(lineColor[0], lineColor[1], lineColor[2])
+		w.pdf.Rect(left, startY, 1, endY-startY, "F")
+	}
+}
+
+func (w *nativeWriter) codeBlock(node *blackfriday.Node) {
+	text := strings.TrimRight(strings.ReplaceAll(string(node.Literal), "\t", "    "), "\n")
+	size := w.tpl.FontSize - 1
+	lineHeight := w.pdf.PointConvert(size) * 1.4
+	width := w.contentWidth()
+
+	// 纯英文代码使用等宽字体
+	family := fontFamily
+	if isASCII(text) {
+		family = "courier"
+	} else {
+		text = w.fonts.sanitize(text)
+	}
+	w.pdf.SetFont(family, "", size)
+	var lines []string
+	for _, raw := range strings.Split(text, "\n") {
+		lines = append(lines, w.breakRunes(raw, width-2*cellPadding)...)
+	}
+
+	w.pdf.SetFillColor(fillColor[0], fillColor[1], fillColor[2])
+	w.pdf.SetTextColor(textColor[0], textColor[1], textColor[2])
+	left := w.left()
+	for i, text := range lines {
+		if w.pdf.GetY()+lineHeight > w.pageBottom() {
+			w.pdf.AddPage()
+			w.pdf.SetFont(family, "", size)
+		}
+		y := w.pdf.GetY()
+		top, bottom := 0.0, 0.0
+		if i == 0 {
+			top = cellPadding
+		}
+		if i == len(lines)-1 {
+			bottom = cellPadding
+		}
+		w.pdf.Rect(left, y, width, top+lineHeight+bottom, "F")
+		w.pdf.SetXY(left+cellPadding, y+top)
+		w.pdf.CellFormat(width-2*cellPadding, lineHeight, text, "", 0, "L", false, 0, "")
+		w.pdf.SetXY(left, y+top+lineHeight+bottom)
+	}
+	w.pdf.Ln(w.paragraphGap())
+}
+
+func (w *nativeWriter) rule() {
+	gap := w.paragraphGap()
+	y := w.pdf.GetY() + gap/2
+	w.pdf.SetDrawColor(lineColor[0], lineColor[1], lineColor[2])
+	w.pdf.SetLineWidth(0.3)
+	w.pdf.Line(w.left(), y, w.left()+w.contentWidth(), y)
+	w.pdf.Ln(gap)
+}
+
+// tableCell 表格单元格
+type tableCell struct {
+	text  string
+	align string
+}
+
+func (w *nativeWriter) table(node *blackfriday.Node) {
+	var header []tableCell
+	var rows [][]tableCell
+	columns := 0
+	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || n.Type != blackfriday.TableRow {
+			return blackfriday.GoToNext
+		}
+		var row []tableCell
+		for cell := n.FirstChild; cell != nil; cell = cell.Next {
+			var text strings.Builder
+			for _, s := range w.collect(cell, inlineStyle{}, nil) {
+				text.WriteString(s.text)
+			}
+			row = append(row, tableCell{text: strings.TrimSpace(text.String()), align: cellAlign(cell.Align)})
+		}
+		columns = max(columns, len(row))
+		if n.Parent != nil && n.Parent.Type == blackfriday.TableHead {
+			header = row
+		} else {
+			rows = append(rows, row)
+		}
+		return blackfriday.SkipChildren
+	})
+	if columns == 0 {
+		return
+	}
+
+	widths := w.columnWidths(append([][]tableCell{header}, rows...), columns)
+	size := w.tpl.FontSize - 1
+	if header != nil {
+		w.tableRow(header, widths, size, true, false, nil)
+	}
+	for i, row := range rows {
+		w.tableRow(row, widths, size, false, i%2 == 1, header)
+	}
+	w.pdf.Ln(w.paragraphGap())
+}
+
+// columnWidths 按内容长度分配列宽，内容越长的列越宽
+func (w *nativeWriter) columnWidths(rows [][]tableCell, columns int) []float64 {
+	total := w.contentWidth()
+	minWidth := total / float64(columns) / 2
+	w.setStyle(inlineStyle{bold: true}, w.tpl.FontSize-1)
+
+	weights := make([]float64, columns)
+	for _, row := range rows {
+		for i, cell := range row {
+			weights[i] = max(weights[i], w.pdf.GetStringWidth(w.fonts.sanitize(cell.text))+2*cellPadding)
+		}
+	}
+	sum := 0.0
+	for i := range weights {
+		weights[i] = math.Min(math.Max(weights[i], minWidth), total)
+		sum += weights[i]
+	}
+	widths := make([]float64, columns)
+	for i := range weights {
+		widths[i] = weights[i] / sum * total
+	}
+	return widths
+}
+
+// tableRow 绘制一行表格，换页后先重复绘制表头 repeat
+func (w *nativeWriter) tableRow(row []tableCell, widths []float64, size float64, header, striped bool, repeat []tableCell) {
+	style := inlineStyle{bold: header}
+	lineHeight := w.pdf.PointConvert(size) * 1.4
+
+	cells := make([][]string, len(widths))
+	lines := 1
+	for i := range widths {
+		if i >= len(row) {
+			continue
+		}
+		for _, l := range w.wrap([]span{{text: row[i].text, style: style}}, size, widths[i]-2*cellPadding) {
+			var text strings.Builder
+			for _, r := range l.runs {
+				text.WriteString(r.text)
+			}
+			cells[i] = append(cells[i], text.String())
+		}
+		lines = max(lines, len(cells[i]))
+	}
+	height := float64(lines)*lineHeight + 2*cellPadding
+	if w.pdf.GetY()+height > w.pageBottom() {
+		w.pdf.AddPage()
+		if repeat != nil {
+			w.tableRow(repeat, widths, size, true, false, nil)
+		}
+	}
+
+	w.setStyle(style, size)
+	w.pdf.SetDrawColor(lineColor[0], lineColor[1], lineColor[2])
+	w.pdf.SetLineWidth(0.2)
+	switch {
+	case header:
+		w.pdf.SetFillColor(w.tpl.Accent[0], w.tpl.Accent[1], w.tpl.Accent[2])
+		w.pdf.SetTextColor(255, 255, 255)
+	case striped:
+		w.pdf.SetFillColor(fillColor[0], fillColor[1], fillColor[2])
+		w.pdf.SetTextColor(w.color[0], w.color[1], w.color[2])
+	default:
+		w.pdf.SetFillColor(255, 255, 255)
+		w.pdf.SetTextColor(w.color[0], w.color[1], w.color[2])
+	}
+
+	x, y := w.left(), w.pdf.GetY()
+	for i, width := range widths {
+		w.pdf.Rect(x, y, width, height, "FD")
+		align := "L"
+		if i < len(row) {
+			align = row[i].align
+		}
+		for j, text := range cells[i] {
+			w.pdf.SetXY(x+cellPadding, y+cellPadding+float64(j)*lineHeight)
+			w.pdf.CellFormat(width-2*cellPadding, lineHeight, text, "", 0, align, false, 0, "")
+		}
+		x += width
+	}
+	w.pdf.SetXY(w.left(), y+height)
+}
+
+func (w *nativeWriter) image(node *blackfriday.Node) {
+	src := string(node.LinkData.Destination)
+	data, err := w.loadImage(src)
+	if err == nil {
+		err = w.drawImage(data)
+	}
+	if err != nil {
+		// 图片加载失败不影响文档的其它内容
+		log.Warn("PDF图片加载失败", zap.String("src", src), zap.Error(err))
+	}
+}
+
+func (w *nativeWriter) drawImage(data []byte) error {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("不支持的图片格式: %w", err)
+	}
+	// JPEG 直接嵌入，其它格式统一转换为 PNG
+	imageType := "JPG"
+	if format != "jpeg" {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("解码图片失败: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return fmt.Errorf("转换图片失败: %w", err)
+		}
+		data, imageType = buf.Bytes(), "PNG"
+	}
+
+	w.images++
+	name := fmt.Sprintf("image%d", w.images)
+	options := fpdf.ImageOptions{ImageType: imageType}
+	info := w.pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(data))
+	if err := w.pdf.Error(); err != nil {
+		w.pdf.ClearError()
+		return fmt.Errorf("嵌入图片失败: %w", err)
+	}
+	if info == nil || info.Width() <= 0 || info.Height() <= 0 {
+		return errors.New("图片尺寸无效")
+	}
+
+	// 按比例缩放到内容宽度以内，高度不超过页面的一部分
+	_, pageHeight := w.pdf.GetPageSize()
+	width := math.Min(info.Width(), w.contentWidth())
+	height := width * info.Height() / info.Width()
+	if maxHeight := pageHeight * maxImageHeightRatio; height > maxHeight {
+		height = maxHeight
+		width = height * info.Width() / info.Height()
+	}
+	if w.pdf.GetY()+height > w.pageBottom() {
+		w.pdf.AddPage()
+	}
+	y := w.pdf.GetY()
+	w.pdf.ImageOptions(name, w.left()+(w.contentWidth()-width)/2, y, width, height, false, options, 0, "")
+	w.pdf.SetY(y + height)
+	w.pdf.Ln(w.paragraphGap() / 2)
+	return nil
+}
+
+// loadImage 读取图片，支持网络图片和 BaseDir 下的本地图片
+func (w *nativeWriter) loadImage(src string) ([]byte, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		req, err := http.NewRequestWithContext(w.ctx, http.MethodGet, src, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := w.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("下载图片失败，状态码: %d", resp.StatusCode)
+		}
+		return readLimited(resp.Body)
+	}
+
+	// 本地图片只允许读取 BaseDir 下的文件
+	if w.doc.BaseDir == "" || filepath.IsAbs(src) {
+		return nil, errors.New("不支持的图片路径")
+	}
+	path := filepath.Join(w.doc.BaseDir, filepath.FromSlash(src))
+	if rel, err := filepath.Rel(w.doc.BaseDir, path); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, errors.New("不支持的图片路径")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readLimited(file)
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, errors.New("图片超过 10MB")
+	}
+	return data, nil
+}
+
+// collect 将行内节点展开为带样式的文本片段
+func (w *nativeWriter) collect(node *blackfriday.Node, style inlineStyle, spans []span) []span {
+	for child := node.FirstChild; child != nil; child = child.Next {
+		switch child.Type {
+		case blackfriday.Text:
+			spans = append(spans, span{text: string(child.Literal), style: style})
+		case blackfriday.Code:
+			codeStyle := style
+			codeStyle.code = true
+			spans = append(spans, span{text: string(child.Literal), style: codeStyle})
+		case blackfriday.Hardbreak:
+			spans = append(spans, span{text: "\n", style: style})
+		case blackfriday.Softbreak:
+			spans = append(spans, span{text: " ", style: style})
+		case blackfriday.HTMLSpan:
+			if strings.HasPrefix(strings.ToLower(string(child.Literal)), "<br") {
+				spans = append(spans, span{text: "\n", style: style})
+			}
+		case blackfriday.Image:
+			spans = append(spans, span{image: child})
+		case blackfriday.Strong:
+			s := style
+			s.bold = true
+			spans = w.collect(child, s, spans)
+		case blackfriday.Emph:
+			s := style
+			s.italic = true
+			spans = w.collect(child, s, spans)
+		case blackfriday.Del:
+			s := style
+			s.strike = true
+			spans = w.collect(child, s, spans)
+		case blackfriday.Link:
+			s := style
+			s.link = string(child.LinkData.Destination)
+			spans = w.collect(child, s, spans)
+		default:
+			spans = w.collect(child, style, spans)
+		}
+	}
+	return spans
+}
+
+// wrap 按宽度折行，中文按字折行，英文按单词折行
+func (w *nativeWriter) wrap(spans []span, size, width float64) []line {
+	var lines []line
+	var current line
+	flush := func() {
+		lines = append(lines, current)
+		current = line{}
+	}
+	add := func(text string, style inlineStyle, textWidth float64) {
+		if n := len(current.runs); n > 0 && current.runs[n-1].style == style {
+			current.runs[n-1].text += text
+			current.runs[n-1].width += textWidth
+		} else {
+			current.runs = append(current.runs, run{text: text, style: style, width: textWidth})
+		}
+		current.width += textWidth
+	}
+
+	for _, s := range spans {
+		if s.image != nil {
+			if len(current.runs) > 0 {
+				flush()
+			}
+			lines = append(lines, line{image: s.image})
+			continue
+		}
+		w.setStyle(s.style, size)
+		for _, token := range tokenize(w.fonts.sanitize(s.text)) {
+			if token == "\n" {
+				flush()
+				continue
+			}
+			space := strings.TrimSpace(token) == ""
+			if space && len(current.runs) == 0 && len(lines) > 0 {
+				// 折行后行首的空格
+				continue
+			}
+			tokenWidth := w.pdf.GetStringWidth(token)
+			if current.width+tokenWidth > width && len(current.runs) > 0 {
+				flush()
+				if space {
+					continue
+				}
+			}
+			if tokenWidth <= width {
+				add(token, s.style, tokenWidth)
+				continue
+			}
+			// 超过一行的长单词（例如链接）按字符折行
+			for i, part := range w.breakRunes(token, width) {
+				if i > 0 {
+					flush()
+				}
+				add(part, s.style, w.pdf.GetStringWidth(part))
+			}
+		}
+	}
+	if len(current.runs) > 0 || len(lines) == 0 {
+		flush()
+	}
+	return lines
+}
+
+// breakRunes 按字符将文本拆分为不超过指定宽度的多行
+func (w *nativeWriter) breakRunes(text string, width float64) []string {
+	var lines []string
+	var current strings.Builder
+	currentWidth := 0.0
+	for _, r := range text {
+		runeWidth := w.pdf.GetStringWidth(string(r))
+		if currentWidth+runeWidth > width && current.Len() > 0 {
+			lines = append(lines, current.String())
+			current.Reset()
+			currentWidth = 0
+		}
+		current.WriteRune(r)
+		currentWidth += runeWidth
+	}
+	return append(lines, current.String())
+}
+
+func (w *nativeWriter) renderLines(lines []line, size float64, align string) {
+	w.renderLinesAt(lines, size, align, false)
+}
+
+// renderLinesAt 绘制折行后的文本，noBreak 为 true 时不换页（例如标题背景已经绘制）
+func (w *nativeWriter) renderLinesAt(lines []line, size float64, align string, noBreak bool) {
+	lineHeight := w.lineHeight(size)
+	for _, l := range lines {
+		if l.image != nil {
+			w.image(l.image)
+			continue
+		}
+		if !noBreak && w.pdf.GetY()+lineHeight > w.pageBottom() {
+			w.pdf.AddPage()
+		}
+		x := w.left()
+		if align == "C" {
+			x += (w.contentWidth() - l.width) / 2
+		}
+		w.pdf.SetX(x)
+		for _, r := range l.runs {
+			w.setStyle(r.style, size)
+			w.pdf.CellFormat(r.width, lineHeight, r.text, "", 0, "L", r.style.code, 0, linkTarget(r.style.link))
+		}
+		w.pdf.Ln(lineHeight)
+	}
+}
+
+// setStyle 设置行内样式对应的字体和颜色，中文字体没有斜体，使用灰色代替
+func (w *nativeWriter) setStyle(style inlineStyle, size float64) {
+	fontStyle := ""
+	if style.bold {
+		fontStyle += "B"
+	}
+	if style.strike {
+		fontStyle += "S"
+	}
+	color := w.color
+	switch {
+	case style.link != "":
+		fontStyle += "U"
+		color = w.tpl.Accent
+	case style.code:
+		color = codeColor
+		w.pdf.SetFillColor(fillColor[0], fillColor[1], fillColor[2])
+	case style.italic:
+		color = mutedColor
+	}
+	w.pdf.SetFont(fontFamily, fontStyle, size)
+	w.pdf.SetTextColor(color[0], color[1], color[2])
+}
+
+func (w *nativeWriter) left() float64 {
+	left, _, _, _ := w.pdf.GetMargins()
+	return left
+}
+
+func (w *nativeWriter) contentWidth() float64 {
+	pageWidth, _ := w.pdf.GetPageSize()
+	return pageWidth - w.left() - w.margin
+}
+
+func (w *nativeWriter) pageBottom() float64 {
+	_, pageHeight := w.pdf.GetPageSize()
+	return pageHeight - w.margin
+}
+
+func (w *nativeWriter) lineHeight(size float64) float64 {
+	return w.pdf.PointConvert(size) * w.tpl.LineSpacing
+}
+
+func (w *nativeWriter) paragraphGap() float64 {
+	return w.pdf.PointConvert(w.tpl.FontSize) * 0.6
+}
+
+// tokenize 将文本拆分为折行的最小单位：中文等宽字符单独成词，英文按单词和空格拆分
+func tokenize(text string) []string {
+	var tokens []string
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, text[start:end])
+			start = -1
+		}
+	}
+	prevSpace := false
+	for i, r := range text {
+		switch {
+		case r == '\n':
+			flush(i)
+			tokens = append(tokens, "\n")
+		case isWide(r):
+			flush(i)
+			tokens = append(tokens, string(r))
+		default:
+			space := unicode.IsSpace(r)
+			if start >= 0 && space != prevSpace {
+				flush(i)
+			}
+			if start < 0 {
+				start = i
+			}
+			prevSpace = space
+		}
+	}
+	flush(len(text))
+	return tokens
+}
+
+// isWide 中日韩文字和全角标点，可以在任意位置折行
+func isWide(r rune) bool {
+	return r >= 0x2E80 && (unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// linkTarget 只保留可以在 PDF 中打开的链接
+func linkTarget(link string) string {
+	if strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") || strings.HasPrefix(link, "mailto:") {
+		return link
+	}
+	return ""
+}
+
+func cellAlign(align blackfriday.CellAlignFlags) string {
+	switch align {
+	case blackfriday.TableAlignmentCenter:
+		return "C"
+	case blackfriday.TableAlignmentRight:
+		return "R"
+	default:
+		return "L"
+	}
+}
@@ -0,0 +1,205 @@
+package pdfrender
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"txing-ai/internal/global/logging"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+const testMarkdown = `# 张三的简历
+
+**Go** 开发工程师，邮箱 [zhangsan@example.com](mailto:zhangsan@example.com)
+第二行使用单个换行 ` + "`code`" + ` ~~删除~~ *强调* 😀
+
+## 工作经历
+
+1. 负责后端服务开发
+2. 参与架构设计
+   - 嵌套列表
+   - https://example.com/a/very/long/url/that/should/be/broken/into/several/lines/because/it/is/longer/than/the/page/width
+
+> 这是一段引用
+
+` + "```go\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n```" + `
+
+| 日期 | 行程 | 费用 |
+| --- | :---: | ---: |
+| 第一天 | 抵达杭州，游览西湖 | 200 |
+| 第二天 | 灵隐寺 | 75 |
+
+---
+
+![图片](image.png)
+
+![不存在的图片](missing.png)
+`
+
+func TestNativeRenderer_Render(t *testing.T) {
+	fonts, err := NewFonts(goregular.TTF, gobold.TTF)
+	if err != nil {
+		t.Fatalf("NewFonts() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		img.Set(x, 10, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	if err := os.WriteFile(filepath.Join(dir, "image.png"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	renderer := NewNativeRenderer(fonts, TemplateDefault)
+	for _, name := range TemplateNames() {
+		t.Run(name, func(t *testing.T) {
+			output := filepath.Join(dir, name+".pdf")
+			doc := &Document{
+				Title:    "测试文档",
+				Markdown: testMarkdown + strings.Repeat("很长的正文内容，用于测试自动分页。\n\n", 80),
+				Template: name,
+				BaseDir:  dir,
+			}
+			if err := renderer.Render(context.Background(), doc, output); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(data, []byte("%PDF-")) {
+				t.Errorf("Render() output is not a PDF")
+			}
+		})
+	}
+}
+
+// loadCJKFonts 加载系统中的中文字体，没有安装时跳过测试
+func loadCJKFonts(t *testing.T) *Fonts {
+	t.Helper()
+	fonts, err := LoadFonts("", "")
+	if errors.Is(err, ErrFontNotFound) {
+		t.Skip("没有找到中文 TTF 字体，例如 fonts-droid-fallback")
+	}
+	if err != nil {
+		t.Fatalf("LoadFonts() error = %v", err)
+	}
+	if !fonts.Supports('中') {
+		t.Skip("找到的字体不包含中文字符")
+	}
+	return fonts
+}
+
+func TestFonts_SanitizeCJK(t *testing.T) {
+	fonts := loadCJKFonts(t)
+	text := "成都三日游：宽窄巷子、熊猫基地\n第二天\t锦里"
+	if got, want := fonts.sanitize(text), strings.ReplaceAll(text, "\t", " "); got != want {
+		t.Errorf("sanitize() = %q, want %q", got, want)
+	}
+	// 字体中没有的字符被删除
+	if !fonts.Supports('\U0010FFFD') {
+		if got := fonts.sanitize("你好\U0010FFFD"); got != "你好" {
+			t.Errorf("sanitize() = %q, want %q", got, "你好")
+		}
+	}
+}
+
+func TestNativeWriter_WrapCJK(t *testing.T) {
+	fonts := loadCJKFonts(t)
+	w := newNativeWriter(context.Background(), NewNativeRenderer(fonts, TemplateDefault), &Document{}, GetTemplate(TemplateDefault))
+	text := strings.Repeat("很长的中文正文内容用于测试按字折行", 10)
+	const size, width = 11.0, 80.0
+
+	lines := w.wrap([]span{{text: text}}, size, width)
+	if len(lines) < 2 {
+		t.Fatalf("wrap() lines = %d, want more than 1", len(lines))
+	}
+	var joined strings.Builder
+	for _, l := range lines {
+		if l.width > width {
+			t.Errorf("line width = %.2f, want <= %.2f", l.width, width)
+		}
+		for _, r := range l.runs {
+			joined.WriteString(r.text)
+		}
+	}
+	// 折行不丢失字符
+	if joined.String() != text {
+		t.Errorf("wrap() text = %q, want %q", joined.String(), text)
+	}
+}
+
+func Test_findFont(t *testing.T) {
+	dir := t.TempDir()
+	// 模拟其他发行版中 fonts-droid-fallback 的安装位置
+	droid := filepath.Join(dir, "google-droid-sans-fonts", "DroidSansFallbackFull.ttf")
+	if err := os.MkdirAll(filepath.Dir(droid), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(droid, goregular.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.ttf")
+
+	if got := findFont([]string{missing}, []string{dir}, defaultFontNames); got != droid {
+		t.Errorf("findFont() = %q, want %q", got, droid)
+	}
+	if got := findFont([]string{missing, droid}, nil, nil); got != droid {
+		t.Errorf("findFont() = %q, want %q", got, droid)
+	}
+	if got := findFont([]string{missing}, []string{filepath.Join(dir, "not_exist")}, defaultFontNames); got != "" {
+		t.Errorf("findFont() = %q, want empty", got)
+	}
+}
+
+func Test_tokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "hello world", want: []string{"hello", " ", "world"}},
+		{text: "你好Go语言", want: []string{"你", "好", "Go", "语", "言"}},
+		{text: "第一行\n第二行", want: []string{"第", "一", "行", "\n", "第", "二", "行"}},
+	}
+	for _, tt := range tests {
+		got := tokenize(tt.text)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestGetTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "resume", want: TemplateResume},
+		{name: " Travel ", want: TemplateTravel},
+		{name: "", want: TemplateDefault},
+		{name: "unknown", want: TemplateDefault},
+	}
+	for _, tt := range tests {
+		if got := GetTemplate(tt.name).Name; got != tt.want {
+			t.Errorf("GetTemplate(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
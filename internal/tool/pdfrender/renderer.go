@@ -0,0 +1,58 @@
+package pdfrender
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"txing-ai/internal/global"
+)
+
+const (
+	// 纯 Go 实现的渲染方式，不依赖外部程序
+	BackendNative = "native"
+	// 使用 wkhtmltopdf 渲染，需要在服务器上安装
+	BackendWkhtmltopdf = "wkhtmltopdf"
+)
+
+// Document 要渲染的 Markdown 文档
+type Document struct {
+	Title    string
+	Markdown string
+	// 模板名称，为空时使用默认模板
+	Template string
+	// 相对路径图片所在的目录
+	BaseDir string
+}
+
+// Renderer 将 Markdown 文档渲染为 PDF 文件
+type Renderer interface {
+	// Name 渲染方式名称
+	Name() string
+	// Render 渲染文档并保存到 output
+	Render(ctx context.Context, doc *Document, output string) error
+}
+
+// NewFromConfig 根据配置创建渲染器，没有配置时使用纯 Go 实现
+func NewFromConfig(config *global.PDFConfig) (Renderer, error) {
+	backend := BackendNative
+	var fontPath, boldFontPath, template string
+	if config != nil {
+		if config.Backend != "" {
+			backend = strings.ToLower(config.Backend)
+		}
+		fontPath, boldFontPath, template = config.FontPath, config.BoldFontPath, config.Template
+	}
+
+	switch backend {
+	case BackendNative:
+		fonts, err := LoadFonts(fontPath, boldFontPath)
+		if err != nil {
+			return nil, err
+		}
+		return NewNativeRenderer(fonts, template), nil
+	case BackendWkhtmltopdf:
+		return NewWkhtmltopdfRenderer(template), nil
+	default:
+		return nil, fmt.Errorf("不支持的 PDF 渲染方式: %s", backend)
+	}
+}
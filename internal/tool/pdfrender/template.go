@@ -0,0 +1,116 @@
+package pdfrender
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	TemplateDefault = "default"
+	TemplateResume  = "resume"
+	TemplateTravel  = "travel"
+)
+
+// Template PDF 的版式，两种渲染方式共用
+type Template struct {
+	Name        string
+	Description string
+	// 页边距 单位毫米
+	Margin float64
+	// 正文字号 单位磅
+	FontSize float64
+	// 行距倍数
+	LineSpacing float64
+	// 一级到六级标题的字号 单位磅
+	HeadingSizes [6]float64
+	// 主题色，用于标题、链接和表头
+	Accent [3]int
+	// 一级标题居中，例如简历中的姓名
+	CenterTitle bool
+	// 一级标题使用主题色背景
+	TitleBand bool
+	// 一、二级标题下方显示分隔线
+	HeadingRule bool
+	// 页眉显示文档标题和 HeaderText
+	ShowHeader bool
+	HeaderText string
+	// 页脚显示页码
+	ShowPageNumber bool
+}
+
+var templates = map[string]*Template{
+	TemplateDefault: {
+		Name:           TemplateDefault,
+		Description:    "通用文档",
+		Margin:         20,
+		FontSize:       11,
+		LineSpacing:    1.6,
+		HeadingSizes:   [6]float64{22, 18, 15, 13, 12, 11},
+		Accent:         [3]int{36, 41, 47},
+		HeadingRule:    true,
+		ShowHeader:     true,
+		ShowPageNumber: true,
+	},
+	TemplateResume: {
+		Name:           TemplateResume,
+		Description:    "简历，版面紧凑，姓名居中",
+		Margin:         15,
+		FontSize:       10,
+		LineSpacing:    1.5,
+		HeadingSizes:   [6]float64{22, 14, 12, 11, 10, 10},
+		Accent:         [3]int{31, 78, 121},
+		CenterTitle:    true,
+		HeadingRule:    true,
+		ShowPageNumber: true,
+	},
+	TemplateTravel: {
+		Name:           TemplateTravel,
+		Description:    "旅行攻略，图文并茂",
+		Margin:         18,
+		FontSize:       11,
+		LineSpacing:    1.7,
+		HeadingSizes:   [6]float64{24, 17, 14, 12, 11, 11},
+		Accent:         [3]int{0, 128, 128},
+		TitleBand:      true,
+		ShowHeader:     true,
+		HeaderText:     "旅行攻略",
+		ShowPageNumber: true,
+	},
+}
+
+// GetTemplate 获取模板，不存在时返回默认模板
+func GetTemplate(name string) *Template {
+	if t, ok := templates[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return t
+	}
+	return templates[TemplateDefault]
+}
+
+// TemplateNames 所有模板的名称
+func TemplateNames() []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// css wkhtmltopdf 使用的模板样式
+func (t *Template) css() string {
+	accent := fmt.Sprintf("rgb(%d, %d, %d)", t.Accent[0], t.Accent[1], t.Accent[2])
+	css := fmt.Sprintf(`
+        body { font-size: %.0fpt; line-height: %.1f; }
+        h1, h2, h3 { color: %s; }
+        th { background-color: %s; color: #fff; }`, t.FontSize, t.LineSpacing, accent, accent)
+	if t.CenterTitle {
+		css += `
+        h1 { text-align: center; border-bottom: none; }`
+	}
+	if t.TitleBand {
+		css += fmt.Sprintf(`
+        h1 { background-color: %s; color: #fff; padding: 8px 12px; border-bottom: none; }`, accent)
+	}
+	return css
+}
@@ -0,0 +1,223 @@
+package pdfrender
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// WkhtmltopdfRenderer 先将 Markdown 转换为 HTML，再调用 wkhtmltopdf 生成 PDF
+// wkhtmltopdf 需要在服务器上安装，并且已经停止维护，只作为可选的渲染方式
+type WkhtmltopdfRenderer struct {
+	defaultTemplate string
+}
+
+func NewWkhtmltopdfRenderer(defaultTemplate string) *WkhtmltopdfRenderer {
+	return &WkhtmltopdfRenderer{defaultTemplate: defaultTemplate}
+}
+
+func (r *WkhtmltopdfRenderer) Name() string {
+	return BackendWkhtmltopdf
+}
+
+func (r *WkhtmltopdfRenderer) Render(ctx context.Context, doc *Document, output string) error {
+	// 检查wkhtmltopdf是否安装
+	if _, err := exec.LookPath("wkhtmltopdf"); err != nil {
+		return fmt.Errorf("wkhtmltopdf未安装: %v", err)
+	}
+
+	name := doc.Template
+	if name == "" {
+		name = r.defaultTemplate
+	}
+	content := markdownToHTML(doc.Markdown, doc.Title, GetTemplate(name))
+
+	// 图片使用相对路径，HTML 临时文件需要和图片放在同一个目录
+	dir := doc.BaseDir
+	if dir == "" {
+		dir = filepath.Dir(output)
+	}
+	htmlPath := filepath.Join(dir, fmt.Sprintf("temp_%d.html", time.Now().UnixNano()))
+	if err := os.WriteFile(htmlPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("保存HTML临时文件失败: %w", err)
+	}
+	defer os.Remove(htmlPath)
+
+	// 构建wkhtmltopdf命令
+	cmd := exec.CommandContext(ctx,
+		"wkhtmltopdf",
+		"--enable-local-file-access",
+		"--enable-javascript",
+		"--javascript-delay", "1000",
+		"--no-stop-slow-scripts",
+		"--disable-smart-shrinking",
+		"--page-size", "A4",
+		"--margin-top", "20",
+		"--margin-right", "20",
+		"--margin-bottom", "20",
+		"--margin-left", "20",
+		"--encoding", "UTF-8",
+		htmlPath,
+		output,
+	)
+
+	// 执行命令
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("wkhtmltopdf执行失败: %v, 输出: %s", err, string(out))
+	}
+	return nil
+}
+
+// markdownToHTML 将Markdown内容转换为HTML
+func markdownToHTML(content string, title string, tpl *Template) string {
+	// 设置Blackfriday扩展选项，单个换行也作为换行显示
+	extensions := blackfriday.CommonExtensions | blackfriday.AutoHeadingIDs | blackfriday.HardLineBreak
+
+	// 创建HTML渲染器
+	htmlFlags := blackfriday.CommonHTMLFlags
+	renderer := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+		Flags: htmlFlags,
+	})
+
+	// 将Markdown转换为HTML
+	body := blackfriday.Run([]byte(content), blackfriday.WithExtensions(extensions), blackfriday.WithRenderer(renderer))
+
+	// 处理HTML中的emoji
+	processedHTML := processEmojis(string(body))
+
+	// 生成完整的HTML文档
+	return generateHTML(processedHTML, html.EscapeString(title), tpl.css())
+}
+
+// processEmojis 处理HTML内容中的emoji字符，为其添加特殊样式类
+func processEmojis(htmlContent string) string {
+	// 匹配常见的emoji字符范围
+	// Go的正则表达式不支持\p{Emoji}，所以使用Unicode范围来匹配常见emoji
+	emojiRegex := regexp.MustCompile(`[\x{1F300}-\x{1F6FF}\x{1F900}-\x{1F9FF}\x{2600}-\x{26FF}\x{2700}-\x{27BF}]`)
+
+	// 为每个emoji添加span标签和emoji类
+	processedHTML := emojiRegex.ReplaceAllStringFunc(htmlContent, func(emoji string) string {
+		return fmt.Sprintf("<span class=\"emoji\">%s</span>", emoji)
+	})
+
+	return processedHTML
+}
+
+// generateHTML 生成完整的HTML文档
+func generateHTML(body string, title string, css string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>%s</title>
+    <style>
+        @font-face {
+            font-family: 'NotoEmoji';
+            src: url('https://cdn.jsdelivr.net/npm/emoji-datasource-apple@14.0.0/img/apple/sheets/32.png') format('png');
+            font-display: swap;
+        }
+
+        body {
+            font-family: 'Noto Sans', 'Noto Sans CJK SC', 'Microsoft YaHei', 'Segoe UI', 'Arial', 'Helvetica', 'DejaVu Sans', sans-serif;
+            line-height: 1.6;
+            color: #333;
+            max-width: 800px;
+            margin: 0 auto;
+            padding: 0px 20px;
+        }
+
+        h1, h2, h3, h4, h5, h6 {
+            margin-top: 24px;
+            margin-bottom: 16px;
+            font-weight: 600;
+            line-height: 1.25;
+        }
+
+        h1 {
+            font-size: 2em;
+            border-bottom: 1px solid #eaecef;
+            padding-bottom: 0.3em;
+        }
+
+        h2 {
+            font-size: 1.5em;
+            border-bottom: 1px solid #eaecef;
+            padding-bottom: 0.3em;
+        }
+
+        a {
+            color: #0366d6;
+            text-decoration: none;
+        }
+
+        a:hover {
+            text-decoration: underline;
+        }
+
+        pre {
+            background-color: #f6f8fa;
+            border-radius: 3px;
+            padding: 16px;
+            overflow: auto;
+        }
+
+        code {
+            font-family: 'SFMono-Regular', Consolas, 'Liberation Mono', Menlo, monospace;
+            background-color: #f6f8fa;
+            padding: 0.2em 0.4em;
+            border-radius: 3px;
+        }
+
+        img {
+            max-width: 100%%
+        }
+
+        blockquote {
+            border-left: 4px solid #dfe2e5;
+            padding: 0 1em;
+            color: #6a737d;
+            margin: 0;
+        }
+
+        table {
+            border-collapse: collapse;
+            width: 100%%
+            margin-bottom: 16px;
+        }
+
+        table th, table td {
+            border: 1px solid #dfe2e5;
+            padding: 6px 13px;
+        }
+
+        table tr {
+            background-color: #fff;
+            border-top: 1px solid #c6cbd1;
+        }
+
+        table tr:nth-child(2n) {
+            background-color: #f6f8fa;
+        }
+
+        .emoji {
+            font-family: 'Noto Color Emoji', 'Apple Color Emoji', 'Segoe UI Emoji', 'NotoEmoji', sans-serif;
+            font-size: 1.2em;
+            line-height: 1;
+            vertical-align: middle;
+        }
+%s
+    </style>
+</head>
+<body>
+    %s
+</body>
+</html>`, title, css, body)
+}
@@ -0,0 +1,76 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/tool/office"
+
+	"go.uber.org/zap"
+)
+
+// pptxSlideParams 幻灯片
+type pptxSlideParams struct {
+	Title    string     `json:"title" jsonschema:"description=标题"`
+	Subtitle string     `json:"subtitle,omitempty" jsonschema:"description=副标题，只有标题和副标题的幻灯片显示为封面"`
+	Bullets  []string   `json:"bullets,omitempty" jsonschema:"description=要点，每个要点前面的两个空格表示一级缩进"`
+	Rows     [][]string `json:"rows,omitempty" jsonschema:"description=表格，第一行为表头"`
+}
+
+// pptxSaveParams 生成PPT文件的参数，slides 和 content 二选一
+type pptxSaveParams struct {
+	Filename string            `json:"filename" jsonschema:"description=文件名(不含扩展名)"`
+	Slides   []pptxSlideParams `json:"slides,omitempty" jsonschema:"description=幻灯片列表"`
+	Content  string            `json:"content,omitempty" jsonschema:"description=Markdown内容，一、二级标题或者分隔线开始新的一页，不提供slides时使用"`
+}
+
+// savePptx 将幻灯片保存为PPT文件
+func savePptx(ctx context.Context, params *pptxSaveParams) (string, error) {
+	var slides []office.Slide
+	for _, s := range params.Slides {
+		slide := office.Slide{Title: s.Title, Subtitle: s.Subtitle, Rows: s.Rows}
+		for _, bullet := range s.Bullets {
+			text := strings.TrimLeft(bullet, " ")
+			slide.Bullets = append(slide.Bullets, office.Bullet{Text: text, Level: (len(bullet) - len(text)) / 2})
+		}
+		slides = append(slides, slide)
+	}
+	if len(slides) == 0 {
+		slides = office.SlidesFromMarkdown(params.Content)
+	}
+	if len(slides) == 0 {
+		return "没有找到幻灯片内容，请提供 slides 或者 Markdown 内容", nil
+	}
+
+	filename, url, err := saveDocument(ctx, pptxSaveToolName, params.Filename, ".pptx", func(w io.Writer) error {
+		return office.WritePPTX(w, params.Filename, slides)
+	})
+	if err != nil {
+		log.Error("保存PPT文件失败", zap.Error(err))
+		return fmt.Sprintf("保存PPT文件失败: %v", err), nil
+	}
+	return fmt.Sprintf("PPT文件已成功保存: ./%s，下载地址: %s", filename, url), nil
+}
+
+// 展示消息构造：将逻辑内聚到工具文件
+type pptxSaveShowBuilder struct{}
+
+func (pptxSaveShowBuilder) BuildRequest(paramsStr string) (string, error) {
+	var params pptxSaveParams
+	if err := json.Unmarshal([]byte(paramsStr), &params); err != nil {
+		log.Error("构建PPT保存请求显示信息失败", zap.Error(err))
+		return "", ErrInvalidJSON
+	}
+	return "保存为 PPT 文件：" + params.Filename, nil
+}
+
+func (pptxSaveShowBuilder) BuildResponse(response string) (string, error) {
+	return response, nil
+}
+
+func init() {
+	RegisterShowMsgBuilder(pptxSaveToolName, pptxSaveShowBuilder{})
+}
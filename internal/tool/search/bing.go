@@ -0,0 +1,52 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"txing-ai/internal/utils/httputils"
+)
+
+// Bing Bing Web Search API
+type Bing struct {
+	endpoint string
+	apiKey   string
+	client   *httputils.HTTPClient
+}
+
+func NewBing(endpoint, apiKey string) *Bing {
+	return &Bing{endpoint: endpoint, apiKey: apiKey, client: newHTTPClient()}
+}
+
+type bingResponse struct {
+	WebPages struct {
+		Value []struct {
+			Name            string `json:"name"`
+			URL             string `json:"url"`
+			Snippet         string `json:"snippet"`
+			DateLastCrawled string `json:"dateLastCrawled"`
+		} `json:"value"`
+	} `json:"webPages"`
+}
+
+func (b *Bing) Name() string {
+	return ProviderBing
+}
+
+func (b *Bing) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	values := url.Values{}
+	values.Add("q", query)
+	values.Add("count", strconv.Itoa(limit))
+	headers := map[string]string{"Ocp-Apim-Subscription-Key": b.apiKey}
+
+	var resp bingResponse
+	if err := b.client.GetJSON(ctx, b.endpoint, values, headers, &resp); err != nil {
+		return nil, fmt.Errorf("bing 搜索失败: %w", err)
+	}
+	results := make([]Result, 0, limit)
+	for _, item := range resp.WebPages.Value {
+		results = appendResult(results, limit, Result{Title: item.Name, URL: item.URL, Snippet: item.Snippet, Date: item.DateLastCrawled})
+	}
+	return results, nil
+}
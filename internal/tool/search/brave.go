@@ -0,0 +1,55 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"txing-ai/internal/utils/httputils"
+)
+
+// Brave Brave Search API
+type Brave struct {
+	endpoint string
+	apiKey   string
+	client   *httputils.HTTPClient
+}
+
+func NewBrave(endpoint, apiKey string) *Brave {
+	return &Brave{endpoint: endpoint, apiKey: apiKey, client: newHTTPClient()}
+}
+
+type braveResponse struct {
+	Web struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+			PageAge     string `json:"page_age"`
+		} `json:"results"`
+	} `json:"web"`
+}
+
+func (b *Brave) Name() string {
+	return ProviderBrave
+}
+
+func (b *Brave) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	values := url.Values{}
+	values.Add("q", query)
+	values.Add("count", strconv.Itoa(limit))
+	headers := map[string]string{
+		"Accept":               "application/json",
+		"X-Subscription-Token": b.apiKey,
+	}
+
+	var resp braveResponse
+	if err := b.client.GetJSON(ctx, b.endpoint, values, headers, &resp); err != nil {
+		return nil, fmt.Errorf("brave 搜索失败: %w", err)
+	}
+	results := make([]Result, 0, limit)
+	for _, item := range resp.Web.Results {
+		results = appendResult(results, limit, Result{Title: item.Title, URL: item.URL, Snippet: item.Description, Date: item.PageAge})
+	}
+	return results, nil
+}
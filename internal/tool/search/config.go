@@ -0,0 +1,77 @@
+package search
+
+import (
+	"strings"
+	"time"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// NewFromConfig 根据配置创建 Searcher，rdb 为空时不缓存搜索结果
+// 没有配置 web_search 时只使用 searchapi，和之前的行为保持一致
+func NewFromConfig(config *global.AppConfig, rdb *redis.Client) *Searcher {
+	var opts []Option
+	names := []string{ProviderSearchAPI}
+	if webSearch := config.WebSearchConfig; webSearch != nil {
+		if len(webSearch.Providers) > 0 {
+			names = webSearch.Providers
+		}
+		opts = append(opts, WithMaxResults(webSearch.MaxResults))
+		if rdb != nil {
+			opts = append(opts, WithCache(NewRedisCache(rdb), webSearch.CacheTTL*time.Second))
+		}
+	}
+
+	for _, name := range names {
+		if opt := providerFromConfig(config, strings.ToLower(strings.TrimSpace(name))); opt != nil {
+			opts = append(opts, opt)
+		}
+	}
+	return NewSearcher(opts...)
+}
+
+func providerFromConfig(config *global.AppConfig, name string) Option {
+	var provider *global.SearchProviderConfig
+	switch name {
+	case ProviderSearchAPI:
+		if c := config.SearchAPIConfig; c != nil {
+			return WithProvider(NewSearchAPI(c.Endpoint, c.ApiKey, c.Engine), c.RateLimit)
+		}
+	case ProviderSearXNG:
+		if provider = webSearchProvider(config, name); provider != nil {
+			return WithProvider(NewSearXNG(provider.Endpoint), provider.RateLimit)
+		}
+	case ProviderBing:
+		if provider = webSearchProvider(config, name); provider != nil {
+			return WithProvider(NewBing(provider.Endpoint, provider.ApiKey), provider.RateLimit)
+		}
+	case ProviderBrave:
+		if provider = webSearchProvider(config, name); provider != nil {
+			return WithProvider(NewBrave(provider.Endpoint, provider.ApiKey), provider.RateLimit)
+		}
+	default:
+		log.Warn("unknown web search provider", zap.String("provider", name))
+		return nil
+	}
+	log.Warn("web search provider not configured", zap.String("provider", name))
+	return nil
+}
+
+func webSearchProvider(config *global.AppConfig, name string) *global.SearchProviderConfig {
+	webSearch := config.WebSearchConfig
+	if webSearch == nil {
+		return nil
+	}
+	switch name {
+	case ProviderSearXNG:
+		return webSearch.SearXNG
+	case ProviderBing:
+		return webSearch.Bing
+	case ProviderBrave:
+		return webSearch.Brave
+	}
+	return nil
+}
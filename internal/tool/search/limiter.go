@@ -0,0 +1,74 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter 令牌桶限流器，容量为每分钟的请求次数，令牌匀速补充
+type rateLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	// 补充一个令牌需要的时间
+	interval time.Duration
+	last     time.Time
+}
+
+// newRateLimiter perMinute 小于等于 0 时不限流，返回空
+func newRateLimiter(perMinute int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		interval: time.Minute / time.Duration(perMinute),
+		last:     time.Now(),
+	}
+}
+
+// reserve 尝试获取一个令牌，失败时返回需要等待的时间
+func (l *rateLimiter) reserve() (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+	if l.tokens > l.capacity {
+		l.tokens = l.capacity
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - l.tokens) * float64(l.interval))
+}
+
+// Allow 立即获取一个令牌，没有可用令牌时返回 false
+func (l *rateLimiter) Allow() bool {
+	ok, _ := l.reserve()
+	return ok
+}
+
+// Wait 等待直到获取一个令牌或者 ctx 结束
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	for {
+		ok, wait := l.reserve()
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
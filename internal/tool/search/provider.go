@@ -0,0 +1,54 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"txing-ai/internal/utils/httputils"
+)
+
+const (
+	// 搜索服务名称
+	ProviderSearchAPI = "searchapi"
+	ProviderSearXNG   = "searxng"
+	ProviderBing      = "bing"
+	ProviderBrave     = "brave"
+
+	// 搜索请求超时时间
+	requestTimeout = 10 * time.Second
+)
+
+// ErrRateLimited 搜索服务请求过于频繁
+var ErrRateLimited = errors.New("搜索服务请求过于频繁")
+
+// Result 统一格式的搜索结果
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+	// 发布时间，格式由搜索服务决定，可能为空
+	Date string `json:"date,omitempty"`
+}
+
+// Provider 网页搜索服务
+type Provider interface {
+	// Name 搜索服务名称
+	Name() string
+	// Search 搜索关键词，最多返回 limit 条结果
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+}
+
+func newHTTPClient() *httputils.HTTPClient {
+	return httputils.NewHTTPClient(requestTimeout)
+}
+
+// appendResult 添加一条搜索结果，忽略没有链接的结果
+func appendResult(results []Result, limit int, result Result) []Result {
+	if len(results) >= limit || result.URL == "" {
+		return results
+	}
+	result.Title = strings.TrimSpace(result.Title)
+	result.Snippet = strings.TrimSpace(result.Snippet)
+	return append(results, result)
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"txing-ai/internal/utils/httputils"
+)
+
+// SearchAPI https://www.searchapi.io 搜索服务，支持百度、谷歌等多种搜索引擎
+type SearchAPI struct {
+	endpoint string
+	apiKey   string
+	engine   string
+	client   *httputils.HTTPClient
+}
+
+func NewSearchAPI(endpoint, apiKey, engine string) *SearchAPI {
+	return &SearchAPI{endpoint: endpoint, apiKey: apiKey, engine: engine, client: newHTTPClient()}
+}
+
+type searchAPIResponse struct {
+	OrganicResults []struct {
+		Title   string `json:"title"`
+		Link    string `json:"link"`
+		Snippet string `json:"snippet"`
+		Date    string `json:"date"`
+	} `json:"organic_results"`
+}
+
+func (s *SearchAPI) Name() string {
+	return ProviderSearchAPI
+}
+
+func (s *SearchAPI) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	values := url.Values{}
+	values.Add("q", query)
+	values.Add("engine", s.engine)
+	values.Add("api_key", s.apiKey)
+
+	var resp searchAPIResponse
+	if err := s.client.GetJSON(ctx, s.endpoint, values, nil, &resp); err != nil {
+		return nil, fmt.Errorf("searchapi 搜索失败: %w", err)
+	}
+	results := make([]Result, 0, limit)
+	for _, item := range resp.OrganicResults {
+		results = appendResult(results, limit, Result{Title: item.Title, URL: item.Link, Snippet: item.Snippet, Date: item.Date})
+	}
+	return results, nil
+}
@@ -0,0 +1,207 @@
+package search
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"txing-ai/internal/global/logging/log"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// Redis key 前缀
+	cacheKeyPrefix = "web_search:"
+	// 默认返回的搜索结果数量
+	defaultMaxResults = 5
+)
+
+// Cache 搜索结果缓存
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// RedisCache 使用 Redis 缓存搜索结果，读写失败时只记录日志
+type RedisCache struct {
+	rdb *redis.Client
+}
+
+func NewRedisCache(rdb *redis.Client) *RedisCache {
+	return &RedisCache{rdb: rdb}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := c.rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Warn("read search cache failed", zap.String("key", key), zap.Error(err))
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if err := c.rdb.Set(ctx, key, value, ttl).Err(); err != nil {
+		log.Warn("write search cache failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// limitedProvider 带限流器的搜索服务
+type limitedProvider struct {
+	Provider
+	limiter *rateLimiter
+}
+
+// Searcher 按顺序尝试多个搜索服务，前一个失败或者限流时使用下一个，结果按关键词缓存
+type Searcher struct {
+	providers  []limitedProvider
+	cache      Cache
+	cacheTTL   time.Duration
+	maxResults int
+}
+
+// Option Searcher 的可选配置
+type Option func(*Searcher)
+
+// WithProvider 添加搜索服务，rateLimit 为每分钟最多请求次数，0 表示不限制
+func WithProvider(provider Provider, rateLimit int) Option {
+	return func(s *Searcher) {
+		s.providers = append(s.providers, limitedProvider{Provider: provider, limiter: newRateLimiter(rateLimit)})
+	}
+}
+
+// WithCache 设置搜索结果缓存，ttl 小于等于 0 时不缓存
+func WithCache(cache Cache, ttl time.Duration) Option {
+	return func(s *Searcher) {
+		s.cache = cache
+		s.cacheTTL = ttl
+	}
+}
+
+// WithMaxResults 设置返回的搜索结果数量
+func WithMaxResults(maxResults int) Option {
+	return func(s *Searcher) {
+		if maxResults > 0 {
+			s.maxResults = maxResults
+		}
+	}
+}
+
+func NewSearcher(opts ...Option) *Searcher {
+	s := &Searcher{maxResults: defaultMaxResults}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Providers 已启用的搜索服务名称
+func (s *Searcher) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for _, p := range s.providers {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+// Search 搜索关键词，优先返回缓存的结果
+func (s *Searcher) Search(ctx context.Context, query string) ([]Result, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("搜索关键词不能为空")
+	}
+	if len(s.providers) == 0 {
+		return nil, fmt.Errorf("没有可用的搜索服务")
+	}
+
+	key := s.cacheKey(query)
+	if results, ok := s.getCache(ctx, key); ok {
+		log.Debug("web search cache hit", zap.String("query", query))
+		return results, nil
+	}
+
+	var errs []error
+	var limited []limitedProvider
+	for _, p := range s.providers {
+		if !p.limiter.Allow() {
+			limited = append(limited, p)
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), ErrRateLimited))
+			continue
+		}
+		results, err := s.search(ctx, p, query, key)
+		if err == nil {
+			return results, nil
+		}
+		errs = append(errs, err)
+	}
+
+	// 所有搜索服务都被限流时，等待第一个搜索服务的令牌，不直接返回错误
+	if len(limited) == len(s.providers) {
+		p := limited[0]
+		if err := p.limiter.Wait(ctx); err != nil {
+			return nil, errors.Join(append(errs, err)...)
+		}
+		results, err := s.search(ctx, p, query, key)
+		if err == nil {
+			return results, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// search 调用单个搜索服务，成功时缓存结果
+func (s *Searcher) search(ctx context.Context, p limitedProvider, query, key string) ([]Result, error) {
+	results, err := p.Search(ctx, query, s.maxResults)
+	if err != nil {
+		log.Warn("web search failed", zap.String("provider", p.Name()), zap.String("query", query), zap.Error(err))
+		return nil, err
+	}
+	log.Debug("web search done", zap.String("provider", p.Name()), zap.Int("resultCount", len(results)))
+	// 空结果可能是搜索服务临时异常，不缓存
+	if len(results) > 0 {
+		s.setCache(ctx, key, results)
+	}
+	return results, nil
+}
+
+// cacheKey 关键词忽略大小写和首尾空白，使用哈希避免 key 过长
+func (s *Searcher) cacheKey(query string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(query)))
+	return cacheKeyPrefix + strconv.Itoa(s.maxResults) + ":" + hex.EncodeToString(sum[:])
+}
+
+func (s *Searcher) getCache(ctx context.Context, key string) ([]Result, bool) {
+	if s.cache == nil || s.cacheTTL <= 0 {
+		return nil, false
+	}
+	value, ok := s.cache.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	var results []Result
+	if err := json.Unmarshal(value, &results); err != nil {
+		return nil, false
+	}
+	return results, true
+}
+
+func (s *Searcher) setCache(ctx context.Context, key string, results []Result) {
+	if s.cache == nil || s.cacheTTL <= 0 {
+		return
+	}
+	value, err := json.Marshal(results)
+	if err != nil {
+		return
+	}
+	s.cache.Set(ctx, key, value, s.cacheTTL)
+}
@@ -0,0 +1,204 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"txing-ai/internal/global/logging"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// newTestServer 模拟搜索服务，返回固定的响应并记录请求次数
+func newTestServer(t *testing.T, status int, body string, check func(r *http.Request)) (*httptest.Server, *int32) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		if check != nil {
+			check(r)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &count
+}
+
+func TestProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		provider func(endpoint string) Provider
+		check    func(t *testing.T, r *http.Request)
+	}{
+		{
+			name: ProviderSearchAPI,
+			body: `{"organic_results":[{"title":"标题","link":"https://a.com","snippet":"摘要","date":"Sep 28, 2025"},{"title":"没有链接"}]}`,
+			provider: func(endpoint string) Provider {
+				return NewSearchAPI(endpoint, "key", "baidu")
+			},
+			check: func(t *testing.T, r *http.Request) {
+				if r.URL.Query().Get("api_key") != "key" || r.URL.Query().Get("engine") != "baidu" {
+					t.Errorf("unexpected query: %s", r.URL.RawQuery)
+				}
+			},
+		},
+		{
+			name: ProviderSearXNG,
+			body: `{"results":[{"title":"标题","url":"https://a.com","content":"摘要","publishedDate":"Sep 28, 2025"}]}`,
+			provider: func(endpoint string) Provider {
+				return NewSearXNG(endpoint + "/")
+			},
+			check: func(t *testing.T, r *http.Request) {
+				if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" {
+					t.Errorf("unexpected request: %s", r.URL)
+				}
+			},
+		},
+		{
+			name: ProviderBing,
+			body: `{"webPages":{"value":[{"name":"标题","url":"https://a.com","snippet":"摘要","dateLastCrawled":"Sep 28, 2025"}]}}`,
+			provider: func(endpoint string) Provider {
+				return NewBing(endpoint, "key")
+			},
+			check: func(t *testing.T, r *http.Request) {
+				if r.Header.Get("Ocp-Apim-Subscription-Key") != "key" {
+					t.Errorf("missing api key header")
+				}
+			},
+		},
+		{
+			name: ProviderBrave,
+			body: `{"web":{"results":[{"title":"标题","url":"https://a.com","description":"摘要","page_age":"Sep 28, 2025"}]}}`,
+			provider: func(endpoint string) Provider {
+				return NewBrave(endpoint, "key")
+			},
+			check: func(t *testing.T, r *http.Request) {
+				if r.Header.Get("X-Subscription-Token") != "key" {
+					t.Errorf("missing api key header")
+				}
+			},
+		},
+	}
+	want := Result{Title: "标题", URL: "https://a.com", Snippet: "摘要", Date: "Sep 28, 2025"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, http.StatusOK, tt.body, func(r *http.Request) {
+				if r.URL.Query().Get("q") != "golang" {
+					t.Errorf("unexpected query: %s", r.URL.RawQuery)
+				}
+				tt.check(t, r)
+			})
+			provider := tt.provider(server.URL)
+			if provider.Name() != tt.name {
+				t.Errorf("Name() = %s, want %s", provider.Name(), tt.name)
+			}
+			results, err := provider.Search(context.Background(), "golang", 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || results[0] != want {
+				t.Errorf("Search() = %+v, want [%+v]", results, want)
+			}
+		})
+	}
+}
+
+// memoryCache 测试用的内存缓存
+type memoryCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.data[key]
+	return value, ok
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, value []byte, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+}
+
+func TestSearcher_Failover(t *testing.T) {
+	failing, failingCount := newTestServer(t, http.StatusInternalServerError, "", nil)
+	working, workingCount := newTestServer(t, http.StatusOK, `{"results":[{"title":"标题","url":"https://a.com"}]}`, nil)
+
+	cache := &memoryCache{data: map[string][]byte{}}
+	searcher := NewSearcher(
+		WithProvider(NewBing(failing.URL, "key"), 0),
+		WithProvider(NewSearXNG(working.URL), 0),
+		WithCache(cache, time.Minute),
+	)
+
+	tests := []struct {
+		name             string
+		query            string
+		wantFailingCount int32
+		wantWorkingCount int32
+	}{
+		{name: "第一个搜索服务失败时使用下一个", query: "golang", wantFailingCount: 1, wantWorkingCount: 1},
+		{name: "相同关键词使用缓存", query: " GoLang ", wantFailingCount: 1, wantWorkingCount: 1},
+		{name: "不同关键词重新搜索", query: "rust", wantFailingCount: 2, wantWorkingCount: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := searcher.Search(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || results[0].URL != "https://a.com" {
+				t.Errorf("Search() = %+v", results)
+			}
+			if got := atomic.LoadInt32(failingCount); got != tt.wantFailingCount {
+				t.Errorf("failing provider called %d times, want %d", got, tt.wantFailingCount)
+			}
+			if got := atomic.LoadInt32(workingCount); got != tt.wantWorkingCount {
+				t.Errorf("working provider called %d times, want %d", got, tt.wantWorkingCount)
+			}
+		})
+	}
+
+	if _, err := NewSearcher(WithProvider(NewBing(failing.URL, "key"), 0)).Search(context.Background(), "golang"); err == nil {
+		t.Errorf("Search() should fail when all providers fail")
+	}
+}
+
+func TestSearcher_RateLimit(t *testing.T) {
+	limited, limitedCount := newTestServer(t, http.StatusOK, `{"results":[{"title":"a","url":"https://a.com"}]}`, nil)
+	other, otherCount := newTestServer(t, http.StatusOK, `{"results":[{"title":"b","url":"https://b.com"}]}`, nil)
+
+	searcher := NewSearcher(
+		WithProvider(NewSearXNG(limited.URL), 1),
+		WithProvider(NewSearXNG(other.URL), 1),
+	)
+	for _, query := range []string{"a", "b"} {
+		if _, err := searcher.Search(context.Background(), query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if atomic.LoadInt32(limitedCount) != 1 || atomic.LoadInt32(otherCount) != 1 {
+		t.Errorf("provider calls = %d, %d, want 1, 1", atomic.LoadInt32(limitedCount), atomic.LoadInt32(otherCount))
+	}
+
+	// 所有搜索服务都被限流时等待令牌
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := searcher.Search(ctx, "c"); !errors.Is(err, ErrRateLimited) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Search() error = %v, want rate limited", err)
+	}
+}
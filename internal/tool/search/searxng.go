@@ -0,0 +1,49 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"txing-ai/internal/utils/httputils"
+)
+
+// SearXNG 自建的 SearXNG 元搜索服务，需要在 settings.yml 中开启 json 格式
+type SearXNG struct {
+	endpoint string
+	client   *httputils.HTTPClient
+}
+
+// NewSearXNG endpoint 为 SearXNG 的地址，例如 http://localhost:8888
+func NewSearXNG(endpoint string) *SearXNG {
+	return &SearXNG{endpoint: strings.TrimSuffix(endpoint, "/"), client: newHTTPClient()}
+}
+
+type searXNGResponse struct {
+	Results []struct {
+		Title         string `json:"title"`
+		URL           string `json:"url"`
+		Content       string `json:"content"`
+		PublishedDate string `json:"publishedDate"`
+	} `json:"results"`
+}
+
+func (s *SearXNG) Name() string {
+	return ProviderSearXNG
+}
+
+func (s *SearXNG) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	values := url.Values{}
+	values.Add("q", query)
+	values.Add("format", "json")
+
+	var resp searXNGResponse
+	if err := s.client.GetJSON(ctx, s.endpoint+"/search", values, nil, &resp); err != nil {
+		return nil, fmt.Errorf("searxng 搜索失败: %w", err)
+	}
+	results := make([]Result, 0, limit)
+	for _, item := range resp.Results {
+		results = appendResult(results, limit, Result{Title: item.Title, URL: item.URL, Snippet: item.Content, Date: item.PublishedDate})
+	}
+	return results, nil
+}
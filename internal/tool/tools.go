@@ -11,6 +11,10 @@ const (
 	// 工具名称
 	webSearchToolName            = "web_search_tool"
 	markdownToPDFToolName        = "markdown_to_pdf_file_tool"
+	markdownToDocxToolName       = "markdown_to_docx_file_tool"
+	xlsxSaveToolName             = "xlsx_file_tool"
+	pptxSaveToolName             = "pptx_file_tool"
+	documentReadToolName         = "document_read_tool"
 	mapsGeoToolName              = "maps_geo"
 	mapsTextSearchToolName       = "maps_text_search"
 	mapsDirectionToolName        = "maps_direction_transit_integrated"
@@ -27,17 +31,30 @@ const (
 
 var (
 	toolRegisterOnce sync.Once
-	tools            []tool.BaseTool
+	// 内置工具，只注册一次
+	builtinTools []tool.BaseTool
 )
 
-// ProvideTools 注册工具
+// ProvideTools 获取当前可用的工具，包括内置工具和 MCP 工具
+// MCP 服务器可能重连或者修改配置，每次调用都返回 MCP 工具的最新列表
 func ProvideTools(res iface.ResourceProvider) []tool.BaseTool {
+	tools := BuiltinTools()
+	// 添加MCP工具（如果已初始化）
+	if mcpClientManager := res.GetMCPClientManager(); mcpClientManager != nil {
+		tools = append(tools, mcpClientManager.GetAllMCPTools()...)
+	}
+	return tools
+}
+
+// BuiltinTools 获取内置工具，不包括 MCP 工具
+func BuiltinTools() []tool.BaseTool {
 	toolRegisterOnce.Do(func() {
+		var tools []tool.BaseTool
 		// 注册网页搜索工具
 		searchWebTool, err := utils.InferTool(
 			webSearchToolName,
-			// 说明有速率限制，需要注意不要频繁调用
-			"Search for information from Baidu Search Engine, has rate limit, please be careful",
+			// 搜索结果会缓存，搜索服务有速率限制，相同的关键词不需要重复搜索
+			"Search the web and return the title, url, snippet and date of the top results. Results are cached and the search providers are rate limited, so do not repeat the same query",
 			searchWeb)
 		if err != nil {
 			panic(err)
@@ -77,84 +94,111 @@ func ProvideTools(res iface.ResourceProvider) []tool.BaseTool {
 		// 注册网页抓取工具
 		webScrapingTool, err := utils.InferTool(
 			"web_scraping_tool",
-			"Scrape the content of a web page",
+			"Read the main content of a web page as markdown. Long pages are returned in chunks, use next_offset to read the rest",
 			scrapeWebPage)
 		if err != nil {
 			panic(err)
 		}
 		tools = append(tools, webScrapingTool)
 
-		// TODO 文件操作相关工具还需要完善，暂时注释掉，避免 LLM 误用
-		//// 注册文件读取工具
-		//fileReadTool, err := utils.InferTool(
-		//	"file_read_tool",
-		//	"Read content from a file using relative path (only files in runtime directory are allowed, e.g. runtime/test.txt, runtime/folder/file.txt)",
-		//	readFile)
-		//if err != nil {
-		//	panic(err)
-		//}
-		//tools = append(tools, fileReadTool)
-		//
-		//// 注册文件写入工具
-		//fileWriteTool, err := utils.InferTool(
-		//	"file_write_tool",
-		//	"Write content to a file using relative path (only files in runtime directory are allowed, e.g. runtime/test.txt, runtime/folder/file.txt)",
-		//	writeFile)
-		//if err != nil {
-		//	panic(err)
-		//}
-		//tools = append(tools, fileWriteTool)
-		//
-		//// 注册文件内容替换工具
-		//fileReplaceTool, err := utils.InferTool(
-		//	"file_replace_tool",
-		//	"Replace content in a file using relative path (only files in runtime directory are allowed, e.g. runtime/test.txt, runtime/folder/file.txt)",
-		//	replaceFileContent)
-		//if err != nil {
-		//	panic(err)
-		//}
-		//tools = append(tools, fileReplaceTool)
-		//
-		//// 注册文件删除工具
-		//fileDeleteTool, err := utils.InferTool(
-		//	"file_delete_tool",
-		//	"Delete a file using relative path (only files in runtime directory are allowed, e.g. runtime/test.txt, runtime/folder/file.txt)",
-		//	deleteFile)
-		//if err != nil {
-		//	panic(err)
-		//}
-		//tools = append(tools, fileDeleteTool)
-		//
-		//// 注册文件列表工具
-		//fileListTool, err := utils.InferTool(
-		//	"file_list_tool",
-		//	"List files in a directory using relative path (only directories in runtime directory are allowed, e.g. runtime, runtime/folder)",
-		//	listFiles)
-		//if err != nil {
-		//	panic(err)
-		//}
-		//tools = append(tools, fileListTool)
+		// 注册文件操作工具，只能操作本次运行的工作区，运行结束后工作区会被删除
+		fileReadTool, err := utils.InferTool(
+			"file_read_tool",
+			"Read content from a file in the workspace of current run (path is relative to the workspace, e.g. notes/draft.md)",
+			readFile)
+		if err != nil {
+			panic(err)
+		}
+		tools = append(tools, fileReadTool)
+
+		fileWriteTool, err := utils.InferTool(
+			"file_write_tool",
+			"Write content to a file in the workspace of current run, parent directories are created automatically. "+
+				"The workspace is deleted after the run, use it for drafts and intermediate results",
+			writeFile)
+		if err != nil {
+			panic(err)
+		}
+		tools = append(tools, fileWriteTool)
+
+		fileReplaceTool, err := utils.InferTool(
+			"file_replace_tool",
+			"Replace all occurrences of old_text with new_text in a file in the workspace of current run",
+			replaceFileContent)
+		if err != nil {
+			panic(err)
+		}
+		tools = append(tools, fileReplaceTool)
+
+		fileDeleteTool, err := utils.InferTool(
+			"file_delete_tool",
+			"Delete a file or an empty directory in the workspace of current run",
+			deleteFile)
+		if err != nil {
+			panic(err)
+		}
+		tools = append(tools, fileDeleteTool)
+
+		fileListTool, err := utils.InferTool(
+			"file_list_tool",
+			"List files in a directory of the workspace of current run, empty dir_path means the workspace root",
+			listFiles)
+		if err != nil {
+			panic(err)
+		}
+		tools = append(tools, fileListTool)
 
 		// 注册Markdown转PDF工具
 		markdownToPDFTool, err := utils.InferTool(
 			"markdown_to_pdf_file_tool",
-			"Convert markdown content to PDF, and save it to local file. Note: use \\n\\n for line breaks; single \\n will not render as a line break in the PDF."+
-				"fileName will be automatically appended with timestamp",
+			"Convert markdown content to PDF, and save it to local file. Supports tables, code blocks and images (http url or file in the same directory). "+
+				"Use template to choose the layout: default, resume or travel. fileName will be automatically appended with timestamp",
 			saveMarkdownToPDF)
 		if err != nil {
 			panic(err)
 		}
 		tools = append(tools, markdownToPDFTool)
 
-		//注册PDF文本提取工具
-		pdfReadTool, err := utils.InferTool(
-			"pdf_read_tool",
-			"Extract text content from a PDF file (only files in runtime directory are allowed, e.g. runtime/test.pdf, runtime/folder/file.pdf)",
-			ReadPdfText)
+		// 注册Office文档生成工具，生成可编辑的Word、Excel和PPT文件
+		markdownToDocxTool, err := utils.InferTool(
+			markdownToDocxToolName,
+			"Convert markdown content to an editable Word (DOCX) document, and save it to local file. "+
+				"Supports headings, lists, tables, code blocks and links. fileName will be automatically appended with timestamp",
+			saveMarkdownToDocx)
+		if err != nil {
+			panic(err)
+		}
+		tools = append(tools, markdownToDocxTool)
+
+		xlsxSaveTool, err := utils.InferTool(
+			xlsxSaveToolName,
+			"Save tables to an Excel (XLSX) file, e.g. budgets and schedules. Provide sheets, or markdown content whose tables become sheets. "+
+				"Numbers are saved as numeric cells and cells starting with = are formulas. fileName will be automatically appended with timestamp",
+			saveXlsx)
 		if err != nil {
 			panic(err)
 		}
-		tools = append(tools, pdfReadTool)
+		tools = append(tools, xlsxSaveTool)
+
+		pptxSaveTool, err := utils.InferTool(
+			pptxSaveToolName,
+			"Save a simple PowerPoint (PPTX) presentation with titles, bullet points and tables. Provide slides, or markdown content "+
+				"where each level 1/2 heading starts a new slide. fileName will be automatically appended with timestamp",
+			savePptx)
+		if err != nil {
+			panic(err)
+		}
+		tools = append(tools, pptxSaveTool)
+
+		// 注册文档文本提取工具
+		documentReadTool, err := utils.InferTool(
+			documentReadToolName,
+			"Extract text content from a document uploaded by user. Supports PDF, Word (docx), Markdown, HTML and plain text files",
+			ReadDocumentText)
+		if err != nil {
+			panic(err)
+		}
+		tools = append(tools, documentReadTool)
 
 		// 注册PDF验证工具
 		//pdfValidateTool, err := utils.InferTool(
@@ -166,14 +210,11 @@ func ProvideTools(res iface.ResourceProvider) []tool.BaseTool {
 		//}
 		//tools = append(tools, pdfValidateTool)
 
-		// 添加MCP工具（如果已初始化）
-		mcpClientManager := res.GetMCPClientManager()
-		mcpTools := mcpClientManager.GetAllMCPTools()
-		if len(mcpTools) > 0 {
-			tools = append(tools, mcpTools...)
-		}
+		builtinTools = tools
 	})
-	return tools
+
+	tools := make([]tool.BaseTool, 0, len(builtinTools))
+	return append(tools, builtinTools...)
 }
 
 // 构建指定工具的调用请求信息（用于前端展示）
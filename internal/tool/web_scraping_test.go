@@ -2,42 +2,63 @@ package tool
 
 import (
 	"context"
-	"reflect"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_scrapeWebPage(t *testing.T) {
-	type args struct {
-		ctx context.Context
-		req *webScrapingRequest
-	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /admin\n"))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<html><head><title>百度百科</title></head><body><nav>导航</nav><div class="content"><h2>简介</h2>` +
+				strings.Repeat("<p>百度百科，你就在这里，这是一段足够长的正文内容。</p>", 10) + `</div></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	// 测试网站运行在本机，使用不检查地址的客户端
+	getWebPageReader().SetHTTPClient(&http.Client{Timeout: 10 * time.Second})
+
 	tests := []struct {
-		name    string
-		args    args
-		want    webScrapingResponse
-		wantErr bool
+		name        string
+		req         *webScrapingRequest
+		wantContent string
+		wantTitle   string
+		wantError   bool
+		wantNext    bool
 	}{
-		{
-			name: "成功抓取网页",
-			args: args{
-				ctx: context.Background(),
-				req: &webScrapingRequest{URL: "https://baijiahao.baidu.com/s?id=1843853053263796289&wfr=spider&for=pc"}},
-			want: webScrapingResponse{
-				Title:   "百度百科 - 百度百科，你就在这里",
-				Content: "百度百科，你就在这里",
-			},
-			wantErr: false,
-		},
+		{name: "成功抓取网页", req: &webScrapingRequest{URL: server.URL + "/page"}, wantContent: "## 简介", wantTitle: "百度百科"},
+		{name: "分段读取", req: &webScrapingRequest{URL: server.URL + "/page", MaxTextLength: 100}, wantContent: "## 简介", wantTitle: "百度百科", wantNext: true},
+		{name: "robots.txt 禁止抓取", req: &webScrapingRequest{URL: server.URL + "/admin"}, wantError: true},
+		{name: "网页不存在", req: &webScrapingRequest{URL: server.URL + "/not_found"}, wantError: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := scrapeWebPage(tt.args.ctx, tt.args.req)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("scrapeWebPage() error = %v, wantErr %v", err, tt.wantErr)
+			got, err := scrapeWebPage(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("scrapeWebPage() error = %v", err)
+			}
+			if (got.Error != "") != tt.wantError {
+				t.Fatalf("scrapeWebPage() Error = %q, wantError %v", got.Error, tt.wantError)
+			}
+			if tt.wantError {
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("scrapeWebPage() got = %v, want %v", got, tt.want)
+			if !strings.Contains(got.Content, tt.wantContent) || strings.Contains(got.Content, "导航") {
+				t.Errorf("scrapeWebPage() content = %q", got.Content)
+			}
+			if got.Title != tt.wantTitle {
+				t.Errorf("scrapeWebPage() title = %q, want %q", got.Title, tt.wantTitle)
+			}
+			if (got.NextOffset > 0) != tt.wantNext || got.Truncated != tt.wantNext {
+				t.Errorf("scrapeWebPage() next_offset = %d, truncated = %v, want %v", got.NextOffset, got.Truncated, tt.wantNext)
 			}
 		})
 	}
@@ -4,13 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
-	"txing-ai/internal/utils/httputils"
+	"txing-ai/internal/tool/search"
 
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
@@ -19,57 +20,43 @@ type webSearchParams struct {
 	Query string `json:"query" jsonschema:"description=搜索关键词"`
 }
 
-// 搜索结果结构体
-type SearchResponse struct {
-	OrganicResults []map[string]interface{} `json:"organic_results"`
-}
-
-func searchWeb(ctx context.Context, params *webSearchParams) (string, error) {
-	config := global.LoadConfig()
+var (
+	webSearcher     *search.Searcher
+	webSearcherOnce sync.Once
+)
 
-	// 创建URL参数
-	values := url.Values{}
-	values.Add("q", params.Query)
-	values.Add("engine", config.SearchAPIConfig.Engine)
-	values.Add("api_key", config.SearchAPIConfig.ApiKey) // 需要替换为实际的API密钥
+// InitWebSearch 根据配置初始化网页搜索服务，rdb 用于缓存搜索结果，为空时不缓存
+func InitWebSearch(rdb *redis.Client) {
+	webSearcherOnce.Do(func() {
+		webSearcher = search.NewFromConfig(global.LoadConfig(), rdb)
+		log.Info("web search providers", zap.Strings("providers", webSearcher.Providers()))
+	})
+}
 
-	// 使用通用HTTP客户端
-	httpClient := httputils.DefaultHTTPClient()
+func getWebSearcher() *search.Searcher {
+	InitWebSearch(nil)
+	return webSearcher
+}
 
-	// 发送请求并解析JSON响应
-	var searchResponse SearchResponse
-	err := httpClient.GetJSON(ctx, config.SearchAPIConfig.Endpoint, values, nil, &searchResponse)
+func searchWeb(ctx context.Context, params *webSearchParams) (string, error) {
+	results, err := getWebSearcher().Search(ctx, params.Query)
 	if err != nil {
 		log.Error("Web搜索请求失败", zap.Error(err))
 		return fmt.Sprintf("Web搜索请求失败: %v", err), nil
 	}
 
-	// 提取前5条结果
-	resultCount := len(searchResponse.OrganicResults)
-	if resultCount > 5 {
-		resultCount = 5
-	}
-
 	// 如果没有结果，返回空字符串
-	if resultCount == 0 {
+	if len(results) == 0 {
 		log.Debug("Web搜索无结果", zap.String("query", params.Query))
 		return "搜索结果为空", nil
 	}
 
-	// 将结果转换为JSON字符串并拼接
-	var resultStrings []string
-	for i := 0; i < resultCount; i++ {
-		resultJSON, err := json.Marshal(searchResponse.OrganicResults[i])
-		if err != nil {
-			log.Warn("结果序列化失败", zap.Error(err))
-			continue
-		}
-		resultStrings = append(resultStrings, string(resultJSON))
+	resultJSON, err := json.Marshal(results)
+	if err != nil {
+		log.Error("结果序列化失败", zap.Error(err))
+		return "", err
 	}
-
-	// 拼接结果
-	log.Debug("Web搜索完成", zap.Int("resultCount", resultCount))
-	return strings.Join(resultStrings, ","), nil
+	return string(resultJSON), nil
 }
 
 // 展示消息构造：将逻辑内聚到工具文件
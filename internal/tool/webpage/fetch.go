@@ -0,0 +1,190 @@
+package webpage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"txing-ai/internal/utils/httputils"
+	"unicode/utf8"
+
+	"github.com/temoto/robotstxt"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	// 检查 robots.txt 时使用的爬虫名称
+	robotsAgent = "TxingAI"
+	// robots.txt 的缓存时间
+	robotsTTL = time.Hour
+	// 网页的最大读取大小
+	maxPageSize = 5 << 20
+	// 网页请求超时时间
+	fetchTimeout = 15 * time.Second
+)
+
+// ErrDisallowedByRobots 网站的 robots.txt 不允许抓取该网页
+var ErrDisallowedByRobots = errors.New("网站的 robots.txt 不允许抓取该网页")
+
+// 常用浏览器User-Agent列表，用于随机切换
+var userAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:120.0) Gecko/20100101 Firefox/120.0",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Edge/120.0.0.0",
+}
+
+// page 抓取到的网页
+type page struct {
+	// 重定向后的最终地址
+	URL         *url.URL
+	ContentType string
+	// 转换为 UTF-8 的网页内容
+	Body []byte
+}
+
+type robotsEntry struct {
+	group   *robotstxt.Group
+	expires time.Time
+}
+
+// fetcher 抓取网页，遵守 robots.txt 并将网页内容转换为 UTF-8
+type fetcher struct {
+	client *http.Client
+
+	mu     sync.Mutex
+	robots map[string]robotsEntry
+}
+
+// newFetcher 网页地址来自模型或者用户，只允许访问公网地址，重定向后的地址同样检查
+func newFetcher() *fetcher {
+	return &fetcher{
+		client: httputils.NewSafeClient(fetchTimeout),
+		robots: make(map[string]robotsEntry),
+	}
+}
+
+func (f *fetcher) fetch(ctx context.Context, pageURL *url.URL) (*page, error) {
+	if pageURL.Scheme != "http" && pageURL.Scheme != "https" {
+		return nil, fmt.Errorf("只支持 http 和 https 网页: %s", pageURL)
+	}
+	if !f.allowed(ctx, pageURL) {
+		return nil, ErrDisallowedByRobots
+	}
+
+	resp, err := f.get(ctx, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("访问网页失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("抓取网页失败, 状态码: %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" && mediaType != "text/plain" {
+		return nil, fmt.Errorf("不支持的网页类型: %s", mediaType)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, fmt.Errorf("读取网页内容失败: %w", err)
+	}
+	body, err = decodeBody(body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return &page{URL: resp.Request.URL, ContentType: mediaType, Body: body}, nil
+}
+
+func (f *fetcher) get(ctx context.Context, u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// 随机设置User-Agent
+	req.Header.Set("User-Agent", userAgents[time.Now().UnixNano()%int64(len(userAgents))])
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	return f.client.Do(req)
+}
+
+// allowed 检查 robots.txt 是否允许抓取，robots.txt 无法访问时允许抓取
+func (f *fetcher) allowed(ctx context.Context, u *url.URL) bool {
+	key := u.Scheme + "://" + u.Host
+	f.mu.Lock()
+	entry, ok := f.robots[key]
+	f.mu.Unlock()
+	if !ok || time.Now().After(entry.expires) {
+		entry = robotsEntry{group: f.fetchRobots(ctx, key), expires: time.Now().Add(robotsTTL)}
+		f.mu.Lock()
+		f.robots[key] = entry
+		f.mu.Unlock()
+	}
+	if entry.group == nil {
+		return true
+	}
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return entry.group.Test(path)
+}
+
+func (f *fetcher) fetchRobots(ctx context.Context, site string) *robotstxt.Group {
+	robotsURL, err := url.Parse(site + "/robots.txt")
+	if err != nil {
+		return nil
+	}
+	resp, err := f.get(ctx, robotsURL)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 512<<10))
+	if err != nil {
+		return nil
+	}
+	// 4xx 允许全部抓取，5xx 不允许抓取
+	robots, err := robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+	if err != nil {
+		return nil
+	}
+	return robots.FindGroup(robotsAgent)
+}
+
+// decodeBody 根据响应头、BOM 和 meta 标签检测网页编码并转换为 UTF-8
+// 没有声明编码且不是合法 UTF-8 的中文网页通常是 GBK 编码，使用 GB18030 解码
+func decodeBody(body []byte, contentType string) ([]byte, error) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" && utf8.Valid(body) {
+		return body, nil
+	}
+	head := body[:min(len(body), 1024)]
+	if !certain && name == "windows-1252" && !utf8.Valid(body) &&
+		!bytes.Contains(bytes.ToLower(head), []byte("charset")) {
+		enc = simplifiedchinese.GB18030
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("转换网页编码失败: %w", err)
+	}
+	return decoded, nil
+}
+
+// isHTML 内容是否为 HTML，没有声明类型时根据内容判断
+func isHTML(p *page) bool {
+	if p.ContentType != "" {
+		return p.ContentType != "text/plain"
+	}
+	return strings.Contains(http.DetectContentType(p.Body), "html")
+}
@@ -0,0 +1,400 @@
+package webpage
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// markdownWriter 将 HTML 节点转换为 Markdown
+// 块级元素之间用空行分隔，列表和引用通过行前缀实现嵌套
+type markdownWriter struct {
+	base *url.URL
+	sb   strings.Builder
+	// 每一行的前缀，列表缩进或者引用
+	prefix []string
+	// 下一行使用的列表标记，替换最后一级缩进
+	marker string
+	// 待写入的换行数
+	newlines int
+	// 待写入的空格
+	space     bool
+	lineStart bool
+	started   bool
+}
+
+func newMarkdownWriter(base *url.URL) *markdownWriter {
+	return &markdownWriter{base: base}
+}
+
+// String 返回转换后的 Markdown，去掉行尾空白
+func (w *markdownWriter) String() string {
+	lines := strings.Split(w.sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// write 写入内容，先写入待写入的换行、行前缀和空格
+func (w *markdownWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	if w.started && w.newlines > 0 {
+		for i := 0; i < w.newlines; i++ {
+			if i > 0 {
+				// 空行也需要保留引用的前缀
+				w.sb.WriteString(strings.TrimRight(strings.Join(w.prefix, ""), " "))
+			}
+			w.sb.WriteByte('\n')
+		}
+		w.lineStart = true
+	}
+	w.newlines = 0
+	if w.lineStart || !w.started {
+		if w.marker != "" && len(w.prefix) > 0 {
+			w.sb.WriteString(strings.Join(w.prefix[:len(w.prefix)-1], ""))
+			w.sb.WriteString(w.marker)
+			w.marker = ""
+		} else {
+			w.sb.WriteString(strings.Join(w.prefix, ""))
+		}
+	} else if w.space {
+		w.sb.WriteByte(' ')
+	}
+	w.space = false
+	w.lineStart = false
+	w.started = true
+	w.sb.WriteString(s)
+}
+
+// breakLine 在下一次写入前换行，n 为 2 时表示空行
+func (w *markdownWriter) breakLine(n int) {
+	if n > w.newlines {
+		w.newlines = n
+	}
+}
+
+// text 写入文本节点，合并连续的空白字符
+func (w *markdownWriter) text(s string) {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		if s != "" {
+			w.space = true
+		}
+		return
+	}
+	if isSpace(s[0]) {
+		w.space = true
+	}
+	w.write(strings.Join(words, " "))
+	if isSpace(s[len(s)-1]) {
+		w.space = true
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// inline 将子节点转换为单行的 Markdown，用于标题、链接和表格单元格
+func (w *markdownWriter) inline(n *html.Node) string {
+	sub := newMarkdownWriter(w.base)
+	sub.children(n)
+	return strings.Join(strings.Fields(sub.String()), " ")
+}
+
+func (w *markdownWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+func (w *markdownWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Iframe, atom.Svg, atom.Canvas, atom.Template,
+		atom.Head, atom.Button, atom.Input, atom.Select, atom.Textarea:
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		if text := w.inline(n); text != "" {
+			level := int(n.Data[1] - '0')
+			w.breakLine(2)
+			w.write(strings.Repeat("#", level) + " " + text)
+			w.breakLine(2)
+		}
+	case atom.Br:
+		w.breakLine(1)
+	case atom.Hr:
+		w.breakLine(2)
+		w.write("---")
+		w.breakLine(2)
+	case atom.A:
+		w.link(n)
+	case atom.Img:
+		w.image(n)
+	case atom.Strong, atom.B:
+		w.wrap(n, "**")
+	case atom.Em, atom.I:
+		w.wrap(n, "*")
+	case atom.Del, atom.S, atom.Strike:
+		w.wrap(n, "~~")
+	case atom.Code:
+		w.wrap(n, "`")
+	case atom.Pre:
+		w.pre(n)
+	case atom.Ul, atom.Ol:
+		w.list(n)
+	case atom.Blockquote:
+		w.breakLine(2)
+		w.prefix = append(w.prefix, "> ")
+		w.children(n)
+		w.prefix = w.prefix[:len(w.prefix)-1]
+		w.breakLine(2)
+	case atom.Table:
+		w.table(n)
+	case atom.Li, atom.Tr, atom.Dt, atom.Dd, atom.Figcaption, atom.Caption:
+		w.breakLine(1)
+		w.children(n)
+		w.breakLine(1)
+	default:
+		if isBlock(n) {
+			w.breakLine(2)
+			w.children(n)
+			w.breakLine(2)
+			return
+		}
+		w.children(n)
+	}
+}
+
+func (w *markdownWriter) wrap(n *html.Node, mark string) {
+	text := w.inline(n)
+	if text == "" {
+		return
+	}
+	if mark == "`" {
+		text = textContent(n)
+	}
+	w.write(mark + text + mark)
+}
+
+func (w *markdownWriter) link(n *html.Node) {
+	text := w.inline(n)
+	if text == "" {
+		return
+	}
+	href := w.resolve(attr(n, "href"))
+	if href == "" {
+		w.write(text)
+		return
+	}
+	w.write("[" + text + "](" + href + ")")
+}
+
+func (w *markdownWriter) image(n *html.Node) {
+	src := attr(n, "src")
+	if src == "" {
+		src = attr(n, "data-src")
+	}
+	if src = w.resolve(src); src == "" {
+		return
+	}
+	alt := strings.Join(strings.Fields(attr(n, "alt")), " ")
+	w.write("![" + alt + "](" + src + ")")
+}
+
+// resolve 将链接转换为绝对地址，忽略页内锚点、javascript 和 data 链接
+func (w *markdownWriter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if w.base != nil {
+		u = w.base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "mailto" {
+		return ""
+	}
+	return u.String()
+}
+
+func (w *markdownWriter) pre(n *html.Node) {
+	lang := ""
+	if code := firstChildElement(n, atom.Code); code != nil {
+		for _, class := range strings.Fields(attr(code, "class")) {
+			if after, ok := strings.CutPrefix(class, "language-"); ok {
+				lang = after
+				break
+			}
+		}
+	}
+	code := strings.Trim(textContent(n), "\n")
+	if strings.TrimSpace(code) == "" {
+		return
+	}
+	w.breakLine(2)
+	w.write("```" + lang)
+	w.newlines = 1
+	for _, line := range strings.Split(code, "\n") {
+		if strings.TrimSpace(line) == "" {
+			w.newlines++
+			continue
+		}
+		w.write(strings.TrimRight(line, " \t\r"))
+		w.newlines = 1
+	}
+	w.write("```")
+	w.breakLine(2)
+}
+
+func (w *markdownWriter) list(n *html.Node) {
+	nested := len(w.prefix) > 0 && w.prefix[len(w.prefix)-1] != "> "
+	if nested {
+		w.breakLine(1)
+	} else {
+		w.breakLine(2)
+	}
+	index := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		index = start
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(index) + ". "
+			index++
+		}
+		w.breakLine(1)
+		w.prefix = append(w.prefix, strings.Repeat(" ", len(marker)))
+		w.marker = marker
+		w.children(c)
+		w.marker = ""
+		w.prefix = w.prefix[:len(w.prefix)-1]
+	}
+	if nested {
+		w.breakLine(1)
+	} else {
+		w.breakLine(2)
+	}
+}
+
+func (w *markdownWriter) table(n *html.Node) {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Table:
+				// 嵌套表格不展开
+			case atom.Tr:
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						row = append(row, strings.ReplaceAll(w.inline(cell), "|", "\\|"))
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	w.breakLine(2)
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		w.write("| " + strings.Join(row, " | ") + " |")
+		w.breakLine(1)
+		if i == 0 {
+			w.write("|" + strings.Repeat(" --- |", columns))
+			w.breakLine(1)
+		}
+	}
+	w.breakLine(2)
+}
+
+// blockElements 转换为 Markdown 时需要单独成段的元素
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Nav: true, atom.Figure: true,
+	atom.Dl: true, atom.Address: true, atom.Center: true, atom.Form: true, atom.Fieldset: true,
+	atom.Details: true, atom.Summary: true, atom.Body: true, atom.Html: true,
+}
+
+func isBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && blockElements[n.DataAtom]
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func firstChildElement(n *html.Node, a atom.Atom) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == a {
+			return c
+		}
+	}
+	return nil
+}
+
+// textContent 节点中的原始文本，保留空白字符
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			sb.WriteString(node.Data)
+			return
+		}
+		if node.Type == html.ElementNode && node.DataAtom == atom.Br {
+			sb.WriteByte('\n')
+			return
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
@@ -0,0 +1,332 @@
+package webpage
+
+import (
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 参考 Mozilla Readability 的正文提取算法：
+// 1. 删除脚本、导航、侧边栏等明显不是正文的元素
+// 2. 按段落的文本长度和标点数量给段落的父节点和祖父节点打分，链接越多得分越低
+// 3. 选出得分最高的节点，并合并得分较高的兄弟节点作为正文
+
+var (
+	// 类名或者 ID 匹配时删除该元素
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumb|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|share|recommend|nav|login|copyright`)
+	// 同时匹配时保留该元素
+	maybeCandidate = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveNames  = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story|detail`)
+	negativeNames  = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|nav|menu|recommend`)
+)
+
+// 正文过短时认为提取失败，使用整个页面
+const minContentLength = 100
+
+// Article 提取的网页正文
+type Article struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Markdown 格式的正文
+	Content string `json:"content"`
+}
+
+// Extract 从 HTML 中提取正文并转换为 Markdown，pageURL 用于将相对链接转换为绝对地址
+func Extract(r io.Reader, pageURL *url.URL) (*Article, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	article := &Article{}
+	extractMetadata(doc, article)
+
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	prune(body)
+
+	content := renderMarkdown(pageURL, mainContent(body)...)
+	if utf8.RuneCountInString(content) < minContentLength {
+		content = renderMarkdown(pageURL, body)
+	}
+	article.Content = content
+	return article, nil
+}
+
+func renderMarkdown(pageURL *url.URL, nodes ...*html.Node) string {
+	w := newMarkdownWriter(pageURL)
+	for _, n := range nodes {
+		w.breakLine(2)
+		w.node(n)
+	}
+	return w.String()
+}
+
+// extractMetadata 提取标题和描述
+func extractMetadata(doc *html.Node, article *Article) {
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if article.Title == "" {
+					article.Title = normalizeSpace(textContent(n))
+				}
+			case atom.Meta:
+				name := strings.ToLower(attr(n, "name") + attr(n, "property"))
+				content := normalizeSpace(attr(n, "content"))
+				switch name {
+				case "description", "og:description":
+					if article.Description == "" {
+						article.Description = content
+					}
+				case "og:title":
+					if article.Title == "" {
+						article.Title = content
+					}
+				}
+			case atom.Body:
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+}
+
+// prune 删除不可能是正文的元素
+func prune(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isUnlikely(c)) {
+			n.RemoveChild(c)
+		} else {
+			prune(c)
+		}
+		c = next
+	}
+}
+
+func isUnlikely(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Iframe, atom.Svg, atom.Canvas, atom.Template,
+		atom.Form, atom.Button, atom.Input, atom.Select, atom.Textarea, atom.Nav, atom.Aside, atom.Footer:
+		return true
+	case atom.Body, atom.Article, atom.Main, atom.A, atom.Table, atom.Tbody, atom.Tr, atom.Td, atom.Th, atom.Pre, atom.Code:
+		return false
+	}
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(names) && !maybeCandidate.MatchString(names)
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// mainContent 选出正文节点，返回得分最高的节点及其得分较高的兄弟节点
+func mainContent(body *html.Node) []*html.Node {
+	scores, candidates := scoreCandidates(body)
+	var top *html.Node
+	topScore := 0.0
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		scores[n] = score
+		if top == nil || score > topScore {
+			top, topScore = n, score
+		}
+	}
+	if top == nil {
+		return []*html.Node{body}
+	}
+	if top.Parent == nil || top == body {
+		return []*html.Node{top}
+	}
+
+	// 正文可能被拆分到多个兄弟节点中
+	threshold := math.Max(10, topScore*0.2)
+	topClass := attr(top, "class")
+	var nodes []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling == top {
+			nodes = append(nodes, sibling)
+			continue
+		}
+		score, ok := scores[sibling]
+		if ok && topClass != "" && attr(sibling, "class") == topClass {
+			score += topScore * 0.2
+		}
+		if ok && score >= threshold {
+			nodes = append(nodes, sibling)
+			continue
+		}
+		if sibling.DataAtom == atom.P {
+			text := normalizeSpace(textContent(sibling))
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(sibling)
+			if (length > 80 && density < 0.25) ||
+				(length > 0 && density == 0 && strings.ContainsAny(text, ".。")) {
+				nodes = append(nodes, sibling)
+			}
+		}
+	}
+	return nodes
+}
+
+// scoreCandidates 按段落给祖先节点打分，同时返回按文档顺序排列的候选节点，保证得分相同时结果稳定
+func scoreCandidates(body *html.Node) (map[*html.Node]float64, []*html.Node) {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if isParagraph(c) {
+				candidates = scoreParagraph(c, scores, candidates)
+			}
+			walk(c)
+		}
+	}
+	walk(body)
+	return scores, candidates
+}
+
+func isParagraph(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		return true
+	case atom.Div, atom.Section:
+		// 很多中文网站直接在 div 中放正文，没有子块级元素的 div 按段落处理
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if isBlock(c) || (c.Type == html.ElementNode && (c.DataAtom == atom.Table || c.DataAtom == atom.Ul || c.DataAtom == atom.Ol || c.DataAtom == atom.Pre || c.DataAtom == atom.Blockquote)) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func scoreParagraph(n *html.Node, scores map[*html.Node]float64, candidates []*html.Node) []*html.Node {
+	text := normalizeSpace(textContent(n))
+	length := utf8.RuneCountInString(text)
+	if length < 25 {
+		return candidates
+	}
+	score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+strings.Count(text, "。")) +
+		math.Min(float64(length/100), 3)
+
+	// 父节点得到全部分数，更上层的祖先依次递减
+	ancestor := n.Parent
+	for level := 0; level < 3 && ancestor != nil && ancestor.Type == html.ElementNode; level++ {
+		if _, ok := scores[ancestor]; !ok {
+			scores[ancestor] = initialScore(ancestor)
+			candidates = append(candidates, ancestor)
+		}
+		divider := 1.0
+		if level == 1 {
+			divider = 2
+		} else if level > 1 {
+			divider = float64(level * 3)
+		}
+		scores[ancestor] += score / divider
+		if ancestor.DataAtom == atom.Body {
+			break
+		}
+		ancestor = ancestor.Parent
+	}
+	return candidates
+}
+
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity 链接文本占全部文本的比例
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(normalizeSpace(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.DataAtom == atom.A {
+				links += utf8.RuneCountInString(normalizeSpace(textContent(c)))
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(links) / float64(total)
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,165 @@
+package webpage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Redis key 前缀
+	cacheKeyPrefix = "web_page:"
+	// 默认每次返回的最大文本长度 (约8000个token)
+	DefaultMaxLength = 16000
+)
+
+// Cache 网页内容缓存
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// Reader 抓取网页并提取正文，正文按 URL 缓存，支持分段读取长网页
+type Reader struct {
+	fetcher  *fetcher
+	cache    Cache
+	cacheTTL time.Duration
+}
+
+// NewReader cache 为空或者 cacheTTL 小于等于 0 时不缓存
+func NewReader(cache Cache, cacheTTL time.Duration) *Reader {
+	return &Reader{fetcher: newFetcher(), cache: cache, cacheTTL: cacheTTL}
+}
+
+// SetHTTPClient 替换抓取网页使用的客户端，默认客户端不允许访问内网地址，测试访问本机网站时使用
+func (r *Reader) SetHTTPClient(client *http.Client) {
+	r.fetcher.client = client
+}
+
+// Chunk 网页正文的一段
+type Chunk struct {
+	*Article
+	URL string `json:"url"`
+	// 本段内容在正文中的起始位置，按字符计算
+	Offset int `json:"offset"`
+	// 下一段内容的起始位置，没有更多内容时为 0
+	NextOffset int `json:"next_offset,omitempty"`
+	// 正文的总字符数
+	TotalLength int `json:"total_length"`
+	// 本段之后是否还有内容
+	Truncated bool `json:"truncated"`
+}
+
+// Read 读取网页正文中从 offset 开始、最多 maxLength 个字符的内容
+func (r *Reader) Read(ctx context.Context, rawURL string, offset, maxLength int) (*Chunk, error) {
+	article, err := r.article(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+	content := []rune(article.Content)
+	if offset < 0 || (offset > 0 && offset >= len(content)) {
+		return nil, fmt.Errorf("偏移量超出范围, 正文共 %d 个字符", len(content))
+	}
+
+	end := chunkEnd(content, offset, maxLength)
+	chunk := &Chunk{
+		Article:     &Article{Title: article.Title, Description: article.Description, Content: string(content[offset:end])},
+		URL:         rawURL,
+		Offset:      offset,
+		TotalLength: len(content),
+		Truncated:   end < len(content),
+	}
+	if chunk.Truncated {
+		chunk.NextOffset = end
+	}
+	return chunk, nil
+}
+
+// chunkEnd 计算本段的结束位置，尽量在段落或者行的边界处截断
+func chunkEnd(content []rune, offset, maxLength int) int {
+	end := offset + maxLength
+	if end >= len(content) {
+		return len(content)
+	}
+	// 只在后 20% 的范围内寻找边界，避免分段过短
+	minEnd := offset + maxLength*4/5
+	for i := end - 1; i > minEnd; i-- {
+		if content[i] == '\n' && content[i-1] == '\n' {
+			return i + 1
+		}
+	}
+	for i := end - 1; i >= minEnd; i-- {
+		if content[i] == '\n' {
+			return i + 1
+		}
+	}
+	return end
+}
+
+// article 获取网页正文，优先使用缓存
+func (r *Reader) article(ctx context.Context, rawURL string) (*Article, error) {
+	pageURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("网页地址格式错误: %w", err)
+	}
+	key := cacheKey(pageURL.String())
+	if article, ok := r.getCache(ctx, key); ok {
+		return article, nil
+	}
+
+	p, err := r.fetcher.fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	var article *Article
+	if isHTML(p) {
+		if article, err = Extract(bytes.NewReader(p.Body), p.URL); err != nil {
+			return nil, fmt.Errorf("解析网页失败: %w", err)
+		}
+	} else {
+		article = &Article{Content: strings.TrimSpace(string(p.Body))}
+	}
+	r.setCache(ctx, key, article)
+	return article, nil
+}
+
+func cacheKey(pageURL string) string {
+	sum := sha256.Sum256([]byte(pageURL))
+	return cacheKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+func (r *Reader) getCache(ctx context.Context, key string) (*Article, bool) {
+	if r.cache == nil || r.cacheTTL <= 0 {
+		return nil, false
+	}
+	value, ok := r.cache.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	var article Article
+	if err := json.Unmarshal(value, &article); err != nil {
+		return nil, false
+	}
+	return &article, true
+}
+
+func (r *Reader) setCache(ctx context.Context, key string, article *Article) {
+	if r.cache == nil || r.cacheTTL <= 0 {
+		return
+	}
+	value, err := json.Marshal(article)
+	if err != nil {
+		return
+	}
+	r.cache.Set(ctx, key, value, r.cacheTTL)
+}
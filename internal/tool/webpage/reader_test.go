@@ -0,0 +1,222 @@
+package webpage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"txing-ai/internal/utils/httputils"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const articleHTML = `<!DOCTYPE html>
+<html><head><title>测试文章</title><meta name="description" content="文章描述"></head>
+<body>
+<nav><a href="/">首页</a><a href="/news">新闻</a></nav>
+<div class="sidebar"><p>热门推荐，热门推荐，热门推荐，热门推荐，热门推荐，热门推荐</p></div>
+<div class="article-content">
+<h1>标题</h1>
+<p>第一段正文，包含一个<a href="/detail">相对链接</a>，以及<strong>加粗</strong>的文字，正文内容足够长，用于提取正文。</p>
+<p>第二段正文，这里有更多的内容，逗号，句号。正文内容足够长，用于提取正文，正文内容足够长。</p>
+<ul><li>列表一</li><li>列表二<ul><li>子列表</li></ul></li></ul>
+<table><tr><th>名称</th><th>数量</th></tr><tr><td>苹果</td><td>3</td></tr></table>
+</div>
+<div class="footer">版权所有</div>
+<script>var a = 1;</script>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+	base, _ := url.Parse("https://example.com/news/1.html")
+	article, err := Extract(strings.NewReader(articleHTML), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Title != "测试文章" || article.Description != "文章描述" {
+		t.Errorf("Extract() title = %q, description = %q", article.Title, article.Description)
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		exclude bool
+	}{
+		{name: "标题", want: "# 标题"},
+		{name: "链接转换为绝对地址", want: "[相对链接](https://example.com/detail)"},
+		{name: "加粗", want: "**加粗**"},
+		{name: "列表", want: "- 列表一\n- 列表二\n  - 子列表"},
+		{name: "表格", want: "| 名称 | 数量 |\n| --- | --- |\n| 苹果 | 3 |"},
+		{name: "删除导航", want: "首页", exclude: true},
+		{name: "删除侧边栏", want: "热门推荐", exclude: true},
+		{name: "删除页脚", want: "版权所有", exclude: true},
+		{name: "删除脚本", want: "var a", exclude: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if strings.Contains(article.Content, tt.want) == tt.exclude {
+				t.Errorf("Extract() content = %q, want contains %q = %v", article.Content, tt.want, !tt.exclude)
+			}
+		})
+	}
+}
+
+// memoryCache 测试用的内存缓存
+type memoryCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.data[key]
+	return value, ok
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, value []byte, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+}
+
+func newTestSite(t *testing.T) (*httptest.Server, *int32) {
+	gbkPage, err := simplifiedchinese.GBK.NewEncoder().String(`<html><head><meta charset="gbk"><title>中文网页</title></head><body><p>这是一个使用 GBK 编码的中文网页，正文内容足够长，用于测试编码检测。</p></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	undeclared, err := simplifiedchinese.GBK.NewEncoder().String(`<html><body><p>没有声明编码的中文网页，正文内容足够长，用于测试编码检测，正文内容足够长。</p></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := "<html><body><div class=\"content\">" + strings.Repeat("<p>这是一个很长的段落，用于测试分段读取，正文内容足够长。</p>", 100) + "</div></body></html>"
+
+	var pageRequests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	})
+	handle := func(path, contentType, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&pageRequests, 1)
+			w.Header().Set("Content-Type", contentType)
+			_, _ = w.Write([]byte(body))
+		})
+	}
+	handle("/article", "text/html; charset=utf-8", articleHTML)
+	handle("/gbk", "text/html", gbkPage)
+	handle("/undeclared", "text/html", undeclared)
+	handle("/long", "text/html; charset=utf-8", long)
+	handle("/private/page", "text/html; charset=utf-8", articleHTML)
+	handle("/image", "image/png", "png")
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &pageRequests
+}
+
+// newTestReader 测试网站运行在本机，使用不检查地址的客户端
+func newTestReader(cache Cache, cacheTTL time.Duration) *Reader {
+	reader := NewReader(cache, cacheTTL)
+	reader.SetHTTPClient(&http.Client{Timeout: fetchTimeout})
+	return reader
+}
+
+func TestReader_Read(t *testing.T) {
+	server, _ := newTestSite(t)
+	reader := newTestReader(nil, 0)
+
+	tests := []struct {
+		name        string
+		path        string
+		want        string
+		wantTitle   string
+		wantErr     error
+		wantAnyErr  bool
+		wantPartial bool
+	}{
+		{name: "UTF-8 网页", path: "/article", want: "# 标题", wantTitle: "测试文章"},
+		{name: "meta 声明 GBK 编码", path: "/gbk", want: "使用 GBK 编码的中文网页", wantTitle: "中文网页"},
+		{name: "没有声明编码的 GBK 网页", path: "/undeclared", want: "没有声明编码的中文网页"},
+		{name: "robots.txt 禁止抓取", path: "/private/page", wantErr: ErrDisallowedByRobots},
+		{name: "不支持的类型", path: "/image", wantAnyErr: true},
+		{name: "网页不存在", path: "/not_found", wantAnyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunk, err := reader.Read(context.Background(), server.URL+tt.path, 0, 0)
+			if tt.wantErr != nil || tt.wantAnyErr {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(chunk.Content, tt.want) {
+				t.Errorf("Read() content = %q, want contains %q", chunk.Content, tt.want)
+			}
+			if chunk.Title != tt.wantTitle {
+				t.Errorf("Read() title = %q, want %q", chunk.Title, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestReader_Chunks(t *testing.T) {
+	server, requests := newTestSite(t)
+	reader := newTestReader(&memoryCache{data: map[string][]byte{}}, time.Minute)
+
+	var content strings.Builder
+	offset, total := 0, 0
+	for i := 0; ; i++ {
+		chunk, err := reader.Read(context.Background(), server.URL+"/long", offset, 500)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Offset != offset {
+			t.Errorf("chunk %d offset = %d, want %d", i, chunk.Offset, offset)
+		}
+		if n := len([]rune(chunk.Content)); n > 500 {
+			t.Errorf("chunk %d length = %d, want <= 500", i, n)
+		}
+		content.WriteString(chunk.Content)
+		total = chunk.TotalLength
+		if !chunk.Truncated {
+			break
+		}
+		// 在段落边界处截断
+		if !strings.HasSuffix(chunk.Content, "\n\n") {
+			t.Errorf("chunk %d should end at paragraph boundary", i)
+		}
+		offset = chunk.NextOffset
+	}
+	if len([]rune(content.String())) != total {
+		t.Errorf("chunks length = %d, want %d", len([]rune(content.String())), total)
+	}
+	// 后续分段使用缓存的正文
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("page requested %d times, want 1", got)
+	}
+	if _, err := reader.Read(context.Background(), server.URL+"/long", total, 500); err == nil {
+		t.Errorf("Read() should fail when offset is out of range")
+	}
+}
+
+func TestReader_RejectsInternalAddress(t *testing.T) {
+	server, requests := newTestSite(t)
+	reader := NewReader(nil, 0)
+
+	if _, err := reader.Read(context.Background(), server.URL+"/article", 0, 0); !errors.Is(err, httputils.ErrForbiddenAddress) {
+		t.Errorf("Read() error = %v, want ErrForbiddenAddress", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 0 {
+		t.Errorf("page requested %d times, want 0", got)
+	}
+}
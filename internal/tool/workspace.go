@@ -0,0 +1,358 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWorkspaceDir         = "runtime/workspace"
+	defaultWorkspaceUserQuota   = 100 << 20
+	defaultWorkspaceMaxFileSize = 10 << 20
+	defaultWorkspaceTTL         = 24 * time.Hour
+)
+
+// 检查配额和写入文件需要串行执行，避免同一用户并发运行时超出配额
+var workspaceQuotaMu sync.Mutex
+
+// Workspace 单次智能体运行的文件工作区
+// 所有文件操作都通过 os.Root 进行，路径（包括符号链接）不能逃逸出工作区目录
+type Workspace struct {
+	// 工作区目录（绝对路径）
+	dir string
+	// 当前用户所有工作区的父目录，用于计算配额
+	userDir     string
+	root        *os.Root
+	userQuota   int64
+	maxFileSize int64
+}
+
+// workspaceSettings 工作区的配置
+type workspaceSettings struct {
+	dir         string
+	userQuota   int64
+	maxFileSize int64
+	ttl         time.Duration
+}
+
+func loadWorkspaceSettings() workspaceSettings {
+	settings := workspaceSettings{
+		dir:         defaultWorkspaceDir,
+		userQuota:   defaultWorkspaceUserQuota,
+		maxFileSize: defaultWorkspaceMaxFileSize,
+		ttl:         defaultWorkspaceTTL,
+	}
+	config := global.LoadConfig().WorkspaceConfig
+	if config == nil {
+		return settings
+	}
+	if config.Dir != "" {
+		settings.dir = config.Dir
+	}
+	if config.UserQuota > 0 {
+		settings.userQuota = config.UserQuota
+	}
+	if config.MaxFileSize > 0 {
+		settings.maxFileSize = config.MaxFileSize
+	}
+	if config.TTL > 0 {
+		settings.ttl = config.TTL * time.Second
+	}
+	return settings
+}
+
+// NewWorkspace 为用户创建新的工作区，同时清理该用户残留的过期工作区
+func NewWorkspace(userId int64) (*Workspace, error) {
+	return newWorkspace(loadWorkspaceSettings(), userId)
+}
+
+func newWorkspace(settings workspaceSettings, userId int64) (*Workspace, error) {
+	baseDir, err := filepath.Abs(settings.dir)
+	if err != nil {
+		return nil, err
+	}
+	userDir := filepath.Join(baseDir, strconv.FormatInt(userId, 10))
+	if err := os.MkdirAll(userDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建工作区目录失败: %w", err)
+	}
+	// 解析符号链接，保证计算相对路径时和 os.Root 看到的目录一致
+	if userDir, err = filepath.EvalSymlinks(userDir); err != nil {
+		return nil, err
+	}
+	removeStaleWorkspaces(userDir, settings.ttl)
+
+	dir, err := os.MkdirTemp(userDir, "run-")
+	if err != nil {
+		return nil, fmt.Errorf("创建工作区目录失败: %w", err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return &Workspace{
+		dir:         dir,
+		userDir:     userDir,
+		root:        root,
+		userQuota:   settings.userQuota,
+		maxFileSize: settings.maxFileSize,
+	}, nil
+}
+
+// removeStaleWorkspaces 删除运行异常退出后残留的工作区
+func removeStaleWorkspaces(userDir string, ttl time.Duration) {
+	entries, err := os.ReadDir(userDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() || time.Since(info.ModTime()) < ttl {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(userDir, entry.Name())); err != nil {
+			log.Warn("remove stale workspace failed", zap.String("dir", entry.Name()), zap.Error(err))
+		}
+	}
+}
+
+// Dir 工作区目录
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+// Close 删除工作区及其中的所有文件
+func (w *Workspace) Close() error {
+	_ = w.root.Close()
+	return os.RemoveAll(w.dir)
+}
+
+// ReadFile 读取工作区中的文件
+func (w *Workspace) ReadFile(name string) ([]byte, error) {
+	name, err := w.relPath(name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := w.root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, w.maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > w.maxFileSize {
+		return nil, fmt.Errorf("文件大小超过上限 %d 字节", w.maxFileSize)
+	}
+	return data, nil
+}
+
+// WriteFile 写入工作区中的文件，自动创建父目录，超过单文件大小或用户配额时返回错误
+func (w *Workspace) WriteFile(name string, data []byte) error {
+	name, err := w.relPath(name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return fmt.Errorf("文件路径不能为空")
+	}
+	if int64(len(data)) > w.maxFileSize {
+		return fmt.Errorf("文件大小超过上限 %d 字节", w.maxFileSize)
+	}
+
+	workspaceQuotaMu.Lock()
+	defer workspaceQuotaMu.Unlock()
+
+	used, err := dirSize(w.userDir)
+	if err != nil {
+		return err
+	}
+	// 覆盖已有文件时不重复计算原文件的大小
+	if info, err := w.root.Stat(name); err == nil && info.Mode().IsRegular() {
+		used -= info.Size()
+	}
+	if used+int64(len(data)) > w.userQuota {
+		return fmt.Errorf("工作区空间不足，用户配额为 %d 字节，已使用 %d 字节", w.userQuota, used)
+	}
+
+	if err := w.mkdirAll(filepath.Dir(name)); err != nil {
+		return err
+	}
+	file, err := w.root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadDir 列出工作区中的目录，name 为空表示工作区根目录
+func (w *Workspace) ReadDir(name string) ([]fs.DirEntry, error) {
+	name, err := w.relPath(name)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := w.root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	return dir.ReadDir(-1)
+}
+
+// Stat 获取工作区中文件的信息，不跟随最后一级的符号链接
+func (w *Workspace) Stat(name string) (fs.FileInfo, error) {
+	name, err := w.relPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return w.root.Lstat(name)
+}
+
+// Remove 删除工作区中的文件或空目录
+func (w *Workspace) Remove(name string) error {
+	name, err := w.relPath(name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return fmt.Errorf("不能删除工作区根目录")
+	}
+	return w.root.Remove(name)
+}
+
+// relPath 将路径转换为工作区内的相对路径，支持工作区内的绝对路径
+func (w *Workspace) relPath(name string) (string, error) {
+	name = filepath.Clean(strings.TrimSpace(name))
+	if filepath.IsAbs(name) {
+		rel, err := filepath.Rel(w.dir, name)
+		if err != nil {
+			return "", fmt.Errorf("不允许访问工作区以外的路径: %s", name)
+		}
+		name = rel
+	}
+	if name != "." && !filepath.IsLocal(name) {
+		return "", fmt.Errorf("不允许访问工作区以外的路径: %s", name)
+	}
+	return name, nil
+}
+
+// mkdirAll 在工作区中逐级创建目录
+func (w *Workspace) mkdirAll(dir string) error {
+	if dir == "." {
+		return nil
+	}
+	current := ""
+	for _, part := range strings.Split(dir, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		if err := w.root.Mkdir(current, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// dirSize 计算目录中所有文件的总大小
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// 并发运行的工作区可能已被删除
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// ToolContext 单次智能体运行中工具共享的上下文
+type ToolContext struct {
+	// 当前用户ID，未登录时为 0
+	UserId int64
+	// 本次运行的工作区，创建失败时为空
+	Workspace *Workspace
+}
+
+type toolContextKey struct{}
+
+// WithToolContext 将工具上下文保存到 context 中
+func WithToolContext(ctx context.Context, toolCtx *ToolContext) context.Context {
+	return context.WithValue(ctx, toolContextKey{}, toolCtx)
+}
+
+// GetToolContext 获取工具上下文，不存在时返回空
+func GetToolContext(ctx context.Context) *ToolContext {
+	toolCtx, _ := ctx.Value(toolContextKey{}).(*ToolContext)
+	return toolCtx
+}
+
+// StartRun 为一次智能体运行创建工具上下文和工作区，运行结束后调用返回的函数删除工作区
+// 工作区创建失败时只记录日志，文件工具在调用时返回错误，不影响其他工具
+func StartRun(ctx context.Context) (context.Context, func()) {
+	if GetToolContext(ctx) != nil {
+		// 子智能体复用外层运行的工作区
+		return ctx, func() {}
+	}
+	userId, _ := utils.GetUIDFromContextAllowEmpty(ctx)
+	toolCtx := &ToolContext{UserId: userId}
+	workspace, err := NewWorkspace(userId)
+	if err != nil {
+		log.Error("create workspace failed", zap.Int64("userId", userId), zap.Error(err))
+	}
+	toolCtx.Workspace = workspace
+	return WithToolContext(ctx, toolCtx), func() {
+		if workspace == nil {
+			return
+		}
+		if err := workspace.Close(); err != nil {
+			log.Warn("remove workspace failed", zap.String("dir", workspace.Dir()), zap.Error(err))
+		}
+	}
+}
+
+// userIdFromContext 获取调用工具的用户ID，优先使用工具上下文
+func userIdFromContext(ctx context.Context) (int64, bool) {
+	if toolCtx := GetToolContext(ctx); toolCtx != nil && toolCtx.UserId != 0 {
+		return toolCtx.UserId, true
+	}
+	if outputs, ok := ctx.Value(outputFilesKey{}).(*OutputFiles); ok && outputs.userId != 0 {
+		return outputs.userId, true
+	}
+	return utils.GetUIDFromContextAllowEmpty(ctx)
+}
+
+// workspaceFromContext 获取当前运行的工作区
+func workspaceFromContext(ctx context.Context) (*Workspace, error) {
+	if toolCtx := GetToolContext(ctx); toolCtx != nil && toolCtx.Workspace != nil {
+		return toolCtx.Workspace, nil
+	}
+	return nil, fmt.Errorf("当前运行没有可用的工作区")
+}
@@ -0,0 +1,107 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"txing-ai/internal/global/logging"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+func TestWorkspace_Quota(t *testing.T) {
+	settings := workspaceSettings{dir: t.TempDir(), userQuota: 10, maxFileSize: 10, ttl: time.Hour}
+	first, err := newWorkspace(settings, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := newWorkspace(settings, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	other, err := newWorkspace(settings, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	tests := []struct {
+		name      string
+		workspace *Workspace
+		file      string
+		size      int
+		wantErr   bool
+	}{
+		{name: "配额内", workspace: first, file: "a.txt", size: 6},
+		{name: "同一用户的其他运行共享配额", workspace: second, file: "b.txt", size: 5, wantErr: true},
+		{name: "覆盖文件不重复计算", workspace: first, file: "a.txt", size: 8},
+		{name: "其他用户不受影响", workspace: other, file: "c.txt", size: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.workspace.WriteFile(tt.file, make([]byte, tt.size))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkspace_Cleanup(t *testing.T) {
+	settings := workspaceSettings{dir: t.TempDir(), userQuota: 1024, maxFileSize: 1024, ttl: time.Hour}
+
+	stale, err := newWorkspace(settings, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模拟运行异常退出后残留的工作区
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(stale.Dir(), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	workspace, err := newWorkspace(settings, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale.Dir()); !os.IsNotExist(err) {
+		t.Errorf("stale workspace should be removed")
+	}
+	if err := workspace.WriteFile("dir/a.txt", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := workspace.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(workspace.Dir()); !os.IsNotExist(err) {
+		t.Errorf("workspace should be removed after close")
+	}
+	if _, err := os.Stat(filepath.Dir(workspace.Dir())); err != nil {
+		t.Errorf("user dir should be kept: %v", err)
+	}
+}
+
+func TestGetToolContext(t *testing.T) {
+	ctx := WithToolContext(context.Background(), &ToolContext{UserId: 3})
+	if userId, ok := userIdFromContext(ctx); !ok || userId != 3 {
+		t.Errorf("userIdFromContext() = %d, %v, want 3", userId, ok)
+	}
+	// 子智能体复用外层运行的工具上下文
+	nested, finish := StartRun(ctx)
+	defer finish()
+	if GetToolContext(nested) != GetToolContext(ctx) {
+		t.Errorf("StartRun() should reuse existing tool context")
+	}
+	if _, err := workspaceFromContext(ctx); err == nil {
+		t.Errorf("workspaceFromContext() should fail without workspace")
+	}
+}
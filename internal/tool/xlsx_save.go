@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"txing-ai/internal/global/logging/log"
+	"txing-ai/internal/tool/office"
+
+	"go.uber.org/zap"
+)
+
+// xlsxSheetParams 工作表
+type xlsxSheetParams struct {
+	Name    string     `json:"name" jsonschema:"description=工作表名称"`
+	Headers []string   `json:"headers,omitempty" jsonschema:"description=表头"`
+	Rows    [][]string `json:"rows" jsonschema:"description=数据行，数字会保存为数值，以=开头的单元格保存为公式，例如 =SUM(B2:B5)"`
+}
+
+// xlsxSaveParams 生成Excel文件的参数，sheets 和 content 二选一
+type xlsxSaveParams struct {
+	Filename string            `json:"filename" jsonschema:"description=文件名(不含扩展名)"`
+	Sheets   []xlsxSheetParams `json:"sheets,omitempty" jsonschema:"description=工作表列表"`
+	Content  string            `json:"content,omitempty" jsonschema:"description=包含表格的Markdown内容，每个表格保存为一个工作表，不提供sheets时使用"`
+}
+
+// saveXlsx 将表格数据保存为Excel文件
+func saveXlsx(ctx context.Context, params *xlsxSaveParams) (string, error) {
+	var sheets []office.Sheet
+	for _, s := range params.Sheets {
+		rows := s.Rows
+		if len(s.Headers) > 0 {
+			rows = append([][]string{s.Headers}, rows...)
+		}
+		sheets = append(sheets, office.Sheet{Name: s.Name, Rows: rows})
+	}
+	if len(sheets) == 0 {
+		sheets = office.SheetsFromMarkdown(params.Content)
+	}
+	if len(sheets) == 0 {
+		return "没有找到表格数据，请提供 sheets 或者包含表格的 Markdown 内容", nil
+	}
+
+	filename, url, err := saveDocument(ctx, xlsxSaveToolName, params.Filename, ".xlsx", func(w io.Writer) error {
+		return office.WriteXLSX(w, params.Filename, sheets)
+	})
+	if err != nil {
+		log.Error("保存Excel文件失败", zap.Error(err))
+		return fmt.Sprintf("保存Excel文件失败: %v", err), nil
+	}
+	return fmt.Sprintf("Excel文件已成功保存: ./%s，下载地址: %s", filename, url), nil
+}
+
+// 展示消息构造：将逻辑内聚到工具文件
+type xlsxSaveShowBuilder struct{}
+
+func (xlsxSaveShowBuilder) BuildRequest(paramsStr string) (string, error) {
+	var params xlsxSaveParams
+	if err := json.Unmarshal([]byte(paramsStr), &params); err != nil {
+		log.Error("构建Excel保存请求显示信息失败", zap.Error(err))
+		return "", ErrInvalidJSON
+	}
+	return "保存为 Excel 文件：" + params.Filename, nil
+}
+
+func (xlsxSaveShowBuilder) BuildResponse(response string) (string, error) {
+	return response, nil
+}
+
+func init() {
+	RegisterShowMsgBuilder(xlsxSaveToolName, xlsxSaveShowBuilder{})
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// ApiKeyPrefix API Key 的固定前缀，用于和登录令牌区分
+const ApiKeyPrefix = "tx-"
+
+// GenerateApiKey 生成 API Key，返回明文、摘要和用于辨认的前缀
+func GenerateApiKey() (key, hash, displayPrefix string, err error) {
+	buf := make([]byte, 24)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key = ApiKeyPrefix + hex.EncodeToString(buf)
+	return key, HashApiKey(key), key[:len(ApiKeyPrefix)+6], nil
+}
+
+// HashApiKey 计算 API Key 的摘要，数据库中只保存摘要
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsApiKey 判断令牌是否是 API Key
+func IsApiKey(token string) bool {
+	return strings.HasPrefix(token, ApiKeyPrefix)
+}
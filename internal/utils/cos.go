@@ -2,9 +2,13 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"path"
 	"reflect"
 	"strings"
 	"time"
@@ -23,6 +27,146 @@ type TempCredential struct {
 	SessionToken string
 	StartTime    int64
 	ExpiredTime  int64
+	// 存储桶、地域和允许上传的对象前缀，客户端只能上传到该前缀下
+	Bucket string
+	Region string
+	Prefix string
+}
+
+// UploadPurpose 客户端直传文件的用途，不同用途上传到不同的前缀并有各自的大小和类型限制
+type UploadPurpose string
+
+const (
+	UploadPurposeAvatar     UploadPurpose = "avatar"     // 用户头像
+	UploadPurposePreset     UploadPurpose = "preset"     // 预设头像
+	UploadPurposeAttachment UploadPurpose = "attachment" // 对话、智能体附件
+)
+
+// 临时密钥的有效期
+const tempCredentialDuration = 30 * time.Minute
+
+var (
+	ErrUploadPurpose     = errors.New("不支持的上传用途")
+	ErrUploadKey         = errors.New("文件路径不属于当前用户")
+	ErrUploadNotFound    = errors.New("上传的文件不存在")
+	ErrUploadTooLarge    = errors.New("上传的文件过大")
+	ErrUploadContentType = errors.New("不支持的文件类型")
+)
+
+// IsUploadError 是否为可以直接提示给用户的上传校验错误
+func IsUploadError(err error) bool {
+	for _, target := range []error{ErrUploadPurpose, ErrUploadKey, ErrUploadNotFound, ErrUploadTooLarge, ErrUploadContentType} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadRule 某种用途允许上传的文件大小和类型
+type uploadRule struct {
+	maxSize int64
+	// 允许的 Content-Type，支持 image/* 形式的通配
+	contentTypes []string
+	// 是否按文件内容校验为图片
+	image bool
+}
+
+var uploadRules = map[UploadPurpose]uploadRule{
+	UploadPurposeAvatar: {
+		maxSize:      5 << 20,
+		contentTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp"},
+		image:        true,
+	},
+	UploadPurposePreset: {
+		maxSize:      5 << 20,
+		contentTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp"},
+		image:        true,
+	},
+	UploadPurposeAttachment: {
+		maxSize: 50 << 20,
+		contentTypes: []string{
+			"image/*",
+			"text/*",
+			"application/pdf",
+			"application/json",
+			"application/msword",
+			"application/vnd.openxmlformats-officedocument.*",
+			"application/vnd.ms-excel",
+			"application/vnd.ms-powerpoint",
+		},
+	},
+}
+
+// Valid 是否为支持的上传用途
+func (p UploadPurpose) Valid() bool {
+	_, ok := uploadRules[p]
+	return ok
+}
+
+// MaxSize 允许上传的最大字节数
+func (p UploadPurpose) MaxSize() int64 {
+	return uploadRules[p].maxSize
+}
+
+// UploadPurposes 所有的上传用途，客户端直传的对象都在这些前缀下
+func UploadPurposes() []UploadPurpose {
+	return []UploadPurpose{UploadPurposeAvatar, UploadPurposePreset, UploadPurposeAttachment}
+}
+
+// UploadPrefix 用户某种用途的上传前缀，例如 avatar/1/
+func UploadPrefix(userId int64, purpose UploadPurpose) string {
+	return fmt.Sprintf("%s/%d/", purpose, userId)
+}
+
+// UploadKey 将客户端给出的文件名放到用户的上传前缀下，只保留文件名部分，没有可用的文件名时使用时间戳
+func UploadKey(userId int64, purpose UploadPurpose, name string) string {
+	name = path.Base(path.Clean("/" + name))
+	if name == "/" {
+		name = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return UploadPrefix(userId, purpose) + name
+}
+
+// IsUserUploadKey 对象键是否在用户某种用途的上传前缀下，purpose 为空时检查所有用途，不允许路径穿越
+func IsUserUploadKey(userId int64, purpose UploadPurpose, key string) bool {
+	if path.Clean("/"+key) != "/"+key {
+		return false
+	}
+	purposes := []UploadPurpose{purpose}
+	if purpose == "" {
+		purposes = UploadPurposes()
+	}
+	for _, p := range purposes {
+		prefix := UploadPrefix(userId, p)
+		if p.Valid() && strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsObjectKey 是否为存储桶中的对象路径，外部图片地址等不是对象路径
+func IsObjectKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "http://") && !strings.HasPrefix(key, "https://")
+}
+
+// matchContentType 判断 Content-Type 是否在允许的列表中
+func matchContentType(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range allowed {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if mediaType == pattern {
+			return true
+		}
+	}
+	return false
 }
 
 // COS 客户端
@@ -42,62 +186,57 @@ func (c *COSClient) PutFromURL(ctx context.Context, key string, url string) (str
 	return uploadResult.Key, err
 }
 
-// 获取临时密钥
-func (c *COSClient) GetTempCredential() (*TempCredential, error) {
-	stsClient := sts.NewClient(
-		// 通过环境变量获取密钥, os.Getenv 方法表示获取环境变量
-		c.config.AccessKey, // 用户的 SecretId，建议使用子账号密钥，授权遵循最小权限指引，降低使用风险。子账号密钥获取可参考https://cloud.tencent.com/document/product/598/37140
-		c.config.SecretKey, // 用户的 SecretKey，建议使用子账号密钥，授权遵循最小权限指引，降低使用风险。子账号密钥获取可参考https://cloud.tencent.com/document/product/598/37140
-		nil,
-		// sts.Host("sts.internal.tencentcloudapi.com"), // 设置域名, 默认域名sts.tencentcloudapi.com
-		// sts.Scheme("http"),      // 设置协议, 默认为https，公有云sts获取临时密钥不允许走http，特殊场景才需要设置http
-	)
-	// 策略概述 https://cloud.tencent.com/document/product/436/18023
-	opt := &sts.CredentialOptions{
-		DurationSeconds: int64(time.Hour.Seconds()),
-		Region:          c.config.Region,
-		Policy: &sts.CredentialPolicy{
-			Statement: []sts.CredentialPolicyStatement{
-				{
-					// 密钥的权限列表。简单上传和分片需要以下的权限，其他权限列表请看 https://cloud.tencent.com/document/product/436/31923
-					Action: []string{
-						// 简单上传
-						"name/cos:PostObject",
-						"name/cos:PutObject",
-						// 删除对象
-						"name/cos:DeleteObject",
-						// 查询对象
-						"name/cos:GetObject",
-						"name/cos:HeadObject",
-						// 分片上传
-						"name/cos:InitiateMultipartUpload",
-						"name/cos:ListMultipartUploads",
-						"name/cos:ListParts",
-						"name/cos:UploadPart",
-						"name/cos:CompleteMultipartUpload",
-						// 列出对象
-						"name/cos:GetBucket",
-						"name/cos:HeadBucket",
+// uploadPolicy 生成只允许上传到用户前缀下的策略，并限制文件大小和类型
+// 只开放简单上传，分片上传无法通过 condition 限制文件大小
+func (c *COSClient) uploadPolicy(userId int64, purpose UploadPurpose) (*sts.CredentialPolicy, error) {
+	rule, ok := uploadRules[purpose]
+	if !ok {
+		return nil, ErrUploadPurpose
+	}
+	// 存储桶的命名格式为 BucketName-APPID
+	i := strings.LastIndex(c.config.Bucket, "-")
+	if i <= 0 || i == len(c.config.Bucket)-1 {
+		return nil, fmt.Errorf("存储桶名称格式应为 BucketName-APPID: %s", c.config.Bucket)
+	}
+	appId := c.config.Bucket[i+1:]
+	resource := fmt.Sprintf("qcs::cos:%s:uid/%s:%s/%s*", c.config.Region, appId, c.config.Bucket, UploadPrefix(userId, purpose))
+
+	// 关于 condition 的详细设置规则和COS支持的condition类型可以参考https://cloud.tencent.com/document/product/436/71306
+	return &sts.CredentialPolicy{
+		Statement: []sts.CredentialPolicyStatement{
+			{
+				Action: []string{
+					"name/cos:PostObject",
+					"name/cos:PutObject",
+				},
+				Effect:   "allow",
+				Resource: []string{resource},
+				Condition: map[string]map[string]interface{}{
+					"numeric_less_than_equal": {
+						"cos:content-length": rule.maxSize,
 					},
-					Effect: "allow",
-					Resource: []string{
-						// 这里改成允许的路径前缀，可以根据自己网站的用户登录态判断允许上传的具体路径，例子： a.jpg 或者 a/* 或者 * (使用通配符*存在重大安全风险, 请谨慎评估使用)
-						// 存储桶的命名格式为 BucketName-APPID，此处填写的 bucket 必须为此格式
-						//"qcs::cos:" + region + ":uid/" + appid + ":" + bucket + "/exampleobject",
-						"*",
+					"string_like": {
+						"cos:content-type": rule.contentTypes,
 					},
-					// 开始构建生效条件 condition
-					// 关于 condition 的详细设置规则和COS支持的condition类型可以参考https://cloud.tencent.com/document/product/436/71306
-					//Condition: map[string]map[string]interface{}{
-					//	"ip_equal": map[string]interface{}{
-					//		"qcs:ip": []string{
-					//			"*",
-					//		},
-					//	},
-					//},
 				},
 			},
 		},
+	}, nil
+}
+
+// GetTempCredential 获取只能上传到用户某种用途前缀下的临时密钥
+func (c *COSClient) GetTempCredential(userId int64, purpose UploadPurpose) (*TempCredential, error) {
+	policy, err := c.uploadPolicy(userId, purpose)
+	if err != nil {
+		return nil, err
+	}
+	// 建议使用子账号密钥，授权遵循最小权限指引，降低使用风险。子账号密钥获取可参考https://cloud.tencent.com/document/product/598/37140
+	stsClient := sts.NewClient(c.config.AccessKey, c.config.SecretKey, nil)
+	// 策略概述 https://cloud.tencent.com/document/product/436/18023
+	opt := &sts.CredentialOptions{
+		DurationSeconds: int64(tempCredentialDuration.Seconds()),
+		Region:          c.config.Region,
+		Policy:          policy,
 	}
 
 	// 请求临时密钥
@@ -107,15 +246,89 @@ func (c *COSClient) GetTempCredential() (*TempCredential, error) {
 		return nil, err
 	}
 
+	startTime := int64(resp.StartTime)
+	if startTime == 0 {
+		startTime = time.Now().Unix()
+	}
+	expiredTime := int64(resp.ExpiredTime)
+	if expiredTime == 0 {
+		expiredTime = time.Now().Add(tempCredentialDuration).Unix()
+	}
 	return &TempCredential{
 		SecretID:     resp.Credentials.TmpSecretID,
 		SecretKey:    resp.Credentials.TmpSecretKey,
 		SessionToken: resp.Credentials.SessionToken,
-		StartTime:    time.Now().Unix(),
-		ExpiredTime:  time.Now().Add(2 * time.Hour).Unix(),
+		StartTime:    startTime,
+		ExpiredTime:  expiredTime,
+		Bucket:       c.config.Bucket,
+		Region:       c.config.Region,
+		Prefix:       UploadPrefix(userId, purpose),
 	}, nil
 }
 
+// UploadedObject 确认过的客户端直传对象
+type UploadedObject struct {
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// ConfirmUpload 确认客户端直传的对象，在把对象路径保存到业务数据之前调用
+// 对象必须在用户对应用途的前缀下，大小和类型符合限制，图片还会按文件内容校验，不符合要求的对象会被删除
+func (c *COSClient) ConfirmUpload(ctx context.Context, userId int64, purpose UploadPurpose, key string) (*UploadedObject, error) {
+	rule, ok := uploadRules[purpose]
+	if !ok {
+		return nil, ErrUploadPurpose
+	}
+	if !IsUserUploadKey(userId, purpose, key) {
+		return nil, ErrUploadKey
+	}
+
+	resp, err := c.client.Object.Head(ctx, key, nil)
+	if err != nil {
+		if cos.IsNotFoundError(err) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("check object failed: %v", err)
+	}
+	object := &UploadedObject{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+
+	reject := func(err error) (*UploadedObject, error) {
+		log.Warn("拒绝上传的文件", zap.Int64("userId", userId), zap.String("key", key),
+			zap.Int64("size", object.Size), zap.String("contentType", object.ContentType), zap.Error(err))
+		if err := c.DeleteObject(key); err != nil {
+			log.Error("删除不符合要求的文件失败", zap.String("key", key), zap.Error(err))
+		}
+		return nil, err
+	}
+	if object.Size > rule.maxSize {
+		return reject(ErrUploadTooLarge)
+	}
+	if !matchContentType(object.ContentType, rule.contentTypes) {
+		return reject(ErrUploadContentType)
+	}
+	if rule.image {
+		// 只读取文件头部按内容识别类型，防止把其他文件伪装成图片
+		getResp, err := c.client.Object.Get(ctx, key, &cos.ObjectGetOptions{Range: "bytes=0-511"})
+		if err != nil {
+			return nil, fmt.Errorf("read object failed: %v", err)
+		}
+		head, err := io.ReadAll(io.LimitReader(getResp.Body, 512))
+		getResp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read object failed: %v", err)
+		}
+		if !matchContentType(http.DetectContentType(head), rule.contentTypes) {
+			return reject(ErrUploadContentType)
+		}
+	}
+	return object, nil
+}
+
 // 创建 COS 客户端
 func NewCOSClient(config *global.CosConfig) (*COSClient, error) {
 	// 存储桶URL
@@ -240,26 +453,70 @@ func (c *COSClient) DeleteObject(key string) error {
 	return nil
 }
 
-// 批量删除文件
+// 单次批量删除的最大数量
+const cosDeleteMultiLimit = 1000
+
+// 批量删除文件，超过 1000 个时分批删除，部分删除失败时返回失败的数量
 func (c *COSClient) DeleteObjects(keys []string) error {
 	ctx := context.Background()
 
-	obs := []cos.Object{}
-	for _, key := range keys {
-		obs = append(obs, cos.Object{Key: key})
-	}
+	for start := 0; start < len(keys); start += cosDeleteMultiLimit {
+		end := min(start+cosDeleteMultiLimit, len(keys))
+		obs := []cos.Object{}
+		for _, key := range keys[start:end] {
+			obs = append(obs, cos.Object{Key: key})
+		}
+
+		opt := &cos.ObjectDeleteMultiOptions{
+			Objects: obs,
+			Quiet:   true,
+		}
 
-	opt := &cos.ObjectDeleteMultiOptions{
-		Objects: obs,
-		Quiet:   true,
+		result, _, err := c.client.Object.DeleteMulti(ctx, opt)
+		if err != nil {
+			return fmt.Errorf("batch delete objects failed: %v", err)
+		}
+		// Quiet 模式只返回删除失败的对象
+		if result != nil && len(result.Errors) > 0 {
+			return fmt.Errorf("batch delete objects failed: %d objects, first %s: %s",
+				len(result.Errors), result.Errors[0].Key, result.Errors[0].Message)
+		}
 	}
 
-	_, _, err := c.client.Object.DeleteMulti(ctx, opt)
+	return nil
+}
+
+// ObjectSummary 列出的对象
+type ObjectSummary struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// ListObjects 列出前缀下的对象，每页最多 maxKeys 个，marker 为上一页返回的 nextMarker，没有下一页时 nextMarker 为空
+func (c *COSClient) ListObjects(ctx context.Context, prefix string, marker string, maxKeys int) ([]ObjectSummary, string, error) {
+	result, _, err := c.client.Bucket.Get(ctx, &cos.BucketGetOptions{
+		Prefix:  prefix,
+		Marker:  marker,
+		MaxKeys: maxKeys,
+	})
 	if err != nil {
-		return fmt.Errorf("batch delete objects failed: %v", err)
+		return nil, "", fmt.Errorf("list objects failed: %v", err)
 	}
 
-	return nil
+	objects := make([]ObjectSummary, 0, len(result.Contents))
+	for _, object := range result.Contents {
+		lastModified, _ := time.Parse(time.RFC3339, object.LastModified)
+		objects = append(objects, ObjectSummary{Key: object.Key, Size: object.Size, LastModified: lastModified})
+	}
+	if !result.IsTruncated {
+		return objects, "", nil
+	}
+	nextMarker := result.NextMarker
+	if nextMarker == "" && len(objects) > 0 {
+		nextMarker = objects[len(objects)-1].Key
+	}
+	return objects, nextMarker, nil
 }
 
 // 检查文件是否存在
@@ -305,6 +562,19 @@ func (c *COSClient) ConvertObjectPath(path string) string {
 	return u.Path[1:]
 }
 
+// ConfirmObjectPath 将前端传来的地址转换为保存到数据库中的路径，新上传的对象需要先通过 ConfirmUpload 确认
+// oldKey 为业务数据中原来保存的路径，路径没有变化或者不是本存储桶中的对象时不再确认
+func (c *COSClient) ConfirmObjectPath(ctx context.Context, userId int64, purpose UploadPurpose, path string, oldKey string) (string, error) {
+	key := c.ConvertObjectPath(path)
+	if key == oldKey || !IsObjectKey(key) {
+		return key, nil
+	}
+	if _, err := c.ConfirmUpload(ctx, userId, purpose, key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
 // ConvertSliceFieldToPresignedURL 将切片中每个元素的指定字段转换为预签名URL
 // slice: 要处理的切片
 // fieldName: 要转换的字段名
@@ -0,0 +1,376 @@
+package utils
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"txing-ai/internal/global"
+	"txing-ai/internal/global/logging"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// fakeCOS 内存中的存储桶，只实现 HEAD、GET、DELETE、列出对象和批量删除
+type fakeCOS struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	contentType  string
+	data         []byte
+	lastModified time.Time
+}
+
+func (f *fakeCOS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	object, ok := f.objects[key]
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, r)
+		return
+	case key == "" && r.Method == http.MethodPost && r.URL.Query().Has("delete"):
+		f.deleteMulti(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodHead, http.MethodGet:
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`))
+			}
+			return
+		}
+		data := object.data
+		if r.Method == http.MethodGet && r.Header.Get("Range") == "bytes=0-511" && len(data) > 512 {
+			data = data[:512]
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", strconv.Itoa(len(object.data)))
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list 按 key 排序列出前缀下的对象，每页 max-keys 个
+func (f *fakeCOS) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix, marker := query.Get("prefix"), query.Get("marker")
+	maxKeys, _ := strconv.Atoi(query.Get("max-keys"))
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	result := cos.BucketGetResult{Prefix: prefix, Marker: marker, MaxKeys: maxKeys}
+	if len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+		result.IsTruncated = true
+		result.NextMarker = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		object := f.objects[key]
+		result.Contents = append(result.Contents, cos.Object{
+			Key:          key,
+			Size:         int64(len(object.data)),
+			LastModified: object.lastModified.UTC().Format("2006-01-02T15:04:05.000Z"),
+		})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeCOS) deleteMulti(w http.ResponseWriter, r *http.Request) {
+	var opt cos.ObjectDeleteMultiOptions
+	if err := xml.NewDecoder(r.Body).Decode(&opt); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, object := range opt.Objects {
+		delete(f.objects, object.Key)
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(`<DeleteResult></DeleteResult>`))
+}
+
+func newTestCOSClient(t *testing.T, objects map[string]fakeObject) (*COSClient, *fakeCOS) {
+	fake := &fakeCOS{objects: objects}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client, err := NewCOSClient(&global.CosConfig{
+		AccessKey: "ak",
+		SecretKey: "sk",
+		Region:    "ap-guangzhou",
+		Bucket:    "examplebucket-1250000000",
+		BaseURL:   server.URL,
+		CDNURL:    "https://cdn.example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, fake
+}
+
+// png 文件头
+var pngHead = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestUploadKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "a.png", want: "avatar/7/a.png"},
+		{name: "../../preset/1/a.png", want: "avatar/7/a.png"},
+		{name: "/etc/passwd", want: "avatar/7/passwd"},
+	}
+	for _, tt := range tests {
+		if got := UploadKey(7, UploadPurposeAvatar, tt.name); got != tt.want {
+			t.Errorf("UploadKey(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	for _, name := range []string{"", "..", "/"} {
+		got := UploadKey(7, UploadPurposeAvatar, name)
+		if !strings.HasPrefix(got, "avatar/7/") || len(got) == len("avatar/7/") || strings.Contains(got, "..") {
+			t.Errorf("UploadKey(%q) = %q", name, got)
+		}
+	}
+}
+
+func TestIsUserUploadKey(t *testing.T) {
+	tests := []struct {
+		purpose UploadPurpose
+		key     string
+		want    bool
+	}{
+		{purpose: UploadPurposeAttachment, key: "attachment/7/a.pdf", want: true},
+		{purpose: "", key: "attachment/7/a.pdf", want: true},
+		{purpose: "", key: "avatar/7/a.png", want: true},
+		{purpose: UploadPurposeAvatar, key: "attachment/7/a.pdf", want: false},
+		{purpose: "", key: "attachment/8/a.pdf", want: false},
+		{purpose: "", key: "attachment/7/", want: false},
+		{purpose: "", key: "attachment/7/../8/a.pdf", want: false},
+		{purpose: "", key: "workspace/7/a.pdf", want: false},
+		{purpose: "unknown", key: "unknown/7/a.pdf", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsUserUploadKey(7, tt.purpose, tt.key); got != tt.want {
+			t.Errorf("IsUserUploadKey(%q, %q) = %v, want %v", tt.purpose, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestCOSClient_uploadPolicy(t *testing.T) {
+	client, _ := newTestCOSClient(t, nil)
+
+	policy, err := client.uploadPolicy(7, UploadPurposeAvatar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Statement) != 1 {
+		t.Fatalf("statements = %d, want 1", len(policy.Statement))
+	}
+	statement := policy.Statement[0]
+	wantResource := "qcs::cos:ap-guangzhou:uid/1250000000:examplebucket-1250000000/avatar/7/*"
+	if len(statement.Resource) != 1 || statement.Resource[0] != wantResource {
+		t.Errorf("resource = %v, want %s", statement.Resource, wantResource)
+	}
+	for _, action := range statement.Action {
+		if action != "name/cos:PutObject" && action != "name/cos:PostObject" {
+			t.Errorf("unexpected action %s", action)
+		}
+	}
+	if got := statement.Condition["numeric_less_than_equal"]["cos:content-length"]; got != UploadPurposeAvatar.MaxSize() {
+		t.Errorf("content-length condition = %v", got)
+	}
+	if got, ok := statement.Condition["string_like"]["cos:content-type"].([]string); !ok || len(got) == 0 {
+		t.Errorf("content-type condition = %v", statement.Condition["string_like"])
+	}
+
+	if _, err := client.uploadPolicy(7, "other"); !errors.Is(err, ErrUploadPurpose) {
+		t.Errorf("unknown purpose err = %v", err)
+	}
+	client.config.Bucket = "examplebucket"
+	if _, err := client.uploadPolicy(7, UploadPurposeAvatar); err == nil {
+		t.Error("bucket without appid should fail")
+	}
+}
+
+func TestCOSClient_ConfirmUpload(t *testing.T) {
+	client, fake := newTestCOSClient(t, map[string]fakeObject{
+		"avatar/7/ok.png":      {contentType: "image/png", data: pngHead},
+		"avatar/7/fake.png":    {contentType: "image/png", data: []byte("<html><script>alert(1)</script></html>")},
+		"avatar/7/big.png":     {contentType: "image/png", data: append(append([]byte{}, pngHead...), make([]byte, 5<<20)...)},
+		"avatar/7/doc.pdf":     {contentType: "application/pdf", data: []byte("%PDF-1.4")},
+		"avatar/8/other.png":   {contentType: "image/png", data: pngHead},
+		"attachment/7/a.md":    {contentType: "text/markdown; charset=utf-8", data: []byte("# title")},
+		"attachment/7/run.exe": {contentType: "application/x-msdownload", data: []byte("MZ")},
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		purpose UploadPurpose
+		key     string
+		wantErr error
+		deleted bool
+	}{
+		{name: "图片", purpose: UploadPurposeAvatar, key: "avatar/7/ok.png"},
+		{name: "其他用户的文件", purpose: UploadPurposeAvatar, key: "avatar/8/other.png", wantErr: ErrUploadKey},
+		{name: "用途不匹配", purpose: UploadPurposePreset, key: "avatar/7/ok.png", wantErr: ErrUploadKey},
+		{name: "路径穿越", purpose: UploadPurposeAvatar, key: "avatar/7/../8/other.png", wantErr: ErrUploadKey},
+		{name: "不存在", purpose: UploadPurposeAvatar, key: "avatar/7/missing.png", wantErr: ErrUploadNotFound},
+		{name: "伪装成图片", purpose: UploadPurposeAvatar, key: "avatar/7/fake.png", wantErr: ErrUploadContentType, deleted: true},
+		{name: "文件过大", purpose: UploadPurposeAvatar, key: "avatar/7/big.png", wantErr: ErrUploadTooLarge, deleted: true},
+		{name: "类型不允许", purpose: UploadPurposeAvatar, key: "avatar/7/doc.pdf", wantErr: ErrUploadContentType, deleted: true},
+		{name: "附件", purpose: UploadPurposeAttachment, key: "attachment/7/a.md"},
+		{name: "附件类型不允许", purpose: UploadPurposeAttachment, key: "attachment/7/run.exe", wantErr: ErrUploadContentType, deleted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := client.ConfirmUpload(ctx, 7, tt.purpose, tt.key)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if !IsUploadError(err) {
+					t.Errorf("IsUploadError(%v) = false", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if object.Key != tt.key || object.Size <= 0 {
+					t.Errorf("object = %+v", object)
+				}
+			}
+			fake.mu.Lock()
+			_, exists := fake.objects[tt.key]
+			fake.mu.Unlock()
+			if tt.deleted && exists {
+				t.Error("rejected object should be deleted")
+			}
+		})
+	}
+}
+
+func TestCOSClient_ConfirmObjectPath(t *testing.T) {
+	client, _ := newTestCOSClient(t, map[string]fakeObject{
+		"avatar/7/ok.png": {contentType: "image/png", data: pngHead},
+	})
+	ctx := context.Background()
+
+	key, err := client.ConfirmObjectPath(ctx, 7, UploadPurposeAvatar, "https://cdn.example.com/avatar/7/ok.png?q-sign-algorithm=sha1", "")
+	if err != nil || key != "avatar/7/ok.png" {
+		t.Errorf("key = %q, err = %v", key, err)
+	}
+	// 没有变化的旧路径不再确认
+	key, err = client.ConfirmObjectPath(ctx, 7, UploadPurposeAvatar, "1745647348066-761.jpg", "1745647348066-761.jpg")
+	if err != nil || key != "1745647348066-761.jpg" {
+		t.Errorf("key = %q, err = %v", key, err)
+	}
+	// 外部图片地址原样保存
+	key, err = client.ConfirmObjectPath(ctx, 7, UploadPurposeAvatar, "https://example.com/a.png", "")
+	if err != nil || key != "https://example.com/a.png" {
+		t.Errorf("key = %q, err = %v", key, err)
+	}
+	if _, err := client.ConfirmObjectPath(ctx, 7, UploadPurposeAvatar, "other.png", ""); !errors.Is(err, ErrUploadKey) {
+		t.Errorf("err = %v, want %v", err, ErrUploadKey)
+	}
+}
+
+func TestCOSClient_ListObjects(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	objects := map[string]fakeObject{
+		"preset/1/a.png": {data: pngHead, lastModified: modified},
+	}
+	for i := 0; i < 5; i++ {
+		objects[fmt.Sprintf("avatar/%d/a.png", i)] = fakeObject{data: pngHead, lastModified: modified}
+	}
+	client, _ := newTestCOSClient(t, objects)
+	ctx := context.Background()
+
+	var keys []string
+	marker := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("too many pages")
+		}
+		page, next, err := client.ListObjects(ctx, "avatar/", marker, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, object := range page {
+			if !object.LastModified.Equal(modified) || object.Size != int64(len(pngHead)) {
+				t.Errorf("object = %+v", object)
+			}
+			keys = append(keys, object.Key)
+		}
+		if next == "" {
+			break
+		}
+		marker = next
+	}
+	if len(keys) != 5 {
+		t.Errorf("keys = %v, want 5 avatar objects", keys)
+	}
+}
+
+func TestCOSClient_DeleteObjects(t *testing.T) {
+	objects := map[string]fakeObject{}
+	var keys []string
+	// 超过单次批量删除的上限
+	for i := 0; i < cosDeleteMultiLimit+5; i++ {
+		key := fmt.Sprintf("attachment/1/%d.md", i)
+		objects[key] = fakeObject{data: []byte("# title")}
+		if i != 0 {
+			keys = append(keys, key)
+		}
+	}
+	client, fake := newTestCOSClient(t, objects)
+
+	if err := client.DeleteObjects(keys); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteObjects(nil); err != nil {
+		t.Fatal(err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.objects) != 1 {
+		t.Errorf("objects left = %d, want 1", len(fake.objects))
+	}
+}
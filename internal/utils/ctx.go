@@ -60,6 +60,26 @@ func GetMessageLimiterFromContext[T any](ctx context.Context) T {
 	return GetFromContext[T](ctx, "messageLimiter")
 }
 
+// GetApprovalManagerFromContext 获取工具调用审批管理器
+func GetApprovalManagerFromContext[T any](ctx context.Context) T {
+	return GetFromContext[T](ctx, "approvalManager")
+}
+
+// GetMCPClientManagerFromContext 获取MCP客户端管理器
+func GetMCPClientManagerFromContext[T any](ctx context.Context) T {
+	return GetFromContext[T](ctx, "mcpClientManager")
+}
+
+// GetBlobStoresFromContext 获取文件存储
+func GetBlobStoresFromContext[T any](ctx context.Context) T {
+	return GetFromContext[T](ctx, "blobStores")
+}
+
+// GetKnowledgeFromContext 获取知识库服务
+func GetKnowledgeFromContext[T any](ctx context.Context) T {
+	return GetFromContext[T](ctx, "knowledge")
+}
+
 // GetRoleFromContext 获取角色
 func GetRoleFromContext(ctx context.Context) int8 {
 	return GetFromContext[int8](ctx, "role")
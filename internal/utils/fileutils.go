@@ -6,9 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 	"txing-ai/internal/global"
 	"txing-ai/internal/global/logging/log"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -44,11 +47,13 @@ func SaveUploadedFile(file io.ReadCloser, fileName string, userId int64, customD
 	// 构建文件目录路径
 	fileDir := filepath.Join(localUploadConfig.Dir, strconv.FormatInt(userId, 10), dirName)
 
-	// 确定文件名
+	// 确定文件名，客户端给出的文件名可能包含路径等字符，需要先清理
 	finalFileName := customFileName
 	if finalFileName == "" {
 		// 生成唯一文件名（时间戳+原始文件名）
-		finalFileName = fmt.Sprintf("%d_%s", time.Now().UnixNano(), fileName)
+		finalFileName = fmt.Sprintf("%d_%s", time.Now().UnixNano(), SanitizeFilename(fileName))
+	} else {
+		finalFileName = SanitizeFilename(finalFileName)
 	}
 
 	// 构建相对路径和绝对路径
@@ -122,3 +127,43 @@ func SaveUploadedFileFromRequest(c *gin.Context, formName string, userId int64,
 
 	return SaveUploadedFile(file, header.Filename, userId, customDir, customFileName)
 }
+
+// 文件名的最大字节数，超出时截断主文件名，保留扩展名
+const maxFilenameBytes = 200
+
+// SanitizeFilename 清理客户端给出的文件名，只保留最后一段文件名，去掉控制字符和文件系统保留的字符
+// 清理后为空时返回 file
+func SanitizeFilename(name string) string {
+	// 兼容 Windows 客户端上传的完整路径
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError, unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`<>:"|?*`, r):
+			return '_'
+		}
+		return r
+	}, name)
+	// 去掉首尾的空格和点，避免出现 .. 或者隐藏文件
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return "file"
+	}
+
+	if len(name) > maxFilenameBytes {
+		ext := filepath.Ext(name)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		base := name[:len(name)-len(ext)]
+		limit := maxFilenameBytes - len(ext)
+		for limit > 0 && !utf8.RuneStart(base[limit]) {
+			limit--
+		}
+		name = base[:limit] + ext
+	}
+	return name
+}
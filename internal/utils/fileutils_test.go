@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "简历.pdf", want: "简历.pdf"},
+		{name: "../../etc/passwd", want: "passwd"},
+		{name: `C:\Users\me\简历.pdf`, want: "简历.pdf"},
+		{name: "a<b>:c|d?.txt", want: "a_b__c_d_.txt"},
+		{name: "a\x00b\nc.txt", want: "abc.txt"},
+		{name: "..", want: "file"},
+		{name: " .hidden ", want: "hidden"},
+		{name: "", want: "file"},
+		{name: "dir/", want: "file"},
+		{name: "bad\xffname.pdf", want: "badname.pdf"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.name); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// 过长的文件名截断后保留扩展名，不会截断半个字符
+	long := SanitizeFilename(strings.Repeat("简", 100) + ".pdf")
+	if len(long) > maxFilenameBytes || !strings.HasSuffix(long, ".pdf") || !utf8.ValidString(long) {
+		t.Errorf("long name = %q (%d bytes)", long, len(long))
+	}
+}
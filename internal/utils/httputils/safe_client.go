@@ -0,0 +1,97 @@
+package httputils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress 目标地址是内网、回环、链路本地等不允许访问的地址
+var ErrForbiddenAddress = errors.New("不允许访问内网地址")
+
+// 最多跟随的重定向次数
+const maxRedirects = 10
+
+// forbiddenPrefixes 除标准库可以判断的私有、回环、链路本地地址以外不允许访问的网段
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),  // 运营商级 NAT，部分云厂商的元数据地址在此网段
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF 协议分配
+	netip.MustParsePrefix("198.18.0.0/15"),  // 基准测试
+	netip.MustParsePrefix("240.0.0.0/4"),    // 保留地址
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64，可以映射到任意 IPv4 地址
+	netip.MustParsePrefix("64:ff9b:1::/48"), // 本地 NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4，可以映射到任意 IPv4 地址
+	netip.MustParsePrefix("fec0::/10"),      // 已废弃的站点本地地址
+	netip.MustParsePrefix("100::/64"),       // 丢弃地址
+	netip.MustParsePrefix("2001:db8::/32"),  // 文档地址
+}
+
+// IsForbiddenIP 判断是否是不允许服务端主动访问的地址，包括私有、回环、链路本地（含云厂商元数据地址）、组播和保留地址
+func IsForbiddenIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// SafeDialControl 拨号前检查 DNS 解析后的实际地址，拒绝访问内网地址
+// 每次建立连接都会检查，重定向以及 DNS 重绑定后的地址同样会被拒绝
+func SafeDialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if IsForbiddenIP(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// NewSafeTransport 创建只允许访问公网地址的 Transport，用于抓取用户或模型给出的地址
+// 不使用环境变量中的代理，代理会替我们解析并连接目标地址，拨号检查就失去了作用
+func NewSafeTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   SafeDialControl,
+	}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// NewSafeClient 创建只允许访问公网地址的 HTTP 客户端，重定向只允许跳转到 http(s) 地址，跳转后的地址同样经过拨号检查
+func NewSafeClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     NewSafeTransport(),
+		CheckRedirect: checkSafeRedirect,
+	}
+}
+
+func checkSafeRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("重定向次数超过 %d 次", maxRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("不允许重定向到 %s 地址", req.URL.Scheme)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package httputils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+)
+
+func TestIsForbiddenIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"fd00:ec2::254", true},
+		{"fe80::1", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"224.0.0.1", true},
+		{"8.8.8.8", false},
+		{"110.242.68.66", false},
+		{"2400:3200::1", false},
+	}
+	for _, tt := range tests {
+		if got := IsForbiddenIP(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("IsForbiddenIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestSafeClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewSafeClient(0)
+	port := strconv.Itoa(server.Listener.Addr().(*net.TCPAddr).Port)
+	for _, rawURL := range []string{server.URL, "http://localhost:" + port} {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, rawURL, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			t.Fatalf("request to %s should be rejected", rawURL)
+		}
+		if !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("request to %s error = %v, want ErrForbiddenAddress", rawURL, err)
+		}
+	}
+
+	// 只允许重定向到 http(s) 地址，跳转后的内网地址在拨号时被拒绝
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	redirect, _ := http.NewRequest(http.MethodGet, "file:///etc/passwd", nil)
+	if err := client.CheckRedirect(redirect, []*http.Request{req}); err == nil {
+		t.Error("redirect to file scheme should be rejected")
+	}
+}
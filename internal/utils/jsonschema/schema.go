@@ -0,0 +1,377 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"txing-ai/internal/global"
+)
+
+// Schema JSON Schema 的常用子集，用于约束大模型的结构化输出
+// 支持 type、properties、required、additionalProperties、items、enum、
+// minLength/maxLength、minimum/maximum、minItems/maxItems、pattern
+type Schema struct {
+	Type                 interface{}        `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+
+	// 原始 schema 内容，透传给支持 JSON Schema 的模型提供商
+	raw json.RawMessage
+}
+
+// ValidationError 校验失败时返回的错误，包含所有不满足约束的字段
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return "JSON Schema 校验失败：" + strings.Join(e.Errors, "；")
+}
+
+var validTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// Parse 解析 JSON Schema
+func Parse(raw []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UnmarshalJSON 解析时保留原始 schema 内容
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type alias Schema
+	if err := json.Unmarshal(data, (*alias)(s)); err != nil {
+		return fmt.Errorf("invalid json schema: %w", err)
+	}
+	s.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// MustParse 解析 JSON Schema，失败时 panic，用于内置的 schema 常量
+func MustParse(raw string) *Schema {
+	s, err := Parse([]byte(raw))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Raw 返回原始 schema 内容
+func (s *Schema) Raw() json.RawMessage {
+	return s.raw
+}
+
+// MarshalJSON 优先输出原始 schema，保留未解析的关键字
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if len(s.raw) > 0 {
+		return s.raw, nil
+	}
+	type alias Schema
+	return json.Marshal((*alias)(s))
+}
+
+// Check 检查 schema 自身是否合法
+func (s *Schema) Check() error {
+	return s.check("$")
+}
+
+func (s *Schema) check(path string) error {
+	for _, t := range s.types() {
+		if !validTypes[t] {
+			return fmt.Errorf("invalid json schema: %s 不支持的类型 %q", path, t)
+		}
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("invalid json schema: %s pattern 不合法: %w", path, err)
+		}
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			continue
+		}
+		if err := prop.check(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check(path + "[]")
+	}
+	return nil
+}
+
+// 获取 type 字段，兼容字符串和数组两种写法
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if str, ok := item.(string); ok {
+				types = append(types, str)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// Validate 校验已解码的 JSON 值（encoding/json 解码得到的 map、slice 等）
+func (s *Schema) Validate(value interface{}) error {
+	var errs []string
+	s.validate("$", value, &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// ValidateJSON 校验 JSON 文本，返回解码后的值
+func (s *Schema) ValidateJSON(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, &ValidationError{Errors: []string{"不是合法的 JSON：" + err.Error()}}
+	}
+	if err := s.Validate(value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (s *Schema) validate(path string, value interface{}, errs *[]string) {
+	if types := s.types(); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*errs = append(*errs, fmt.Sprintf("%s 类型应为 %s，实际为 %s", path, strings.Join(types, "|"), typeOf(value)))
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			enum, _ := json.Marshal(s.Enum)
+			*errs = append(*errs, fmt.Sprintf("%s 取值必须是 %s 之一", path, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.validateObject(path, v, errs)
+	case []interface{}:
+		s.validateArray(path, v, errs)
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			*errs = append(*errs, fmt.Sprintf("%s 长度不能小于 %d", path, *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			*errs = append(*errs, fmt.Sprintf("%s 长度不能大于 %d", path, *s.MaxLength))
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(v) {
+				*errs = append(*errs, fmt.Sprintf("%s 不匹配正则 %s", path, s.Pattern))
+			}
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			*errs = append(*errs, fmt.Sprintf("%s 不能小于 %v", path, *s.Minimum))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			*errs = append(*errs, fmt.Sprintf("%s 不能大于 %v", path, *s.Maximum))
+		}
+	}
+}
+
+func (s *Schema) validateObject(path string, obj map[string]interface{}, errs *[]string) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, fmt.Sprintf("%s 缺少必填字段 %s", path, name))
+		}
+	}
+
+	// 按字段名排序，保证错误信息稳定
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*errs = append(*errs, fmt.Sprintf("%s 不允许出现字段 %s", path, name))
+			}
+			continue
+		}
+		if prop != nil {
+			prop.validate(path+"."+name, obj[name], errs)
+		}
+	}
+}
+
+func (s *Schema) validateArray(path string, arr []interface{}, errs *[]string) {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		*errs = append(*errs, fmt.Sprintf("%s 元素个数不能少于 %d", path, *s.MinItems))
+	}
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		*errs = append(*errs, fmt.Sprintf("%s 元素个数不能多于 %d", path, *s.MaxItems))
+	}
+	if s.Items == nil {
+		return
+	}
+	for i, item := range arr {
+		s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+	}
+}
+
+func matchType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		v, ok := value.(float64)
+		return ok && v == math.Trunc(v)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func equal(a, b interface{}) bool {
+	ja, err1 := json.Marshal(a)
+	jb, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(ja) == string(jb)
+}
+
+// ErrNoJSON 模型输出中找不到 JSON
+var ErrNoJSON = errors.New("输出中没有找到 JSON 内容")
+
+// ExtractJSON 从模型输出中提取 JSON 文本，兼容 ```json 代码块以及前后带有说明文字的情况
+func ExtractJSON(text string) (string, error) {
+	text = strings.TrimSpace(text)
+
+	// 去掉 markdown 代码块
+	if start := strings.Index(text, "```"); start >= 0 {
+		rest := text[start+3:]
+		if nl := strings.Index(rest, "\n"); nl >= 0 {
+			rest = rest[nl+1:]
+		}
+		if end := strings.LastIndex(rest, "```"); end >= 0 {
+			rest = rest[:end]
+		}
+		text = strings.TrimSpace(rest)
+	}
+
+	if json.Valid([]byte(text)) {
+		return text, nil
+	}
+
+	// 截取第一个 { 或 [ 到最后一个 } 或 ] 之间的内容
+	start := strings.IndexAny(text, "{[")
+	end := strings.LastIndexAny(text, "}]")
+	if start < 0 || end <= start {
+		return "", ErrNoJSON
+	}
+	return text[start : end+1], nil
+}
+
+// ParseOutput 从模型输出中提取 JSON 并校验，返回解码后的值
+func (s *Schema) ParseOutput(content string) (interface{}, error) {
+	text, err := ExtractJSON(content)
+	if err != nil {
+		return nil, &ValidationError{Errors: []string{err.Error()}}
+	}
+	return s.ValidateJSON([]byte(text))
+}
+
+// Instruction 生成要求模型按 schema 输出的提示词
+func (s *Schema) Instruction() string {
+	schema, _ := json.Marshal(s)
+	return fmt.Sprintf("请严格按照以下 JSON Schema 输出最终结果，只输出 JSON 本身，不要包含任何解释说明或 markdown 代码块：\n%s", schema)
+}
+
+// RepairInstruction 生成校验失败后要求模型修正输出的提示词
+func (s *Schema) RepairInstruction(err error) string {
+	return fmt.Sprintf("你上一次的输出不符合要求：%s\n请修正后重新输出完整结果。%s", err.Error(), s.Instruction())
+}
+
+// 默认最多修正次数
+const defaultMaxRepairAttempts = 2
+
+// MaxRepairAttempts 输出不符合 schema 时最多要求模型修正的次数
+func MaxRepairAttempts() int {
+	conf := global.LoadConfig().StructuredOutputConfig
+	if conf == nil || conf.MaxRepairAttempts < 0 {
+		return defaultMaxRepairAttempts
+	}
+	return conf.MaxRepairAttempts
+}
@@ -0,0 +1,144 @@
+package jsonschema
+
+import (
+	"errors"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"content": {"type": "string", "minLength": 1},
+		"score": {"type": "integer", "minimum": 0, "maximum": 100},
+		"level": {"type": "string", "enum": ["low", "high"]},
+		"files": {
+			"type": "array",
+			"maxItems": 2,
+			"items": {
+				"type": "object",
+				"properties": {"name": {"type": "string", "pattern": "\\.pdf$"}},
+				"required": ["name"]
+			}
+		}
+	},
+	"required": ["content", "files"],
+	"additionalProperties": false
+}`
+
+func TestSchema_ValidateJSON(t *testing.T) {
+	schema := MustParse(testSchema)
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:    "合法输出",
+			input:   `{"content":"ok","score":90,"level":"high","files":[{"name":"a.pdf"}]}`,
+			wantErr: false,
+		},
+		{
+			name:    "缺少必填字段",
+			input:   `{"content":"ok"}`,
+			wantErr: true,
+		},
+		{
+			name:    "类型错误",
+			input:   `{"content":"ok","score":"90","files":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "整数校验",
+			input:   `{"content":"ok","score":1.5,"files":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "枚举校验",
+			input:   `{"content":"ok","level":"mid","files":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "不允许额外字段",
+			input:   `{"content":"ok","files":[],"extra":1}`,
+			wantErr: true,
+		},
+		{
+			name:    "数组元素校验",
+			input:   `{"content":"ok","files":[{"name":"a.txt"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "数组长度校验",
+			input:   `{"content":"ok","files":[{"name":"a.pdf"},{"name":"b.pdf"},{"name":"c.pdf"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "非法 JSON",
+			input:   `{"content":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := schema.ValidateJSON([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var validationErr *ValidationError
+			if err != nil && !errors.As(err, &validationErr) {
+				t.Errorf("ValidateJSON() error type = %T, want *ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "合法 schema", input: `{"type":["string","null"]}`, wantErr: false},
+		{name: "非法 JSON", input: `{"type":`, wantErr: true},
+		{name: "不支持的类型", input: `{"type":"date"}`, wantErr: true},
+		{name: "非法正则", input: `{"type":"object","properties":{"a":{"type":"string","pattern":"("}}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "纯 JSON", input: `{"a":1}`, want: `{"a":1}`},
+		{name: "代码块", input: "```json\n{\"a\":1}\n```", want: `{"a":1}`},
+		{name: "前后有说明文字", input: "结果如下：{\"a\":1} 以上", want: `{"a":1}`},
+		{name: "没有 JSON", input: "没有结果", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractJSON(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExtractJSON() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ExtractJSON() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package utils
+
+// secretMask 脱敏后的占位字符
+const secretMask = "******"
+
+// MaskSecret 脱敏密钥，较长的密钥保留前 3 位和后 4 位，便于管理员辨认
+func MaskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	runes := []rune(secret)
+	if len(runes) <= 12 {
+		return secretMask
+	}
+	return string(runes[:3]) + secretMask + string(runes[len(runes)-4:])
+}
+
+// MaskSecrets 脱敏 map 中所有的值
+func MaskSecrets(secrets map[string]string) map[string]string {
+	if secrets == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(secrets))
+	for key, value := range secrets {
+		masked[key] = MaskSecret(value)
+	}
+	return masked
+}
+
+// UnmaskSecret 提交的值与原值脱敏后的结果相同时，说明没有修改，返回原值
+func UnmaskSecret(submitted, original string) string {
+	if submitted != "" && submitted == MaskSecret(original) {
+		return original
+	}
+	return submitted
+}
+
+// UnmaskSecrets 按 key 还原 map 中未修改的脱敏值
+func UnmaskSecrets(submitted, original map[string]string) map[string]string {
+	if submitted == nil {
+		return nil
+	}
+	unmasked := make(map[string]string, len(submitted))
+	for key, value := range submitted {
+		unmasked[key] = UnmaskSecret(value, original[key])
+	}
+	return unmasked
+}
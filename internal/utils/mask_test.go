@@ -0,0 +1,28 @@
+package utils
+
+import "testing"
+
+func TestUnmaskSecret(t *testing.T) {
+	tests := []struct {
+		name      string
+		original  string
+		submitted string
+		want      string
+	}{
+		{name: "未修改的长密钥", original: "sk-1234567890abcdef", submitted: "sk-******cdef", want: "sk-1234567890abcdef"},
+		{name: "未修改的短密钥", original: "secret", submitted: "******", want: "secret"},
+		{name: "修改为新值", original: "secret", submitted: "new-secret", want: "new-secret"},
+		{name: "清空", original: "secret", submitted: "", want: ""},
+		{name: "原值为空", original: "", submitted: "******", want: "******"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskSecret(tt.original); tt.original != "" && got == tt.original {
+				t.Errorf("MaskSecret(%q) should not return original", tt.original)
+			}
+			if got := UnmaskSecret(tt.submitted, tt.original); got != tt.want {
+				t.Errorf("UnmaskSecret(%q, %q) = %q, want %q", tt.submitted, tt.original, got, tt.want)
+			}
+		})
+	}
+}
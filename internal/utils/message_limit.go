@@ -16,12 +16,14 @@ const (
 	// 其他业务类型可以在这里添加，例如：
 	BusinessTypeChat   = "chat"   // 聊天消息
 	BusinessTypeResume = "resume" // 简历优化功能
+	BusinessTypeTool   = "tool"   // MCP 客户端直接调用内置工具
 )
 
 // 业务限制配置
 var BusinessUseLimits = map[string]int{
-	BusinessTypeChat:   5, // 聊天消息每日限制
-	BusinessTypeResume: 2, // 智能代理每日限制
+	BusinessTypeChat:   5,  // 聊天消息每日限制
+	BusinessTypeResume: 2,  // 智能代理每日限制
+	BusinessTypeTool:   50, // 工具调用每日限制
 }
 
 const (
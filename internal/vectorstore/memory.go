@@ -0,0 +1,209 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Memory 内存中的暴力检索索引，第一次检索某个知识库时通过 Loader 从数据库加载全部记录
+// 向量在写入时归一化，检索时计算点积；单个知识库几万条记录以内检索耗时在毫秒级
+// 每次检索前通过 Versioner 检查数据库中的版本，其他副本写入或删除记录后重新加载，没有 Versioner 时只适用于单副本部署
+type Memory struct {
+	loader    Loader
+	versioner Versioner
+	// 加载和写入都持有写锁，保证加载过程中写入的记录不会丢失
+	mu    sync.RWMutex
+	parts map[int64]*partition
+}
+
+// partition 一个知识库的全部记录
+type partition struct {
+	// 加载时数据库中的版本
+	version string
+	dim     int
+	ids     []int64
+	docs    []int64
+	vectors [][]float32
+	// 记录 ID 在切片中的位置
+	index map[int64]int
+}
+
+// NewMemory 创建内存索引，loader 为 nil 时只包含写入的记录，versioner 为 nil 时加载后不再检查数据库中的变化
+func NewMemory(loader Loader, versioner Versioner) *Memory {
+	return &Memory{loader: loader, versioner: versioner, parts: map[int64]*partition{}}
+}
+
+func newPartition() *partition {
+	return &partition{index: map[int64]int{}}
+}
+
+func (p *partition) upsert(record Record) error {
+	if len(record.Vector) == 0 {
+		return nil
+	}
+	if p.dim == 0 {
+		p.dim = len(record.Vector)
+	} else if len(record.Vector) != p.dim {
+		return fmt.Errorf("%w: 知识库 %d 为 %d 维，记录 %d 为 %d 维", ErrDimensionMismatch, record.KnowledgeBaseID, p.dim, record.ID, len(record.Vector))
+	}
+	vector := Normalize(record.Vector)
+	if i, ok := p.index[record.ID]; ok {
+		p.docs[i] = record.DocumentID
+		p.vectors[i] = vector
+		return nil
+	}
+	p.index[record.ID] = len(p.ids)
+	p.ids = append(p.ids, record.ID)
+	p.docs = append(p.docs, record.DocumentID)
+	p.vectors = append(p.vectors, vector)
+	return nil
+}
+
+// removeIf 删除满足条件的记录
+func (p *partition) removeIf(match func(i int) bool) {
+	n := 0
+	for i := range p.ids {
+		if match(i) {
+			continue
+		}
+		p.ids[n], p.docs[n], p.vectors[n] = p.ids[i], p.docs[i], p.vectors[i]
+		n++
+	}
+	clear(p.ids[n:])
+	clear(p.vectors[n:])
+	p.ids, p.docs, p.vectors = p.ids[:n], p.docs[:n], p.vectors[:n]
+	p.index = make(map[int64]int, n)
+	for i, id := range p.ids {
+		p.index[id] = i
+	}
+}
+
+// load 加载知识库的记录，已经加载并且版本没有变化时直接返回，调用方持有写锁
+// version 在加载记录之前获取，加载过程中发生的变化会在下一次检索时发现
+func (m *Memory) load(ctx context.Context, knowledgeBaseId int64, version string) (*partition, error) {
+	if part, ok := m.parts[knowledgeBaseId]; ok && part.version == version {
+		return part, nil
+	}
+	part := newPartition()
+	part.version = version
+	if m.loader != nil {
+		records, err := m.loader(ctx, knowledgeBaseId)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if err := part.upsert(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+	m.parts[knowledgeBaseId] = part
+	return part, nil
+}
+
+// Upsert 写入记录，还没有加载的知识库不写入，加载时会从数据库读到这些记录
+// 写入后数据库中的版本发生变化，配置了 Versioner 时下一次检索会重新加载一次
+func (m *Memory) Upsert(ctx context.Context, records []Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, record := range records {
+		part, ok := m.parts[record.KnowledgeBaseID]
+		if !ok {
+			if m.loader != nil {
+				continue
+			}
+			part = newPartition()
+			m.parts[record.KnowledgeBaseID] = part
+		}
+		if err := part.upsert(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Search(ctx context.Context, knowledgeBaseIds []int64, vector []float32, topK int) ([]Hit, error) {
+	if topK <= 0 || len(vector) == 0 {
+		return nil, nil
+	}
+	versions, err := m.versions(ctx, knowledgeBaseIds)
+	if err != nil {
+		return nil, err
+	}
+	parts := make(map[int64]*partition, len(knowledgeBaseIds))
+	m.mu.RLock()
+	for _, id := range knowledgeBaseIds {
+		if part, ok := m.parts[id]; ok && part.version == versions[id] {
+			parts[id] = part
+		}
+	}
+	m.mu.RUnlock()
+	if len(parts) < len(knowledgeBaseIds) {
+		m.mu.Lock()
+		for _, id := range knowledgeBaseIds {
+			part, err := m.load(ctx, id, versions[id])
+			if err != nil {
+				m.mu.Unlock()
+				return nil, err
+			}
+			parts[id] = part
+		}
+		m.mu.Unlock()
+	}
+
+	query := Normalize(vector)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var hits []Hit
+	for id, part := range parts {
+		if part.dim == 0 {
+			continue
+		}
+		if part.dim != len(query) {
+			return nil, fmt.Errorf("%w: 知识库 %d 为 %d 维，查询为 %d 维", ErrDimensionMismatch, id, part.dim, len(query))
+		}
+		for i, v := range part.vectors {
+			hits = append(hits, Hit{ID: part.ids[i], KnowledgeBaseID: id, DocumentID: part.docs[i], Score: dot(query, v)})
+		}
+		// 累积的结果过多时先截断，避免检索多个大知识库时占用过多内存
+		if len(hits) > 4*topK {
+			hits = topHits(hits, topK)
+		}
+	}
+	return topHits(hits, topK), nil
+}
+
+// versions 获取知识库在数据库中的当前版本，没有 Versioner 时都为空
+func (m *Memory) versions(ctx context.Context, knowledgeBaseIds []int64) (map[int64]string, error) {
+	versions := make(map[int64]string, len(knowledgeBaseIds))
+	if m.versioner == nil {
+		return versions, nil
+	}
+	for _, id := range knowledgeBaseIds {
+		version, err := m.versioner(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		versions[id] = version
+	}
+	return versions, nil
+}
+
+func (m *Memory) DeleteDocument(ctx context.Context, knowledgeBaseId int64, documentId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if part, ok := m.parts[knowledgeBaseId]; ok {
+		part.removeIf(func(i int) bool { return part.docs[i] == documentId })
+	}
+	return nil
+}
+
+func (m *Memory) DeleteKnowledgeBase(ctx context.Context, knowledgeBaseId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.parts, knowledgeBaseId)
+	return nil
+}
+
+var _ Store = (*Memory)(nil)
@@ -0,0 +1,132 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	vector := []float32{0, 1.5, -2.25, 3e-8}
+	got, err := Decode(Encode(vector))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	for i := range vector {
+		if got[i] != vector[i] {
+			t.Fatalf("Decode() = %v, want %v", got, vector)
+		}
+	}
+	if _, err := Decode([]byte{1, 2, 3}); err == nil {
+		t.Error("Decode() error = nil, want error")
+	}
+}
+
+func TestMemory_Search(t *testing.T) {
+	ctx := context.Background()
+	loads := 0
+	store := NewMemory(func(ctx context.Context, knowledgeBaseId int64) ([]Record, error) {
+		loads++
+		if knowledgeBaseId != 1 {
+			return nil, nil
+		}
+		return []Record{
+			{ID: 1, KnowledgeBaseID: 1, DocumentID: 10, Vector: []float32{1, 0, 0}},
+			{ID: 2, KnowledgeBaseID: 1, DocumentID: 10, Vector: []float32{0.8, 0.6, 0}},
+			{ID: 3, KnowledgeBaseID: 1, DocumentID: 11, Vector: []float32{0, 0, 2}},
+		}, nil
+	}, nil)
+
+	// 没有加载的知识库不写入，加载时从 Loader 读取
+	if err := store.Upsert(ctx, []Record{{ID: 9, KnowledgeBaseID: 1, DocumentID: 10, Vector: []float32{1, 0, 0}}}); err != nil {
+		t.Fatal(err)
+	}
+	hits, err := store.Search(ctx, []int64{1, 2}, []float32{2, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(hits) != 2 || hits[0].ID != 1 || hits[1].ID != 2 || hits[0].Score < 0.999 || hits[1].Score < 0.799 || hits[1].Score > 0.801 {
+		t.Errorf("Search() = %+v", hits)
+	}
+	if loads != 2 {
+		t.Errorf("loads = %d, want 2", loads)
+	}
+
+	// 已经加载的知识库直接写入
+	if err := store.Upsert(ctx, []Record{{ID: 4, KnowledgeBaseID: 2, DocumentID: 20, Vector: []float32{0, 0, 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	hits, _ = store.Search(ctx, []int64{1, 2}, []float32{0, 0, 1}, 5)
+	if len(hits) != 4 || hits[0].ID != 3 || hits[1].ID != 4 || loads != 2 {
+		t.Errorf("Search() = %+v, loads = %d", hits, loads)
+	}
+
+	if err := store.DeleteDocument(ctx, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	hits, _ = store.Search(ctx, []int64{1}, []float32{1, 0, 0}, 5)
+	if len(hits) != 1 || hits[0].ID != 3 {
+		t.Errorf("删除文档后 Search() = %+v", hits)
+	}
+
+	if _, err := store.Search(ctx, []int64{1}, []float32{1, 0}, 5); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Search() error = %v, want ErrDimensionMismatch", err)
+	}
+	if err := store.Upsert(ctx, []Record{{ID: 5, KnowledgeBaseID: 2, Vector: []float32{1}}}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Upsert() error = %v, want ErrDimensionMismatch", err)
+	}
+
+	if err := store.DeleteKnowledgeBase(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	store.Search(ctx, []int64{1}, []float32{1, 0, 0}, 5)
+	if loads != 3 {
+		t.Errorf("删除知识库后没有重新加载, loads = %d", loads)
+	}
+}
+
+func TestMemory_SearchReloadsOnVersionChange(t *testing.T) {
+	ctx := context.Background()
+	// 模拟数据库，其他副本写入记录后版本变化
+	records := []Record{{ID: 1, KnowledgeBaseID: 1, DocumentID: 10, Vector: []float32{1, 0}}}
+	version, loads := "1-1", 0
+	store := NewMemory(func(ctx context.Context, knowledgeBaseId int64) ([]Record, error) {
+		loads++
+		return append([]Record(nil), records...), nil
+	}, func(ctx context.Context, knowledgeBaseId int64) (string, error) {
+		return version, nil
+	})
+
+	if hits, _ := store.Search(ctx, []int64{1}, []float32{0, 1}, 5); len(hits) != 1 || loads != 1 {
+		t.Fatalf("Search() = %+v, loads = %d", hits, loads)
+	}
+	// 版本没有变化时使用已经加载的记录
+	if hits, _ := store.Search(ctx, []int64{1}, []float32{0, 1}, 5); len(hits) != 1 || loads != 1 {
+		t.Fatalf("Search() = %+v, loads = %d", hits, loads)
+	}
+
+	records = append(records, Record{ID: 2, KnowledgeBaseID: 1, DocumentID: 11, Vector: []float32{0, 1}})
+	version = "2-2"
+	hits, err := store.Search(ctx, []int64{1}, []float32{0, 1}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].ID != 2 || loads != 2 {
+		t.Errorf("其他副本写入后 Search() = %+v, loads = %d", hits, loads)
+	}
+
+	records = records[1:]
+	version = "1-2"
+	if hits, _ := store.Search(ctx, []int64{1}, []float32{1, 0}, 5); len(hits) != 1 || hits[0].ID != 2 || loads != 3 {
+		t.Errorf("其他副本删除后 Search() = %+v, loads = %d", hits, loads)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("", nil, nil); err != nil {
+		t.Errorf("New(\"\") error = %v", err)
+	}
+	if _, err := New("unknown", nil, nil); err == nil {
+		t.Error("New(\"unknown\") error = nil, want error")
+	}
+}
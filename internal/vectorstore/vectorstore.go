@@ -0,0 +1,139 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 向量索引的后端名称
+const (
+	BackendMemory = "memory"
+)
+
+var ErrDimensionMismatch = errors.New("向量维度不一致")
+
+// Record 向量索引中的一条记录，ID 为知识库文本块的 ID
+type Record struct {
+	ID              int64
+	KnowledgeBaseID int64
+	DocumentID      int64
+	Vector          []float32
+}
+
+// Hit 检索结果
+type Hit struct {
+	ID              int64
+	KnowledgeBaseID int64
+	DocumentID      int64
+	// 余弦相似度，越大越相关
+	Score float32
+}
+
+// Store 向量索引，按知识库分区，外部向量数据库实现这个接口后通过 Register 注册
+type Store interface {
+	// Upsert 写入或覆盖记录
+	Upsert(ctx context.Context, records []Record) error
+	// Search 在指定的知识库中检索与 vector 最相似的 topK 条记录，按相似度从高到低排列
+	Search(ctx context.Context, knowledgeBaseIds []int64, vector []float32, topK int) ([]Hit, error)
+	// DeleteDocument 删除文档的所有记录
+	DeleteDocument(ctx context.Context, knowledgeBaseId int64, documentId int64) error
+	// DeleteKnowledgeBase 删除知识库的所有记录
+	DeleteKnowledgeBase(ctx context.Context, knowledgeBaseId int64) error
+}
+
+// Loader 从数据库加载知识库的全部记录，内存索引第一次检索某个知识库时调用
+type Loader func(ctx context.Context, knowledgeBaseId int64) ([]Record, error)
+
+// Versioner 获取数据库中知识库记录的版本，记录增删后版本发生变化
+// 多副本部署时内存索引检索前据此发现其他副本写入或删除的记录，版本变化后重新加载
+type Versioner func(ctx context.Context, knowledgeBaseId int64) (string, error)
+
+// Factory 创建向量索引
+type Factory func(loader Loader, versioner Versioner) (Store, error)
+
+var factories = map[string]Factory{
+	BackendMemory: func(loader Loader, versioner Versioner) (Store, error) {
+		return NewMemory(loader, versioner), nil
+	},
+}
+
+// Register 注册向量索引后端
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// New 按名称创建向量索引，名称为空时使用内存索引
+func New(name string, loader Loader, versioner Versioner) (Store, error) {
+	if name == "" {
+		name = BackendMemory
+	}
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的向量索引后端 %s", name)
+	}
+	return factory(loader, versioner)
+}
+
+// Encode 将向量编码为小端序 float32 数组，保存到数据库
+func Encode(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	return data
+}
+
+// Decode 解码 Encode 编码的向量
+func Decode(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("向量数据长度 %d 不是 4 的倍数", len(data))
+	}
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector, nil
+}
+
+// Normalize 返回单位长度的向量，单位向量的点积即余弦相似度
+func Normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	out := make([]float32, len(vector))
+	if sum == 0 {
+		return out
+	}
+	norm := float32(1 / math.Sqrt(sum))
+	for i, v := range vector {
+		out[i] = v * norm
+	}
+	return out
+}
+
+func dot(a []float32, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// topHits 按相似度从高到低保留前 k 条
+func topHits(hits []Hit, k int) []Hit {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
@@ -0,0 +1,64 @@
+package vo
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"txing-ai/internal/agent"
+	"txing-ai/internal/domain"
+	fileservice "txing-ai/internal/service/file"
+)
+
+// AgentOutputFileVO 智能体生成的文件
+type AgentOutputFileVO struct {
+	Id          int64  `json:"id,omitempty" example:"1024"`                  // 文件ID，文件未登记时为空
+	Name        string `json:"name" example:"优化简历_lzw_腾讯后台开发工程师.pdf"`        // 文件名
+	Description string `json:"description,omitempty" example:"优化后的简历"`       // 文件说明
+	MimeType    string `json:"mimeType,omitempty" example:"application/pdf"` // MIME类型
+	Size        int64  `json:"size,omitempty" example:"102400"`              // 文件大小（字节）
+	URL         string `json:"url" example:"/api/file/1024/download"`        // 下载地址
+}
+
+// ToAgentOutputFileVO 将登记的文件转换为 AgentOutputFileVO
+func ToAgentOutputFileVO(file *domain.File, description string) *AgentOutputFileVO {
+	return &AgentOutputFileVO{
+		Id:          file.Id,
+		Name:        file.Name,
+		Description: description,
+		MimeType:    file.MimeType,
+		Size:        file.Size,
+		URL:         fileservice.DownloadURL(file.Id),
+	}
+}
+
+// ToAgentOutputFileVOs 将本次运行中登记的文件转换为 AgentOutputFileVO，文件说明取自智能体的结构化输出
+// 结构化输出中的文件名可能带有 ./ 前缀，按文件名匹配；提到但没有登记的文件（例如登记失败）使用按文件名下载的地址
+func ToAgentOutputFileVOs(files []*domain.File, output *agent.FinalOutput) []*AgentOutputFileVO {
+	descriptions := make(map[string]string)
+	if output != nil {
+		for _, file := range output.Files {
+			descriptions[path.Base(file.Name)] = file.Description
+		}
+	}
+
+	vos := make([]*AgentOutputFileVO, 0, len(files))
+	registered := make(map[string]bool, len(files))
+	for _, file := range files {
+		registered[file.Name] = true
+		vos = append(vos, ToAgentOutputFileVO(file, descriptions[file.Name]))
+	}
+	if output != nil {
+		for _, file := range output.Files {
+			name := path.Base(file.Name)
+			if registered[name] {
+				continue
+			}
+			vos = append(vos, &AgentOutputFileVO{
+				Name:        name,
+				Description: file.Description,
+				URL:         fmt.Sprintf("/api/file/download?filePath=%s", url.QueryEscape(name)),
+			})
+		}
+	}
+	return vos
+}
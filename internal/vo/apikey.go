@@ -0,0 +1,51 @@
+package vo
+
+import (
+	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/enum"
+)
+
+// ApiKeyVO API Key 视图对象，不包含明文
+type ApiKeyVO struct {
+	Id           int64      `json:"id"`                            // API Key ID
+	Name         string     `json:"name" example:"Cursor"`         // 名称
+	KeyPrefix    string     `json:"keyPrefix" example:"tx-3f9a2c"` // 前缀，用于辨认
+	Scopes       []string   `json:"scopes" example:"chat,mcp"`     // 权限范围
+	LastUsedTime *time.Time `json:"lastUsedTime"`                  // 最后使用时间
+	RevokedTime  *time.Time `json:"revokedTime"`                   // 吊销时间，为空表示有效
+	CreatedAt    time.Time  `json:"createdAt"`                     // 创建时间
+}
+
+// CreateApiKeyVO 创建 API Key 响应，明文只返回这一次
+type CreateApiKeyVO struct {
+	ApiKeyVO
+	Key string `json:"key" example:"tx-3f9a2c..."` // API Key 明文
+}
+
+// ToApiKeyVO 将 ApiKey 转换为 ApiKeyVO
+func ToApiKeyVO(apiKey domain.ApiKey) ApiKeyVO {
+	scopes := apiKey.Scopes
+	if len(scopes) == 0 {
+		// 没有权限范围的 API Key 不受限制
+		scopes = enum.ApiKeyScopes
+	}
+	return ApiKeyVO{
+		Id:           apiKey.Id,
+		Name:         apiKey.Name,
+		KeyPrefix:    apiKey.KeyPrefix,
+		Scopes:       scopes,
+		LastUsedTime: apiKey.LastUsedTime,
+		RevokedTime:  apiKey.RevokedTime,
+		CreatedAt:    apiKey.CreateTime,
+	}
+}
+
+// ToApiKeyVOs 将 ApiKey 切片转换为 ApiKeyVO 切片
+func ToApiKeyVOs(apiKeys []domain.ApiKey) []ApiKeyVO {
+	vos := make([]ApiKeyVO, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		vos[i] = ToApiKeyVO(apiKey)
+	}
+	return vos
+}
@@ -33,6 +33,8 @@ type ConversationDetailVO struct {
 	FrequencyPenalty  *float32 `json:"frequencyPenalty,omitempty"`
 	RepetitionPenalty *float32 `json:"repetitionPenalty,omitempty"`
 
+	KnowledgeBaseIDs []int64 `json:"knowledgeBaseIds"` // 引用的知识库ID列表
+
 	Messages []MessageVO `json:"messages"`         // 消息列表
 	Preset   *PresetVO   `json:"preset,omitempty"` // 预设信息
 }
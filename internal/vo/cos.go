@@ -6,3 +6,26 @@ type GetPresignedURLVO struct {
 	Key      string `json:"key"`      // 对象键
 	ExpireAt int64  `json:"expireAt"` // 过期时间戳
 }
+
+// 临时密钥响应
+type TempCredentialVO struct {
+	SecretID     string `json:"secretId"`     // 临时 SecretId
+	SecretKey    string `json:"secretKey"`    // 临时 SecretKey
+	SessionToken string `json:"sessionToken"` // 临时密钥的 Token
+	StartTime    int64  `json:"startTime"`    // 生效时间戳
+	ExpiredTime  int64  `json:"expiredTime"`  // 过期时间戳
+	Bucket       string `json:"bucket"`       // 存储桶
+	Region       string `json:"region"`       // 地域
+	Prefix       string `json:"prefix"`       // 只允许上传到该前缀下
+	MaxSize      int64  `json:"maxSize"`      // 允许上传的最大字节数
+}
+
+// 确认直传文件响应
+type ConfirmUploadVO struct {
+	Key         string `json:"key"`                   // 对象键，保存到业务数据中
+	Size        int64  `json:"size"`                  // 文件大小
+	ContentType string `json:"contentType"`           // 文件类型
+	URL         string `json:"url"`                   // 预签名下载地址
+	FileId      int64  `json:"fileId,omitempty"`      // 附件登记到文件表后的文件ID
+	DownloadURL string `json:"downloadUrl,omitempty"` // 附件按文件ID下载的地址
+}
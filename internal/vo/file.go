@@ -0,0 +1,37 @@
+package vo
+
+import (
+	"txing-ai/internal/domain"
+	fileservice "txing-ai/internal/service/file"
+)
+
+// StorageUsageVO 用户的存储空间使用情况
+type StorageUsageVO struct {
+	UserID     int64  `json:"userId"`                       // 用户ID
+	Username   string `json:"username" example:"zhangsan"`  // 用户名
+	FileCount  int64  `json:"fileCount" example:"12"`       // 文件数量
+	TotalSize  int64  `json:"totalSize" example:"10485760"` // 文件总大小（字节）
+	UploadSize int64  `json:"uploadSize" example:"8388608"` // 用户上传的文件大小（字节）
+	AgentSize  int64  `json:"agentSize" example:"1048576"`  // 智能体附件大小（字节）
+	ToolSize   int64  `json:"toolSize" example:"1048576"`   // 工具生成的文件大小（字节）
+	Quota      int64  `json:"quota" example:"1073741824"`   // 存储配额（字节），0 表示不限制
+	OverQuota  bool   `json:"overQuota" example:"false"`    // 是否超出配额
+}
+
+// ToStorageUsageVOs 合并用户和存储空间使用情况，没有文件的用户使用量为 0
+func ToStorageUsageVOs(users []domain.User, usages map[int64]*fileservice.Usage, quota int64) []StorageUsageVO {
+	result := make([]StorageUsageVO, 0, len(users))
+	for _, user := range users {
+		item := StorageUsageVO{UserID: user.Id, Username: user.Username, Quota: quota}
+		if usage, ok := usages[user.Id]; ok {
+			item.FileCount = usage.FileCount
+			item.TotalSize = usage.TotalSize
+			item.UploadSize = usage.UploadSize
+			item.AgentSize = usage.AgentSize
+			item.ToolSize = usage.ToolSize
+		}
+		item.OverQuota = quota > 0 && item.TotalSize > quota
+		result = append(result, item)
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package vo
+
+import (
+	"time"
+	"txing-ai/internal/domain"
+)
+
+// KnowledgeBaseVO 知识库视图对象
+type KnowledgeBaseVO struct {
+	ID             int64     `json:"id"`                                              // 主键ID
+	UserID         int64     `json:"userId" example:"1"`                              // 创建者用户ID
+	Name           string    `json:"name" example:"产品手册"`                             // 知识库名称
+	Description    string    `json:"description" example:"产品的安装和使用说明"`                // 知识库描述
+	EmbeddingModel string    `json:"embeddingModel" example:"text-embedding-3-small"` // 向量化模型
+	Dimensions     int       `json:"dimensions" example:"1536"`                       // 向量维度，还没有向量化时为 0
+	ChunkSize      int       `json:"chunkSize" example:"800"`                         // 切分块大小（字符）
+	ChunkOverlap   int       `json:"chunkOverlap" example:"100"`                      // 相邻块重叠字符数
+	Official       bool      `json:"official" example:"false"`                        // 是否官方知识库
+	CreatedAt      time.Time `json:"createdAt"`                                       // 创建时间
+	UpdatedAt      time.Time `json:"updatedAt"`                                       // 更新时间
+}
+
+// KnowledgeDocumentVO 知识库文档视图对象
+type KnowledgeDocumentVO struct {
+	ID              int64     `json:"id"`                      // 主键ID
+	KnowledgeBaseID int64     `json:"knowledgeBaseId"`         // 知识库ID
+	FileID          int64     `json:"fileId"`                  // 文件ID
+	Name            string    `json:"name" example:"产品手册.pdf"` // 文档名称
+	Status          string    `json:"status" example:"ready"`  // 处理状态 pending/processing/ready/failed
+	Error           string    `json:"error,omitempty"`         // 处理失败的原因
+	Language        string    `json:"language" example:"zh"`   // 检测到的语言
+	Pages           int       `json:"pages" example:"12"`      // 页数
+	ChunkCount      int       `json:"chunkCount" example:"48"` // 切分块数量
+	CreatedAt       time.Time `json:"createdAt"`               // 创建时间
+	UpdatedAt       time.Time `json:"updatedAt"`               // 更新时间
+}
+
+// ToKnowledgeBaseVO 将 KnowledgeBase 转换为 KnowledgeBaseVO
+func ToKnowledgeBaseVO(kb domain.KnowledgeBase) KnowledgeBaseVO {
+	return KnowledgeBaseVO{
+		ID:             kb.Id,
+		UserID:         kb.UserID,
+		Name:           kb.Name,
+		Description:    kb.Description,
+		EmbeddingModel: kb.EmbeddingModel,
+		Dimensions:     kb.Dimensions,
+		ChunkSize:      kb.ChunkSize,
+		ChunkOverlap:   kb.ChunkOverlap,
+		Official:       kb.Official,
+		CreatedAt:      kb.CreateTime,
+		UpdatedAt:      kb.UpdateTime,
+	}
+}
+
+// ToKnowledgeBaseVOs 将 KnowledgeBase 切片转换为 KnowledgeBaseVO 切片
+func ToKnowledgeBaseVOs(kbs []domain.KnowledgeBase) []KnowledgeBaseVO {
+	vos := make([]KnowledgeBaseVO, len(kbs))
+	for i, kb := range kbs {
+		vos[i] = ToKnowledgeBaseVO(kb)
+	}
+	return vos
+}
+
+// ToKnowledgeDocumentVO 将 KnowledgeDocument 转换为 KnowledgeDocumentVO
+func ToKnowledgeDocumentVO(doc domain.KnowledgeDocument) KnowledgeDocumentVO {
+	return KnowledgeDocumentVO{
+		ID:              doc.Id,
+		KnowledgeBaseID: doc.KnowledgeBaseID,
+		FileID:          doc.FileID,
+		Name:            doc.Name,
+		Status:          doc.Status,
+		Error:           doc.Error,
+		Language:        doc.Language,
+		Pages:           doc.Pages,
+		ChunkCount:      doc.ChunkCount,
+		CreatedAt:       doc.CreateTime,
+		UpdatedAt:       doc.UpdateTime,
+	}
+}
+
+// ToKnowledgeDocumentVOs 将 KnowledgeDocument 切片转换为 KnowledgeDocumentVO 切片
+func ToKnowledgeDocumentVOs(docs []domain.KnowledgeDocument) []KnowledgeDocumentVO {
+	vos := make([]KnowledgeDocumentVO, len(docs))
+	for i, doc := range docs {
+		vos[i] = ToKnowledgeDocumentVO(doc)
+	}
+	return vos
+}
@@ -0,0 +1,199 @@
+package vo
+
+import (
+	"time"
+	"txing-ai/internal/domain"
+	"txing-ai/internal/tool/mcp"
+	"txing-ai/internal/utils"
+)
+
+// MCPServerVO MCP服务器视图对象，环境变量、请求头和认证令牌已脱敏
+type MCPServerVO struct {
+	Id            int64             `json:"id"`                                     // 服务器ID
+	Name          string            `json:"name" example:"amap"`                    // 服务器名称
+	Description   string            `json:"description" example:"高德地图"`             // 服务器描述
+	Transport     string            `json:"transport" example:"stdio"`              // 传输方式
+	Command       string            `json:"command" example:"npx"`                  // 启动命令
+	Args          []string          `json:"args"`                                   // 启动参数
+	Environment   map[string]string `json:"environment"`                            // 环境变量（已脱敏）
+	URL           string            `json:"url" example:"https://mcp.amap.com/mcp"` // 服务地址
+	Headers       map[string]string `json:"headers"`                                // 请求头（已脱敏）
+	AuthToken     string            `json:"authToken"`                              // 认证令牌（已脱敏）
+	Enabled       bool              `json:"enabled"`                                // 启用状态
+	DisabledTools []string          `json:"disabledTools"`                          // 禁用的工具名称
+	Connected     bool              `json:"connected"`                              // 是否已连接
+	LastError     string            `json:"lastError"`                              // 最近一次连接或健康检查的错误
+	Tools         []MCPToolVO       `json:"tools"`                                  // 服务器当前提供的工具，未连接时为空
+	CreatedAt     time.Time         `json:"createdAt"`                              // 创建时间
+	UpdatedAt     time.Time         `json:"updatedAt"`                              // 更新时间
+}
+
+// MCPToolVO MCP工具视图对象
+type MCPToolVO struct {
+	Name        string `json:"name" example:"maps_weather"`     // 工具名称
+	Description string `json:"description" example:"查询指定城市的天气"` // 工具描述
+	Enabled     bool   `json:"enabled"`                         // 是否启用
+}
+
+// ToMCPServerVO 将 MCPServer 和运行状态转换为 MCPServerVO，status 为空表示服务器未运行
+func ToMCPServerVO(server domain.MCPServer, status *mcp.ServerStatus) MCPServerVO {
+	serverVO := MCPServerVO{
+		Id:            server.Id,
+		Name:          server.Name,
+		Description:   server.Description,
+		Transport:     server.Transport,
+		Command:       server.Command,
+		Args:          server.Args,
+		Environment:   utils.MaskSecrets(server.Environment),
+		URL:           server.URL,
+		Headers:       utils.MaskSecrets(server.Headers),
+		AuthToken:     utils.MaskSecret(server.AuthToken),
+		Enabled:       server.Enabled,
+		DisabledTools: server.DisabledTools,
+		Tools:         []MCPToolVO{},
+		CreatedAt:     server.CreateTime,
+		UpdatedAt:     server.UpdateTime,
+	}
+	if status != nil {
+		serverVO.Connected = status.Connected
+		serverVO.LastError = status.LastError
+		serverVO.Tools = ToMCPToolVOs(status.Tools)
+	}
+	return serverVO
+}
+
+// ToMCPToolVOs 将 MCP 工具信息转换为 MCPToolVO 切片
+func ToMCPToolVOs(tools []mcp.ToolInfo) []MCPToolVO {
+	vos := make([]MCPToolVO, len(tools))
+	for i, tool := range tools {
+		vos[i] = MCPToolVO{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Enabled:     tool.Enabled,
+		}
+	}
+	return vos
+}
+
+// MCPBrowseServerVO 用户可浏览的MCP服务器
+type MCPBrowseServerVO struct {
+	Name              string `json:"name" example:"docs"` // 服务器名称
+	Connected         bool   `json:"connected"`           // 是否已连接
+	SupportsResources bool   `json:"supportsResources"`   // 是否支持资源
+	SupportsPrompts   bool   `json:"supportsPrompts"`     // 是否支持 prompt
+}
+
+// MCPResourcesVO MCP服务器的资源和资源模板
+type MCPResourcesVO struct {
+	Resources []MCPResourceVO         `json:"resources"` // 资源
+	Templates []MCPResourceTemplateVO `json:"templates"` // 资源模板
+}
+
+// MCPResourceVO MCP资源视图对象
+type MCPResourceVO struct {
+	URI         string `json:"uri" example:"docs://readme"`      // 资源地址
+	Name        string `json:"name" example:"readme"`            // 资源名称
+	Description string `json:"description"`                      // 资源描述
+	MIMEType    string `json:"mimeType" example:"text/markdown"` // 资源类型
+}
+
+// MCPResourceTemplateVO MCP资源模板视图对象
+type MCPResourceTemplateVO struct {
+	URITemplate string `json:"uriTemplate" example:"docs://{name}"` // 资源地址模板
+	Name        string `json:"name" example:"doc"`                  // 模板名称
+	Description string `json:"description"`                         // 模板描述
+	MIMEType    string `json:"mimeType"`                            // 资源类型
+}
+
+// MCPResourceContentVO MCP资源内容视图对象
+type MCPResourceContentVO struct {
+	URI      string `json:"uri" example:"docs://readme"`      // 资源地址
+	MIMEType string `json:"mimeType" example:"text/markdown"` // 资源类型
+	Text     string `json:"text,omitempty"`                   // 文本内容
+	Blob     string `json:"blob,omitempty"`                   // 二进制内容（base64 编码）
+}
+
+// MCPPromptVO MCP prompt 视图对象
+type MCPPromptVO struct {
+	Name        string                `json:"name" example:"review"` // prompt 名称
+	Description string                `json:"description"`           // prompt 描述
+	Arguments   []MCPPromptArgumentVO `json:"arguments"`             // prompt 参数
+}
+
+// MCPPromptArgumentVO MCP prompt 参数视图对象
+type MCPPromptArgumentVO struct {
+	Name        string `json:"name" example:"language"` // 参数名称
+	Description string `json:"description"`             // 参数描述
+	Required    bool   `json:"required"`                // 是否必填
+}
+
+// ToMCPBrowseServerVO 将MCP服务器的运行状态转换为 MCPBrowseServerVO
+func ToMCPBrowseServerVO(name string, status *mcp.ServerStatus) MCPBrowseServerVO {
+	serverVO := MCPBrowseServerVO{Name: name}
+	if status != nil {
+		serverVO.Connected = status.Connected
+		serverVO.SupportsResources = status.SupportsResources
+		serverVO.SupportsPrompts = status.SupportsPrompts
+	}
+	return serverVO
+}
+
+// ToMCPResourcesVO 将MCP资源和资源模板转换为 MCPResourcesVO
+func ToMCPResourcesVO(resources []mcp.Resource, templates []mcp.ResourceTemplate) MCPResourcesVO {
+	resourcesVO := MCPResourcesVO{
+		Resources: make([]MCPResourceVO, len(resources)),
+		Templates: make([]MCPResourceTemplateVO, len(templates)),
+	}
+	for i, resource := range resources {
+		resourcesVO.Resources[i] = MCPResourceVO{
+			URI:         resource.URI,
+			Name:        resource.Name,
+			Description: resource.Description,
+			MIMEType:    resource.MIMEType,
+		}
+	}
+	for i, template := range templates {
+		resourcesVO.Templates[i] = MCPResourceTemplateVO{
+			URITemplate: template.URITemplate,
+			Name:        template.Name,
+			Description: template.Description,
+			MIMEType:    template.MIMEType,
+		}
+	}
+	return resourcesVO
+}
+
+// ToMCPResourceContentVOs 将MCP资源内容转换为 MCPResourceContentVO 切片
+func ToMCPResourceContentVOs(contents []mcp.ResourceContent) []MCPResourceContentVO {
+	vos := make([]MCPResourceContentVO, len(contents))
+	for i, content := range contents {
+		vos[i] = MCPResourceContentVO{
+			URI:      content.URI,
+			MIMEType: content.MIMEType,
+			Text:     content.Text,
+			Blob:     content.Blob,
+		}
+	}
+	return vos
+}
+
+// ToMCPPromptVOs 将MCP prompt 转换为 MCPPromptVO 切片
+func ToMCPPromptVOs(prompts []mcp.Prompt) []MCPPromptVO {
+	vos := make([]MCPPromptVO, len(prompts))
+	for i, prompt := range prompts {
+		arguments := make([]MCPPromptArgumentVO, len(prompt.Arguments))
+		for j, argument := range prompt.Arguments {
+			arguments[j] = MCPPromptArgumentVO{
+				Name:        argument.Name,
+				Description: argument.Description,
+				Required:    argument.Required,
+			}
+		}
+		vos[i] = MCPPromptVO{
+			Name:        prompt.Name,
+			Description: prompt.Description,
+			Arguments:   arguments,
+		}
+	}
+	return vos
+}
@@ -7,16 +7,18 @@ import (
 
 // PresetVO 预设视图对象
 type PresetVO struct {
-	ID          int64     `json:"id"`                                              // 主键ID
-	UserID      *int64    `json:"userId" example:"1"`                              // 用户ID
-	Avatar      string    `json:"avatar" example:"https://example.com/avatar.png"` // 预设头像
-	Name        string    `json:"name" example:"GPT助手"`                            // 预设名称
-	Description string    `json:"description" example:"一个智能的GPT助手"`                // 预设描述
-	Context     string    `json:"context" example:"你是一个智能助手..."`                   // 预设上下文
-	Tags        string    `json:"tags" example:"popular,tools"`                    // 预设标签
-	Official    bool      `json:"official" example:"false"`                        // 是否官方预设
-	CreatedAt   time.Time `json:"createdAt"`                                       // 创建时间
-	UpdatedAt   time.Time `json:"updatedAt"`                                       // 更新时间
+	ID          int64  `json:"id"`                                              // 主键ID
+	UserID      *int64 `json:"userId" example:"1"`                              // 用户ID
+	Avatar      string `json:"avatar" example:"https://example.com/avatar.png"` // 预设头像
+	Name        string `json:"name" example:"GPT助手"`                            // 预设名称
+	Description string `json:"description" example:"一个智能的GPT助手"`                // 预设描述
+	Context     string `json:"context" example:"你是一个智能助手..."`                   // 预设上下文
+	Tags        string `json:"tags" example:"popular,tools"`                    // 预设标签
+	Official    bool   `json:"official" example:"false"`                        // 是否官方预设
+	// 引用的知识库ID列表
+	KnowledgeBaseIds []int64   `json:"knowledgeBaseIds"`
+	CreatedAt        time.Time `json:"createdAt"` // 创建时间
+	UpdatedAt        time.Time `json:"updatedAt"` // 更新时间
 }
 
 // ToPresetVO 将 Preset 转换为 PresetVO
@@ -32,6 +34,8 @@ func ToPresetVO(preset domain.Preset) PresetVO {
 		Official:    preset.Official,
 		CreatedAt:   preset.CreateTime,
 		UpdatedAt:   preset.UpdateTime,
+
+		KnowledgeBaseIds: preset.KnowledgeBaseIDs,
 	}
 }
 